@@ -0,0 +1,103 @@
+package analyzer
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// ErrNoCoreData is returned when no context switch in the capture identifies a CPU core.
+// The .prof format has no core field; the core can only be recovered from switch
+// names that carry it, such as "CPU 3" or "core#1".
+var ErrNoCoreData = errors.New("context switches carry no CPU core information")
+
+// coreNamePattern extracts a core number from a context switch name
+var coreNamePattern = regexp.MustCompile(`(?i)\b(?:cpu|core)\s*#?\s*(\d+)\b`)
+
+// ThreadAffinity summarizes how a thread moved between CPU cores
+type ThreadAffinity struct {
+	ThreadID          uint64
+	ThreadName        string
+	Switches          int // Context switches that identify a core
+	Migrations        int // Consecutive switches landing on different cores
+	Cores             int // Distinct cores the thread ran on
+	DominantCore      int
+	DominantCoreShare float64 // Percent of switches on the dominant core
+}
+
+// GetThreadAffinity reports core migrations per thread, inferred from context switch
+// names, busiest migrators first. Threads without core-tagged switches are omitted.
+func (a *Analyzer) GetThreadAffinity() ([]*ThreadAffinity, error) {
+	var result []*ThreadAffinity
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+
+		switches := make([]*parser.ContextSwitch, len(thread.ContextSwitches))
+		copy(switches, thread.ContextSwitches)
+		sort.Slice(switches, func(i, j int) bool {
+			return switches[i].Begin < switches[j].Begin
+		})
+
+		affinity := &ThreadAffinity{ThreadID: threadID, ThreadName: thread.ThreadName}
+		coreCounts := make(map[int]int)
+		previous := -1
+		for _, cs := range switches {
+			core, ok := coreFromSwitchName(cs.Name)
+			if !ok {
+				continue
+			}
+			affinity.Switches++
+			coreCounts[core]++
+			if previous >= 0 && core != previous {
+				affinity.Migrations++
+			}
+			previous = core
+		}
+
+		if affinity.Switches == 0 {
+			continue
+		}
+
+		affinity.Cores = len(coreCounts)
+		affinity.DominantCore = -1
+		for core, count := range coreCounts {
+			dominant := coreCounts[affinity.DominantCore]
+			if count > dominant || (count == dominant && core < affinity.DominantCore) {
+				affinity.DominantCore = core
+			}
+		}
+		affinity.DominantCoreShare = float64(coreCounts[affinity.DominantCore]) / float64(affinity.Switches) * 100
+
+		result = append(result, affinity)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNoCoreData
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Migrations != result[j].Migrations {
+			return result[i].Migrations > result[j].Migrations
+		}
+		return result[i].ThreadID < result[j].ThreadID
+	})
+
+	return result, nil
+}
+
+// coreFromSwitchName returns the core number named by a context switch, if any
+func coreFromSwitchName(name string) (int, bool) {
+	match := coreNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return 0, false
+	}
+	core, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+	return core, true
+}
@@ -0,0 +1,104 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestCoreFromSwitchName(t *testing.T) {
+	tests := []struct {
+		name string
+		core int
+		ok   bool
+	}{
+		{"CPU 3", 3, true},
+		{"cpu3", 3, true},
+		{"core#1", 1, true},
+		{"Core # 12", 12, true},
+		{"game.exe", 0, false},
+		{"scpu 3", 0, false},
+		{"CPU 99999999999999999999", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, ok := coreFromSwitchName(tt.name)
+			if core != tt.core || ok != tt.ok {
+				t.Errorf("coreFromSwitchName(%q) = %d, %t; want %d, %t", tt.name, core, ok, tt.core, tt.ok)
+			}
+		})
+	}
+}
+
+// affinityThread returns a thread whose context switches, written in reverse
+// order, name the given switch targets
+func affinityThread(id uint64, names ...string) proftest.Thread {
+	thread := proftest.Thread{ID: id, Name: fmt.Sprintf("T%d", id), Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}}
+	for i := len(names) - 1; i >= 0; i-- {
+		begin := uint64(i) * 100
+		thread.ContextSwitches = append(thread.ContextSwitches, proftest.ContextSwitch{ThreadID: id, Begin: begin, End: begin + 10, Name: names[i]})
+	}
+	return thread
+}
+
+func TestGetThreadAffinity(t *testing.T) {
+	tests := []struct {
+		name    string
+		threads []proftest.Thread
+		want    []string // "thread switches migrations cores dominant share"
+		wantErr error
+	}{
+		{
+			name:    "no core names",
+			threads: []proftest.Thread{affinityThread(1, "game.exe", "game.exe")},
+			wantErr: ErrNoCoreData,
+		},
+		{
+			name:    "no switches",
+			threads: []proftest.Thread{affinityThread(1)},
+			wantErr: ErrNoCoreData,
+		},
+		{
+			name:    "pinned",
+			threads: []proftest.Thread{affinityThread(1, "CPU 2", "CPU 2", "game.exe", "CPU 2")},
+			want:    []string{"1 3 0 1 2 100"},
+		},
+		{
+			name:    "migrating in time order",
+			threads: []proftest.Thread{affinityThread(1, "CPU 0", "CPU 1", "CPU 1", "CPU 0")},
+			want:    []string{"1 4 2 2 0 50"},
+		},
+		{
+			name: "busiest migrator first",
+			threads: []proftest.Thread{
+				affinityThread(1, "CPU 0", "CPU 0"),
+				affinityThread(2, "CPU 0", "CPU 1", "CPU 2"),
+				affinityThread(3, "game.exe"),
+			},
+			want: []string{"2 3 2 3 0 33", "1 2 0 1 0 100"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t, &proftest.Profile{Descriptors: proftest.Descriptors("Run"), Threads: tt.threads})
+			affinities, err := a.GetThreadAffinity()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetThreadAffinity error = %v, want %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, affinity := range affinities {
+				got = append(got, fmt.Sprintf("%d %d %d %d %d %.0f", affinity.ThreadID, affinity.Switches, affinity.Migrations,
+					affinity.Cores, affinity.DominantCore, affinity.DominantCoreShare))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("affinities = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
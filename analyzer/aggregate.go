@@ -0,0 +1,165 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// RunStats is one function's cumulative time across several profiles of the same workload
+type RunStats struct {
+	Name      string
+	File      string
+	Line      int32
+	Durations []time.Duration // Per profile, in the order given; 0 where the function didn't appear
+	Runs      int             // Profiles the function appeared in
+	Mean      time.Duration
+	Variance  float64 // Sample variance across profiles, in ns²
+	StdDev    time.Duration
+	Min       time.Duration
+	Max       time.Duration
+	CV        float64 // Coefficient of variation (StdDev / Mean), 0 if Mean is 0
+}
+
+// AggregateHotspots computes per-function time (measured in mode) across several runs
+// of the same workload, with its mean and spread. A low coefficient of variation marks
+// a consistent bottleneck, a high one run-to-run jitter. Functions are matched by name,
+// as in CompareProfiles, and count as 0 in runs where they don't appear. Results are
+// sorted by mean, largest first.
+func AggregateHotspots(analyzers []*Analyzer, mode TimeMode) []*RunStats {
+	stats := make(map[string]*RunStats)
+
+	for run, a := range analyzers {
+		for name, info := range hotspotsByName(a) {
+			s, ok := stats[name]
+			if !ok {
+				s = &RunStats{Name: name, Durations: make([]time.Duration, len(analyzers))}
+				stats[name] = s
+			}
+			s.File = info.File
+			s.Line = info.Line
+			s.Durations[run] = mode.Of(info)
+			s.Runs++
+		}
+	}
+
+	result := make([]*RunStats, 0, len(stats))
+	for _, s := range stats {
+		s.summarize()
+		result = append(result, s)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Mean != result[j].Mean {
+			return result[i].Mean > result[j].Mean
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// summarize fills in the mean and spread from Durations
+func (s *RunStats) summarize() {
+	n := len(s.Durations)
+	if n == 0 {
+		return
+	}
+
+	sum := 0.0
+	s.Min, s.Max = s.Durations[0], s.Durations[0]
+	for _, d := range s.Durations {
+		sum += float64(d)
+		s.Min = min(s.Min, d)
+		s.Max = max(s.Max, d)
+	}
+	mean := sum / float64(n)
+	s.Mean = time.Duration(mean)
+
+	if n > 1 {
+		squares := 0.0
+		for _, d := range s.Durations {
+			squares += (float64(d) - mean) * (float64(d) - mean)
+		}
+		s.Variance = squares / float64(n-1)
+	}
+	s.StdDev = time.Duration(math.Sqrt(s.Variance))
+
+	if mean > 0 {
+		s.CV = math.Sqrt(s.Variance) / mean
+	}
+}
+
+// HistoricalAnomaly is a function whose average call in one profile is well above
+// its average call in other runs of the same workload
+type HistoricalAnomaly struct {
+	Name          string
+	File          string
+	Line          int32
+	CurrentAvg    time.Duration
+	CurrentCalls  int
+	HistoricalAvg time.Duration // Mean of the per-run averages over the baselines it appears in
+	Runs          int           // Baselines the function appears in
+	Ratio         float64       // CurrentAvg / HistoricalAvg
+}
+
+// FindHistoricalAnomalies flags the functions of current whose average call, measured
+// in mode, is at least minRatio times their historical norm: the mean of their
+// average call in each baseline, each run weighing the same. Functions are matched by
+// name, as in CompareProfiles; those missing from every baseline have no norm and are
+// skipped. Results are sorted by ratio, largest first.
+func FindHistoricalAnomalies(current *Analyzer, baselines []*Analyzer, mode TimeMode, minRatio float64) []*HistoricalAnomaly {
+	type norm struct {
+		sum  time.Duration
+		runs int
+	}
+	norms := make(map[string]*norm)
+	for _, baseline := range baselines {
+		for name, info := range hotspotsByName(baseline) {
+			if info.CallCount == 0 {
+				continue
+			}
+			n, ok := norms[name]
+			if !ok {
+				n = &norm{}
+				norms[name] = n
+			}
+			n.sum += mode.Of(info) / time.Duration(info.CallCount)
+			n.runs++
+		}
+	}
+
+	var anomalies []*HistoricalAnomaly
+	for name, info := range hotspotsByName(current) {
+		n, ok := norms[name]
+		if !ok || info.CallCount == 0 {
+			continue
+		}
+
+		historical := n.sum / time.Duration(n.runs)
+		avg := mode.Of(info) / time.Duration(info.CallCount)
+		if historical <= 0 || float64(avg) < float64(historical)*minRatio {
+			continue
+		}
+
+		anomalies = append(anomalies, &HistoricalAnomaly{
+			Name:          name,
+			File:          info.File,
+			Line:          info.Line,
+			CurrentAvg:    avg,
+			CurrentCalls:  info.CallCount,
+			HistoricalAvg: historical,
+			Runs:          n.runs,
+			Ratio:         float64(avg) / float64(historical),
+		})
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		if anomalies[i].Ratio != anomalies[j].Ratio {
+			return anomalies[i].Ratio > anomalies[j].Ratio
+		}
+		return anomalies[i].Name < anomalies[j].Name
+	})
+
+	return anomalies
+}
@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestAggregateHotspots(t *testing.T) {
+	ms := time.Millisecond
+	// Update is consistently hot; Render swings from run to run; Physics shows up once
+	analyzers := []*Analyzer{
+		newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 10, "Render": 2})),
+		newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 10, "Render": 30, "Physics": 3})),
+		newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 10, "Render": 10})),
+	}
+
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		runs      int
+		mean      time.Duration
+		variance  float64 // ns²
+		min, max  time.Duration
+		cv        float64
+	}{
+		{"Render", []time.Duration{2 * ms, 30 * ms, 10 * ms}, 3, 14 * ms, 208e12, 2 * ms, 30 * ms, math.Sqrt(208e12) / 14e6},
+		{"Update", []time.Duration{10 * ms, 10 * ms, 10 * ms}, 3, 10 * ms, 0, 10 * ms, 10 * ms, 0},
+		{"Physics", []time.Duration{0, 3 * ms, 0}, 1, ms, 3e12, 0, 3 * ms, math.Sqrt(3e12) / 1e6},
+	}
+
+	stats := AggregateHotspots(analyzers, Inclusive)
+	if len(stats) != len(tests) {
+		t.Fatalf("got %d functions, want %d", len(stats), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := stats[i]
+			if s.Name != tt.name || !reflect.DeepEqual(s.Durations, tt.durations) || s.Runs != tt.runs {
+				t.Fatalf("stats[%d] = %s %v in %d runs, want %s %v in %d", i, s.Name, s.Durations, s.Runs, tt.name, tt.durations, tt.runs)
+			}
+			if s.Mean != tt.mean || s.Min != tt.min || s.Max != tt.max {
+				t.Errorf("mean %v, min %v, max %v; want %v, %v, %v", s.Mean, s.Min, s.Max, tt.mean, tt.min, tt.max)
+			}
+			if math.Abs(s.Variance-tt.variance) > 1 || math.Abs(s.CV-tt.cv) > 1e-9 {
+				t.Errorf("variance %v, CV %v; want %v, %v", s.Variance, s.CV, tt.variance, tt.cv)
+			}
+			if want := time.Duration(math.Sqrt(tt.variance)); s.StdDev != want {
+				t.Errorf("stddev = %v, want %v", s.StdDev, want)
+			}
+		})
+	}
+}
+
+func TestAggregateHotspotsSingleRun(t *testing.T) {
+	stats := AggregateHotspots([]*Analyzer{newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 10}))}, Exclusive)
+	if len(stats) != 1 {
+		t.Fatalf("got %d functions, want 1", len(stats))
+	}
+	if s := stats[0]; s.Mean != 10*time.Millisecond || s.Variance != 0 || s.StdDev != 0 || s.CV != 0 {
+		t.Errorf("stats = %+v, want Update at 10ms without spread", s)
+	}
+}
+
+func TestFindHistoricalAnomalies(t *testing.T) {
+	// Across the baselines Update averages 15ms over two runs, Render 20ms and
+	// Physics 5ms in its one run. The current run triples Update.
+	baselines := []*Analyzer{
+		newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 10, "Render": 20})),
+		newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 20, "Render": 20, "Physics": 5})),
+	}
+	current := newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 45, "Render": 20, "Physics": 4}))
+
+	tests := []struct {
+		name      string
+		baselines []*Analyzer
+		minRatio  float64
+		want      []string // "name current historical runs ratio"
+	}{
+		{"tripled function", baselines, 2, []string{"Update 45ms 15ms 2 3"}},
+		{"ratio equal to the threshold", baselines, 3, []string{"Update 45ms 15ms 2 3"}},
+		{"threshold above every ratio", baselines, 3.5, nil},
+		{"at or above the norm", baselines, 1, []string{"Update 45ms 15ms 2 3", "Render 20ms 20ms 2 1"}},
+		{"every shared function", baselines, 0, []string{"Update 45ms 15ms 2 3", "Render 20ms 20ms 2 1", "Physics 4ms 5ms 1 0.8"}},
+		{"functions missing from every baseline", baselines[:1], 0, []string{"Update 45ms 10ms 1 4.5", "Render 20ms 20ms 1 1"}},
+		{"no baselines", nil, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, anomaly := range FindHistoricalAnomalies(current, tt.baselines, Inclusive, tt.minRatio) {
+				got = append(got, fmt.Sprintf("%s %v %v %d %v", anomaly.Name, anomaly.CurrentAvg, anomaly.HistoricalAvg, anomaly.Runs, anomaly.Ratio))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("anomalies = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindHistoricalAnomaliesAveragesCalls(t *testing.T) {
+	// The current run calls Update three times for 90ms in all: 30ms a call, three
+	// times the baseline's single 10ms call, though its total is nine times as long
+	ms := uint64(time.Millisecond)
+	current := newTestAnalyzer(t, &proftest.Profile{
+		Descriptors: []proftest.Descriptor{{ID: 1, Name: "Update", File: "game.cpp", Line: 1}},
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 30 * ms},
+			{ID: 1, Begin: 30 * ms, End: 60 * ms},
+			{ID: 1, Begin: 60 * ms, End: 90 * ms},
+		}}},
+	})
+	baseline := newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 10}))
+
+	anomalies := FindHistoricalAnomalies(current, []*Analyzer{baseline}, Exclusive, 2)
+	if len(anomalies) != 1 {
+		t.Fatalf("got %d anomalies, want 1", len(anomalies))
+	}
+	if a := anomalies[0]; a.CurrentAvg != 30*time.Millisecond || a.CurrentCalls != 3 || a.Ratio != 3 || a.File != "game.cpp" {
+		t.Errorf("anomaly = %+v, want Update at 30ms over 3 calls, ratio 3", a)
+	}
+}
@@ -3,6 +3,7 @@ package analyzer
 import (
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/yourusername/easyprofiler-mcp/parser"
@@ -10,24 +11,50 @@ import (
 
 // Analyzer provides performance analysis tools
 type Analyzer struct {
-	profile *parser.ProfileData
+	profile Profile
+
+	// Exclusive time per block, built lazily by selfTime
+	selfTimesOnce sync.Once
+	selfTimes     map[*parser.Block]time.Duration
+
+	// Merged switched-out intervals per thread, built lazily by OnCPUTime
+	switchedOutOnce sync.Once
+	switchedOut     map[uint64][]interval
+
+	anonymousPolicy AnonymousPolicy
+
+	// Names of blocks that don't count as busy time; see SetIdlePatterns
+	idlePatterns []string
 }
 
-// NewAnalyzer creates a new analyzer for the given profile
-func NewAnalyzer(profile *parser.ProfileData) *Analyzer {
+// NewAnalyzer creates a new analyzer for the given profile, usually a *parser.ProfileData
+func NewAnalyzer(profile Profile) *Analyzer {
 	return &Analyzer{profile: profile}
 }
 
 // BlockInfo contains analyzed block information
 type BlockInfo struct {
-	Name        string
-	File        string
-	Line        int32
-	Duration    time.Duration
-	CallCount   int
-	ThreadID    uint64
-	ThreadName  string
-	AvgDuration time.Duration
+	Name         string
+	File         string
+	Line         int32
+	Duration     time.Duration
+	SelfDuration time.Duration // Duration minus time spent in child blocks
+	CallCount    int
+	ThreadID     uint64
+	ThreadName   string
+	AvgDuration  time.Duration
+	ThreadTime   time.Duration // Busy time of the threads the blocks ran on (aggregates only)
+	Depth        int           // Nesting level, 0 for top-level (individual blocks only)
+	Begin        uint64        // Raw begin timestamp (individual blocks only)
+	End          uint64        // Raw end timestamp (individual blocks only)
+	DescriptorID uint32        // Descriptor of the block (individual blocks only)
+	Ordinal      uint32        // Occurrence of the descriptor on the thread (individual blocks only)
+}
+
+// Key returns the stable identity of an individual block, the same in full and
+// sampled loads of a file; see parser.Block.Key
+func (b *BlockInfo) Key() string {
+	return parser.BlockKey(b.ThreadID, b.DescriptorID, b.Ordinal)
 }
 
 // ThreadStats contains thread statistics
@@ -39,71 +66,118 @@ type ThreadStats struct {
 	ContextSwitches   int
 	AvgBlockDuration  time.Duration
 	PercentOfTotal    float64
+	StartOffset       time.Duration // First block begin, relative to the capture begin
+	EndOffset         time.Duration // Last block end, relative to the capture begin
+	WallSpan          time.Duration // First block begin to last block end, busy or not
+	SwitchedOut       time.Duration // Time switched out within WallSpan; 0 without context switches
 }
 
 // PerformanceIssue represents a detected performance problem
 type PerformanceIssue struct {
 	Type        string
-	Severity    string // "high", "medium", "low"
+	Severity    string  // "high", "medium", "low"
+	Score       float64 // 0-100 impact score the severity is derived from
 	Description string
 	Location    string
+	Function    string // Offending function, empty for thread-level issues
+	Suggestion  string // Generic remediation advice for the issue type
 	Duration    time.Duration
 	ThreadID    uint64
 	ThreadName  string
 }
 
-// GetSlowestBlocks returns the N slowest blocks
+// GetSlowestBlocks returns the N slowest blocks by inclusive time
 func (a *Analyzer) GetSlowestBlocks(limit int) []*BlockInfo {
+	return a.GetSlowestBlocksWithMode(limit, Inclusive)
+}
+
+// GetSlowestBlocksWithMode returns the N slowest blocks, measuring time in mode
+func (a *Analyzer) GetSlowestBlocksWithMode(limit int, mode TimeMode) []*BlockInfo {
+	allBlocks := a.blocksByDuration(mode)
+
+	if limit > len(allBlocks) {
+		limit = len(allBlocks)
+	}
+
+	return allBlocks[:limit]
+}
+
+// GetDistinctSlowestBlocks returns the N slowest blocks by inclusive time, keeping only
+// the slowest instance of each call site. CallCount holds the number of instances of that site.
+func (a *Analyzer) GetDistinctSlowestBlocks(limit int) []*BlockInfo {
+	return a.GetDistinctSlowestBlocksWithMode(limit, Inclusive)
+}
+
+// GetDistinctSlowestBlocksWithMode is GetDistinctSlowestBlocks measuring time in mode
+func (a *Analyzer) GetDistinctSlowestBlocksWithMode(limit int, mode TimeMode) []*BlockInfo {
+	var result []*BlockInfo
+	seen := make(map[string]*BlockInfo)
+
+	for _, block := range a.blocksByDuration(mode) {
+		key := fmt.Sprintf("%s:%s:%d", block.Name, block.File, block.Line)
+		if slowest, ok := seen[key]; ok {
+			slowest.CallCount++
+			continue
+		}
+		seen[key] = block
+		result = append(result, block)
+	}
+
+	if limit > len(result) {
+		limit = len(result)
+	}
+
+	return result[:limit]
+}
+
+// blocksByDuration returns every block in the profile, slowest first in mode.
+// Unclosed blocks are left out: their true duration is unknown.
+func (a *Analyzer) blocksByDuration(mode TimeMode) []*BlockInfo {
 	var allBlocks []*BlockInfo
 
-	for threadID, thread := range a.profile.Threads {
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
 		blocks := a.analyzeBlocksRecursive(thread.Blocks, threadID, thread.ThreadName)
 		allBlocks = append(allBlocks, blocks...)
 	}
 
 	// Sort by duration
 	sort.Slice(allBlocks, func(i, j int) bool {
-		return allBlocks[i].Duration > allBlocks[j].Duration
+		di, dj := mode.Of(allBlocks[i]), mode.Of(allBlocks[j])
+		if di != dj {
+			return di > dj
+		}
+		return lessBlockInfo(allBlocks[i], allBlocks[j])
 	})
 
-	if limit > len(allBlocks) {
-		limit = len(allBlocks)
-	}
-
-	return allBlocks[:limit]
+	return allBlocks
 }
 
 func (a *Analyzer) analyzeBlocksRecursive(blocks []*parser.Block, threadID uint64, threadName string) []*BlockInfo {
 	var result []*BlockInfo
 
-	for _, block := range blocks {
-		descriptor := a.profile.Descriptors[block.ID]
-
-		name := block.Name
-		if name == "" && descriptor != nil {
-			name = descriptor.Name
-		}
-
-		file := ""
-		line := int32(0)
-		if descriptor != nil {
-			file = descriptor.File
-			line = descriptor.Line
+	walkBlocks(blocks, func(block *parser.Block, _ int) {
+		if block.Unclosed {
+			return
 		}
+		name, file, line := a.resolveBlock(block)
 
 		result = append(result, &BlockInfo{
-			Name:       name,
-			File:       file,
-			Line:       line,
-			Duration:   block.Duration(),
-			CallCount:  1,
-			ThreadID:   threadID,
-			ThreadName: threadName,
+			Name:         name,
+			File:         file,
+			Line:         line,
+			Duration:     block.Duration(),
+			SelfDuration: a.selfTime(block),
+			CallCount:    1,
+			ThreadID:     threadID,
+			ThreadName:   threadName,
+			Depth:        int(block.Depth),
+			Begin:        block.Begin,
+			End:          block.End,
+			DescriptorID: block.ID,
+			Ordinal:      block.Ordinal,
 		})
-
-		// Recursively process children
-		result = append(result, a.analyzeBlocksRecursive(block.Children, threadID, threadName)...)
-	}
+	})
 
 	return result
 }
@@ -113,7 +187,8 @@ func (a *Analyzer) GetThreadStatistics() []*ThreadStats {
 	totalDuration := a.profile.GetTotalDuration()
 	var stats []*ThreadStats
 
-	for threadID, thread := range a.profile.Threads {
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
 		threadDuration := a.calculateThreadDuration(thread.Blocks)
 		blockCount := a.countBlocks(thread.Blocks)
 
@@ -135,13 +210,14 @@ func (a *Analyzer) GetThreadStatistics() []*ThreadStats {
 			ContextSwitches:  len(thread.ContextSwitches),
 			AvgBlockDuration: avgBlockDuration,
 			PercentOfTotal:   percentOfTotal,
+			StartOffset:      a.CaptureOffset(thread.FirstBlockBegin),
+			EndOffset:        a.CaptureOffset(thread.LastBlockEnd),
+			WallSpan:         time.Duration(thread.LastBlockEnd - thread.FirstBlockBegin),
+			SwitchedOut:      a.switchedOutTime(thread),
 		})
 	}
 
-	// Sort by total duration
-	sort.Slice(stats, func(i, j int) bool {
-		return stats[i].TotalDuration > stats[j].TotalDuration
-	})
+	SortThreadStats(stats, ThreadsByDuration)
 
 	return stats
 }
@@ -155,20 +231,93 @@ func (a *Analyzer) calculateThreadDuration(blocks []*parser.Block) time.Duration
 }
 
 func (a *Analyzer) countBlocks(blocks []*parser.Block) int {
-	count := len(blocks)
-	for _, block := range blocks {
-		count += a.countBlocks(block.Children)
-	}
+	count := 0
+	walkBlocks(blocks, func(*parser.Block, int) {
+		count++
+	})
 	return count
 }
 
-// GetHotspots returns functions with the highest cumulative time
+// GetHotspots returns functions with the highest cumulative inclusive time
 func (a *Analyzer) GetHotspots(limit int) []*BlockInfo {
-	// Group blocks by name and aggregate
+	return a.GetHotspotsWithMode(limit, Inclusive)
+}
+
+// GetSelfTimeHotspots returns functions with the highest cumulative self time,
+// i.e. time not attributed to any nested child block
+func (a *Analyzer) GetSelfTimeHotspots(limit int) []*BlockInfo {
+	return a.GetHotspotsWithMode(limit, Exclusive)
+}
+
+// GetHotspotsWithMode returns functions with the highest cumulative time measured in mode
+func (a *Analyzer) GetHotspotsWithMode(limit int, mode TimeMode) []*BlockInfo {
+	return a.GetHotspotsExcluding(limit, mode, nil)
+}
+
+// GetMostCalled returns the functions with the most invocations, regardless of duration
+func (a *Analyzer) GetMostCalled(limit int) []*BlockInfo {
+	functions := a.aggregateHotspots()
+
+	sort.Slice(functions, func(i, j int) bool {
+		if functions[i].CallCount != functions[j].CallCount {
+			return functions[i].CallCount > functions[j].CallCount
+		}
+		if functions[i].Duration != functions[j].Duration {
+			return functions[i].Duration > functions[j].Duration
+		}
+		return lessBlockInfo(functions[i], functions[j])
+	})
+
+	if limit > len(functions) {
+		limit = len(functions)
+	}
+
+	return functions[:limit]
+}
+
+// aggregateHotspots groups all blocks by function and returns the unsorted totals
+func (a *Analyzer) aggregateHotspots() []*BlockInfo {
+	return a.aggregateHotspotsBy(ByCallSite)
+}
+
+// aggregateHotspotsBy groups all blocks at the given granularity and returns the
+// unsorted totals
+func (a *Analyzer) aggregateHotspotsBy(granularity Granularity) []*BlockInfo {
+	return a.aggregateHotspotsWithin(newBudget(nil), granularity)
+}
+
+// aggregateHotspotsWithin is aggregateHotspotsBy stopping when the budget runs out,
+// with the totals of the blocks visited until then
+func (a *Analyzer) aggregateHotspotsWithin(b *budget, granularity Granularity) []*BlockInfo {
+	// Group blocks by key and aggregate
 	blockMap := make(map[string]*BlockInfo)
 
-	for threadID, thread := range a.profile.Threads {
-		a.aggregateBlocks(thread.Blocks, threadID, thread.ThreadName, blockMap)
+	// Threads are aggregated one at a time so every entry learns which threads'
+	// busy time it shares
+	for _, threadID := range a.sortedThreadIDs() {
+		if b.done() {
+			break
+		}
+		thread := a.profile.Thread(threadID)
+		threadMap := make(map[string]*BlockInfo)
+		a.aggregateBlocksWithin(b, thread.Blocks, threadID, thread.ThreadName, granularity, threadMap)
+
+		busy := a.calculateThreadDuration(thread.Blocks)
+		for key, info := range threadMap {
+			existing, ok := blockMap[key]
+			if !ok {
+				info.ThreadTime = busy
+				blockMap[key] = info
+				continue
+			}
+			existing.Duration += info.Duration
+			existing.SelfDuration += info.SelfDuration
+			existing.CallCount += info.CallCount
+			existing.ThreadTime += busy
+		}
+	}
+	if granularity == ByCallSite {
+		mergeNameOnlyEntries(blockMap)
 	}
 
 	// Convert map to slice
@@ -180,79 +329,306 @@ func (a *Analyzer) GetHotspots(limit int) []*BlockInfo {
 		hotspots = append(hotspots, info)
 	}
 
-	// Sort by total duration
-	sort.Slice(hotspots, func(i, j int) bool {
-		return hotspots[i].Duration > hotspots[j].Duration
-	})
-
-	if limit > len(hotspots) {
-		limit = len(hotspots)
-	}
+	return hotspots
+}
 
-	return hotspots[:limit]
+// aggregateBlocks adds blocks and their descendants to blockMap, grouped at granularity.
+// Keys on the current ancestor chain are tracked so that recursive calls don't add
+// their inclusive time a second time on top of the outermost call's.
+func (a *Analyzer) aggregateBlocks(blocks []*parser.Block, threadID uint64, threadName string, granularity Granularity, blockMap map[string]*BlockInfo) {
+	a.aggregateBlocksWithin(newBudget(nil), blocks, threadID, threadName, granularity, blockMap)
 }
 
-func (a *Analyzer) aggregateBlocks(blocks []*parser.Block, threadID uint64, threadName string, blockMap map[string]*BlockInfo) {
-	for _, block := range blocks {
-		descriptor := a.profile.Descriptors[block.ID]
+// aggregateBlocksWithin is aggregateBlocks stopping when the budget runs out
+func (a *Analyzer) aggregateBlocksWithin(b *budget, blocks []*parser.Block, threadID uint64, threadName string, granularity Granularity, blockMap map[string]*BlockInfo) {
+	// Aggregation keys of the current block's ancestors; "" for anonymous blocks
+	// that were folded into an ancestor or excluded
+	var path []string
+	active := make(map[string]int)
+
+	walkBlocksWithin(b, blocks, func(block *parser.Block, depth int) {
+		for len(path) > depth {
+			active[path[len(path)-1]]--
+			path = path[:len(path)-1]
+		}
 
-		name := block.Name
-		if name == "" && descriptor != nil {
-			name = descriptor.Name
+		if a.isAnonymous(block) && a.anonymousPolicy != AnonymousByDescriptor {
+			if folded := a.foldAnonymous(block, path, blockMap); folded {
+				path = append(path, "")
+				return
+			}
 		}
 
-		key := name
-		if descriptor != nil {
-			key = fmt.Sprintf("%s:%s:%d", name, descriptor.File, descriptor.Line)
+		name, file, line := a.resolveBlock(block)
+		key, name, line := a.group(granularity, block, name, file, line)
+
+		inclusive := block.Duration()
+		if active[key] > 0 {
+			inclusive = 0
 		}
 
 		if existing, ok := blockMap[key]; ok {
-			existing.Duration += block.Duration()
+			existing.Duration += inclusive
+			existing.SelfDuration += a.selfTime(block)
 			existing.CallCount++
 		} else {
-			file := ""
-			line := int32(0)
-			if descriptor != nil {
-				file = descriptor.File
-				line = descriptor.Line
-			}
-
 			blockMap[key] = &BlockInfo{
-				Name:       name,
-				File:       file,
-				Line:       line,
-				Duration:   block.Duration(),
-				CallCount:  1,
-				ThreadID:   threadID,
-				ThreadName: threadName,
+				Name:         name,
+				File:         file,
+				Line:         line,
+				Duration:     inclusive,
+				SelfDuration: a.selfTime(block),
+				CallCount:    1,
+				ThreadID:     threadID,
+				ThreadName:   threadName,
 			}
 		}
 
-		// Recursively process children
-		a.aggregateBlocks(block.Children, threadID, threadName, blockMap)
+		path = append(path, key)
+		active[key]++
+	})
+}
+
+// foldAnonymous applies the anonymous block policy to an anonymous block whose
+// ancestors' aggregation keys are path. It returns false if the block should be
+// aggregated under its own descriptor instead.
+func (a *Analyzer) foldAnonymous(block *parser.Block, path []string, blockMap map[string]*BlockInfo) bool {
+	if a.anonymousPolicy == AnonymousExclude {
+		return true
+	}
+
+	// The nearest named ancestor's inclusive time already covers the block,
+	// so only its self time moves over
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] != "" {
+			blockMap[path[i]].SelfDuration += a.selfTime(block)
+			return true
+		}
+	}
+	return false
+}
+
+// mergeNameOnlyEntries folds entries keyed on a bare name (blocks without a descriptor)
+// into the descriptor-backed entry with the same name, when exactly one such entry
+// exists. With several candidates the call site is ambiguous and the entry is kept.
+func mergeNameOnlyEntries(blockMap map[string]*BlockInfo) {
+	candidates := make(map[string][]string)
+	for key, info := range blockMap {
+		if key != info.Name {
+			candidates[info.Name] = append(candidates[info.Name], key)
+		}
+	}
+
+	for key, info := range blockMap {
+		if key != info.Name || len(candidates[key]) != 1 {
+			continue
+		}
+
+		target := blockMap[candidates[key][0]]
+		target.Duration += info.Duration
+		target.SelfDuration += info.SelfDuration
+		target.CallCount += info.CallCount
+		// A thread both entries ran on is counted twice, which errs toward a
+		// lower percentage of thread time rather than one above 100%
+		target.ThreadTime += info.ThreadTime
+		delete(blockMap, key)
 	}
 }
 
-// AnalyzePerformanceIssues detects common performance problems
+// walkBlocks visits blocks and their descendants in pre-order without recursion.
+// Parsing already rejects trees deeper than parser.DefaultMaxTreeDepth, so the
+// depth limit only cuts off malformed structures built elsewhere.
+func walkBlocks(blocks []*parser.Block, visit func(block *parser.Block, depth int)) {
+	_ = parser.WalkBlocks(blocks, parser.DefaultMaxTreeDepth, visit)
+}
+
+// Sentinels reported in place of names and locations missing from the capture,
+// e.g. when a block references a descriptor that wasn't recorded. Results never
+// carry blank names or files.
+const (
+	UnknownFileLabel     = "(unknown file)"
+	UnnamedBlockLabel    = "(unnamed block)"
+	UnknownLocationLabel = "(unknown location)"
+)
+
+// resolveBlock returns the display name and source location of a block,
+// preferring the runtime name and falling back to its descriptor.
+// Anonymous blocks are named UnnamedBlockLabel followed by their descriptor ID,
+// so that unrelated ones don't share a name. A missing file is reported as
+// UnknownFileLabel.
+func (a *Analyzer) resolveBlock(block *parser.Block) (name, file string, line int32) {
+	descriptor := a.profile.Descriptor(block.ID)
+
+	name = block.Name
+	if name == "" && descriptor != nil {
+		name = descriptor.Name
+	}
+	if name == "" {
+		name = unnamedLabel(block.ID)
+	}
+
+	file = UnknownFileLabel
+	if descriptor != nil {
+		if descriptor.File != "" {
+			file = descriptor.File
+		}
+		line = descriptor.Line
+	}
+
+	return name, file, line
+}
+
+// formatLocation renders file:line, or UnknownLocationLabel if the file is unknown
+func formatLocation(file string, line int32) string {
+	if file == "" || file == UnknownFileLabel {
+		return UnknownLocationLabel
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// aggregationKey returns the key used to group invocations of the same function.
+// Blocks without a descriptor are keyed on their name alone; see mergeNameOnlyEntries.
+func (a *Analyzer) aggregationKey(block *parser.Block, name string) string {
+	if descriptor := a.profile.Descriptor(block.ID); descriptor != nil {
+		return fmt.Sprintf("%s:%s:%d", name, descriptor.File, descriptor.Line)
+	}
+	return name
+}
+
+// CaptureOffset converts a raw timestamp to an offset from the capture begin,
+// clamping timestamps that precede it to 0
+func (a *Analyzer) CaptureOffset(timestamp uint64) time.Duration {
+	if timestamp <= a.profile.CaptureBegin() {
+		return 0
+	}
+	return time.Duration(timestamp - a.profile.CaptureBegin())
+}
+
+// AnalyzePerformanceIssues detects common performance problems using the default severity cutoffs
 func (a *Analyzer) AnalyzePerformanceIssues() []*PerformanceIssue {
+	return a.AnalyzePerformanceIssuesWithCutoffs(DefaultSeverityCutoffs())
+}
+
+// AnalyzePerformanceIssuesWithCutoffs detects common performance problems and rates
+// each issue's score against the given cutoffs
+func (a *Analyzer) AnalyzePerformanceIssuesWithCutoffs(cutoffs SeverityCutoffs) []*PerformanceIssue {
+	options := DefaultIssueOptions()
+	options.Cutoffs = cutoffs
+	return a.AnalyzePerformanceIssuesWithOptions(options)
+}
+
+// IssueOptions tunes issue detection
+type IssueOptions struct {
+	Cutoffs SeverityCutoffs
+
+	// FragmentedMinCalls and FragmentedMaxAvg flag functions called at least this
+	// often whose average call is no longer than this
+	FragmentedMinCalls int
+	FragmentedMaxAvg   time.Duration
+
+	// IOPatterns are the name fragments (case-insensitive) of blocks checked for
+	// blocking I/O; blocks shorter than BlockingIOMinDuration are ignored
+	IOPatterns            []string
+	BlockingIOMinDuration time.Duration
+
+	// MaxRecursionDepth flags functions recursing deeper than this (0 disables
+	// Unbalanced Recursion); RecursionDepthRatio also flags functions whose deepest
+	// recursion is this many times their median depth
+	MaxRecursionDepth   int
+	RecursionDepthRatio float64
+
+	// PercentBase is what Hot Function percentages are relative to. Scores stay
+	// relative to the capture so they remain comparable across issue types.
+	PercentBase PercentBase
+
+	// Progress, if set, is called after each detector finishes
+	Progress ProgressFunc
+}
+
+// DefaultIssueOptions returns the default detection settings
+func DefaultIssueOptions() IssueOptions {
+	return IssueOptions{
+		Cutoffs:            DefaultSeverityCutoffs(),
+		FragmentedMinCalls: 10000,
+		FragmentedMaxAvg:   10 * time.Microsecond,
+
+		IOPatterns:            DefaultIOPatterns,
+		BlockingIOMinDuration: 10 * time.Millisecond,
+
+		MaxRecursionDepth:   16,
+		RecursionDepthRatio: 4,
+	}
+}
+
+// AnalyzePerformanceIssuesWithOptions detects common performance problems using the
+// given detection settings and rates each issue's score against its cutoffs
+func (a *Analyzer) AnalyzePerformanceIssuesWithOptions(options IssueOptions) []*PerformanceIssue {
+	cutoffs := options.Cutoffs
 	var issues []*PerformanceIssue
 
-	// Detect long blocking operations (>100ms)
-	issues = append(issues, a.detectLongBlocks()...)
+	detectors := []func() []*PerformanceIssue{
+		// Detect long blocking operations (>100ms)
+		a.detectLongBlocks,
+
+		// Detect thread imbalance
+		a.detectThreadImbalance,
 
-	// Detect thread imbalance
-	issues = append(issues, a.detectThreadImbalance()...)
+		// Detect excessive context switches
+		a.detectExcessiveContextSwitches,
 
-	// Detect excessive context switches
-	issues = append(issues, a.detectExcessiveContextSwitches()...)
+		// Detect hot functions (>10% of total time, or of their threads' time)
+		func() []*PerformanceIssue {
+			return a.detectHotFunctions(options.PercentBase)
+		},
 
-	// Detect hot functions (>10% of total time)
-	issues = append(issues, a.detectHotFunctions()...)
+		// Detect fast paths that occasionally do heavy work in a child
+		a.detectIntermittentSlowChildren,
 
-	// Sort by severity
+		// Detect thread churn
+		a.detectShortLivedThreads,
+
+		// Detect work split into huge numbers of tiny calls
+		func() []*PerformanceIssue {
+			return a.detectFragmentedWork(options.FragmentedMinCalls, options.FragmentedMaxAvg)
+		},
+
+		// Detect I/O-looking blocks that spent most of their time switched out
+		func() []*PerformanceIssue {
+			return a.detectBlockingIO(options.IOPatterns, options.BlockingIOMinDuration)
+		},
+
+		// Detect recursion that gets very deep, or much deeper in some calls than others
+		func() []*PerformanceIssue {
+			return a.detectUnbalancedRecursion(options.MaxRecursionDepth, options.RecursionDepthRatio)
+		},
+	}
+
+	for i, detect := range detectors {
+		issues = append(issues, detect()...)
+		options.Progress.report(i+1, len(detectors))
+	}
+
+	for _, issue := range issues {
+		issue.Severity = cutoffs.Severity(issue.Score)
+		issue.Suggestion = issueSuggestions[issue.Type]
+	}
+
+	// Sort by severity, then by score within a severity. Ties are broken by type and
+	// location so an issue keeps its position between runs.
 	sort.Slice(issues, func(i, j int) bool {
-		severityOrder := map[string]int{"high": 0, "medium": 1, "low": 2}
-		return severityOrder[issues[i].Severity] < severityOrder[issues[j].Severity]
+		if issues[i].Severity != issues[j].Severity {
+			return severityRanks[issues[i].Severity] < severityRanks[issues[j].Severity]
+		}
+		if issues[i].Score != issues[j].Score {
+			return issues[i].Score > issues[j].Score
+		}
+		if issues[i].Type != issues[j].Type {
+			return issues[i].Type < issues[j].Type
+		}
+		if issues[i].Location != issues[j].Location {
+			return issues[i].Location < issues[j].Location
+		}
+		return issues[i].ThreadID < issues[j].ThreadID
 	})
 
 	return issues
@@ -262,30 +638,19 @@ func (a *Analyzer) detectLongBlocks() []*PerformanceIssue {
 	var issues []*PerformanceIssue
 	threshold := 100 * time.Millisecond
 
-	for threadID, thread := range a.profile.Threads {
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
 		blocks := a.findLongBlocks(thread.Blocks, threshold)
 		for _, block := range blocks {
-			descriptor := a.profile.Descriptors[block.ID]
-			name := block.Name
-			location := "unknown"
-
-			if descriptor != nil {
-				if name == "" {
-					name = descriptor.Name
-				}
-				location = fmt.Sprintf("%s:%d", descriptor.File, descriptor.Line)
-			}
-
-			severity := "medium"
-			if block.Duration() > 500*time.Millisecond {
-				severity = "high"
-			}
+			name, file, line := a.resolveBlock(block)
+			location := formatLocation(file, line)
 
 			issues = append(issues, &PerformanceIssue{
 				Type:        "Long Blocking Operation",
-				Severity:    severity,
+				Score:       impactScore(a.captureFraction(float64(block.Duration()))),
 				Description: fmt.Sprintf("Block '%s' took %v", name, block.Duration()),
 				Location:    location,
+				Function:    name,
 				Duration:    block.Duration(),
 				ThreadID:    threadID,
 				ThreadName:  thread.ThreadName,
@@ -299,12 +664,11 @@ func (a *Analyzer) detectLongBlocks() []*PerformanceIssue {
 func (a *Analyzer) findLongBlocks(blocks []*parser.Block, threshold time.Duration) []*parser.Block {
 	var result []*parser.Block
 
-	for _, block := range blocks {
+	walkBlocks(blocks, func(block *parser.Block, _ int) {
 		if block.Duration() > threshold {
 			result = append(result, block)
 		}
-		result = append(result, a.findLongBlocks(block.Children, threshold)...)
-	}
+	})
 
 	return result
 }
@@ -325,7 +689,7 @@ func (a *Analyzer) detectThreadImbalance() []*PerformanceIssue {
 	if minDuration > 0 && float64(maxDuration)/float64(minDuration) > 2.0 {
 		issues = append(issues, &PerformanceIssue{
 			Type:        "Thread Imbalance",
-			Severity:    "medium",
+			Score:       impactScore(a.captureFraction(float64(maxDuration - minDuration))),
 			Description: fmt.Sprintf("Thread workload imbalance detected: max=%v, min=%v (ratio=%.2fx)",
 				maxDuration, minDuration, float64(maxDuration)/float64(minDuration)),
 			Location:    "across all threads",
@@ -340,11 +704,18 @@ func (a *Analyzer) detectExcessiveContextSwitches() []*PerformanceIssue {
 	var issues []*PerformanceIssue
 	threshold := 1000
 
-	for threadID, thread := range a.profile.Threads {
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
 		if len(thread.ContextSwitches) > threshold {
+			// Impact is the time the thread spent switched out
+			switchedOut := time.Duration(0)
+			for _, cs := range thread.ContextSwitches {
+				switchedOut += cs.Duration()
+			}
+
 			issues = append(issues, &PerformanceIssue{
 				Type:        "Excessive Context Switches",
-				Severity:    "medium",
+				Score:       impactScore(a.captureFraction(float64(switchedOut))),
 				Description: fmt.Sprintf("Thread has %d context switches (threshold: %d)",
 					len(thread.ContextSwitches), threshold),
 				Location:    thread.ThreadName,
@@ -357,33 +728,45 @@ func (a *Analyzer) detectExcessiveContextSwitches() []*PerformanceIssue {
 	return issues
 }
 
-func (a *Analyzer) detectHotFunctions() []*PerformanceIssue {
+func (a *Analyzer) detectHotFunctions(base PercentBase) []*PerformanceIssue {
 	var issues []*PerformanceIssue
 	totalDuration := a.profile.GetTotalDuration()
 	threshold := 0.10 // 10%
 
+	if totalDuration <= 0 {
+		return issues
+	}
+
+	// Against thread time, the top functions are ranked by that share instead: a
+	// function owning a quiet thread ranks low globally
 	hotspots := a.GetHotspots(10)
-	for _, hotspot := range hotspots {
-		percent := float64(hotspot.Duration) / float64(totalDuration)
-		if percent > threshold {
-			severity := "low"
-			if percent > 0.3 {
-				severity = "high"
-			} else if percent > 0.2 {
-				severity = "medium"
+	if base == PercentOfThread {
+		hotspots = a.aggregateHotspots()
+		sort.Slice(hotspots, func(i, j int) bool {
+			pi, pj := a.Percent(hotspots[i].Duration, hotspots[i], base), a.Percent(hotspots[j].Duration, hotspots[j], base)
+			if pi != pj {
+				return pi > pj
 			}
+			return lessBlockInfo(hotspots[i], hotspots[j])
+		})
+		hotspots = hotspots[:min(10, len(hotspots))]
+	}
 
+	for _, hotspot := range hotspots {
+		percent := a.Percent(hotspot.Duration, hotspot, base) / 100
+		if percent > threshold {
 			location := hotspot.Name
-			if hotspot.File != "" {
-				location = fmt.Sprintf("%s (%s:%d)", hotspot.Name, hotspot.File, hotspot.Line)
+			if hotspot.File != UnknownFileLabel {
+				location = fmt.Sprintf("%s (%s)", hotspot.Name, formatLocation(hotspot.File, hotspot.Line))
 			}
 
 			issues = append(issues, &PerformanceIssue{
 				Type:        "Hot Function",
-				Severity:    severity,
-				Description: fmt.Sprintf("Function '%s' consumes %.1f%% of total time (%v total, %d calls, avg %v)",
-					hotspot.Name, percent*100, hotspot.Duration, hotspot.CallCount, hotspot.AvgDuration),
+				Score:       impactScore(a.captureFraction(float64(hotspot.Duration))),
+				Description: fmt.Sprintf("Function '%s' consumes %.1f%% of %s time (%v total, %d calls, avg %v)",
+					hotspot.Name, percent*100, percentBaseLabel(base), hotspot.Duration, hotspot.CallCount, hotspot.AvgDuration),
 				Location:    location,
+				Function:    hotspot.Name,
 				Duration:    hotspot.Duration,
 			})
 		}
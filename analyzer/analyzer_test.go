@@ -0,0 +1,444 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// newTestAnalyzer parses the capture with the default options and analyzes it
+func newTestAnalyzer(t *testing.T, p *proftest.Profile) *Analyzer {
+	t.Helper()
+	return NewAnalyzer(p.Load(t))
+}
+
+// blockNames returns the names of the given block infos, in order
+func blockNames(infos []*BlockInfo) []string {
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name
+	}
+	return names
+}
+
+func TestResolveBlock(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: []proftest.Descriptor{
+			{ID: 1, Name: "Update", File: "update.cpp", Line: 12},
+			{ID: 2, Name: "", File: "", Line: 40},
+		},
+		Threads: []proftest.Thread{{ID: 1, Name: "Main"}},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name     string
+		block    *parser.Block
+		want     string
+		file     string
+		location string
+	}{
+		{"descriptor", &parser.Block{ID: 1}, "Update", "update.cpp", "update.cpp:12"},
+		{"runtime name", &parser.Block{ID: 1, Name: "Update(player)"}, "Update(player)", "update.cpp", "update.cpp:12"},
+		{"empty descriptor", &parser.Block{ID: 2}, "(unnamed block #2)", UnknownFileLabel, UnknownLocationLabel},
+		{"missing descriptor", &parser.Block{ID: 9}, "(unnamed block #9)", UnknownFileLabel, UnknownLocationLabel},
+		{"missing descriptor with runtime name", &parser.Block{ID: 9, Name: "Tick"}, "Tick", UnknownFileLabel, UnknownLocationLabel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, file, line := a.resolveBlock(tt.block)
+			if name != tt.want || file != tt.file {
+				t.Errorf("resolveBlock = %q, %q; want %q, %q", name, file, tt.want, tt.file)
+			}
+			if location := formatLocation(file, line); location != tt.location {
+				t.Errorf("formatLocation = %q, want %q", location, tt.location)
+			}
+		})
+	}
+}
+
+func TestZeroDurationProfile(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 1000, End: 1000},
+			{ID: 1, Begin: 1000, End: 1000},
+		}}},
+	}
+	a := newTestAnalyzer(t, p)
+
+	if total := a.profile.GetTotalDuration(); total != 0 {
+		t.Fatalf("total duration = %v, want 0", total)
+	}
+	if issues := a.detectHotFunctions(PercentOfCapture); len(issues) != 0 {
+		t.Errorf("detectHotFunctions reported %d issues on a zero-duration profile", len(issues))
+	}
+	hotspots := a.GetHotspots(10)
+	if len(hotspots) != 1 {
+		t.Fatalf("GetHotspots returned %d entries, want 1", len(hotspots))
+	}
+	if percent := a.Percent(hotspots[0].Duration, hotspots[0], PercentOfCapture); percent != 0 {
+		t.Errorf("Percent = %v, want 0", percent)
+	}
+}
+
+func TestGetSlowestBlocks(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Update", "Render"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 50},
+			{ID: 1, Begin: 100, End: 140},
+			{ID: 2, Begin: 200, End: 230},
+			{ID: 1, Begin: 300, End: 320},
+			{ID: 2, Begin: 400, End: 410},
+		}}},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name      string
+		distinct  bool
+		limit     int
+		want      []string
+		instances []int
+	}{
+		{"all", false, 10, []string{"Update", "Update", "Render", "Update", "Render"}, nil},
+		{"limited", false, 2, []string{"Update", "Update"}, nil},
+		{"zero limit", false, 0, []string{}, nil},
+		{"distinct", true, 10, []string{"Update", "Render"}, []int{3, 2}},
+		{"distinct limited", true, 1, []string{"Update"}, []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var blocks []*BlockInfo
+			if tt.distinct {
+				blocks = a.GetDistinctSlowestBlocks(tt.limit)
+			} else {
+				blocks = a.GetSlowestBlocks(tt.limit)
+			}
+			if got := blockNames(blocks); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("blocks = %v, want %v", got, tt.want)
+			}
+			for i, want := range tt.instances {
+				if blocks[i].CallCount != want {
+					t.Errorf("%s has %d instances, want %d", blocks[i].Name, blocks[i].CallCount, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetThreadStatisticsActivitySpan(t *testing.T) {
+	p := &proftest.Profile{
+		Begin:       1000,
+		End:         11000,
+		Descriptors: proftest.Descriptors("Work"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 1000, End: 3000}, {ID: 1, Begin: 6000, End: 9000}}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 1, Begin: 4000, End: 5000}}},
+		},
+	}
+
+	tests := []struct {
+		thread string
+		start  time.Duration
+		end    time.Duration
+		span   time.Duration
+	}{
+		{"Main", 0, 8000, 8000},
+		{"Worker", 3000, 4000, 1000},
+	}
+
+	stats := newTestAnalyzer(t, p).GetThreadStatistics()
+	byName := make(map[string]*ThreadStats)
+	for _, stat := range stats {
+		byName[stat.ThreadName] = stat
+	}
+	for _, tt := range tests {
+		t.Run(tt.thread, func(t *testing.T) {
+			stat := byName[tt.thread]
+			if stat.StartOffset != tt.start || stat.EndOffset != tt.end || stat.WallSpan != tt.span {
+				t.Errorf("start %v, end %v, span %v; want %v, %v, %v",
+					stat.StartOffset, stat.EndOffset, stat.WallSpan, tt.start, tt.end, tt.span)
+			}
+		})
+	}
+}
+
+func TestGetThreadStatisticsWallSpanGap(t *testing.T) {
+	// Sparse works 20ns across a 1µs span and is switched out for most of the rest;
+	// Dense is busy for its whole span
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Work"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Sparse",
+				ContextSwitches: []proftest.ContextSwitch{{ThreadID: 1, Begin: 10, End: 990}},
+				Blocks:          []proftest.Block{{ID: 1, Begin: 0, End: 10}, {ID: 1, Begin: 990, End: 1000}},
+			},
+			{ID: 2, Name: "Dense", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 500}, {ID: 1, Begin: 500, End: 1000}}},
+		},
+	}
+
+	tests := []struct {
+		thread      string
+		busy        time.Duration
+		span        time.Duration
+		switchedOut time.Duration
+	}{
+		{"Sparse", 20, 1000, 980},
+		{"Dense", 1000, 1000, 0},
+	}
+
+	byName := make(map[string]*ThreadStats)
+	for _, stat := range newTestAnalyzer(t, p).GetThreadStatistics() {
+		byName[stat.ThreadName] = stat
+	}
+	for _, tt := range tests {
+		t.Run(tt.thread, func(t *testing.T) {
+			stat := byName[tt.thread]
+			if stat.TotalDuration != tt.busy || stat.WallSpan != tt.span || stat.SwitchedOut != tt.switchedOut {
+				t.Errorf("busy %v of %v, switched out %v; want %v of %v, %v",
+					stat.TotalDuration, stat.WallSpan, stat.SwitchedOut, tt.busy, tt.span, tt.switchedOut)
+			}
+		})
+	}
+}
+
+func TestTraversalsTerminateOnCycles(t *testing.T) {
+	// A 200ms block listed as its own child, as a tree-building bug could leave it.
+	// Traversals stop at parser.DefaultMaxTreeDepth instead of recursing forever.
+	loop := &parser.Block{ID: 1, Begin: 0, End: uint64(200 * time.Millisecond)}
+	loop.Children = []*parser.Block{loop}
+	a := NewAnalyzer(&parser.ProfileData{
+		Descriptors: map[uint32]*parser.BlockDescriptor{1: {ID: 1, Name: "Loop", Type: parser.BlockTypeBlock}},
+		Threads:     map[uint64]*parser.ThreadData{1: {ThreadID: 1, ThreadName: "Main", Blocks: []*parser.Block{loop}}},
+	})
+	visits := parser.DefaultMaxTreeDepth + 1
+
+	tests := []struct {
+		name string
+		run  func() int
+	}{
+		{"long blocks", func() int { return len(a.findLongBlocks([]*parser.Block{loop}, time.Millisecond)) }},
+		{"window aggregation", func() int {
+			blockMap := make(map[string]*BlockInfo)
+			a.aggregateWindow([]*parser.Block{loop}, 0, loop.End, 1, "Main", blockMap)
+			for _, info := range blockMap {
+				return info.CallCount
+			}
+			return 0
+		}},
+		{"child times", func() int {
+			statsMap := make(map[string]*childTimeStats)
+			a.collectChildTimes([]*parser.Block{loop}, 1, "Main", statsMap)
+			for _, stats := range statsMap {
+				return len(stats.childTimes)
+			}
+			return 0
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.run(); got != visits {
+				t.Errorf("visited %d blocks, want %d", got, visits)
+			}
+		})
+	}
+}
+
+func TestAnalysesHandleDeepestAcceptedTree(t *testing.T) {
+	levels := parser.DefaultMaxTreeDepth + 1
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Recurse"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: proftest.Nested(1, 0, levels)}},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name string
+		run  func() int
+		want int
+	}{
+		{"slowest blocks", func() int { return len(a.GetSlowestBlocksWithMode(levels+1, Exclusive)) }, levels},
+		{"hotspots", func() int { return a.GetHotspots(10)[0].CallCount }, levels},
+		{"thread statistics", func() int { return a.GetThreadStatistics()[0].BlockCount }, levels},
+		// Each level lasts 2ns longer than the one it encloses
+		{"exclusive time", func() int { return int(a.GetHotspotsWithMode(1, Exclusive)[0].SelfDuration) }, 2 * levels},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.run(); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeNameOnlyEntries(t *testing.T) {
+	entry := func(name string, calls int) *BlockInfo {
+		return &BlockInfo{Name: name, Duration: time.Duration(calls) * time.Millisecond, SelfDuration: time.Duration(calls) * time.Microsecond, CallCount: calls}
+	}
+
+	tests := []struct {
+		name  string
+		input map[string]*BlockInfo
+		want  map[string]int // Call counts by key after merging
+	}{
+		{
+			name:  "single call site",
+			input: map[string]*BlockInfo{"Load:io.cpp:10": entry("Load", 2), "Load": entry("Load", 3)},
+			want:  map[string]int{"Load:io.cpp:10": 5},
+		},
+		{
+			name:  "ambiguous call site",
+			input: map[string]*BlockInfo{"Load:io.cpp:10": entry("Load", 2), "Load:net.cpp:20": entry("Load", 1), "Load": entry("Load", 3)},
+			want:  map[string]int{"Load:io.cpp:10": 2, "Load:net.cpp:20": 1, "Load": 3},
+		},
+		{
+			name:  "no call site",
+			input: map[string]*BlockInfo{"Load": entry("Load", 3), "Save:io.cpp:30": entry("Save", 1)},
+			want:  map[string]int{"Load": 3, "Save:io.cpp:30": 1},
+		},
+		{
+			name:  "empty",
+			input: map[string]*BlockInfo{},
+			want:  map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mergeNameOnlyEntries(tt.input)
+			got := make(map[string]int)
+			for key, info := range tt.input {
+				got[key] = info.CallCount
+				if want := time.Duration(info.CallCount) * time.Millisecond; info.Duration != want {
+					t.Errorf("%s duration = %v, want %v", key, info.Duration, want)
+				}
+				if want := time.Duration(info.CallCount) * time.Microsecond; info.SelfDuration != want {
+					t.Errorf("%s self duration = %v, want %v", key, info.SelfDuration, want)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("call counts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHotspotsMergeDescriptorlessBlocks(t *testing.T) {
+	// Block 9 has no descriptor and carries the name of descriptor 1
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Load"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 100},
+			{ID: 9, Begin: 200, End: 250, Name: "Load"},
+		}}},
+	}
+	hotspots := newTestAnalyzer(t, p).GetHotspots(10)
+	if len(hotspots) != 1 {
+		t.Fatalf("got hotspots %v, want only Load", blockNames(hotspots))
+	}
+	if got := hotspots[0]; got.CallCount != 2 || got.Duration != 150 || got.AvgDuration != 75 || got.File != "Load.cpp" {
+		t.Errorf("Load hotspot = %+v, want 2 calls for 150ns in Load.cpp", got)
+	}
+}
+
+func TestGetMostCalled(t *testing.T) {
+	// A runs 3 times briefly, B once for long, C twice for 20ns and D twice for 10ns
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("A", "B", "C", "D"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 1}, {ID: 1, Begin: 2, End: 3}, {ID: 1, Begin: 4, End: 5},
+			{ID: 2, Begin: 10, End: 1000},
+			{ID: 3, Begin: 1000, End: 1010}, {ID: 3, Begin: 1010, End: 1020},
+			{ID: 4, Begin: 1020, End: 1025}, {ID: 4, Begin: 1025, End: 1030},
+		}}},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		limit int
+		want  []string
+	}{
+		{0, []string{}},
+		{2, []string{"A", "C"}},
+		{4, []string{"A", "C", "D", "B"}},
+		{10, []string{"A", "C", "D", "B"}},
+	}
+
+	for _, tt := range tests {
+		if got := blockNames(a.GetMostCalled(tt.limit)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetMostCalled(%d) = %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestGetSlowestBlocksDepth(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Physics"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 3, Begin: 20, End: 40},
+			{ID: 2, Begin: 10, End: 60},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name  string
+		depth int
+	}{
+		{"Frame", 0},
+		{"Update", 1},
+		{"Physics", 2},
+	}
+
+	blocks := a.GetSlowestBlocks(len(tests))
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if blocks[i].Name != tt.name || blocks[i].Depth != tt.depth {
+				t.Errorf("block %d = %s at depth %d, want %s at depth %d", i, blocks[i].Name, blocks[i].Depth, tt.name, tt.depth)
+			}
+		})
+	}
+}
+
+func TestSlowestBlocksSkipUnclosed(t *testing.T) {
+	// Crash never ended; closed at the capture end it would be the slowest block
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         1000,
+		Descriptors: proftest.Descriptors("Frame", "Update", "Crash"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10, End: 60},
+			{ID: 1, Begin: 0, End: 100},
+			{ID: 3, Begin: 200, End: 0},
+		}}},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name string
+		got  []*BlockInfo
+	}{
+		{"inclusive", a.GetSlowestBlocks(10)},
+		{"exclusive", a.GetSlowestBlocksWithMode(10, Exclusive)},
+		{"distinct", a.GetDistinctSlowestBlocks(10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := blockNames(tt.got); !reflect.DeepEqual(got, []string{"Frame", "Update"}) {
+				t.Errorf("slowest blocks = %v, want Frame and Update only", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// AnonymousPolicy controls how function aggregations treat anonymous blocks, i.e.
+// blocks with neither a runtime name nor a descriptor name
+type AnonymousPolicy int
+
+const (
+	// AnonymousByDescriptor keeps anonymous blocks of different descriptors apart,
+	// labeled "(unnamed block #ID)". This is the default.
+	AnonymousByDescriptor AnonymousPolicy = iota
+
+	// AnonymousByParent folds anonymous blocks into their nearest named ancestor,
+	// adding their self time to it. Top-level anonymous blocks fall back to
+	// AnonymousByDescriptor.
+	AnonymousByParent
+
+	// AnonymousExclude leaves anonymous blocks out of aggregated results
+	AnonymousExclude
+)
+
+// String returns the name accepted by ParseAnonymousPolicy
+func (p AnonymousPolicy) String() string {
+	switch p {
+	case AnonymousByParent:
+		return "parent"
+	case AnonymousExclude:
+		return "exclude"
+	default:
+		return "descriptor"
+	}
+}
+
+// ParseAnonymousPolicy parses "descriptor", "parent" or "exclude"
+func ParseAnonymousPolicy(s string) (AnonymousPolicy, error) {
+	for _, policy := range []AnonymousPolicy{AnonymousByDescriptor, AnonymousByParent, AnonymousExclude} {
+		if policy.String() == s {
+			return policy, nil
+		}
+	}
+	return AnonymousByDescriptor, fmt.Errorf("unknown anonymous block policy '%s' (use descriptor, parent or exclude)", s)
+}
+
+// SetAnonymousPolicy sets how anonymous blocks are aggregated
+func (a *Analyzer) SetAnonymousPolicy(policy AnonymousPolicy) {
+	a.anonymousPolicy = policy
+}
+
+// AnonymousPolicy returns how anonymous blocks are aggregated
+func (a *Analyzer) AnonymousPolicy() AnonymousPolicy {
+	return a.anonymousPolicy
+}
+
+// isAnonymous reports whether block has neither a runtime nor a descriptor name
+func (a *Analyzer) isAnonymous(block *parser.Block) bool {
+	if block.Name != "" {
+		return false
+	}
+	descriptor := a.profile.Descriptor(block.ID)
+	return descriptor == nil || descriptor.Name == ""
+}
+
+// unnamedLabel labels an anonymous block by the descriptor it references
+func unnamedLabel(id uint32) string {
+	return fmt.Sprintf("(unnamed block #%d)", id)
+}
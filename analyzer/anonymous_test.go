@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestParseAnonymousPolicy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    AnonymousPolicy
+		wantErr bool
+	}{
+		{"descriptor", AnonymousByDescriptor, false},
+		{"parent", AnonymousByParent, false},
+		{"exclude", AnonymousExclude, false},
+		{"Parent", AnonymousByDescriptor, true},
+		{"", AnonymousByDescriptor, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseAnonymousPolicy(tt.input)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAnonymousPolicy(%q) = %v, %v; want %v, error %t", tt.input, got, err, tt.want, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.input {
+				t.Errorf("String() = %q, want %q", got.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestAnonymousPolicyHotspots(t *testing.T) {
+	// Descriptors 2 and 3 have no name. Frame holds one block of each; another
+	// block of descriptor 2 runs at the top level.
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "", ""),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10, End: 40},
+			{ID: 3, Begin: 50, End: 60},
+			{ID: 1, Begin: 0, End: 100},
+			{ID: 2, Begin: 200, End: 220},
+		}}},
+	}
+
+	tests := []struct {
+		policy AnonymousPolicy
+		want   []string // "name calls total self", sorted
+	}{
+		{AnonymousByDescriptor, []string{
+			fmt.Sprintf("%s 2 50ns 50ns", unnamedLabel(2)),
+			fmt.Sprintf("%s 1 10ns 10ns", unnamedLabel(3)),
+			"Frame 1 100ns 60ns",
+		}},
+		{AnonymousByParent, []string{
+			fmt.Sprintf("%s 1 20ns 20ns", unnamedLabel(2)),
+			"Frame 1 100ns 100ns",
+		}},
+		{AnonymousExclude, []string{
+			"Frame 1 100ns 60ns",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			a := newTestAnalyzer(t, p)
+			a.SetAnonymousPolicy(tt.policy)
+			if a.AnonymousPolicy() != tt.policy {
+				t.Fatalf("AnonymousPolicy() = %v, want %v", a.AnonymousPolicy(), tt.policy)
+			}
+
+			var got []string
+			for _, hotspot := range a.GetHotspots(10) {
+				got = append(got, fmt.Sprintf("%s %d %v %v", hotspot.Name, hotspot.CallCount, hotspot.Duration, hotspot.SelfDuration))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hotspots = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
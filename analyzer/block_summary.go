@@ -0,0 +1,147 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// FunctionSummary combines a function's aggregate statistics with a typical example
+// of where it is called from
+type FunctionSummary struct {
+	Name         string
+	File         string
+	Line         int32
+	CallCount    int
+	Threads      int
+	Duration     time.Duration // Inclusive time, counting only the outermost of recursive calls
+	SelfDuration time.Duration
+	AvgDuration  time.Duration
+	P95Duration  time.Duration // 95th percentile of single-call inclusive time
+	MaxDuration  time.Duration
+
+	// TypicalPath is the most common ancestor chain, outermost first and ending with
+	// the function itself, taken from the first call made along it
+	TypicalPath      []*PathNode
+	TypicalPathCalls int // Calls made along TypicalPath
+	DistinctPaths    int // Number of different ancestor chains the function is called from
+}
+
+// GetFunctionSummary returns the statistics of the function called name together with
+// the ancestor chain most of its calls come from
+func (a *Analyzer) GetFunctionSummary(name string) (*FunctionSummary, error) {
+	invocations, err := a.requireInvocations(name)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &FunctionSummary{Name: name}
+	if info := hotspotsByName(a)[name]; info != nil {
+		summary.File = info.File
+		summary.Line = info.Line
+		summary.Duration = info.Duration
+		summary.SelfDuration = info.SelfDuration
+	}
+
+	threads := make(map[uint64]bool)
+	durations := make([]time.Duration, len(invocations))
+	for i, invocation := range invocations {
+		threads[invocation.ThreadID] = true
+		durations[i] = invocation.Block.Duration()
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	summary.CallCount = len(invocations)
+	summary.Threads = len(threads)
+	summary.AvgDuration = summary.Duration / time.Duration(summary.CallCount)
+	summary.P95Duration = percentile(durations, 95)
+	summary.MaxDuration = durations[len(durations)-1]
+
+	a.findTypicalPath(summary)
+
+	return summary, nil
+}
+
+// findTypicalPath fills in the summary's ancestor chain statistics
+func (a *Analyzer) findTypicalPath(summary *FunctionSummary) {
+	type chain struct {
+		calls   int
+		first   int // Order of the first call along the chain, for ties
+		example []*parser.Block
+	}
+	chains := make(map[string]*chain)
+
+	for _, threadID := range a.sortedThreadIDs() {
+		var stack []*parser.Block
+		var names []string
+
+		walkBlocks(a.profile.Thread(threadID).Blocks, func(block *parser.Block, depth int) {
+			stack, names = stack[:depth], names[:depth]
+			blockName, _, _ := a.resolveBlock(block)
+			stack = append(stack, block)
+			names = append(names, blockName)
+
+			if blockName != summary.Name {
+				return
+			}
+			key := strings.Join(names, "\x00")
+			c, ok := chains[key]
+			if !ok {
+				c = &chain{first: len(chains), example: append([]*parser.Block(nil), stack...)}
+				chains[key] = c
+			}
+			c.calls++
+		})
+	}
+
+	var typical *chain
+	for _, c := range chains {
+		if typical == nil || c.calls > typical.calls || (c.calls == typical.calls && c.first < typical.first) {
+			typical = c
+		}
+	}
+
+	summary.DistinctPaths = len(chains)
+	if typical == nil {
+		return
+	}
+	summary.TypicalPathCalls = typical.calls
+	summary.TypicalPath = a.pathNodes(typical.example)
+}
+
+// pathNodes describes a chain of nested blocks, outermost first
+func (a *Analyzer) pathNodes(blocks []*parser.Block) []*PathNode {
+	nodes := make([]*PathNode, len(blocks))
+	for i, block := range blocks {
+		name, file, line := a.resolveBlock(block)
+
+		percent := 100.0
+		if i > 0 && blocks[i-1].Duration() > 0 {
+			percent = float64(block.Duration()) / float64(blocks[i-1].Duration()) * 100
+		}
+
+		nodes[i] = &PathNode{
+			Name:            name,
+			File:            file,
+			Line:            line,
+			Depth:           i,
+			Duration:        block.Duration(),
+			PercentOfParent: percent,
+			Begin:           block.Begin,
+			End:             block.End,
+		}
+	}
+	return nodes
+}
+
+// percentile returns the p-th percentile (nearest rank) of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
@@ -0,0 +1,124 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestGetFunctionSummary(t *testing.T) {
+	// Update runs twice under Frame, once under Frame > Render, and once on its own on Worker
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Physics", "Render"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 3, Begin: 20, End: 30},
+				{ID: 2, Begin: 10, End: 40},
+				{ID: 1, Begin: 0, End: 100},
+				{ID: 2, Begin: 110, End: 130},
+				{ID: 1, Begin: 100, End: 200},
+				{ID: 2, Begin: 220, End: 225},
+				{ID: 4, Begin: 210, End: 250},
+				{ID: 1, Begin: 200, End: 300},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 50}}},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name      string
+		stats     string   // "calls threads total self avg p95 max"
+		path      []string // "name duration percent-of-parent"
+		pathCalls int
+		distinct  int
+		wantErr   bool
+	}{
+		{
+			name:      "Update",
+			stats:     "4 2 105ns 95ns 26ns 50ns 50ns",
+			path:      []string{"Frame 100ns 100.00%", "Update 30ns 30.00%"},
+			pathCalls: 2,
+			distinct:  3,
+		},
+		{
+			name:      "Physics",
+			stats:     "1 1 10ns 10ns 10ns 10ns 10ns",
+			path:      []string{"Frame 100ns 100.00%", "Update 30ns 30.00%", "Physics 10ns 33.33%"},
+			pathCalls: 1,
+			distinct:  1,
+		},
+		{
+			name:      "Frame",
+			stats:     "3 1 300ns 210ns 100ns 100ns 100ns",
+			path:      []string{"Frame 100ns 100.00%"},
+			pathCalls: 3,
+			distinct:  1,
+		},
+		{name: "Missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := a.GetFunctionSummary(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetFunctionSummary error = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			stats := fmt.Sprintf("%d %d %v %v %v %v %v", summary.CallCount, summary.Threads, summary.Duration,
+				summary.SelfDuration, summary.AvgDuration, summary.P95Duration, summary.MaxDuration)
+			if stats != tt.stats {
+				t.Errorf("stats = %s, want %s", stats, tt.stats)
+			}
+
+			var path []string
+			for i, node := range summary.TypicalPath {
+				if node.Depth != i {
+					t.Errorf("path node %d has depth %d", i, node.Depth)
+				}
+				path = append(path, fmt.Sprintf("%s %v %.2f%%", node.Name, node.Duration, node.PercentOfParent))
+			}
+			if !reflect.DeepEqual(path, tt.path) {
+				t.Errorf("typical path = %q, want %q", path, tt.path)
+			}
+			if summary.TypicalPathCalls != tt.pathCalls || summary.DistinctPaths != tt.distinct {
+				t.Errorf("typical path calls %d of %d paths, want %d of %d", summary.TypicalPathCalls, summary.DistinctPaths, tt.pathCalls, tt.distinct)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var twenty []time.Duration
+	for i := 1; i <= 20; i++ {
+		twenty = append(twenty, time.Duration(i))
+	}
+
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{"empty", nil, 95, 0},
+		{"single", []time.Duration{7}, 95, 7},
+		{"p95 of 20", twenty, 95, 19},
+		{"p50 of 20", twenty, 50, 10},
+		{"p100", twenty, 100, 20},
+		{"p0", twenty, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// DefaultIOPatterns are the name fragments that mark a block as doing I/O
+var DefaultIOPatterns = []string{"read", "write", "fopen", "recv", "send", "query", "fetch", "http", "sql"}
+
+// detectBlockingIO flags blocks at least minDuration long whose names look like I/O
+// and that spent most of their wall time switched out, which strongly suggests the
+// thread blocked on I/O. Needs context switches; without them nothing is reported.
+// Blocks nested inside an already flagged block are skipped.
+func (a *Analyzer) detectBlockingIO(patterns []string, minDuration time.Duration) []*PerformanceIssue {
+	var issues []*PerformanceIssue
+	if !a.profile.ContextSwitchesLoaded() || len(patterns) == 0 {
+		return issues
+	}
+
+	lowered := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+			lowered = append(lowered, pattern)
+		}
+	}
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		flaggedDepth := -1
+
+		walkBlocks(thread.Blocks, func(block *parser.Block, depth int) {
+			if flaggedDepth >= 0 && depth > flaggedDepth {
+				return
+			}
+			flaggedDepth = -1
+
+			duration := block.Duration()
+			if duration < minDuration {
+				return
+			}
+
+			name, file, line := a.resolveBlock(block)
+			pattern := matchIOPattern(name, lowered)
+			if pattern == "" {
+				return
+			}
+
+			onCPU, ok := a.OnCPUTime(threadID, block.Begin, block.End)
+			wait := duration - onCPU
+			if !ok || wait*2 <= duration {
+				return
+			}
+
+			flaggedDepth = depth
+			issues = append(issues, &PerformanceIssue{
+				Type:  "Blocking I/O",
+				Score: impactScore(a.captureFraction(float64(wait))),
+				Description: fmt.Sprintf("Block '%s' looks like I/O ('%s') and waited %v of %v (%.0f%%) switched out on thread '%s'",
+					name, pattern, wait, duration, float64(wait)/float64(duration)*100, thread.ThreadName),
+				Location:   formatLocation(file, line),
+				Function:   name,
+				Duration:   duration,
+				ThreadID:   threadID,
+				ThreadName: thread.ThreadName,
+			})
+		})
+	}
+
+	return issues
+}
+
+// matchIOPattern returns the first lowercase pattern contained in name, or "" if none is
+func matchIOPattern(name string, patterns []string) string {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if strings.Contains(name, pattern) {
+			return pattern
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestMatchIOPattern(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"ReadFile", "read"},
+		{"HTTPFetch", "fetch"},
+		{"RunSQLQuery", "query"},
+		{"ComputeHash", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := matchIOPattern(tt.name, DefaultIOPatterns); got != tt.want {
+			t.Errorf("matchIOPattern(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// blockingIOProfile has, on Main:
+//   - ReadFile (0-100ms), switched out 80% of the time, with ReadChunk nested inside
+//   - ComputeHash (200-300ms), never switched out
+//   - WriteLog (400-405ms), switched out throughout but short
+//   - SendPacket (500-600ms), switched out 30% of the time
+func blockingIOProfile() *proftest.Profile {
+	ms := uint64(time.Millisecond)
+	return &proftest.Profile{
+		Descriptors: proftest.Descriptors("ReadFile", "ReadChunk", "ComputeHash", "WriteLog", "SendPacket"),
+		Threads: []proftest.Thread{{
+			ID:   1,
+			Name: "Main",
+			ContextSwitches: []proftest.ContextSwitch{
+				{ThreadID: 1, Begin: 10 * ms, End: 90 * ms},
+				{ThreadID: 1, Begin: 400 * ms, End: 405 * ms},
+				{ThreadID: 1, Begin: 510 * ms, End: 540 * ms},
+			},
+			Blocks: []proftest.Block{
+				{ID: 2, Begin: 20 * ms, End: 80 * ms},
+				{ID: 1, Begin: 0, End: 100 * ms},
+				{ID: 3, Begin: 200 * ms, End: 300 * ms},
+				{ID: 4, Begin: 400 * ms, End: 405 * ms},
+				{ID: 5, Begin: 500 * ms, End: 600 * ms},
+			},
+		}},
+	}
+}
+
+func TestDetectBlockingIO(t *testing.T) {
+	tests := []struct {
+		name        string
+		patterns    []string
+		minDuration time.Duration
+		options     parser.ReadOptions
+		want        []string
+	}{
+		{"defaults", DefaultIOPatterns, 10 * time.Millisecond, parser.DefaultReadOptions(), []string{"ReadFile"}},
+		{"short blocks included", DefaultIOPatterns, time.Millisecond, parser.DefaultReadOptions(), []string{"ReadFile", "WriteLog"}},
+		{"patterns trimmed and case-insensitive", []string{" CHUNK ", ""}, 0, parser.DefaultReadOptions(), []string{"ReadChunk"}},
+		{"never switched out", []string{"hash"}, 0, parser.DefaultReadOptions(), nil},
+		{"no patterns", nil, 0, parser.DefaultReadOptions(), nil},
+		{"without context switches", DefaultIOPatterns, 0, parser.ReadOptions{SkipContextSwitches: true}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAnalyzer(blockingIOProfile().Parse(t, tt.options))
+			var got []string
+			for _, issue := range a.detectBlockingIO(tt.patterns, tt.minDuration) {
+				got = append(got, issue.Function)
+				if issue.Type != "Blocking I/O" || issue.ThreadName != "Main" {
+					t.Errorf("issue = %+v, want a Blocking I/O issue on Main", issue)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flagged %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package analyzer
+
+import (
+	"context"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// budgetPollInterval is how many steps an analysis takes between deadline checks
+const budgetPollInterval = 1024
+
+// budget stops an analysis once its context is done, so that a caller with a time
+// limit gets the result built so far instead of waiting on a pathological profile
+type budget struct {
+	ctx     context.Context
+	steps   int
+	expired bool
+}
+
+// newBudget returns a budget bounded by ctx; a nil ctx never expires
+func newBudget(ctx context.Context) *budget {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &budget{ctx: ctx}
+}
+
+// exceeded counts a step and reports whether the budget has run out. The context
+// is only polled every budgetPollInterval steps, and once out the budget stays out.
+func (b *budget) exceeded() bool {
+	if !b.expired {
+		b.steps++
+		b.expired = b.steps%budgetPollInterval == 0 && b.ctx.Err() != nil
+	}
+	return b.expired
+}
+
+// done reports whether the budget has run out, polling the context right away.
+// It suits coarse steps, such as starting on the next thread.
+func (b *budget) done() bool {
+	if !b.expired {
+		b.expired = b.ctx.Err() != nil
+	}
+	return b.expired
+}
+
+// walkBlocksWithin is walkBlocks stopping when the budget runs out
+func walkBlocksWithin(b *budget, blocks []*parser.Block, visit func(block *parser.Block, depth int)) {
+	_ = parser.WalkBlocksUntil(blocks, parser.DefaultMaxTreeDepth, func(block *parser.Block, depth int) bool {
+		if b.exceeded() {
+			return false
+		}
+		visit(block, depth)
+		return true
+	})
+}
@@ -0,0 +1,183 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// countdownContext is done once its Err has been polled polls times, so that a
+// test can expire a budget at an exact step rather than after some wall time
+type countdownContext struct {
+	context.Context
+	polls int
+}
+
+func (c *countdownContext) Err() error {
+	if c.polls <= 0 {
+		return context.DeadlineExceeded
+	}
+	c.polls--
+	return nil
+}
+
+// expiringAfter returns a context whose Err starts failing after polls polls
+func expiringAfter(polls int) context.Context {
+	return &countdownContext{Context: context.Background(), polls: polls}
+}
+
+// stepsProfile has a Main thread of n sequential Step blocks, the i-th lasting
+// i+1ns, and a Worker thread with a single Job
+func stepsProfile(n int) *proftest.Profile {
+	steps := make([]proftest.Block, n)
+	for i := range steps {
+		begin := uint64(i) * 5000
+		steps[i] = proftest.Block{ID: 1, Begin: begin, End: begin + uint64(i) + 1}
+	}
+	return &proftest.Profile{
+		Descriptors: proftest.Descriptors("Step", "Job"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: steps},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 10}}},
+		},
+	}
+}
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		steps   int
+		expired bool
+	}{
+		{"nil context never expires", nil, 5000, false},
+		{"live context", context.Background(), 5000, false},
+		{"expired context waits for the next poll", expiringAfter(0), budgetPollInterval - 1, false},
+		{"expired context caught at the poll", expiringAfter(0), budgetPollInterval, true},
+		{"expires at the second poll", expiringAfter(1), 2 * budgetPollInterval, true},
+		{"not yet at the second poll", expiringAfter(1), 2*budgetPollInterval - 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBudget(tt.ctx)
+			exceeded := false
+			for i := 0; i < tt.steps; i++ {
+				if exceeded = b.exceeded(); exceeded {
+					if i != tt.steps-1 {
+						t.Fatalf("exceeded at step %d of %d", i+1, tt.steps)
+					}
+				}
+			}
+			if exceeded != tt.expired || b.expired != tt.expired {
+				t.Errorf("exceeded %t (expired %t) after %d steps, want %t", exceeded, b.expired, tt.steps, tt.expired)
+			}
+			if tt.expired && !b.exceeded() {
+				t.Error("budget recovered after running out")
+			}
+		})
+	}
+}
+
+func TestBudgetDone(t *testing.T) {
+	if newBudget(nil).done() {
+		t.Error("nil context done")
+	}
+	b := newBudget(expiringAfter(0))
+	if !b.done() || !b.exceeded() {
+		t.Error("expired context not done right away, or not kept")
+	}
+}
+
+func TestGetHotspotsByContext(t *testing.T) {
+	const steps = 3000
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		calls    map[string]int
+		timedOut bool
+	}{
+		{"no limit", context.Background(), map[string]int{"Step": steps, "Job": 1}, false},
+		// Passes the poll at Main's start, then runs out partway through its blocks
+		{"runs out within a thread", expiringAfter(1), map[string]int{"Step": budgetPollInterval - 1}, true},
+		{"runs out before starting", expiringAfter(0), map[string]int{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t, stepsProfile(steps))
+			hotspots, timedOut := a.GetHotspotsByContext(tt.ctx, 10, Inclusive, ByCallSite, nil)
+			calls := make(map[string]int)
+			for _, hotspot := range hotspots {
+				calls[hotspot.Name] = hotspot.CallCount
+			}
+			if timedOut != tt.timedOut || len(calls) != len(tt.calls) {
+				t.Fatalf("timedOut %t with calls %v, want %t with %v", timedOut, calls, tt.timedOut, tt.calls)
+			}
+			for name, want := range tt.calls {
+				if calls[name] != want {
+					t.Errorf("%s called %d times, want %d", name, calls[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetThreadCriticalPathContext(t *testing.T) {
+	const steps = 3000
+	a := newTestAnalyzer(t, stepsProfile(steps))
+	thread := a.profile.Thread(1)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		longest  uint64 // Begin of the block the path starts at
+		timedOut bool
+	}{
+		{"no limit", context.Background(), (steps - 1) * 5000, false},
+		// The first block takes no step, so the poll stops the scan before block 1024
+		{"runs out", expiringAfter(0), (budgetPollInterval - 1) * 5000, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := a.GetThreadCriticalPathContext(tt.ctx, thread)
+			if len(path.Nodes) != 1 || path.Nodes[0].Begin != tt.longest || path.TimedOut != tt.timedOut {
+				t.Errorf("path %d nodes from %d, timedOut %t; want one node from %d, %t",
+					len(path.Nodes), path.Nodes[0].Begin, path.TimedOut, tt.longest, tt.timedOut)
+			}
+		})
+	}
+}
+
+func TestFindOverlappingBlocksContext(t *testing.T) {
+	// Two siblings under Frame overlap by 10ns
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 0, End: 50},
+			{ID: 3, Begin: 40, End: 90},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		count    int
+		timedOut bool
+	}{
+		{"no limit", context.Background(), 1, false},
+		{"out before starting", expiringAfter(0), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := newTestAnalyzer(t, p).FindOverlappingBlocksContext(tt.ctx, 10)
+			if report.Count != tt.count || report.TimedOut != tt.timedOut {
+				t.Errorf("%d overlaps, timedOut %t; want %d, %t", report.Count, report.TimedOut, tt.count, tt.timedOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// rootCaller names the caller of top-level blocks
+const rootCaller = "(thread root)"
+
+// CallEdge aggregates the calls between a function and one of its callers or callees
+type CallEdge struct {
+	Name      string
+	CallCount int
+	Duration  time.Duration // Inclusive time of the calls made along this edge
+}
+
+// GetCallers returns the functions that directly call name, with the number and
+// inclusive time of the calls each made, most expensive first
+func (a *Analyzer) GetCallers(name string) []*CallEdge {
+	edges := make(map[string]*CallEdge)
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		var path []string // resolved names of the current block's ancestors
+		walkBlocks(thread.Blocks, func(block *parser.Block, depth int) {
+			path = path[:depth]
+
+			blockName, _, _ := a.resolveBlock(block)
+			if blockName == name {
+				caller := rootCaller
+				if depth > 0 {
+					caller = path[depth-1]
+				}
+				addCallEdge(edges, caller, block.Duration())
+			}
+
+			path = append(path, blockName)
+		})
+	}
+
+	return sortedCallEdges(edges)
+}
+
+// GetCallees returns the functions name calls directly, with the number and
+// inclusive time of those calls, most expensive first
+func (a *Analyzer) GetCallees(name string) []*CallEdge {
+	edges := make(map[string]*CallEdge)
+
+	for _, invocation := range a.GetInvocations(name) {
+		for _, child := range invocation.Block.Children {
+			childName, _, _ := a.resolveBlock(child)
+			addCallEdge(edges, childName, child.Duration())
+		}
+	}
+
+	return sortedCallEdges(edges)
+}
+
+// addCallEdge records one call along the edge to name
+func addCallEdge(edges map[string]*CallEdge, name string, duration time.Duration) {
+	edge, ok := edges[name]
+	if !ok {
+		edge = &CallEdge{Name: name}
+		edges[name] = edge
+	}
+	edge.CallCount++
+	edge.Duration += duration
+}
+
+// sortedCallEdges returns the edges ordered by inclusive time, then name
+func sortedCallEdges(edges map[string]*CallEdge) []*CallEdge {
+	result := make([]*CallEdge, 0, len(edges))
+	for _, edge := range edges {
+		result = append(result, edge)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Duration != result[j].Duration {
+			return result[i].Duration > result[j].Duration
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
@@ -0,0 +1,59 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// edgeStrings renders call edges as "name×calls=duration"
+func edgeStrings(edges []*CallEdge) []string {
+	result := make([]string, len(edges))
+	for i, edge := range edges {
+		result[i] = fmt.Sprintf("%s×%d=%v", edge.Name, edge.CallCount, edge.Duration)
+	}
+	return result
+}
+
+func TestCallersAndCallees(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Physics", "Render"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 3, Begin: 10, End: 40},
+				{ID: 2, Begin: 0, End: 50},
+				{ID: 4, Begin: 50, End: 80},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{
+				{ID: 3, Begin: 0, End: 5},
+				{ID: 2, Begin: 0, End: 20},
+			}},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name    string
+		callers []string
+		callees []string
+	}{
+		{"Frame", []string{"(thread root)×1=100ns"}, []string{"Update×1=50ns", "Render×1=30ns"}},
+		{"Update", []string{"Frame×1=50ns", "(thread root)×1=20ns"}, []string{"Physics×2=35ns"}},
+		{"Physics", []string{"Update×2=35ns"}, []string{}},
+		{"Missing", []string{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := edgeStrings(a.GetCallers(tt.name)); !reflect.DeepEqual(got, tt.callers) {
+				t.Errorf("callers = %v, want %v", got, tt.callers)
+			}
+			if got := edgeStrings(a.GetCallees(tt.name)); !reflect.DeepEqual(got, tt.callees) {
+				t.Errorf("callees = %v, want %v", got, tt.callees)
+			}
+		})
+	}
+}
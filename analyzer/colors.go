@@ -0,0 +1,103 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ColorLegendEntry lists the functions and files drawn in one descriptor color
+type ColorLegendEntry struct {
+	Color     uint32
+	Category  string // Name prefix shared by all functions, if any (e.g. "Render::")
+	Functions []string
+	Files     []string
+}
+
+// Hex renders the color as #RRGGBB, ignoring the alpha channel
+func (e *ColorLegendEntry) Hex() string {
+	return colorHex(e.Color)
+}
+
+// colorHex renders an ARGB descriptor color as #RRGGBB
+func colorHex(color uint32) string {
+	return fmt.Sprintf("#%06X", color&0xFFFFFF)
+}
+
+// GetColorLegend groups descriptors by color so the category meaning the
+// instrumented code assigned to each color can be reconstructed
+func (a *Analyzer) GetColorLegend() []*ColorLegendEntry {
+	functions := make(map[uint32]map[string]bool)
+	files := make(map[uint32]map[string]bool)
+
+	for _, id := range a.profile.DescriptorIDs() {
+		descriptor := a.profile.Descriptor(id)
+		if functions[descriptor.Color] == nil {
+			functions[descriptor.Color] = make(map[string]bool)
+			files[descriptor.Color] = make(map[string]bool)
+		}
+		if descriptor.Name != "" {
+			functions[descriptor.Color][descriptor.Name] = true
+		}
+		if descriptor.File != "" {
+			files[descriptor.Color][descriptor.File] = true
+		}
+	}
+
+	legend := make([]*ColorLegendEntry, 0, len(functions))
+	for color, names := range functions {
+		entry := &ColorLegendEntry{
+			Color:     color,
+			Functions: sortedKeys(names),
+			Files:     sortedKeys(files[color]),
+		}
+		entry.Category = commonNamePrefix(entry.Functions)
+		legend = append(legend, entry)
+	}
+
+	// Most widely used colors first
+	sort.Slice(legend, func(i, j int) bool {
+		if len(legend[i].Functions) != len(legend[j].Functions) {
+			return len(legend[i].Functions) > len(legend[j].Functions)
+		}
+		return legend[i].Color < legend[j].Color
+	})
+
+	return legend
+}
+
+// commonNamePrefix returns the longest prefix shared by at least two names that
+// ends at a namespace or word separator ("::", ".", "_"), or "" if there is none
+func commonNamePrefix(names []string) string {
+	if len(names) < 2 {
+		return ""
+	}
+
+	prefix := names[0]
+	for _, name := range names[1:] {
+		for !strings.HasPrefix(name, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	cut := -1
+	for _, sep := range []string{"::", ".", "_"} {
+		if i := strings.LastIndex(prefix, sep); i >= 0 {
+			cut = max(cut, i+len(sep))
+		}
+	}
+	if cut <= 0 {
+		return ""
+	}
+	return prefix[:cut]
+}
+
+// sortedKeys returns the keys of set in ascending order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,58 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestCommonNamePrefix(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"Render::Draw"}, ""},
+		{[]string{"Render::Draw", "Render::Present"}, "Render::"},
+		{[]string{"Render::Pass::A", "Render::Pass::B", "Render::Post"}, "Render::"},
+		{[]string{"io.read", "io.write"}, "io."},
+		{[]string{"gc_mark", "gc_sweep"}, "gc_"},
+		{[]string{"Update", "Upload"}, ""},
+		{[]string{"Physics::Step", "Render::Draw"}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := commonNamePrefix(tt.names); got != tt.want {
+			t.Errorf("commonNamePrefix(%q) = %q, want %q", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestGetColorLegend(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: []proftest.Descriptor{
+			{ID: 1, Name: "Render::Draw", File: "render.cpp", Color: 0xFF00FF00},
+			{ID: 2, Name: "Render::Present", File: "present.cpp", Color: 0xFF00FF00},
+			{ID: 3, Name: "Render::Draw", File: "render.cpp", Line: 40, Color: 0xFF00FF00},
+			{ID: 4, Name: "Update", File: "game.cpp", Color: 0x80123456},
+			{ID: 5, Name: "", File: "", Color: 0xFFABCDEF},
+		},
+	}
+
+	var got []string
+	var functions [][]string
+	var files [][]string
+	for _, entry := range newTestAnalyzer(t, p).GetColorLegend() {
+		got = append(got, entry.Hex()+" "+entry.Category)
+		functions = append(functions, entry.Functions)
+		files = append(files, entry.Files)
+	}
+
+	want := []string{"#00FF00 Render::", "#123456 ", "#ABCDEF "}
+	wantFunctions := [][]string{{"Render::Draw", "Render::Present"}, {"Update"}, {}}
+	wantFiles := [][]string{{"present.cpp", "render.cpp"}, {"game.cpp"}, {}}
+	if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(functions, wantFunctions) || !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("legend = %q %q %q, want %q %q %q", got, functions, files, want, wantFunctions, wantFiles)
+	}
+}
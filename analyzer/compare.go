@@ -0,0 +1,256 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// FunctionDiff is the change in a function's cumulative time between two profiles
+type FunctionDiff struct {
+	Name             string
+	Caller           string // Calling function, when compared by call site
+	File             string
+	Line             int32
+	BaselineDuration time.Duration
+	CurrentDuration  time.Duration
+	BaselineCalls    int
+	CurrentCalls     int
+	Delta            time.Duration
+	DeltaPercent     float64 // +Inf for functions absent from the baseline
+
+	// Normalized is set by NormalizeDiffs. BaselineShare and CurrentShare are then
+	// the function's percent of each capture's duration, and ShareDelta their
+	// difference in percentage points.
+	Normalized    bool
+	BaselineShare float64
+	CurrentShare  float64
+	ShareDelta    float64
+}
+
+// CompareProfiles diffs cumulative time (measured in mode) per function between a baseline
+// and a current profile. Functions are matched by name so diffs survive line-number changes
+// between builds. Results are sorted by absolute delta, largest first.
+func CompareProfiles(baseline, current *Analyzer, mode TimeMode) []*FunctionDiff {
+	return diffCallSites(bySite(hotspotsByName(baseline)), bySite(hotspotsByName(current)), mode)
+}
+
+// CompareProfilesByCallSite is CompareProfiles matching each function separately per
+// direct caller, so a regression in one calling context isn't averaged away by the
+// function's other callers. Callers are matched by name like functions are.
+func CompareProfilesByCallSite(baseline, current *Analyzer, mode TimeMode) []*FunctionDiff {
+	return diffCallSites(baseline.hotspotsByCallSite(), current.hotspotsByCallSite(), mode)
+}
+
+// callSite identifies a function called from a given caller; Caller is empty when
+// functions are compared regardless of caller
+type callSite struct {
+	Caller string
+	Name   string
+}
+
+// diffCallSites diffs two profiles' aggregates keyed by call site
+func diffCallSites(baselineBySite, currentBySite map[callSite]*BlockInfo, mode TimeMode) []*FunctionDiff {
+	diffs := make(map[callSite]*FunctionDiff)
+	for site, info := range baselineBySite {
+		diffs[site] = &FunctionDiff{
+			Name:             site.Name,
+			Caller:           site.Caller,
+			File:             info.File,
+			Line:             info.Line,
+			BaselineDuration: mode.Of(info),
+			BaselineCalls:    info.CallCount,
+		}
+	}
+	for site, info := range currentBySite {
+		diff, ok := diffs[site]
+		if !ok {
+			diff = &FunctionDiff{Name: site.Name, Caller: site.Caller}
+			diffs[site] = diff
+		}
+		diff.File = info.File
+		diff.Line = info.Line
+		diff.CurrentDuration = mode.Of(info)
+		diff.CurrentCalls = info.CallCount
+	}
+
+	result := make([]*FunctionDiff, 0, len(diffs))
+	for _, diff := range diffs {
+		diff.Delta = diff.CurrentDuration - diff.BaselineDuration
+		if diff.BaselineDuration > 0 {
+			diff.DeltaPercent = float64(diff.Delta) / float64(diff.BaselineDuration) * 100
+		} else if diff.CurrentDuration > 0 {
+			diff.DeltaPercent = math.Inf(1)
+		}
+		result = append(result, diff)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		di, dj := absDuration(result[i].Delta), absDuration(result[j].Delta)
+		if di != dj {
+			return di > dj
+		}
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Caller < result[j].Caller
+	})
+
+	return result
+}
+
+// NormalizeDiffs relates each function's time to its capture's duration, so profiles
+// of different lengths compare by share: a function that stayed at 10% of a longer
+// run has no share delta even though its absolute time grew. The diffs are re-sorted
+// by absolute share delta, largest first.
+func NormalizeDiffs(diffs []*FunctionDiff, baseline, current *Analyzer) {
+	baselineTotal := baseline.profile.GetTotalDuration()
+	currentTotal := current.profile.GetTotalDuration()
+
+	for _, diff := range diffs {
+		diff.Normalized = true
+		if baselineTotal > 0 {
+			diff.BaselineShare = float64(diff.BaselineDuration) / float64(baselineTotal) * 100
+		}
+		if currentTotal > 0 {
+			diff.CurrentShare = float64(diff.CurrentDuration) / float64(currentTotal) * 100
+		}
+		diff.ShareDelta = diff.CurrentShare - diff.BaselineShare
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return math.Abs(diffs[i].ShareDelta) > math.Abs(diffs[j].ShareDelta)
+	})
+}
+
+// RegressionReport is the outcome of a threshold-gated regression check
+type RegressionReport struct {
+	ThresholdPercent  float64
+	MinDuration       time.Duration
+	FunctionsCompared int
+	Regressions       []*FunctionDiff
+	Passed            bool
+}
+
+// CheckRegressions reports functions that got more than thresholdPercent slower than
+// the baseline. Functions under minDuration in both profiles are ignored as noise, and
+// functions absent from the baseline aren't counted since they have nothing to regress from.
+func CheckRegressions(baseline, current *Analyzer, thresholdPercent float64, minDuration time.Duration, mode TimeMode) *RegressionReport {
+	report := &RegressionReport{
+		ThresholdPercent: thresholdPercent,
+		MinDuration:      minDuration,
+	}
+
+	for _, diff := range CompareProfiles(baseline, current, mode) {
+		if diff.BaselineDuration < minDuration && diff.CurrentDuration < minDuration {
+			continue
+		}
+		if diff.BaselineDuration == 0 {
+			continue
+		}
+
+		report.FunctionsCompared++
+		if diff.DeltaPercent > thresholdPercent {
+			report.Regressions = append(report.Regressions, diff)
+		}
+	}
+
+	sort.Slice(report.Regressions, func(i, j int) bool {
+		if report.Regressions[i].DeltaPercent != report.Regressions[j].DeltaPercent {
+			return report.Regressions[i].DeltaPercent > report.Regressions[j].DeltaPercent
+		}
+		return report.Regressions[i].Name < report.Regressions[j].Name
+	})
+
+	report.Passed = len(report.Regressions) == 0
+	return report
+}
+
+// hotspotsByName aggregates a profile's cumulative time per function name
+func hotspotsByName(a *Analyzer) map[string]*BlockInfo {
+	return groupByName(a.aggregateHotspots())
+}
+
+// bySite keys entries grouped by name on call sites without a caller
+func bySite(byName map[string]*BlockInfo) map[callSite]*BlockInfo {
+	result := make(map[callSite]*BlockInfo, len(byName))
+	for name, info := range byName {
+		result[callSite{Name: name}] = info
+	}
+	return result
+}
+
+// hotspotsByCallSite aggregates a profile's cumulative time per function name and
+// direct caller name; top-level blocks are called by rootCaller. As in aggregateBlocks,
+// recursive calls through the same call site add their inclusive time only once.
+func (a *Analyzer) hotspotsByCallSite() map[callSite]*BlockInfo {
+	result := make(map[callSite]*BlockInfo)
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		var names []string   // Resolved names of the current block's ancestors
+		var sites []callSite // Call sites of the current block's ancestors
+		active := make(map[callSite]int)
+
+		walkBlocks(thread.Blocks, func(block *parser.Block, depth int) {
+			for len(sites) > depth {
+				active[sites[len(sites)-1]]--
+				sites = sites[:len(sites)-1]
+			}
+			names = names[:depth]
+
+			name, file, line := a.resolveBlock(block)
+			site := callSite{Caller: rootCaller, Name: name}
+			if depth > 0 {
+				site.Caller = names[depth-1]
+			}
+
+			inclusive := block.Duration()
+			if active[site] > 0 {
+				inclusive = 0
+			}
+
+			info, ok := result[site]
+			if !ok {
+				info = &BlockInfo{Name: name, File: file, Line: line, ThreadID: threadID, ThreadName: thread.ThreadName}
+				result[site] = info
+			}
+			info.Duration += inclusive
+			info.SelfDuration += a.selfTime(block)
+			info.CallCount++
+
+			names = append(names, name)
+			sites = append(sites, site)
+			active[site]++
+		})
+	}
+
+	return result
+}
+
+// groupByName merges aggregated entries that share a function name, e.g. the same
+// function at several call sites
+func groupByName(infos []*BlockInfo) map[string]*BlockInfo {
+	result := make(map[string]*BlockInfo)
+	for _, info := range infos {
+		if existing, ok := result[info.Name]; ok {
+			existing.Duration += info.Duration
+			existing.SelfDuration += info.SelfDuration
+			existing.CallCount += info.CallCount
+			continue
+		}
+		copied := *info
+		result[info.Name] = &copied
+	}
+	return result
+}
+
+// absDuration returns the absolute value of d
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,224 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// runProfile is a capture with one call per function, each lasting the given
+// number of milliseconds
+func runProfile(durations map[string]uint64) *proftest.Profile {
+	p := &proftest.Profile{Threads: []proftest.Thread{{ID: 1, Name: "Main"}}}
+	begin := uint64(0)
+	for _, name := range []string{"Update", "Render", "Physics"} {
+		ms, ok := durations[name]
+		if !ok {
+			continue
+		}
+		id := uint32(len(p.Descriptors) + 1)
+		p.Descriptors = append(p.Descriptors, proftest.Descriptor{ID: id, Name: name, File: "game.cpp", Line: int32(id)})
+		end := begin + ms*uint64(time.Millisecond)
+		p.Threads[0].Blocks = append(p.Threads[0].Blocks, proftest.Block{ID: id, Begin: begin, End: end})
+		begin = end
+	}
+	return p
+}
+
+func TestCompareProfiles(t *testing.T) {
+	baseline := newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 10, "Render": 20}))
+	current := newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 15, "Render": 18, "Physics": 2}))
+
+	diffs := CompareProfiles(baseline, current, Inclusive)
+
+	tests := []struct {
+		name         string
+		delta        time.Duration
+		deltaPercent float64
+	}{
+		{"Update", 5 * time.Millisecond, 50},
+		{"Physics", 2 * time.Millisecond, math.Inf(1)},
+		{"Render", -2 * time.Millisecond, -10},
+	}
+	if len(diffs) != len(tests) {
+		t.Fatalf("got %d diffs, want %d", len(diffs), len(tests))
+	}
+	for i, tt := range tests {
+		diff := diffs[i]
+		if diff.Name != tt.name || diff.Delta != tt.delta || diff.DeltaPercent != tt.deltaPercent {
+			t.Errorf("diffs[%d] = %s %v (%v%%), want %s %v (%v%%)",
+				i, diff.Name, diff.Delta, diff.DeltaPercent, tt.name, tt.delta, tt.deltaPercent)
+		}
+	}
+}
+
+// callSiteProfile is a capture where Load and then Render each run for 20ms,
+// calling Alloc for the given number of milliseconds at their start
+func callSiteProfile(loadAllocMs, renderAllocMs uint64) *proftest.Profile {
+	ms := uint64(time.Millisecond)
+	return &proftest.Profile{
+		Descriptors: proftest.Descriptors("Load", "Render", "Alloc"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 3, Begin: 0, End: loadAllocMs * ms},
+			{ID: 1, Begin: 0, End: 20 * ms},
+			{ID: 3, Begin: 20 * ms, End: (20 + renderAllocMs) * ms},
+			{ID: 2, Begin: 20 * ms, End: 40 * ms},
+		}}},
+	}
+}
+
+func TestCompareProfilesByCallSite(t *testing.T) {
+	// Alloc regresses by 10ms when Render calls it, and not when Load does
+	baseline := newTestAnalyzer(t, callSiteProfile(5, 5))
+	current := newTestAnalyzer(t, callSiteProfile(5, 15))
+
+	tests := []struct {
+		name    string
+		compare func(baseline, current *Analyzer, mode TimeMode) []*FunctionDiff
+		mode    TimeMode
+		want    []string // "caller>name delta percent"
+	}{
+		{"by name", CompareProfiles, Inclusive, []string{
+			">Alloc 10ms 100%", ">Load 0s 0%", ">Render 0s 0%",
+		}},
+		{"by call site", CompareProfilesByCallSite, Inclusive, []string{
+			"Render>Alloc 10ms 200%", "Load>Alloc 0s 0%", rootCaller + ">Load 0s 0%", rootCaller + ">Render 0s 0%",
+		}},
+		{"by call site, exclusive", CompareProfilesByCallSite, Exclusive, []string{
+			"Render>Alloc 10ms 200%", rootCaller + ">Render -10ms -66.66666666666666%", "Load>Alloc 0s 0%", rootCaller + ">Load 0s 0%",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, diff := range tt.compare(baseline, current, tt.mode) {
+				got = append(got, fmt.Sprintf("%s>%s %v %v%%", diff.Caller, diff.Name, diff.Delta, diff.DeltaPercent))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareProfilesByCallSiteRecursion(t *testing.T) {
+	// Walk calls itself twice over: the inner calls share the Walk>Walk call site,
+	// whose inclusive time counts only the outer of them
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Walk"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: proftest.Nested(1, 0, 3)}},
+	}
+	a := newTestAnalyzer(t, p)
+
+	var got []string
+	for _, diff := range CompareProfilesByCallSite(a, a, Inclusive) {
+		got = append(got, fmt.Sprintf("%s>%s %v %d calls", diff.Caller, diff.Name, diff.BaselineDuration, diff.BaselineCalls))
+	}
+	if want := []string{rootCaller + ">Walk 6ns 1 calls", "Walk>Walk 4ns 2 calls"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("diffs = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeDiffs(t *testing.T) {
+	baseline := newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 10, "Render": 10}))
+
+	tests := []struct {
+		name    string
+		current map[string]uint64
+		want    []string // "name delta baseline%->current% points"
+	}{
+		{"run twice as long", map[string]uint64{"Update": 20, "Render": 20}, []string{
+			"Render 10ms 50->50 0", "Update 10ms 50->50 0",
+		}},
+		{"shifted share", map[string]uint64{"Update": 10, "Render": 30}, []string{
+			"Render 20ms 50->75 25", "Update 0s 50->25 -25",
+		}},
+		{"new function", map[string]uint64{"Update": 10, "Render": 10, "Physics": 20}, []string{
+			"Physics 20ms 0->50 50", "Render 0s 50->25 -25", "Update 0s 50->25 -25",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := newTestAnalyzer(t, runProfile(tt.current))
+			diffs := CompareProfiles(baseline, current, Inclusive)
+			NormalizeDiffs(diffs, baseline, current)
+
+			var got []string
+			for _, diff := range diffs {
+				if !diff.Normalized {
+					t.Errorf("%s isn't marked normalized", diff.Name)
+				}
+				got = append(got, fmt.Sprintf("%s %v %v->%v %v", diff.Name, diff.Delta, diff.BaselineShare, diff.CurrentShare, diff.ShareDelta))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeDiffsZeroTotal(t *testing.T) {
+	// The baseline capture has no duration, so its shares stay 0
+	baseline := newTestAnalyzer(t, &proftest.Profile{
+		Begin:       100,
+		End:         100,
+		Descriptors: proftest.Descriptors("Update"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 100, End: 100}}}},
+	})
+	current := newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 10}))
+
+	diffs := CompareProfiles(baseline, current, Inclusive)
+	NormalizeDiffs(diffs, baseline, current)
+	if len(diffs) != 1 || diffs[0].BaselineShare != 0 || diffs[0].CurrentShare != 100 || diffs[0].ShareDelta != 100 {
+		t.Errorf("diffs = %+v, want Update from 0%% to 100%%", diffs[0])
+	}
+}
+
+func TestCheckRegressions(t *testing.T) {
+	baseline := newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 10, "Render": 20}))
+	current := newTestAnalyzer(t, runProfile(map[string]uint64{"Update": 15, "Render": 22, "Physics": 30}))
+
+	tests := []struct {
+		name        string
+		threshold   float64
+		minDuration time.Duration
+		regressions []string
+		compared    int
+	}{
+		{"both over threshold", 5, time.Millisecond, []string{"Update", "Render"}, 2},
+		{"one over threshold", 20, time.Millisecond, []string{"Update"}, 2},
+		{"none over threshold", 60, time.Millisecond, nil, 2},
+		{"below min duration", 5, 16 * time.Millisecond, []string{"Render"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := CheckRegressions(baseline, current, tt.threshold, tt.minDuration, Inclusive)
+
+			var names []string
+			for _, diff := range report.Regressions {
+				names = append(names, diff.Name)
+			}
+			if len(names) != len(tt.regressions) {
+				t.Fatalf("regressions = %v, want %v", names, tt.regressions)
+			}
+			for i := range names {
+				if names[i] != tt.regressions[i] {
+					t.Errorf("regressions = %v, want %v", names, tt.regressions)
+				}
+			}
+			if report.FunctionsCompared != tt.compared {
+				t.Errorf("compared %d functions, want %d", report.FunctionsCompared, tt.compared)
+			}
+			if report.Passed != (len(tt.regressions) == 0) {
+				t.Errorf("passed = %t with %d regressions", report.Passed, len(tt.regressions))
+			}
+		})
+	}
+}
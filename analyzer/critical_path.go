@@ -0,0 +1,136 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// PathNode is a single block on a critical path
+type PathNode struct {
+	Name            string
+	File            string
+	Line            int32
+	Depth           int
+	Duration        time.Duration
+	PercentOfParent float64
+	Begin           uint64 // Raw begin timestamp
+	End             uint64 // Raw end timestamp
+}
+
+// CriticalPath is the dominant nested chain of blocks on a thread
+type CriticalPath struct {
+	ThreadID        uint64
+	ThreadName      string
+	ThreadDuration  time.Duration
+	PercentOfThread float64 // Root block's share of the thread's total time
+	Nodes           []*PathNode
+	TimedOut        bool // The descent gave up early; see GetThreadCriticalPathContext
+}
+
+// FindThread resolves a thread reference given either as a numeric thread ID or a thread name
+func (a *Analyzer) FindThread(ref string) (*parser.ThreadData, error) {
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		if thread := a.profile.Thread(id); thread != nil {
+			return thread, nil
+		}
+	}
+
+	var match *parser.ThreadData
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		if thread.ThreadName != ref {
+			continue
+		}
+		if match != nil {
+			return nil, fmt.Errorf("thread name '%s' is ambiguous, use the thread ID", ref)
+		}
+		match = thread
+	}
+
+	if match == nil {
+		return nil, fmt.Errorf("thread '%s' not found", ref)
+	}
+	return match, nil
+}
+
+// GetThreadCriticalPath follows the longest top-level block on the thread and
+// descends into the longest child at each level until reaching a leaf
+func (a *Analyzer) GetThreadCriticalPath(thread *parser.ThreadData) *CriticalPath {
+	return a.GetThreadCriticalPathContext(context.Background(), thread)
+}
+
+// GetThreadCriticalPathContext is GetThreadCriticalPath giving up when ctx is done.
+// The path then ends at the longest block among those examined at the level it
+// stopped at, and TimedOut is set.
+func (a *Analyzer) GetThreadCriticalPathContext(ctx context.Context, thread *parser.ThreadData) *CriticalPath {
+	b := newBudget(ctx)
+	path := &CriticalPath{
+		ThreadID:       thread.ThreadID,
+		ThreadName:     thread.ThreadName,
+		ThreadDuration: a.calculateThreadDuration(thread.Blocks),
+	}
+
+	current := longestBlockWithin(b, thread.Blocks)
+	if current == nil {
+		path.TimedOut = b.expired
+		return path
+	}
+
+	if path.ThreadDuration > 0 {
+		path.PercentOfThread = float64(current.Duration()) / float64(path.ThreadDuration) * 100
+	}
+
+	var parent *parser.Block
+	for depth := 0; current != nil; depth++ {
+		name, file, line := a.resolveBlock(current)
+
+		percent := 100.0
+		if parent != nil && parent.Duration() > 0 {
+			percent = float64(current.Duration()) / float64(parent.Duration()) * 100
+		}
+
+		path.Nodes = append(path.Nodes, &PathNode{
+			Name:            name,
+			File:            file,
+			Line:            line,
+			Depth:           depth,
+			Duration:        current.Duration(),
+			PercentOfParent: percent,
+			Begin:           current.Begin,
+			End:             current.End,
+		})
+
+		parent = current
+		if b.expired {
+			break
+		}
+		current = longestBlockWithin(b, current.Children)
+	}
+	path.TimedOut = b.expired
+
+	return path
+}
+
+// longestBlock returns the block with the largest duration, or nil if blocks is empty
+func longestBlock(blocks []*parser.Block) *parser.Block {
+	return longestBlockWithin(newBudget(nil), blocks)
+}
+
+// longestBlockWithin is longestBlock stopping when the budget runs out, with the
+// longest of the blocks examined until then
+func longestBlockWithin(b *budget, blocks []*parser.Block) *parser.Block {
+	var longest *parser.Block
+	for _, block := range blocks {
+		if longest != nil && b.exceeded() {
+			break
+		}
+		if longest == nil || block.Duration() > longest.Duration() {
+			longest = block
+		}
+	}
+	return longest
+}
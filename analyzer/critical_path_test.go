@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// criticalPathProfile has two threads, the second sharing the first one's name
+func criticalPathProfile() *proftest.Profile {
+	return &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render", "Physics", "Draw"),
+		Threads: []proftest.Thread{
+			{ID: 10, Name: "Main", Blocks: []proftest.Block{
+				{ID: 4, Begin: 110, End: 150},
+				{ID: 2, Begin: 100, End: 300},
+				{ID: 5, Begin: 320, End: 380},
+				{ID: 3, Begin: 310, End: 400},
+				{ID: 1, Begin: 0, End: 1000},
+				{ID: 1, Begin: 1000, End: 1100},
+			}},
+			{ID: 20, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 50}}},
+			{ID: 30, Name: "Worker", Blocks: []proftest.Block{{ID: 3, Begin: 0, End: 50}}},
+		},
+	}
+}
+
+func TestFindThread(t *testing.T) {
+	a := newTestAnalyzer(t, criticalPathProfile())
+
+	tests := []struct {
+		ref     string
+		wantID  uint64
+		wantErr bool
+	}{
+		{"10", 10, false},
+		{"Main", 10, false},
+		{"30", 30, false},
+		{"Worker", 0, true}, // Ambiguous
+		{"99", 0, true},
+		{"Nope", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			thread, err := a.FindThread(tt.ref)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("FindThread(%q) = thread %d, want an error", tt.ref, thread.ThreadID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindThread(%q): %v", tt.ref, err)
+			}
+			if thread.ThreadID != tt.wantID {
+				t.Errorf("FindThread(%q) = thread %d, want %d", tt.ref, thread.ThreadID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestGetThreadCriticalPath(t *testing.T) {
+	a := newTestAnalyzer(t, criticalPathProfile())
+	thread, err := a.FindThread("Main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := a.GetThreadCriticalPath(thread)
+
+	var names []string
+	var percents []float64
+	for _, node := range path.Nodes {
+		names = append(names, node.Name)
+		percents = append(percents, node.PercentOfParent)
+	}
+	if want := []string{"Frame", "Update", "Physics"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("path = %v, want %v", names, want)
+	}
+	if want := []float64{100, 20, 20}; !reflect.DeepEqual(percents, want) {
+		t.Errorf("percent of parent = %v, want %v", percents, want)
+	}
+	if path.ThreadDuration != 1100 {
+		t.Errorf("ThreadDuration = %v, want 1.1µs", path.ThreadDuration)
+	}
+	if got := path.PercentOfThread; got < 90.9 || got > 91 {
+		t.Errorf("PercentOfThread = %v, want 1000/1100", got)
+	}
+	if path.TimedOut {
+		t.Error("TimedOut without a budget")
+	}
+}
+
+func TestGetThreadCriticalPathEmptyThread(t *testing.T) {
+	a := newTestAnalyzer(t, &proftest.Profile{Threads: []proftest.Thread{{ID: 1, Name: "Idle"}}})
+	thread, err := a.FindThread("Idle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path := a.GetThreadCriticalPath(thread); len(path.Nodes) != 0 {
+		t.Errorf("path has %d nodes, want none", len(path.Nodes))
+	}
+}
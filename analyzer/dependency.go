@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+)
+
+// ThreadNode is a thread in the dependency graph
+type ThreadNode struct {
+	ThreadID   uint64
+	ThreadName string
+	Profiled   bool // False for switch targets that have no thread data in the capture
+}
+
+// ThreadEdge is a directed "yielded to" relationship between two threads
+type ThreadEdge struct {
+	From        uint64
+	To          uint64
+	SwitchCount int
+	TotalTime   time.Duration
+}
+
+// ThreadDependencyGraph describes which threads yield to which via context switches
+type ThreadDependencyGraph struct {
+	Nodes []*ThreadNode
+	Edges []*ThreadEdge
+}
+
+// GetThreadDependencyGraph aggregates context switches into a directed graph from the
+// switching thread to the switch target, weighted by switch count and switched-out time
+func (a *Analyzer) GetThreadDependencyGraph() *ThreadDependencyGraph {
+	graph := &ThreadDependencyGraph{}
+	nodes := make(map[uint64]*ThreadNode)
+	edges := make(map[[2]uint64]*ThreadEdge)
+
+	addNode := func(threadID uint64) {
+		if _, ok := nodes[threadID]; ok {
+			return
+		}
+		node := &ThreadNode{ThreadID: threadID}
+		if thread := a.profile.Thread(threadID); thread != nil {
+			node.ThreadName = thread.ThreadName
+			node.Profiled = true
+		}
+		nodes[threadID] = node
+	}
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		for _, cs := range thread.ContextSwitches {
+			addNode(threadID)
+			addNode(cs.ThreadID)
+
+			key := [2]uint64{threadID, cs.ThreadID}
+			edge, ok := edges[key]
+			if !ok {
+				edge = &ThreadEdge{From: threadID, To: cs.ThreadID}
+				edges[key] = edge
+			}
+			edge.SwitchCount++
+			edge.TotalTime += cs.Duration()
+		}
+	}
+
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].ThreadID < graph.Nodes[j].ThreadID
+	})
+
+	for _, edge := range edges {
+		graph.Edges = append(graph.Edges, edge)
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].SwitchCount != graph.Edges[j].SwitchCount {
+			return graph.Edges[i].SwitchCount > graph.Edges[j].SwitchCount
+		}
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+
+	return graph
+}
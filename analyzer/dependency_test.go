@@ -0,0 +1,52 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestGetThreadDependencyGraph(t *testing.T) {
+	graph := newTestAnalyzer(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Work"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", ContextSwitches: []proftest.ContextSwitch{
+				{ThreadID: 2, Begin: 0, End: 100},
+				{ThreadID: 2, Begin: 200, End: 250},
+				{ThreadID: 99, Begin: 300, End: 310},
+			}, Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}},
+			{ID: 2, Name: "Worker", ContextSwitches: []proftest.ContextSwitch{
+				{ThreadID: 1, Begin: 400, End: 420},
+			}},
+			{ID: 3, Name: "Quiet", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}}},
+		},
+	}).GetThreadDependencyGraph()
+
+	wantNodes := []ThreadNode{
+		{ThreadID: 1, ThreadName: "Main", Profiled: true},
+		{ThreadID: 2, ThreadName: "Worker", Profiled: true},
+		{ThreadID: 99, Profiled: false}, // Switch target without thread data
+	}
+	var nodes []ThreadNode
+	for _, node := range graph.Nodes {
+		nodes = append(nodes, *node)
+	}
+	if !reflect.DeepEqual(nodes, wantNodes) {
+		t.Errorf("nodes = %+v, want %+v", nodes, wantNodes)
+	}
+
+	wantEdges := []ThreadEdge{
+		{From: 1, To: 2, SwitchCount: 2, TotalTime: 150 * time.Nanosecond},
+		{From: 1, To: 99, SwitchCount: 1, TotalTime: 10 * time.Nanosecond},
+		{From: 2, To: 1, SwitchCount: 1, TotalTime: 20 * time.Nanosecond},
+	}
+	var edges []ThreadEdge
+	for _, edge := range graph.Edges {
+		edges = append(edges, *edge)
+	}
+	if !reflect.DeepEqual(edges, wantEdges) {
+		t.Errorf("edges = %+v, want %+v", edges, wantEdges)
+	}
+}
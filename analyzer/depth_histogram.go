@@ -0,0 +1,59 @@
+package analyzer
+
+import "github.com/yourusername/easyprofiler-mcp/parser"
+
+// DepthHistogram counts blocks per nesting depth
+type DepthHistogram struct {
+	ThreadID   uint64 // Zero for the histogram over all threads
+	ThreadName string
+	Blocks     int
+	Counts     []int // Blocks at each depth; index 0 counts top-level blocks
+}
+
+// DepthReport is the block depth histogram of every thread and of the whole capture
+type DepthReport struct {
+	Threads []*DepthHistogram
+	Total   *DepthHistogram
+}
+
+// GetBlockCountByDepth counts the blocks at each nesting depth, per thread and
+// overall, to show whether the capture is mostly shallow or deeply nested
+func (a *Analyzer) GetBlockCountByDepth() *DepthReport {
+	report := &DepthReport{Total: &DepthHistogram{}}
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		histogram := &DepthHistogram{ThreadID: threadID, ThreadName: thread.ThreadName}
+
+		walkBlocks(thread.Blocks, func(_ *parser.Block, depth int) {
+			histogram.add(depth)
+			report.Total.add(depth)
+		})
+
+		report.Threads = append(report.Threads, histogram)
+	}
+
+	return report
+}
+
+// add counts one block at depth
+func (h *DepthHistogram) add(depth int) {
+	for len(h.Counts) <= depth {
+		h.Counts = append(h.Counts, 0)
+	}
+	h.Counts[depth]++
+	h.Blocks++
+}
+
+// LevelsCovering returns the fewest nesting levels, counted from the top, that hold
+// at least percent of the blocks: a depth limit that keeps that share of the capture
+func (h *DepthHistogram) LevelsCovering(percent float64) int {
+	covered := 0
+	for depth, count := range h.Counts {
+		covered += count
+		if float64(covered) >= float64(h.Blocks)*percent/100 {
+			return depth + 1
+		}
+	}
+	return len(h.Counts)
+}
@@ -0,0 +1,68 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestGetBlockCountByDepth(t *testing.T) {
+	// On Main, Frame holds Update and Render, and Update holds three Steps; Worker
+	// has two top-level blocks and Idle none
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render", "Step", "Job"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 4, Begin: 10, End: 20},
+				{ID: 4, Begin: 20, End: 30},
+				{ID: 4, Begin: 30, End: 40},
+				{ID: 2, Begin: 10, End: 50},
+				{ID: 3, Begin: 50, End: 90},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 5, Begin: 0, End: 10}, {ID: 5, Begin: 20, End: 30}}},
+			{ID: 3, Name: "Idle"},
+		},
+	}
+	report := newTestAnalyzer(t, p).GetBlockCountByDepth()
+
+	var got []string
+	for _, histogram := range report.Threads {
+		got = append(got, fmt.Sprintf("%d %s %d %v", histogram.ThreadID, histogram.ThreadName, histogram.Blocks, histogram.Counts))
+	}
+	want := []string{"1 Main 6 [1 2 3]", "2 Worker 2 [2]", "3 Idle 0 []"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("threads = %q, want %q", got, want)
+	}
+	if total := report.Total; total.ThreadID != 0 || total.Blocks != 8 || !reflect.DeepEqual(total.Counts, []int{3, 2, 3}) {
+		t.Errorf("total = %+v, want 8 blocks as [3 2 3]", total)
+	}
+}
+
+func TestLevelsCovering(t *testing.T) {
+	shaped := &DepthHistogram{Blocks: 6, Counts: []int{1, 2, 3}}
+
+	tests := []struct {
+		name      string
+		histogram *DepthHistogram
+		percent   float64
+		want      int
+	}{
+		{"none", shaped, 0, 1},
+		{"top level is enough", shaped, 10, 1},
+		{"exactly half", shaped, 50, 2},
+		{"just over half", shaped, 51, 3},
+		{"all", shaped, 100, 3},
+		{"no blocks", &DepthHistogram{}, 95, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.histogram.LevelsCovering(tt.percent); got != tt.want {
+				t.Errorf("LevelsCovering(%v) = %d, want %d", tt.percent, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DurationPoint is one call of a function, or a run of consecutive calls when the
+// series is downsampled
+type DurationPoint struct {
+	Offset      time.Duration // Begin of the (first) call, relative to the capture begin
+	Duration    time.Duration // Call duration, or the mean over the run
+	MaxDuration time.Duration // Longest call in the run; equals Duration for single calls
+	Calls       int
+}
+
+// DurationSeries is a function's call durations in the order the calls began
+type DurationSeries struct {
+	Name        string
+	Mode        TimeMode
+	Calls       int
+	Downsampled bool // Points hold runs of consecutive calls rather than single calls
+
+	// Slope is the least-squares change in call duration per second of capture:
+	// positive when the function gets slower as the capture goes on
+	Slope time.Duration
+
+	// ChangePercent is the fitted duration at the last call relative to the first
+	ChangePercent float64
+
+	Points []*DurationPoint
+}
+
+// GetDurationOverTime returns the duration (measured in mode) of every call of name
+// against its begin time, across all threads, to show whether the function degrades
+// over the capture or spikes periodically. When there are more calls than maxPoints
+// (> 0), consecutive calls are merged into maxPoints runs of equal size. Unclosed
+// blocks are left out: their true duration is unknown.
+func (a *Analyzer) GetDurationOverTime(name string, mode TimeMode, maxPoints int) (*DurationSeries, error) {
+	invocations, err := a.requireInvocations(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []*DurationPoint
+	for _, iv := range invocations {
+		if iv.Block.Unclosed {
+			continue
+		}
+		duration := a.blockTime(iv.Block, mode)
+		points = append(points, &DurationPoint{
+			Offset:      a.CaptureOffset(iv.Block.Begin),
+			Duration:    duration,
+			MaxDuration: duration,
+			Calls:       1,
+		})
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("function '%s' has no closed calls", name)
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Offset < points[j].Offset
+	})
+
+	series := &DurationSeries{
+		Name:   name,
+		Mode:   mode,
+		Calls:  len(points),
+		Points: points,
+	}
+	series.Slope, series.ChangePercent = fitDurationTrend(points)
+
+	if maxPoints > 0 && len(points) > maxPoints {
+		series.Points = downsampleDurations(points, maxPoints)
+		series.Downsampled = true
+	}
+
+	return series, nil
+}
+
+// fitDurationTrend fits call duration against begin offset by least squares and
+// returns the slope per second and the fitted change from the first call to the last
+func fitDurationTrend(points []*DurationPoint) (time.Duration, float64) {
+	n := float64(len(points))
+	var sumX, sumY float64
+	for _, p := range points {
+		sumX += float64(p.Offset)
+		sumY += float64(p.Duration)
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var covariance, variance float64
+	for _, p := range points {
+		dx := float64(p.Offset) - meanX
+		covariance += dx * (float64(p.Duration) - meanY)
+		variance += dx * dx
+	}
+	if variance == 0 {
+		return 0, 0
+	}
+
+	slope := covariance / variance // Nanoseconds of duration per nanosecond of capture
+	first := meanY + slope*(float64(points[0].Offset)-meanX)
+	last := meanY + slope*(float64(points[len(points)-1].Offset)-meanX)
+
+	change := 0.0
+	if first > 0 {
+		change = (last - first) / first * 100
+	}
+	return time.Duration(slope * float64(time.Second)), change
+}
+
+// downsampleDurations merges time-ordered points into count runs of (nearly) equal
+// size, each keeping its first offset, mean duration and longest call
+func downsampleDurations(points []*DurationPoint, count int) []*DurationPoint {
+	result := make([]*DurationPoint, count)
+	for i := range result {
+		run := points[i*len(points)/count : (i+1)*len(points)/count]
+
+		merged := &DurationPoint{Offset: run[0].Offset, Calls: len(run)}
+		var total time.Duration
+		for _, p := range run {
+			total += p.Duration
+			merged.MaxDuration = max(merged.MaxDuration, p.MaxDuration)
+		}
+		merged.Duration = total / time.Duration(len(run))
+		result[i] = merged
+	}
+	return result
+}
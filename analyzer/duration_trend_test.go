@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// tickProfile runs Tick every 100ns for the given durations, alternating between
+// Main and Worker. Each call holds a 5ns Child.
+func tickProfile(durations ...uint64) *proftest.Profile {
+	p := &proftest.Profile{
+		Begin:       0,
+		Descriptors: proftest.Descriptors("Tick", "Child"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main"}, {ID: 2, Name: "Worker"}},
+	}
+	for i, duration := range durations {
+		begin := uint64(i) * 100
+		thread := &p.Threads[i%2]
+		thread.Blocks = append(thread.Blocks,
+			proftest.Block{ID: 2, Begin: begin, End: begin + 5},
+			proftest.Block{ID: 1, Begin: begin, End: begin + duration},
+		)
+		p.End = begin + duration
+	}
+	return p
+}
+
+func TestGetDurationOverTime(t *testing.T) {
+	ramp := tickProfile(10, 15, 20, 25, 30, 35, 40, 45, 50, 55)
+
+	tests := []struct {
+		name        string
+		profile     *proftest.Profile
+		mode        TimeMode
+		maxPoints   int
+		slope       time.Duration
+		change      string
+		downsampled bool
+		want        []string // "offset duration max calls"
+	}{
+		{
+			name:    "ramp",
+			profile: ramp,
+			mode:    Inclusive,
+			slope:   50 * time.Millisecond, // 5ns more every 100ns
+			change:  "450.00",
+			want: []string{"0s 10ns 10ns 1", "100ns 15ns 15ns 1", "200ns 20ns 20ns 1", "300ns 25ns 25ns 1", "400ns 30ns 30ns 1",
+				"500ns 35ns 35ns 1", "600ns 40ns 40ns 1", "700ns 45ns 45ns 1", "800ns 50ns 50ns 1", "900ns 55ns 55ns 1"},
+		},
+		{
+			name:        "downsampled",
+			profile:     ramp,
+			mode:        Inclusive,
+			maxPoints:   3,
+			slope:       50 * time.Millisecond,
+			change:      "450.00",
+			downsampled: true,
+			want:        []string{"0s 15ns 20ns 3", "300ns 30ns 35ns 3", "600ns 47ns 55ns 4"},
+		},
+		{
+			name:      "as many points as calls",
+			profile:   tickProfile(10, 15, 20),
+			mode:      Inclusive,
+			maxPoints: 3,
+			slope:     50 * time.Millisecond,
+			change:    "100.00",
+			want:      []string{"0s 10ns 10ns 1", "100ns 15ns 15ns 1", "200ns 20ns 20ns 1"},
+		},
+		{
+			name:    "exclusive",
+			profile: tickProfile(10, 15, 20),
+			mode:    Exclusive,
+			slope:   50 * time.Millisecond,
+			change:  "200.00",
+			want:    []string{"0s 5ns 5ns 1", "100ns 10ns 10ns 1", "200ns 15ns 15ns 1"},
+		},
+		{
+			name:    "flat",
+			profile: tickProfile(20, 20, 20),
+			mode:    Inclusive,
+			change:  "0.00",
+			want:    []string{"0s 20ns 20ns 1", "100ns 20ns 20ns 1", "200ns 20ns 20ns 1"},
+		},
+		{
+			name:    "single call",
+			profile: tickProfile(20),
+			mode:    Inclusive,
+			change:  "0.00",
+			want:    []string{"0s 20ns 20ns 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series, err := newTestAnalyzer(t, tt.profile).GetDurationOverTime("Tick", tt.mode, tt.maxPoints)
+			if err != nil {
+				t.Fatalf("GetDurationOverTime: %v", err)
+			}
+			calls := (len(tt.profile.Threads[0].Blocks) + len(tt.profile.Threads[1].Blocks)) / 2 // Tick and Child per call
+			if series.Name != "Tick" || series.Mode != tt.mode || series.Calls != calls {
+				t.Errorf("series of %d %s calls in %v, want %d Tick calls in %v", series.Calls, series.Name, series.Mode, calls, tt.mode)
+			}
+			if series.Slope != tt.slope || fmt.Sprintf("%.2f", series.ChangePercent) != tt.change || series.Downsampled != tt.downsampled {
+				t.Errorf("slope %v, change %.2f%%, downsampled %t; want %v, %s%%, %t",
+					series.Slope, series.ChangePercent, series.Downsampled, tt.slope, tt.change, tt.downsampled)
+			}
+
+			var got []string
+			for _, point := range series.Points {
+				got = append(got, fmt.Sprintf("%v %v %v %d", point.Offset, point.Duration, point.MaxDuration, point.Calls))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("points = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDurationOverTimeErrors(t *testing.T) {
+	// Tick's only call never closes
+	unclosed := &proftest.Profile{
+		Begin:       0,
+		End:         1000,
+		Descriptors: proftest.Descriptors("Tick"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 100, End: 0}}}},
+	}
+
+	tests := []struct {
+		name    string
+		profile *proftest.Profile
+		fn      string
+	}{
+		{"unknown function", tickProfile(10), "Render"},
+		{"only unclosed calls", unclosed, "Tick"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newTestAnalyzer(t, tt.profile).GetDurationOverTime(tt.fn, Inclusive, 0); err == nil {
+				t.Error("GetDurationOverTime succeeded")
+			}
+		})
+	}
+}
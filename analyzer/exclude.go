@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+)
+
+// matchNamePattern reports whether name matches pattern, where '*' matches any run
+// of characters and everything else matches itself. Brackets and other glob syntax
+// are literal, as they're common in C++ names (operator[], templates).
+func matchNamePattern(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return name == pattern
+	}
+
+	// The first and last parts are anchored; the ones between match leftmost
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return len(name) >= len(last) && strings.HasSuffix(name, last)
+}
+
+// matchesAnyPattern reports whether name matches one of patterns
+func matchesAnyPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchNamePattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetHotspotsExcluding is GetHotspotsWithMode leaving out the functions whose names
+// match any of exclude (see matchNamePattern), e.g. a main loop known to dominate.
+// Functions are removed before ranking, so the next ones move up to fill limit.
+func (a *Analyzer) GetHotspotsExcluding(limit int, mode TimeMode, exclude []string) []*BlockInfo {
+	return a.GetHotspotsBy(limit, mode, ByCallSite, exclude)
+}
+
+// GetHotspotsBy is GetHotspotsExcluding aggregating at granularity. Under ByFile the
+// entries, and the names exclude is matched against, are source files.
+func (a *Analyzer) GetHotspotsBy(limit int, mode TimeMode, granularity Granularity, exclude []string) []*BlockInfo {
+	hotspots, _ := a.GetHotspotsByContext(context.Background(), limit, mode, granularity, exclude)
+	return hotspots
+}
+
+// GetHotspotsByContext is GetHotspotsBy giving up when ctx is done. It then ranks
+// the blocks aggregated so far, in thread ID order, and reports timedOut.
+func (a *Analyzer) GetHotspotsByContext(ctx context.Context, limit int, mode TimeMode, granularity Granularity, exclude []string) (hotspots []*BlockInfo, timedOut bool) {
+	b := newBudget(ctx)
+	hotspots = a.aggregateHotspotsWithin(b, granularity)
+	if len(exclude) > 0 {
+		kept := hotspots[:0]
+		for _, hotspot := range hotspots {
+			if !matchesAnyPattern(exclude, hotspot.Name) {
+				kept = append(kept, hotspot)
+			}
+		}
+		hotspots = kept
+	}
+
+	sortByMode(hotspots, mode)
+
+	if limit > len(hotspots) {
+		limit = len(hotspots)
+	}
+
+	return hotspots[:limit], b.expired
+}
@@ -0,0 +1,90 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestMatchNamePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"Frame", "Frame", true},
+		{"Frame", "FrameEnd", false},
+		{"Engine::*", "Engine::Tick", true},
+		{"Engine::*", "Engine::", true},
+		{"Engine::*", "MyEngine::Tick", false},
+		{"*::Tick", "Engine::Tick", true},
+		{"*::Tick", "Engine::TickAll", false},
+		{"*Tick*", "Engine::TickAll", true},
+		{"Engine::*::Update", "Engine::Physics::Update", true},
+		{"Engine::*::Update", "Engine::Update", false},
+		{"a*a", "a", false},
+		{"a*a", "aa", true},
+		{"*", "", true},
+		{"operator[]", "operator[]", true},
+		{"operator[*]", "operator[]", true},
+		{"vector<int>", "vector<long>", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if got := matchNamePattern(tt.pattern, tt.name); got != tt.want {
+				t.Errorf("matchNamePattern(%q, %q) = %t, want %t", tt.pattern, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetHotspotsExcluding(t *testing.T) {
+	// Frame holds Update, which holds Physics, and Render; Worker runs two Engine functions
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Physics", "Render", "Engine::Tick", "Engine::Idle"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 3, Begin: 10, End: 50},
+				{ID: 2, Begin: 0, End: 60},
+				{ID: 4, Begin: 60, End: 90},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{
+				{ID: 5, Begin: 0, End: 20},
+				{ID: 6, Begin: 20, End: 25},
+			}},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name    string
+		limit   int
+		mode    TimeMode
+		exclude []string
+		want    []string // "name time"
+	}{
+		{"nothing excluded", 3, Inclusive, nil, []string{"Frame 100ns", "Update 60ns", "Physics 40ns"}},
+		{"top function excluded", 3, Inclusive, []string{"Frame"}, []string{"Update 60ns", "Physics 40ns", "Render 30ns"}},
+		{"pattern", 10, Inclusive, []string{"Engine::*"}, []string{"Frame 100ns", "Update 60ns", "Physics 40ns", "Render 30ns"}},
+		{"several patterns", 10, Inclusive, []string{"Frame", "Update", "*::Tick"}, []string{"Physics 40ns", "Render 30ns", "Engine::Idle 5ns"}},
+		{"exclusive", 2, Exclusive, []string{"Physics", "Engine::*"}, []string{"Render 30ns", "Update 20ns"}},
+		{"everything excluded", 10, Inclusive, []string{"*"}, nil},
+		{"no match", 1, Inclusive, []string{"Audio"}, []string{"Frame 100ns"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, hotspot := range a.GetHotspotsExcluding(tt.limit, tt.mode, tt.exclude) {
+				got = append(got, fmt.Sprintf("%s %v", hotspot.Name, tt.mode.Of(hotspot)))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hotspots = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
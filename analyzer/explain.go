@@ -0,0 +1,129 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// explainListLimit is the number of callers, callees and invocations in an explanation
+const explainListLimit = 5
+
+// IssueExplanation gives the context needed to act on a single performance issue
+type IssueExplanation struct {
+	Issue            *PerformanceIssue
+	Summary          *BlockInfo   // Aggregate totals for the offending function (nil for thread-level issues)
+	Callers          []*CallEdge  // Functions calling the offending function
+	Callees          []*CallEdge  // Functions the offending function calls
+	WorstInvocations []*BlockInfo // Slowest individual calls of the offending function
+	Thread           *ThreadStats // Statistics of the affected thread, if the issue names one
+	Suggestion       string
+}
+
+// ExplainIssue gathers the call tree context, worst invocations and thread statistics for an issue
+func (a *Analyzer) ExplainIssue(issue *PerformanceIssue) *IssueExplanation {
+	explanation := &IssueExplanation{
+		Issue:      issue,
+		Suggestion: issue.Suggestion,
+	}
+
+	if issue.Function != "" {
+		for _, hotspot := range a.aggregateHotspots() {
+			if hotspot.Name != issue.Function {
+				continue
+			}
+			if explanation.Summary == nil {
+				copied := *hotspot
+				explanation.Summary = &copied
+				continue
+			}
+			// The same name at several call sites is summarized together
+			explanation.Summary.Duration += hotspot.Duration
+			explanation.Summary.SelfDuration += hotspot.SelfDuration
+			explanation.Summary.CallCount += hotspot.CallCount
+		}
+		if explanation.Summary != nil && explanation.Summary.CallCount > 0 {
+			explanation.Summary.AvgDuration = explanation.Summary.Duration / time.Duration(explanation.Summary.CallCount)
+		}
+
+		explanation.Callers = limitEdges(a.GetCallers(issue.Function))
+		explanation.Callees = limitEdges(a.GetCallees(issue.Function))
+		explanation.WorstInvocations = a.worstInvocations(issue.Function, explainListLimit)
+	}
+
+	if issue.ThreadID != 0 {
+		for _, stats := range a.GetThreadStatistics() {
+			if stats.ThreadID == issue.ThreadID {
+				explanation.Thread = stats
+				break
+			}
+		}
+	}
+
+	return explanation
+}
+
+// FindIssue returns the issue at the 1-based index of AnalyzePerformanceIssues, or the
+// first issue matching issueType and location when index is 0
+func (a *Analyzer) FindIssue(index int, issueType, location string) (*PerformanceIssue, error) {
+	return a.FindIssueWithOptions(DefaultIssueOptions(), index, issueType, location)
+}
+
+// FindIssueWithOptions is FindIssue on the issues detected with the given options, so
+// that index matches the numbering of AnalyzePerformanceIssuesWithOptions
+func (a *Analyzer) FindIssueWithOptions(options IssueOptions, index int, issueType, location string) (*PerformanceIssue, error) {
+	issues := a.AnalyzePerformanceIssuesWithOptions(options)
+
+	if index > 0 {
+		if index > len(issues) {
+			return nil, fmt.Errorf("issue %d not found: %d issues detected", index, len(issues))
+		}
+		return issues[index-1], nil
+	}
+
+	for _, issue := range issues {
+		if issue.Type == issueType && (location == "" || issue.Location == location) {
+			return issue, nil
+		}
+	}
+	return nil, fmt.Errorf("no '%s' issue found at '%s'", issueType, location)
+}
+
+// worstInvocations returns the limit slowest calls of name
+func (a *Analyzer) worstInvocations(name string, limit int) []*BlockInfo {
+	invocations := a.GetInvocations(name)
+	sort.Slice(invocations, func(i, j int) bool {
+		di, dj := invocations[i].Block.Duration(), invocations[j].Block.Duration()
+		if di != dj {
+			return di > dj
+		}
+		if invocations[i].ThreadID != invocations[j].ThreadID {
+			return invocations[i].ThreadID < invocations[j].ThreadID
+		}
+		return invocations[i].Block.Begin < invocations[j].Block.Begin
+	})
+
+	result := make([]*BlockInfo, 0, min(limit, len(invocations)))
+	for _, invocation := range invocations[:min(limit, len(invocations))] {
+		_, file, line := a.resolveBlock(invocation.Block)
+		result = append(result, &BlockInfo{
+			Name:         name,
+			File:         file,
+			Line:         line,
+			Duration:     invocation.Block.Duration(),
+			SelfDuration: a.selfTime(invocation.Block),
+			CallCount:    1,
+			ThreadID:     invocation.ThreadID,
+			ThreadName:   invocation.ThreadName,
+			Depth:        int(invocation.Block.Depth),
+			Begin:        invocation.Block.Begin,
+			End:          invocation.Block.End,
+		})
+	}
+	return result
+}
+
+// limitEdges truncates edges to explainListLimit entries
+func limitEdges(edges []*CallEdge) []*CallEdge {
+	return edges[:min(len(edges), explainListLimit)]
+}
@@ -0,0 +1,134 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// explainProfile has one long Load call, and six shorter ones, inside frames on Main
+func explainProfile() *proftest.Profile {
+	ms := uint64(time.Millisecond)
+	blocks := []proftest.Block{
+		{ID: 2, Begin: 10 * ms, End: 160 * ms},
+		{ID: 1, Begin: 0, End: 200 * ms},
+	}
+	for i := uint64(0); i < 6; i++ {
+		begin := (200 + 10*i) * ms
+		blocks = append(blocks, proftest.Block{ID: 2, Begin: begin, End: begin + (i+1)*ms})
+	}
+	return &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Load"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: blocks},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 2 * ms}}},
+		},
+	}
+}
+
+func TestFindIssue(t *testing.T) {
+	a := newTestAnalyzer(t, explainProfile())
+	count := len(a.AnalyzePerformanceIssues())
+
+	tests := []struct {
+		name      string
+		index     int
+		issueType string
+		location  string
+		wantErr   bool
+	}{
+		{"first by index", 1, "", "", false},
+		{"last by index", count, "", "", false},
+		{"past the last", count + 1, "", "", true},
+		{"by type", 0, "Long Blocking Operation", "", false},
+		{"by type and location", 0, "Long Blocking Operation", "Load.cpp:20", false},
+		{"wrong location", 0, "Long Blocking Operation", "Render.cpp:10", true},
+		{"unknown type", 0, "Cosmic Rays", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issue, err := a.FindIssue(tt.index, tt.issueType, tt.location)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FindIssue error = %v, want error %t", err, tt.wantErr)
+			}
+			if err == nil && tt.issueType != "" && issue.Type != tt.issueType {
+				t.Errorf("found a %q issue, want %q", issue.Type, tt.issueType)
+			}
+		})
+	}
+}
+
+func TestFindIssueWithOptions(t *testing.T) {
+	a := newTestAnalyzer(t, explainProfile())
+
+	fragmented := DefaultIssueOptions()
+	fragmented.FragmentedMinCalls = 5
+	fragmented.FragmentedMaxAvg = 100 * time.Millisecond
+	lowCutoffs := DefaultIssueOptions()
+	lowCutoffs.Cutoffs = SeverityCutoffs{High: 1, Medium: 0.5}
+
+	tests := []struct {
+		name    string
+		options IssueOptions
+	}{
+		{"defaults", DefaultIssueOptions()},
+		{"cutoffs", lowCutoffs},
+		{"fragmented work", fragmented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := a.AnalyzePerformanceIssuesWithOptions(tt.options)
+			for i, want := range issues {
+				issue, err := a.FindIssueWithOptions(tt.options, i+1, "", "")
+				if err != nil {
+					t.Fatalf("issue %d: %v", i+1, err)
+				}
+				if issue.Type != want.Type || issue.Location != want.Location || issue.Severity != want.Severity {
+					t.Errorf("issue %d = %s %s at %s, want %s %s at %s", i+1, issue.Severity, issue.Type, issue.Location, want.Severity, want.Type, want.Location)
+				}
+			}
+			if _, err := a.FindIssueWithOptions(tt.options, len(issues)+1, "", ""); err == nil {
+				t.Errorf("found issue %d of %d", len(issues)+1, len(issues))
+			}
+		})
+	}
+
+	if issue, err := a.FindIssueWithOptions(fragmented, 0, "Fragmented Work", ""); err != nil || issue.Function != "Load" {
+		t.Errorf("Fragmented Work = %+v, %v; want Load", issue, err)
+	}
+}
+
+func TestExplainIssue(t *testing.T) {
+	a := newTestAnalyzer(t, explainProfile())
+	issue, err := a.FindIssue(0, "Long Blocking Operation", "Load.cpp:20")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explanation := a.ExplainIssue(issue)
+
+	if summary := explanation.Summary; summary == nil || summary.CallCount != 8 || summary.Duration != 173*time.Millisecond {
+		t.Errorf("summary = %+v, want 8 calls taking 173ms", summary)
+	}
+	if got := edgeStrings(explanation.Callers); len(got) != 2 || got[0] != "Frame×1=150ms" || got[1] != "(thread root)×7=23ms" {
+		t.Errorf("callers = %v", got)
+	}
+	if len(explanation.Callees) != 0 {
+		t.Errorf("callees = %v, want none", edgeStrings(explanation.Callees))
+	}
+
+	var worst []time.Duration
+	for _, invocation := range explanation.WorstInvocations {
+		worst = append(worst, invocation.Duration)
+	}
+	if len(worst) != explainListLimit || worst[0] != 150*time.Millisecond || worst[explainListLimit-1] != 3*time.Millisecond {
+		t.Errorf("worst invocations = %v, want the %d slowest from 150ms to 3ms", worst, explainListLimit)
+	}
+
+	if explanation.Thread == nil || explanation.Thread.ThreadName != "Main" {
+		t.Errorf("thread = %+v, want Main", explanation.Thread)
+	}
+}
@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/google/pprof/profile"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// pprofBuilder accumulates functions, locations and samples while walking the call tree
+type pprofBuilder struct {
+	analyzer  *Analyzer
+	prof      *profile.Profile
+	locations map[string]*profile.Location
+	samples   map[string]*profile.Sample
+}
+
+// BuildPprof converts the call tree into a pprof profile. Each descriptor becomes a
+// function with a single location, and each distinct call stack per thread becomes
+// a sample valued by invocation count and summed self time in nanoseconds.
+func (a *Analyzer) BuildPprof() (*profile.Profile, error) {
+	builder := &pprofBuilder{
+		analyzer: a,
+		prof: &profile.Profile{
+			SampleType: []*profile.ValueType{
+				{Type: "samples", Unit: "count"},
+				{Type: "self_time", Unit: "nanoseconds"},
+			},
+			PeriodType:    &profile.ValueType{Type: "self_time", Unit: "nanoseconds"},
+			Period:        1,
+			DurationNanos: a.profile.GetTotalDuration().Nanoseconds(),
+		},
+		locations: make(map[string]*profile.Location),
+		samples:   make(map[string]*profile.Sample),
+	}
+
+	// Walk threads in ID order so the output is deterministic
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		builder.walk(thread)
+	}
+
+	if err := builder.prof.CheckValid(); err != nil {
+		return nil, fmt.Errorf("invalid pprof profile: %w", err)
+	}
+	return builder.prof, nil
+}
+
+// ExportPprof writes the call tree as a gzip-compressed pprof protobuf
+func (a *Analyzer) ExportPprof(w io.Writer) (*profile.Profile, error) {
+	prof, err := a.BuildPprof()
+	if err != nil {
+		return nil, err
+	}
+	if err := prof.Write(w); err != nil {
+		return nil, fmt.Errorf("failed to write pprof profile: %w", err)
+	}
+	return prof, nil
+}
+
+// walk adds a sample for every block of thread, keyed by its full stack so identical
+// stacks merge
+func (b *pprofBuilder) walk(thread *parser.ThreadData) {
+	var path []*profile.Location // Locations of the current block and its ancestors, root first
+	walkBlocks(thread.Blocks, func(block *parser.Block, depth int) {
+		path = append(path[:depth], b.location(block))
+
+		// pprof stacks are leaf-first
+		current := make([]*profile.Location, len(path))
+		for i, location := range path {
+			current[len(path)-1-i] = location
+		}
+
+		key := b.sampleKey(thread.ThreadID, current)
+		sample, ok := b.samples[key]
+		if !ok {
+			sample = &profile.Sample{
+				Location: current,
+				Value:    []int64{0, 0},
+				Label:    map[string][]string{"thread": {thread.ThreadName}},
+				NumLabel: map[string][]int64{"thread_id": {int64(thread.ThreadID)}},
+			}
+			b.samples[key] = sample
+			b.prof.Sample = append(b.prof.Sample, sample)
+		}
+		sample.Value[0]++
+		sample.Value[1] += b.analyzer.selfTime(block).Nanoseconds()
+	})
+}
+
+// location returns the location for the block's function, creating it on first use
+func (b *pprofBuilder) location(block *parser.Block) *profile.Location {
+	name, file, line := b.analyzer.resolveBlock(block)
+	key := b.analyzer.aggregationKey(block, name)
+
+	if location, ok := b.locations[key]; ok {
+		return location
+	}
+
+	function := &profile.Function{
+		ID:         uint64(len(b.prof.Function) + 1),
+		Name:       name,
+		SystemName: name,
+		Filename:   file,
+		StartLine:  int64(line),
+	}
+	b.prof.Function = append(b.prof.Function, function)
+
+	location := &profile.Location{
+		ID:   uint64(len(b.prof.Location) + 1),
+		Line: []profile.Line{{Function: function, Line: int64(line)}},
+	}
+	b.prof.Location = append(b.prof.Location, location)
+	b.locations[key] = location
+
+	return location
+}
+
+// sampleKey identifies a stack on a thread
+func (b *pprofBuilder) sampleKey(threadID uint64, stack []*profile.Location) string {
+	var key strings.Builder
+	key.WriteString(strconv.FormatUint(threadID, 10))
+	for _, location := range stack {
+		key.WriteByte(':')
+		key.WriteString(strconv.FormatUint(location.ID, 10))
+	}
+	return key.String()
+}
@@ -0,0 +1,80 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestExportPprofParsesBack(t *testing.T) {
+	a := newTestAnalyzer(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 100, End: 500},
+				{ID: 1, Begin: 0, End: 1000},
+				{ID: 2, Begin: 1100, End: 1200},
+				{ID: 1, Begin: 1000, End: 2000},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{
+				{ID: 2, Begin: 0, End: 300},
+			}},
+		},
+	})
+
+	var buf bytes.Buffer
+	exported, err := a.ExportPprof(&buf)
+	if err != nil {
+		t.Fatalf("ExportPprof: %v", err)
+	}
+
+	prof, err := profile.Parse(&buf)
+	if err != nil {
+		t.Fatalf("profile.Parse: %v", err)
+	}
+	if err := prof.CheckValid(); err != nil {
+		t.Fatalf("parsed profile is invalid: %v", err)
+	}
+
+	wantTypes := []string{"samples/count", "self_time/nanoseconds"}
+	var types []string
+	for _, sampleType := range prof.SampleType {
+		types = append(types, sampleType.Type+"/"+sampleType.Unit)
+	}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("sample types = %v, want %v", types, wantTypes)
+	}
+	if prof.DurationNanos != 2000 {
+		t.Errorf("DurationNanos = %d, want 2000", prof.DurationNanos)
+	}
+	if len(prof.Function) != 2 || len(prof.Sample) != len(exported.Sample) {
+		t.Errorf("parsed %d functions and %d samples, exported %d and %d",
+			len(prof.Function), len(prof.Sample), len(exported.Function), len(exported.Sample))
+	}
+
+	// Stacks are leaf-first; identical stacks on a thread merge into one sample
+	var got []string
+	for _, sample := range prof.Sample {
+		var frames []string
+		for _, location := range sample.Location {
+			line := location.Line[0]
+			frames = append(frames, line.Function.Name+"@"+line.Function.Filename)
+		}
+		got = append(got, fmt.Sprintf("%s %s %v", sample.Label["thread"][0], strings.Join(frames, "<"), sample.Value))
+	}
+	sort.Strings(got)
+	want := []string{
+		"Main Frame@Frame.cpp [2 1500]",
+		"Main Update@Update.cpp<Frame@Frame.cpp [2 500]",
+		"Worker Update@Update.cpp [1 300]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("samples =\n%v\nwant\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,139 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// speedscopeSchema is the URL speedscope uses to recognize its file format
+const speedscopeSchema = "https://www.speedscope.app/file-format-schema.json"
+
+// SpeedscopeFile is the top level of a speedscope JSON document
+type SpeedscopeFile struct {
+	Schema   string              `json:"$schema"`
+	Shared   SpeedscopeShared    `json:"shared"`
+	Profiles []SpeedscopeProfile `json:"profiles"`
+	Name     string              `json:"name,omitempty"`
+	Exporter string              `json:"exporter,omitempty"`
+}
+
+// SpeedscopeShared holds the frames referenced by index from profile events
+type SpeedscopeShared struct {
+	Frames []SpeedscopeFrame `json:"frames"`
+}
+
+// SpeedscopeFrame is a single function
+type SpeedscopeFrame struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+	Line int32  `json:"line,omitempty"`
+}
+
+// SpeedscopeProfile is an evented profile of one thread
+type SpeedscopeProfile struct {
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Unit       string            `json:"unit"`
+	StartValue uint64            `json:"startValue"`
+	EndValue   uint64            `json:"endValue"`
+	Events     []SpeedscopeEvent `json:"events"`
+}
+
+// SpeedscopeEvent opens ("O") or closes ("C") a frame at a time
+type SpeedscopeEvent struct {
+	Type  string `json:"type"`
+	Frame int    `json:"frame"`
+	At    uint64 `json:"at"`
+}
+
+// BuildSpeedscope converts every thread's blocks into a speedscope evented profile
+// in time order, without aggregation, so speedscope's "time order" view shows the
+// exact sequence of calls. Times are nanoseconds from the capture begin. Blocks that
+// start before their predecessor's events or outlive their parent are clamped so
+// events stay ordered and properly nested, as speedscope requires.
+func (a *Analyzer) BuildSpeedscope(name string) *SpeedscopeFile {
+	file := &SpeedscopeFile{
+		Schema:   speedscopeSchema,
+		Name:     name,
+		Exporter: "easyprofiler-mcp",
+	}
+	frames := make(map[string]int)
+
+	frameIndex := func(block *parser.Block) int {
+		blockName, blockFile, line := a.resolveBlock(block)
+		key := a.aggregationKey(block, blockName)
+		if index, ok := frames[key]; ok {
+			return index
+		}
+		index := len(file.Shared.Frames)
+		frame := SpeedscopeFrame{Name: blockName, Line: line}
+		if blockFile != UnknownFileLabel {
+			frame.File = blockFile
+		}
+		file.Shared.Frames = append(file.Shared.Frames, frame)
+		frames[key] = index
+		return index
+	}
+
+	end := uint64(a.profile.GetTotalDuration())
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		profile := SpeedscopeProfile{
+			Type:     "evented",
+			Name:     fmt.Sprintf("%s (%d)", thread.ThreadName, threadID),
+			Unit:     "nanoseconds",
+			EndValue: end,
+			Events:   []SpeedscopeEvent{},
+		}
+
+		type openFrame struct {
+			frame int
+			end   uint64
+		}
+		var open []openFrame
+		var last uint64
+
+		closeTo := func(depth int) {
+			for len(open) > depth {
+				top := open[len(open)-1]
+				open = open[:len(open)-1]
+				last = max(last, top.end)
+				profile.Events = append(profile.Events, SpeedscopeEvent{Type: "C", Frame: top.frame, At: last})
+			}
+		}
+
+		walkBlocks(thread.Blocks, func(block *parser.Block, depth int) {
+			closeTo(depth)
+
+			begin := max(last, uint64(a.CaptureOffset(block.Begin)))
+			blockEnd := max(begin, uint64(a.CaptureOffset(block.End)))
+			if len(open) > 0 {
+				blockEnd = min(blockEnd, open[len(open)-1].end)
+				begin = min(begin, blockEnd)
+			}
+			last = begin
+
+			frame := frameIndex(block)
+			profile.Events = append(profile.Events, SpeedscopeEvent{Type: "O", Frame: frame, At: begin})
+			open = append(open, openFrame{frame: frame, end: blockEnd})
+		})
+		closeTo(0)
+
+		profile.EndValue = max(profile.EndValue, last)
+		file.Profiles = append(file.Profiles, profile)
+	}
+
+	return file
+}
+
+// ExportSpeedscope writes the time-ordered speedscope JSON document
+func (a *Analyzer) ExportSpeedscope(w io.Writer, name string) (*SpeedscopeFile, error) {
+	file := a.BuildSpeedscope(name)
+	if err := json.NewEncoder(w).Encode(file); err != nil {
+		return nil, fmt.Errorf("failed to write speedscope profile: %w", err)
+	}
+	return file, nil
+}
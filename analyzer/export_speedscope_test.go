@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// checkSpeedscopeEvents verifies what speedscope requires of an evented profile:
+// events in time order within the profile's range, each close matching the most
+// recent open frame, and no frame left open
+func checkSpeedscopeEvents(t *testing.T, profile SpeedscopeProfile) {
+	t.Helper()
+	var open []int
+	var last uint64
+	for i, event := range profile.Events {
+		if event.At < last || event.At < profile.StartValue || event.At > profile.EndValue {
+			t.Fatalf("event %d at %d is out of order or outside %d-%d", i, event.At, profile.StartValue, profile.EndValue)
+		}
+		last = event.At
+		switch event.Type {
+		case "O":
+			open = append(open, event.Frame)
+		case "C":
+			if len(open) == 0 || open[len(open)-1] != event.Frame {
+				t.Fatalf("event %d closes frame %d, but the open frames are %v", i, event.Frame, open)
+			}
+			open = open[:len(open)-1]
+		default:
+			t.Fatalf("event %d has type %q", i, event.Type)
+		}
+	}
+	if len(open) != 0 {
+		t.Fatalf("frames %v are never closed", open)
+	}
+}
+
+func TestBuildSpeedscope(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []proftest.Block
+		want   []string // "O|C frame-name at"
+	}{
+		{
+			name: "nested calls in time order",
+			blocks: []proftest.Block{
+				{ID: 2, Begin: 110, End: 130},
+				{ID: 1, Begin: 100, End: 200},
+				{ID: 2, Begin: 250, End: 260},
+			},
+			want: []string{"O Frame 0", "O Update 10", "C Update 30", "C Frame 100", "O Update 150", "C Update 160"},
+		},
+		{
+			name: "overlapping siblings are clamped",
+			blocks: []proftest.Block{
+				{ID: 1, Begin: 100, End: 160},
+				{ID: 2, Begin: 150, End: 200},
+			},
+			want: []string{"O Frame 0", "C Frame 60", "O Update 60", "C Update 100"},
+		},
+		{
+			name:   "no blocks",
+			blocks: nil,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &proftest.Profile{
+				Begin:       100,
+				End:         300,
+				Descriptors: proftest.Descriptors("Frame", "Update"),
+				Threads:     []proftest.Thread{{ID: 7, Name: "Main", Blocks: tt.blocks}},
+			}
+			file := newTestAnalyzer(t, p).BuildSpeedscope("capture.prof")
+			if file.Schema != speedscopeSchema || file.Name != "capture.prof" || len(file.Profiles) != 1 {
+				t.Fatalf("file = %+v, want one profile named capture.prof", file)
+			}
+
+			profile := file.Profiles[0]
+			if profile.Name != "Main (7)" || profile.Type != "evented" || profile.Unit != "nanoseconds" || profile.EndValue != 200 {
+				t.Errorf("profile header = %+v", profile)
+			}
+			checkSpeedscopeEvents(t, profile)
+
+			var got []string
+			for _, event := range profile.Events {
+				got = append(got, fmt.Sprintf("%s %s %d", event.Type, file.Shared.Frames[event.Frame].Name, event.At))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportSpeedscopeSharesFrames(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads: []proftest.Thread{
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}}},
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 20}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	exported, err := newTestAnalyzer(t, p).ExportSpeedscope(&buf, "capture")
+	if err != nil {
+		t.Fatalf("ExportSpeedscope: %v", err)
+	}
+
+	var decoded SpeedscopeFile
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, exported) {
+		t.Errorf("written document differs from the returned one")
+	}
+	if want := []SpeedscopeFrame{{Name: "Frame", File: "Frame.cpp", Line: 10}}; !reflect.DeepEqual(decoded.Shared.Frames, want) {
+		t.Errorf("frames = %+v, want %+v", decoded.Shared.Frames, want)
+	}
+	if decoded.Profiles[0].Name != "Main (1)" || decoded.Profiles[1].Name != "Worker (2)" {
+		t.Errorf("profiles are not in thread ID order: %s, %s", decoded.Profiles[0].Name, decoded.Profiles[1].Name)
+	}
+}
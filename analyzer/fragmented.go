@@ -0,0 +1,33 @@
+package analyzer
+
+import (
+	"fmt"
+	"time"
+)
+
+// detectFragmentedWork flags functions called a huge number of times with tiny
+// individual durations, where per-call overhead likely dominates and batching helps
+func (a *Analyzer) detectFragmentedWork(minCalls int, maxAvg time.Duration) []*PerformanceIssue {
+	var issues []*PerformanceIssue
+	if minCalls <= 0 {
+		return issues
+	}
+
+	for _, hotspot := range a.aggregateHotspots() {
+		if hotspot.CallCount < minCalls || hotspot.AvgDuration > maxAvg {
+			continue
+		}
+
+		issues = append(issues, &PerformanceIssue{
+			Type:  "Fragmented Work",
+			Score: impactScore(a.captureFraction(float64(hotspot.Duration))),
+			Description: fmt.Sprintf("Function '%s' was called %d times averaging %v (%v total) - per-call overhead may dominate, consider batching",
+				hotspot.Name, hotspot.CallCount, hotspot.AvgDuration, hotspot.Duration),
+			Location: formatLocation(hotspot.File, hotspot.Line),
+			Function: hotspot.Name,
+			Duration: hotspot.Duration,
+		})
+	}
+
+	return issues
+}
@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// fragmentedProfile has 20 Tiny calls of 1µs each and 3 Big calls of 5µs each on Main
+func fragmentedProfile() *proftest.Profile {
+	var blocks []proftest.Block
+	for i := uint64(0); i < 20; i++ {
+		blocks = append(blocks, proftest.Block{ID: 1, Begin: i * 2000, End: i*2000 + 1000})
+	}
+	for i := uint64(0); i < 3; i++ {
+		begin := 40000 + i*10000
+		blocks = append(blocks, proftest.Block{ID: 2, Begin: begin, End: begin + 5000})
+	}
+	return &proftest.Profile{
+		Descriptors: proftest.Descriptors("Tiny", "Big"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+	}
+}
+
+func TestDetectFragmentedWork(t *testing.T) {
+	tests := []struct {
+		name     string
+		minCalls int
+		maxAvg   time.Duration
+		want     []string
+	}{
+		{"disabled", 0, time.Hour, nil},
+		{"negative disables", -1, time.Hour, nil},
+		{"tiny calls", 10, 2 * time.Microsecond, []string{"Tiny"}},
+		{"average at the limit", 20, time.Microsecond, []string{"Tiny"}},
+		{"too few calls", 21, time.Hour, nil},
+		{"averages too long", 3, 500 * time.Nanosecond, nil},
+		{"both", 3, 5 * time.Microsecond, []string{"Tiny", "Big"}},
+	}
+
+	a := newTestAnalyzer(t, fragmentedProfile())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := a.detectFragmentedWork(tt.minCalls, tt.maxAvg)
+			if len(issues) != len(tt.want) {
+				t.Fatalf("got %d issues, want %d", len(issues), len(tt.want))
+			}
+			for _, name := range tt.want {
+				var found *PerformanceIssue
+				for _, issue := range issues {
+					if issue.Function == name {
+						found = issue
+					}
+				}
+				if found == nil {
+					t.Errorf("no issue for %s", name)
+					continue
+				}
+				if found.Type != "Fragmented Work" {
+					t.Errorf("%s issue type = %q", name, found.Type)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzePerformanceIssuesWithOptionsFragmented(t *testing.T) {
+	tests := []struct {
+		name     string
+		minCalls int
+		want     bool
+	}{
+		{"defaults need 10000 calls", DefaultIssueOptions().FragmentedMinCalls, false},
+		{"lowered call count", 10, true},
+	}
+
+	a := newTestAnalyzer(t, fragmentedProfile())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultIssueOptions()
+			options.FragmentedMinCalls = tt.minCalls
+
+			found := false
+			for _, issue := range a.AnalyzePerformanceIssuesWithOptions(options) {
+				if issue.Type == "Fragmented Work" {
+					found = true
+					if issue.Location != "Tiny.cpp:10" || issue.Duration != 20*time.Microsecond {
+						t.Errorf("issue at %s for %v, want Tiny.cpp:10 for 20µs", issue.Location, issue.Duration)
+					}
+					if issue.Severity == "" {
+						t.Error("issue has no severity")
+					}
+				}
+			}
+			if found != tt.want {
+				t.Errorf("Fragmented Work reported = %t, want %t", found, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// minFrameCount is the fewest calls a top-level block needs to be proposed as the frame delimiter
+const minFrameCount = 3
+
+// FrameStats describes the frames of a capture, each frame being one call of the
+// frame delimiter block
+type FrameStats struct {
+	Delimiter    string
+	AutoDetected bool
+	Confidence   float64 // 0-100, how periodic the detected delimiter is; 0 if named by the caller
+	ThreadID     uint64
+	ThreadName   string
+
+	Frames         int
+	AvgDuration    time.Duration
+	MedianDuration time.Duration
+	P95Duration    time.Duration
+	P99Duration    time.Duration
+	MinDuration    time.Duration
+	MaxDuration    time.Duration
+
+	// AvgInterval is the mean time from one frame's begin to the next one's
+	AvgInterval     time.Duration
+	FramesPerSecond float64
+}
+
+// GetFrameStatistics computes frame time statistics from the calls of the frame
+// delimiter block on the thread that calls it most. If delimiter is empty it is
+// chosen by DetectFrameDelimiter.
+func (a *Analyzer) GetFrameStatistics(delimiter string) (*FrameStats, error) {
+	var stats *FrameStats
+	var frames []*parser.Block
+
+	if delimiter == "" {
+		detected, blocks, err := a.DetectFrameDelimiter()
+		if err != nil {
+			return nil, err
+		}
+		stats, frames = detected, blocks
+	} else {
+		invocations, err := a.requireInvocations(delimiter)
+		if err != nil {
+			return nil, err
+		}
+
+		// Frames are sequential, so keep to the one thread calling the delimiter most
+		perThread := make(map[uint64][]*parser.Block)
+		var thread *parser.ThreadData
+		for _, invocation := range invocations {
+			blocks := append(perThread[invocation.ThreadID], invocation.Block)
+			perThread[invocation.ThreadID] = blocks
+			if thread == nil || len(blocks) > len(perThread[thread.ThreadID]) {
+				thread = a.profile.Thread(invocation.ThreadID)
+			}
+		}
+
+		stats = &FrameStats{Delimiter: delimiter, ThreadID: thread.ThreadID, ThreadName: thread.ThreadName}
+		frames = sortedByBegin(perThread[thread.ThreadID])
+	}
+
+	durations := make([]time.Duration, len(frames))
+	total := time.Duration(0)
+	for i, frame := range frames {
+		durations[i] = frame.Duration()
+		total += durations[i]
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	stats.Frames = len(frames)
+	stats.AvgDuration = total / time.Duration(len(frames))
+	stats.MedianDuration = percentile(durations, 50)
+	stats.P95Duration = percentile(durations, 95)
+	stats.P99Duration = percentile(durations, 99)
+	stats.MinDuration = durations[0]
+	stats.MaxDuration = durations[len(durations)-1]
+
+	if len(frames) > 1 {
+		stats.AvgInterval = time.Duration(frames[len(frames)-1].Begin-frames[0].Begin) / time.Duration(len(frames)-1)
+		if stats.AvgInterval > 0 {
+			stats.FramesPerSecond = float64(time.Second) / float64(stats.AvgInterval)
+		}
+	}
+
+	return stats, nil
+}
+
+// DetectFrameDelimiter proposes the frame delimiter: the top-level block of the
+// busiest thread that repeats most often at a regular cadence. Each candidate's
+// confidence is the product of its regularity (one minus the coefficient of
+// variation of the intervals between its calls) and the share of the thread's
+// active span its calls cover, so a block called in a short burst or at erratic
+// times scores low; the candidate with the highest calls times confidence wins.
+// It returns the chosen delimiter's stats with only the detection fields set,
+// together with its calls sorted by begin time.
+func (a *Analyzer) DetectFrameDelimiter() (*FrameStats, []*parser.Block, error) {
+	var busiest *parser.ThreadData
+	var busiestTime time.Duration
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		if busy := a.calculateThreadDuration(thread.Blocks); busiest == nil || busy > busiestTime {
+			busiest, busiestTime = thread, busy
+		}
+	}
+	if busiest == nil || len(busiest.Blocks) == 0 {
+		return nil, nil, fmt.Errorf("no blocks to detect frames from")
+	}
+
+	candidates := make(map[string][]*parser.Block)
+	var names []string
+	for _, block := range sortedByBegin(busiest.Blocks) {
+		name, _, _ := a.resolveBlock(block)
+		if _, seen := candidates[name]; !seen {
+			names = append(names, name)
+		}
+		candidates[name] = append(candidates[name], block)
+	}
+
+	span := float64(busiest.LastBlockEnd - busiest.FirstBlockBegin)
+	var best *FrameStats
+	var bestBlocks []*parser.Block
+	bestScore := 0.0
+	for _, name := range names {
+		blocks := candidates[name]
+		if len(blocks) < minFrameCount {
+			continue
+		}
+
+		confidence := frameRegularity(blocks)
+		if span > 0 {
+			confidence *= float64(blocks[len(blocks)-1].End-blocks[0].Begin) / span
+		}
+		if score := float64(len(blocks)) * confidence; best == nil || score > bestScore {
+			best = &FrameStats{
+				Delimiter:    name,
+				AutoDetected: true,
+				Confidence:   confidence * 100,
+				ThreadID:     busiest.ThreadID,
+				ThreadName:   busiest.ThreadName,
+			}
+			bestBlocks, bestScore = blocks, score
+		}
+	}
+
+	if best == nil {
+		return nil, nil, fmt.Errorf("no top-level block on thread '%s' is called at least %d times; name the frame block explicitly",
+			busiest.ThreadName, minFrameCount)
+	}
+	return best, bestBlocks, nil
+}
+
+// frameRegularity rates how evenly spaced blocks sorted by begin time are, from 0
+// (erratic) to 1 (a fixed cadence)
+func frameRegularity(blocks []*parser.Block) float64 {
+	intervals := make([]float64, len(blocks)-1)
+	mean := 0.0
+	for i := 1; i < len(blocks); i++ {
+		intervals[i-1] = float64(blocks[i].Begin - blocks[i-1].Begin)
+		mean += intervals[i-1]
+	}
+	mean /= float64(len(intervals))
+	if mean == 0 {
+		return 0
+	}
+
+	variance := 0.0
+	for _, interval := range intervals {
+		variance += (interval - mean) * (interval - mean)
+	}
+	variance /= float64(len(intervals))
+
+	return math.Max(0, 1-math.Sqrt(variance)/mean)
+}
@@ -0,0 +1,183 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// blocksAt returns one block of descriptor id and the given length at each begin
+func blocksAt(id uint32, length uint64, begins ...uint64) []proftest.Block {
+	blocks := make([]proftest.Block, len(begins))
+	for i, begin := range begins {
+		blocks[i] = proftest.Block{ID: id, Begin: begin, End: begin + length}
+	}
+	return blocks
+}
+
+func TestDetectFrameDelimiter(t *testing.T) {
+	descriptors := proftest.Descriptors("Frame", "Load", "Tick", "Job")
+
+	tests := []struct {
+		name       string
+		threads    []proftest.Thread
+		want       string // "delimiter thread calls"
+		confidence float64
+		wantErr    string
+	}{
+		{
+			// Load is called as often but only in a burst at the end
+			name: "periodic block beats a burst",
+			threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: append(
+				blocksAt(1, 100, 0, 100, 200, 300, 400),
+				blocksAt(2, 5, 500, 505, 510, 515)...)}},
+			want:       "Frame Main 5",
+			confidence: 100 * 500.0 / 520,
+		},
+		{
+			// Tick is called more often but at erratic times
+			name: "periodic block beats an erratic one",
+			threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: append(
+				blocksAt(3, 5, 0, 10, 300, 310),
+				blocksAt(1, 70, 20, 120, 220)...)}},
+			want:       "Frame Main 3",
+			confidence: 100 * 270.0 / 315,
+		},
+		{
+			name: "busiest thread",
+			threads: []proftest.Thread{
+				{ID: 1, Name: "Main", Blocks: blocksAt(1, 10, 0, 100, 200)},
+				{ID: 2, Name: "Worker", Blocks: blocksAt(4, 50, 0, 100, 200, 300)},
+			},
+			want:       "Job Worker 4",
+			confidence: 100,
+		},
+		{
+			name:    "too few calls",
+			threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocksAt(1, 10, 0, 100)}},
+			wantErr: "no top-level block on thread 'Main' is called at least 3 times",
+		},
+		{
+			name:    "no blocks",
+			threads: []proftest.Thread{{ID: 1, Name: "Main"}},
+			wantErr: "no blocks to detect frames from",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t, &proftest.Profile{Descriptors: descriptors, Threads: tt.threads})
+			stats, blocks, err := a.DetectFrameDelimiter()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DetectFrameDelimiter: %v", err)
+			}
+
+			if got := fmt.Sprintf("%s %s %d", stats.Delimiter, stats.ThreadName, len(blocks)); got != tt.want {
+				t.Errorf("detected %s, want %s", got, tt.want)
+			}
+			if !stats.AutoDetected || math.Abs(stats.Confidence-tt.confidence) > 1e-9 {
+				t.Errorf("auto-detected %t with confidence %v, want true with %v", stats.AutoDetected, stats.Confidence, tt.confidence)
+			}
+			for i := 1; i < len(blocks); i++ {
+				if blocks[i].Begin < blocks[i-1].Begin {
+					t.Fatalf("frames not sorted by begin: %d after %d", blocks[i].Begin, blocks[i-1].Begin)
+				}
+			}
+		})
+	}
+}
+
+func TestFrameRegularity(t *testing.T) {
+	tests := []struct {
+		name   string
+		begins []uint64
+		want   float64
+	}{
+		{"fixed cadence", []uint64{0, 10, 20, 30}, 1},
+		{"slight jitter", []uint64{0, 9, 20, 29, 40}, 1 - 1/10.0 /* std dev 1 over mean 10 */},
+		{"erratic", []uint64{0, 10, 300, 310}, 0},
+		{"same begin", []uint64{5, 5, 5}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t, &proftest.Profile{
+				Descriptors: proftest.Descriptors("Frame"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocksAt(1, 0, tt.begins...)}},
+			})
+			got := frameRegularity(sortedByBegin(a.profile.Thread(1).Blocks))
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("frameRegularity = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFrameStatistics(t *testing.T) {
+	// Main runs four frames of 10..40ns every 100ns; Worker calls Frame only twice
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Job"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 1, Begin: 0, End: 30},
+				{ID: 1, Begin: 100, End: 110},
+				{ID: 1, Begin: 200, End: 240},
+				{ID: 1, Begin: 300, End: 320},
+			}},
+			{ID: 2, Name: "Worker", Blocks: blocksAt(1, 500, 0, 500)},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		delimiter string
+		want      string // "delimiter thread auto frames avg median p95 min max interval fps"
+		wantErr   string
+	}{
+		{"named", "Frame", "Frame Main false 4 25ns 20ns 40ns 10ns 40ns 100ns 1e+07", ""},
+		{"unknown name", "Render", "", "function 'Render' not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := newTestAnalyzer(t, p).GetFrameStatistics(tt.delimiter)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetFrameStatistics: %v", err)
+			}
+			got := fmt.Sprintf("%s %s %t %d %v %v %v %v %v %v %v", stats.Delimiter, stats.ThreadName, stats.AutoDetected, stats.Frames,
+				stats.AvgDuration, stats.MedianDuration, stats.P95Duration, stats.MinDuration, stats.MaxDuration, stats.AvgInterval, stats.FramesPerSecond)
+			if got != tt.want {
+				t.Errorf("stats = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFrameStatisticsAutoDetect(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocksAt(1, 50, 0, 100, 200)}},
+	}
+	stats, err := newTestAnalyzer(t, p).GetFrameStatistics("")
+	if err != nil {
+		t.Fatalf("GetFrameStatistics: %v", err)
+	}
+	if stats.Delimiter != "Frame" || !stats.AutoDetected || stats.Frames != 3 || stats.AvgDuration != 50 || stats.AvgInterval != 100 {
+		t.Errorf("stats = %+v, want 3 auto-detected Frames of 50ns every 100ns", stats)
+	}
+}
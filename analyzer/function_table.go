@@ -0,0 +1,132 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FunctionTableColumn selects the order of the function table
+type FunctionTableColumn int
+
+const (
+	// FunctionsByTotalTime orders functions by inclusive time, largest first. This is the default.
+	FunctionsByTotalTime FunctionTableColumn = iota
+
+	// FunctionsBySelfTime orders functions by exclusive time, largest first
+	FunctionsBySelfTime
+
+	// FunctionsByCallCount orders functions by number of calls, most first
+	FunctionsByCallCount
+
+	// FunctionsByName orders functions alphabetically by name
+	FunctionsByName
+
+	// FunctionsByFile orders functions by source file, then line
+	FunctionsByFile
+
+	// FunctionsByDescriptorID orders functions by descriptor ID, i.e. registration order
+	FunctionsByDescriptorID
+)
+
+// functionTableColumns lists every column, in the order they're documented
+var functionTableColumns = []FunctionTableColumn{
+	FunctionsByTotalTime, FunctionsBySelfTime, FunctionsByCallCount,
+	FunctionsByName, FunctionsByFile, FunctionsByDescriptorID,
+}
+
+// String returns the name accepted by ParseFunctionTableColumn
+func (c FunctionTableColumn) String() string {
+	switch c {
+	case FunctionsBySelfTime:
+		return "self_time"
+	case FunctionsByCallCount:
+		return "call_count"
+	case FunctionsByName:
+		return "name"
+	case FunctionsByFile:
+		return "file"
+	case FunctionsByDescriptorID:
+		return "descriptor_id"
+	default:
+		return "total_time"
+	}
+}
+
+// ParseFunctionTableColumn parses "total_time", "self_time", "call_count", "name",
+// "file" or "descriptor_id"
+func ParseFunctionTableColumn(s string) (FunctionTableColumn, error) {
+	names := make([]string, len(functionTableColumns))
+	for i, column := range functionTableColumns {
+		if column.String() == s {
+			return column, nil
+		}
+		names[i] = column.String()
+	}
+	return FunctionsByTotalTime, fmt.Errorf("unknown function table column '%s' (use %s)", s, strings.Join(names, ", "))
+}
+
+// compare returns a negative number if x sorts before y in this column, a positive
+// one if after, and 0 if the column doesn't tell them apart
+func (c FunctionTableColumn) compare(x, y *BlockIdentity) int {
+	switch c {
+	case FunctionsBySelfTime:
+		return compareDurations(y.SelfDuration, x.SelfDuration)
+	case FunctionsByCallCount:
+		return y.CallCount - x.CallCount
+	case FunctionsByName:
+		return strings.Compare(x.Name(), y.Name())
+	case FunctionsByFile:
+		if cmp := strings.Compare(x.File(), y.File()); cmp != 0 {
+			return cmp
+		}
+		return int(x.Descriptor.Line) - int(y.Descriptor.Line)
+	case FunctionsByDescriptorID:
+		return 0
+	default:
+		return compareDurations(y.Duration, x.Duration)
+	}
+}
+
+// compareDurations returns -1, 0 or 1 as x is shorter than, equal to or longer than y
+func compareDurations(x, y time.Duration) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// GetFunctionTable joins every descriptor with the usage of its blocks: one row per
+// descriptor with at least one call, ordered by column with ties broken by
+// descriptor ID. limit <= 0 returns every row.
+func (a *Analyzer) GetFunctionTable(column FunctionTableColumn, limit int) []*BlockIdentity {
+	ids := a.profile.DescriptorIDs()
+	identities := make(map[uint32]*BlockIdentity, len(ids))
+	for _, id := range ids {
+		identities[id] = &BlockIdentity{Descriptor: a.profile.Descriptor(id)}
+	}
+	a.measureUsage(identities)
+
+	rows := make([]*BlockIdentity, 0, len(identities))
+	for _, identity := range identities {
+		if identity.CallCount > 0 {
+			rows = append(rows, identity)
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if c := column.compare(rows[i], rows[j]); c != 0 {
+			return c < 0
+		}
+		return rows[i].Descriptor.ID < rows[j].Descriptor.ID
+	})
+
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return rows
+}
@@ -0,0 +1,76 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestParseFunctionTableColumn(t *testing.T) {
+	for _, column := range functionTableColumns {
+		if got, err := ParseFunctionTableColumn(column.String()); got != column || err != nil {
+			t.Errorf("ParseFunctionTableColumn(%q) = %v, %v", column.String(), got, err)
+		}
+	}
+	if _, err := ParseFunctionTableColumn("duration"); err == nil {
+		t.Error("ParseFunctionTableColumn accepted an unknown column")
+	}
+}
+
+func TestGetFunctionTable(t *testing.T) {
+	// Frame runs twice around Update and Render; Update also runs on Worker, next to a
+	// recursive Recurse; Unused is declared but never called. Recurse is declared in
+	// A.cpp and Render in Frame.cpp, after Frame.
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render", "Unused", "Recurse"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 10, End: 60},
+				{ID: 3, Begin: 60, End: 95},
+				{ID: 1, Begin: 0, End: 100},
+				{ID: 2, Begin: 210, End: 250},
+				{ID: 1, Begin: 200, End: 260},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{
+				{ID: 5, Begin: 10, End: 20},
+				{ID: 5, Begin: 0, End: 50},
+				{ID: 2, Begin: 60, End: 90},
+			}},
+		},
+	}
+	p.Descriptors[2].File = "Frame.cpp"
+	p.Descriptors[4].File = "A.cpp"
+	a := newTestAnalyzer(t, p)
+
+	// Each row as "name calls total self"
+	frame, update, render, recurse := "Frame 2 160ns 35ns", "Update 3 120ns 120ns", "Render 1 35ns 35ns", "Recurse 2 50ns 50ns"
+
+	tests := []struct {
+		column FunctionTableColumn
+		limit  int
+		want   []string
+	}{
+		{FunctionsByTotalTime, 0, []string{frame, update, recurse, render}},
+		{FunctionsBySelfTime, 0, []string{update, recurse, frame, render}},
+		{FunctionsByCallCount, 0, []string{update, frame, recurse, render}},
+		{FunctionsByName, 0, []string{frame, recurse, render, update}},
+		{FunctionsByFile, 0, []string{recurse, frame, render, update}},
+		{FunctionsByDescriptorID, 0, []string{frame, update, render, recurse}},
+		{FunctionsByTotalTime, 2, []string{frame, update}},
+		{FunctionsByTotalTime, 10, []string{frame, update, recurse, render}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v limit %d", tt.column, tt.limit), func(t *testing.T) {
+			var got []string
+			for _, row := range a.GetFunctionTable(tt.column, tt.limit) {
+				got = append(got, fmt.Sprintf("%s %d %v %v", row.Name(), row.CallCount, row.Duration, row.SelfDuration))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// GanttInterval is one block on a thread's timeline
+type GanttInterval struct {
+	ThreadID     uint64
+	Name         string
+	DescriptorID uint32
+	Depth        int
+	Begin        uint64 // Raw begin timestamp
+	End          uint64 // Raw end timestamp
+}
+
+// GanttFilter restricts the intervals returned by GetGanttIntervals
+type GanttFilter struct {
+	Threads     []*parser.ThreadData // Threads to include (nil = all)
+	WindowBegin uint64               // Raw timestamp; blocks ending at or before it are skipped (0 = capture begin)
+	WindowEnd   uint64               // Raw timestamp; blocks starting at or after it are skipped (0 = capture end)
+	MaxDepth    int                  // Deepest nesting level to include, 0 being top-level (-1 = unlimited)
+}
+
+// GetGanttIntervals returns the raw block intervals overlapping the filter's window,
+// ordered by thread ID and then by begin time (parents before their children)
+func (a *Analyzer) GetGanttIntervals(filter GanttFilter) []*GanttInterval {
+	threads := filter.Threads
+	if threads == nil {
+		for _, threadID := range a.sortedThreadIDs() {
+			thread := a.profile.Thread(threadID)
+			threads = append(threads, thread)
+		}
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].ThreadID < threads[j].ThreadID
+	})
+
+	windowEnd := filter.WindowEnd
+	if windowEnd == 0 {
+		windowEnd = ^uint64(0)
+	}
+
+	var result []*GanttInterval
+	for _, thread := range threads {
+		walkBlocks(thread.Blocks, func(block *parser.Block, _ int) {
+			depth := int(block.Depth)
+			if filter.MaxDepth >= 0 && depth > filter.MaxDepth {
+				return
+			}
+			if block.End <= filter.WindowBegin || block.Begin >= windowEnd {
+				return
+			}
+
+			name, _, _ := a.resolveBlock(block)
+			result = append(result, &GanttInterval{
+				ThreadID:     thread.ThreadID,
+				Name:         name,
+				DescriptorID: block.ID,
+				Depth:        depth,
+				Begin:        block.Begin,
+				End:          block.End,
+			})
+		})
+	}
+
+	return result
+}
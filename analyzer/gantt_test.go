@@ -0,0 +1,49 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestGetGanttIntervals(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Job"),
+		Threads: []proftest.Thread{
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 3, Begin: 50, End: 150}}},
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 10, End: 40},
+				{ID: 1, Begin: 0, End: 100},
+				{ID: 1, Begin: 100, End: 200},
+			}},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name   string
+		filter GanttFilter
+		want   []string
+	}{
+		{"everything", GanttFilter{MaxDepth: -1}, []string{"1 Frame@0 0-100", "1 Update@1 10-40", "1 Frame@0 100-200", "2 Job@0 50-150"}},
+		{"top level", GanttFilter{MaxDepth: 0}, []string{"1 Frame@0 0-100", "1 Frame@0 100-200", "2 Job@0 50-150"}},
+		{"window", GanttFilter{MaxDepth: -1, WindowBegin: 40, WindowEnd: 100}, []string{"1 Frame@0 0-100", "2 Job@0 50-150"}},
+		{"one thread", GanttFilter{MaxDepth: -1, Threads: []*parser.ThreadData{a.profile.Thread(2)}}, []string{"2 Job@0 50-150"}},
+		{"empty window", GanttFilter{MaxDepth: -1, WindowBegin: 300}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, iv := range a.GetGanttIntervals(tt.filter) {
+				got = append(got, fmt.Sprintf("%d %s@%d %d-%d", iv.ThreadID, iv.Name, iv.Depth, iv.Begin, iv.End))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("intervals = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// DefaultGCPattern matches block names commonly used for garbage collection work
+const DefaultGCPattern = `(?i)(^|[^a-z])gc([^a-z]|$)|garbage|collect|stop.?the.?world`
+
+// GCPause is one garbage collection pause: a period during which at least one
+// GC-named block was running. Overlapping GC blocks on several threads (e.g. a
+// parallel collector) form a single pause.
+type GCPause struct {
+	Begin    uint64 // Raw begin timestamp
+	End      uint64 // Raw end timestamp
+	Duration time.Duration
+	Threads  []string // Names of the threads that ran GC blocks during the pause
+}
+
+// GCBucket counts the pauses starting within one time slice of the capture
+type GCBucket struct {
+	Start time.Duration // Offset of the bucket from the capture begin
+	Count int
+	Time  time.Duration
+}
+
+// GCPauseReport lists garbage collection pauses and how they were spread over time
+type GCPauseReport struct {
+	Pattern      string
+	Pauses       []*GCPause // In time order
+	TotalTime    time.Duration
+	LongestPause *GCPause // nil if there were no pauses
+	BucketSize   time.Duration
+	Buckets      []*GCBucket
+}
+
+// GetGCPauses merges blocks whose names match pattern (DefaultGCPattern if empty)
+// into discrete pauses and buckets them by start time. Only the outermost matching
+// block of a nested chain counts.
+func (a *Analyzer) GetGCPauses(pattern string, bucketSize time.Duration) (*GCPauseReport, error) {
+	if pattern == "" {
+		pattern = DefaultGCPattern
+	}
+	matcher, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GC pattern: %w", err)
+	}
+	if bucketSize <= 0 {
+		return nil, fmt.Errorf("bucket size must be positive")
+	}
+	if a.profile.GetTotalDuration()/bucketSize >= maxRateBuckets {
+		return nil, fmt.Errorf("bucket size %v splits the capture into more than %d buckets; use a larger bucket",
+			bucketSize, maxRateBuckets)
+	}
+
+	type gcBlock struct {
+		begin, end uint64
+		thread     string
+	}
+	var blocks []gcBlock
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		matchedDepth := -1
+
+		walkBlocks(thread.Blocks, func(block *parser.Block, depth int) {
+			if matchedDepth >= 0 && depth > matchedDepth {
+				return
+			}
+			matchedDepth = -1
+
+			if name, _, _ := a.resolveBlock(block); matcher.MatchString(name) && block.End > block.Begin {
+				matchedDepth = depth
+				blocks = append(blocks, gcBlock{begin: block.Begin, end: block.End, thread: thread.ThreadName})
+			}
+		})
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].begin < blocks[j].begin
+	})
+
+	report := &GCPauseReport{Pattern: pattern, BucketSize: bucketSize}
+
+	var current *GCPause
+	threads := make(map[string]bool)
+	finish := func() {
+		if current == nil {
+			return
+		}
+		current.Duration = time.Duration(current.End - current.Begin)
+		current.Threads = sortedKeys(threads)
+		report.Pauses = append(report.Pauses, current)
+		report.TotalTime += current.Duration
+		if report.LongestPause == nil || current.Duration > report.LongestPause.Duration {
+			report.LongestPause = current
+		}
+		threads = make(map[string]bool)
+	}
+
+	for _, block := range blocks {
+		if current == nil || block.begin > current.End {
+			finish()
+			current = &GCPause{Begin: block.begin, End: block.end}
+		}
+		current.End = max(current.End, block.end)
+		threads[block.thread] = true
+	}
+	finish()
+
+	captureDuration := a.profile.GetTotalDuration()
+	bucketCount := max(1, int((captureDuration+bucketSize-1)/bucketSize))
+	report.Buckets = make([]*GCBucket, bucketCount)
+	for i := range report.Buckets {
+		report.Buckets[i] = &GCBucket{Start: time.Duration(i) * bucketSize}
+	}
+	for _, pause := range report.Pauses {
+		index := min(int(a.CaptureOffset(pause.Begin)/bucketSize), bucketCount-1)
+		report.Buckets[index].Count++
+		report.Buckets[index].Time += pause.Duration
+	}
+
+	return report, nil
+}
@@ -0,0 +1,106 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// gcProfile is a 3s capture with a GC pause shared by Main and Worker at 100-200ms,
+// another on Main at 1500-1510ms, and a gcd_compute block that isn't GC work
+func gcProfile() *proftest.Profile {
+	ms := uint64(time.Millisecond)
+	return &proftest.Profile{
+		Begin:       0,
+		End:         3000 * ms,
+		Descriptors: proftest.Descriptors("Frame", "GC::Collect", "gc_mark", "GC worker", "Garbage sweep", "gcd_compute"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 3, Begin: 110 * ms, End: 120 * ms},
+				{ID: 2, Begin: 100 * ms, End: 150 * ms},
+				{ID: 1, Begin: 0, End: 1000 * ms},
+				{ID: 5, Begin: 1500 * ms, End: 1510 * ms},
+				{ID: 6, Begin: 2000 * ms, End: 2100 * ms},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 4, Begin: 140 * ms, End: 200 * ms}}},
+		},
+	}
+}
+
+func TestGetGCPauses(t *testing.T) {
+	a := newTestAnalyzer(t, gcProfile())
+
+	tests := []struct {
+		name       string
+		pattern    string
+		bucketSize time.Duration
+		pauses     []string // "begin-end threads"
+		total      time.Duration
+		buckets    []string // "count time"
+		wantErr    bool
+	}{
+		{
+			name:       "default pattern",
+			bucketSize: time.Second,
+			pauses:     []string{"100ms-200ms [Main Worker]", "1.5s-1.51s [Main]"},
+			total:      110 * time.Millisecond,
+			buckets:    []string{"1 100ms", "1 10ms", "0 0s"},
+		},
+		{
+			name:       "custom pattern",
+			pattern:    "^gcd",
+			bucketSize: 2 * time.Second,
+			pauses:     []string{"2s-2.1s [Main]"},
+			total:      100 * time.Millisecond,
+			buckets:    []string{"0 0s", "1 100ms"},
+		},
+		{
+			name:       "no pauses",
+			pattern:    "Audio",
+			bucketSize: time.Second,
+			buckets:    []string{"0 0s", "0 0s", "0 0s"},
+		},
+		{name: "invalid pattern", pattern: "(", bucketSize: time.Second, wantErr: true},
+		{name: "zero bucket", bucketSize: 0, wantErr: true},
+		{name: "too many buckets", bucketSize: time.Nanosecond, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := a.GetGCPauses(tt.pattern, tt.bucketSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetGCPauses error = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var pauses []string
+			var longest time.Duration
+			for _, pause := range report.Pauses {
+				pauses = append(pauses, fmt.Sprintf("%v-%v %v", a.CaptureOffset(pause.Begin), a.CaptureOffset(pause.End), pause.Threads))
+				longest = max(longest, pause.Duration)
+			}
+			if !reflect.DeepEqual(pauses, tt.pauses) {
+				t.Errorf("pauses = %q, want %q", pauses, tt.pauses)
+			}
+			if report.TotalTime != tt.total {
+				t.Errorf("total = %v, want %v", report.TotalTime, tt.total)
+			}
+			if (report.LongestPause == nil) != (len(tt.pauses) == 0) || (report.LongestPause != nil && report.LongestPause.Duration != longest) {
+				t.Errorf("longest pause = %+v, want duration %v", report.LongestPause, longest)
+			}
+
+			var buckets []string
+			for _, bucket := range report.Buckets {
+				buckets = append(buckets, fmt.Sprintf("%d %v", bucket.Count, bucket.Time))
+			}
+			if !reflect.DeepEqual(buckets, tt.buckets) {
+				t.Errorf("buckets = %q, want %q", buckets, tt.buckets)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// Granularity selects which blocks aggregated results group together
+type Granularity int
+
+const (
+	// ByCallSite groups blocks by name and source location. This is the default.
+	ByCallSite Granularity = iota
+
+	// ByName groups blocks by name alone, merging a function's call sites
+	ByName
+
+	// ByDescriptor groups blocks by descriptor ID, merging the runtime names a
+	// descriptor's blocks were given
+	ByDescriptor
+
+	// ByFile groups blocks by source file; entries are named after the file
+	ByFile
+)
+
+// granularities lists every granularity, in the order they're documented
+var granularities = []Granularity{ByCallSite, ByName, ByDescriptor, ByFile}
+
+// String returns the name accepted by ParseGranularity
+func (g Granularity) String() string {
+	switch g {
+	case ByName:
+		return "name"
+	case ByDescriptor:
+		return "descriptor"
+	case ByFile:
+		return "file"
+	default:
+		return "call_site"
+	}
+}
+
+// ParseGranularity parses "call_site", "name", "descriptor" or "file"
+func ParseGranularity(s string) (Granularity, error) {
+	names := make([]string, len(granularities))
+	for i, g := range granularities {
+		if g.String() == s {
+			return g, nil
+		}
+		names[i] = g.String()
+	}
+	return ByCallSite, fmt.Errorf("unknown granularity '%s' (use %s)", s, strings.Join(names, ", "))
+}
+
+// group returns the aggregation key of a block resolved to name, file and line under
+// g, with the name and line its aggregated entry is reported under
+func (a *Analyzer) group(g Granularity, block *parser.Block, name, file string, line int32) (key, groupName string, groupLine int32) {
+	switch g {
+	case ByName:
+		return name, name, line
+	case ByDescriptor:
+		if descriptor := a.profile.Descriptor(block.ID); descriptor != nil && descriptor.Name != "" {
+			name = descriptor.Name
+		}
+		return fmt.Sprintf("#%d", block.ID), name, line
+	case ByFile:
+		return file, file, 0
+	default:
+		return a.aggregationKey(block, name), name, line
+	}
+}
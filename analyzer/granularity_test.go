@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestParseGranularity(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Granularity
+		wantErr bool
+	}{
+		{"call_site", ByCallSite, false},
+		{"name", ByName, false},
+		{"descriptor", ByDescriptor, false},
+		{"file", ByFile, false},
+		{"File", ByCallSite, true},
+		{"", ByCallSite, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseGranularity(tt.input)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGranularity(%q) = %v, %v; want %v, error %t", tt.input, got, err, tt.want, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.input {
+				t.Errorf("String() = %q, want %q", got.String(), tt.input)
+			}
+		})
+	}
+}
+
+// granularityProfile has Tick at two call sites, in engine.cpp and render.cpp, and
+// Load in engine.cpp called under the runtime names x and y
+func granularityProfile() *proftest.Profile {
+	return &proftest.Profile{
+		Descriptors: []proftest.Descriptor{
+			{ID: 1, Name: "Tick", File: "engine.cpp", Line: 10, Type: parser.BlockTypeBlock},
+			{ID: 2, Name: "Tick", File: "render.cpp", Line: 20, Type: parser.BlockTypeBlock},
+			{ID: 3, Name: "Load", File: "engine.cpp", Line: 30, Type: parser.BlockTypeBlock},
+		},
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 12},
+			{ID: 2, Begin: 20, End: 50},
+			{ID: 3, Begin: 60, End: 70, Name: "x"},
+			{ID: 3, Begin: 70, End: 95, Name: "y"},
+		}}},
+	}
+}
+
+func TestGetHotspotsBy(t *testing.T) {
+	a := newTestAnalyzer(t, granularityProfile())
+
+	tests := []struct {
+		granularity Granularity
+		exclude     []string
+		want        []string // "name line calls total"
+	}{
+		{ByCallSite, nil, []string{"Tick 20 1 30ns", "y 30 1 25ns", "Tick 10 1 12ns", "x 30 1 10ns"}},
+		{ByName, nil, []string{"Tick 10 2 42ns", "y 30 1 25ns", "x 30 1 10ns"}},
+		{ByDescriptor, nil, []string{"Load 30 2 35ns", "Tick 20 1 30ns", "Tick 10 1 12ns"}},
+		{ByFile, nil, []string{"engine.cpp 0 3 47ns", "render.cpp 0 1 30ns"}},
+		{ByFile, []string{"engine.*"}, []string{"render.cpp 0 1 30ns"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v excluding %v", tt.granularity, tt.exclude), func(t *testing.T) {
+			var got []string
+			for _, hotspot := range a.GetHotspotsBy(10, Inclusive, tt.granularity, tt.exclude) {
+				got = append(got, fmt.Sprintf("%s %d %d %v", hotspot.Name, hotspot.Line, hotspot.CallCount, hotspot.Duration))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hotspots = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
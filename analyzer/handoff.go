@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+)
+
+// HandoffSegment is one same-named block taking part in a cross-thread operation
+type HandoffSegment struct {
+	ThreadID   uint64
+	ThreadName string
+	Begin      uint64 // Raw begin timestamp
+	End        uint64 // Raw end timestamp
+}
+
+// HandoffChain is a logical operation handed from thread to thread, assembled from
+// same-named blocks where each one starts on another thread shortly after the
+// previous one ended
+type HandoffChain struct {
+	Segments []*HandoffSegment
+	EndToEnd time.Duration // First segment's begin to last segment's end
+	Busy     time.Duration // Summed segment durations
+	Gap      time.Duration // Time spent between segments, waiting for the handoff
+}
+
+// HandoffReport summarizes the cross-thread chains of one block name
+type HandoffReport struct {
+	Name        string
+	Chains      []*HandoffChain // Longest end-to-end first
+	Unlinked    int             // Blocks that didn't join any chain
+	AvgEndToEnd time.Duration
+	MaxEndToEnd time.Duration
+}
+
+// CorrelateHandoffs links blocks named name across threads into end-to-end operations.
+// A block continues a chain when it starts on a thread the chain hasn't visited yet,
+// no earlier than the chain's last block ended and at most maxGap after it; when
+// several chains qualify, the one that ended most recently wins. This is a heuristic:
+// it assumes the instrumentation reuses one name for every stage of the operation
+// and that operations flow through a pipeline of threads without going back.
+func (a *Analyzer) CorrelateHandoffs(name string, maxGap time.Duration) (*HandoffReport, error) {
+	invocations, err := a.requireInvocations(name)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(invocations, func(i, j int) bool {
+		return invocations[i].Block.Begin < invocations[j].Block.Begin
+	})
+
+	var chains []*HandoffChain
+	var tails []*HandoffChain // Chains that can still be extended
+
+	for _, invocation := range invocations {
+		block := invocation.Block
+		segment := &HandoffSegment{
+			ThreadID:   invocation.ThreadID,
+			ThreadName: invocation.ThreadName,
+			Begin:      block.Begin,
+			End:        block.End,
+		}
+
+		// Drop tails that ended too long ago to be continued by this or any later block
+		kept := tails[:0]
+		for _, tail := range tails {
+			if last := tail.Segments[len(tail.Segments)-1]; block.Begin < last.End || time.Duration(block.Begin-last.End) <= maxGap {
+				kept = append(kept, tail)
+			}
+		}
+		tails = kept
+
+		var best *HandoffChain
+		bestIndex := -1
+		for i, tail := range tails {
+			last := tail.Segments[len(tail.Segments)-1]
+			if last.End > block.Begin || tail.visits(segment.ThreadID) {
+				continue
+			}
+			if best == nil || last.End > best.Segments[len(best.Segments)-1].End {
+				best, bestIndex = tail, i
+			}
+		}
+
+		if best != nil {
+			best.Segments = append(best.Segments, segment)
+			tails = append(tails[:bestIndex], tails[bestIndex+1:]...)
+		} else {
+			best = &HandoffChain{Segments: []*HandoffSegment{segment}}
+			chains = append(chains, best)
+		}
+		tails = append(tails, best)
+	}
+
+	report := &HandoffReport{Name: name}
+	total := time.Duration(0)
+	for _, chain := range chains {
+		if len(chain.Segments) < 2 {
+			report.Unlinked++
+			continue
+		}
+
+		first, last := chain.Segments[0], chain.Segments[len(chain.Segments)-1]
+		chain.EndToEnd = time.Duration(last.End - first.Begin)
+		for _, segment := range chain.Segments {
+			chain.Busy += time.Duration(segment.End - segment.Begin)
+		}
+		chain.Gap = max(chain.EndToEnd-chain.Busy, 0)
+
+		report.Chains = append(report.Chains, chain)
+		total += chain.EndToEnd
+		report.MaxEndToEnd = max(report.MaxEndToEnd, chain.EndToEnd)
+	}
+
+	if len(report.Chains) > 0 {
+		report.AvgEndToEnd = total / time.Duration(len(report.Chains))
+	}
+
+	sort.SliceStable(report.Chains, func(i, j int) bool {
+		return report.Chains[i].EndToEnd > report.Chains[j].EndToEnd
+	})
+
+	return report, nil
+}
+
+// visits reports whether any segment of the chain ran on the thread
+func (c *HandoffChain) visits(threadID uint64) bool {
+	for _, segment := range c.Segments {
+		if segment.ThreadID == threadID {
+			return true
+		}
+	}
+	return false
+}
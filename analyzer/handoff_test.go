@@ -0,0 +1,118 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// handoffProfile puts a Job block on thread t from begin to end ms for each
+// {t, begin, end} span, on threads 1 to 3
+func handoffProfile(spans ...[3]uint64) *proftest.Profile {
+	ms := uint64(time.Millisecond)
+	p := &proftest.Profile{Descriptors: proftest.Descriptors("Job")}
+	for id := uint64(1); id <= 3; id++ {
+		thread := proftest.Thread{ID: id, Name: fmt.Sprintf("T%d", id)}
+		for _, span := range spans {
+			if span[0] == id {
+				thread.Blocks = append(thread.Blocks, proftest.Block{ID: 1, Begin: span[1] * ms, End: span[2] * ms})
+			}
+		}
+		p.Threads = append(p.Threads, thread)
+	}
+	return p
+}
+
+func TestCorrelateHandoffs(t *testing.T) {
+	tests := []struct {
+		name     string
+		spans    [][3]uint64
+		maxGap   time.Duration
+		want     []string // Chains as "thread>thread end-to-end busy gap"
+		unlinked int
+	}{
+		{
+			name:   "pipeline",
+			spans:  [][3]uint64{{1, 0, 10}, {2, 12, 20}, {3, 21, 30}},
+			maxGap: 10 * time.Millisecond,
+			want:   []string{"1>2>3 30ms 27ms 3ms"},
+		},
+		{
+			name:     "gap too long",
+			spans:    [][3]uint64{{1, 0, 10}, {2, 30, 40}},
+			maxGap:   10 * time.Millisecond,
+			unlinked: 2,
+		},
+		{
+			name:   "longer gap allowed",
+			spans:  [][3]uint64{{1, 0, 10}, {2, 30, 40}},
+			maxGap: 30 * time.Millisecond,
+			want:   []string{"1>2 40ms 20ms 20ms"},
+		},
+		{
+			name:     "overlapping stages",
+			spans:    [][3]uint64{{1, 0, 10}, {2, 5, 15}},
+			maxGap:   10 * time.Millisecond,
+			unlinked: 2,
+		},
+		{
+			name:     "same thread again",
+			spans:    [][3]uint64{{1, 0, 10}, {1, 12, 20}},
+			maxGap:   10 * time.Millisecond,
+			unlinked: 2,
+		},
+		{
+			name:     "most recent tail wins",
+			spans:    [][3]uint64{{1, 0, 10}, {2, 0, 15}, {3, 16, 20}},
+			maxGap:   10 * time.Millisecond,
+			want:     []string{"2>3 20ms 19ms 1ms"},
+			unlinked: 1,
+		},
+		{
+			name:   "longest first",
+			spans:  [][3]uint64{{1, 0, 10}, {2, 11, 20}, {1, 100, 110}, {3, 112, 150}},
+			maxGap: 10 * time.Millisecond,
+			want:   []string{"1>3 50ms 48ms 2ms", "1>2 20ms 19ms 1ms"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := newTestAnalyzer(t, handoffProfile(tt.spans...)).CorrelateHandoffs("Job", tt.maxGap)
+			if err != nil {
+				t.Fatalf("CorrelateHandoffs: %v", err)
+			}
+
+			var got []string
+			var maxEndToEnd time.Duration
+			for _, chain := range report.Chains {
+				threads := make([]string, len(chain.Segments))
+				for i, segment := range chain.Segments {
+					threads[i] = fmt.Sprint(segment.ThreadID)
+				}
+				got = append(got, fmt.Sprintf("%s %v %v %v", strings.Join(threads, ">"), chain.EndToEnd, chain.Busy, chain.Gap))
+				maxEndToEnd = max(maxEndToEnd, chain.EndToEnd)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chains = %v, want %v", got, tt.want)
+			}
+			if report.Unlinked != tt.unlinked {
+				t.Errorf("unlinked = %d, want %d", report.Unlinked, tt.unlinked)
+			}
+			if report.MaxEndToEnd != maxEndToEnd {
+				t.Errorf("max end-to-end = %v, want %v", report.MaxEndToEnd, maxEndToEnd)
+			}
+		})
+	}
+}
+
+func TestCorrelateHandoffsUnknownName(t *testing.T) {
+	a := newTestAnalyzer(t, handoffProfile([3]uint64{1, 0, 10}))
+	if _, err := a.CorrelateHandoffs("Missing", time.Millisecond); err == nil {
+		t.Error("CorrelateHandoffs succeeded for a name that never ran")
+	}
+}
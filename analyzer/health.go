@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+const (
+	// descriptorEnabled is the ON bit of a descriptor status in EasyProfiler
+	// (OFF = 0, ON = 1, FORCE_ON = 3, ON_WITHOUT_CHILDREN = 9, ...)
+	descriptorEnabled = 1
+
+	// minHealthDescriptors is the number of descriptors needed before the share of
+	// disabled ones says anything about the capture
+	minHealthDescriptors = 4
+
+	// minSparseCapture is the capture length below which few blocks are expected
+	minSparseCapture = time.Second
+
+	// sparseBlocksPerSecond and sparseCoverage are the block rate and the percent
+	// of the capture covered by blocks under which a capture counts as near-empty
+	sparseBlocksPerSecond = 10
+	sparseCoverage        = 10
+)
+
+// ProfilerHealth tells whether the profiler appears to have been mostly off while
+// capturing, making the profile close to useless
+type ProfilerHealth struct {
+	Descriptors         int
+	DisabledDescriptors int // Unused descriptors whose status lacks the ON bit
+	UsedDescriptors     int // Descriptors with at least one block
+
+	Blocks          int
+	CaptureDuration time.Duration
+	BlocksPerSecond float64
+	Coverage        float64 // Percent of the capture covered by top-level blocks
+
+	MostlyOff bool
+	Evidence  []string // Why MostlyOff was set, one finding per entry
+}
+
+// CheckProfilerHealth looks for signs that the profiler was mostly disabled: most
+// descriptors having a disabled status, or a long capture holding very few blocks
+// that cover little of it
+func (a *Analyzer) CheckProfilerHealth() *ProfilerHealth {
+	health := &ProfilerHealth{CaptureDuration: a.profile.GetTotalDuration()}
+
+	used := make(map[uint32]bool)
+	for _, threadID := range a.sortedThreadIDs() {
+		walkBlocks(a.profile.Thread(threadID).Blocks, func(block *parser.Block, _ int) {
+			health.Blocks++
+			used[block.ID] = true
+		})
+	}
+
+	for _, id := range a.profile.DescriptorIDs() {
+		health.Descriptors++
+		// A status saved as disabled doesn't matter for descriptors that still
+		// recorded blocks, e.g. because they were switched off late in the capture
+		switch {
+		case used[id]:
+			health.UsedDescriptors++
+		case a.profile.Descriptor(id).Status&descriptorEnabled == 0:
+			health.DisabledDescriptors++
+		}
+	}
+
+	if health.CaptureDuration > 0 {
+		health.BlocksPerSecond = float64(health.Blocks) / health.CaptureDuration.Seconds()
+	}
+	health.Coverage = a.GetUtilization().Coverage
+
+	if health.Descriptors >= minHealthDescriptors && health.DisabledDescriptors*2 > health.Descriptors {
+		health.Evidence = append(health.Evidence, fmt.Sprintf("%d of %d block descriptors are disabled and recorded nothing",
+			health.DisabledDescriptors, health.Descriptors))
+	}
+	if health.CaptureDuration >= minSparseCapture && health.BlocksPerSecond < sparseBlocksPerSecond && health.Coverage < sparseCoverage {
+		health.Evidence = append(health.Evidence, fmt.Sprintf("only %d blocks over a %v capture (%.1f per second), covering %.1f%% of it",
+			health.Blocks, health.CaptureDuration, health.BlocksPerSecond, health.Coverage))
+	}
+	health.MostlyOff = len(health.Evidence) > 0
+
+	return health
+}
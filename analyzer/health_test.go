@@ -0,0 +1,113 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// healthProfile is a capture from begin to end with the given blocks on one thread,
+// and descriptors with the given statuses
+func healthProfile(begin, end uint64, statuses []uint8, blocks ...proftest.Block) *proftest.Profile {
+	names := make([]string, len(statuses))
+	for i := range names {
+		names[i] = string(rune('A' + i))
+	}
+	descriptors := proftest.Descriptors(names...)
+	for i, status := range statuses {
+		descriptors[i].Status = status
+	}
+	return &proftest.Profile{
+		Begin:       begin,
+		End:         end,
+		Descriptors: descriptors,
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+	}
+}
+
+func TestCheckProfilerHealth(t *testing.T) {
+	ms := uint64(time.Millisecond)
+
+	tests := []struct {
+		name     string
+		profile  *proftest.Profile
+		disabled int
+		used     int
+		blocks   int
+		evidence []string // Substrings of each finding, in order
+	}{
+		{
+			name:     "long capture with almost no blocks",
+			profile:  healthProfile(0, 10000*ms, []uint8{1, 1}, proftest.Block{ID: 1, Begin: 0, End: ms}, proftest.Block{ID: 2, Begin: 5000 * ms, End: 5001 * ms}),
+			used:     2,
+			blocks:   2,
+			evidence: []string{"only 2 blocks over a 10s capture (0.2 per second), covering 0.0%"},
+		},
+		{
+			name:     "most descriptors disabled",
+			profile:  healthProfile(0, 10*ms, []uint8{0, 0, 0, 0, 1, 1}, proftest.Block{ID: 5, Begin: 0, End: 10 * ms}),
+			disabled: 4,
+			used:     1,
+			blocks:   1,
+			evidence: []string{"4 of 6 block descriptors are disabled"},
+		},
+		{
+			name:     "both",
+			profile:  healthProfile(0, 2000*ms, []uint8{0, 0, 0, 1}, proftest.Block{ID: 4, Begin: 0, End: ms}),
+			disabled: 3,
+			used:     1,
+			blocks:   1,
+			evidence: []string{"3 of 4 block descriptors are disabled", "only 1 blocks over a 2s capture"},
+		},
+		{
+			name:     "used descriptors don't count as disabled",
+			profile:  healthProfile(0, 10*ms, []uint8{0, 0, 0, 1}, proftest.Block{ID: 1, Begin: 0, End: 4 * ms}, proftest.Block{ID: 2, Begin: 4 * ms, End: 10 * ms}),
+			disabled: 1,
+			used:     2,
+			blocks:   2,
+		},
+		{
+			name:    "too few descriptors to judge",
+			profile: healthProfile(0, 10*ms, []uint8{0, 0, 0}, proftest.Block{ID: 1, Begin: 0, End: 10 * ms}),
+			used:    1,
+			blocks:  1,
+			// Two unused descriptors are disabled, but three descriptors say nothing
+			disabled: 2,
+		},
+		{
+			name:    "long capture covered by few long blocks",
+			profile: healthProfile(0, 10000*ms, []uint8{1}, proftest.Block{ID: 1, Begin: 0, End: 9000 * ms}),
+			used:    1,
+			blocks:  1,
+		},
+		{
+			name:    "zero duration",
+			profile: healthProfile(50, 50, []uint8{1}, proftest.Block{ID: 1, Begin: 50, End: 50}),
+			used:    1,
+			blocks:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := newTestAnalyzer(t, tt.profile).CheckProfilerHealth()
+			if health.DisabledDescriptors != tt.disabled || health.UsedDescriptors != tt.used || health.Blocks != tt.blocks {
+				t.Errorf("disabled, used, blocks = %d, %d, %d; want %d, %d, %d",
+					health.DisabledDescriptors, health.UsedDescriptors, health.Blocks, tt.disabled, tt.used, tt.blocks)
+			}
+			if health.MostlyOff != (len(tt.evidence) > 0) || len(health.Evidence) != len(tt.evidence) {
+				t.Fatalf("mostly off = %t with evidence %q, want %q", health.MostlyOff, health.Evidence, tt.evidence)
+			}
+			for i, want := range tt.evidence {
+				if !strings.Contains(health.Evidence[i], want) {
+					t.Errorf("evidence[%d] = %q, want it to contain %q", i, health.Evidence[i], want)
+				}
+			}
+			if health.CaptureDuration == 0 && (health.BlocksPerSecond != 0 || health.Coverage != 0) {
+				t.Errorf("zero-length capture has rate %v and coverage %v, want 0", health.BlocksPerSecond, health.Coverage)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// HeatmapRow is one function's busy time per time bucket
+type HeatmapRow struct {
+	Name  string
+	File  string
+	Line  int32
+	Total time.Duration
+	Cells []time.Duration // Indexed by bucket
+}
+
+// Heatmap is a function × time bucket matrix of busy time
+type Heatmap struct {
+	BucketSize time.Duration
+	Buckets    int
+	Rows       []*HeatmapRow // Busiest function first
+}
+
+// maxHeatmapBuckets bounds the number of buckets GetBlockHeatmap splits the capture
+// into, as every row holds one cell per bucket
+const maxHeatmapBuckets = 10000
+
+// GetBlockHeatmap splits the capture into buckets equal time buckets and returns the
+// limit busiest functions with the time each spent active in every bucket. A function
+// is active while any of its calls is on the stack; recursive calls only count once.
+func (a *Analyzer) GetBlockHeatmap(limit, buckets int) (*Heatmap, error) {
+	if buckets <= 0 {
+		return nil, fmt.Errorf("bucket count must be positive")
+	}
+	if buckets > maxHeatmapBuckets {
+		return nil, fmt.Errorf("bucket count must be at most %d", maxHeatmapBuckets)
+	}
+	total := a.profile.GetTotalDuration()
+	if total <= 0 {
+		return nil, fmt.Errorf("profile has no duration")
+	}
+
+	heatmap := &Heatmap{
+		BucketSize: (total + time.Duration(buckets) - 1) / time.Duration(buckets),
+		Buckets:    buckets,
+	}
+	rows := make(map[string]*HeatmapRow)
+
+	for _, threadID := range a.sortedThreadIDs() {
+		var path []string
+		active := make(map[string]int)
+
+		walkBlocks(a.profile.Thread(threadID).Blocks, func(block *parser.Block, depth int) {
+			for len(path) > depth {
+				active[path[len(path)-1]]--
+				path = path[:len(path)-1]
+			}
+
+			name, file, line := a.resolveBlock(block)
+			key := a.aggregationKey(block, name)
+			path = append(path, key)
+			active[key]++
+			if active[key] > 1 {
+				return
+			}
+
+			row, ok := rows[key]
+			if !ok {
+				row = &HeatmapRow{Name: name, File: file, Line: line, Cells: make([]time.Duration, buckets)}
+				rows[key] = row
+			}
+			row.Total += block.Duration()
+			heatmap.spread(row, a.CaptureOffset(block.Begin), a.CaptureOffset(block.End))
+		})
+	}
+
+	for _, row := range rows {
+		heatmap.Rows = append(heatmap.Rows, row)
+	}
+	sort.Slice(heatmap.Rows, func(i, j int) bool {
+		x, y := heatmap.Rows[i], heatmap.Rows[j]
+		if x.Total != y.Total {
+			return x.Total > y.Total
+		}
+		if x.Name != y.Name {
+			return x.Name < y.Name
+		}
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		return x.Line < y.Line
+	})
+	if limit > 0 && limit < len(heatmap.Rows) {
+		heatmap.Rows = heatmap.Rows[:limit]
+	}
+
+	return heatmap, nil
+}
+
+// spread adds the interval [begin, end), given as capture offsets, to the row's buckets
+func (h *Heatmap) spread(row *HeatmapRow, begin, end time.Duration) {
+	for bucket := int(begin / h.BucketSize); bucket < h.Buckets && begin < end; bucket++ {
+		bucketEnd := time.Duration(bucket+1) * h.BucketSize
+		row.Cells[bucket] += min(end, bucketEnd) - begin
+		begin = bucketEnd
+	}
+}
@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestGetBlockHeatmap(t *testing.T) {
+	// Frame spans the 100ns capture; Recurse calls itself; Update also runs on Worker
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         100,
+		Descriptors: proftest.Descriptors("Frame", "Update", "Recurse"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 10, End: 60},
+				{ID: 3, Begin: 75, End: 85},
+				{ID: 3, Begin: 70, End: 90},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 40, End: 50}}},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name       string
+		limit      int
+		buckets    int
+		bucketSize string
+		want       []string // "name total cells"
+		wantErr    bool
+	}{
+		{"all rows", 0, 4, "25ns", []string{
+			"Frame 100ns [25ns 25ns 25ns 25ns]",
+			"Update 60ns [15ns 35ns 10ns 0s]",
+			"Recurse 20ns [0s 0s 5ns 15ns]",
+		}, false},
+		{"limited", 2, 4, "25ns", []string{
+			"Frame 100ns [25ns 25ns 25ns 25ns]",
+			"Update 60ns [15ns 35ns 10ns 0s]",
+		}, false},
+		{"uneven buckets", 1, 3, "34ns", []string{"Frame 100ns [34ns 34ns 32ns]"}, false},
+		{"negative limit keeps all", -1, 1, "100ns", []string{"Frame 100ns [100ns]", "Update 60ns [60ns]", "Recurse 20ns [20ns]"}, false},
+		{"no buckets", 0, 0, "", nil, true},
+		{"too many buckets", 0, maxHeatmapBuckets + 1, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			heatmap, err := a.GetBlockHeatmap(tt.limit, tt.buckets)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBlockHeatmap error = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if heatmap.BucketSize.String() != tt.bucketSize || heatmap.Buckets != tt.buckets {
+				t.Errorf("%d buckets of %v, want %d of %s", heatmap.Buckets, heatmap.BucketSize, tt.buckets, tt.bucketSize)
+			}
+			var got []string
+			for _, row := range heatmap.Rows {
+				got = append(got, fmt.Sprintf("%s %v %v", row.Name, row.Total, row.Cells))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBlockHeatmapZeroDuration(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Tick"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 50, End: 50}}}},
+	}
+	if _, err := newTestAnalyzer(t, p).GetBlockHeatmap(10, 4); err == nil {
+		t.Error("GetBlockHeatmap succeeded on a capture without duration")
+	}
+}
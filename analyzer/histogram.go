@@ -0,0 +1,144 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// DefaultHistogramBoundaries split call durations into <1ms, 1-10ms, 10-100ms and >=100ms
+var DefaultHistogramBoundaries = []time.Duration{
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+}
+
+// HistogramBucket counts calls whose duration falls in [Min, Max). Max is 0 for the last, unbounded bucket.
+type HistogramBucket struct {
+	Min           time.Duration
+	Max           time.Duration
+	Count         int
+	TotalDuration time.Duration
+}
+
+// Label returns a human-readable range such as "1ms-10ms"
+func (b *HistogramBucket) Label() string {
+	switch {
+	case b.Min == 0 && b.Max > 0:
+		return fmt.Sprintf("<%v", b.Max)
+	case b.Max == 0:
+		return fmt.Sprintf(">=%v", b.Min)
+	default:
+		return fmt.Sprintf("%v-%v", b.Min, b.Max)
+	}
+}
+
+// DurationHistogram is the distribution of a function's call durations
+type DurationHistogram struct {
+	Name      string
+	CallCount int
+	Buckets   []*HistogramBucket
+}
+
+// GetDurationHistogram buckets every call of the named function by its duration in mode.
+// boundaries are the bucket edges; they're sorted and deduplicated.
+func (a *Analyzer) GetDurationHistogram(name string, boundaries []time.Duration, mode TimeMode) (*DurationHistogram, error) {
+	invocations, err := a.requireInvocations(name)
+	if err != nil {
+		return nil, err
+	}
+
+	histogram := &DurationHistogram{
+		Name:      name,
+		CallCount: len(invocations),
+		Buckets:   newHistogramBuckets(boundaries),
+	}
+
+	for _, invocation := range invocations {
+		duration := a.blockTime(invocation.Block, mode)
+		bucket := histogram.Buckets[bucketIndex(histogram.Buckets, duration)]
+		bucket.Count++
+		bucket.TotalDuration += duration
+	}
+
+	return histogram, nil
+}
+
+// newHistogramBuckets creates the buckets delimited by the given boundaries
+func newHistogramBuckets(boundaries []time.Duration) []*HistogramBucket {
+	edges := make([]time.Duration, 0, len(boundaries))
+	for _, boundary := range boundaries {
+		if boundary > 0 {
+			edges = append(edges, boundary)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i] < edges[j] })
+
+	buckets := make([]*HistogramBucket, 0, len(edges)+1)
+	lower := time.Duration(0)
+	for _, edge := range edges {
+		if edge == lower {
+			continue
+		}
+		buckets = append(buckets, &HistogramBucket{Min: lower, Max: edge})
+		lower = edge
+	}
+	buckets = append(buckets, &HistogramBucket{Min: lower})
+
+	return buckets
+}
+
+// bucketIndex returns the index of the bucket containing d
+func bucketIndex(buckets []*HistogramBucket, d time.Duration) int {
+	for i, bucket := range buckets {
+		if bucket.Max == 0 || d < bucket.Max {
+			return i
+		}
+	}
+	return len(buckets) - 1
+}
+
+// MagnitudeBoundaries split durations by order of magnitude, from <1µs to >=1s
+var MagnitudeBoundaries = []time.Duration{
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// DurationDistribution is the distribution of the durations of every block in the
+// capture, whatever its function
+type DurationDistribution struct {
+	Blocks        int
+	TotalDuration time.Duration // Summed over all buckets
+	Buckets       []*HistogramBucket
+}
+
+// GetDurationDistribution buckets every closed block of every thread by the order of
+// magnitude of its duration in mode, showing whether the time goes to a few huge
+// blocks or a swarm of small ones. Inclusive totals count nested time once per
+// enclosing block; exclusive totals add up to the instrumented time.
+func (a *Analyzer) GetDurationDistribution(mode TimeMode) *DurationDistribution {
+	distribution := &DurationDistribution{Buckets: newHistogramBuckets(MagnitudeBoundaries)}
+
+	for _, threadID := range a.sortedThreadIDs() {
+		walkBlocks(a.profile.Thread(threadID).Blocks, func(block *parser.Block, _ int) {
+			if block.Unclosed {
+				return
+			}
+			duration := a.blockTime(block, mode)
+			bucket := distribution.Buckets[bucketIndex(distribution.Buckets, duration)]
+			bucket.Count++
+			bucket.TotalDuration += duration
+			distribution.Blocks++
+			distribution.TotalDuration += duration
+		})
+	}
+
+	return distribution
+}
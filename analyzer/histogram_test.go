@@ -0,0 +1,146 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestNewHistogramBuckets(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name       string
+		boundaries []time.Duration
+		want       []string
+	}{
+		{"default", DefaultHistogramBoundaries, []string{"<1ms", "1ms-10ms", "10ms-100ms", ">=100ms"}},
+		{"unsorted with duplicates", []time.Duration{10 * ms, ms, 10 * ms}, []string{"<1ms", "1ms-10ms", ">=10ms"}},
+		{"non-positive edges ignored", []time.Duration{0, -ms, ms}, []string{"<1ms", ">=1ms"}},
+		{"no edges", nil, []string{">=0s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var labels []string
+			for _, bucket := range newHistogramBuckets(tt.boundaries) {
+				labels = append(labels, bucket.Label())
+			}
+			if !reflect.DeepEqual(labels, tt.want) {
+				t.Errorf("buckets = %v, want %v", labels, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDurationHistogram(t *testing.T) {
+	us := uint64(time.Microsecond)
+	a := newTestAnalyzer(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Update", "Child"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 500 * us},
+			{ID: 2, Begin: 1000 * us, End: 4000 * us},
+			{ID: 1, Begin: 1000 * us, End: 5000 * us},
+			{ID: 1, Begin: 10000 * us, End: 30000 * us},
+		}}},
+	})
+
+	tests := []struct {
+		name   string
+		mode   TimeMode
+		counts []int
+	}{
+		// 0.5ms, 4ms and 20ms calls
+		{"inclusive", Inclusive, []int{1, 1, 1, 0}},
+		// The 4ms call spends 3ms in its child
+		{"exclusive", Exclusive, []int{1, 1, 1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			histogram, err := a.GetDurationHistogram("Update", DefaultHistogramBoundaries, tt.mode)
+			if err != nil {
+				t.Fatalf("GetDurationHistogram: %v", err)
+			}
+			var counts []int
+			for _, bucket := range histogram.Buckets {
+				counts = append(counts, bucket.Count)
+			}
+			if histogram.CallCount != 3 || !reflect.DeepEqual(counts, tt.counts) {
+				t.Errorf("calls %d counts %v, want 3 and %v", histogram.CallCount, counts, tt.counts)
+			}
+		})
+	}
+
+	histogram, _ := a.GetDurationHistogram("Update", []time.Duration{2 * time.Millisecond}, Exclusive)
+	if got := histogram.Buckets[0].Count; got != 2 {
+		t.Errorf("exclusive calls under 2ms = %d, want 2 (0.5ms and the 1ms self time)", got)
+	}
+
+	if _, err := a.GetDurationHistogram("Missing", DefaultHistogramBoundaries, Inclusive); err == nil {
+		t.Error("GetDurationHistogram succeeded for an unknown function")
+	}
+}
+
+func TestGetDurationDistribution(t *testing.T) {
+	// On Main, a 2s Frame holds a 500ns Tiny, a 5µs Mid, a 1µs Edge right on a
+	// boundary, a zero-length Mark and a 1s Sec. Worker's Job never closes.
+	us, s := uint64(time.Microsecond), uint64(time.Second)
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Tiny", "Mid", "Edge", "Mark", "Sec", "Job"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 10, End: 510},
+				{ID: 3, Begin: us, End: 6 * us},
+				{ID: 4, Begin: 10 * us, End: 11 * us},
+				{ID: 5, Begin: 20 * us, End: 20 * us},
+				{ID: 6, Begin: s / 10, End: s/10 + s},
+				{ID: 1, Begin: 0, End: 2 * s},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 7, Begin: 5, End: 0}}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		mode  TimeMode
+		want  []string // "range count total" of every non-empty bucket
+		total time.Duration
+	}{
+		{"inclusive", Inclusive, []string{"<1µs 2 500ns", "1µs-10µs 2 6µs", ">=1s 2 3s"}, 3*time.Second + 6500},
+		{"exclusive", Exclusive, []string{"<1µs 2 500ns", "1µs-10µs 2 6µs", "100ms-1s 1 999.9935ms", ">=1s 1 1s"}, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distribution := newTestAnalyzer(t, p).GetDurationDistribution(tt.mode)
+			if len(distribution.Buckets) != len(MagnitudeBoundaries)+1 {
+				t.Fatalf("%d buckets, want %d", len(distribution.Buckets), len(MagnitudeBoundaries)+1)
+			}
+
+			var got []string
+			count := 0
+			for _, bucket := range distribution.Buckets {
+				count += bucket.Count
+				if bucket.Count > 0 {
+					got = append(got, fmt.Sprintf("%s %d %v", bucket.Label(), bucket.Count, bucket.TotalDuration))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buckets = %q, want %q", got, tt.want)
+			}
+			if distribution.Blocks != 6 || count != 6 || distribution.TotalDuration != tt.total {
+				t.Errorf("%d blocks (%d in buckets) over %v, want 6 over %v", distribution.Blocks, count, distribution.TotalDuration, tt.total)
+			}
+		})
+	}
+}
+
+func TestGetDurationDistributionEmpty(t *testing.T) {
+	distribution := newTestAnalyzer(t, &proftest.Profile{Threads: []proftest.Thread{{ID: 1, Name: "Idle"}}}).GetDurationDistribution(Inclusive)
+	if distribution.Blocks != 0 || distribution.TotalDuration != 0 || len(distribution.Buckets) != len(MagnitudeBoundaries)+1 {
+		t.Errorf("distribution = %+v, want empty buckets", distribution)
+	}
+}
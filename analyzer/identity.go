@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// BlockIdentity combines a descriptor with the usage of its blocks in the capture
+type BlockIdentity struct {
+	Descriptor   *parser.BlockDescriptor
+	CallCount    int
+	Duration     time.Duration // Inclusive time, counting only the outermost of recursive calls
+	SelfDuration time.Duration
+}
+
+// Color returns the descriptor color as #RRGGBB
+func (i *BlockIdentity) Color() string {
+	return colorHex(i.Descriptor.Color)
+}
+
+// Name returns the descriptor name, or a sentinel naming the descriptor ID if it has none
+func (i *BlockIdentity) Name() string {
+	if i.Descriptor.Name == "" {
+		return unnamedLabel(i.Descriptor.ID)
+	}
+	return i.Descriptor.Name
+}
+
+// File returns the descriptor's source file, or UnknownFileLabel if it has none
+func (i *BlockIdentity) File() string {
+	if i.Descriptor.File == "" {
+		return UnknownFileLabel
+	}
+	return i.Descriptor.File
+}
+
+// GetBlockIdentity looks up descriptors by ID or by name and reports how their
+// blocks were used. A numeric query matching a descriptor ID is treated as an ID;
+// otherwise every descriptor with that name is returned, ordered by ID.
+func (a *Analyzer) GetBlockIdentity(query string) ([]*BlockIdentity, error) {
+	var descriptors []*parser.BlockDescriptor
+	if id, err := strconv.ParseUint(query, 10, 32); err == nil {
+		if descriptor := a.profile.Descriptor(uint32(id)); descriptor != nil {
+			descriptors = append(descriptors, descriptor)
+		}
+	}
+	if len(descriptors) == 0 {
+		for _, id := range a.profile.DescriptorIDs() {
+			if descriptor := a.profile.Descriptor(id); descriptor.Name == query {
+				descriptors = append(descriptors, descriptor)
+			}
+		}
+	}
+	if len(descriptors) == 0 {
+		return nil, fmt.Errorf("block '%s' not found", query)
+	}
+
+	sort.Slice(descriptors, func(i, j int) bool {
+		return descriptors[i].ID < descriptors[j].ID
+	})
+
+	identities := make(map[uint32]*BlockIdentity, len(descriptors))
+	result := make([]*BlockIdentity, len(descriptors))
+	for i, descriptor := range descriptors {
+		result[i] = &BlockIdentity{Descriptor: descriptor}
+		identities[descriptor.ID] = result[i]
+	}
+	a.measureUsage(identities)
+
+	return result, nil
+}
+
+// measureUsage adds up the calls and time of the blocks of every descriptor in identities
+func (a *Analyzer) measureUsage(identities map[uint32]*BlockIdentity) {
+	type activeMatch struct {
+		depth int
+		id    uint32
+	}
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		// Enclosing blocks that are themselves matches, so that recursive
+		// calls don't add their inclusive time again
+		var active []activeMatch
+		enclosing := make(map[uint32]int)
+		walkBlocks(thread.Blocks, func(block *parser.Block, depth int) {
+			for len(active) > 0 && active[len(active)-1].depth >= depth {
+				enclosing[active[len(active)-1].id]--
+				active = active[:len(active)-1]
+			}
+
+			identity := identities[block.ID]
+			if identity == nil {
+				return
+			}
+			identity.CallCount++
+			identity.SelfDuration += a.selfTime(block)
+			if enclosing[block.ID] == 0 {
+				identity.Duration += block.Duration()
+			}
+			enclosing[block.ID]++
+			active = append(active, activeMatch{depth: depth, id: block.ID})
+		})
+	}
+}
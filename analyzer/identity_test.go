@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestGetBlockIdentity(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: []proftest.Descriptor{
+			{ID: 1, Name: "Parse", File: "json.cpp", Line: 10, Color: 0xFF00FF00, Type: parser.BlockTypeBlock},
+			{ID: 2, Name: "Parse", File: "xml.cpp", Line: 20, Type: parser.BlockTypeBlock},
+			{ID: 3, Name: "7", File: "odd.cpp", Line: 30, Type: parser.BlockTypeBlock},
+			{ID: 4, Name: "Walk", File: "tree.cpp", Line: 40, Type: parser.BlockTypeBlock},
+		},
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 1, Begin: 0, End: 100},
+				{ID: 1, Begin: 200, End: 250},
+				// Walk recurses: 3 calls, 100ns inclusive, 60+20+20 self
+				{ID: 4, Begin: 320, End: 340},
+				{ID: 4, Begin: 310, End: 350},
+				{ID: 4, Begin: 300, End: 400},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{
+				{ID: 2, Begin: 0, End: 30},
+				{ID: 3, Begin: 50, End: 60},
+			}},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		query   string
+		want    []string // "id calls duration self"
+		wantErr bool
+	}{
+		{"1", []string{"1 2 150ns 150ns"}, false},
+		{"Parse", []string{"1 2 150ns 150ns", "2 1 30ns 30ns"}, false},
+		{"Walk", []string{"4 3 100ns 100ns"}, false},
+		// Numeric, but no descriptor has ID 7, so it's looked up by name
+		{"7", []string{"3 1 10ns 10ns"}, false},
+		{"Missing", nil, true},
+		{"99", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			identities, err := a.GetBlockIdentity(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBlockIdentity(%q) error = %v, want error %t", tt.query, err, tt.wantErr)
+			}
+			var got []string
+			for _, identity := range identities {
+				got = append(got, fmt.Sprintf("%d %d %v %v", identity.Descriptor.ID, identity.CallCount, identity.Duration, identity.SelfDuration))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("identities = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	identities, _ := a.GetBlockIdentity("1")
+	if got := identities[0].Color(); got != "#00FF00" {
+		t.Errorf("Color() = %q, want #00FF00", got)
+	}
+}
+
+func TestBlockIdentityNameAndFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		descriptor parser.BlockDescriptor
+		wantName   string
+		wantFile   string
+	}{
+		{"named", parser.BlockDescriptor{ID: 1, Name: "Parse", File: "json.cpp"}, "Parse", "json.cpp"},
+		{"no name", parser.BlockDescriptor{ID: 2, File: "json.cpp"}, "(unnamed block #2)", "json.cpp"},
+		{"no file", parser.BlockDescriptor{ID: 3, Name: "Parse"}, "Parse", UnknownFileLabel},
+		{"neither", parser.BlockDescriptor{ID: 4}, "(unnamed block #4)", UnknownFileLabel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity := &BlockIdentity{Descriptor: &tt.descriptor}
+			if got := identity.Name(); got != tt.wantName {
+				t.Errorf("Name() = %q, want %q", got, tt.wantName)
+			}
+			if got := identity.File(); got != tt.wantFile {
+				t.Errorf("File() = %q, want %q", got, tt.wantFile)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package analyzer
+
+import "github.com/yourusername/easyprofiler-mcp/parser"
+
+// SetIdlePatterns sets the names of blocks in which a thread waits for work rather
+// than does it, e.g. "WaitForWork"; '*' matches any characters. Utilization doesn't
+// count time inside them as busy.
+func (a *Analyzer) SetIdlePatterns(patterns []string) {
+	a.idlePatterns = patterns
+}
+
+// IdlePatterns returns the names of blocks counted as idle
+func (a *Analyzer) IdlePatterns() []string {
+	return a.idlePatterns
+}
+
+// busyIntervals returns the merged intervals during which a thread was working,
+// while one of its top-level blocks was active, minus the merged intervals it
+// spent in idle blocks, which are returned as well
+func (a *Analyzer) busyIntervals(thread *parser.ThreadData) (busy []interval, idle []interval) {
+	busy = mergeIntervals(blockIntervals(thread.Blocks))
+	if len(a.idlePatterns) == 0 {
+		return busy, nil
+	}
+
+	walkBlocks(thread.Blocks, func(block *parser.Block, _ int) {
+		if block.End <= block.Begin {
+			return
+		}
+		if name, _, _ := a.resolveBlock(block); matchesAnyPattern(a.idlePatterns, name) {
+			idle = append(idle, interval{begin: block.Begin, end: block.End})
+		}
+	})
+	idle = mergeIntervals(idle)
+
+	return subtractIntervals(busy, idle), idle
+}
+
+// subtractIntervals returns the parts of intervals not covered by holes. Both must
+// be merged: sorted and non-overlapping.
+func subtractIntervals(intervals, holes []interval) []interval {
+	var result []interval
+	h := 0
+	for _, iv := range intervals {
+		begin := iv.begin
+		for h < len(holes) && holes[h].end <= begin {
+			h++
+		}
+		for i := h; i < len(holes) && holes[i].begin < iv.end; i++ {
+			if holes[i].begin > begin {
+				result = append(result, interval{begin: begin, end: holes[i].begin})
+			}
+			begin = max(begin, holes[i].end)
+		}
+		if begin < iv.end {
+			result = append(result, interval{begin: begin, end: iv.end})
+		}
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestSubtractIntervals(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals []interval
+		holes     []interval
+		want      []interval
+	}{
+		{"no holes", []interval{{0, 10}}, nil, []interval{{0, 10}}},
+		{"hole inside", []interval{{0, 100}}, []interval{{20, 30}}, []interval{{0, 20}, {30, 100}}},
+		{"hole at the start", []interval{{0, 100}}, []interval{{0, 30}}, []interval{{30, 100}}},
+		{"hole covers all", []interval{{10, 20}}, []interval{{0, 100}}, nil},
+		{"hole spans two intervals", []interval{{0, 10}, {20, 30}}, []interval{{5, 25}}, []interval{{0, 5}, {25, 30}}},
+		{"holes outside", []interval{{20, 30}}, []interval{{0, 10}, {40, 50}}, []interval{{20, 30}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subtractIntervals(tt.intervals, tt.holes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("subtractIntervals = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUtilizationIdlePatterns(t *testing.T) {
+	// Main works throughout; Worker's Job spends 200..800 waiting for work
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         1000,
+		Descriptors: proftest.Descriptors("Work", "Job", "WaitForWork"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{
+				{ID: 3, Begin: 200, End: 800},
+				{ID: 2, Begin: 0, End: 1000},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		patterns        []string
+		wantBusy        time.Duration
+		wantIdle        time.Duration
+		wantParallelism float64
+	}{
+		{"no patterns", nil, 2000, 0, 2},
+		{"exact name", []string{"WaitForWork"}, 1400, 600, 1.4},
+		{"wildcard", []string{"Wait*"}, 1400, 600, 1.4},
+		{"no match", []string{"Sleep"}, 2000, 0, 2},
+		{"whole thread idle", []string{"Job"}, 1000, 1000, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t, p)
+			a.SetIdlePatterns(tt.patterns)
+			util := a.GetUtilization()
+			if util.BusyTime != tt.wantBusy || util.IdleTime != tt.wantIdle {
+				t.Errorf("busy %v idle %v, want %v and %v", util.BusyTime, util.IdleTime, tt.wantBusy, tt.wantIdle)
+			}
+			if util.Parallelism != tt.wantParallelism || util.CoveredTime != 1000 {
+				t.Errorf("parallelism %v over %v, want %v over 1µs", util.Parallelism, util.CoveredTime, tt.wantParallelism)
+			}
+		})
+	}
+}
+
+func TestGetUtilizationIdleOnlyThread(t *testing.T) {
+	// Worker only waits, so the second half of the capture isn't covered
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         1000,
+		Descriptors: proftest.Descriptors("Work", "WaitForWork"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 500}}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 500, End: 1000}}},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+	a.SetIdlePatterns([]string{"WaitForWork"})
+
+	util := a.GetUtilization()
+	if util.CoveredTime != 500 || util.Coverage != 50 || util.Parallelism != 1 {
+		t.Errorf("covered %v (%v%%) at parallelism %v, want 500ns (50%%) at 1", util.CoveredTime, util.Coverage, util.Parallelism)
+	}
+}
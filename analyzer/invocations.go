@@ -0,0 +1,43 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// Invocation is a single call of a function on a specific thread
+type Invocation struct {
+	Block      *parser.Block
+	ThreadID   uint64
+	ThreadName string
+}
+
+// GetInvocations returns every block whose resolved name matches name, across all threads
+func (a *Analyzer) GetInvocations(name string) []*Invocation {
+	var result []*Invocation
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		walkBlocks(thread.Blocks, func(block *parser.Block, _ int) {
+			if blockName, _, _ := a.resolveBlock(block); blockName == name {
+				result = append(result, &Invocation{
+					Block:      block,
+					ThreadID:   thread.ThreadID,
+					ThreadName: thread.ThreadName,
+				})
+			}
+		})
+	}
+
+	return result
+}
+
+// requireInvocations returns the invocations of name or an error if there are none
+func (a *Analyzer) requireInvocations(name string) ([]*Invocation, error) {
+	invocations := a.GetInvocations(name)
+	if len(invocations) == 0 {
+		return nil, fmt.Errorf("function '%s' not found", name)
+	}
+	return invocations, nil
+}
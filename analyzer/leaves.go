@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// GetLeafHotspots ranks functions by the time spent in their leaf calls, i.e. calls
+// that made no nested calls, so all of their time is self time. Unlike hotspots,
+// composite functions that only dispatch to others never appear, which points
+// straight at the innermost code doing the work. A function that is a leaf in
+// some calls and not in others only counts its leaf calls. It also returns the
+// total leaf time across all functions.
+func (a *Analyzer) GetLeafHotspots(limit int) ([]*BlockInfo, time.Duration) {
+	blockMap := make(map[string]*BlockInfo)
+	total := time.Duration(0)
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		walkBlocks(thread.Blocks, func(block *parser.Block, _ int) {
+			if len(block.Children) > 0 || block.Duration() <= 0 {
+				return
+			}
+			if a.isAnonymous(block) && a.anonymousPolicy != AnonymousByDescriptor {
+				return
+			}
+
+			name, file, line := a.resolveBlock(block)
+			key := a.aggregationKey(block, name)
+			info, ok := blockMap[key]
+			if !ok {
+				info = &BlockInfo{Name: name, File: file, Line: line}
+				blockMap[key] = info
+			}
+			info.Duration += block.Duration()
+			info.SelfDuration += block.Duration()
+			info.CallCount++
+			total += block.Duration()
+		})
+	}
+	mergeNameOnlyEntries(blockMap)
+
+	result := make([]*BlockInfo, 0, len(blockMap))
+	for _, info := range blockMap {
+		info.AvgDuration = info.SelfDuration / time.Duration(info.CallCount)
+		result = append(result, info)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SelfDuration != result[j].SelfDuration {
+			return result[i].SelfDuration > result[j].SelfDuration
+		}
+		return lessBlockInfo(result[i], result[j])
+	})
+
+	if limit > 0 && limit < len(result) {
+		result = result[:limit]
+	}
+	return result, total
+}
@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestGetLeafHotspots(t *testing.T) {
+	// Frame only dispatches to Update and Render; Update calls Physics once and is a
+	// leaf once; Tick is zero-length
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Physics", "Render", "Tick"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 3, Begin: 10, End: 50},
+				{ID: 2, Begin: 0, End: 60},
+				{ID: 4, Begin: 60, End: 90},
+				{ID: 5, Begin: 95, End: 95},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 20}}},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string // "name calls self avg"
+	}{
+		{"all", 0, []string{"Physics 1 40ns 40ns", "Render 1 30ns 30ns", "Update 1 20ns 20ns"}},
+		{"limited", 2, []string{"Physics 1 40ns 40ns", "Render 1 30ns 30ns"}},
+		{"negative keeps all", -1, []string{"Physics 1 40ns 40ns", "Render 1 30ns 30ns", "Update 1 20ns 20ns"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaves, total := a.GetLeafHotspots(tt.limit)
+			var got []string
+			for _, leaf := range leaves {
+				got = append(got, fmt.Sprintf("%s %d %v %v", leaf.Name, leaf.CallCount, leaf.SelfDuration, leaf.AvgDuration))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("leaves = %q, want %q", got, tt.want)
+			}
+			if total != 90 {
+				t.Errorf("total = %v, want 90ns whatever the limit", total)
+			}
+		})
+	}
+}
+
+func TestGetLeafHotspotsZeroTotal(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Tick"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 50, End: 50}}}},
+	}
+	leaves, total := newTestAnalyzer(t, p).GetLeafHotspots(10)
+	if len(leaves) != 0 || total != 0 {
+		t.Errorf("got %d leaves totaling %v, want none", len(leaves), total)
+	}
+}
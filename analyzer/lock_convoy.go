@@ -0,0 +1,135 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// DefaultLockPattern matches block names commonly used for lock-protected sections,
+// leaving out "Block" and "Clock"
+const DefaultLockPattern = `(?i)(^|[^bc])lock|mutex|critical.?section|semaphore`
+
+// LockConvoy is one run of acquisitions of a lock passed from thread to thread
+// back to back, each starting right after the previous one ended
+type LockConvoy struct {
+	Begin        uint64 // Raw begin timestamp of the first acquisition
+	End          uint64 // Raw end timestamp of the last acquisition
+	Acquisitions int
+	Threads      []string // Names of the threads taking part
+}
+
+// LockConvoyReport summarizes the convoys of one lock
+type LockConvoyReport struct {
+	Lock           string
+	Acquisitions   int           // Blocks of the lock, in or out of convoys
+	Handoffs       int           // Back-to-back passes to another thread within convoys
+	SerializedTime time.Duration // Summed spans of the convoys
+	Threads        []string      // Names of the threads taking part in any convoy
+	Convoys        []*LockConvoy // In time order
+	Longest        *LockConvoy   // Convoy with the most acquisitions
+}
+
+// DetectLockConvoys finds locks that threads queue on. Blocks whose names match
+// pattern (DefaultLockPattern if empty) are taken to cover the time a lock is held,
+// and the blocks of each name are ordered by begin time across threads: when one
+// starts on another thread at most maxGap after the previous one ended, the lock was
+// handed straight to a waiting thread. At least minHandoffs consecutive handoffs
+// form a convoy; a gap or the same thread taking the lock again ends it. Locks with
+// convoys are returned, most serialized time first.
+func (a *Analyzer) DetectLockConvoys(pattern string, maxGap time.Duration, minHandoffs int) ([]*LockConvoyReport, error) {
+	if pattern == "" {
+		pattern = DefaultLockPattern
+	}
+	matcher, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lock pattern: %w", err)
+	}
+
+	// Group lock blocks by name, keeping the names in order of first appearance
+	var names []string
+	locks := make(map[string][]*Invocation)
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		walkBlocks(thread.Blocks, func(block *parser.Block, _ int) {
+			if block.Unclosed {
+				return
+			}
+			name, _, _ := a.resolveBlock(block)
+			if !matcher.MatchString(name) {
+				return
+			}
+			if _, seen := locks[name]; !seen {
+				names = append(names, name)
+			}
+			locks[name] = append(locks[name], &Invocation{Block: block, ThreadID: threadID, ThreadName: thread.ThreadName})
+		})
+	}
+
+	var reports []*LockConvoyReport
+	for _, name := range names {
+		if report := findConvoys(name, locks[name], maxGap, minHandoffs); len(report.Convoys) > 0 {
+			reports = append(reports, report)
+		}
+	}
+
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].SerializedTime > reports[j].SerializedTime
+	})
+
+	return reports, nil
+}
+
+// findConvoys splits the acquisitions of one lock into runs of back-to-back
+// handoffs and keeps the runs with at least minHandoffs of them
+func findConvoys(name string, acquisitions []*Invocation, maxGap time.Duration, minHandoffs int) *LockConvoyReport {
+	report := &LockConvoyReport{Lock: name, Acquisitions: len(acquisitions)}
+	sort.SliceStable(acquisitions, func(i, j int) bool {
+		return acquisitions[i].Block.Begin < acquisitions[j].Block.Begin
+	})
+
+	allThreads := make(map[string]bool)
+	var run []*Invocation
+	flush := func() {
+		if len(run)-1 >= minHandoffs {
+			threads := make(map[string]bool)
+			for _, acquisition := range run {
+				threads[acquisition.ThreadName] = true
+				allThreads[acquisition.ThreadName] = true
+			}
+			convoy := &LockConvoy{
+				Begin:        run[0].Block.Begin,
+				End:          run[len(run)-1].Block.End,
+				Acquisitions: len(run),
+				Threads:      sortedKeys(threads),
+			}
+			report.Convoys = append(report.Convoys, convoy)
+			report.Handoffs += len(run) - 1
+			report.SerializedTime += time.Duration(convoy.End - convoy.Begin)
+			if report.Longest == nil || convoy.Acquisitions > report.Longest.Acquisitions {
+				report.Longest = convoy
+			}
+		}
+		run = run[:0]
+	}
+
+	for _, acquisition := range acquisitions {
+		if len(run) > 0 {
+			previous := run[len(run)-1]
+			handoff := acquisition.ThreadID != previous.ThreadID &&
+				acquisition.Block.Begin >= previous.Block.End &&
+				time.Duration(acquisition.Block.Begin-previous.Block.End) <= maxGap
+			if !handoff {
+				flush()
+			}
+		}
+		run = append(run, acquisition)
+	}
+	flush()
+
+	report.Threads = sortedKeys(allThreads)
+	return report
+}
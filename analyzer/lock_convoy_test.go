@@ -0,0 +1,119 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// convoyProfile has three threads, A, B and C, passing locks around:
+//   - Lock goes A, B, C, A with gaps of 2, 1 and 2ns, then A takes it alone later
+//   - Mutex goes A, A, B, C with no gaps, so the run starts over at the second A
+//   - Block and Clock go A, B, C, A with no gaps but aren't lock names
+func convoyProfile() *proftest.Profile {
+	return &proftest.Profile{
+		Begin:       0,
+		End:         1000,
+		Descriptors: proftest.Descriptors("Lock", "Mutex", "Block", "Clock"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "A", Blocks: []proftest.Block{
+				{ID: 1, Begin: 0, End: 10},
+				{ID: 1, Begin: 35, End: 45},
+				{ID: 1, Begin: 200, End: 210},
+				{ID: 2, Begin: 500, End: 510},
+				{ID: 2, Begin: 510, End: 520},
+				{ID: 3, Begin: 600, End: 610},
+				{ID: 3, Begin: 630, End: 640},
+				{ID: 4, Begin: 700, End: 710},
+				{ID: 4, Begin: 730, End: 740},
+			}},
+			{ID: 2, Name: "B", Blocks: []proftest.Block{
+				{ID: 1, Begin: 12, End: 22},
+				{ID: 2, Begin: 520, End: 530},
+				{ID: 3, Begin: 610, End: 620},
+				{ID: 4, Begin: 710, End: 720},
+			}},
+			{ID: 3, Name: "C", Blocks: []proftest.Block{
+				{ID: 1, Begin: 23, End: 33},
+				{ID: 2, Begin: 530, End: 540},
+				{ID: 3, Begin: 620, End: 630},
+				{ID: 4, Begin: 720, End: 730},
+			}},
+		},
+	}
+}
+
+func TestDetectLockConvoys(t *testing.T) {
+	tests := []struct {
+		name        string
+		pattern     string
+		maxGap      time.Duration
+		minHandoffs int
+		want        []string // "lock acquisitions handoffs serialized threads convoys longest"
+	}{
+		{"three-thread convoy", "", 5, 3, []string{"Lock 5 3 45ns [A B C] 1 4"}},
+		{"fewer handoffs", "", 5, 2, []string{"Lock 5 3 45ns [A B C] 1 4", "Mutex 4 2 30ns [A B C] 1 3"}},
+		{"narrow gap", "", 1, 1, []string{"Mutex 4 2 30ns [A B C] 1 3", "Lock 5 1 21ns [B C] 1 2"}},
+		{"gap too narrow for a convoy", "", 1, 3, nil},
+		{"zero gap", "", 0, 2, []string{"Mutex 4 2 30ns [A B C] 1 3"}},
+		{"custom pattern", "^(Block|Clock)$", 0, 3, []string{"Block 4 3 40ns [A B C] 1 4", "Clock 4 3 40ns [A B C] 1 4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, err := newTestAnalyzer(t, convoyProfile()).DetectLockConvoys(tt.pattern, tt.maxGap, tt.minHandoffs)
+			if err != nil {
+				t.Fatalf("DetectLockConvoys: %v", err)
+			}
+			var got []string
+			for _, report := range reports {
+				got = append(got, fmt.Sprintf("%s %d %d %v %v %d %d", report.Lock, report.Acquisitions, report.Handoffs,
+					report.SerializedTime, report.Threads, len(report.Convoys), report.Longest.Acquisitions))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reports = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLockConvoysSeparateRuns(t *testing.T) {
+	// Two convoys of the same lock, split by a long gap; the second is longer
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Lock"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "A", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}, {ID: 1, Begin: 100, End: 110}, {ID: 1, Begin: 120, End: 130}}},
+			{ID: 2, Name: "B", Blocks: []proftest.Block{{ID: 1, Begin: 10, End: 20}, {ID: 1, Begin: 110, End: 120}}},
+			{ID: 3, Name: "C", Blocks: []proftest.Block{{ID: 1, Begin: 130, End: 140}}},
+		},
+	}
+	reports, err := newTestAnalyzer(t, p).DetectLockConvoys("", 0, 1)
+	if err != nil {
+		t.Fatalf("DetectLockConvoys: %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("got %d reports, want 1", len(reports))
+	}
+
+	report := reports[0]
+	var convoys []string
+	for _, convoy := range report.Convoys {
+		convoys = append(convoys, fmt.Sprintf("%d-%d %d %v", convoy.Begin, convoy.End, convoy.Acquisitions, convoy.Threads))
+	}
+	want := []string{"0-20 2 [A B]", "100-140 4 [A B C]"}
+	if !reflect.DeepEqual(convoys, want) {
+		t.Errorf("convoys = %q, want %q", convoys, want)
+	}
+	if report.Longest != report.Convoys[1] || report.Handoffs != 4 || report.SerializedTime != 60 {
+		t.Errorf("longest %+v, %d handoffs over %v; want the second convoy, 4 over 60ns", report.Longest, report.Handoffs, report.SerializedTime)
+	}
+}
+
+func TestDetectLockConvoysInvalidPattern(t *testing.T) {
+	if _, err := newTestAnalyzer(t, convoyProfile()).DetectLockConvoys("(", 5, 3); err == nil {
+		t.Error("accepted an invalid pattern")
+	}
+}
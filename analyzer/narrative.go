@@ -0,0 +1,63 @@
+package analyzer
+
+import "time"
+
+// narrativeSteps is the number of progress steps reported by GetNarrativeSummary
+const narrativeSteps = 4
+
+// NarrativeSummary holds the handful of facts needed to describe a capture in a few
+// sentences, each taken from the analysis behind the dedicated tool
+type NarrativeSummary struct {
+	CaptureDuration time.Duration
+	DominantThread  *ThreadStats     // Busiest thread, as ranked by GetThreadStatistics; nil if no threads
+	TopFunction     *BlockInfo       // Function with the most self time on the dominant thread, by name
+	WorstBlock      *WorstInvocation // As found by FindWorstInvocation; nil if no closed blocks
+	Parallelism     float64          // As computed by GetUtilization
+
+	HighSeverityIssues int
+	TotalIssues        int
+	Headline           *PerformanceIssue // Highest-scoring high-severity issue; nil if none
+}
+
+// GetNarrativeSummary gathers the opening facts about a capture: its duration, the
+// dominant thread and what it spent its time on, the single worst block, the
+// high-severity issues and the overall parallelism
+func (a *Analyzer) GetNarrativeSummary(progress ProgressFunc) *NarrativeSummary {
+	summary := &NarrativeSummary{CaptureDuration: a.profile.GetTotalDuration()}
+
+	if stats := a.GetThreadStatistics(); len(stats) > 0 {
+		summary.DominantThread = stats[0]
+		thread := a.profile.Thread(stats[0].ThreadID)
+		functions := make(map[string]*BlockInfo)
+		a.aggregateBlocks(thread.Blocks, thread.ThreadID, thread.ThreadName, ByName, functions)
+		for _, info := range functions {
+			if summary.TopFunction == nil || info.SelfDuration > summary.TopFunction.SelfDuration ||
+				(info.SelfDuration == summary.TopFunction.SelfDuration && lessBlockInfo(info, summary.TopFunction)) {
+				summary.TopFunction = info
+			}
+		}
+	}
+	progress.report(1, narrativeSteps)
+
+	summary.WorstBlock = a.FindWorstInvocation()
+	progress.report(2, narrativeSteps)
+
+	summary.Parallelism = a.GetUtilization().Parallelism
+	progress.report(3, narrativeSteps)
+
+	// Issues are sorted by severity first, so the high ones lead
+	issues := a.AnalyzePerformanceIssues()
+	summary.TotalIssues = len(issues)
+	for _, issue := range issues {
+		if issue.Severity != "high" {
+			break
+		}
+		if summary.HighSeverityIssues == 0 {
+			summary.Headline = issue
+		}
+		summary.HighSeverityIssues++
+	}
+	progress.report(4, narrativeSteps)
+
+	return summary
+}
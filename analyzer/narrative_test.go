@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestGetNarrativeSummary(t *testing.T) {
+	// Main runs Frame, which calls Update twice (30ns of self time each) and Render
+	// (20ns); Worker runs one 70ns Job, the worst single block
+	mainBlocks := []proftest.Block{
+		{ID: 2, Begin: 0, End: 30},
+		{ID: 2, Begin: 40, End: 70},
+		{ID: 3, Begin: 70, End: 90},
+		{ID: 1, Begin: 0, End: 100},
+	}
+	tests := []struct {
+		name    string
+		threads []proftest.Thread
+		want    string // "dominant top self calls worst"
+	}{
+		{"two threads", []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: mainBlocks},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 4, Begin: 100, End: 170}}},
+		}, "Main Update 60ns 2 2/4/0"},
+		{"busier worker", []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: mainBlocks},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 4, Begin: 0, End: 150}}},
+		}, "Worker Job 150ns 1 2/4/0"},
+		{"single thread", []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: mainBlocks},
+		}, "Main Update 60ns 2 1/2/0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t, &proftest.Profile{
+				Begin:       0,
+				End:         200,
+				Descriptors: proftest.Descriptors("Frame", "Update", "Render", "Job"),
+				Threads:     tt.threads,
+			})
+			summary := a.GetNarrativeSummary(nil)
+			if summary.DominantThread == nil || summary.TopFunction == nil || summary.WorstBlock == nil {
+				t.Fatalf("summary is missing facts: %+v", summary)
+			}
+
+			got := fmt.Sprintf("%s %s %v %d %s", summary.DominantThread.ThreadName, summary.TopFunction.Name,
+				summary.TopFunction.SelfDuration, summary.TopFunction.CallCount, summary.WorstBlock.Key)
+			if got != tt.want {
+				t.Errorf("summary = %s, want %s", got, tt.want)
+			}
+
+			// Each fact matches the analysis it is taken from
+			if stats := a.GetThreadStatistics(); *summary.DominantThread != *stats[0] {
+				t.Errorf("dominant thread = %+v, want %+v", summary.DominantThread, stats[0])
+			}
+			if worst := a.FindWorstInvocation(); summary.WorstBlock.Key != worst.Key || summary.WorstBlock.SelfDuration != worst.SelfDuration {
+				t.Errorf("worst block = %s (%v), want %s (%v)", summary.WorstBlock.Key, summary.WorstBlock.SelfDuration, worst.Key, worst.SelfDuration)
+			}
+			if parallelism := a.GetUtilization().Parallelism; summary.Parallelism != parallelism {
+				t.Errorf("parallelism = %v, want %v", summary.Parallelism, parallelism)
+			}
+			checkIssueCounts(t, summary, a.AnalyzePerformanceIssues())
+		})
+	}
+}
+
+// checkIssueCounts checks the issue counts and headline of a summary against the issues
+func checkIssueCounts(t *testing.T, summary *NarrativeSummary, issues []*PerformanceIssue) {
+	t.Helper()
+	high := 0
+	var headline *PerformanceIssue
+	for _, issue := range issues {
+		if issue.Severity == "high" {
+			if headline == nil {
+				headline = issue
+			}
+			high++
+		}
+	}
+	if summary.TotalIssues != len(issues) || summary.HighSeverityIssues != high {
+		t.Errorf("issues = %d high of %d, want %d of %d", summary.HighSeverityIssues, summary.TotalIssues, high, len(issues))
+	}
+	if (headline == nil) != (summary.Headline == nil) ||
+		headline != nil && (summary.Headline.Type != headline.Type || summary.Headline.Description != headline.Description) {
+		t.Errorf("headline = %+v, want %+v", summary.Headline, headline)
+	}
+}
+
+func TestGetNarrativeSummaryEmpty(t *testing.T) {
+	summary := newTestAnalyzer(t, &proftest.Profile{Begin: 100, End: 100}).GetNarrativeSummary(nil)
+	if summary.DominantThread != nil || summary.TopFunction != nil || summary.WorstBlock != nil || summary.Headline != nil {
+		t.Errorf("summary = %+v, want no thread, function, block or headline", summary)
+	}
+	if summary.CaptureDuration != 0 || summary.TotalIssues != 0 || summary.Parallelism != 0 {
+		t.Errorf("summary = %+v, want zero duration, issues and parallelism", summary)
+	}
+}
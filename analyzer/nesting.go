@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+const (
+	// slowChildMinCalls is the minimum invocation count for a function to be checked
+	slowChildMinCalls = 10
+	// slowChildRatio is how many times the typical child time the worst case must exceed
+	slowChildRatio = 10.0
+	// slowChildMinWorst ignores worst cases too small to matter
+	slowChildMinWorst = time.Millisecond
+)
+
+// childTimeStats tracks per-invocation child time for one function
+type childTimeStats struct {
+	name       string
+	location   string
+	threadID   uint64
+	threadName string
+	childTimes []time.Duration
+	worst      *parser.Block // Invocation with the largest child time
+	worstChild time.Duration
+}
+
+// detectIntermittentSlowChildren flags frequently-called functions whose child time is
+// usually small but occasionally huge - a "fast path" that sometimes does heavy work
+func (a *Analyzer) detectIntermittentSlowChildren() []*PerformanceIssue {
+	var issues []*PerformanceIssue
+
+	statsMap := make(map[string]*childTimeStats)
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		a.collectChildTimes(thread.Blocks, threadID, thread.ThreadName, statsMap)
+	}
+
+	for _, stats := range statsMap {
+		if len(stats.childTimes) < slowChildMinCalls || stats.worstChild < slowChildMinWorst {
+			continue
+		}
+
+		sort.Slice(stats.childTimes, func(i, j int) bool {
+			return stats.childTimes[i] < stats.childTimes[j]
+		})
+		typical := stats.childTimes[len(stats.childTimes)/2]
+
+		if typical > 0 && float64(stats.worstChild)/float64(typical) < slowChildRatio {
+			continue
+		}
+
+		worstChildName, _, _ := a.resolveBlock(longestBlock(stats.worst.Children))
+
+		issues = append(issues, &PerformanceIssue{
+			Type:  "Intermittent Slow Child",
+			Score: impactScore(a.captureFraction(float64(stats.worstChild - typical))),
+			Description: fmt.Sprintf("Function '%s' usually spends %v in children but once spent %v (in '%s') across %d calls",
+				stats.name, typical, stats.worstChild, worstChildName, len(stats.childTimes)),
+			Location:   stats.location,
+			Function:   stats.name,
+			Duration:   stats.worstChild,
+			ThreadID:   stats.threadID,
+			ThreadName: stats.threadName,
+		})
+	}
+
+	return issues
+}
+
+// collectChildTimes records each invocation's total child time per function
+func (a *Analyzer) collectChildTimes(blocks []*parser.Block, threadID uint64, threadName string, statsMap map[string]*childTimeStats) {
+	walkBlocks(blocks, func(block *parser.Block, _ int) {
+		name, file, line := a.resolveBlock(block)
+		key := a.aggregationKey(block, name)
+
+		stats, ok := statsMap[key]
+		if !ok {
+			stats = &childTimeStats{name: name, location: formatLocation(file, line)}
+			statsMap[key] = stats
+		}
+
+		childTime := block.Duration() - a.selfTime(block)
+		stats.childTimes = append(stats.childTimes, childTime)
+		if stats.worst == nil || childTime > stats.worstChild {
+			stats.worst = block
+			stats.worstChild = childTime
+			stats.threadID = threadID
+			stats.threadName = threadName
+		}
+	})
+}
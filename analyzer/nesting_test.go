@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// fastPathProfile has calls of Fast (ID 1), each with one Slow child (ID 2) taking
+// childTime(i) of its i-th call, laid out back to back
+func fastPathProfile(calls int, childTime func(i int) time.Duration) *proftest.Profile {
+	var blocks []proftest.Block
+	begin := uint64(0)
+	for i := 0; i < calls; i++ {
+		child := uint64(childTime(i))
+		blocks = append(blocks,
+			proftest.Block{ID: 2, Begin: begin + 1, End: begin + 1 + child},
+			proftest.Block{ID: 1, Begin: begin, End: begin + child + 2},
+		)
+		begin += child + 10
+	}
+	return &proftest.Profile{
+		Descriptors: proftest.Descriptors("Fast", "Slow"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+	}
+}
+
+func TestDetectIntermittentSlowChildren(t *testing.T) {
+	spikeAt := func(spike time.Duration) func(int) time.Duration {
+		return func(i int) time.Duration {
+			if i == 3 {
+				return spike
+			}
+			return 10 * time.Microsecond
+		}
+	}
+
+	tests := []struct {
+		name      string
+		calls     int
+		childTime func(int) time.Duration
+		want      bool
+	}{
+		{"one huge child time", 20, spikeAt(5 * time.Millisecond), true},
+		{"too few calls", slowChildMinCalls - 1, spikeAt(5 * time.Millisecond), false},
+		{"worst case too small", 20, spikeAt(500 * time.Microsecond), false},
+		{"steady child time", 20, func(int) time.Duration { return 2 * time.Millisecond }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := newTestAnalyzer(t, fastPathProfile(tt.calls, tt.childTime)).detectIntermittentSlowChildren()
+			if got := len(issues) > 0; got != tt.want {
+				t.Fatalf("reported = %t, want %t (%d issues)", got, tt.want, len(issues))
+			}
+			if !tt.want {
+				return
+			}
+			issue := issues[0]
+			if issue.Function != "Fast" || issue.Location != "Fast.cpp:10" {
+				t.Errorf("issue for %s at %s, want Fast at Fast.cpp:10", issue.Function, issue.Location)
+			}
+			if issue.Duration != 5*time.Millisecond {
+				t.Errorf("Duration = %v, want the 5ms worst child time", issue.Duration)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// OnCPUTime splits the wall time of an interval on a thread into the part the thread
+// was actually running. Time overlapping the thread's context switches (switched-out
+// periods) is subtracted. ok is false when the profile was loaded without context
+// switches, since on-CPU time can't be told apart from wall time then.
+func (a *Analyzer) OnCPUTime(threadID uint64, begin, end uint64) (onCPU time.Duration, ok bool) {
+	if !a.profile.ContextSwitchesLoaded() || end <= begin {
+		return 0, a.profile.ContextSwitchesLoaded()
+	}
+
+	a.switchedOutOnce.Do(a.buildSwitchedOut)
+
+	wall := time.Duration(end - begin)
+	return wall - overlapDuration(a.switchedOut[threadID], begin, end), true
+}
+
+// switchedOutTime returns how long the thread was switched out between its first
+// block's begin and its last block's end
+func (a *Analyzer) switchedOutTime(thread *parser.ThreadData) time.Duration {
+	onCPU, ok := a.OnCPUTime(thread.ThreadID, thread.FirstBlockBegin, thread.LastBlockEnd)
+	if !ok {
+		return 0
+	}
+	return time.Duration(thread.LastBlockEnd-thread.FirstBlockBegin) - onCPU
+}
+
+// buildSwitchedOut merges each thread's context switches into sorted, disjoint intervals
+func (a *Analyzer) buildSwitchedOut() {
+	a.switchedOut = make(map[uint64][]interval)
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		intervals := make([]interval, 0, len(thread.ContextSwitches))
+		for _, cs := range thread.ContextSwitches {
+			if cs.End > cs.Begin {
+				intervals = append(intervals, interval{begin: cs.Begin, end: cs.End})
+			}
+		}
+		a.switchedOut[threadID] = mergeIntervals(intervals)
+	}
+}
+
+// overlapDuration returns how much of [begin, end) is covered by the sorted, disjoint intervals
+func overlapDuration(intervals []interval, begin, end uint64) time.Duration {
+	// First interval ending after begin
+	i := sort.Search(len(intervals), func(i int) bool {
+		return intervals[i].end > begin
+	})
+
+	total := time.Duration(0)
+	for ; i < len(intervals) && intervals[i].begin < end; i++ {
+		total += time.Duration(min(end, intervals[i].end) - max(begin, intervals[i].begin))
+	}
+	return total
+}
@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestOnCPUTime(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Work"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main",
+				ContextSwitches: []proftest.ContextSwitch{
+					{ThreadID: 1, Begin: 200, End: 300},
+					{ThreadID: 1, Begin: 250, End: 400}, // Overlaps the previous one
+					{ThreadID: 1, Begin: 600, End: 650},
+					{ThreadID: 1, Begin: 700, End: 700}, // Empty
+				},
+				Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}},
+			},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 500}}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		thread     uint64
+		begin, end uint64
+		want       time.Duration
+	}{
+		{"whole block", 1, 0, 1000, 750},
+		{"before any switch", 1, 0, 200, 200},
+		{"inside a switch", 1, 260, 390, 0},
+		{"partial overlap", 1, 350, 625, 200},
+		{"empty interval", 1, 500, 500, 0},
+		{"never switched out", 2, 0, 500, 500},
+	}
+
+	a := newTestAnalyzer(t, p)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			onCPU, ok := a.OnCPUTime(tt.thread, tt.begin, tt.end)
+			if !ok || onCPU != tt.want {
+				t.Errorf("OnCPUTime = %v, %t; want %v, true", onCPU, ok, tt.want)
+			}
+		})
+	}
+
+	t.Run("without context switches", func(t *testing.T) {
+		a := NewAnalyzer(p.Parse(t, parser.ReadOptions{SkipContextSwitches: true}))
+		if onCPU, ok := a.OnCPUTime(1, 0, 1000); ok {
+			t.Errorf("OnCPUTime = %v, true; want not ok", onCPU)
+		}
+	})
+}
+
+func TestSwitchedOutTime(t *testing.T) {
+	// Main's blocks span 100..900; one switch starts before the span, one ends after
+	// it and one falls between its blocks. Worker is never switched out; Idle has no blocks.
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Work"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main",
+				ContextSwitches: []proftest.ContextSwitch{
+					{ThreadID: 1, Begin: 0, End: 150},
+					{ThreadID: 1, Begin: 400, End: 600},
+					{ThreadID: 1, Begin: 850, End: 2000},
+				},
+				Blocks: []proftest.Block{{ID: 1, Begin: 100, End: 200}, {ID: 1, Begin: 800, End: 900}},
+			},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 500}}},
+			{ID: 3, Name: "Idle"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		thread uint64
+		skip   bool
+		want   time.Duration
+	}{
+		{"clipped to the span", 1, false, 50 + 200 + 50},
+		{"never switched out", 2, false, 0},
+		{"no blocks", 3, false, 0},
+		{"without context switches", 1, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAnalyzer(p.Parse(t, parser.ReadOptions{SkipContextSwitches: tt.skip}))
+			if got := a.switchedOutTime(a.profile.Thread(tt.thread)); got != tt.want {
+				t.Errorf("switchedOutTime = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+)
+
+// sortedThreadIDs returns the IDs of all captured threads in ascending order.
+// Aggregations walk threads in this order so that results which keep the first
+// thread seen (e.g. the ThreadID of a hotspot) don't depend on map iteration.
+func (a *Analyzer) sortedThreadIDs() []uint64 {
+	threadIDs := append([]uint64(nil), a.profile.ThreadIDs()...)
+	sort.Slice(threadIDs, func(i, j int) bool { return threadIDs[i] < threadIDs[j] })
+	return threadIDs
+}
+
+// lessBlockInfo is the tiebreaker for rankings whose primary key is equal:
+// name, then source location, then thread ID, then begin time
+func lessBlockInfo(x, y *BlockInfo) bool {
+	if c := strings.Compare(x.Name, y.Name); c != 0 {
+		return c < 0
+	}
+	if c := strings.Compare(x.File, y.File); c != 0 {
+		return c < 0
+	}
+	if x.Line != y.Line {
+		return x.Line < y.Line
+	}
+	if x.ThreadID != y.ThreadID {
+		return x.ThreadID < y.ThreadID
+	}
+	return x.Begin < y.Begin
+}
@@ -0,0 +1,100 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestLessBlockInfo(t *testing.T) {
+	base := BlockInfo{Name: "Update", File: "update.cpp", Line: 10, ThreadID: 2, Begin: 100}
+	with := func(change func(*BlockInfo)) *BlockInfo {
+		info := base
+		change(&info)
+		return &info
+	}
+
+	tests := []struct {
+		name string
+		x, y *BlockInfo
+		want bool
+	}{
+		{"name", with(func(b *BlockInfo) { b.Name = "Render" }), &base, true},
+		{"name before file", with(func(b *BlockInfo) { b.Name = "Render"; b.File = "z.cpp" }), &base, true},
+		{"file", with(func(b *BlockInfo) { b.File = "a.cpp" }), &base, true},
+		{"line", with(func(b *BlockInfo) { b.Line = 5 }), &base, true},
+		{"thread", with(func(b *BlockInfo) { b.ThreadID = 1 }), &base, true},
+		{"begin", with(func(b *BlockInfo) { b.Begin = 50 }), &base, true},
+		{"greater", &base, with(func(b *BlockInfo) { b.Begin = 50 }), false},
+		{"equal", &base, with(func(*BlockInfo) {}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lessBlockInfo(tt.x, tt.y); got != tt.want {
+				t.Errorf("lessBlockInfo = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortedThreadIDs(t *testing.T) {
+	p := &proftest.Profile{Descriptors: proftest.Descriptors("Run")}
+	for _, id := range []uint64{30, 10, 20} {
+		p.Threads = append(p.Threads, proftest.Thread{ID: id, Name: fmt.Sprint(id), Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}}})
+	}
+	if got, want := newTestAnalyzer(t, p).sortedThreadIDs(), []uint64{10, 20, 30}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sortedThreadIDs() = %v, want %v", got, want)
+	}
+}
+
+func TestRankingsBreakTiesDeterministically(t *testing.T) {
+	// Every block lasts 10ns, on threads written in descending ID order
+	p := &proftest.Profile{Descriptors: proftest.Descriptors("C", "A", "B")}
+	for _, id := range []uint64{3, 2, 1} {
+		var blocks []proftest.Block
+		for d := uint32(1); d <= 3; d++ {
+			begin := uint64(d) * 100
+			blocks = append(blocks, proftest.Block{ID: d, Begin: begin, End: begin + 10})
+		}
+		p.Threads = append(p.Threads, proftest.Thread{ID: id, Name: fmt.Sprint(id), Blocks: blocks})
+	}
+
+	describe := func(infos []*BlockInfo) []string {
+		s := make([]string, len(infos))
+		for i, info := range infos {
+			s[i] = fmt.Sprintf("%s@%d", info.Name, info.ThreadID)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name string
+		run  func(a *Analyzer) []string
+		want []string
+	}{
+		{"slowest blocks", func(a *Analyzer) []string { return describe(a.GetSlowestBlocks(4)) }, []string{"A@1", "A@2", "A@3", "B@1"}},
+		{"hotspots", func(a *Analyzer) []string { return describe(a.GetHotspots(3)) }, []string{"A@1", "B@1", "C@1"}},
+		{"most called", func(a *Analyzer) []string { return describe(a.GetMostCalled(3)) }, []string{"A@1", "B@1", "C@1"}},
+		{"thread statistics", func(a *Analyzer) []string {
+			var ids []string
+			for _, stats := range a.GetThreadStatistics() {
+				ids = append(ids, fmt.Sprint(stats.ThreadID))
+			}
+			return ids
+		}, []string{"1", "2", "3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map iteration order varies between runs, so repeat with fresh analyzers
+			for i := 0; i < 10; i++ {
+				if got := tt.run(newTestAnalyzer(t, p)); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("run %d: got %v, want %v", i, got, tt.want)
+				}
+			}
+		})
+	}
+}
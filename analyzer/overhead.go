@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+const (
+	// defaultBlockCost is the assumed cost of recording one block (two timestamps
+	// and a buffer write) when the capture doesn't reveal it
+	defaultBlockCost = 50 * time.Nanosecond
+
+	// maxInstrumentationGap is the longest gap between back-to-back sibling blocks
+	// taken to be pure instrumentation cost rather than uninstrumented code
+	maxInstrumentationGap = time.Microsecond
+
+	// minGapSamples is the number of such gaps needed to trust the measured cost
+	minGapSamples = 50
+
+	// tinyBlockDuration is the length under which a block is mostly overhead
+	tinyBlockDuration = time.Microsecond
+)
+
+// InstrumentationOverhead estimates how much of the recorded time is the profiler's
+// own cost. It is a heuristic: the per-block cost is taken from the smallest gaps
+// between back-to-back sibling blocks, where nothing but the profiler ran, and
+// multiplied by the number of blocks.
+type InstrumentationOverhead struct {
+	Blocks   int
+	BusyTime time.Duration // Time covered by top-level blocks, summed over threads
+
+	// BlockCost is the estimated cost of recording one block, measured from
+	// GapSamples gaps if BlockCostMeasured and assumed otherwise
+	BlockCost         time.Duration
+	BlockCostMeasured bool
+	GapSamples        int
+
+	Overhead        time.Duration // Blocks * BlockCost
+	OverheadPercent float64       // Overhead as a percentage of BusyTime
+	Level           string        // "low", "moderate" or "high"
+
+	TinyBlocks int // Blocks shorter than a microsecond
+
+	// Densest are the functions with the most calls averaging under a microsecond,
+	// the first candidates for removing instrumentation
+	Densest []*BlockInfo
+}
+
+// EstimateInstrumentationOverhead measures block density and per-block recording cost
+// to tell whether the profiler likely distorted the capture. limit caps Densest.
+func (a *Analyzer) EstimateInstrumentationOverhead(limit int) *InstrumentationOverhead {
+	result := &InstrumentationOverhead{}
+	var gaps []time.Duration
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		for _, block := range thread.Blocks {
+			result.BusyTime += block.Duration()
+		}
+		gaps = appendSiblingGaps(gaps, thread.Blocks)
+
+		walkBlocks(thread.Blocks, func(block *parser.Block, _ int) {
+			result.Blocks++
+			if block.Duration() < tinyBlockDuration {
+				result.TinyBlocks++
+			}
+			gaps = appendSiblingGaps(gaps, block.Children)
+		})
+	}
+
+	result.GapSamples = len(gaps)
+	result.BlockCost = defaultBlockCost
+	if len(gaps) >= minGapSamples {
+		sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+		result.BlockCost = gaps[len(gaps)/2]
+		result.BlockCostMeasured = true
+	}
+
+	result.Overhead = time.Duration(result.Blocks) * result.BlockCost
+	if result.BusyTime > 0 {
+		result.OverheadPercent = float64(result.Overhead) / float64(result.BusyTime) * 100
+	}
+
+	switch {
+	case result.OverheadPercent >= 10:
+		result.Level = "high"
+	case result.OverheadPercent >= 2:
+		result.Level = "moderate"
+	default:
+		result.Level = "low"
+	}
+
+	for _, hotspot := range a.aggregateHotspots() {
+		if hotspot.AvgDuration < tinyBlockDuration {
+			result.Densest = append(result.Densest, hotspot)
+		}
+	}
+	sort.Slice(result.Densest, func(i, j int) bool {
+		if result.Densest[i].CallCount != result.Densest[j].CallCount {
+			return result.Densest[i].CallCount > result.Densest[j].CallCount
+		}
+		return lessBlockInfo(result.Densest[i], result.Densest[j])
+	})
+	if limit > 0 && len(result.Densest) > limit {
+		result.Densest = result.Densest[:limit]
+	}
+
+	return result
+}
+
+// appendSiblingGaps appends the gaps between consecutive blocks that are short
+// enough to be only instrumentation cost
+func appendSiblingGaps(gaps []time.Duration, blocks []*parser.Block) []time.Duration {
+	for i := 1; i < len(blocks); i++ {
+		if blocks[i].Begin <= blocks[i-1].End {
+			continue
+		}
+		if gap := time.Duration(blocks[i].Begin - blocks[i-1].End); gap <= maxInstrumentationGap {
+			gaps = append(gaps, gap)
+		}
+	}
+	return gaps
+}
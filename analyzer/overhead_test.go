@@ -0,0 +1,98 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// denseProfile is a 30µs Frame holding 100 back-to-back 200ns blocks 100ns apart,
+// every fifth one Small and the others Tiny
+func denseProfile() *proftest.Profile {
+	var blocks []proftest.Block
+	for i := uint64(0); i < 100; i++ {
+		id := uint32(2)
+		if i%5 == 0 {
+			id = 3
+		}
+		blocks = append(blocks, proftest.Block{ID: id, Begin: i * 300, End: i*300 + 200})
+	}
+	blocks = append(blocks, proftest.Block{ID: 1, Begin: 0, End: 30000})
+	return &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Tiny", "Small"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+	}
+}
+
+func TestEstimateInstrumentationOverhead(t *testing.T) {
+	ms := uint64(time.Millisecond)
+	sparse := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 0, End: 100 * ms},
+			{ID: 3, Begin: 200 * ms, End: 300 * ms},
+			{ID: 1, Begin: 0, End: 1000 * ms},
+		}}},
+	}
+	single := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}}},
+	}
+	empty := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main"}},
+	}
+
+	tests := []struct {
+		name     string
+		profile  *proftest.Profile
+		limit    int
+		blocks   int
+		tiny     int
+		busy     time.Duration
+		cost     time.Duration
+		measured bool
+		samples  int
+		overhead time.Duration
+		level    string
+		densest  []string
+	}{
+		{"extreme density", denseProfile(), 0, 101, 100, 30 * time.Microsecond, 100, true, 99, 10100, "high", []string{"Tiny", "Small"}},
+		{"densest limited", denseProfile(), 1, 101, 100, 30 * time.Microsecond, 100, true, 99, 10100, "high", []string{"Tiny"}},
+		{"sparse", sparse, 0, 3, 0, time.Second, defaultBlockCost, false, 0, 3 * defaultBlockCost, "low", []string{}},
+		{"one short block", single, 0, 1, 0, time.Microsecond, defaultBlockCost, false, 0, defaultBlockCost, "moderate", []string{}},
+		{"no blocks", empty, 0, 0, 0, 0, defaultBlockCost, false, 0, 0, "low", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overhead := newTestAnalyzer(t, tt.profile).EstimateInstrumentationOverhead(tt.limit)
+
+			if overhead.Blocks != tt.blocks || overhead.TinyBlocks != tt.tiny || overhead.BusyTime != tt.busy {
+				t.Errorf("%d blocks (%d tiny) over %v, want %d (%d tiny) over %v",
+					overhead.Blocks, overhead.TinyBlocks, overhead.BusyTime, tt.blocks, tt.tiny, tt.busy)
+			}
+			if overhead.BlockCost != tt.cost || overhead.BlockCostMeasured != tt.measured || overhead.GapSamples != tt.samples {
+				t.Errorf("block cost %v (measured %t from %d gaps), want %v (measured %t from %d gaps)",
+					overhead.BlockCost, overhead.BlockCostMeasured, overhead.GapSamples, tt.cost, tt.measured, tt.samples)
+			}
+			if overhead.Overhead != tt.overhead || overhead.Level != tt.level {
+				t.Errorf("overhead %v (%s), want %v (%s)", overhead.Overhead, overhead.Level, tt.overhead, tt.level)
+			}
+
+			wantPercent := 0.0
+			if tt.busy > 0 {
+				wantPercent = float64(tt.overhead) / float64(tt.busy) * 100
+			}
+			if overhead.OverheadPercent != wantPercent {
+				t.Errorf("overhead percent = %v, want %v", overhead.OverheadPercent, wantPercent)
+			}
+
+			if got := blockNames(overhead.Densest); !reflect.DeepEqual(got, tt.densest) {
+				t.Errorf("densest = %v, want %v", got, tt.densest)
+			}
+		})
+	}
+}
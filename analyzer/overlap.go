@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// OverlapBlock is one side of a BlockOverlap
+type OverlapBlock struct {
+	Name  string
+	File  string
+	Line  int32
+	Begin uint64 // Raw timestamps
+	End   uint64
+	Depth int
+}
+
+// BlockOverlap is a pair of blocks on one thread whose intervals overlap without one
+// containing the other. Stack-based instrumentation can't produce this, so it points
+// at a begin/end mismatch; the tree keeps both blocks as siblings, which inflates
+// their parent's children time.
+type BlockOverlap struct {
+	ThreadID   uint64
+	ThreadName string
+	First      *OverlapBlock // The block that began first
+	Second     *OverlapBlock
+	Overlap    time.Duration // How long both were open
+}
+
+// OverlapReport summarizes the overlapping blocks of a profile
+type OverlapReport struct {
+	Count    int
+	Threads  int             // Threads with at least one overlap
+	Worst    []*BlockOverlap // Longest overlap first
+	TimedOut bool            // The search gave up early; see FindOverlappingBlocksContext
+}
+
+// FindOverlappingBlocks finds sibling blocks whose intervals overlap, on every
+// thread, and returns the count with the limit longest overlaps. Each block is
+// paired with the earlier sibling reaching furthest into it. Unclosed blocks are
+// skipped: their end is a guess.
+func (a *Analyzer) FindOverlappingBlocks(limit int) *OverlapReport {
+	return a.FindOverlappingBlocksContext(context.Background(), limit)
+}
+
+// FindOverlappingBlocksContext is FindOverlappingBlocks giving up when ctx is done,
+// with the overlaps found until then and TimedOut set
+func (a *Analyzer) FindOverlappingBlocksContext(ctx context.Context, limit int) *OverlapReport {
+	report := &OverlapReport{}
+	b := newBudget(ctx)
+
+	for _, threadID := range a.sortedThreadIDs() {
+		if b.done() {
+			break
+		}
+		thread := a.profile.Thread(threadID)
+		before := report.Count
+
+		a.appendOverlaps(report, threadID, thread.ThreadName, thread.Blocks, 0)
+		walkBlocksWithin(b, thread.Blocks, func(block *parser.Block, depth int) {
+			a.appendOverlaps(report, threadID, thread.ThreadName, block.Children, depth+1)
+		})
+
+		if report.Count > before {
+			report.Threads++
+		}
+	}
+
+	sort.Slice(report.Worst, func(i, j int) bool {
+		x, y := report.Worst[i], report.Worst[j]
+		if x.Overlap != y.Overlap {
+			return x.Overlap > y.Overlap
+		}
+		if x.ThreadID != y.ThreadID {
+			return x.ThreadID < y.ThreadID
+		}
+		return x.Second.Begin < y.Second.Begin
+	})
+	if limit >= 0 && len(report.Worst) > limit {
+		report.Worst = report.Worst[:limit]
+	}
+	report.TimedOut = b.expired
+
+	return report
+}
+
+// appendOverlaps adds the overlaps among one list of siblings at depth to report.
+// Siblings are sorted by begin time, as the tree builder leaves them.
+func (a *Analyzer) appendOverlaps(report *OverlapReport, threadID uint64, threadName string, siblings []*parser.Block, depth int) {
+	var furthest *parser.Block // Earlier sibling with the latest end
+	for _, block := range siblings {
+		if block.Unclosed {
+			continue
+		}
+		if furthest != nil && block.Begin < furthest.End {
+			report.Count++
+			report.Worst = append(report.Worst, &BlockOverlap{
+				ThreadID:   threadID,
+				ThreadName: threadName,
+				First:      a.overlapBlock(furthest, depth),
+				Second:     a.overlapBlock(block, depth),
+				Overlap:    time.Duration(min(furthest.End, block.End) - block.Begin),
+			})
+		}
+		if furthest == nil || block.End > furthest.End {
+			furthest = block
+		}
+	}
+}
+
+// overlapBlock describes block for an overlap report
+func (a *Analyzer) overlapBlock(block *parser.Block, depth int) *OverlapBlock {
+	name, file, line := a.resolveBlock(block)
+	return &OverlapBlock{
+		Name:  name,
+		File:  file,
+		Line:  line,
+		Begin: block.Begin,
+		End:   block.End,
+		Depth: depth,
+	}
+}
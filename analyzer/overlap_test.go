@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestFindOverlappingBlocks(t *testing.T) {
+	// On Main, Load and Save overlap at the top level and Update and Render inside
+	// Frame; on Worker, Load overlaps Save. Decode is unclosed.
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         300,
+		Descriptors: proftest.Descriptors("Load", "Save", "Frame", "Update", "Render", "Decode"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 1, Begin: 0, End: 50},
+				{ID: 2, Begin: 30, End: 80},
+				{ID: 4, Begin: 110, End: 150},
+				{ID: 5, Begin: 140, End: 160},
+				{ID: 3, Begin: 100, End: 200},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{
+				{ID: 1, Begin: 0, End: 10},
+				{ID: 2, Begin: 5, End: 30},
+				{ID: 6, Begin: 20, End: 0},
+			}},
+			{ID: 3, Name: "Clean", Blocks: []proftest.Block{
+				{ID: 4, Begin: 10, End: 20},
+				{ID: 3, Begin: 0, End: 40},
+			}},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+
+	all := []string{"Main Load>Save depth 0 20ns", "Main Update>Render depth 1 10ns", "Worker Load>Save depth 0 5ns"}
+	tests := []struct {
+		name  string
+		limit int
+		want  []string // "thread first>second depth overlap"
+	}{
+		{"all", 10, all},
+		{"limited", 1, all[:1]},
+		{"negative limit keeps all", -1, all},
+		{"zero limit only counts", 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := a.FindOverlappingBlocks(tt.limit)
+			if report.Count != 3 || report.Threads != 2 || report.TimedOut {
+				t.Errorf("count %d on %d threads, timed out %t; want 3 on 2", report.Count, report.Threads, report.TimedOut)
+			}
+			var got []string
+			for _, overlap := range report.Worst {
+				got = append(got, fmt.Sprintf("%s %s>%s depth %d %v", overlap.ThreadName, overlap.First.Name, overlap.Second.Name, overlap.First.Depth, overlap.Overlap))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("overlaps = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindOverlappingBlocksPairsFurthestReaching(t *testing.T) {
+	// Long reaches past Short; Late begins after Short ended but within Long
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Long", "Short", "Late"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 0, End: 10},
+			{ID: 1, Begin: 5, End: 100},
+			{ID: 3, Begin: 50, End: 150},
+		}}},
+	}
+	report := newTestAnalyzer(t, p).FindOverlappingBlocks(-1)
+
+	var got []string
+	for _, overlap := range report.Worst {
+		got = append(got, fmt.Sprintf("%s>%s %v", overlap.First.Name, overlap.Second.Name, overlap.Overlap))
+	}
+	if want := []string{"Long>Late 50ns", "Short>Long 5ns"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("overlaps = %q, want %q", got, want)
+	}
+}
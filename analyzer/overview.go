@@ -0,0 +1,52 @@
+package analyzer
+
+// overviewLimit is the number of entries in each top-N list of the overview
+const overviewLimit = 5
+
+// Overview is a compact snapshot combining the headline results of several analyses
+type Overview struct {
+	SelfTimeHotspots   []*BlockInfo
+	SlowestBlocks      []*BlockInfo
+	BusiestThread      *ThreadStats // nil if the profile has no threads
+	Utilization        *Utilization
+	HighSeverityIssues int
+	TotalIssues        int
+}
+
+// overviewSteps is the number of progress steps reported by GetOverviewWithProgress
+const overviewSteps = 5
+
+// GetOverview runs the core analyses and keeps only their headline results
+func (a *Analyzer) GetOverview() *Overview {
+	return a.GetOverviewWithProgress(nil)
+}
+
+// GetOverviewWithProgress is GetOverview reporting progress after each analysis
+func (a *Analyzer) GetOverviewWithProgress(progress ProgressFunc) *Overview {
+	overview := &Overview{}
+
+	overview.SelfTimeHotspots = a.GetSelfTimeHotspots(overviewLimit)
+	progress.report(1, overviewSteps)
+
+	overview.SlowestBlocks = a.GetSlowestBlocks(overviewLimit)
+	progress.report(2, overviewSteps)
+
+	overview.Utilization = a.GetUtilization()
+	progress.report(3, overviewSteps)
+
+	if stats := a.GetThreadStatistics(); len(stats) > 0 {
+		overview.BusiestThread = stats[0]
+	}
+	progress.report(4, overviewSteps)
+
+	issues := a.AnalyzePerformanceIssues()
+	overview.TotalIssues = len(issues)
+	for _, issue := range issues {
+		if issue.Severity == "high" {
+			overview.HighSeverityIssues++
+		}
+	}
+	progress.report(5, overviewSteps)
+
+	return overview
+}
@@ -0,0 +1,58 @@
+package analyzer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestGetOverview(t *testing.T) {
+	names := make([]string, 8)
+	for i := range names {
+		names[i] = fmt.Sprintf("F%d", i+1)
+	}
+	var blocks []proftest.Block
+	for i := range names {
+		begin := uint64(i * 1000)
+		blocks = append(blocks, proftest.Block{ID: uint32(i + 1), Begin: begin, End: begin + uint64(100*(i+1))})
+	}
+
+	var steps []int
+	overview := newTestAnalyzer(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors(names...),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: blocks},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 50}}},
+		},
+	}).GetOverviewWithProgress(func(done, total int) { steps = append(steps, done) })
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"self time hotspots", fmt.Sprint(blockNames(overview.SelfTimeHotspots)), "[F8 F7 F6 F5 F4]"},
+		{"slowest blocks", fmt.Sprint(blockNames(overview.SlowestBlocks)), "[F8 F7 F6 F5 F4]"},
+		{"busiest thread", overview.BusiestThread.ThreadName, "Main"},
+		{"progress", fmt.Sprint(steps), "[1 2 3 4 5]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+	if overview.Utilization == nil {
+		t.Fatal("Utilization is nil")
+	}
+}
+
+func TestGetOverviewEmptyProfile(t *testing.T) {
+	overview := newTestAnalyzer(t, &proftest.Profile{}).GetOverview()
+	if overview.BusiestThread != nil || len(overview.SlowestBlocks) != 0 || overview.TotalIssues != 0 {
+		t.Errorf("overview of an empty profile = %+v", overview)
+	}
+}
@@ -0,0 +1,63 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PercentBase selects what percentages of a function's time are relative to
+type PercentBase int
+
+const (
+	// PercentOfCapture relates time to the whole capture duration. This is the default.
+	PercentOfCapture PercentBase = iota
+
+	// PercentOfThread relates time to the busy time of the threads the function
+	// ran on, so that a function dominating a quiet thread stands out
+	PercentOfThread
+)
+
+// percentBases lists every percentage base, in the order they're documented
+var percentBases = []PercentBase{PercentOfCapture, PercentOfThread}
+
+// String returns the name accepted by ParsePercentBase
+func (b PercentBase) String() string {
+	if b == PercentOfThread {
+		return "thread"
+	}
+	return "global"
+}
+
+// ParsePercentBase parses "global" or "thread"
+func ParsePercentBase(s string) (PercentBase, error) {
+	names := make([]string, len(percentBases))
+	for i, b := range percentBases {
+		if b.String() == s {
+			return b, nil
+		}
+		names[i] = b.String()
+	}
+	return PercentOfCapture, fmt.Errorf("unknown percent base '%s' (use %s)", s, strings.Join(names, ", "))
+}
+
+// Percent returns d, a share of the aggregated entry info's time, as a percentage
+// of base: the capture duration, or info's ThreadTime
+func (a *Analyzer) Percent(d time.Duration, info *BlockInfo, base PercentBase) float64 {
+	total := a.profile.GetTotalDuration()
+	if base == PercentOfThread {
+		total = info.ThreadTime
+	}
+	if total <= 0 {
+		return 0
+	}
+	return float64(d) / float64(total) * 100
+}
+
+// percentBaseLabel names base in issue descriptions, e.g. "% of total time"
+func percentBaseLabel(base PercentBase) string {
+	if base == PercentOfThread {
+		return "its threads'"
+	}
+	return "total"
+}
@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestParsePercentBase(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    PercentBase
+		wantErr bool
+	}{
+		{"global", PercentOfCapture, false},
+		{"thread", PercentOfThread, false},
+		{"Thread", PercentOfCapture, true},
+		{"", PercentOfCapture, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePercentBase(tt.input)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePercentBase(%q) = %v, %v; want %v, error %t", tt.input, got, err, tt.want, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.input {
+				t.Errorf("String() = %q, want %q", got.String(), tt.input)
+			}
+		})
+	}
+}
+
+// percentBaseProfile is a 1000ns capture where Frame keeps Main busy throughout,
+// and Worker is busy for 50ns: 40ns of Audio and 10ns of Mix
+func percentBaseProfile() *proftest.Profile {
+	return &proftest.Profile{
+		Begin:       0,
+		End:         1000,
+		Descriptors: proftest.Descriptors("Frame", "Audio", "Mix"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 40}, {ID: 3, Begin: 40, End: 50}}},
+		},
+	}
+}
+
+func TestPercent(t *testing.T) {
+	a := newTestAnalyzer(t, percentBaseProfile())
+	hotspots := make(map[string]*BlockInfo)
+	for _, hotspot := range a.GetHotspots(10) {
+		hotspots[hotspot.Name] = hotspot
+	}
+
+	tests := []struct {
+		name   string
+		base   PercentBase
+		want   float64
+		thread string // ThreadTime of the entry
+	}{
+		{"Frame", PercentOfCapture, 100, "1µs"},
+		{"Frame", PercentOfThread, 100, "1µs"},
+		{"Audio", PercentOfCapture, 4, "50ns"},
+		{"Audio", PercentOfThread, 80, "50ns"},
+		{"Mix", PercentOfCapture, 1, "50ns"},
+		{"Mix", PercentOfThread, 20, "50ns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.base.String(), func(t *testing.T) {
+			hotspot := hotspots[tt.name]
+			if got := a.Percent(hotspot.Duration, hotspot, tt.base); got != tt.want {
+				t.Errorf("Percent = %v, want %v", got, tt.want)
+			}
+			if hotspot.ThreadTime.String() != tt.thread {
+				t.Errorf("ThreadTime = %v, want %s", hotspot.ThreadTime, tt.thread)
+			}
+		})
+	}
+}
+
+func TestPercentZeroTotal(t *testing.T) {
+	// Both the capture and the block take no time
+	p := &proftest.Profile{
+		Begin:       50,
+		End:         50,
+		Descriptors: proftest.Descriptors("Tick"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 50, End: 50}}}},
+	}
+	a := newTestAnalyzer(t, p)
+	info := &BlockInfo{}
+	for _, base := range percentBases {
+		if got := a.Percent(0, info, base); got != 0 {
+			t.Errorf("Percent of a zero %v total = %v, want 0", base, got)
+		}
+	}
+	if issues := a.detectHotFunctions(PercentOfThread); len(issues) != 0 {
+		t.Errorf("zero-length capture has %d hot functions", len(issues))
+	}
+}
+
+func TestDetectHotFunctionsPercentBase(t *testing.T) {
+	a := newTestAnalyzer(t, percentBaseProfile())
+
+	tests := []struct {
+		base PercentBase
+		want []string // Issue descriptions, sorted
+	}{
+		{PercentOfCapture, []string{
+			"Function 'Frame' consumes 100.0% of total time (1µs total, 1 calls, avg 1µs)",
+		}},
+		{PercentOfThread, []string{
+			"Function 'Audio' consumes 80.0% of its threads' time (40ns total, 1 calls, avg 40ns)",
+			"Function 'Frame' consumes 100.0% of its threads' time (1µs total, 1 calls, avg 1µs)",
+			"Function 'Mix' consumes 20.0% of its threads' time (10ns total, 1 calls, avg 10ns)",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.base.String(), func(t *testing.T) {
+			var got []string
+			for _, issue := range a.detectHotFunctions(tt.base) {
+				got = append(got, issue.Description)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
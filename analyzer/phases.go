@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// Phase is a stretch of a thread's timeline between significant idle gaps
+type Phase struct {
+	Start      time.Duration // Relative to the capture begin
+	End        time.Duration
+	IdleBefore time.Duration // Gap since the previous phase ended; 0 for the first
+	Busy       time.Duration // Time covered by the phase's top-level blocks
+	Blocks     int           // Top-level blocks in the phase
+
+	// Top is the function with the most inclusive time in the phase, which names it
+	Top *BlockInfo
+}
+
+// PhaseBreakdown is a thread's timeline segmented into phases
+type PhaseBreakdown struct {
+	ThreadID   uint64
+	ThreadName string
+	MinGap     time.Duration
+	IdleTime   time.Duration // Summed gaps between phases
+	Phases     []*Phase
+}
+
+// GetPhases splits a thread's timeline into phases wherever its top-level blocks
+// leave it idle for at least minGap, and names each phase by its dominant function,
+// e.g. a loading phase followed by a render loop
+func (a *Analyzer) GetPhases(thread *parser.ThreadData, minGap time.Duration) *PhaseBreakdown {
+	breakdown := &PhaseBreakdown{
+		ThreadID:   thread.ThreadID,
+		ThreadName: thread.ThreadName,
+		MinGap:     minGap,
+	}
+
+	// Every gap ends one phase and begins the next
+	gaps := idleGaps(thread.Blocks, minGap)
+	var members []*parser.Block
+	var idleBefore time.Duration
+	for _, block := range sortedByBegin(thread.Blocks) {
+		for len(gaps) > 0 && block.Begin >= gaps[0].end {
+			if len(members) > 0 {
+				breakdown.Phases = append(breakdown.Phases, a.newPhase(thread, members, idleBefore))
+				members = nil
+			}
+			idleBefore = time.Duration(gaps[0].end - gaps[0].begin)
+			breakdown.IdleTime += idleBefore
+			gaps = gaps[1:]
+		}
+		members = append(members, block)
+	}
+	if len(members) > 0 {
+		breakdown.Phases = append(breakdown.Phases, a.newPhase(thread, members, idleBefore))
+	}
+
+	return breakdown
+}
+
+// newPhase summarizes the top-level blocks of one phase, sorted by begin time
+func (a *Analyzer) newPhase(thread *parser.ThreadData, blocks []*parser.Block, idleBefore time.Duration) *Phase {
+	end := blocks[0].End
+	for _, block := range blocks {
+		end = max(end, block.End)
+	}
+
+	phase := &Phase{
+		Start:      a.CaptureOffset(blocks[0].Begin),
+		End:        a.CaptureOffset(end),
+		IdleBefore: idleBefore,
+		Busy:       intervalsDuration(mergeIntervals(blockIntervals(blocks))),
+		Blocks:     len(blocks),
+	}
+
+	blockMap := make(map[string]*BlockInfo)
+	a.aggregateBlocks(blocks, thread.ThreadID, thread.ThreadName, ByCallSite, blockMap)
+	for _, info := range blockMap {
+		if phase.Top == nil || info.Duration > phase.Top.Duration ||
+			(info.Duration == phase.Top.Duration && lessBlockInfo(info, phase.Top)) {
+			phase.Top = info
+		}
+	}
+	if phase.Top != nil && phase.Top.CallCount > 0 {
+		phase.Top.AvgDuration = phase.Top.Duration / time.Duration(phase.Top.CallCount)
+	}
+
+	return phase
+}
+
+// idleGaps returns the stretches of at least minGap during which none of blocks
+// was active, between the first block's begin and the last block's end
+func idleGaps(blocks []*parser.Block, minGap time.Duration) []interval {
+	var gaps []interval
+	busy := mergeIntervals(blockIntervals(blocks))
+	for i := 1; i < len(busy); i++ {
+		if gap := (interval{begin: busy[i-1].end, end: busy[i].begin}); time.Duration(gap.end-gap.begin) >= minGap {
+			gaps = append(gaps, gap)
+		}
+	}
+	return gaps
+}
+
+// sortedByBegin returns a copy of blocks ordered by begin time
+func sortedByBegin(blocks []*parser.Block) []*parser.Block {
+	sorted := append([]*parser.Block(nil), blocks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Begin < sorted[j].Begin
+	})
+	return sorted
+}
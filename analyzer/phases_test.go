@@ -0,0 +1,89 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestGetPhases(t *testing.T) {
+	// Main loads for 40ms, idles for 60ms, then renders three frames 5ms apart
+	ms := uint64(time.Millisecond)
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         200 * ms,
+		Descriptors: proftest.Descriptors("Load", "Parse", "Render"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 5 * ms, End: 20 * ms},
+				{ID: 1, Begin: 0, End: 30 * ms},
+				{ID: 1, Begin: 30 * ms, End: 40 * ms},
+				{ID: 3, Begin: 100 * ms, End: 110 * ms},
+				{ID: 3, Begin: 115 * ms, End: 125 * ms},
+				{ID: 3, Begin: 130 * ms, End: 140 * ms},
+			}},
+			{ID: 2, Name: "Idle"},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name   string
+		thread uint64
+		minGap time.Duration
+		want   []string // "start-end idle-before busy blocks top calls"
+		idle   time.Duration
+	}{
+		{"two phases", 1, 10 * time.Millisecond, []string{
+			"0s-40ms 0s 40ms 2 Load 2",
+			"100ms-140ms 60ms 30ms 3 Render 3",
+		}, 60 * time.Millisecond},
+		{"gap below the minimum", 1, 70 * time.Millisecond, []string{
+			"0s-140ms 0s 70ms 5 Load 2",
+		}, 0},
+		{"every gap", 1, time.Millisecond, []string{
+			"0s-40ms 0s 40ms 2 Load 2",
+			"100ms-110ms 60ms 10ms 1 Render 1",
+			"115ms-125ms 5ms 10ms 1 Render 1",
+			"130ms-140ms 5ms 10ms 1 Render 1",
+		}, 70 * time.Millisecond},
+		{"no blocks", 2, 10 * time.Millisecond, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown := a.GetPhases(a.profile.Thread(tt.thread), tt.minGap)
+			var got []string
+			for _, phase := range breakdown.Phases {
+				got = append(got, fmt.Sprintf("%v-%v %v %v %d %s %d", phase.Start, phase.End, phase.IdleBefore, phase.Busy, phase.Blocks, phase.Top.Name, phase.Top.CallCount))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("phases = %q, want %q", got, tt.want)
+			}
+			if breakdown.IdleTime != tt.idle || breakdown.MinGap != tt.minGap {
+				t.Errorf("idle %v with min gap %v, want %v with %v", breakdown.IdleTime, breakdown.MinGap, tt.idle, tt.minGap)
+			}
+		})
+	}
+}
+
+func TestGetPhasesZeroLengthBlocks(t *testing.T) {
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         100,
+		Descriptors: proftest.Descriptors("Tick"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 10, End: 10}, {ID: 1, Begin: 90, End: 90}}}},
+	}
+	a := newTestAnalyzer(t, p)
+
+	breakdown := a.GetPhases(a.profile.Thread(1), time.Nanosecond)
+	if len(breakdown.Phases) != 1 {
+		t.Fatalf("%d phases, want 1", len(breakdown.Phases))
+	}
+	if phase := breakdown.Phases[0]; phase.Busy != 0 || phase.Blocks != 2 || phase.Top.Name != "Tick" {
+		t.Errorf("phase = %+v, want both ticks with no busy time", phase)
+	}
+}
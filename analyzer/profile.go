@@ -0,0 +1,61 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// ThreadIterator gives access to the threads of a capture
+type ThreadIterator interface {
+	// ThreadIDs returns the ID of every thread, in any order
+	ThreadIDs() []uint64
+
+	// Thread returns the thread with the given ID, or nil if there is none
+	Thread(id uint64) *parser.ThreadData
+}
+
+// DescriptorLookup gives access to the block descriptors of a capture
+type DescriptorLookup interface {
+	// DescriptorIDs returns the ID of every descriptor, in any order
+	DescriptorIDs() []uint32
+
+	// Descriptor returns the descriptor with the given ID, or nil if there is none
+	Descriptor(id uint32) *parser.BlockDescriptor
+}
+
+// Profile is everything the analyzer reads from a capture. *parser.ProfileData
+// implements it; going through it instead of the parser's maps keeps the analyzer
+// working across parser layout changes, and lets it run on hand-built data.
+type Profile interface {
+	ThreadIterator
+	DescriptorLookup
+
+	// CaptureBegin returns the raw timestamp the capture began at
+	CaptureBegin() uint64
+
+	// GetTotalDuration returns the length of the capture
+	GetTotalDuration() time.Duration
+
+	// ContextSwitchesLoaded reports whether the threads' context switches were read
+	ContextSwitchesLoaded() bool
+
+	// BlockSampling returns N when only every Nth block was kept, and 1 otherwise
+	BlockSampling() int
+
+	// RetainedSlowestCount returns N when only the N slowest blocks were kept,
+	// without call trees, and 0 otherwise
+	RetainedSlowestCount() int
+
+	// FileHeader returns the header the capture was read from
+	FileHeader() parser.FileHeader
+
+	// EstimateMemory estimates the heap held by the loaded data
+	EstimateMemory() parser.MemoryEstimate
+
+	// Extract returns a standalone profile holding copies of the given top-level
+	// blocks of thread and their subtrees; see parser.ProfileData.Extract
+	Extract(thread *parser.ThreadData, blocks []*parser.Block) *parser.ProfileData
+}
+
+var _ Profile = (*parser.ProfileData)(nil)
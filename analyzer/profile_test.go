@@ -0,0 +1,122 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// mockProfile builds by hand the capture mockCapture writes: Frame holds Update and
+// Render on Main, and Update runs once more on Worker
+func mockProfile() *proftest.Mock {
+	descriptors := make([]*parser.BlockDescriptor, 0, 3)
+	for _, d := range proftest.Descriptors("Frame", "Update", "Render") {
+		descriptors = append(descriptors, &parser.BlockDescriptor{ID: d.ID, Line: d.Line, Type: d.Type, Name: d.Name, File: d.File})
+	}
+	return proftest.NewMock(0, 100, descriptors,
+		&parser.ThreadData{ThreadID: 1, ThreadName: "Main", LastBlockEnd: 100, Blocks: []*parser.Block{
+			proftest.Tree(1, 0, 100, proftest.Tree(2, 10, 40), proftest.Tree(3, 50, 90)),
+		}},
+		&parser.ThreadData{ThreadID: 2, ThreadName: "Worker", LastBlockEnd: 30, Blocks: []*parser.Block{
+			proftest.Tree(2, 0, 30),
+		}},
+	)
+}
+
+func mockCapture() *proftest.Profile {
+	return &proftest.Profile{
+		Begin:       0,
+		End:         100,
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 10, End: 40},
+				{ID: 3, Begin: 50, End: 90},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 30}}},
+		},
+	}
+}
+
+func TestAnalyzerOnMockProfile(t *testing.T) {
+	describe := func(blocks []*BlockInfo) []string {
+		var got []string
+		for _, block := range blocks {
+			got = append(got, fmt.Sprintf("%s %d %v %v", block.Name, block.CallCount, block.Duration, block.SelfDuration))
+		}
+		return got
+	}
+
+	tests := []struct {
+		name    string
+		analyze func(a *Analyzer) []string
+		want    []string
+	}{
+		{
+			name:    "hotspots",
+			analyze: func(a *Analyzer) []string { return describe(a.GetHotspots(10)) },
+			want:    []string{"Frame 1 100ns 30ns", "Update 2 60ns 60ns", "Render 1 40ns 40ns"},
+		},
+		{
+			name:    "slowest blocks",
+			analyze: func(a *Analyzer) []string { return describe(a.GetSlowestBlocks(2)) },
+			want:    []string{"Frame 1 100ns 30ns", "Render 1 40ns 40ns"},
+		},
+		{
+			name: "leaf hotspots",
+			analyze: func(a *Analyzer) []string {
+				hotspots, _ := a.GetLeafHotspots(10)
+				return describe(hotspots)
+			},
+			want: []string{"Update 2 60ns 60ns", "Render 1 40ns 40ns"},
+		},
+		{
+			name: "thread statistics",
+			analyze: func(a *Analyzer) []string {
+				var got []string
+				for _, stats := range a.GetThreadStatistics() {
+					got = append(got, fmt.Sprintf("%s %v %d", stats.ThreadName, stats.TotalDuration, stats.BlockCount))
+				}
+				return got
+			},
+			want: []string{"Main 100ns 3", "Worker 30ns 1"},
+		},
+		{
+			name: "subprofile",
+			analyze: func(a *Analyzer) []string {
+				sub, err := a.ExtractSubprofile("Render", 0, false)
+				if err != nil {
+					return []string{err.Error()}
+				}
+				return []string{fmt.Sprintf("%d %d", sub.Root.ThreadID, sub.Profile.GetBlocksCount())}
+			},
+			want: []string{"1 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := mockProfile()
+			if got := tt.analyze(NewAnalyzer(mock)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("on the mock = %q, want %q", got, tt.want)
+			}
+			if got := tt.analyze(newTestAnalyzer(t, mockCapture())); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("on the parsed capture = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractSubprofileGoesThroughProfile(t *testing.T) {
+	mock := mockProfile()
+	if _, err := NewAnalyzer(mock).ExtractSubprofile("Update", 1, false); err != nil {
+		t.Fatalf("ExtractSubprofile: %v", err)
+	}
+	if mock.Extracted != 1 {
+		t.Errorf("Extract called %d times, want 1", mock.Extracted)
+	}
+}
@@ -0,0 +1,12 @@
+package analyzer
+
+// ProgressFunc receives updates from long-running analyses: done of total steps
+// have completed. A nil ProgressFunc discards updates.
+type ProgressFunc func(done, total int)
+
+// report forwards an update if a sink is set
+func (f ProgressFunc) report(done, total int) {
+	if f != nil {
+		f(done, total)
+	}
+}
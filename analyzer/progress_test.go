@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestProgressReports(t *testing.T) {
+	a := newTestAnalyzer(t, explainProfile())
+
+	tests := []struct {
+		name string
+		run  func(progress ProgressFunc)
+	}{
+		{"issues", func(progress ProgressFunc) {
+			options := DefaultIssueOptions()
+			options.Progress = progress
+			a.AnalyzePerformanceIssuesWithOptions(options)
+		}},
+		{"overview", func(progress ProgressFunc) { a.GetOverviewWithProgress(progress) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var done, totals []int
+			tt.run(func(d, total int) {
+				done = append(done, d)
+				totals = append(totals, total)
+			})
+			if len(done) == 0 {
+				t.Fatal("no progress reported")
+			}
+			total := totals[0]
+			for i := range done {
+				if done[i] != i+1 || totals[i] != total {
+					t.Fatalf("reported %v of %v, want 1..n of a fixed total", done, totals)
+				}
+			}
+			if done[len(done)-1] != total {
+				t.Errorf("last report %d of %d, want the total", done[len(done)-1], total)
+			}
+
+			// A nil sink is allowed
+			tt.run(nil)
+		})
+	}
+}
+
+func TestProgressDoesNotChangeResults(t *testing.T) {
+	a := newTestAnalyzer(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}}},
+	})
+	options := DefaultIssueOptions()
+	without := a.AnalyzePerformanceIssuesWithOptions(options)
+	options.Progress = func(int, int) {}
+	if with := a.AnalyzePerformanceIssuesWithOptions(options); !reflect.DeepEqual(with, without) {
+		t.Errorf("issues with progress %v differ from %v", with, without)
+	}
+}
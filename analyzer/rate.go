@@ -0,0 +1,98 @@
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// RateBucket counts event occurrences within one time slice of the capture
+type RateBucket struct {
+	Start     time.Duration // Offset of the bucket from the capture begin
+	Count     int
+	PerSecond float64
+}
+
+// EventRate describes how often an event occurred over the capture
+type EventRate struct {
+	Name             string
+	Count            int
+	BucketSize       time.Duration
+	AveragePerSecond float64
+	PeakPerSecond    float64
+	Buckets          []*RateBucket
+}
+
+// maxRateBuckets bounds the number of buckets GetEventRate and GetGCPauses split the
+// capture into, so a tiny bucket size on a long capture can't exhaust memory
+const maxRateBuckets = 100000
+
+// GetEventRate counts the occurrences of the named event in fixed-size buckets
+// spanning the capture and converts them to per-second rates
+func (a *Analyzer) GetEventRate(name string, bucketSize time.Duration) (*EventRate, error) {
+	if bucketSize <= 0 {
+		return nil, fmt.Errorf("bucket size must be positive")
+	}
+
+	invocations, err := a.requireInvocations(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, inv := range invocations {
+		if !a.isEvent(inv.Block) {
+			return nil, fmt.Errorf("'%s' is not an event block", name)
+		}
+	}
+
+	captureDuration := a.profile.GetTotalDuration()
+	if captureDuration <= 0 {
+		return nil, fmt.Errorf("profile has zero capture duration")
+	}
+
+	if captureDuration/bucketSize >= maxRateBuckets {
+		return nil, fmt.Errorf("bucket size %v splits the capture into more than %d buckets; use a larger bucket",
+			bucketSize, maxRateBuckets)
+	}
+
+	bucketCount := int((captureDuration + bucketSize - 1) / bucketSize)
+	rate := &EventRate{
+		Name:       name,
+		Count:      len(invocations),
+		BucketSize: bucketSize,
+		Buckets:    make([]*RateBucket, bucketCount),
+	}
+	for i := range rate.Buckets {
+		rate.Buckets[i] = &RateBucket{Start: time.Duration(i) * bucketSize}
+	}
+
+	begin := a.profile.CaptureBegin()
+	for _, inv := range invocations {
+		offset := time.Duration(0)
+		if inv.Block.Begin > begin {
+			offset = time.Duration(inv.Block.Begin - begin)
+		}
+		index := min(int(offset/bucketSize), bucketCount-1)
+		rate.Buckets[index].Count++
+	}
+
+	for i, bucket := range rate.Buckets {
+		// The last bucket may be cut short by the end of the capture
+		width := bucketSize
+		if i == bucketCount-1 {
+			width = captureDuration - bucket.Start
+		}
+		bucket.PerSecond = float64(bucket.Count) / width.Seconds()
+		rate.PeakPerSecond = max(rate.PeakPerSecond, bucket.PerSecond)
+	}
+
+	rate.AveragePerSecond = float64(rate.Count) / captureDuration.Seconds()
+
+	return rate, nil
+}
+
+// isEvent reports whether block is an instantaneous event marker
+func (a *Analyzer) isEvent(block *parser.Block) bool {
+	descriptor := a.profile.Descriptor(block.ID)
+	return descriptor != nil && descriptor.Type == parser.BlockTypeEvent
+}
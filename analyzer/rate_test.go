@@ -0,0 +1,73 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestGetEventRate(t *testing.T) {
+	ms := uint64(time.Millisecond)
+	p := &proftest.Profile{
+		Descriptors: []proftest.Descriptor{
+			{ID: 1, Name: "Frame", File: "main.cpp", Line: 1, Type: parser.BlockTypeBlock},
+			{ID: 2, Name: "Present", File: "main.cpp", Line: 2, Type: parser.BlockTypeEvent},
+		},
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 2500 * ms},
+			{ID: 2, Begin: 100 * ms, End: 100 * ms},
+			{ID: 2, Begin: 200 * ms, End: 200 * ms},
+			{ID: 2, Begin: 1200 * ms, End: 1200 * ms},
+			{ID: 2, Begin: 2400 * ms, End: 2400 * ms},
+		}}},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name      string
+		event     string
+		bucket    time.Duration
+		wantErr   bool
+		counts    []int
+		perSecond []float64
+		peak      float64
+	}{
+		{"one second buckets", "Present", time.Second, false, []int{2, 1, 1}, []float64{2, 1, 2}, 2},
+		{"one bucket", "Present", time.Minute, false, []int{4}, []float64{1.6}, 1.6},
+		{"not an event", "Frame", time.Second, true, nil, nil, 0},
+		{"unknown event", "Missing", time.Second, true, nil, nil, 0},
+		{"zero bucket", "Present", 0, true, nil, nil, 0},
+		{"too many buckets", "Present", time.Nanosecond, true, nil, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := a.GetEventRate(tt.event, tt.bucket)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("succeeded with %d buckets", len(rate.Buckets))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var counts []int
+			var perSecond []float64
+			for _, bucket := range rate.Buckets {
+				counts = append(counts, bucket.Count)
+				perSecond = append(perSecond, bucket.PerSecond)
+			}
+			if !reflect.DeepEqual(counts, tt.counts) || !reflect.DeepEqual(perSecond, tt.perSecond) {
+				t.Errorf("buckets %v at %v per second, want %v at %v", counts, perSecond, tt.counts, tt.perSecond)
+			}
+			if rate.Count != 4 || rate.AveragePerSecond != 1.6 || rate.PeakPerSecond != tt.peak {
+				t.Errorf("count %d, average %v, peak %v; want 4, 1.6, %v", rate.Count, rate.AveragePerSecond, rate.PeakPerSecond, tt.peak)
+			}
+		})
+	}
+}
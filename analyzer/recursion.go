@@ -0,0 +1,161 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// minVariedRecursionDepth is the shallowest maximum depth reported for varying
+// wildly, so a function recursing once or twice in rare calls isn't flagged
+const minVariedRecursionDepth = 8
+
+// recursionStats summarizes the recursion depths reached by a function's outermost
+// calls, i.e. calls not nested in another call of the same function
+type recursionStats struct {
+	depthCounts map[int]int // Outermost calls by the deepest recursion reached; 1 = none
+	calls       int
+
+	deepestBlock    *parser.Block
+	deepestThreadID uint64
+	deepestDepth    int
+}
+
+// add records an outermost call that reached depth
+func (s *recursionStats) add(block *parser.Block, threadID uint64, depth int) {
+	s.depthCounts[depth]++
+	s.calls++
+	if depth > s.deepestDepth {
+		s.deepestBlock, s.deepestThreadID, s.deepestDepth = block, threadID, depth
+	}
+}
+
+// median returns the median depth over the outermost calls
+func (s *recursionStats) median() int {
+	depths := make([]int, 0, len(s.depthCounts))
+	for depth := range s.depthCounts {
+		depths = append(depths, depth)
+	}
+	sort.Ints(depths)
+
+	seen := 0
+	for _, depth := range depths {
+		seen += s.depthCounts[depth]
+		if seen > s.calls/2 {
+			return depth
+		}
+	}
+	return 0
+}
+
+// detectUnbalancedRecursion flags functions whose recursion gets deeper than maxDepth,
+// or whose deepest recursion is at least depthRatio times the median over their
+// outermost calls. Either suggests unbounded recursion risk or an algorithm that
+// degrades on some inputs. maxDepth <= 0 disables the detector.
+func (a *Analyzer) detectUnbalancedRecursion(maxDepth int, depthRatio float64) []*PerformanceIssue {
+	var issues []*PerformanceIssue
+	if maxDepth <= 0 {
+		return issues
+	}
+
+	stats := a.measureRecursion()
+
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s := stats[name]
+		if s.deepestDepth < 2 {
+			continue
+		}
+		median := s.median()
+
+		tooDeep := s.deepestDepth > maxDepth
+		varied := s.deepestDepth >= minVariedRecursionDepth && depthRatio > 0 && float64(s.deepestDepth) >= depthRatio*float64(median)
+		if !tooDeep && !varied {
+			continue
+		}
+
+		thread := a.profile.Thread(s.deepestThreadID)
+		duration := s.deepestBlock.Duration()
+		_, file, line := a.resolveBlock(s.deepestBlock)
+		issues = append(issues, &PerformanceIssue{
+			Type:  "Unbalanced Recursion",
+			Score: impactScore(a.captureFraction(float64(duration))),
+			Description: fmt.Sprintf("Function '%s' recursed %d levels deep on thread '%s' at %v into the capture (%v), while the median over its %d outermost calls is %d",
+				name, s.deepestDepth, thread.ThreadName, a.CaptureOffset(s.deepestBlock.Begin), duration, s.calls, median),
+			Location:   formatLocation(file, line),
+			Function:   name,
+			Duration:   duration,
+			ThreadID:   s.deepestThreadID,
+			ThreadName: thread.ThreadName,
+		})
+	}
+
+	return issues
+}
+
+// measureRecursion measures, for each function, the recursion depth reached by each
+// outermost call: the most calls of the same name on any path beneath it, itself included
+func (a *Analyzer) measureRecursion() map[string]*recursionStats {
+	stats := make(map[string]*recursionStats)
+
+	type frame struct {
+		name      string
+		outermost bool
+		depth     int // Deepest recursion beneath an outermost call so far
+		block     *parser.Block
+	}
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		var path []frame
+		active := make(map[string]int)
+		outermost := make(map[string]int) // Path index of each active function's outermost call
+
+		pop := func() {
+			top := path[len(path)-1]
+			path = path[:len(path)-1]
+			active[top.name]--
+			if !top.outermost {
+				return
+			}
+			delete(outermost, top.name)
+
+			s, ok := stats[top.name]
+			if !ok {
+				s = &recursionStats{depthCounts: make(map[int]int)}
+				stats[top.name] = s
+			}
+			s.add(top.block, threadID, top.depth)
+		}
+
+		walkBlocks(thread.Blocks, func(block *parser.Block, depth int) {
+			for len(path) > depth {
+				pop()
+			}
+
+			name, _, _ := a.resolveBlock(block)
+			active[name]++
+
+			if active[name] == 1 {
+				outermost[name] = len(path)
+				path = append(path, frame{name: name, outermost: true, depth: 1, block: block})
+				return
+			}
+			if call := &path[outermost[name]]; active[name] > call.depth {
+				call.depth = active[name]
+			}
+			path = append(path, frame{name: name})
+		})
+		for len(path) > 0 {
+			pop()
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// recursionProfile has Walk recursing deep levels under Frame once, then five
+// calls recursing two levels each
+func recursionProfile(deep uint64) *proftest.Profile {
+	var blocks []proftest.Block
+	for level := deep; level > 0; level-- {
+		blocks = append(blocks, proftest.Block{ID: 2, Begin: level, End: 1001 - level})
+	}
+	blocks = append(blocks, proftest.Block{ID: 1, Begin: 0, End: 1001})
+	for i := uint64(0); i < 5; i++ {
+		begin := 2000 + i*100
+		blocks = append(blocks,
+			proftest.Block{ID: 2, Begin: begin + 10, End: begin + 50},
+			proftest.Block{ID: 2, Begin: begin, End: begin + 90},
+		)
+	}
+	return &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Walk"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+	}
+}
+
+func TestDetectUnbalancedRecursion(t *testing.T) {
+	tests := []struct {
+		name     string
+		deep     uint64
+		maxDepth int
+		ratio    float64
+		want     bool
+	}{
+		{"defaults", 20, 16, 4, true},
+		{"disabled", 20, 0, 4, false},
+		{"negative disables", 20, -1, 4, false},
+		{"only varied", 20, 20, 4, true},
+		{"only too deep", 20, 19, 0, true},
+		{"neither", 20, 20, 0, false},
+		{"ratio above the spread", 20, 20, 11, false},
+		{"shallow recursion tolerated", 2, 16, 4, false},
+		{"shallow recursion too deep", 2, 1, 0, true},
+		{"varied but below the minimum depth", 7, 16, 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := newTestAnalyzer(t, recursionProfile(tt.deep)).detectUnbalancedRecursion(tt.maxDepth, tt.ratio)
+			if !tt.want {
+				if len(issues) != 0 {
+					t.Errorf("got %d issues, want none: %s", len(issues), issues[0].Description)
+				}
+				return
+			}
+			if len(issues) != 1 {
+				t.Fatalf("got %d issues, want 1", len(issues))
+			}
+
+			issue := issues[0]
+			if issue.Type != "Unbalanced Recursion" || issue.Function != "Walk" || issue.Location != "Walk.cpp:20" || issue.ThreadName != "Main" {
+				t.Errorf("issue = %s for %s at %s on %s", issue.Type, issue.Function, issue.Location, issue.ThreadName)
+			}
+			// The first call reaching the deepest recursion is reported, by its outermost block
+			if issue.Duration != 999*time.Nanosecond {
+				t.Errorf("duration = %v, want 999ns", issue.Duration)
+			}
+			for _, want := range []string{fmt.Sprintf("recursed %d levels deep", tt.deep), "at 1ns into the capture", "its 6 outermost calls is 2"} {
+				if !strings.Contains(issue.Description, want) {
+					t.Errorf("description %q doesn't contain %q", issue.Description, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maxRollingWindows bounds the number of windows GetRollingHotspots evaluates, since
+// each one walks every thread
+const maxRollingWindows = 10000
+
+// WindowHotspots is the ranking of functions within one window of the capture
+type WindowHotspots struct {
+	Start    time.Duration // Offset of the window from the capture begin
+	End      time.Duration
+	Hotspots []*BlockInfo // Busiest first; empty if nothing ran in the window
+}
+
+// HotspotShift is a run of consecutive windows that share the same top hotspot
+type HotspotShift struct {
+	Start    time.Duration
+	End      time.Duration
+	Name     string // "" if nothing ran
+	Windows  int
+	Duration time.Duration // Time the function spent in these windows
+}
+
+// RollingHotspots tells what was hot when, window by window
+type RollingHotspots struct {
+	WindowSize time.Duration
+	Step       time.Duration
+	Mode       TimeMode
+	Windows    []*WindowHotspots
+
+	// Shifts merges consecutive windows with the same top hotspot into a timeline.
+	// With overlapping windows (Step < WindowSize), durations count overlaps twice.
+	Shifts []*HotspotShift
+}
+
+// GetRollingHotspots slides a window of windowSize across the capture, step apart,
+// and ranks the top functions in each by time (measured in mode) clipped to the window
+func (a *Analyzer) GetRollingHotspots(windowSize, step time.Duration, top int, mode TimeMode) (*RollingHotspots, error) {
+	if windowSize <= 0 || step <= 0 {
+		return nil, fmt.Errorf("window size and step must be positive")
+	}
+	total := a.profile.GetTotalDuration()
+	if total <= 0 {
+		return nil, fmt.Errorf("profile has no duration")
+	}
+	if count := (total + step - 1) / step; count > maxRollingWindows {
+		return nil, fmt.Errorf("%d windows exceed the limit of %d; use a larger step", count, maxRollingWindows)
+	}
+
+	result := &RollingHotspots{WindowSize: windowSize, Step: step, Mode: mode}
+	captureBegin := a.profile.CaptureBegin()
+
+	for start := time.Duration(0); start < total; start += step {
+		end := min(start+windowSize, total)
+		window := &WindowHotspots{Start: start, End: end}
+
+		blockMap := make(map[string]*BlockInfo)
+		for _, threadID := range a.sortedThreadIDs() {
+			thread := a.profile.Thread(threadID)
+			a.aggregateWindow(thread.Blocks, captureBegin+uint64(start), captureBegin+uint64(end), threadID, thread.ThreadName, blockMap)
+		}
+		mergeNameOnlyEntries(blockMap)
+
+		for _, info := range blockMap {
+			if mode.Of(info) > 0 {
+				window.Hotspots = append(window.Hotspots, info)
+			}
+		}
+		sort.Slice(window.Hotspots, func(i, j int) bool {
+			di, dj := mode.Of(window.Hotspots[i]), mode.Of(window.Hotspots[j])
+			if di != dj {
+				return di > dj
+			}
+			return lessBlockInfo(window.Hotspots[i], window.Hotspots[j])
+		})
+		if top > 0 && len(window.Hotspots) > top {
+			window.Hotspots = window.Hotspots[:top]
+		}
+
+		result.Windows = append(result.Windows, window)
+		result.addShift(window)
+	}
+
+	return result, nil
+}
+
+// addShift extends the last shift with window, or starts a new one if its top hotspot differs
+func (r *RollingHotspots) addShift(window *WindowHotspots) {
+	name, duration := "", time.Duration(0)
+	if len(window.Hotspots) > 0 {
+		name, duration = window.Hotspots[0].Name, r.Mode.Of(window.Hotspots[0])
+	}
+
+	if len(r.Shifts) > 0 {
+		if last := r.Shifts[len(r.Shifts)-1]; last.Name == name {
+			last.End = window.End
+			last.Windows++
+			last.Duration += duration
+			return
+		}
+	}
+
+	r.Shifts = append(r.Shifts, &HotspotShift{
+		Start:    window.Start,
+		End:      window.End,
+		Name:     name,
+		Windows:  1,
+		Duration: duration,
+	})
+}
@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// rollingProfile is a 3s capture where Update is busy in the first and last second
+// and Spike only in the middle one; Render runs briefly after the last Update
+func rollingProfile() *proftest.Profile {
+	ms := uint64(time.Millisecond)
+	return &proftest.Profile{
+		Begin:       0,
+		End:         3000 * ms,
+		Descriptors: proftest.Descriptors("Update", "Spike", "Render"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 800 * ms},
+			{ID: 2, Begin: 1100 * ms, End: 1900 * ms},
+			{ID: 1, Begin: 2000 * ms, End: 2600 * ms},
+			{ID: 3, Begin: 2700 * ms, End: 2750 * ms},
+		}}},
+	}
+}
+
+func TestGetRollingHotspots(t *testing.T) {
+	a := newTestAnalyzer(t, rollingProfile())
+
+	tests := []struct {
+		name         string
+		window, step time.Duration
+		top          int
+		windows      []string // "start-end name:duration ..."
+		shifts       []string // "start-end name windows duration"
+		wantErr      bool
+	}{
+		{
+			name:   "tumbling windows",
+			window: time.Second, step: time.Second,
+			windows: []string{
+				"0s-1s Update:800ms",
+				"1s-2s Spike:800ms",
+				"2s-3s Update:600ms Render:50ms",
+			},
+			shifts: []string{"0s-1s Update 1 800ms", "1s-2s Spike 1 800ms", "2s-3s Update 1 600ms"},
+		},
+		{
+			name:   "sliding windows",
+			window: time.Second, step: 500 * time.Millisecond, top: 1,
+			windows: []string{
+				"0s-1s Update:800ms",
+				"500ms-1.5s Spike:400ms",
+				"1s-2s Spike:800ms",
+				"1.5s-2.5s Update:500ms",
+				"2s-3s Update:600ms",
+				"2.5s-3s Update:100ms",
+			},
+			shifts: []string{"0s-1s Update 1 800ms", "500ms-2s Spike 2 1.2s", "1.5s-3s Update 3 1.2s"},
+		},
+		{
+			name:   "idle window",
+			window: 250 * time.Millisecond, step: 2750 * time.Millisecond,
+			windows: []string{"0s-250ms Update:250ms", "2.75s-3s"},
+			shifts:  []string{"0s-250ms Update 1 250ms", "2.75s-3s  1 0s"},
+		},
+		{name: "zero window", window: 0, step: time.Second, wantErr: true},
+		{name: "zero step", window: time.Second, step: 0, wantErr: true},
+		{name: "too many windows", window: time.Second, step: time.Microsecond, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rolling, err := a.GetRollingHotspots(tt.window, tt.step, tt.top, Inclusive)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetRollingHotspots error = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var windows []string
+			for _, window := range rolling.Windows {
+				line := fmt.Sprintf("%v-%v", window.Start, window.End)
+				for _, hotspot := range window.Hotspots {
+					line += fmt.Sprintf(" %s:%v", hotspot.Name, hotspot.Duration)
+				}
+				windows = append(windows, line)
+			}
+			if !reflect.DeepEqual(windows, tt.windows) {
+				t.Errorf("windows = %q, want %q", windows, tt.windows)
+			}
+
+			var shifts []string
+			for _, shift := range rolling.Shifts {
+				shifts = append(shifts, fmt.Sprintf("%v-%v %s %d %v", shift.Start, shift.End, shift.Name, shift.Windows, shift.Duration))
+			}
+			if !reflect.DeepEqual(shifts, tt.shifts) {
+				t.Errorf("shifts = %q, want %q", shifts, tt.shifts)
+			}
+		})
+	}
+}
+
+func TestGetRollingHotspotsZeroDuration(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Tick"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 50, End: 50}}}},
+	}
+	if _, err := newTestAnalyzer(t, p).GetRollingHotspots(time.Second, time.Second, 1, Inclusive); err == nil {
+		t.Error("GetRollingHotspots succeeded on a capture without duration")
+	}
+}
@@ -0,0 +1,27 @@
+package analyzer
+
+import "time"
+
+// SamplingFactor returns how many real blocks each retained block stands for
+// (1 for a profile read without sampling)
+func (a *Analyzer) SamplingFactor() int {
+	return max(a.profile.BlockSampling(), 1)
+}
+
+// ScaleForSampling returns copies of infos with cumulative durations and call counts
+// multiplied by the sampling factor, estimating the totals an unsampled read would
+// report. Averages are unaffected by sampling and are kept as is.
+func (a *Analyzer) ScaleForSampling(infos []*BlockInfo) []*BlockInfo {
+	factor := a.SamplingFactor()
+
+	scaled := make([]*BlockInfo, len(infos))
+	for i, info := range infos {
+		copied := *info
+		copied.Duration *= time.Duration(factor)
+		copied.SelfDuration *= time.Duration(factor)
+		copied.ThreadTime *= time.Duration(factor)
+		copied.CallCount *= factor
+		scaled[i] = &copied
+	}
+	return scaled
+}
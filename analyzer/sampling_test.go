@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestScaleForSampling(t *testing.T) {
+	var blocks []proftest.Block
+	for i := uint64(0); i < 8; i++ {
+		blocks = append(blocks, proftest.Block{ID: 1, Begin: i * 100, End: i*100 + 50})
+	}
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Update"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+	}
+
+	tests := []struct {
+		name     string
+		sample   int
+		factor   int
+		calls    int
+		duration int64
+	}{
+		{"unsampled", 0, 1, 8, 400},
+		{"every second block", 2, 2, 8, 400},
+		{"every third block", 3, 3, 9, 450},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAnalyzer(p.Parse(t, parser.ReadOptions{SampleBlocks: tt.sample}))
+			if a.SamplingFactor() != tt.factor {
+				t.Fatalf("SamplingFactor = %d, want %d", a.SamplingFactor(), tt.factor)
+			}
+
+			hotspots := a.GetHotspots(1)
+			scaled := a.ScaleForSampling(hotspots)[0]
+			if scaled.CallCount != tt.calls || int64(scaled.Duration) != tt.duration || int64(scaled.SelfDuration) != tt.duration {
+				t.Errorf("scaled to %d calls, %v inclusive, %v exclusive; want %d calls, %dns",
+					scaled.CallCount, scaled.Duration, scaled.SelfDuration, tt.calls, tt.duration)
+			}
+			if scaled.AvgDuration != hotspots[0].AvgDuration {
+				t.Errorf("average changed from %v to %v", hotspots[0].AvgDuration, scaled.AvgDuration)
+			}
+			if hotspots[0].CallCount*tt.factor != tt.calls {
+				t.Errorf("ScaleForSampling modified its input: %d calls", hotspots[0].CallCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package analyzer
+
+// impactSaturation is the fraction of capture time at which an issue's score reaches 100
+const impactSaturation = 0.5
+
+// SeverityCutoffs are the minimum scores (0-100) for an issue to be rated at each level.
+// Issues scoring below Medium are rated "low".
+type SeverityCutoffs struct {
+	High   float64
+	Medium float64
+}
+
+// DefaultSeverityCutoffs rates issues affecting at least 30% of the capture as high
+// and at least 20% as medium
+func DefaultSeverityCutoffs() SeverityCutoffs {
+	return SeverityCutoffs{
+		High:   60,
+		Medium: 40,
+	}
+}
+
+// Severity maps a score to "high", "medium" or "low"
+func (c SeverityCutoffs) Severity(score float64) string {
+	switch {
+	case score >= c.High:
+		return "high"
+	case score >= c.Medium:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// severityRanks orders severities from most to least severe
+var severityRanks = map[string]int{"high": 0, "medium": 1, "low": 2}
+
+// ValidSeverity reports whether severity is "high", "medium" or "low"
+func ValidSeverity(severity string) bool {
+	_, ok := severityRanks[severity]
+	return ok
+}
+
+// FilterIssues keeps the issues rated at least minSeverity ("" keeps all) and caps
+// them at maxIssues (0 means no cap). issues must be sorted as returned by
+// AnalyzePerformanceIssues; the result is then a prefix of it, so 1-based issue
+// indices stay valid.
+func FilterIssues(issues []*PerformanceIssue, minSeverity string, maxIssues int) []*PerformanceIssue {
+	count := len(issues)
+	if rank, ok := severityRanks[minSeverity]; ok {
+		count = 0
+		for count < len(issues) && severityRanks[issues[count].Severity] <= rank {
+			count++
+		}
+	}
+	if maxIssues > 0 {
+		count = min(count, maxIssues)
+	}
+	return issues[:count]
+}
+
+// impactScore converts the fraction of capture time an issue affects into a 0-100 score.
+// Every detector expresses its magnitude this way so scores are comparable across issue types.
+func impactScore(fraction float64) float64 {
+	if fraction <= 0 {
+		return 0
+	}
+	return min(100, fraction/impactSaturation*100)
+}
+
+// captureFraction returns d as a fraction of the total capture duration
+func (a *Analyzer) captureFraction(d float64) float64 {
+	total := a.profile.GetTotalDuration()
+	if total <= 0 {
+		return 0
+	}
+	return d / float64(total)
+}
@@ -0,0 +1,164 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestSeverityCutoffs(t *testing.T) {
+	tests := []struct {
+		cutoffs SeverityCutoffs
+		score   float64
+		want    string
+	}{
+		{DefaultSeverityCutoffs(), 100, "high"},
+		{DefaultSeverityCutoffs(), 60, "high"},
+		{DefaultSeverityCutoffs(), 59.9, "medium"},
+		{DefaultSeverityCutoffs(), 40, "medium"},
+		{DefaultSeverityCutoffs(), 39.9, "low"},
+		{DefaultSeverityCutoffs(), 0, "low"},
+		{SeverityCutoffs{High: 90, Medium: 10}, 60, "medium"},
+		{SeverityCutoffs{High: 0, Medium: 0}, 0, "high"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.cutoffs.Severity(tt.score); got != tt.want {
+			t.Errorf("%+v.Severity(%v) = %q, want %q", tt.cutoffs, tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestImpactScore(t *testing.T) {
+	tests := []struct {
+		fraction float64
+		want     float64
+	}{
+		{-1, 0},
+		{0, 0},
+		{0.1, 20},
+		{0.3, 60},
+		{impactSaturation, 100},
+		{1, 100},
+	}
+
+	for _, tt := range tests {
+		if got := impactScore(tt.fraction); got < tt.want-1e-9 || got > tt.want+1e-9 {
+			t.Errorf("impactScore(%v) = %v, want %v", tt.fraction, got, tt.want)
+		}
+	}
+}
+
+func TestIssueSeverityFollowsCutoffs(t *testing.T) {
+	// One 300ms block in a 1s capture: a Long Blocking Operation scoring 60
+	ms := uint64(time.Millisecond)
+	a := newTestAnalyzer(t, &proftest.Profile{
+		Begin: 0, End: 1000 * ms,
+		Descriptors: proftest.Descriptors("Load"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 300 * ms}}},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		cutoffs SeverityCutoffs
+		want    string
+	}{
+		{"default", DefaultSeverityCutoffs(), "high"},
+		{"stricter high", SeverityCutoffs{High: 70, Medium: 40}, "medium"},
+		{"stricter medium", SeverityCutoffs{High: 90, Medium: 80}, "low"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, issue := range a.AnalyzePerformanceIssuesWithCutoffs(tt.cutoffs) {
+				if issue.Type != "Long Blocking Operation" {
+					continue
+				}
+				if issue.Score < 59.9 || issue.Score > 60.1 {
+					t.Errorf("score = %v, want 60", issue.Score)
+				}
+				if issue.Severity != tt.want {
+					t.Errorf("severity = %q, want %q", issue.Severity, tt.want)
+				}
+				return
+			}
+			t.Error("no Long Blocking Operation issue")
+		})
+	}
+}
+
+func TestIssuesSortedBySeverityThenScore(t *testing.T) {
+	ms := uint64(time.Millisecond)
+	issues := newTestAnalyzer(t, &proftest.Profile{
+		Begin: 0, End: 1000 * ms,
+		Descriptors: proftest.Descriptors("A", "B", "C"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 1, Begin: 0, End: 150 * ms},
+				{ID: 2, Begin: 200 * ms, End: 550 * ms},
+				{ID: 3, Begin: 600 * ms, End: 850 * ms},
+			}},
+		},
+	}).AnalyzePerformanceIssues()
+
+	for i := 1; i < len(issues); i++ {
+		prev, cur := issues[i-1], issues[i]
+		if severityRanks[prev.Severity] > severityRanks[cur.Severity] ||
+			(prev.Severity == cur.Severity && prev.Score < cur.Score) {
+			t.Errorf("issue %d (%s %.1f) sorted before issue %d (%s %.1f)", i-1, prev.Severity, prev.Score, i, cur.Severity, cur.Score)
+		}
+	}
+}
+
+func TestValidSeverity(t *testing.T) {
+	for severity, want := range map[string]bool{"high": true, "medium": true, "low": true, "": false, "High": false, "critical": false} {
+		if got := ValidSeverity(severity); got != want {
+			t.Errorf("ValidSeverity(%q) = %t, want %t", severity, got, want)
+		}
+	}
+}
+
+func TestFilterIssues(t *testing.T) {
+	var issues []*PerformanceIssue
+	for _, severity := range []string{"high", "high", "medium", "low", "low"} {
+		issues = append(issues, &PerformanceIssue{Severity: severity})
+	}
+
+	tests := []struct {
+		name        string
+		minSeverity string
+		maxIssues   int
+		want        int
+	}{
+		{"everything", "", 0, 5},
+		{"at least low", "low", 0, 5},
+		{"at least medium", "medium", 0, 3},
+		{"only high", "high", 0, 2},
+		{"capped", "", 4, 4},
+		{"cap above the severity filter", "medium", 10, 3},
+		{"cap below the severity filter", "medium", 1, 1},
+		{"negative cap means none", "", -1, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterIssues(issues, tt.minSeverity, tt.maxIssues)
+			if len(got) != tt.want {
+				t.Fatalf("kept %d issues, want %d", len(got), tt.want)
+			}
+			// The result is a prefix, so issue indices stay valid
+			for i := range got {
+				if got[i] != issues[i] {
+					t.Errorf("issue %d is not the original issue %d", i, i)
+				}
+			}
+		})
+	}
+
+	if got := FilterIssues(nil, "high", 3); len(got) != 0 {
+		t.Errorf("FilterIssues(nil) = %v, want none", got)
+	}
+}
@@ -0,0 +1,56 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+)
+
+// SourceFile aggregates the descriptors declared in one source file
+type SourceFile struct {
+	File          string // UnknownFileLabel for descriptors without a file
+	Functions     int    // Descriptors declared in the file
+	UsedFunctions int    // Those with at least one call
+	CallCount     int
+	SelfDuration  time.Duration // Time spent in the file's own code, excluding its callees
+}
+
+// GetSourceFiles lists every source file referenced by a descriptor, with its number
+// of functions and the self time of their blocks, most time first. Self time is used
+// because inclusive times of functions calling each other would add up to more than
+// the file took.
+func (a *Analyzer) GetSourceFiles() []*SourceFile {
+	ids := a.profile.DescriptorIDs()
+	identities := make(map[uint32]*BlockIdentity, len(ids))
+	for _, id := range ids {
+		identities[id] = &BlockIdentity{Descriptor: a.profile.Descriptor(id)}
+	}
+	a.measureUsage(identities)
+
+	byFile := make(map[string]*SourceFile)
+	for _, identity := range identities {
+		file, ok := byFile[identity.File()]
+		if !ok {
+			file = &SourceFile{File: identity.File()}
+			byFile[file.File] = file
+		}
+		file.Functions++
+		if identity.CallCount > 0 {
+			file.UsedFunctions++
+		}
+		file.CallCount += identity.CallCount
+		file.SelfDuration += identity.SelfDuration
+	}
+
+	files := make([]*SourceFile, 0, len(byFile))
+	for _, file := range byFile {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].SelfDuration != files[j].SelfDuration {
+			return files[i].SelfDuration > files[j].SelfDuration
+		}
+		return files[i].File < files[j].File
+	})
+
+	return files
+}
@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestGetSourceFiles(t *testing.T) {
+	// Update and Render share engine.cpp; Update calls Mystery, which has no file;
+	// Audio is declared in audio.cpp but never runs
+	descriptors := []proftest.Descriptor{
+		{ID: 1, Name: "Update", File: "engine.cpp", Line: 10, Type: parser.BlockTypeBlock},
+		{ID: 2, Name: "Render", File: "engine.cpp", Line: 20, Type: parser.BlockTypeBlock},
+		{ID: 3, Name: "Audio", File: "audio.cpp", Line: 30, Type: parser.BlockTypeBlock},
+		{ID: 4, Name: "Mystery", Type: parser.BlockTypeBlock},
+	}
+
+	tests := []struct {
+		name   string
+		blocks []proftest.Block
+		want   []string // "file functions used calls self"
+	}{
+		{
+			name: "files in use",
+			blocks: []proftest.Block{
+				{ID: 4, Begin: 10, End: 20},
+				{ID: 1, Begin: 0, End: 30},
+				{ID: 2, Begin: 30, End: 40},
+				{ID: 2, Begin: 40, End: 50},
+			},
+			want: []string{
+				"engine.cpp 2 2 3 40ns",
+				UnknownFileLabel + " 1 1 1 10ns",
+				"audio.cpp 1 0 0 0s",
+			},
+		},
+		{
+			name: "no blocks",
+			want: []string{
+				UnknownFileLabel + " 1 0 0 0s",
+				"audio.cpp 1 0 0 0s",
+				"engine.cpp 2 0 0 0s",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &proftest.Profile{
+				Descriptors: descriptors,
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: tt.blocks}},
+			}
+			var got []string
+			for _, file := range newTestAnalyzer(t, p).GetSourceFiles() {
+				got = append(got, fmt.Sprintf("%s %d %d %d %v", file.File, file.Functions, file.UsedFunctions, file.CallCount, file.SelfDuration))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
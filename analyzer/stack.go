@@ -0,0 +1,149 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// GetStackAt returns the blocks active on the thread at the given offset from the
+// capture begin, outermost first. It returns nil if the thread was idle at that moment.
+func (a *Analyzer) GetStackAt(thread *parser.ThreadData, offset time.Duration) []*PathNode {
+	timestamp := a.profile.CaptureBegin() + uint64(offset)
+
+	var stack []*PathNode
+	var parent *parser.Block
+	for block := activeBlockAt(thread.Blocks, timestamp); block != nil; block = activeBlockAt(block.Children, timestamp) {
+		name, file, line := a.resolveBlock(block)
+
+		percent := 100.0
+		if parent != nil && parent.Duration() > 0 {
+			percent = float64(block.Duration()) / float64(parent.Duration()) * 100
+		}
+
+		stack = append(stack, &PathNode{
+			Name:            name,
+			File:            file,
+			Line:            line,
+			Depth:           len(stack),
+			Duration:        block.Duration(),
+			PercentOfParent: percent,
+			Begin:           block.Begin,
+			End:             block.End,
+		})
+		parent = block
+	}
+
+	return stack
+}
+
+// activeBlockAt returns the block among siblings (sorted by Begin) whose
+// [Begin, End) interval contains timestamp, or nil if none does
+func activeBlockAt(blocks []*parser.Block, timestamp uint64) *parser.Block {
+	// Siblings don't overlap, so only the last block starting at or before
+	// timestamp can contain it
+	i := sort.Search(len(blocks), func(i int) bool {
+		return blocks[i].Begin > timestamp
+	})
+
+	if i > 0 && blocks[i-1].End > timestamp {
+		return blocks[i-1]
+	}
+	return nil
+}
+
+// GetBlockAncestors returns the chain of blocks enclosing an individual block
+// returned by the slowest-block queries, outermost first and excluding the block
+// itself. It returns nil for a top-level block, or if the block isn't in the tree.
+func (a *Analyzer) GetBlockAncestors(info *BlockInfo) []*PathNode {
+	thread := a.profile.Thread(info.ThreadID)
+	if thread == nil {
+		return nil
+	}
+
+	var chain []*parser.Block
+	blocks := thread.Blocks
+	for depth := 0; depth <= info.Depth; depth++ {
+		block := enclosingBlock(blocks, info, depth == info.Depth)
+		if block == nil {
+			return nil
+		}
+		chain = append(chain, block)
+		blocks = block.Children
+	}
+
+	return a.pathNodes(chain)[:len(chain)-1]
+}
+
+// enclosingBlock returns the block among siblings (sorted by Begin) that spans the
+// block described by info, or that is that block when exact is set
+func enclosingBlock(blocks []*parser.Block, info *BlockInfo, exact bool) *parser.Block {
+	i := sort.Search(len(blocks), func(i int) bool {
+		return blocks[i].Begin > info.Begin
+	})
+
+	// Zero-length siblings may share a begin with the block sought, so rather than
+	// taking the last candidate as activeBlockAt does, walk back until one matches
+	for ; i > 0; i-- {
+		block := blocks[i-1]
+		if exact {
+			if block.Begin < info.Begin {
+				break
+			}
+			if block.End == info.End {
+				return block
+			}
+		} else if block.End >= info.End {
+			return block
+		}
+	}
+	return nil
+}
+
+// FindBlockByKey returns the block with the given stable identity (see
+// parser.Block.Key) together with its ancestors, outermost first, so a block seen in
+// one load of a file can be looked up in another, e.g. a sampled one. It returns an
+// error if the key is malformed or the block isn't in this profile.
+func (a *Analyzer) FindBlockByKey(key string) (*BlockInfo, []*PathNode, error) {
+	threadID, descriptorID, ordinal, err := parser.ParseBlockKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	thread := a.profile.Thread(threadID)
+	if thread == nil {
+		return nil, nil, fmt.Errorf("thread %d not found", threadID)
+	}
+
+	var stack, found []*parser.Block
+	walkBlocks(thread.Blocks, func(block *parser.Block, depth int) {
+		stack = append(stack[:depth], block)
+		if found == nil && block.ID == descriptorID && block.Ordinal == ordinal {
+			found = append([]*parser.Block(nil), stack...)
+		}
+	})
+	if found == nil {
+		return nil, nil, fmt.Errorf("block %s not found; it may have been dropped by sampling or filtering", key)
+	}
+
+	block := found[len(found)-1]
+	name, file, line := a.resolveBlock(block)
+	info := &BlockInfo{
+		Name:         name,
+		File:         file,
+		Line:         line,
+		Duration:     block.Duration(),
+		SelfDuration: a.selfTime(block),
+		CallCount:    1,
+		ThreadID:     threadID,
+		ThreadName:   thread.ThreadName,
+		Depth:        len(found) - 1,
+		Begin:        block.Begin,
+		End:          block.End,
+		DescriptorID: block.ID,
+		Ordinal:      block.Ordinal,
+	}
+
+	return info, a.pathNodes(found)[:len(found)-1], nil
+}
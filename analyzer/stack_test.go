@@ -0,0 +1,241 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// percentOf computes part as a percentage of whole the way the analyzer does, at
+// run time, so constant folding doesn't round differently
+func percentOf(part, whole time.Duration) float64 {
+	return float64(part) / float64(whole) * 100
+}
+
+func TestGetStackAt(t *testing.T) {
+	p := &proftest.Profile{
+		Begin:       1000,
+		End:         2000,
+		Descriptors: proftest.Descriptors("Frame", "Update", "Physics", "Render"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 3, Begin: 1150, End: 1250},
+			{ID: 2, Begin: 1100, End: 1300},
+			{ID: 4, Begin: 1300, End: 1500},
+			{ID: 1, Begin: 1000, End: 1600},
+			{ID: 1, Begin: 1700, End: 1800},
+		}}},
+	}
+	a := newTestAnalyzer(t, p)
+	thread := a.profile.Thread(1)
+	third := percentOf(200, 600)
+
+	tests := []struct {
+		name    string
+		offset  time.Duration
+		want    []string
+		percent []float64
+	}{
+		{"capture begin", 0, []string{"Frame"}, []float64{100}},
+		{"innermost", 200, []string{"Frame", "Update", "Physics"}, []float64{100, third, 50}},
+		{"block end is exclusive", 300, []string{"Frame", "Render"}, []float64{100, third}},
+		{"between frames", 650, nil, nil},
+		{"second frame", 750, []string{"Frame"}, []float64{100}},
+		{"past the capture", 5000, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			var percents []float64
+			for i, node := range a.GetStackAt(thread, tt.offset) {
+				if node.Depth != i {
+					t.Errorf("%s has depth %d, want %d", node.Name, node.Depth, i)
+				}
+				names = append(names, node.Name)
+				percents = append(percents, node.PercentOfParent)
+			}
+			if !reflect.DeepEqual(names, tt.want) || !reflect.DeepEqual(percents, tt.percent) {
+				t.Errorf("stack = %v (%v%% of parent), want %v (%v%%)", names, percents, tt.want, tt.percent)
+			}
+		})
+	}
+}
+
+func TestGetBlockAncestors(t *testing.T) {
+	// Main: Frame holds Update and Render, both calling memcpy; Render's second
+	// memcpy holds a zero-length Mark at its very begin, where the first one ends.
+	// Worker: Walk recurses twice with the outer two calls spanning the same time.
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render", "memcpy", "Mark", "Walk"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 4, Begin: 150, End: 400},
+				{ID: 2, Begin: 100, End: 500},
+				{ID: 4, Begin: 600, End: 700},
+				{ID: 5, Begin: 700, End: 700},
+				{ID: 4, Begin: 700, End: 750},
+				{ID: 3, Begin: 500, End: 900},
+				{ID: 1, Begin: 0, End: 1000},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{
+				{ID: 6, Begin: 10, End: 50},
+				{ID: 6, Begin: 0, End: 100},
+				{ID: 6, Begin: 0, End: 100},
+			}},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+
+	got := make(map[string]string)
+	for _, info := range a.GetSlowestBlocks(100) {
+		var names []string
+		for i, node := range a.GetBlockAncestors(info) {
+			if node.Depth != i {
+				t.Errorf("%s ancestor %s has depth %d, want %d", info.Name, node.Name, node.Depth, i)
+			}
+			names = append(names, node.Name)
+		}
+		got[fmt.Sprintf("%s@%d/%d", info.Name, info.Begin, info.Depth)] = strings.Join(names, ">")
+	}
+
+	want := map[string]string{
+		"Frame@0/0":    "",
+		"Update@100/1": "Frame",
+		"memcpy@150/2": "Frame>Update",
+		"Render@500/1": "Frame",
+		"memcpy@600/2": "Frame>Render",
+		"Mark@700/3":   "Frame>Render>memcpy",
+		"memcpy@700/2": "Frame>Render",
+		"Walk@0/0":     "",
+		"Walk@0/1":     "Walk",
+		"Walk@10/2":    "Walk>Walk",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ancestors = %v, want %v", got, want)
+	}
+}
+
+func TestGetBlockAncestorsNotInTree(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10, End: 20},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name string
+		info *BlockInfo
+	}{
+		{"unknown thread", &BlockInfo{ThreadID: 9, Begin: 10, End: 20, Depth: 1}},
+		{"no such block", &BlockInfo{ThreadID: 1, Begin: 10, End: 30, Depth: 1}},
+		{"outside any parent", &BlockInfo{ThreadID: 1, Begin: 200, End: 300, Depth: 1}},
+		{"too deep", &BlockInfo{ThreadID: 1, Begin: 10, End: 20, Depth: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ancestors := a.GetBlockAncestors(tt.info); ancestors != nil {
+				t.Errorf("ancestors = %v, want none", ancestors)
+			}
+		})
+	}
+}
+
+func TestFindBlockByKey(t *testing.T) {
+	// Main runs three Frames, each holding Update, which holds Physics
+	var blocks []proftest.Block
+	for i := uint64(0); i < 3; i++ {
+		begin := i * 1000
+		blocks = append(blocks,
+			proftest.Block{ID: 3, Begin: begin + 200, End: begin + 300},
+			proftest.Block{ID: 2, Begin: begin + 100, End: begin + 400},
+			proftest.Block{ID: 1, Begin: begin, End: begin + 500},
+		)
+	}
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Physics"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+	}
+	full := NewAnalyzer(p.Parse(t, parser.ReadOptions{}))
+	// Every second record: Physics and Frame of the first frame, Update of the second...
+	sampled := NewAnalyzer(p.Parse(t, parser.ReadOptions{SampleBlocks: 2}))
+
+	tests := []struct {
+		name     string
+		analyzer *Analyzer
+		key      string
+		want     string // "name begin-end self depth path"
+		wantErr  string
+	}{
+		{"top level", full, "1/1/2", "Frame 2000-2500 200ns 0 []", ""},
+		{"nested", full, "1/3/1", "Physics 1200-1300 100ns 2 [Frame Update]", ""},
+		{"kept by sampling", sampled, "1/2/1", "Update 1100-1400 300ns 0 []", ""},
+		{"dropped by sampling", sampled, "1/2/0", "", "block 1/2/0 not found; it may have been dropped by sampling or filtering"},
+		{"past the last call", full, "1/1/3", "", "block 1/1/3 not found"},
+		{"unknown thread", full, "9/1/0", "", "thread 9 not found"},
+		{"malformed", full, "1/1", "", "invalid block key '1/1'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ancestors, err := tt.analyzer.FindBlockByKey(tt.key)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindBlockByKey: %v", err)
+			}
+
+			var path []string
+			for _, node := range ancestors {
+				path = append(path, node.Name)
+			}
+			got := fmt.Sprintf("%s %d-%d %v %d %v", info.Name, info.Begin, info.End, info.SelfDuration, info.Depth, path)
+			if got != tt.want || info.Key() != tt.key {
+				t.Errorf("block %s = %s, want %s", info.Key(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockKeysMatchAcrossLoads(t *testing.T) {
+	// Every block of a sampled load is found under its key in the full load, with the same span
+	var blocks []proftest.Block
+	for i := uint64(0); i < 10; i++ {
+		begin := i * 100
+		blocks = append(blocks, proftest.Block{ID: 2, Begin: begin + 10, End: begin + 20 + i}, proftest.Block{ID: 1, Begin: begin, End: begin + 90})
+	}
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+	}
+	full := NewAnalyzer(p.Parse(t, parser.ReadOptions{}))
+	sampled := NewAnalyzer(p.Parse(t, parser.ReadOptions{SampleBlocks: 3}))
+
+	found := 0
+	for _, info := range sampled.GetSlowestBlocks(100) {
+		match, _, err := full.FindBlockByKey(info.Key())
+		if err != nil {
+			t.Errorf("sampled block %s: %v", info.Key(), err)
+			continue
+		}
+		if match.Name != info.Name || match.Begin != info.Begin || match.End != info.End {
+			t.Errorf("block %s is %s %d-%d in the full load, %s %d-%d sampled", info.Key(), match.Name, match.Begin, match.End, info.Name, info.Begin, info.End)
+		}
+		found++
+	}
+	if found != 7 {
+		t.Errorf("matched %d sampled blocks, want 7", found)
+	}
+}
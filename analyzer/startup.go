@@ -0,0 +1,166 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// StartupCost describes the time spent before the application reached steady state
+type StartupCost struct {
+	Marker       string // Steady-state marker block name ("" if none was given)
+	MarkerFound  bool
+	Begin        uint64 // Start of the startup window (t0)
+	End          uint64 // End of the startup window
+	Duration     time.Duration
+	Contributors []*BlockInfo // Functions ranked by time spent inside the window
+}
+
+// GetStartupCost measures the time from the first top-level block (t0) to the first
+// occurrence of the marker block on any thread, and ranks the functions that ran
+// in that window. Without a marker, the first top-level block itself is treated
+// as the startup phase.
+func (a *Analyzer) GetStartupCost(marker string, limit int) (*StartupCost, error) {
+	first := a.firstTopLevelBlock()
+	if first == nil {
+		return nil, fmt.Errorf("profile contains no blocks")
+	}
+
+	cost := &StartupCost{
+		Marker: marker,
+		Begin:  first.Begin,
+		End:    first.End,
+	}
+
+	if marker != "" {
+		markerBegin, found := a.findFirstBlockByName(marker)
+		if !found {
+			return nil, fmt.Errorf("marker block '%s' not found", marker)
+		}
+		cost.MarkerFound = true
+		cost.End = markerBegin
+	}
+
+	if cost.End > cost.Begin {
+		cost.Duration = time.Duration(cost.End - cost.Begin)
+	}
+
+	// Aggregate time each function spent inside the startup window
+	blockMap := make(map[string]*BlockInfo)
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		a.aggregateWindow(thread.Blocks, cost.Begin, cost.End, threadID, thread.ThreadName, blockMap)
+	}
+	mergeNameOnlyEntries(blockMap)
+
+	for _, info := range blockMap {
+		if info.CallCount > 0 {
+			info.AvgDuration = info.Duration / time.Duration(info.CallCount)
+		}
+		cost.Contributors = append(cost.Contributors, info)
+	}
+
+	sort.Slice(cost.Contributors, func(i, j int) bool {
+		if cost.Contributors[i].Duration != cost.Contributors[j].Duration {
+			return cost.Contributors[i].Duration > cost.Contributors[j].Duration
+		}
+		return lessBlockInfo(cost.Contributors[i], cost.Contributors[j])
+	})
+
+	if limit > len(cost.Contributors) {
+		limit = len(cost.Contributors)
+	}
+	cost.Contributors = cost.Contributors[:limit]
+
+	return cost, nil
+}
+
+// firstTopLevelBlock returns the earliest-starting top-level block across all threads
+func (a *Analyzer) firstTopLevelBlock() *parser.Block {
+	var first *parser.Block
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		for _, block := range thread.Blocks {
+			if first == nil || block.Begin < first.Begin {
+				first = block
+			}
+		}
+	}
+	return first
+}
+
+// findFirstBlockByName returns the earliest begin time of any block with the given name
+func (a *Analyzer) findFirstBlockByName(target string) (uint64, bool) {
+	var earliest uint64
+	found := false
+
+	var walk func(blocks []*parser.Block)
+	walk = func(blocks []*parser.Block) {
+		for _, block := range blocks {
+			name, _, _ := a.resolveBlock(block)
+			if name == target && (!found || block.Begin < earliest) {
+				earliest = block.Begin
+				found = true
+			}
+			walk(block.Children)
+		}
+	}
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		walk(thread.Blocks)
+	}
+
+	return earliest, found
+}
+
+// aggregateWindow accumulates each block's inclusive and self time clipped to
+// [begin, end) into blockMap
+func (a *Analyzer) aggregateWindow(blocks []*parser.Block, begin, end uint64, threadID uint64, threadName string, blockMap map[string]*BlockInfo) {
+	walkBlocks(blocks, func(block *parser.Block, _ int) {
+		// Children lie within their parent, so they are outside the window as well
+		if block.Begin >= end || block.End <= begin {
+			return
+		}
+
+		clippedBegin := max(block.Begin, begin)
+		clippedEnd := min(block.End, end)
+		duration := time.Duration(clippedEnd - clippedBegin)
+
+		self := duration
+		for _, child := range block.Children {
+			if child.Begin < clippedEnd && child.End > clippedBegin {
+				self -= time.Duration(min(child.End, clippedEnd) - max(child.Begin, clippedBegin))
+			}
+		}
+		self = max(self, 0)
+
+		// Window totals are inclusive, so an excluded or folded anonymous block's
+		// time is either dropped or already covered by its ancestor
+		if a.isAnonymous(block) && a.anonymousPolicy != AnonymousByDescriptor {
+			return
+		}
+
+		name, file, line := a.resolveBlock(block)
+		key := a.aggregationKey(block, name)
+
+		if existing, ok := blockMap[key]; ok {
+			existing.Duration += duration
+			existing.SelfDuration += self
+			existing.CallCount++
+		} else {
+			blockMap[key] = &BlockInfo{
+				Name:         name,
+				File:         file,
+				Line:         line,
+				Duration:     duration,
+				SelfDuration: self,
+				CallCount:    1,
+				ThreadID:     threadID,
+				ThreadName:   threadName,
+			}
+		}
+	})
+}
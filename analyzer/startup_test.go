@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestGetStartupCost(t *testing.T) {
+	// Init runs 0-1000 with LoadAssets inside; the worker starts Ready at 1500
+	capture := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Init", "LoadAssets", "Ready", "Frame"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 100, End: 700},
+				{ID: 1, Begin: 0, End: 1000},
+				{ID: 4, Begin: 1200, End: 2000},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{
+				{ID: 3, Begin: 1500, End: 1600},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		marker       string
+		limit        int
+		wantDuration time.Duration
+		wantNames    []string
+		wantErr      bool
+	}{
+		{"first block without a marker", "", 10, 1000, []string{"Init", "LoadAssets"}, false},
+		{"until the marker", "Ready", 10, 1500, []string{"Init", "LoadAssets", "Frame"}, false},
+		{"limit", "Ready", 1, 1500, []string{"Init"}, false},
+		{"missing marker", "Nope", 10, 0, nil, true},
+	}
+
+	a := newTestAnalyzer(t, capture)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost, err := a.GetStartupCost(tt.marker, tt.limit)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("GetStartupCost succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetStartupCost: %v", err)
+			}
+			if cost.Duration != tt.wantDuration {
+				t.Errorf("Duration = %v, want %v", cost.Duration, tt.wantDuration)
+			}
+			if cost.MarkerFound != (tt.marker != "") {
+				t.Errorf("MarkerFound = %t", cost.MarkerFound)
+			}
+			if got := blockNames(cost.Contributors); !reflect.DeepEqual(got, tt.wantNames) {
+				t.Errorf("contributors = %v, want %v", got, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestGetStartupCostClipsToWindow(t *testing.T) {
+	capture := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Init", "Ready", "Frame"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 100},
+			{ID: 3, Begin: 200, End: 1200},
+			{ID: 2, Begin: 500, End: 600},
+		}}},
+	}
+
+	cost, err := newTestAnalyzer(t, capture).GetStartupCost("Ready", 10)
+	if err != nil {
+		t.Fatalf("GetStartupCost: %v", err)
+	}
+	for _, info := range cost.Contributors {
+		if info.Name == "Frame" && info.Duration != 300 {
+			t.Errorf("Frame duration = %v, want 300ns clipped to the window", info.Duration)
+		}
+	}
+}
+
+func TestGetStartupCostEmptyProfile(t *testing.T) {
+	a := newTestAnalyzer(t, &proftest.Profile{Threads: []proftest.Thread{{ID: 1, Name: "Main"}}})
+	if _, err := a.GetStartupCost("", 10); err == nil {
+		t.Error("GetStartupCost succeeded on a profile without blocks")
+	}
+}
@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// Subprofile is a slice of the profile cut around one call of a function
+type Subprofile struct {
+	Profile    *parser.ProfileData
+	Root       *Invocation // The chosen call, in the source profile
+	Invocation int         // 1-based index of Root among the function's invocations
+	Roots      int         // Top-level blocks in the slice: 1, or the call and its siblings
+}
+
+// ExtractSubprofile copies the subtree of one call of name into a standalone profile.
+// invocation is the 1-based index into GetInvocations' order (threads by ID, then
+// calls in time order); 0 picks the slowest call. withSiblings also keeps the blocks
+// sharing the call's parent (or the thread's other top-level blocks), so the call
+// can be compared with what ran around it.
+func (a *Analyzer) ExtractSubprofile(name string, invocation int, withSiblings bool) (*Subprofile, error) {
+	invocations, err := a.requireInvocations(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if invocation == 0 {
+		slowest := 0
+		for i, iv := range invocations {
+			if iv.Block.Duration() > invocations[slowest].Block.Duration() {
+				slowest = i
+			}
+		}
+		invocation = slowest + 1
+	}
+	if invocation < 1 || invocation > len(invocations) {
+		return nil, fmt.Errorf("invocation %d out of range: '%s' has %d invocations", invocation, name, len(invocations))
+	}
+
+	root := invocations[invocation-1]
+	thread := a.profile.Thread(root.ThreadID)
+
+	blocks := []*parser.Block{root.Block}
+	if withSiblings {
+		blocks = siblingsOf(thread.Blocks, root.Block)
+	}
+
+	return &Subprofile{
+		Profile:    a.profile.Extract(thread, blocks),
+		Root:       root,
+		Invocation: invocation,
+		Roots:      len(blocks),
+	}, nil
+}
+
+// siblingsOf returns the slice of blocks containing target: its parent's children,
+// or the thread's top-level blocks
+func siblingsOf(blocks []*parser.Block, target *parser.Block) []*parser.Block {
+	stack := [][]*parser.Block{blocks}
+	for len(stack) > 0 {
+		level := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, block := range level {
+			if block == target {
+				return level
+			}
+			if len(block.Children) > 0 {
+				stack = append(stack, block.Children)
+			}
+		}
+	}
+	return []*parser.Block{target}
+}
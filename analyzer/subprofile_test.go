@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestExtractSubprofile(t *testing.T) {
+	// Update runs three times under Frame on Main, the second call being the slowest
+	// and holding Physics; Worker runs one more
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Physics"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 0, End: 10},
+				{ID: 3, Begin: 25, End: 35},
+				{ID: 2, Begin: 20, End: 60},
+				{ID: 2, Begin: 70, End: 80},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 5}}},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name         string
+		function     string
+		invocation   int
+		withSiblings bool
+		want         int // Chosen invocation
+		thread       uint64
+		roots        int
+		blocks       int
+		wantErr      bool
+	}{
+		{"slowest call", "Update", 0, false, 2, 1, 1, 2, false},
+		{"first call", "Update", 1, false, 1, 1, 1, 1, false},
+		{"on another thread", "Update", 4, false, 4, 2, 1, 1, false},
+		{"with siblings", "Update", 2, true, 2, 1, 3, 4, false},
+		{"top-level with siblings", "Frame", 1, true, 1, 1, 1, 5, false},
+		{"out of range", "Update", 5, false, 0, 0, 0, 0, true},
+		{"negative", "Update", -1, false, 0, 0, 0, 0, true},
+		{"unknown name", "Render", 0, false, 0, 0, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := a.ExtractSubprofile(tt.function, tt.invocation, tt.withSiblings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExtractSubprofile error = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if sub.Invocation != tt.want || sub.Root.ThreadID != tt.thread || sub.Roots != tt.roots {
+				t.Errorf("invocation %d on thread %d with %d roots, want %d on %d with %d",
+					sub.Invocation, sub.Root.ThreadID, sub.Roots, tt.want, tt.thread, tt.roots)
+			}
+			if sub.Profile.TotalBlocksCount != tt.blocks {
+				t.Errorf("slice has %d blocks, want %d", sub.Profile.TotalBlocksCount, tt.blocks)
+			}
+			if !tt.withSiblings && sub.Profile.GetTotalDuration() != sub.Root.Block.Duration() {
+				t.Errorf("slice lasts %v, want the call's %v", sub.Profile.GetTotalDuration(), sub.Root.Block.Duration())
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package analyzer
+
+// issueSuggestions holds generic remediation advice for each issue type
+var issueSuggestions = map[string]string{
+	"Long Blocking Operation":    "Check whether the block waits on I/O, locks or another thread; move blocking work off this thread or split it into smaller steps.",
+	"Hot Function":               "Optimize the function's own code if its self time dominates, otherwise its most expensive callees; call it less often or cache its results.",
+	"Thread Imbalance":           "Compare per-thread busy time in get_thread_statistics and redistribute work, e.g. with a task queue or work stealing.",
+	"Excessive Context Switches": "Look for fine-grained locking, sleeping or blocking calls on this thread and reduce the number of threads to the CPU core count.",
+	"Intermittent Slow Child":    "Inspect the worst invocations: find what the slow child does differently there (cache miss, allocation, first-time initialization).",
+	"Short-Lived Threads":        "Replace per-task thread creation with a thread pool.",
+	"Fragmented Work":            "Batch the work so each call handles many items, or inline the function into its caller's loop to remove per-call overhead.",
+	"Unbalanced Recursion":       "Check what input drives the deepest recursion; bound the depth, convert the recursion to an explicit stack or loop, or rebalance the data structure being traversed.",
+	"Blocking I/O":               "Move the I/O to a dedicated I/O thread or use asynchronous I/O so this thread keeps running; check get_parent_at_timestamp to see what the thread was waiting in.",
+}
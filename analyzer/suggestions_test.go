@@ -0,0 +1,49 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestIssueSuggestions(t *testing.T) {
+	tests := []string{
+		"Long Blocking Operation",
+		"Hot Function",
+		"Thread Imbalance",
+		"Excessive Context Switches",
+		"Intermittent Slow Child",
+		"Short-Lived Threads",
+		"Fragmented Work",
+		"Unbalanced Recursion",
+		"Blocking I/O",
+	}
+
+	for _, issueType := range tests {
+		if issueSuggestions[issueType] == "" {
+			t.Errorf("no suggestion for %q issues", issueType)
+		}
+	}
+}
+
+func TestIssuesCarrySuggestions(t *testing.T) {
+	ms := uint64(time.Millisecond)
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Load", "Tick"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 300 * ms}}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: ms}}},
+		},
+	}
+
+	issues := newTestAnalyzer(t, p).AnalyzePerformanceIssues()
+	if len(issues) == 0 {
+		t.Fatal("no issues detected")
+	}
+	for _, issue := range issues {
+		if issue.Suggestion == "" || issue.Suggestion != issueSuggestions[issue.Type] {
+			t.Errorf("%s issue has suggestion %q, want %q", issue.Type, issue.Suggestion, issueSuggestions[issue.Type])
+		}
+	}
+}
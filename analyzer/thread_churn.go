@@ -0,0 +1,63 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// shortLivedThreadFraction is the largest share of the capture a thread may be active for to count as short-lived
+	shortLivedThreadFraction = 0.05
+	// shortLivedThreadMinCount is how many short-lived threads indicate thread churn
+	shortLivedThreadMinCount = 3
+	// shortLivedThreadNamesShown limits the thread names listed in the issue location
+	shortLivedThreadNamesShown = 5
+)
+
+// detectShortLivedThreads flags captures where several threads were each active for
+// only a small fraction of the capture - threads being created per task instead of pooled
+func (a *Analyzer) detectShortLivedThreads() []*PerformanceIssue {
+	captureDuration := a.profile.GetTotalDuration()
+	if captureDuration <= 0 {
+		return nil
+	}
+	maxLifetime := time.Duration(float64(captureDuration) * shortLivedThreadFraction)
+
+	var names []string
+	totalLifetime := time.Duration(0)
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		if len(thread.Blocks) == 0 {
+			continue
+		}
+
+		lifetime := time.Duration(thread.LastBlockEnd - thread.FirstBlockBegin)
+		if lifetime >= maxLifetime {
+			continue
+		}
+
+		names = append(names, thread.ThreadName)
+		totalLifetime += lifetime
+	}
+
+	if len(names) < shortLivedThreadMinCount {
+		return nil
+	}
+
+	sort.Strings(names)
+	location := strings.Join(names[:min(len(names), shortLivedThreadNamesShown)], ", ")
+	if len(names) > shortLivedThreadNamesShown {
+		location += fmt.Sprintf(" and %d more", len(names)-shortLivedThreadNamesShown)
+	}
+
+	return []*PerformanceIssue{{
+		Type:  "Short-Lived Threads",
+		Score: impactScore(a.captureFraction(float64(totalLifetime))),
+		Description: fmt.Sprintf("%d threads were each active for less than %.0f%% of the capture (total lifetime %v, avg %v) - consider a thread pool",
+			len(names), shortLivedThreadFraction*100, totalLifetime, totalLifetime/time.Duration(len(names))),
+		Location: location,
+		Duration: totalLifetime,
+	}}
+}
@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// churnProfile is a 100µs capture with a main thread busy throughout and the given
+// number of worker threads, each active for lifetime ns
+func churnProfile(workers int, lifetime uint64) *proftest.Profile {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Run"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 100000}}}},
+	}
+	for i := 0; i < workers; i++ {
+		begin := uint64(i) * 10000
+		p.Threads = append(p.Threads, proftest.Thread{
+			ID:     uint64(i + 2),
+			Name:   fmt.Sprintf("Worker %02d", i),
+			Blocks: []proftest.Block{{ID: 1, Begin: begin, End: begin + lifetime}},
+		})
+	}
+	// An idle thread without blocks is never counted
+	p.Threads = append(p.Threads, proftest.Thread{ID: 99, Name: "Idle"})
+	return p
+}
+
+func TestDetectShortLivedThreads(t *testing.T) {
+	tests := []struct {
+		name     string
+		workers  int
+		lifetime uint64
+		location string
+	}{
+		{"too few", shortLivedThreadMinCount - 1, 1000, ""},
+		{"long-lived", 5, 5000, ""},
+		{"churn", 3, 1000, "Worker 00, Worker 01, Worker 02"},
+		{"more than shown", 7, 1000, "Worker 00, Worker 01, Worker 02, Worker 03, Worker 04 and 2 more"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := newTestAnalyzer(t, churnProfile(tt.workers, tt.lifetime)).detectShortLivedThreads()
+			if tt.location == "" {
+				if len(issues) != 0 {
+					t.Errorf("reported %q", issues[0].Description)
+				}
+				return
+			}
+			if len(issues) != 1 {
+				t.Fatalf("got %d issues, want 1", len(issues))
+			}
+			if issues[0].Location != tt.location {
+				t.Errorf("location = %q, want %q", issues[0].Location, tt.location)
+			}
+			if want := time.Duration(tt.workers) * time.Duration(tt.lifetime); issues[0].Duration != want {
+				t.Errorf("duration = %v, want %v", issues[0].Duration, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// ThreadSide summarizes one of the two threads being compared
+type ThreadSide struct {
+	ThreadID      uint64
+	ThreadName    string
+	TotalDuration time.Duration
+	BlockCount    int
+}
+
+// ThreadFunctionDiff is a function's time on each of two threads
+type ThreadFunctionDiff struct {
+	Name              string
+	File              string
+	Line              int32
+	FirstDuration     time.Duration
+	SecondDuration    time.Duration
+	FirstCalls        int
+	SecondCalls       int
+	Delta             time.Duration // SecondDuration - FirstDuration
+	DivergencePercent float64       // |Delta| as a percentage of the larger duration
+}
+
+// ThreadComparison compares two threads of one profile function by function
+type ThreadComparison struct {
+	First  *ThreadSide
+	Second *ThreadSide
+	Mode   TimeMode
+
+	// Shared holds the functions run on both threads, largest absolute delta first
+	Shared []*ThreadFunctionDiff
+
+	// Divergent holds the shared functions whose time differs by at least the
+	// threshold, most divergent first
+	Divergent []*ThreadFunctionDiff
+
+	// OnlyFirst and OnlySecond hold the functions run on just one thread, by time
+	OnlyFirst  []*BlockInfo
+	OnlySecond []*BlockInfo
+}
+
+// CompareThreads diffs the time (measured in mode) each function took on two threads,
+// e.g. two workers that should behave the same. Functions are matched by name. A
+// shared function is divergent when its times differ by at least divergencePercent
+// of the larger one.
+func (a *Analyzer) CompareThreads(first, second *parser.ThreadData, mode TimeMode, divergencePercent float64) *ThreadComparison {
+	result := &ThreadComparison{
+		First:  a.threadSide(first),
+		Second: a.threadSide(second),
+		Mode:   mode,
+	}
+
+	firstByName := groupByName(a.threadHotspots(first))
+	secondByName := groupByName(a.threadHotspots(second))
+
+	for name, info := range firstByName {
+		other, ok := secondByName[name]
+		if !ok {
+			result.OnlyFirst = append(result.OnlyFirst, info)
+			continue
+		}
+
+		diff := &ThreadFunctionDiff{
+			Name:           name,
+			File:           info.File,
+			Line:           info.Line,
+			FirstDuration:  mode.Of(info),
+			SecondDuration: mode.Of(other),
+			FirstCalls:     info.CallCount,
+			SecondCalls:    other.CallCount,
+		}
+		diff.Delta = diff.SecondDuration - diff.FirstDuration
+		if larger := max(diff.FirstDuration, diff.SecondDuration); larger > 0 {
+			diff.DivergencePercent = float64(absDuration(diff.Delta)) / float64(larger) * 100
+		}
+
+		result.Shared = append(result.Shared, diff)
+		if diff.Delta != 0 && diff.DivergencePercent >= divergencePercent {
+			result.Divergent = append(result.Divergent, diff)
+		}
+	}
+	for name, info := range secondByName {
+		if _, ok := firstByName[name]; !ok {
+			result.OnlySecond = append(result.OnlySecond, info)
+		}
+	}
+
+	sort.Slice(result.Shared, func(i, j int) bool {
+		di, dj := absDuration(result.Shared[i].Delta), absDuration(result.Shared[j].Delta)
+		if di != dj {
+			return di > dj
+		}
+		return result.Shared[i].Name < result.Shared[j].Name
+	})
+	sort.Slice(result.Divergent, func(i, j int) bool {
+		x, y := result.Divergent[i], result.Divergent[j]
+		if x.DivergencePercent != y.DivergencePercent {
+			return x.DivergencePercent > y.DivergencePercent
+		}
+		if dx, dy := absDuration(x.Delta), absDuration(y.Delta); dx != dy {
+			return dx > dy
+		}
+		return x.Name < y.Name
+	})
+	sortByMode(result.OnlyFirst, mode)
+	sortByMode(result.OnlySecond, mode)
+
+	return result
+}
+
+// threadHotspots aggregates one thread's blocks by function, like aggregateHotspots
+// does for the whole profile
+func (a *Analyzer) threadHotspots(thread *parser.ThreadData) []*BlockInfo {
+	blockMap := make(map[string]*BlockInfo)
+	a.aggregateBlocks(thread.Blocks, thread.ThreadID, thread.ThreadName, ByCallSite, blockMap)
+	mergeNameOnlyEntries(blockMap)
+
+	hotspots := make([]*BlockInfo, 0, len(blockMap))
+	for _, info := range blockMap {
+		if info.CallCount > 0 {
+			info.AvgDuration = info.Duration / time.Duration(info.CallCount)
+		}
+		hotspots = append(hotspots, info)
+	}
+	return hotspots
+}
+
+// threadSide summarizes thread's totals
+func (a *Analyzer) threadSide(thread *parser.ThreadData) *ThreadSide {
+	return &ThreadSide{
+		ThreadID:      thread.ThreadID,
+		ThreadName:    thread.ThreadName,
+		TotalDuration: a.calculateThreadDuration(thread.Blocks),
+		BlockCount:    a.countBlocks(thread.Blocks),
+	}
+}
+
+// sortByMode orders functions by time measured in mode, largest first
+func sortByMode(infos []*BlockInfo, mode TimeMode) {
+	sort.Slice(infos, func(i, j int) bool {
+		di, dj := mode.Of(infos[i]), mode.Of(infos[j])
+		if di != dj {
+			return di > dj
+		}
+		return lessBlockInfo(infos[i], infos[j])
+	})
+}
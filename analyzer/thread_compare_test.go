@@ -0,0 +1,136 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// workersProfile has two 100ns Frames: WorkerA decodes for 60ns and uploads for
+// 20ns; WorkerB decodes for 30ns from two call sites and compresses for 50ns
+func workersProfile() *proftest.Profile {
+	return &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Decode", "Upload", "Compress", "Decode"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "WorkerA", Blocks: []proftest.Block{
+				{ID: 2, Begin: 10, End: 70},
+				{ID: 3, Begin: 70, End: 90},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+			{ID: 2, Name: "WorkerB", Blocks: []proftest.Block{
+				{ID: 2, Begin: 0, End: 10},
+				{ID: 5, Begin: 10, End: 30},
+				{ID: 4, Begin: 40, End: 90},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+		},
+	}
+}
+
+func TestCompareThreads(t *testing.T) {
+	a := newTestAnalyzer(t, workersProfile())
+	workerA, _ := a.FindThread("WorkerA")
+	workerB, _ := a.FindThread("WorkerB")
+
+	// diffs renders functions as "name first second calls delta divergence"
+	diffs := func(diffs []*ThreadFunctionDiff) []string {
+		var got []string
+		for _, diff := range diffs {
+			got = append(got, fmt.Sprintf("%s %v %v %d/%d %v %.0f%%", diff.Name, diff.FirstDuration, diff.SecondDuration,
+				diff.FirstCalls, diff.SecondCalls, diff.Delta, diff.DivergencePercent))
+		}
+		return got
+	}
+
+	tests := []struct {
+		name       string
+		reversed   bool
+		mode       TimeMode
+		threshold  float64
+		shared     []string
+		divergent  []string
+		onlyFirst  []string
+		onlySecond []string
+	}{
+		{
+			name:       "inclusive",
+			mode:       Inclusive,
+			threshold:  25,
+			shared:     []string{"Decode 60ns 30ns 1/2 -30ns 50%", "Frame 100ns 100ns 1/1 0s 0%"},
+			divergent:  []string{"Decode 60ns 30ns 1/2 -30ns 50%"},
+			onlyFirst:  []string{"Upload"},
+			onlySecond: []string{"Compress"},
+		},
+		{
+			name:       "exclusive",
+			mode:       Exclusive,
+			threshold:  25,
+			shared:     []string{"Decode 60ns 30ns 1/2 -30ns 50%", "Frame 20ns 20ns 1/1 0s 0%"},
+			divergent:  []string{"Decode 60ns 30ns 1/2 -30ns 50%"},
+			onlyFirst:  []string{"Upload"},
+			onlySecond: []string{"Compress"},
+		},
+		{
+			name:       "zero threshold skips equal times",
+			mode:       Inclusive,
+			threshold:  0,
+			shared:     []string{"Decode 60ns 30ns 1/2 -30ns 50%", "Frame 100ns 100ns 1/1 0s 0%"},
+			divergent:  []string{"Decode 60ns 30ns 1/2 -30ns 50%"},
+			onlyFirst:  []string{"Upload"},
+			onlySecond: []string{"Compress"},
+		},
+		{
+			name:       "threshold above every divergence",
+			mode:       Inclusive,
+			threshold:  60,
+			shared:     []string{"Decode 60ns 30ns 1/2 -30ns 50%", "Frame 100ns 100ns 1/1 0s 0%"},
+			onlyFirst:  []string{"Upload"},
+			onlySecond: []string{"Compress"},
+		},
+		{
+			name:       "reversed",
+			reversed:   true,
+			mode:       Inclusive,
+			threshold:  25,
+			shared:     []string{"Decode 30ns 60ns 2/1 30ns 50%", "Frame 100ns 100ns 1/1 0s 0%"},
+			divergent:  []string{"Decode 30ns 60ns 2/1 30ns 50%"},
+			onlyFirst:  []string{"Compress"},
+			onlySecond: []string{"Upload"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, second := workerA, workerB
+			if tt.reversed {
+				first, second = second, first
+			}
+			comparison := a.CompareThreads(first, second, tt.mode, tt.threshold)
+
+			if comparison.First.ThreadID != first.ThreadID || comparison.Second.ThreadID != second.ThreadID || comparison.Mode != tt.mode {
+				t.Errorf("compared threads %d and %d in %v, want %d and %d in %v",
+					comparison.First.ThreadID, comparison.Second.ThreadID, comparison.Mode, first.ThreadID, second.ThreadID, tt.mode)
+			}
+			for _, side := range []*ThreadSide{comparison.First, comparison.Second} {
+				if want := map[string]int{"WorkerA": 3, "WorkerB": 4}[side.ThreadName]; side.TotalDuration != 100 || side.BlockCount != want {
+					t.Errorf("%s: %v over %d blocks, want 100ns over %d", side.ThreadName, side.TotalDuration, side.BlockCount, want)
+				}
+			}
+
+			if got := diffs(comparison.Shared); !reflect.DeepEqual(got, tt.shared) {
+				t.Errorf("shared = %q, want %q", got, tt.shared)
+			}
+			if got := diffs(comparison.Divergent); !reflect.DeepEqual(got, tt.divergent) {
+				t.Errorf("divergent = %q, want %q", got, tt.divergent)
+			}
+			if got := blockNames(comparison.OnlyFirst); !reflect.DeepEqual(got, tt.onlyFirst) {
+				t.Errorf("only first = %v, want %v", got, tt.onlyFirst)
+			}
+			if got := blockNames(comparison.OnlySecond); !reflect.DeepEqual(got, tt.onlySecond) {
+				t.Errorf("only second = %v, want %v", got, tt.onlySecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ThreadSortKey selects the order of thread statistics
+type ThreadSortKey int
+
+const (
+	// ThreadsByDuration orders threads by total block time, busiest first. This is the default.
+	ThreadsByDuration ThreadSortKey = iota
+
+	// ThreadsByName orders threads alphabetically by name, for stable output across runs
+	ThreadsByName
+
+	// ThreadsByBlockCount orders threads by number of blocks, most first
+	ThreadsByBlockCount
+
+	// ThreadsByContextSwitches orders threads by number of context switches, most first
+	ThreadsByContextSwitches
+)
+
+// threadSortKeys lists every key, in the order they're documented
+var threadSortKeys = []ThreadSortKey{ThreadsByDuration, ThreadsByName, ThreadsByBlockCount, ThreadsByContextSwitches}
+
+// String returns the name accepted by ParseThreadSortKey
+func (k ThreadSortKey) String() string {
+	switch k {
+	case ThreadsByName:
+		return "name"
+	case ThreadsByBlockCount:
+		return "block_count"
+	case ThreadsByContextSwitches:
+		return "context_switches"
+	default:
+		return "duration"
+	}
+}
+
+// ParseThreadSortKey parses "duration", "name", "block_count" or "context_switches"
+func ParseThreadSortKey(s string) (ThreadSortKey, error) {
+	names := make([]string, len(threadSortKeys))
+	for i, key := range threadSortKeys {
+		if key.String() == s {
+			return key, nil
+		}
+		names[i] = key.String()
+	}
+	return ThreadsByDuration, fmt.Errorf("unknown thread sort key '%s' (use %s)", s, strings.Join(names, ", "))
+}
+
+// compare returns a negative number if x sorts before y under this key, a positive
+// one if after, and 0 if the key doesn't tell them apart
+func (k ThreadSortKey) compare(x, y *ThreadStats) int {
+	switch k {
+	case ThreadsByName:
+		return strings.Compare(x.ThreadName, y.ThreadName)
+	case ThreadsByBlockCount:
+		return y.BlockCount - x.BlockCount
+	case ThreadsByContextSwitches:
+		return y.ContextSwitches - x.ContextSwitches
+	default:
+		switch {
+		case x.TotalDuration > y.TotalDuration:
+			return -1
+		case x.TotalDuration < y.TotalDuration:
+			return 1
+		}
+		return 0
+	}
+}
+
+// SortThreadStats orders stats in place by key, breaking ties by thread ID
+func SortThreadStats(stats []*ThreadStats, key ThreadSortKey) {
+	sort.Slice(stats, func(i, j int) bool {
+		if c := key.compare(stats[i], stats[j]); c != 0 {
+			return c < 0
+		}
+		return stats[i].ThreadID < stats[j].ThreadID
+	})
+}
@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseThreadSortKey(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ThreadSortKey
+		wantErr bool
+	}{
+		{"duration", ThreadsByDuration, false},
+		{"name", ThreadsByName, false},
+		{"block_count", ThreadsByBlockCount, false},
+		{"context_switches", ThreadsByContextSwitches, false},
+		{"Name", ThreadsByDuration, true},
+		{"", ThreadsByDuration, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseThreadSortKey(tt.input)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Fatalf("ParseThreadSortKey(%q) = %v, %v; want %v, error %t", tt.input, got, err, tt.want, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.input {
+				t.Errorf("String() = %q, want %q", got.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestSortThreadStats(t *testing.T) {
+	tests := []struct {
+		key  ThreadSortKey
+		want []uint64 // Thread IDs
+	}{
+		// Render and Audio tie on duration, Main and Audio on block count: ties go by ID
+		{ThreadsByDuration, []uint64{1, 3, 4, 2}},
+		{ThreadsByName, []uint64{4, 1, 3, 2}},
+		{ThreadsByBlockCount, []uint64{2, 1, 4, 3}},
+		{ThreadsByContextSwitches, []uint64{3, 4, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key.String(), func(t *testing.T) {
+			stats := []*ThreadStats{
+				{ThreadID: 4, ThreadName: "Audio", TotalDuration: 50, BlockCount: 10, ContextSwitches: 5},
+				{ThreadID: 3, ThreadName: "Render", TotalDuration: 50, BlockCount: 2, ContextSwitches: 9},
+				{ThreadID: 2, ThreadName: "Worker", TotalDuration: 10, BlockCount: 30, ContextSwitches: 1},
+				{ThreadID: 1, ThreadName: "Main", TotalDuration: 100, BlockCount: 10, ContextSwitches: 0},
+			}
+			SortThreadStats(stats, tt.key)
+
+			var got []uint64
+			for _, s := range stats {
+				got = append(got, s.ThreadID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
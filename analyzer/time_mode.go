@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// TimeMode selects how a block's time is measured.
+//
+// Inclusive time is the block's full duration, including the time spent in nested
+// child blocks. Exclusive (self) time is the inclusive time minus the time spent in
+// direct children, i.e. the time attributable to the block's own code. Summing
+// exclusive time never double-counts; summing inclusive time across nesting levels does.
+type TimeMode int
+
+const (
+	Inclusive TimeMode = iota
+	Exclusive
+)
+
+// String returns the mode name as used in tool output
+func (m TimeMode) String() string {
+	if m == Exclusive {
+		return "exclusive"
+	}
+	return "inclusive"
+}
+
+// Of returns the duration of info measured in this mode
+func (m TimeMode) Of(info *BlockInfo) time.Duration {
+	if m == Exclusive {
+		return info.SelfDuration
+	}
+	return info.Duration
+}
+
+// blockTime returns the duration of block measured in mode
+func (a *Analyzer) blockTime(block *parser.Block, mode TimeMode) time.Duration {
+	if mode == Exclusive {
+		return a.selfTime(block)
+	}
+	return block.Duration()
+}
+
+// selfTime returns the block's exclusive time. Self times are computed once for the
+// whole profile on first use and reused by every analysis.
+func (a *Analyzer) selfTime(block *parser.Block) time.Duration {
+	a.selfTimesOnce.Do(func() {
+		a.selfTimes = make(map[*parser.Block]time.Duration)
+		for _, threadID := range a.sortedThreadIDs() {
+			thread := a.profile.Thread(threadID)
+			a.computeSelfTimes(thread.Blocks)
+		}
+	})
+
+	if self, ok := a.selfTimes[block]; ok {
+		return self
+	}
+	// Blocks outside the profile's trees (not expected) are computed directly
+	return computeSelfTime(block)
+}
+
+// computeSelfTimes fills the self time cache for blocks and their descendants
+func (a *Analyzer) computeSelfTimes(blocks []*parser.Block) {
+	walkBlocks(blocks, func(block *parser.Block, _ int) {
+		a.selfTimes[block] = computeSelfTime(block)
+	})
+}
+
+// computeSelfTime returns the block's duration minus the time spent in its direct children
+func computeSelfTime(block *parser.Block) time.Duration {
+	self := block.Duration()
+	for _, child := range block.Children {
+		self -= child.Duration()
+	}
+	if self < 0 {
+		return 0
+	}
+	return self
+}
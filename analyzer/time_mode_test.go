@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestComputeSelfTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		block *parser.Block
+		want  time.Duration
+	}{
+		{"leaf", &parser.Block{Begin: 0, End: 100}, 100},
+		{"children", &parser.Block{Begin: 0, End: 100, Children: []*parser.Block{
+			{Begin: 10, End: 30},
+			{Begin: 50, End: 60},
+		}}, 70},
+		{"grandchildren don't count", &parser.Block{Begin: 0, End: 100, Children: []*parser.Block{
+			{Begin: 10, End: 90, Children: []*parser.Block{{Begin: 20, End: 80}}},
+		}}, 20},
+		{"children exceeding the parent", &parser.Block{Begin: 0, End: 100, Children: []*parser.Block{
+			{Begin: 0, End: 80},
+			{Begin: 20, End: 100},
+		}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeSelfTime(tt.block); got != tt.want {
+				t.Errorf("computeSelfTime = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHotspotsByTimeMode(t *testing.T) {
+	// Frame is the longest inclusively, but spends most of it in Update
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 0, End: 700},
+			{ID: 1, Begin: 0, End: 1000},
+			{ID: 3, Begin: 1000, End: 1400},
+		}}},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		mode      TimeMode
+		want      []string
+		durations []time.Duration
+	}{
+		{Inclusive, []string{"Frame", "Update", "Render"}, []time.Duration{1000, 700, 400}},
+		{Exclusive, []string{"Update", "Render", "Frame"}, []time.Duration{700, 400, 300}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			hotspots := a.GetHotspotsWithMode(10, tt.mode)
+			if got := blockNames(hotspots); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("hotspots = %v, want %v", got, tt.want)
+			}
+			for i, hotspot := range hotspots {
+				if d := tt.mode.Of(hotspot); d != tt.durations[i] {
+					t.Errorf("%s takes %v, want %v", hotspot.Name, d, tt.durations[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// NestingAnomaly is a block whose direct children add up to more time than the
+// block itself lasted, which can't happen with properly nested, non-overlapping
+// calls. It points at overlapping sibling blocks, clock skew between the
+// timestamps, or a mis-built tree; the block's self time is clamped to 0.
+type NestingAnomaly struct {
+	Name             string
+	File             string
+	Line             int32
+	ThreadID         uint64
+	ThreadName       string
+	Begin            uint64 // Raw begin timestamp
+	Duration         time.Duration
+	ChildrenDuration time.Duration
+	Excess           time.Duration // ChildrenDuration - Duration
+}
+
+// NestingReport summarizes the blocks whose children exceed them
+type NestingReport struct {
+	Count int
+	Worst []*NestingAnomaly // Largest excess first
+}
+
+// CheckNesting finds every block whose direct children's durations sum to more
+// than its own and returns the count with the limit worst offenders
+func (a *Analyzer) CheckNesting(limit int) *NestingReport {
+	report := &NestingReport{}
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		walkBlocks(thread.Blocks, func(block *parser.Block, _ int) {
+			children := time.Duration(0)
+			for _, child := range block.Children {
+				children += child.Duration()
+			}
+			if children <= block.Duration() {
+				return
+			}
+
+			report.Count++
+			name, file, line := a.resolveBlock(block)
+			report.Worst = append(report.Worst, &NestingAnomaly{
+				Name:             name,
+				File:             file,
+				Line:             line,
+				ThreadID:         threadID,
+				ThreadName:       thread.ThreadName,
+				Begin:            block.Begin,
+				Duration:         block.Duration(),
+				ChildrenDuration: children,
+				Excess:           children - block.Duration(),
+			})
+		})
+	}
+
+	sort.Slice(report.Worst, func(i, j int) bool {
+		x, y := report.Worst[i], report.Worst[j]
+		if x.Excess != y.Excess {
+			return x.Excess > y.Excess
+		}
+		if x.ThreadID != y.ThreadID {
+			return x.ThreadID < y.ThreadID
+		}
+		return x.Begin < y.Begin
+	})
+	if limit >= 0 && len(report.Worst) > limit {
+		report.Worst = report.Worst[:limit]
+	}
+
+	return report
+}
@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestCheckNesting(t *testing.T) {
+	// On Main, both Frames hold overlapping siblings that add up to more than the
+	// Frame; on Worker, the children fill the Frame exactly
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "A", "B"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 10, End: 60},
+				{ID: 3, Begin: 40, End: 100},
+				{ID: 1, Begin: 0, End: 100},
+				{ID: 2, Begin: 200, End: 290},
+				{ID: 3, Begin: 210, End: 300},
+				{ID: 1, Begin: 200, End: 300},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{
+				{ID: 2, Begin: 0, End: 50},
+				{ID: 3, Begin: 50, End: 100},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+		},
+	}
+	a := newTestAnalyzer(t, p)
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string // "name thread begin duration children excess"
+	}{
+		{"all", 5, []string{"Frame Main 200 100ns 180ns 80ns", "Frame Main 0 100ns 110ns 10ns"}},
+		{"worst only", 1, []string{"Frame Main 200 100ns 180ns 80ns"}},
+		{"count only", 0, nil},
+		{"negative keeps all", -1, []string{"Frame Main 200 100ns 180ns 80ns", "Frame Main 0 100ns 110ns 10ns"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := a.CheckNesting(tt.limit)
+			if report.Count != 2 {
+				t.Errorf("count = %d, want 2 whatever the limit", report.Count)
+			}
+			var got []string
+			for _, anomaly := range report.Worst {
+				got = append(got, fmt.Sprintf("%s %s %d %v %v %v", anomaly.Name, anomaly.ThreadName, anomaly.Begin,
+					anomaly.Duration, anomaly.ChildrenDuration, anomaly.Excess))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("worst = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckNestingWellFormed(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10, End: 40},
+			{ID: 2, Begin: 50, End: 90},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}
+	if report := newTestAnalyzer(t, p).CheckNesting(5); report.Count != 0 || len(report.Worst) != 0 {
+		t.Errorf("report = %+v, want no anomalies", report)
+	}
+}
@@ -0,0 +1,94 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// Utilization describes how busy the profiled threads were over the capture
+type Utilization struct {
+	CaptureDuration time.Duration
+	BusyTime        time.Duration // Sum of each thread's busy time
+	IdleTime        time.Duration // Sum of each thread's time in idle blocks, not counted as busy
+	CoveredTime     time.Duration // Time during which at least one thread was busy
+	Parallelism     float64       // Average number of busy threads while any thread was busy
+	Coverage        float64       // Percent of the capture covered by instrumented blocks
+}
+
+// interval is a half-open [begin, end) time range
+type interval struct {
+	begin uint64
+	end   uint64
+}
+
+// GetUtilization computes overall parallelism and instrumentation coverage.
+// A thread counts as busy whenever one of its top-level blocks is active, except
+// inside blocks matching the idle patterns (see SetIdlePatterns).
+func (a *Analyzer) GetUtilization() *Utilization {
+	util := &Utilization{CaptureDuration: a.profile.GetTotalDuration()}
+
+	var all []interval
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		busy, idle := a.busyIntervals(thread)
+		util.BusyTime += intervalsDuration(busy)
+		util.IdleTime += intervalsDuration(idle)
+		all = append(all, busy...)
+	}
+
+	util.CoveredTime = intervalsDuration(mergeIntervals(all))
+
+	if util.CoveredTime > 0 {
+		util.Parallelism = float64(util.BusyTime) / float64(util.CoveredTime)
+	}
+	if util.CaptureDuration > 0 {
+		util.Coverage = float64(util.CoveredTime) / float64(util.CaptureDuration) * 100
+	}
+
+	return util
+}
+
+// blockIntervals returns the intervals of the given blocks
+func blockIntervals(blocks []*parser.Block) []interval {
+	intervals := make([]interval, 0, len(blocks))
+	for _, block := range blocks {
+		if block.End > block.Begin {
+			intervals = append(intervals, interval{begin: block.Begin, end: block.End})
+		}
+	}
+	return intervals
+}
+
+// mergeIntervals sorts intervals and merges any that overlap or touch
+func mergeIntervals(intervals []interval) []interval {
+	if len(intervals) == 0 {
+		return intervals
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].begin < intervals[j].begin
+	})
+
+	merged := []interval{intervals[0]}
+	for _, next := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if next.begin <= last.end {
+			last.end = max(last.end, next.end)
+		} else {
+			merged = append(merged, next)
+		}
+	}
+
+	return merged
+}
+
+// intervalsDuration returns the summed length of non-overlapping intervals
+func intervalsDuration(intervals []interval) time.Duration {
+	total := time.Duration(0)
+	for _, iv := range intervals {
+		total += time.Duration(iv.end - iv.begin)
+	}
+	return total
+}
@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestMergeIntervals(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []interval
+		want []interval
+	}{
+		{"empty", []interval{}, []interval{}},
+		{"disjoint", []interval{{30, 40}, {0, 10}}, []interval{{0, 10}, {30, 40}}},
+		{"overlapping", []interval{{0, 10}, {5, 20}}, []interval{{0, 20}}},
+		{"touching", []interval{{0, 10}, {10, 20}}, []interval{{0, 20}}},
+		{"contained", []interval{{0, 100}, {10, 20}, {30, 40}}, []interval{{0, 100}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeIntervals(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeIntervals = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUtilization(t *testing.T) {
+	tests := []struct {
+		name            string
+		threads         []proftest.Thread
+		wantBusy        time.Duration
+		wantCovered     time.Duration
+		wantParallelism float64
+		wantCoverage    float64
+	}{
+		{
+			name: "one thread",
+			threads: []proftest.Thread{
+				{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 500}, {ID: 1, Begin: 750, End: 1000}}},
+			},
+			wantBusy: 750, wantCovered: 750, wantParallelism: 1, wantCoverage: 75,
+		},
+		{
+			name: "two threads in parallel",
+			threads: []proftest.Thread{
+				{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}},
+				{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}},
+			},
+			wantBusy: 2000, wantCovered: 1000, wantParallelism: 2, wantCoverage: 100,
+		},
+		{
+			name: "nested blocks count once",
+			threads: []proftest.Thread{
+				{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 100, End: 200}, {ID: 1, Begin: 0, End: 1000}}},
+				{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 1, Begin: 500, End: 1000}}},
+			},
+			wantBusy: 1500, wantCovered: 1000, wantParallelism: 1.5, wantCoverage: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t, &proftest.Profile{
+				Begin: 0, End: 1000,
+				Descriptors: proftest.Descriptors("Work"),
+				Threads:     tt.threads,
+			})
+			util := a.GetUtilization()
+			if util.BusyTime != tt.wantBusy || util.CoveredTime != tt.wantCovered {
+				t.Errorf("busy %v covered %v, want %v and %v", util.BusyTime, util.CoveredTime, tt.wantBusy, tt.wantCovered)
+			}
+			if util.Parallelism != tt.wantParallelism || util.Coverage != tt.wantCoverage {
+				t.Errorf("parallelism %v coverage %v, want %v and %v", util.Parallelism, util.Coverage, tt.wantParallelism, tt.wantCoverage)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// ValueSummary aggregates all samples of a named value over the capture.
+// Integer-typed values are accumulated exactly in the Int* fields and
+// floating-point values in the Float* fields; IsFloat tells which are set.
+type ValueSummary struct {
+	Name        string
+	Type        string
+	IsFloat     bool
+	SampleCount int
+	Avg         float64
+
+	IntSum  int64
+	IntMin  int64
+	IntMax  int64
+	IntLast int64
+
+	FloatSum  float64
+	FloatMin  float64
+	FloatMax  float64
+	FloatLast float64
+
+	lastBegin uint64
+}
+
+// GetValueSummary aggregates numeric value blocks by name into sum, avg, min, max and last.
+// Array values contribute each element as a sample; string values are ignored.
+func (a *Analyzer) GetValueSummary() []*ValueSummary {
+	summaries := make(map[string]*ValueSummary)
+
+	var walk func(blocks []*parser.Block)
+	walk = func(blocks []*parser.Block) {
+		for _, block := range blocks {
+			if block.Value != nil {
+				name, _, _ := a.resolveBlock(block)
+				addValueSample(summaries, name, block)
+			}
+			walk(block.Children)
+		}
+	}
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		walk(thread.Blocks)
+	}
+
+	var result []*ValueSummary
+	for _, summary := range summaries {
+		if summary.SampleCount == 0 {
+			continue
+		}
+		if summary.IsFloat {
+			summary.Avg = summary.FloatSum / float64(summary.SampleCount)
+		} else {
+			summary.Avg = float64(summary.IntSum) / float64(summary.SampleCount)
+		}
+		result = append(result, summary)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// addValueSample folds a value block's elements into the summary for its name
+func addValueSample(summaries map[string]*ValueSummary, name string, block *parser.Block) {
+	value := block.Value
+	if !value.IsFloat() && !value.IsInteger() {
+		return
+	}
+
+	summary, ok := summaries[name]
+	if !ok {
+		summary = &ValueSummary{
+			Name:    name,
+			Type:    value.Type.String(),
+			IsFloat: value.IsFloat(),
+		}
+		summaries[name] = summary
+	}
+
+	// A name recorded with mixed types is summarized using its first type
+	if summary.IsFloat != value.IsFloat() {
+		return
+	}
+
+	isLatest := summary.SampleCount == 0 || block.Begin >= summary.lastBegin
+
+	if value.IsFloat() {
+		for _, v := range value.Floats() {
+			if summary.SampleCount == 0 || v < summary.FloatMin {
+				summary.FloatMin = v
+			}
+			if summary.SampleCount == 0 || v > summary.FloatMax {
+				summary.FloatMax = v
+			}
+			summary.FloatSum += v
+			summary.SampleCount++
+			if isLatest {
+				summary.FloatLast = v
+			}
+		}
+	} else {
+		for _, v := range value.Integers() {
+			if summary.SampleCount == 0 || v < summary.IntMin {
+				summary.IntMin = v
+			}
+			if summary.SampleCount == 0 || v > summary.IntMax {
+				summary.IntMax = v
+			}
+			summary.IntSum += v
+			summary.SampleCount++
+			if isLatest {
+				summary.IntLast = v
+			}
+		}
+	}
+
+	if isLatest {
+		summary.lastBegin = block.Begin
+	}
+}
@@ -0,0 +1,80 @@
+package analyzer
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestGetValueSummary(t *testing.T) {
+	int16s := func(values ...int16) []byte {
+		var data []byte
+		for _, v := range values {
+			data = binary.LittleEndian.AppendUint16(data, uint16(v))
+		}
+		return data
+	}
+
+	capture := &proftest.Profile{
+		Descriptors: []proftest.Descriptor{
+			{ID: 1, Name: "Frame", Type: parser.BlockTypeBlock},
+			{ID: 2, Name: "fps", Type: parser.BlockTypeValue},
+			{ID: 3, Name: "entities", Type: parser.BlockTypeValue},
+			{ID: 4, Name: "samples", Type: parser.BlockTypeValue},
+			{ID: 5, Name: "label", Type: parser.BlockTypeValue},
+		},
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 300, End: 1, Payload: proftest.DoubleValue(30)},
+				{ID: 3, Begin: 100, End: 2, Payload: proftest.Int32Value(-5)},
+				{ID: 1, Begin: 0, End: 1000},
+				{ID: 2, Begin: 100, End: 1, Payload: proftest.DoubleValue(60)},
+				{ID: 4, Begin: 500, End: 3, Payload: proftest.Value(parser.ValueTypeInt16, true, int16s(1, 2, 3))},
+				{ID: 5, Begin: 500, End: 4, Payload: proftest.Value(parser.ValueTypeString, true, []byte("hi\x00"))},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{
+				{ID: 3, Begin: 50, End: 2, Payload: proftest.Int32Value(15)},
+				// A different type under the same name is skipped
+				{ID: 3, Begin: 60, End: 2, Payload: proftest.DoubleValue(1000)},
+			}},
+		},
+	}
+
+	summaries := newTestAnalyzer(t, capture).GetValueSummary()
+
+	tests := []struct {
+		name      string
+		wantType  string
+		wantFloat bool
+		count     int
+		avg       float64
+		min, max  float64
+		last      float64
+	}{
+		{"entities", "int32", false, 2, 5, -5, 15, -5},
+		{"fps", "double", true, 2, 45, 30, 60, 30},
+		{"samples", "int16", false, 3, 2, 1, 3, 3},
+	}
+
+	if len(summaries) != len(tests) {
+		t.Fatalf("got %d summaries, want %d (strings skipped)", len(summaries), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := summaries[i]
+			if s.Name != tt.name || s.Type != tt.wantType || s.IsFloat != tt.wantFloat {
+				t.Fatalf("summary = %s %s float %t, want %s %s float %t", s.Name, s.Type, s.IsFloat, tt.name, tt.wantType, tt.wantFloat)
+			}
+			min, max, last := float64(s.IntMin), float64(s.IntMax), float64(s.IntLast)
+			if s.IsFloat {
+				min, max, last = s.FloatMin, s.FloatMax, s.FloatLast
+			}
+			if s.SampleCount != tt.count || s.Avg != tt.avg || min != tt.min || max != tt.max || last != tt.last {
+				t.Errorf("count %d avg %v min %v max %v last %v, want %d %v %v %v %v",
+					s.SampleCount, s.Avg, min, max, last, tt.count, tt.avg, tt.min, tt.max, tt.last)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// WorstInvocation is the single block with the most self time in the capture
+type WorstInvocation struct {
+	ThreadID     uint64
+	ThreadName   string
+	SelfDuration time.Duration
+	Offset       time.Duration // Begin of the block, relative to the capture begin
+	Key          string        // Stable identity of the block; see parser.Block.Key
+
+	// Path is the block's ancestor chain, outermost first, ending with the block
+	Path []*PathNode
+}
+
+// FindWorstInvocation returns the one block instance, on any thread, that spent
+// the most time in its own code rather than in children: the single worst moment
+// of the run, as opposed to aggregated hotspots. Unclosed blocks are skipped as
+// their duration is a guess. It returns nil if the capture has no closed blocks.
+func (a *Analyzer) FindWorstInvocation() *WorstInvocation {
+	var worst *WorstInvocation
+
+	for _, threadID := range a.sortedThreadIDs() {
+		thread := a.profile.Thread(threadID)
+		var stack []*parser.Block
+
+		walkBlocks(thread.Blocks, func(block *parser.Block, depth int) {
+			stack = append(stack[:depth], block)
+			if block.Unclosed {
+				return
+			}
+
+			self := a.selfTime(block)
+			if worst != nil && self <= worst.SelfDuration {
+				return
+			}
+			worst = &WorstInvocation{
+				ThreadID:     threadID,
+				ThreadName:   thread.ThreadName,
+				SelfDuration: self,
+				Offset:       a.CaptureOffset(block.Begin),
+				Key:          block.Key(threadID),
+				Path:         a.pathNodes(stack),
+			}
+		})
+	}
+
+	return worst
+}
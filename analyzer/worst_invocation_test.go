@@ -0,0 +1,70 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestFindWorstInvocation(t *testing.T) {
+	// On Main, Frame (10ns of self time) holds Update (10ns) and Render (30ns), and
+	// Update holds Physics (50ns). Worker runs the given Job blocks.
+	profile := func(worker ...proftest.Block) *proftest.Profile {
+		return &proftest.Profile{
+			Begin:       0,
+			End:         200,
+			Descriptors: proftest.Descriptors("Frame", "Update", "Physics", "Render", "Job"),
+			Threads: []proftest.Thread{
+				{ID: 1, Name: "Main", Blocks: []proftest.Block{
+					{ID: 3, Begin: 5, End: 55},
+					{ID: 2, Begin: 0, End: 60},
+					{ID: 4, Begin: 60, End: 90},
+					{ID: 1, Begin: 0, End: 100},
+				}},
+				{ID: 2, Name: "Worker", Blocks: worker},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		worker []proftest.Block
+		want   string // "thread key self offset path"
+	}{
+		{"deepest leaf", nil, "Main 1/3/0 50ns 5ns Frame>Update>Physics"},
+		{"on another thread", []proftest.Block{{ID: 5, Begin: 100, End: 170}}, "Worker 2/5/0 70ns 100ns Job"},
+		{"tie keeps the earlier thread", []proftest.Block{{ID: 5, Begin: 100, End: 150}}, "Main 1/3/0 50ns 5ns Frame>Update>Physics"},
+		{"unclosed blocks are skipped", []proftest.Block{{ID: 5, Begin: 100, End: 0}}, "Main 1/3/0 50ns 5ns Frame>Update>Physics"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			worst := newTestAnalyzer(t, profile(tt.worker...)).FindWorstInvocation()
+			if worst == nil {
+				t.Fatal("FindWorstInvocation found nothing")
+			}
+			var path []string
+			for _, node := range worst.Path {
+				path = append(path, node.Name)
+			}
+			got := fmt.Sprintf("%s %s %v %v %s", worst.ThreadName, worst.Key, worst.SelfDuration, worst.Offset, strings.Join(path, ">"))
+			if got != tt.want {
+				t.Errorf("worst = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindWorstInvocationNoClosedBlocks(t *testing.T) {
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         100,
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 10, End: 0}}}},
+	}
+	if worst := newTestAnalyzer(t, p).FindWorstInvocation(); worst != nil {
+		t.Errorf("worst = %+v, want none", worst)
+	}
+}
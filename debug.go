@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// debugEnabled tells whether EASYPROFILER_DEBUG asks for the debugging tools. They
+// expose raw file internals that only matter when reverse-engineering the format.
+func debugEnabled() bool {
+	switch os.Getenv("EASYPROFILER_DEBUG") {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
+
+func registerDebugTools(s *server.MCPServer) {
+	// Dump the first records of a file with their offsets and sizes
+	debugPeekRecordsTool := mcp.NewTool("debug_peek_records",
+		mcp.WithDescription("Debugging aid for format variants the parser gets wrong: decode the header and the first descriptor, thread and block records of a .prof file, with the byte offset and size of each record, without loading it. Decoding stops at the first error, which is reported with its offset"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the .prof file"),
+		),
+		mcp.WithNumber("records",
+			mcp.Description("Number of descriptors, threads and blocks per thread to dump (default: 5)"),
+		),
+	)
+
+	s.AddTool(debugPeekRecordsTool, timed(debugPeekRecordsHandler))
+}
+
+func debugPeekRecordsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filePath, ok := request.Params.Arguments["file_path"].(string)
+	if !ok {
+		return mcp.NewToolResultError("file_path parameter is required"), nil
+	}
+
+	records := 5
+	if r, ok := request.Params.Arguments["records"].(float64); ok && r > 0 {
+		records = int(r)
+	}
+
+	reader, err := parser.NewReader(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to open file: %v", err)), nil
+	}
+	defer reader.Close()
+
+	report, err := reader.Peek(records)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to peek profile: %v", err)), nil
+	}
+
+	// Format results
+	header := report.Header
+	headerFields := map[string]interface{}{
+		"offset":                  byteOffset(0),
+		"size":                    report.HeaderSize,
+		"signature":               fmt.Sprintf("0x%08X", header.Signature),
+		"version":                 fmt.Sprintf("0x%08X (%s)", header.Version, header.VersionString()),
+		"pid":                     header.PID,
+		"cpu_frequency":           header.CPUFrequency,
+		"begin_time":              header.BeginTime,
+		"end_time":                header.EndTime,
+		"memory_size":             header.MemorySize,
+		"descriptors_memory_size": header.DescriptorsMemorySize,
+		"blocks_count":            header.BlocksCount,
+		"descriptors_count":       header.DescriptorsCount,
+		"threads_count":           header.ThreadsCount,
+		"bookmarks_count":         header.BookmarksCount,
+		"padding":                 header.Padding,
+	}
+	if len(report.HeaderWarnings) > 0 {
+		headerFields["warnings"] = report.HeaderWarnings
+	}
+
+	result := map[string]interface{}{
+		"file":      filePath,
+		"file_size": report.FileSize,
+		"header":    headerFields,
+	}
+
+	if report.DescriptorsAt > 0 {
+		descriptors := make([]map[string]interface{}, len(report.Descriptors))
+		for i, d := range report.Descriptors {
+			descriptors[i] = map[string]interface{}{
+				"offset": byteOffset(d.Offset),
+				"size":   d.Size,
+				"id":     d.Descriptor.ID,
+				"name":   d.Descriptor.Name,
+				"file":   d.Descriptor.File,
+				"line":   d.Descriptor.Line,
+				"type":   d.Descriptor.Type,
+				"status": d.Descriptor.Status,
+				"color":  fmt.Sprintf("0x%08X", d.Descriptor.Color),
+			}
+		}
+		result["descriptors"] = map[string]interface{}{
+			"offset":  byteOffset(report.DescriptorsAt),
+			"records": descriptors,
+		}
+	}
+
+	if report.ThreadsAt > 0 {
+		threads := make([]map[string]interface{}, len(report.Threads))
+		for i, t := range report.Threads {
+			blocks := make([]map[string]interface{}, len(t.Blocks))
+			for j, b := range t.Blocks {
+				blocks[j] = map[string]interface{}{
+					"offset":   byteOffset(b.Offset),
+					"size":     b.Size,
+					"begin":    b.Block.Begin,
+					"end":      b.Block.End,
+					"duration": formatDuration(time.Duration(b.Block.End - b.Block.Begin)),
+					"id":       b.Block.ID,
+					"name":     b.Block.Name,
+				}
+			}
+			threads[i] = map[string]interface{}{
+				"offset":                  byteOffset(t.Offset),
+				"thread_id":               t.ThreadID,
+				"name":                    t.Name,
+				"context_switches_offset": byteOffset(t.ContextSwitchesAt),
+				"context_switches":        t.ContextSwitches,
+				"blocks_offset":           byteOffset(t.BlocksAt),
+				"blocks_count":            t.BlocksCount,
+				"blocks":                  blocks,
+				"blocks_not_shown":        t.BlocksNotShown,
+			}
+			if t.EndOffset > 0 {
+				threads[i]["end_offset"] = byteOffset(t.EndOffset)
+			}
+		}
+		result["threads"] = map[string]interface{}{
+			"offset":  byteOffset(report.ThreadsAt),
+			"records": threads,
+		}
+	}
+
+	if report.Error != "" {
+		result["error"] = report.Error
+		result["stopped_at"] = byteOffset(report.StoppedAt)
+	}
+
+	return jsonResult(result)
+}
+
+// byteOffset formats a file offset in decimal and hex, to match against hex dumps
+func byteOffset(offset int64) string {
+	return fmt.Sprintf("%d (0x%X)", offset, offset)
+}
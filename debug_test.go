@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestDebugEnabled(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"", false},
+		{"0", false},
+		{"false", false},
+		{"1", true},
+		{"true", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("EASYPROFILER_DEBUG", tt.value)
+			if got := debugEnabled(); got != tt.want {
+				t.Errorf("debugEnabled() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugPeekRecordsHandler(t *testing.T) {
+	path := (&proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 10},
+			{ID: 1, Begin: 20, End: 30},
+		}}},
+	}).WriteFile(t)
+
+	tests := []struct {
+		name    string
+		records interface{}
+		blocks  int
+	}{
+		{"default", nil, 2},
+		{"one record", float64(1), 1},
+		{"negative records use the default", float64(-3), 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]interface{}{"file_path": path}
+			if tt.records != nil {
+				args["records"] = tt.records
+			}
+			result := callToolJSON(t, debugPeekRecordsHandler, args)
+
+			header := result["header"].(map[string]interface{})
+			if header["offset"] != "0 (0x0)" || header["size"] != float64(72) || header["signature"] != "0x45617379" {
+				t.Errorf("header = %v", header)
+			}
+			descriptors := result["descriptors"].(map[string]interface{})
+			if descriptors["offset"] != "72 (0x48)" {
+				t.Errorf("descriptors offset = %v, want 72", descriptors["offset"])
+			}
+
+			threads := list(t, result["threads"].(map[string]interface{}), "records")
+			if len(threads) != 1 {
+				t.Fatalf("%d threads, want 1", len(threads))
+			}
+			blocks := list(t, threads[0], "blocks")
+			if len(blocks) != tt.blocks || threads[0]["blocks_not_shown"] != float64(2-tt.blocks) {
+				t.Errorf("%d blocks shown, %v not shown; want %d", len(blocks), threads[0]["blocks_not_shown"], tt.blocks)
+			}
+			if blocks[0]["size"] != float64(23) {
+				t.Errorf("block size = %v, want 23", blocks[0]["size"])
+			}
+			if _, stopped := result["error"]; stopped {
+				t.Errorf("peek stopped: %v", result["error"])
+			}
+		})
+	}
+}
+
+func TestDebugPeekRecordsHandlerMissingFile(t *testing.T) {
+	if text, isError := callTool(t, debugPeekRecordsHandler, map[string]interface{}{"file_path": "/nonexistent.prof"}); !isError {
+		t.Errorf("want a tool error, got %s", text)
+	}
+}
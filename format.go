@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// durationUnit is a fixed unit durations can be rendered in
+type durationUnit struct {
+	size   time.Duration
+	suffix string
+}
+
+// durationUnits are the accepted duration_unit values; "auto" uses time.Duration.String
+var durationUnits = map[string]durationUnit{
+	"ns": {time.Nanosecond, "ns"},
+	"us": {time.Microsecond, "µs"},
+	"ms": {time.Millisecond, "ms"},
+	"s":  {time.Second, "s"},
+}
+
+// outputFormat controls how durations and other numbers are rendered by every tool
+var outputFormat = struct {
+	Unit      string
+	Precision int
+
+	// Raw emits machine-friendly values for scripts: durations as integer
+	// nanoseconds, percentages as fractions (0-1), unrounded numbers and compact JSON
+	Raw bool
+
+	// MaxNameLength truncates function names longer than this many characters (0 =
+	// no limit); FullNames then adds the untruncated name alongside
+	MaxNameLength int
+	FullNames     bool
+}{
+	Unit:      "auto",
+	Precision: 3,
+}
+
+// formatDuration renders d in the configured output unit and precision, or as
+// integer nanoseconds in raw mode
+func formatDuration(d time.Duration) interface{} {
+	if outputFormat.Raw {
+		return d.Nanoseconds()
+	}
+	return humanDuration(d)
+}
+
+// humanDuration renders d in the configured output unit and precision regardless of
+// raw mode, for text meant to be read rather than parsed
+func humanDuration(d time.Duration) string {
+	unit, ok := durationUnits[outputFormat.Unit]
+	if !ok {
+		return d.String()
+	}
+	value := float64(d) / float64(unit.size)
+	return strconv.FormatFloat(value, 'f', outputFormat.Precision, 64) + unit.suffix
+}
+
+// formatPercent renders a percentage (0-100) as e.g. "45.00%", or as a fraction
+// (0.45) in raw mode
+func formatPercent(percent float64) interface{} {
+	if outputFormat.Raw {
+		return percent / 100
+	}
+	return fmt.Sprintf("%.2f%%", percent)
+}
+
+// formatNumber renders v with precision digits after the decimal point, or
+// unrounded in raw mode
+func formatNumber(v float64, precision int) interface{} {
+	if outputFormat.Raw {
+		return v
+	}
+	return strconv.FormatFloat(v, 'f', precision, 64)
+}
+
+func setOutputFormatHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if unit, ok := request.Params.Arguments["duration_unit"].(string); ok && unit != "" {
+		if _, known := durationUnits[unit]; !known && unit != "auto" {
+			return mcp.NewToolResultError(fmt.Sprintf("Unknown duration_unit '%s' (use auto, ns, us, ms or s)", unit)), nil
+		}
+		outputFormat.Unit = unit
+	}
+	if precision, ok := request.Params.Arguments["precision"].(float64); ok {
+		if precision < 0 || precision > 9 {
+			return mcp.NewToolResultError("precision must be between 0 and 9"), nil
+		}
+		outputFormat.Precision = int(precision)
+	}
+	if length, ok := request.Params.Arguments["max_name_length"].(float64); ok {
+		if length != 0 && length < minNameLength {
+			return mcp.NewToolResultError(fmt.Sprintf("max_name_length must be 0 (no limit) or at least %d", minNameLength)), nil
+		}
+		outputFormat.MaxNameLength = int(length)
+	}
+	if full, ok := request.Params.Arguments["full_names"].(bool); ok {
+		outputFormat.FullNames = full
+	}
+	if mode, ok := request.Params.Arguments["mode"].(string); ok && mode != "" {
+		switch mode {
+		case "human":
+			outputFormat.Raw = false
+		case "raw":
+			outputFormat.Raw = true
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("Unknown mode '%s' (use human or raw)", mode)), nil
+		}
+	}
+
+	mode := "human"
+	if outputFormat.Raw {
+		mode = "raw"
+	}
+
+	result := map[string]interface{}{
+		"status":          "success",
+		"mode":            mode,
+		"duration_unit":   outputFormat.Unit,
+		"precision":       outputFormat.Precision,
+		"max_name_length": outputFormat.MaxNameLength,
+		"full_names":      outputFormat.FullNames,
+		"example":         formatDuration(1234567890 * time.Nanosecond),
+	}
+
+	return jsonResult(result)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestFormatDuration(t *testing.T) {
+	d := 1234567890 * time.Nanosecond
+
+	tests := []struct {
+		name      string
+		unit      string
+		precision int
+		raw       bool
+		want      interface{}
+	}{
+		{"auto", "auto", 3, false, "1.23456789s"},
+		{"ns", "ns", 0, false, "1234567890ns"},
+		{"us", "us", 1, false, "1234567.9µs"},
+		{"ms", "ms", 3, false, "1234.568ms"},
+		{"s", "s", 2, false, "1.23s"},
+		{"unknown unit falls back to auto", "min", 3, false, "1.23456789s"},
+		{"raw", "ms", 3, true, int64(1234567890)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			outputFormat.Unit = tt.unit
+			outputFormat.Precision = tt.precision
+			outputFormat.Raw = tt.raw
+
+			if got := formatDuration(d); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("formatDuration(%v) = %#v, want %#v", d, got, tt.want)
+			}
+			// humanDuration ignores raw mode
+			if human := humanDuration(d); tt.raw && human != "1234.568ms" {
+				t.Errorf("humanDuration(%v) in raw mode = %q, want 1234.568ms", d, human)
+			}
+		})
+	}
+}
+
+func TestFormatPercentAndNumber(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         bool
+		wantPercent interface{}
+		wantNumber  interface{}
+	}{
+		{"human", false, "45.13%", "2.72"},
+		{"raw", true, 0.45126, 2.71828},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			outputFormat.Raw = tt.raw
+
+			if got := formatPercent(45.126); !reflect.DeepEqual(got, tt.wantPercent) {
+				t.Errorf("formatPercent(45.126) = %#v, want %#v", got, tt.wantPercent)
+			}
+			if got := formatNumber(2.71828, 2); !reflect.DeepEqual(got, tt.wantNumber) {
+				t.Errorf("formatNumber(2.71828, 2) = %#v, want %#v", got, tt.wantNumber)
+			}
+		})
+	}
+}
+
+func TestSetOutputFormatHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr string
+		want    map[string]interface{}
+	}{
+		{
+			name: "defaults",
+			args: map[string]interface{}{},
+			want: map[string]interface{}{"mode": "human", "duration_unit": "auto", "precision": 3.0, "example": "1.23456789s"},
+		},
+		{
+			name: "unit and precision",
+			args: map[string]interface{}{"duration_unit": "ms", "precision": 1.0},
+			want: map[string]interface{}{"mode": "human", "duration_unit": "ms", "precision": 1.0, "example": "1234.6ms"},
+		},
+		{
+			name: "raw mode",
+			args: map[string]interface{}{"mode": "raw"},
+			want: map[string]interface{}{"mode": "raw", "example": 1234567890.0},
+		},
+		{
+			name: "name length",
+			args: map[string]interface{}{"max_name_length": 20.0, "full_names": true},
+			want: map[string]interface{}{"max_name_length": 20.0, "full_names": true},
+		},
+		{"unknown unit", map[string]interface{}{"duration_unit": "min"}, "Unknown duration_unit", nil},
+		{"negative precision", map[string]interface{}{"precision": -1.0}, "precision must be between", nil},
+		{"precision too large", map[string]interface{}{"precision": 10.0}, "precision must be between", nil},
+		{"name length too short", map[string]interface{}{"max_name_length": 3.0}, "max_name_length must be 0", nil},
+		{"unknown mode", map[string]interface{}{"mode": "fancy"}, "Unknown mode", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+
+			if tt.wantErr != "" {
+				before := outputFormat
+				text, isError := callTool(t, setOutputFormatHandler, tt.args)
+				if !isError || !strings.Contains(text, tt.wantErr) {
+					t.Fatalf("result = %q (error %t), want an error containing %q", text, isError, tt.wantErr)
+				}
+				if outputFormat != before {
+					t.Errorf("rejected call changed the output format to %+v", outputFormat)
+				}
+				return
+			}
+
+			result := callToolJSON(t, setOutputFormatHandler, tt.args)
+			for key, want := range tt.want {
+				if !reflect.DeepEqual(result[key], want) {
+					t.Errorf("%s = %#v, want %#v", key, result[key], want)
+				}
+			}
+		})
+	}
+}
+
+// formattedValue matches the human renderings raw mode must never emit: durations
+// such as "1.5ms" or "2m3s", percentages such as "45.00%" and rounded numbers
+// such as "2.72"
+var formattedValue = regexp.MustCompile(`^-?[0-9.]+(ns|µs|us|ms|s|m|h|%)?([0-9.]+(ns|µs|us|ms|s|m))*$`)
+
+// checkRawValues fails for any string in v that looks like a formatted number,
+// and counts the numbers it finds
+func checkRawValues(t *testing.T, path string, v interface{}, numbers *int) {
+	t.Helper()
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			checkRawValues(t, path+"."+key, value, numbers)
+		}
+	case []interface{}:
+		for _, value := range v {
+			checkRawValues(t, path+"[]", value, numbers)
+		}
+	case string:
+		if formattedValue.MatchString(v) {
+			t.Errorf("%s = %q is formatted in raw mode", path, v)
+		}
+	case float64:
+		*numbers++
+	}
+}
+
+func TestRawModeAcrossTools(t *testing.T) {
+	// Frame runs 40ms on Main around Update and a Tick event; Work runs 10ms on Worker
+	ms := uint64(time.Millisecond)
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         40 * ms,
+		Descriptors: proftest.Descriptors("Frame", "Update", "Work", "Tick"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 5 * ms, End: 20 * ms},
+				{ID: 4, Begin: 30 * ms, End: 30 * ms},
+				{ID: 1, Begin: 0, End: 40 * ms},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 3, Begin: 10 * ms, End: 20 * ms}}},
+		},
+	}
+	p.Descriptors[3].Type = parser.BlockTypeEvent
+
+	tests := []struct {
+		name    string
+		handler server.ToolHandlerFunc
+		args    map[string]interface{}
+		check   map[string]interface{} // Top-level values of an object result
+	}{
+		{"slowest blocks", getSlowestBlocksHandler, nil, nil},
+		{"thread statistics", getThreadStatisticsHandler, nil, nil},
+		{"hotspots", getHotspotsHandler, map[string]interface{}{"exclusive": true}, nil},
+		{"performance issues", analyzePerformanceIssuesHandler, nil, nil},
+		{"hot path", getHotPathForThreadHandler, map[string]interface{}{"thread": "Main"}, nil},
+		{"overview", getOverviewHandler, nil, map[string]interface{}{"coverage": 1.0}},
+		{"histogram", getDurationHistogramHandler, map[string]interface{}{"name": "Frame"}, nil},
+		{"per-second rate", getPerSecondRateHandler, map[string]interface{}{"name": "Tick"}, map[string]interface{}{"average_per_second": 25.0}},
+		{"block summary", getBlockByNameSummaryHandler, map[string]interface{}{"name": "Update"}, map[string]interface{}{"typical_path_percent": 1.0}},
+		{"leaf hotspots", getLeafHotspotsHandler, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, p, nil)
+			outputFormat.Raw = true
+
+			text, isError := callTool(t, tt.handler, tt.args)
+			if isError {
+				t.Fatalf("tool error: %s", text)
+			}
+			if strings.Contains(text, "\n") {
+				t.Errorf("raw output is indented:\n%s", text)
+			}
+
+			var result interface{}
+			if err := json.Unmarshal([]byte(text), &result); err != nil {
+				t.Fatalf("result is not JSON: %v\n%s", err, text)
+			}
+			numbers := 0
+			checkRawValues(t, "result", result, &numbers)
+			if numbers == 0 {
+				t.Errorf("result has no numbers:\n%s", text)
+			}
+
+			for key, want := range tt.check {
+				if got := result.(map[string]interface{})[key]; got != want {
+					t.Errorf("%s = %#v, want %#v", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,9 @@ go 1.23.2
 
 toolchain go1.24.3
 
-require github.com/mark3labs/mcp-go v0.8.1
+require (
+	github.com/google/pprof v0.0.0-20250403155104-27863c87afa6
+	github.com/mark3labs/mcp-go v0.8.1
+)
 
 require github.com/google/uuid v1.6.0 // indirect
@@ -0,0 +1,111 @@
+package proftest
+
+import (
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// Mock is a hand-built capture implementing analyzer.Profile without a parsed file,
+// for checking that the analyzer and the tools only read a capture through it
+type Mock struct {
+	Threads     map[uint64]*parser.ThreadData
+	Descriptors map[uint32]*parser.BlockDescriptor
+	Header      parser.FileHeader
+	Memory      parser.MemoryEstimate
+
+	NoContextSwitches bool
+	Sampling          int // 0 = 1
+	RetainedSlowest   int
+
+	// Extracted counts the calls to Extract
+	Extracted int
+}
+
+// NewMock returns a mock holding the given descriptors and threads, with a header
+// spanning begin to end
+func NewMock(begin, end uint64, descriptors []*parser.BlockDescriptor, threads ...*parser.ThreadData) *Mock {
+	m := &Mock{
+		Threads:     make(map[uint64]*parser.ThreadData),
+		Descriptors: make(map[uint32]*parser.BlockDescriptor),
+		Header: parser.FileHeader{
+			Signature:        parser.EasyProfilerSignature,
+			Version:          parser.Version210,
+			PID:              1,
+			CPUFrequency:     1_000_000_000,
+			BeginTime:        begin,
+			EndTime:          end,
+			DescriptorsCount: uint32(len(descriptors)),
+			ThreadsCount:     uint32(len(threads)),
+		},
+	}
+	for _, descriptor := range descriptors {
+		m.Descriptors[descriptor.ID] = descriptor
+	}
+	for _, thread := range threads {
+		m.Threads[thread.ThreadID] = thread
+	}
+	return m
+}
+
+// Tree returns a block and its children, setting the children's depth below it
+func Tree(id uint32, begin, end uint64, children ...*parser.Block) *parser.Block {
+	block := &parser.Block{ID: id, Begin: begin, End: end, Children: children}
+	setDepth(block, 0)
+	return block
+}
+
+func setDepth(block *parser.Block, depth uint16) {
+	block.Depth = depth
+	for _, child := range block.Children {
+		setDepth(child, depth+1)
+	}
+}
+
+func (m *Mock) ThreadIDs() []uint64 {
+	ids := make([]uint64, 0, len(m.Threads))
+	for id := range m.Threads {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (m *Mock) Thread(id uint64) *parser.ThreadData { return m.Threads[id] }
+
+func (m *Mock) DescriptorIDs() []uint32 {
+	ids := make([]uint32, 0, len(m.Descriptors))
+	for id := range m.Descriptors {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (m *Mock) Descriptor(id uint32) *parser.BlockDescriptor { return m.Descriptors[id] }
+
+func (m *Mock) CaptureBegin() uint64 { return m.Header.BeginTime }
+
+func (m *Mock) GetTotalDuration() time.Duration {
+	return time.Duration(m.Header.EndTime - m.Header.BeginTime)
+}
+
+func (m *Mock) ContextSwitchesLoaded() bool { return !m.NoContextSwitches }
+
+func (m *Mock) BlockSampling() int { return max(m.Sampling, 1) }
+
+func (m *Mock) RetainedSlowestCount() int { return m.RetainedSlowest }
+
+func (m *Mock) FileHeader() parser.FileHeader { return m.Header }
+
+func (m *Mock) EstimateMemory() parser.MemoryEstimate { return m.Memory }
+
+// Extract copies the mock into a parser.ProfileData and extracts from that
+func (m *Mock) Extract(thread *parser.ThreadData, blocks []*parser.Block) *parser.ProfileData {
+	m.Extracted++
+	source := parser.NewProfileData()
+	source.Header = m.Header
+	source.Descriptors = m.Descriptors
+	source.Threads = m.Threads
+	source.SamplingFactor = m.Sampling
+	source.ContextSwitchesSkipped = m.NoContextSwitches
+	return source.Extract(thread, blocks)
+}
@@ -0,0 +1,289 @@
+// Package proftest builds small synthetic EasyProfiler captures for tests.
+package proftest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// Profile describes a v2.1 capture. Zero fields get the defaults noted on them.
+type Profile struct {
+	Version      uint32 // 0 = parser.Version210; the v2.1 layout is written either way
+	PID          uint64 // 0 = 1
+	CPUFrequency int64  // 0 = 1 GHz
+	Begin, End   uint64 // Both 0 = the earliest begin and latest end of the blocks
+	Padding      uint16
+
+	Descriptors []Descriptor
+	Threads     []Thread
+	Bookmarks   []Bookmark
+
+	// DeclaredBlocks, if > 0, replaces the block count written to the header
+	DeclaredBlocks uint32
+
+	// NoEndSignature omits the signature after the last thread, and the bookmarks
+	NoEndSignature bool
+}
+
+// Descriptor is a block descriptor record
+type Descriptor struct {
+	ID     uint32
+	Name   string
+	File   string
+	Line   int32
+	Color  uint32
+	Type   parser.BlockType
+	Status uint8
+}
+
+// Thread holds one thread's context switches and blocks, written in slice order
+type Thread struct {
+	ID              uint64
+	Name            string
+	ContextSwitches []ContextSwitch
+	Blocks          []Block
+}
+
+// ContextSwitch is a context switch record
+type ContextSwitch struct {
+	ThreadID   uint64
+	Begin, End uint64
+	Name       string
+}
+
+// Block is a block record. Payload, if set, is written instead of the
+// NUL-terminated Name, as value blocks store their data there.
+type Block struct {
+	ID         uint32
+	Begin, End uint64
+	Name       string
+	Payload    []byte
+}
+
+// Bookmark is a bookmark record
+type Bookmark struct {
+	Position uint64
+	Color    uint32
+	Text     string
+}
+
+// Bytes serializes the capture
+func (p *Profile) Bytes() []byte {
+	buf := &bytes.Buffer{}
+	write := func(v interface{}) { binary.Write(buf, binary.LittleEndian, v) }
+	writeString := func(s string) {
+		buf.WriteString(s)
+		buf.WriteByte(0)
+	}
+
+	version := p.Version
+	if version == 0 {
+		version = parser.Version210
+	}
+	pid := p.PID
+	if pid == 0 {
+		pid = 1
+	}
+	frequency := p.CPUFrequency
+	if frequency == 0 {
+		frequency = 1_000_000_000
+	}
+	begin, end := p.Begin, p.End
+	if begin == 0 && end == 0 {
+		begin, end = p.span()
+	}
+	blocks := p.DeclaredBlocks
+	if blocks == 0 {
+		for _, thread := range p.Threads {
+			blocks += uint32(len(thread.Blocks))
+		}
+	}
+
+	write(uint32(parser.EasyProfilerSignature))
+	write(version)
+	write(pid)
+	write(frequency)
+	write(begin)
+	write(end)
+	write(uint64(1 << 20)) // Memory size
+	write(uint64(1 << 10)) // Descriptors memory size
+	write(blocks)
+	write(uint32(len(p.Descriptors)))
+	write(uint32(len(p.Threads)))
+	write(uint16(len(p.Bookmarks)))
+	write(p.Padding)
+
+	for _, d := range p.Descriptors {
+		write(uint16(4 + 4 + 4 + 1 + 1 + 2 + len(d.Name) + 1 + len(d.File) + 1))
+		write(d.ID)
+		write(d.Line)
+		write(d.Color)
+		write(uint8(d.Type))
+		write(d.Status)
+		write(uint16(len(d.Name) + 1))
+		writeString(d.Name)
+		writeString(d.File)
+	}
+
+	for _, thread := range p.Threads {
+		write(thread.ID)
+		write(uint16(len(thread.Name)))
+		buf.WriteString(thread.Name)
+
+		write(uint32(len(thread.ContextSwitches)))
+		for _, cs := range thread.ContextSwitches {
+			write(uint16(8 + 8 + 8 + len(cs.Name) + 1))
+			write(cs.ThreadID)
+			write(cs.Begin)
+			write(cs.End)
+			writeString(cs.Name)
+		}
+
+		write(uint32(len(thread.Blocks)))
+		for _, block := range thread.Blocks {
+			if block.Payload != nil {
+				write(uint16(8 + 8 + 4 + len(block.Payload)))
+			} else {
+				write(uint16(8 + 8 + 4 + len(block.Name) + 1))
+			}
+			write(block.Begin)
+			write(block.End)
+			write(block.ID)
+			if block.Payload != nil {
+				buf.Write(block.Payload)
+			} else {
+				writeString(block.Name)
+			}
+		}
+	}
+
+	if p.NoEndSignature {
+		return buf.Bytes()
+	}
+	write(uint32(parser.EasyProfilerSignature))
+
+	if len(p.Bookmarks) > 0 {
+		for _, bookmark := range p.Bookmarks {
+			write(uint16(8 + 4 + len(bookmark.Text) + 1))
+			write(bookmark.Position)
+			write(bookmark.Color)
+			writeString(bookmark.Text)
+		}
+		write(uint32(parser.EasyProfilerSignature))
+	}
+	return buf.Bytes()
+}
+
+// span returns the earliest begin and latest end over all blocks
+func (p *Profile) span() (uint64, uint64) {
+	var begin, end uint64
+	first := true
+	for _, thread := range p.Threads {
+		for _, block := range thread.Blocks {
+			if block.Payload != nil {
+				continue // End holds the value ID
+			}
+			if first || block.Begin < begin {
+				begin = block.Begin
+			}
+			if first || block.End > end {
+				end = block.End
+			}
+			first = false
+		}
+	}
+	return begin, end
+}
+
+// WriteFile writes the capture to a file in a fresh test directory and returns its path
+func (p *Profile) WriteFile(t testing.TB) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "capture.prof")
+	if err := os.WriteFile(path, p.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write capture: %v", err)
+	}
+	return path
+}
+
+// Parse writes the capture and parses it with options, failing the test on error
+func (p *Profile) Parse(t testing.TB, options parser.ReadOptions) *parser.ProfileData {
+	t.Helper()
+	data, err := ParseFile(p.WriteFile(t), options)
+	if err != nil {
+		t.Fatalf("failed to parse capture: %v", err)
+	}
+	return data
+}
+
+// Load parses the capture with the default options
+func (p *Profile) Load(t testing.TB) *parser.ProfileData {
+	t.Helper()
+	return p.Parse(t, parser.DefaultReadOptions())
+}
+
+// ParseFile parses the capture at path with options
+func ParseFile(path string, options parser.ReadOptions) (*parser.ProfileData, error) {
+	reader, err := parser.NewReaderWithOptions(path, options)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return reader.Parse()
+}
+
+// Nested returns depth blocks with the given descriptor, each one nested in the
+// previous one, starting at begin and one nanosecond shorter on each side
+func Nested(id uint32, begin uint64, depth int) []Block {
+	blocks := make([]Block, depth)
+	for i := range blocks {
+		blocks[i] = Block{ID: id, Begin: begin + uint64(i), End: begin + uint64(2*depth-i)}
+	}
+	return blocks
+}
+
+// Value returns the payload of a value block holding the little-endian data
+func Value(valueType parser.ValueType, isArray bool, data []byte) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, uint16(len(data)))
+	buf.WriteByte(byte(valueType))
+	if isArray {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// Int32Value returns the payload of a scalar int32 value block
+func Int32Value(v int32) []byte {
+	return Value(parser.ValueTypeInt32, false, binary.LittleEndian.AppendUint32(nil, uint32(v)))
+}
+
+// DoubleValue returns the payload of a scalar double value block
+func DoubleValue(v float64) []byte {
+	return Value(parser.ValueTypeDouble, false, binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)))
+}
+
+// Descriptors returns block descriptors with IDs 1, 2, ... for the given names,
+// each in "<name>.cpp" at line 10 times its ID
+func Descriptors(names ...string) []Descriptor {
+	descriptors := make([]Descriptor, len(names))
+	for i, name := range names {
+		id := uint32(i + 1)
+		descriptors[i] = Descriptor{
+			ID:   id,
+			Name: name,
+			File: name + ".cpp",
+			Line: int32(10 * id),
+			Type: parser.BlockTypeBlock,
+		}
+	}
+	return descriptors
+}
@@ -2,9 +2,15 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -13,7 +19,7 @@ import (
 )
 
 var (
-	currentProfile  *parser.ProfileData
+	currentProfile  analyzer.Profile
 	currentAnalyzer *analyzer.Analyzer
 )
 
@@ -27,247 +33,3500 @@ func main() {
 
 	// Register tools
 	registerTools(s)
+	registerProfileTools(s)
+	registerStatsTools(s)
+	if debugEnabled() {
+		registerDebugTools(s)
+	}
+
+	// Start server using stdio
+	if err := server.ServeStdio(s); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func registerTools(s *server.MCPServer) {
+	// Tool 1: Load profile
+	loadProfileTool := mcp.NewTool("load_profile",
+		mcp.WithDescription(fmt.Sprintf("Load an EasyProfiler .prof file for analysis. For large files (>100MB), use fast_mode=true. The profile gets a new profile_id and becomes the current one; earlier loads are kept for comparison, up to %d profiles, beyond which the oldest are unloaded (see unload_profile)", maxLoadedProfiles)),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the .prof file to load"),
+		),
+		mcp.WithBoolean("fast_mode",
+			mcp.Description("Use fast mode for large files - reads every 10th block, drops blocks nested 5 or more levels deep and skips context switches and bookmarks (default: false)"),
+		),
+		mcp.WithNumber("min_block_duration_us",
+			mcp.Description("Drop blocks shorter than this many microseconds while parsing to reduce memory; values and events inside kept blocks stay (default: 0, keep all)"),
+		),
+		mcp.WithNumber("retain_slowest",
+			mcp.Description("For files too large to hold in memory: stream through the blocks keeping only this many slowest ones, without call trees. Only get_slowest_blocks (inclusive time) is meaningful on the result (default: 0, keep all)"),
+		),
+		mcp.WithBoolean("use_cache",
+			mcp.Description("Reuse a cached parse of this file if it hasn't changed, and cache the result otherwise (default: false)"),
+		),
+		mcp.WithString("thread_filter",
+			mcp.Description("Only load threads whose name matches this regular expression or substring, e.g. \"Render\" (default: all threads)"),
+		),
+		mcp.WithNumber("max_threads",
+			mcp.Description("Load at most this many threads (default: 0, all)"),
+		),
+		mcp.WithString("anonymous_blocks",
+			mcp.Description("How blocks without any name are aggregated: 'descriptor' (separately per descriptor ID, default), 'parent' (fold into the nearest named ancestor) or 'exclude'"),
+		),
+		mcp.WithString("idle_blocks",
+			mcp.Description("Comma-separated names of blocks in which a thread waits rather than works, e.g. \"WaitForWork\"; '*' matches any characters. Time inside them doesn't count as busy in parallelism and coverage (default: none)"),
+		),
+		mcp.WithBoolean("strict_header",
+			mcp.Description("Reject the file when reserved header fields hold non-zero values or its version is newer than any known, both signs of a header layout the parser may misread. By default such files load with header_warnings (default: false)"),
+		),
+	)
+
+	s.AddTool(loadProfileTool, timed(loadProfileHandler)) // Locks the registry itself, after parsing
+
+	// Tool 2: Get slowest blocks
+	slowestBlocksTool := mcp.NewTool("get_slowest_blocks",
+		mcp.WithDescription("Get the slowest profiling blocks"),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of blocks to return (default: 10)"),
+		),
+		mcp.WithBoolean("raw_timestamps",
+			mcp.Description("Include unconverted begin_ticks/end_ticks and cpu_frequency for each block (default: false)"),
+		),
+		mcp.WithBoolean("distinct",
+			mcp.Description("Return only the slowest instance of each call site, with its instance count (default: false)"),
+		),
+		mcp.WithBoolean("exclusive",
+			mcp.Description("Measure exclusive (self) time, excluding nested child blocks, instead of inclusive time (default: false)"),
+		),
+		mcp.WithBoolean("include_path",
+			mcp.Description("Attach each block's caller (parent) and its full ancestor chain, outermost first, so a slow generic function like memcpy shows where it was called from (default: false)"),
+		),
+	)
+
+	s.AddTool(slowestBlocksTool, readLocked(getSlowestBlocksHandler))
+
+	// Tool 3: Get thread statistics
+	threadStatsTool := mcp.NewTool("get_thread_statistics",
+		mcp.WithDescription("Get statistics for all threads in the profile"),
+		mcp.WithString("sort_by",
+			mcp.Description("Order of threads: duration (busiest first), name, block_count or context_switches (default: duration)"),
+		),
+		mcp.WithBoolean("wall_span",
+			mcp.Description("Also report each thread's wall-clock span from its first block's begin to its last block's end, the share of it spent in blocks, and, with context switches loaded, the time switched out within it. A wide gap shows a thread that mostly waits (default: false)"),
+		),
+	)
+
+	s.AddTool(threadStatsTool, readLocked(getThreadStatisticsHandler))
+
+	// Tool 4: Get hotspots
+	hotspotsTool := mcp.NewTool("get_hotspots",
+		mcp.WithDescription("Get functions with the highest cumulative execution time"),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of hotspots to return (default: 10)"),
+		),
+		mcp.WithBoolean("scale_sampled",
+			mcp.Description("For profiles loaded with block sampling (fast_mode), multiply cumulative times and call counts by the sampling factor to estimate true totals (default: false)"),
+		),
+		mcp.WithBoolean("exclusive",
+			mcp.Description("Measure exclusive (self) time, excluding nested child blocks, instead of inclusive time (default: false)"),
+		),
+		mcp.WithString("exclude",
+			mcp.Description("Comma-separated function names to hide, e.g. a known main loop; '*' matches any characters (e.g. \"Engine::*\"). Excluded functions are removed before ranking; percent_of_total stays relative to the whole capture"),
+		),
+		mcp.WithString("granularity",
+			mcp.Description("What to aggregate by: call_site (name and file:line, default), name (merge a function's call sites), descriptor (merge runtime names of one descriptor) or file (one entry per source file)"),
+		),
+		mcp.WithString("percent_base",
+			mcp.Description("What percentages are relative to: global (the capture duration, default) or thread (the busy time of the threads each function ran on, reported as percent_of_thread)"),
+		),
+		timeBudgetOption(),
+	)
+
+	s.AddTool(hotspotsTool, readLocked(getHotspotsHandler))
+
+	// Tool 5: Analyze performance issues
+	analyzeIssuesTool := mcp.NewTool("analyze_performance_issues", append([]mcp.ToolOption{
+		mcp.WithDescription("Perform comprehensive performance analysis and detect common issues"),
+	}, issueToolOptions()...)...)
+
+	s.AddTool(analyzeIssuesTool, readLocked(analyzePerformanceIssuesHandler))
+
+	// Tool 6: Clear parse cache
+	clearCacheTool := mcp.NewTool("clear_cache",
+		mcp.WithDescription("Delete all cached parsed profiles created by load_profile with use_cache=true"),
+	)
+
+	s.AddTool(clearCacheTool, readLocked(clearCacheHandler))
+
+	// Tool 7: Get startup cost
+	startupCostTool := mcp.NewTool("get_startup_cost",
+		mcp.WithDescription("Measure time spent before the first occurrence of a steady-state marker block and break down the startup hotspots"),
+		mcp.WithString("marker",
+			mcp.Description("Name of the block that marks steady state, e.g. \"MainLoop\". If omitted, the first top-level block is treated as startup"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of startup contributors to return (default: 10)"),
+		),
+	)
+
+	s.AddTool(startupCostTool, readLocked(getStartupCostHandler))
+
+	// Tool 8: Get hot path for thread
+	hotPathTool := mcp.NewTool("get_hot_path_for_thread",
+		mcp.WithDescription("Get the dominant nested chain of blocks (critical path) on a single thread"),
+		mcp.WithString("thread",
+			mcp.Required(),
+			mcp.Description("Thread ID or thread name"),
+		),
+		mcp.WithBoolean("raw_timestamps",
+			mcp.Description("Include unconverted begin_ticks/end_ticks and cpu_frequency for each block (default: false)"),
+		),
+		timeBudgetOption(),
+	)
+
+	s.AddTool(hotPathTool, readLocked(getHotPathForThreadHandler))
+
+	// Tool 9: Get overview
+	overviewTool := mcp.NewTool("get_overview",
+		mcp.WithDescription("Get a compact big-picture snapshot: top self-time hotspots, slowest blocks, busiest thread, parallelism, instrumentation coverage and high-severity issue count"),
+	)
+
+	s.AddTool(overviewTool, readLocked(getOverviewHandler))
+
+	// Tool 10: Get value summary
+	valueSummaryTool := mcp.NewTool("get_value_summary",
+		mcp.WithDescription("Summarize numeric values recorded with EASY_VALUE by name: sample count, sum, avg, min, max and last"),
+	)
+
+	s.AddTool(valueSummaryTool, readLocked(getValueSummaryHandler))
+
+	// Tool 11: Get thread dependency graph
+	dependencyGraphTool := mcp.NewTool("get_thread_dependency_graph",
+		mcp.WithDescription("Build a directed graph of which threads yield to which, from context switches, weighted by switch count and time. Not available for profiles loaded in fast mode"),
+	)
+
+	s.AddTool(dependencyGraphTool, readLocked(getThreadDependencyGraphHandler))
+
+	// Tool 12: Export pprof
+	exportPprofTool := mcp.NewTool("export_pprof",
+		mcp.WithDescription("Export the call tree as a pprof profile (profile.proto) viewable with `go tool pprof`"),
+		mcp.WithString("output_path",
+			mcp.Required(),
+			mcp.Description("Path of the .pb.gz file to write"),
+		),
+	)
+
+	s.AddTool(exportPprofTool, readLocked(exportPprofHandler))
+
+	// Tool 13: Get duration histogram
+	histogramTool := mcp.NewTool("get_duration_histogram",
+		mcp.WithDescription("Get a histogram of a function's call durations to reveal bimodal behavior and slow tails"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Function (block) name"),
+		),
+		mcp.WithString("buckets",
+			mcp.Description("Comma-separated bucket boundaries as Go durations (default: \"1ms,10ms,100ms\")"),
+		),
+		mcp.WithBoolean("exclusive",
+			mcp.Description("Measure exclusive (self) time, excluding nested child blocks, instead of inclusive time (default: false)"),
+		),
+	)
+
+	s.AddTool(histogramTool, readLocked(getDurationHistogramHandler))
+
+	// Tool 14: Get memory estimate
+	memoryEstimateTool := mcp.NewTool("get_memory_estimate",
+		mcp.WithDescription("Estimate the server memory retained by the loaded profile(s), alongside the capture's own memory size from the header"),
+	)
+
+	s.AddTool(memoryEstimateTool, readLocked(getMemoryEstimateHandler))
+
+	// Tool 15: Get per-second event rate
+	eventRateTool := mcp.NewTool("get_per_second_rate",
+		mcp.WithDescription("Get how often an event marker occurred over the capture, as occurrences per second in time buckets"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Event name"),
+		),
+		mcp.WithString("bucket",
+			mcp.Description("Bucket size as a Go duration (default: \"1s\")"),
+		),
+	)
+
+	s.AddTool(eventRateTool, readLocked(getPerSecondRateHandler))
+
+	// Tool 16: Peek at a profile header
+	peekProfileTool := mcp.NewTool("peek_profile",
+		mcp.WithDescription("Read only the header of a .prof file (version, pid, duration, counts, memory) without loading it"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the .prof file to inspect"),
+		),
+	)
+
+	s.AddTool(peekProfileTool, readLocked(peekProfileHandler))
+
+	// Tool 17: Get the block stack at a timestamp
+	parentAtTimestampTool := mcp.NewTool("get_parent_at_timestamp",
+		mcp.WithDescription("Get the stack of blocks (innermost block and all its ancestors) active on a thread at a moment in the capture"),
+		mcp.WithString("thread",
+			mcp.Required(),
+			mcp.Description("Thread ID or thread name"),
+		),
+		mcp.WithString("offset",
+			mcp.Required(),
+			mcp.Description("Time since the capture began as a Go duration (e.g. \"3.2s\", \"150ms\")"),
+		),
+		mcp.WithBoolean("raw_timestamps",
+			mcp.Description("Include unconverted begin_ticks/end_ticks and cpu_frequency for each block (default: false)"),
+		),
+	)
+
+	s.AddTool(parentAtTimestampTool, readLocked(getParentAtTimestampHandler))
+
+	// Tool 18: Get color legend
+	colorLegendTool := mcp.NewTool("get_color_legend",
+		mcp.WithDescription("Map each descriptor color (#RRGGBB) to the functions and files using it, to reconstruct what the colors mean"),
+	)
+
+	s.AddTool(colorLegendTool, readLocked(getColorLegendHandler))
+
+	// Tool 19: Explain a performance issue
+	explainIssueTool := mcp.NewTool("explain_issue", append([]mcp.ToolOption{
+		mcp.WithDescription("Drill into one issue from analyze_performance_issues: callers, callees, worst invocations, affected thread and a suggested investigation. Pass the same detection parameters as the listing so the index refers to the same issue"),
+		mcp.WithNumber("index",
+			mcp.Description("The issue's index from analyze_performance_issues"),
+		),
+		mcp.WithString("type",
+			mcp.Description("Issue type (e.g. \"Hot Function\"), used when index is not given"),
+		),
+		mcp.WithString("location",
+			mcp.Description("Issue location, narrowing a type match (optional)"),
+		),
+	}, issueDetectionOptions()...)...)
+
+	s.AddTool(explainIssueTool, readLocked(explainIssueHandler))
+
+	// Tool 20: Get Gantt data
+	ganttTool := mcp.NewTool("get_block_gantt_data",
+		mcp.WithDescription("Get raw block intervals per thread for building custom timeline visualizations. Times are nanoseconds since the capture began"),
+		mcp.WithString("thread",
+			mcp.Description("Thread ID or thread name (default: all threads)"),
+		),
+		mcp.WithString("window_start",
+			mcp.Description("Only blocks overlapping a window starting at this offset, as a Go duration (default: capture begin)"),
+		),
+		mcp.WithString("window_end",
+			mcp.Description("Only blocks overlapping a window ending at this offset, as a Go duration (default: capture end)"),
+		),
+		mcp.WithNumber("max_depth",
+			mcp.Description("Deepest nesting level to include, 0 being top-level blocks (default: unlimited)"),
+		),
+		mcp.WithNumber("page",
+			mcp.Description("Page number, starting at 1 (default: 1)"),
+		),
+		mcp.WithNumber("page_size",
+			mcp.Description("Intervals per page (default: 1000)"),
+		),
+	)
+
+	s.AddTool(ganttTool, readLocked(getBlockGanttDataHandler))
+
+	// Tool 21: Set output format
+	outputFormatTool := mcp.NewTool("set_output_format",
+		mcp.WithDescription("Choose how durations are rendered by all tools: a fixed unit with fixed precision, or Go's automatic format. Mode raw switches every tool to machine-friendly output for scripts"),
+		mcp.WithString("mode",
+			mcp.Description("human (formatted strings, default) or raw (compact JSON, durations as integer nanoseconds, percentages as fractions 0-1, unrounded numbers)"),
+		),
+		mcp.WithNumber("max_name_length",
+			mcp.Description("Truncate function names longer than this many characters with an ellipsis, to keep responses from template-heavy captures small (default: 0, no limit)"),
+		),
+		mcp.WithBoolean("full_names",
+			mcp.Description("Add a full_name field next to every truncated name (default: false)"),
+		),
+		mcp.WithString("duration_unit",
+			mcp.Description("One of auto, ns, us, ms, s (default: auto)"),
+		),
+		mcp.WithNumber("precision",
+			mcp.Description("Digits after the decimal point for fixed units (default: 3)"),
+		),
+	)
+
+	s.AddTool(outputFormatTool, writeLocked(setOutputFormatHandler))
+
+	// Tool 22: Get most called functions
+	mostCalledTool := mcp.NewTool("get_most_called",
+		mcp.WithDescription("Get functions ranked by invocation count regardless of duration, to spot chatty interfaces or missing caching"),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of functions to return (default: 10)"),
+		),
+	)
+
+	s.AddTool(mostCalledTool, readLocked(getMostCalledHandler))
+
+	// Tool 23: Get block path
+	blockPathTool := mcp.NewTool("get_block_path",
+		mcp.WithDescription("Get everything about one block in a single lookup: name, source location, color, type, status, invocation count and total time"),
+		mcp.WithString("block",
+			mcp.Required(),
+			mcp.Description("Function name or descriptor ID"),
+		),
+	)
+
+	s.AddTool(blockPathTool, readLocked(getBlockPathHandler))
+
+	// Tool 24: Get thread affinity report
+	threadAffinityTool := mcp.NewTool("get_thread_affinity_report",
+		mcp.WithDescription("Get per-thread CPU core migrations inferred from context switches; frequent migration hurts cache locality"),
+	)
+
+	s.AddTool(threadAffinityTool, readLocked(getThreadAffinityReportHandler))
+
+	// Tool 25: Get capture metadata
+	captureMetadataTool := mcp.NewTool("get_capture_metadata",
+		mcp.WithDescription("Get all capture metadata recorded in the profile header: format version, PID, CPU frequency, capture begin/end timestamps, memory size and record counts"),
+	)
+
+	s.AddTool(captureMetadataTool, readLocked(getCaptureMetadataHandler))
+
+	// Tool 26: Export speedscope
+	exportSpeedscopeTool := mcp.NewTool("export_speedscope",
+		mcp.WithDescription("Export every thread's blocks in time order (not aggregated) as a speedscope JSON file for its \"time order\" view, preserving the exact sequence and timing of calls"),
+		mcp.WithString("output_path",
+			mcp.Required(),
+			mcp.Description("Path of the .speedscope.json file to write"),
+		),
+	)
+
+	s.AddTool(exportSpeedscopeTool, readLocked(exportSpeedscopeHandler))
+
+	// Tool 27: Get cross-thread latency
+	crossThreadLatencyTool := mcp.NewTool("get_cross_thread_latency",
+		mcp.WithDescription("Heuristic, opt-in per block name: link same-named blocks handed off between threads (e.g. producer then consumer) into end-to-end operations and report their latency including the handoff gaps"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Block name shared by every stage of the operation"),
+		),
+		mcp.WithString("max_gap",
+			mcp.Description("Longest handoff gap between stages as a Go duration (default: \"10ms\")"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of slowest operations to list (default: 10)"),
+		),
+		mcp.WithBoolean("raw_timestamps",
+			mcp.Description("Include raw begin/end ticks and the CPU frequency for each stage (default: false)"),
+		),
+	)
+
+	s.AddTool(crossThreadLatencyTool, readLocked(getCrossThreadLatencyHandler))
+
+	// Tool 28: Get block heatmap
+	blockHeatmapTool := mcp.NewTool("get_block_heatmap",
+		mcp.WithDescription("Get a function × time bucket matrix of busy time for the busiest functions, revealing which functions dominate which phases of the run"),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of functions (rows) (default: 10)"),
+		),
+		mcp.WithNumber("buckets",
+			mcp.Description("Number of equal time buckets (columns) the capture is split into (default: 20, at most 10000)"),
+		),
+	)
+
+	s.AddTool(blockHeatmapTool, readLocked(getBlockHeatmapHandler))
+
+	// Tool 29: Get GC pauses
+	gcPausesTool := mcp.NewTool("get_gc_pauses",
+		mcp.WithDescription("Get garbage collection pauses of managed-runtime captures: GC-named blocks merged into discrete pauses with start, duration and threads, plus total GC time, the longest pause and pause frequency over time"),
+		mcp.WithString("pattern",
+			mcp.Description("Regular expression matching GC block names (default: GC, garbage, collect, stop-the-world)"),
+		),
+		mcp.WithString("bucket",
+			mcp.Description("Bucket size for pause frequency as a Go duration (default: \"1s\")"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of pauses to list, in time order (default: 50)"),
+		),
+	)
+
+	s.AddTool(gcPausesTool, readLocked(getGCPausesHandler))
+
+	// Tool 30: Get block by name summary
+	blockSummaryTool := mcp.NewTool("get_block_by_name_summary",
+		mcp.WithDescription("Everything about one function in a single call: call count, total, self, average, p95 and max time, plus the ancestor chain most of its calls come from"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Function (block) name"),
+		),
+	)
+
+	s.AddTool(blockSummaryTool, readLocked(getBlockByNameSummaryHandler))
+
+	// Tool 31: Get rolling hotspots
+	rollingHotspotsTool := mcp.NewTool("get_rolling_hotspots",
+		mcp.WithDescription("Slide a fixed window across the capture and report the top hotspot of each window, plus a timeline of when the bottleneck shifted from one function to another"),
+		mcp.WithString("window",
+			mcp.Description("Window size as a Go duration (default: \"1s\")"),
+		),
+		mcp.WithString("step",
+			mcp.Description("Distance between window starts as a Go duration (default: the window size, i.e. no overlap)"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of functions to list per window (default: 1)"),
+		),
+		mcp.WithBoolean("exclusive",
+			mcp.Description("Measure exclusive (self) time, excluding nested child blocks, instead of inclusive time (default: false)"),
+		),
+	)
+
+	s.AddTool(rollingHotspotsTool, readLocked(getRollingHotspotsHandler))
+
+	// Tool 32: Get leaf hotspots
+	leafHotspotsTool := mcp.NewTool("get_leaf_hotspots",
+		mcp.WithDescription("Get the leaf functions (calls with no nested blocks) ranked by self time: the innermost operations actually consuming time, without composite functions that only call others"),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of functions to return (default: 20)"),
+		),
+	)
+
+	s.AddTool(leafHotspotsTool, readLocked(getLeafHotspotsHandler))
+
+	// Tool 33: Export issues as Markdown
+	exportIssuesMarkdownTool := mcp.NewTool("export_issues_markdown", append([]mcp.ToolOption{
+		mcp.WithDescription("Render the analyze_performance_issues report as Markdown (summary, then a table of issues per severity) for pasting into a GitHub issue or pull request"),
+		mcp.WithString("title",
+			mcp.Description("Report title (default: the profile file name)"),
+		),
+		mcp.WithString("output_path",
+			mcp.Description("Also write the report to this file"),
+		),
+	}, issueToolOptions()...)...)
+
+	s.AddTool(exportIssuesMarkdownTool, readLocked(exportIssuesMarkdownHandler))
+
+	// Tool 34: Estimate instrumentation overhead
+	instrumentationOverheadTool := mcp.NewTool("get_instrumentation_overhead",
+		mcp.WithDescription("Heuristically estimate how much of the recorded time is the profiler's own cost, from block density and the gaps between back-to-back blocks, and warn when too many tiny blocks likely distort the capture"),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of densest tiny functions to list as candidates for removing instrumentation (default: 5)"),
+		),
+	)
+
+	s.AddTool(instrumentationOverheadTool, readLocked(getInstrumentationOverheadHandler))
+
+	// Tool 35: Compare two threads
+	threadComparisonTool := mcp.NewTool("get_thread_comparison",
+		mcp.WithDescription("Compare two threads of the loaded profile function by function: totals, functions shared with their time on each thread, and functions unique to either. Divergent functions are listed first; useful when supposedly identical workers behave differently"),
+		mcp.WithString("thread_a",
+			mcp.Required(),
+			mcp.Description("First thread ID or thread name"),
+		),
+		mcp.WithString("thread_b",
+			mcp.Required(),
+			mcp.Description("Second thread ID or thread name"),
+		),
+		mcp.WithNumber("divergence_percent",
+			mcp.Description("Report a shared function as divergent when its times differ by at least this percentage of the larger one (default: 25)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of functions per list (default: 20)"),
+		),
+		mcp.WithBoolean("exclusive",
+			mcp.Description("Measure exclusive (self) time, excluding nested child blocks, instead of inclusive time (default: false)"),
+		),
+	)
+
+	s.AddTool(threadComparisonTool, readLocked(getThreadComparisonHandler))
+
+	// Tool 36: Get function table
+	functionTableTool := mcp.NewTool("get_function_table",
+		mcp.WithDescription("List every used descriptor joined with its usage in one table: name, file:line, type, color, call count, total inclusive and self time, and percent of total. The profiler's classic function list view"),
+		mcp.WithString("sort_by",
+			mcp.Description("Column to sort by: total_time (default), self_time, call_count, name, file or descriptor_id"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of rows (default: all)"),
+		),
+	)
+
+	s.AddTool(functionTableTool, readLocked(getFunctionTableHandler))
+
+	// Tool 37: Get block duration over time
+	durationOverTimeTool := mcp.NewTool("get_block_duration_over_time",
+		mcp.WithDescription("Get a function's per-call durations against their begin offsets, in time order, with a fitted trend. Shows whether the function gets progressively slower over the capture (e.g. a leak) or spikes periodically, which aggregate statistics flatten"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Function (block) name"),
+		),
+		mcp.WithNumber("max_points",
+			mcp.Description("Merge consecutive calls into this many points (mean and max duration each) when there are more calls; 0 returns every call (default: 200)"),
+		),
+		mcp.WithBoolean("exclusive",
+			mcp.Description("Measure exclusive (self) time, excluding nested child blocks, instead of inclusive time (default: false)"),
+		),
+	)
+
+	s.AddTool(durationOverTimeTool, readLocked(getBlockDurationOverTimeHandler))
+
+	// Tool 38: Get overlapping blocks
+	overlappingBlocksTool := mcp.NewTool("get_overlapping_blocks",
+		mcp.WithDescription("Find blocks on the same thread whose intervals overlap without nesting. Correct stack-based instrumentation can't produce them, so each pair points at a begin/end mismatch in the instrumented code; they also distort the reconstructed call tree"),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of overlapping pairs to list, longest overlap first (default: 20)"),
+		),
+		timeBudgetOption(),
+	)
+
+	s.AddTool(overlappingBlocksTool, readLocked(getOverlappingBlocksHandler))
+
+	// Tool 39: Get phases
+	phasesTool := mcp.NewTool("get_phases",
+		mcp.WithDescription("Segment a thread's timeline into phases separated by significant idle gaps and name each phase by its dominant function, giving an automatic phase breakdown of the run (e.g. loading, then a render loop)"),
+		mcp.WithString("thread",
+			mcp.Required(),
+			mcp.Description("Thread ID or name"),
+		),
+		mcp.WithString("min_gap",
+			mcp.Description("Shortest idle stretch between top-level blocks that separates two phases, as a Go duration (default: \"10ms\")"),
+		),
+	)
+
+	s.AddTool(phasesTool, readLocked(getPhasesHandler))
+
+	// Tool 40: Get block count by depth
+	blockCountByDepthTool := mcp.NewTool("get_block_count_by_depth",
+		mcp.WithDescription("Histogram of how many blocks sit at each nesting depth, per thread and overall, showing whether the capture is mostly shallow or deeply nested. Also reports how many levels hold 95% and 99% of the blocks, to help choose a depth limit"),
+	)
+
+	s.AddTool(blockCountByDepthTool, readLocked(getBlockCountByDepthHandler))
+
+	// Tool 41: Get worst invocation
+	worstInvocationTool := mcp.NewTool("get_worst_invocation",
+		mcp.WithDescription("Find the single block instance with the largest self time anywhere in the capture, with its full ancestor chain and timestamp: the worst single moment of the run. Unlike hotspots nothing is aggregated, and unlike slowest blocks time spent in children doesn't count"),
+		mcp.WithBoolean("raw_timestamps",
+			mcp.Description("Include raw begin/end ticks and the CPU frequency for each block on the path (default: false)"),
+		),
+	)
+
+	s.AddTool(worstInvocationTool, readLocked(getWorstInvocationHandler))
+
+	// Tool 42: List source files
+	listSourceFilesTool := mcp.NewTool("list_source_files",
+		mcp.WithDescription("List the distinct source files referenced by block descriptors, with how many functions each declares and the time spent in their own code, giving a quick map of the modules the instrumentation spans"),
+	)
+
+	s.AddTool(listSourceFilesTool, readLocked(listSourceFilesHandler))
+
+	// Tool 43: Export Prometheus metrics
+	exportPrometheusTool := mcp.NewTool("export_prometheus", append([]mcp.ToolOption{
+		mcp.WithDescription("Export key aggregates (capture duration, top functions by self time, per-thread busy time and block count, issue counts by severity) in the Prometheus text exposition format, for scraping profiling results of CI runs into dashboards"),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of top functions by self time to export (default: 20)"),
+		),
+		mcp.WithString("output_path",
+			mcp.Description("Also write the metrics to this file, e.g. for the node exporter's textfile collector"),
+		),
+	}, issueToolOptions()...)...)
+
+	s.AddTool(exportPrometheusTool, readLocked(exportPrometheusHandler))
+
+	// Tool 44: Get frame statistics
+	frameStatisticsTool := mcp.NewTool("get_frame_statistics",
+		mcp.WithDescription("Get frame time statistics (average, median, p95, p99, worst frame, frames per second) where each call of the frame block is one frame. Without frame_block, the most repeated top-level block of the busiest thread with a regular cadence is chosen and reported with a confidence"),
+		mcp.WithString("frame_block",
+			mcp.Description("Name of the block that wraps each frame, e.g. \"Frame\" (default: detected automatically)"),
+		),
+	)
+
+	s.AddTool(frameStatisticsTool, readLocked(getFrameStatisticsHandler))
+
+	// Tool 45: Detect lock convoys
+	lockConvoysTool := mcp.NewTool("detect_lock_convoys",
+		mcp.WithDescription("Detect lock convoys: a lock block passed from thread to thread back to back, one thread's block ending and another's beginning almost immediately, again and again, showing threads queued on the lock. Reports each lock with the threads involved and the total serialized time"),
+		mcp.WithString("pattern",
+			mcp.Description("Regular expression matching the names of blocks that cover holding a lock (default: lock, mutex, critical section, semaphore)"),
+		),
+		mcp.WithString("max_gap",
+			mcp.Description("Longest gap between one thread releasing the lock and the next acquiring it, as a Go duration (default: \"50us\")"),
+		),
+		mcp.WithNumber("min_handoffs",
+			mcp.Description("Fewest consecutive handoffs between threads that make a convoy (default: 3)"),
+		),
+		mcp.WithBoolean("raw_timestamps",
+			mcp.Description("Include raw begin/end ticks and the CPU frequency for each convoy (default: false)"),
+		),
+	)
+
+	s.AddTool(lockConvoysTool, readLocked(detectLockConvoysHandler))
+
+	// Tool 46: Get duration distribution
+	durationDistributionTool := mcp.NewTool("get_duration_distribution",
+		mcp.WithDescription("Bucket every block of the capture, whatever its function, by order of magnitude of its duration (<1µs, 1-10µs, ... >=1s) with the count and total time per bucket. Shows whether time goes to a few huge blocks (chase a hotspot) or a swarm of small ones (reduce call overhead)"),
+		mcp.WithBoolean("exclusive",
+			mcp.Description("Measure exclusive (self) time, excluding nested child blocks, instead of inclusive time, so bucket totals add up to the instrumented time without counting nested time twice (default: false)"),
+		),
+	)
+
+	s.AddTool(durationDistributionTool, readLocked(getDurationDistributionHandler))
+
+	// Tool 47: Get block by key
+	blockByKeyTool := mcp.NewTool("get_block_by_key",
+		mcp.WithDescription("Look up one block instance by its block_key (thread/descriptor/ordinal, as returned by get_slowest_blocks and get_worst_invocation) with its ancestor chain. Keys are the same whether the file was loaded fully or with fast_mode sampling, so a block found in one load can be found again in another"),
+		mcp.WithString("block_key",
+			mcp.Required(),
+			mcp.Description("Key of the block, e.g. \"1234/5/67\""),
+		),
+		mcp.WithBoolean("raw_timestamps",
+			mcp.Description("Include raw begin/end timestamps (default: false)"),
+		),
+	)
+
+	s.AddTool(blockByKeyTool, readLocked(getBlockByKeyHandler))
+
+	// Tool 48: Get narrative summary
+	narrativeSummaryTool := mcp.NewTool("get_narrative_summary",
+		mcp.WithDescription("Best first call after loading a profile: one small payload with the facts to describe the capture in a few sentences - total duration, the dominant thread and its top self-time function, the single worst block, the number of high-severity issues with the top one's headline, and overall parallelism. Follow up with the dedicated tools for details"),
+	)
+
+	s.AddTool(narrativeSummaryTool, readLocked(getNarrativeSummaryHandler))
+}
+
+func loadProfileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filePath, ok := request.Params.Arguments["file_path"].(string)
+	if !ok {
+		return mcp.NewToolResultError("file_path parameter is required"), nil
+	}
+
+	// Check if fast mode is requested
+	fastMode := false
+	if fast, ok := request.Params.Arguments["fast_mode"].(bool); ok {
+		fastMode = fast
+	}
+
+	// Choose read options
+	options := parser.DefaultReadOptions()
+	if fastMode {
+		options = parser.FastReadOptions()
+	}
+	if minUs, ok := request.Params.Arguments["min_block_duration_us"].(float64); ok && minUs > 0 {
+		options.MinBlockDuration = time.Duration(minUs * float64(time.Microsecond))
+	}
+	if filter, ok := request.Params.Arguments["thread_filter"].(string); ok {
+		options.ThreadNameFilter = filter
+	}
+	if maxThreads, ok := request.Params.Arguments["max_threads"].(float64); ok && maxThreads > 0 {
+		options.MaxThreads = int(maxThreads)
+	}
+	if retain, ok := request.Params.Arguments["retain_slowest"].(float64); ok && retain > 0 {
+		options.RetainSlowest = int(retain)
+	}
+	if strict, ok := request.Params.Arguments["strict_header"].(bool); ok {
+		options.StrictReservedFields = strict
+	}
+	options.PhaseCallback = phaseReporter(ctx, request)
+
+	useCache := false
+	if c, ok := request.Params.Arguments["use_cache"].(bool); ok {
+		useCache = c
+	}
+
+	anonymousPolicy := analyzer.AnonymousByDescriptor
+	if policy, ok := request.Params.Arguments["anonymous_blocks"].(string); ok && policy != "" {
+		var err error
+		if anonymousPolicy, err = analyzer.ParseAnonymousPolicy(policy); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	var idlePatterns []string
+	if idle, ok := request.Params.Arguments["idle_blocks"].(string); ok {
+		idlePatterns = parseNameList(idle)
+	}
+
+	var cache *parser.Cache
+	var profile *parser.ProfileData
+	cacheHit := false
+	if useCache {
+		var err error
+		cache, err = openProfileCache()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to open cache: %v", err)), nil
+		}
+		profile, cacheHit, err = cache.Load(filePath, options)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to read cache: %v", err)), nil
+		}
+	}
+
+	if !cacheHit {
+		// Parse the profile
+		reader, err := parser.NewReaderWithOptions(filePath, options)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to open file: %v", err)), nil
+		}
+		defer reader.Close()
+
+		parseStart := time.Now()
+		profile, err = reader.Parse()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to parse profile: %v", err)), nil
+		}
+		recordParse(filePath, time.Since(parseStart))
+
+		if cache != nil {
+			// Caching is best-effort; a failed write shouldn't fail the load
+			if err := cache.Store(filePath, options, profile); err != nil {
+				log.Printf("failed to cache profile %s: %v", filePath, err)
+			}
+		}
+	}
+
+	// Store in the registry and make it current. Parsing above runs unlocked so
+	// other tools keep working on the previous profile meanwhile.
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	loaded := registerProfile(filePath, profile)
+	evicted := evictOldProfiles()
+	loaded.Analyzer.SetAnonymousPolicy(anonymousPolicy)
+	loaded.Analyzer.SetIdlePatterns(idlePatterns)
+
+	// Prepare summary
+	summary := map[string]interface{}{
+		"status":            "success",
+		"profile_id":        loaded.ID,
+		"file":              filePath,
+		"fast_mode":         fastMode,
+		"cache_hit":         cacheHit,
+		"anonymous_blocks":  anonymousPolicy.String(),
+		"version":           profile.Header.VersionString(),
+		"pid":               profile.Header.PID,
+		"total_duration":    formatDuration(profile.GetTotalDuration()),
+		"threads_count":     profile.GetThreadCount(),
+		"blocks_count":      profile.GetBlocksCount(),
+		"descriptors_count": len(profile.Descriptors),
+		"bookmarks_count":   len(profile.Bookmarks),
+		"memory_mb":         formatNumber(float64(profile.Header.MemorySize)/1024/1024, 2),
+	}
+
+	if profile.DroppedBlocksCount > 0 {
+		summary["dropped_blocks"] = profile.DroppedBlocksCount
+	}
+
+	if profile.DepthLimitedBlocksCount > 0 {
+		summary["depth_limited_blocks"] = profile.DepthLimitedBlocksCount
+	}
+
+	if integrity := profile.CheckIntegrity(); integrity != nil && !integrity.Complete() {
+		summary["integrity"] = formatIntegrity(integrity)
+	}
+
+	if nesting := loaded.Analyzer.CheckNesting(nestingAnomaliesShown); nesting.Count > 0 {
+		summary["nesting_anomalies"] = formatNestingReport(loaded.Analyzer, nesting)
+	}
+
+	if profile.UnclosedBlocksCount > 0 {
+		summary["unclosed_blocks"] = profile.UnclosedBlocksCount
+	}
+
+	if profile.SkippedThreadsCount > 0 {
+		summary["skipped_threads"] = profile.SkippedThreadsCount
+	}
+
+	if profile.SamplingFactor > 1 {
+		summary["sampling_factor"] = profile.SamplingFactor
+	}
+
+	if profile.RetainedSlowest > 0 {
+		summary["retained_slowest"] = profile.RetainedSlowest
+		summary["retained_slowest_note"] = "Only the slowest blocks were kept, without call trees: use get_slowest_blocks; other analyses see an incomplete profile"
+	}
+
+	if health := loaded.Analyzer.CheckProfilerHealth(); health.MostlyOff {
+		summary["profiler_health_warning"] = "The profiler appears to have been mostly off during this capture; results may be near-empty: " + strings.Join(health.Evidence, "; ")
+		summary["profiler_health"] = map[string]interface{}{
+			"descriptors":          health.Descriptors,
+			"disabled_descriptors": health.DisabledDescriptors,
+			"used_descriptors":     health.UsedDescriptors,
+			"blocks":               health.Blocks,
+			"blocks_per_second":    formatNumber(health.BlocksPerSecond, 1),
+			"coverage_percent":     formatPercent(health.Coverage),
+		}
+	}
+
+	if profile.MissingEndSignature {
+		summary["missing_end_signature"] = true
+		summary["end_signature_note"] = "The file ends without its end signature, likely because the profiler was stopped before flushing it; all declared threads were read, bookmarks are unavailable"
+	}
+
+	if len(profile.HeaderWarnings) > 0 {
+		summary["header_warnings"] = profile.HeaderWarnings
+	}
+
+	if loaded.PreviousID != "" {
+		summary["previous_profile_id"] = loaded.PreviousID
+	}
+
+	if len(evicted) > 0 {
+		summary["evicted_profiles"] = evicted
+	}
+
+	if profile.SanitizedNamesCount > 0 {
+		summary["sanitized_names"] = profile.SanitizedNamesCount
+		summary["warning"] = fmt.Sprintf("%d names contained invalid UTF-8; invalid bytes were replaced with %q",
+			profile.SanitizedNamesCount, parser.InvalidUTF8Marker)
+	}
+
+	return jsonResult(summary)
+}
+
+func getSlowestBlocksHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	limit := 10
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	rawTimestamps, _ := request.Params.Arguments["raw_timestamps"].(bool)
+
+	distinct, _ := request.Params.Arguments["distinct"].(bool)
+	mode := timeModeArg(request)
+	includePath, _ := request.Params.Arguments["include_path"].(bool)
+
+	// Retained blocks have no children, so their self time is unknown
+	retained := currentProfile.RetainedSlowestCount() > 0
+	if retained && mode == analyzer.Exclusive {
+		return mcp.NewToolResultError("Exclusive time needs the call trees, which aren't kept for profiles loaded with retain_slowest. Reload without it."), nil
+	}
+	if retained && includePath {
+		return mcp.NewToolResultError("include_path needs the call trees, which aren't kept for profiles loaded with retain_slowest. Reload without it."), nil
+	}
+
+	var blocks []*analyzer.BlockInfo
+	if distinct {
+		blocks = currentAnalyzer.GetDistinctSlowestBlocksWithMode(limit, mode)
+	} else {
+		blocks = currentAnalyzer.GetSlowestBlocksWithMode(limit, mode)
+	}
+
+	// Format results
+	results := make([]map[string]interface{}, len(blocks))
+	for i, block := range blocks {
+		results[i] = map[string]interface{}{
+			"rank":        i + 1,
+			"name":        block.Name,
+			"file":        block.File,
+			"line":        block.Line,
+			"duration":    formatDuration(mode.Of(block)),
+			"duration_ns": mode.Of(block).Nanoseconds(),
+			"time_mode":   mode.String(),
+			"depth":       block.Depth,
+			"thread_id":   block.ThreadID,
+			"thread_name": block.ThreadName,
+			"block_key":   block.Key(),
+		}
+		if retained {
+			delete(results[i], "depth") // Unknown without the call trees
+		}
+		if distinct {
+			results[i]["instances"] = block.CallCount
+		}
+		if onCPU, ok := currentAnalyzer.OnCPUTime(block.ThreadID, block.Begin, block.End); ok {
+			results[i]["on_cpu_duration"] = formatDuration(onCPU)
+			if block.Duration > 0 {
+				results[i]["on_cpu_percent"] = formatPercent(float64(onCPU) / float64(block.Duration) * 100)
+			}
+		}
+		if rawTimestamps {
+			addRawTimestamps(results[i], block.Begin, block.End)
+		}
+		if includePath {
+			ancestors := currentAnalyzer.GetBlockAncestors(block)
+			path := make([]map[string]interface{}, len(ancestors))
+			for j, node := range ancestors {
+				path[j] = map[string]interface{}{
+					"depth": node.Depth,
+					"name":  node.Name,
+					"file":  node.File,
+					"line":  node.Line,
+				}
+			}
+			results[i]["path"] = path
+			if len(ancestors) > 0 {
+				results[i]["parent"] = ancestors[len(ancestors)-1].Name
+			}
+		}
+	}
+
+	return jsonResult(results)
+}
+
+func getThreadStatisticsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	stats := currentAnalyzer.GetThreadStatistics()
+	if sortBy, ok := request.Params.Arguments["sort_by"].(string); ok && sortBy != "" {
+		key, err := analyzer.ParseThreadSortKey(sortBy)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		analyzer.SortThreadStats(stats, key)
+	}
+
+	wallSpan, _ := request.Params.Arguments["wall_span"].(bool)
+
+	// Format results
+	results := make([]map[string]interface{}, len(stats))
+	for i, stat := range stats {
+		results[i] = map[string]interface{}{
+			"thread_id":          stat.ThreadID,
+			"thread_name":        stat.ThreadName,
+			"total_duration":     formatDuration(stat.TotalDuration),
+			"block_count":        stat.BlockCount,
+			"context_switches":   stat.ContextSwitches,
+			"avg_block_duration": formatDuration(stat.AvgBlockDuration),
+			"percent_of_total":   formatPercent(stat.PercentOfTotal),
+			"start_offset":       formatDuration(stat.StartOffset),
+			"end_offset":         formatDuration(stat.EndOffset),
+		}
+		if wallSpan {
+			results[i]["wall_span"] = formatDuration(stat.WallSpan)
+			results[i]["idle_in_span"] = formatDuration(max(stat.WallSpan-stat.TotalDuration, 0))
+			if stat.WallSpan > 0 {
+				results[i]["busy_percent_of_span"] = formatPercent(float64(stat.TotalDuration) / float64(stat.WallSpan) * 100)
+			}
+			if currentProfile.ContextSwitchesLoaded() {
+				results[i]["switched_out_in_span"] = formatDuration(stat.SwitchedOut)
+			}
+		}
+	}
+
+	return jsonResult(results)
+}
+
+func getHotspotsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	limit := 10
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	mode := timeModeArg(request)
+
+	var exclude []string
+	if e, ok := request.Params.Arguments["exclude"].(string); ok {
+		exclude = parseNameList(e)
+	}
+
+	granularity := analyzer.ByCallSite
+	if g, ok := request.Params.Arguments["granularity"].(string); ok && g != "" {
+		parsed, err := analyzer.ParseGranularity(g)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		granularity = parsed
+	}
+
+	percentBase, errResult := percentBaseArg(request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	budgetCtx, cancel, errResult := timeBudgetArg(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer cancel()
+
+	hotspots, timedOut := currentAnalyzer.GetHotspotsByContext(budgetCtx, limit, mode, granularity, exclude)
+
+	scaleSampled, _ := request.Params.Arguments["scale_sampled"].(bool)
+	estimated := scaleSampled && currentAnalyzer.SamplingFactor() > 1
+	if estimated {
+		hotspots = currentAnalyzer.ScaleForSampling(hotspots)
+	}
+
+	// Format results
+	results := make([]map[string]interface{}, len(hotspots))
+	for i, hotspot := range hotspots {
+		duration := mode.Of(hotspot)
+
+		avgDuration := time.Duration(0)
+		if hotspot.CallCount > 0 {
+			avgDuration = duration / time.Duration(hotspot.CallCount)
+		}
+
+		results[i] = map[string]interface{}{
+			"rank":           i + 1,
+			"name":           hotspot.Name,
+			"file":           hotspot.File,
+			"line":           hotspot.Line,
+			"total_duration": formatDuration(duration),
+			"time_mode":      mode.String(),
+			"call_count":     hotspot.CallCount,
+			"avg_duration":   formatDuration(avgDuration),
+		}
+		percent := currentAnalyzer.Percent(duration, hotspot, percentBase)
+		if percentBase == analyzer.PercentOfThread {
+			results[i]["percent_of_thread"] = formatPercent(percent)
+			results[i]["thread_time"] = formatDuration(hotspot.ThreadTime)
+		} else {
+			results[i]["percent_of_total"] = formatPercent(percent)
+		}
+		if estimated {
+			results[i]["estimated"] = true
+			results[i]["sampling_factor"] = currentAnalyzer.SamplingFactor()
+		}
+	}
+
+	// A budget can cut the aggregation short, which the bare list has no room to say
+	if budget, _ := request.Params.Arguments["time_budget"].(string); budget != "" {
+		return jsonResult(map[string]interface{}{
+			"timed_out": timedOut,
+			"hotspots":  results,
+		})
+	}
+
+	return jsonResult(results)
+}
+
+func analyzePerformanceIssuesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	issues, shown, errResult := detectIssues(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	// Group by severity
+	grouped := map[string][]map[string]interface{}{
+		"high":   make([]map[string]interface{}, 0),
+		"medium": make([]map[string]interface{}, 0),
+		"low":    make([]map[string]interface{}, 0),
+	}
+
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		counts[issue.Severity]++
+	}
+
+	for i, issue := range shown {
+		issueData := map[string]interface{}{
+			"index":       i + 1,
+			"type":        issue.Type,
+			"score":       formatNumber(issue.Score, 1),
+			"description": issue.Description,
+			"location":    issue.Location,
+		}
+
+		if issue.Duration > 0 {
+			issueData["duration"] = formatDuration(issue.Duration)
+		}
+		if issue.ThreadName != "" {
+			issueData["thread_name"] = issue.ThreadName
+		}
+		if issue.Suggestion != "" {
+			issueData["suggestion"] = issue.Suggestion
+		}
+
+		grouped[issue.Severity] = append(grouped[issue.Severity], issueData)
+	}
+
+	result := map[string]interface{}{
+		"total_issues": len(issues),
+		"by_severity":  grouped,
+		"summary": fmt.Sprintf("Found %d performance issues (%d high, %d medium, %d low)",
+			len(issues),
+			counts["high"],
+			counts["medium"],
+			counts["low"]),
+	}
+
+	if len(shown) < len(issues) {
+		result["shown_issues"] = len(shown)
+		result["omitted_issues"] = len(issues) - len(shown)
+	}
+
+	return jsonResult(result)
+}
+
+// issueToolOptions declares the detection and filtering parameters read by detectIssues
+func issueToolOptions() []mcp.ToolOption {
+	return append(issueDetectionOptions(),
+		mcp.WithString("min_severity",
+			mcp.Description("Only list issues at least this severe: high, medium or low (default: low, i.e. all)"),
+		),
+		mcp.WithNumber("max_issues",
+			mcp.Description("List at most this many issues, highest score first (default: 0, no cap)"),
+		),
+	)
+}
+
+// issueDetectionOptions declares the detection parameters read by issueOptions. Tools
+// referring to issues by number take them too, so the numbers match the listing.
+func issueDetectionOptions() []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithNumber("high_cutoff",
+			mcp.Description("Minimum score (0-100) for an issue to be rated high (default: 60)"),
+		),
+		mcp.WithNumber("medium_cutoff",
+			mcp.Description("Minimum score (0-100) for an issue to be rated medium (default: 40)"),
+		),
+		mcp.WithNumber("fragmented_min_calls",
+			mcp.Description("Minimum call count for a function to be reported as Fragmented Work (default: 10000, 0 disables)"),
+		),
+		mcp.WithNumber("fragmented_max_avg_us",
+			mcp.Description("Maximum average call duration in microseconds for Fragmented Work (default: 10)"),
+		),
+		mcp.WithString("io_patterns",
+			mcp.Description("Comma-separated, case-insensitive name fragments of I/O blocks for Blocking I/O (default: read,write,fopen,recv,send,query,fetch,http,sql)"),
+		),
+		mcp.WithNumber("blocking_io_min_ms",
+			mcp.Description("Minimum block duration in milliseconds checked for Blocking I/O (default: 10)"),
+		),
+		mcp.WithNumber("max_recursion_depth",
+			mcp.Description("Report Unbalanced Recursion for functions recursing deeper than this (default: 16, 0 disables)"),
+		),
+		mcp.WithNumber("recursion_depth_ratio",
+			mcp.Description("Also report functions whose deepest recursion is at least this many times their median depth (default: 4)"),
+		),
+		mcp.WithString("percent_base",
+			mcp.Description("What Hot Function percentages are relative to: global (the capture duration, default) or thread (the busy time of the threads the function ran on). Scores stay relative to the capture"),
+		),
+	}
+}
+
+// detectIssues runs issue detection on the current profile with the request's
+// detection parameters, returning every issue and the ones that pass its
+// min_severity and max_issues filters
+func detectIssues(ctx context.Context, request mcp.CallToolRequest) ([]*analyzer.PerformanceIssue, []*analyzer.PerformanceIssue, *mcp.CallToolResult) {
+	options, errResult := issueOptions(ctx, request)
+	if errResult != nil {
+		return nil, nil, errResult
+	}
+
+	minSeverity, _ := request.Params.Arguments["min_severity"].(string)
+	if minSeverity != "" && !analyzer.ValidSeverity(minSeverity) {
+		return nil, nil, mcp.NewToolResultError(fmt.Sprintf("Invalid min_severity '%s': use high, medium or low", minSeverity))
+	}
+
+	maxIssues := 0
+	if m, ok := request.Params.Arguments["max_issues"].(float64); ok {
+		maxIssues = int(m)
+	}
+
+	issues := currentAnalyzer.AnalyzePerformanceIssuesWithOptions(options)
+	return issues, analyzer.FilterIssues(issues, minSeverity, maxIssues), nil
+}
+
+// issueOptions reads the request's issue detection parameters
+func issueOptions(ctx context.Context, request mcp.CallToolRequest) (analyzer.IssueOptions, *mcp.CallToolResult) {
+	options := analyzer.DefaultIssueOptions()
+	if high, ok := request.Params.Arguments["high_cutoff"].(float64); ok {
+		options.Cutoffs.High = high
+	}
+	if medium, ok := request.Params.Arguments["medium_cutoff"].(float64); ok {
+		options.Cutoffs.Medium = medium
+	}
+	if calls, ok := request.Params.Arguments["fragmented_min_calls"].(float64); ok {
+		options.FragmentedMinCalls = int(calls)
+	}
+	if avgUs, ok := request.Params.Arguments["fragmented_max_avg_us"].(float64); ok {
+		options.FragmentedMaxAvg = time.Duration(avgUs * float64(time.Microsecond))
+	}
+	if patterns, ok := request.Params.Arguments["io_patterns"].(string); ok && patterns != "" {
+		options.IOPatterns = parseNameList(patterns)
+	}
+	if minMs, ok := request.Params.Arguments["blocking_io_min_ms"].(float64); ok {
+		options.BlockingIOMinDuration = time.Duration(minMs * float64(time.Millisecond))
+	}
+	if depth, ok := request.Params.Arguments["max_recursion_depth"].(float64); ok {
+		options.MaxRecursionDepth = int(depth)
+	}
+	if ratio, ok := request.Params.Arguments["recursion_depth_ratio"].(float64); ok {
+		options.RecursionDepthRatio = ratio
+	}
+	percentBase, errResult := percentBaseArg(request)
+	if errResult != nil {
+		return options, errResult
+	}
+	options.PercentBase = percentBase
+	options.Progress = progressReporter(ctx, request)
+	return options, nil
+}
+
+func getStartupCostHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	marker, _ := request.Params.Arguments["marker"].(string)
+
+	limit := 10
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	cost, err := currentAnalyzer.GetStartupCost(marker, limit)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to compute startup cost: %v", err)), nil
+	}
+
+	totalDuration := currentProfile.GetTotalDuration()
+	startupPercent := 0.0
+	if totalDuration > 0 {
+		startupPercent = float64(cost.Duration) / float64(totalDuration) * 100
+	}
+
+	// Format results
+	contributors := make([]map[string]interface{}, len(cost.Contributors))
+	for i, contributor := range cost.Contributors {
+		percent := 0.0
+		if cost.Duration > 0 {
+			percent = float64(contributor.Duration) / float64(cost.Duration) * 100
+		}
+
+		contributors[i] = map[string]interface{}{
+			"rank":               i + 1,
+			"name":               contributor.Name,
+			"file":               contributor.File,
+			"line":               contributor.Line,
+			"total_duration":     formatDuration(contributor.Duration),
+			"call_count":         contributor.CallCount,
+			"percent_of_startup": formatPercent(percent),
+		}
+	}
+
+	result := map[string]interface{}{
+		"marker":              cost.Marker,
+		"marker_found":        cost.MarkerFound,
+		"startup_duration":    formatDuration(cost.Duration),
+		"startup_duration_ns": cost.Duration.Nanoseconds(),
+		"percent_of_total":    formatPercent(startupPercent),
+		"top_contributors":    contributors,
+	}
+
+	return jsonResult(result)
+}
+
+func getHotPathForThreadHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	threadRef, ok := request.Params.Arguments["thread"].(string)
+	if !ok {
+		return mcp.NewToolResultError("thread parameter is required"), nil
+	}
+
+	thread, err := currentAnalyzer.FindThread(threadRef)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	rawTimestamps, _ := request.Params.Arguments["raw_timestamps"].(bool)
+
+	budgetCtx, cancel, errResult := timeBudgetArg(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer cancel()
+
+	path := currentAnalyzer.GetThreadCriticalPathContext(budgetCtx, thread)
+
+	// Format results
+	nodes := make([]map[string]interface{}, len(path.Nodes))
+	for i, node := range path.Nodes {
+		nodes[i] = map[string]interface{}{
+			"depth":             node.Depth,
+			"name":              node.Name,
+			"file":              node.File,
+			"line":              node.Line,
+			"duration":          formatDuration(node.Duration),
+			"duration_ns":       node.Duration.Nanoseconds(),
+			"percent_of_parent": formatPercent(node.PercentOfParent),
+		}
+		if rawTimestamps {
+			addRawTimestamps(nodes[i], node.Begin, node.End)
+		}
+	}
+
+	result := map[string]interface{}{
+		"thread_id":         path.ThreadID,
+		"thread_name":       path.ThreadName,
+		"thread_duration":   formatDuration(path.ThreadDuration),
+		"percent_of_thread": formatPercent(path.PercentOfThread),
+		"path":              nodes,
+	}
+	if path.TimedOut {
+		result["timed_out"] = true
+	}
+
+	return jsonResult(result)
+}
+
+func getOverviewHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	overview := currentAnalyzer.GetOverviewWithProgress(progressReporter(ctx, request))
+
+	// Format results
+	hotspots := make([]map[string]interface{}, len(overview.SelfTimeHotspots))
+	for i, hotspot := range overview.SelfTimeHotspots {
+		hotspots[i] = map[string]interface{}{
+			"name":          hotspot.Name,
+			"self_duration": formatDuration(hotspot.SelfDuration),
+			"call_count":    hotspot.CallCount,
+		}
+	}
+
+	slowest := make([]map[string]interface{}, len(overview.SlowestBlocks))
+	for i, block := range overview.SlowestBlocks {
+		slowest[i] = map[string]interface{}{
+			"name":        block.Name,
+			"duration":    formatDuration(block.Duration),
+			"thread_name": block.ThreadName,
+		}
+	}
+
+	var busiest map[string]interface{}
+	if overview.BusiestThread != nil {
+		busiest = map[string]interface{}{
+			"thread_id":        overview.BusiestThread.ThreadID,
+			"thread_name":      overview.BusiestThread.ThreadName,
+			"total_duration":   formatDuration(overview.BusiestThread.TotalDuration),
+			"percent_of_total": formatPercent(overview.BusiestThread.PercentOfTotal),
+		}
+	}
+
+	result := map[string]interface{}{
+		"total_duration":       formatDuration(currentProfile.GetTotalDuration()),
+		"top_self_time":        hotspots,
+		"slowest_blocks":       slowest,
+		"busiest_thread":       busiest,
+		"parallelism":          formatNumber(overview.Utilization.Parallelism, 2),
+		"coverage":             formatPercent(overview.Utilization.Coverage),
+		"high_severity_issues": overview.HighSeverityIssues,
+		"total_issues":         overview.TotalIssues,
+	}
+	if patterns := currentAnalyzer.IdlePatterns(); len(patterns) > 0 {
+		result["idle_blocks"] = patterns
+		result["idle_time"] = formatDuration(overview.Utilization.IdleTime)
+	}
+
+	return jsonResult(result)
+}
+
+func getValueSummaryHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	summaries := currentAnalyzer.GetValueSummary()
+
+	// Format results, keeping integer values as integers
+	results := make([]map[string]interface{}, len(summaries))
+	for i, summary := range summaries {
+		result := map[string]interface{}{
+			"name":         summary.Name,
+			"type":         summary.Type,
+			"sample_count": summary.SampleCount,
+			"avg":          summary.Avg,
+		}
+
+		if summary.IsFloat {
+			result["sum"] = summary.FloatSum
+			result["min"] = summary.FloatMin
+			result["max"] = summary.FloatMax
+			result["last"] = summary.FloatLast
+		} else {
+			result["sum"] = summary.IntSum
+			result["min"] = summary.IntMin
+			result["max"] = summary.IntMax
+			result["last"] = summary.IntLast
+		}
+
+		results[i] = result
+	}
+
+	return jsonResult(results)
+}
+
+func getThreadDependencyGraphHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	if !currentProfile.ContextSwitchesLoaded() {
+		return mcp.NewToolResultError("Context switches were not loaded. Reload the profile without fast_mode."), nil
+	}
+
+	graph := currentAnalyzer.GetThreadDependencyGraph()
+
+	// Format results
+	nodes := make([]map[string]interface{}, len(graph.Nodes))
+	for i, node := range graph.Nodes {
+		nodes[i] = map[string]interface{}{
+			"thread_id":   node.ThreadID,
+			"thread_name": node.ThreadName,
+			"profiled":    node.Profiled,
+		}
+	}
+
+	edges := make([]map[string]interface{}, len(graph.Edges))
+	for i, edge := range graph.Edges {
+		edges[i] = map[string]interface{}{
+			"from":          edge.From,
+			"to":            edge.To,
+			"switch_count":  edge.SwitchCount,
+			"total_time":    formatDuration(edge.TotalTime),
+			"total_time_ns": edge.TotalTime.Nanoseconds(),
+		}
+	}
+
+	result := map[string]interface{}{
+		"nodes": nodes,
+		"edges": edges,
+	}
+
+	return jsonResult(result)
+}
+
+func exportPprofHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	outputPath, ok := request.Params.Arguments["output_path"].(string)
+	if !ok {
+		return mcp.NewToolResultError("output_path parameter is required"), nil
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create file: %v", err)), nil
+	}
+	defer file.Close()
+
+	prof, err := currentAnalyzer.ExportPprof(file)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to export pprof: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":          "success",
+		"output_path":     outputPath,
+		"samples_count":   len(prof.Sample),
+		"functions_count": len(prof.Function),
+	}
+
+	return jsonResult(result)
+}
+
+func exportSpeedscopeHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	outputPath, ok := request.Params.Arguments["output_path"].(string)
+	if !ok {
+		return mcp.NewToolResultError("output_path parameter is required"), nil
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create file: %v", err)), nil
+	}
+	defer file.Close()
+
+	name := currentProfileID
+	if loaded, err := lookupProfile(""); err == nil {
+		name = filepath.Base(loaded.FilePath)
+	}
+
+	exported, err := currentAnalyzer.ExportSpeedscope(file, name)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to export speedscope: %v", err)), nil
+	}
+
+	events := 0
+	for _, profile := range exported.Profiles {
+		events += len(profile.Events)
+	}
+
+	result := map[string]interface{}{
+		"status":        "success",
+		"output_path":   outputPath,
+		"threads_count": len(exported.Profiles),
+		"frames_count":  len(exported.Shared.Frames),
+		"events_count":  events,
+	}
+
+	return jsonResult(result)
+}
+
+func getDurationHistogramHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	name, ok := request.Params.Arguments["name"].(string)
+	if !ok {
+		return mcp.NewToolResultError("name parameter is required"), nil
+	}
+
+	boundaries := analyzer.DefaultHistogramBoundaries
+	if b, ok := request.Params.Arguments["buckets"].(string); ok && b != "" {
+		var err error
+		boundaries, err = parseDurationList(b)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid buckets: %v", err)), nil
+		}
+	}
+
+	mode := timeModeArg(request)
+
+	histogram, err := currentAnalyzer.GetDurationHistogram(name, boundaries, mode)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Format results
+	buckets := make([]map[string]interface{}, len(histogram.Buckets))
+	for i, bucket := range histogram.Buckets {
+		percent := 0.0
+		if histogram.CallCount > 0 {
+			percent = float64(bucket.Count) / float64(histogram.CallCount) * 100
+		}
+
+		buckets[i] = map[string]interface{}{
+			"range":            bucket.Label(),
+			"count":            bucket.Count,
+			"percent_of_calls": formatPercent(percent),
+			"total_duration":   formatDuration(bucket.TotalDuration),
+		}
+	}
+
+	result := map[string]interface{}{
+		"name":       histogram.Name,
+		"call_count": histogram.CallCount,
+		"time_mode":  mode.String(),
+		"buckets":    buckets,
+	}
+
+	return jsonResult(result)
+}
+
+func getMemoryEstimateHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	estimate := currentProfile.EstimateMemory()
+
+	totalLoaded := int64(0)
+	for _, loaded := range loadedProfiles {
+		totalLoaded += loaded.Profile.EstimateMemory().TotalBytes
+	}
+
+	result := map[string]interface{}{
+		"profile_id":           currentProfileID,
+		"header_memory_mb":     formatMB(int64(currentProfile.FileHeader().MemorySize)),
+		"estimated_heap_mb":    formatMB(estimate.TotalBytes),
+		"estimated_heap_bytes": estimate.TotalBytes,
+		"breakdown_mb": map[string]interface{}{
+			"blocks":           formatMB(estimate.BlocksBytes),
+			"context_switches": formatMB(estimate.ContextSwitchesBytes),
+			"descriptors":      formatMB(estimate.DescriptorsBytes),
+			"threads":          formatMB(estimate.ThreadsBytes),
+			"bookmarks":        formatMB(estimate.BookmarksBytes),
+		},
+		"loaded_profiles":           len(loadedProfiles),
+		"all_profiles_estimated_mb": formatMB(totalLoaded),
+	}
+
+	return jsonResult(result)
+}
+
+func getPerSecondRateHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	name, ok := request.Params.Arguments["name"].(string)
+	if !ok {
+		return mcp.NewToolResultError("name parameter is required"), nil
+	}
+
+	bucketSize := time.Second
+	if b, ok := request.Params.Arguments["bucket"].(string); ok && b != "" {
+		var err error
+		bucketSize, err = time.ParseDuration(b)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid bucket: %v", err)), nil
+		}
+	}
+
+	rate, err := currentAnalyzer.GetEventRate(name, bucketSize)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Format results
+	buckets := make([]map[string]interface{}, len(rate.Buckets))
+	for i, bucket := range rate.Buckets {
+		buckets[i] = map[string]interface{}{
+			"start":      formatDuration(bucket.Start),
+			"count":      bucket.Count,
+			"per_second": formatNumber(bucket.PerSecond, 2),
+		}
+	}
+
+	result := map[string]interface{}{
+		"name":               rate.Name,
+		"count":              rate.Count,
+		"bucket_size":        formatDuration(rate.BucketSize),
+		"average_per_second": formatNumber(rate.AveragePerSecond, 2),
+		"peak_per_second":    formatNumber(rate.PeakPerSecond, 2),
+		"buckets":            buckets,
+	}
+
+	return jsonResult(result)
+}
+
+func peekProfileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filePath, ok := request.Params.Arguments["file_path"].(string)
+	if !ok {
+		return mcp.NewToolResultError("file_path parameter is required"), nil
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to open file: %v", err)), nil
+	}
+
+	reader, err := parser.NewReader(filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to open file: %v", err)), nil
+	}
+	defer reader.Close()
+
+	header, err := reader.ParseHeaderOnly()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to parse profile: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"file":              filePath,
+		"file_size_mb":      formatMB(info.Size()),
+		"version":           header.VersionString(),
+		"pid":               header.PID,
+		"total_duration":    formatDuration(time.Duration(header.EndTime - header.BeginTime)),
+		"blocks_count":      header.BlocksCount,
+		"descriptors_count": header.DescriptorsCount,
+		"memory_mb":         formatMB(int64(header.MemorySize)),
+	}
+
+	// Thread and bookmark counts are only recorded in the header since v2.1.0
+	if header.Version >= parser.Version210 {
+		result["threads_count"] = header.ThreadsCount
+		result["bookmarks_count"] = header.BookmarksCount
+	}
+
+	return jsonResult(result)
+}
+
+func getParentAtTimestampHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	threadRef, ok := request.Params.Arguments["thread"].(string)
+	if !ok {
+		return mcp.NewToolResultError("thread parameter is required"), nil
+	}
+
+	offsetArg, ok := request.Params.Arguments["offset"].(string)
+	if !ok {
+		return mcp.NewToolResultError("offset parameter is required"), nil
+	}
+	offset, err := time.ParseDuration(offsetArg)
+	if err != nil || offset < 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid offset: %s", offsetArg)), nil
+	}
+
+	thread, err := currentAnalyzer.FindThread(threadRef)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	rawTimestamps, _ := request.Params.Arguments["raw_timestamps"].(bool)
+
+	stack := currentAnalyzer.GetStackAt(thread, offset)
+
+	result := map[string]interface{}{
+		"thread_id":   thread.ThreadID,
+		"thread_name": thread.ThreadName,
+		"offset":      formatDuration(offset),
+	}
+
+	if len(stack) == 0 {
+		result["state"] = "idle"
+		return jsonResult(result)
+	}
+
+	// Format results
+	nodes := make([]map[string]interface{}, len(stack))
+	for i, node := range stack {
+		nodes[i] = map[string]interface{}{
+			"depth":       node.Depth,
+			"name":        node.Name,
+			"file":        node.File,
+			"line":        node.Line,
+			"duration":    formatDuration(node.Duration),
+			"duration_ns": node.Duration.Nanoseconds(),
+		}
+		if rawTimestamps {
+			addRawTimestamps(nodes[i], node.Begin, node.End)
+		}
+	}
+
+	result["state"] = "active"
+	result["innermost"] = stack[len(stack)-1].Name
+	result["stack"] = nodes
+
+	return jsonResult(result)
+}
+
+func getColorLegendHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	legend := currentAnalyzer.GetColorLegend()
+
+	// Format results
+	results := make([]map[string]interface{}, len(legend))
+	for i, entry := range legend {
+		results[i] = map[string]interface{}{
+			"color":     entry.Hex(),
+			"functions": entry.Functions,
+			"files":     entry.Files,
+		}
+		if entry.Category != "" {
+			results[i]["category"] = entry.Category
+		}
+	}
+
+	return jsonResult(results)
+}
+
+func explainIssueHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	index := 0
+	if i, ok := request.Params.Arguments["index"].(float64); ok {
+		index = int(i)
+	}
+	issueType, _ := request.Params.Arguments["type"].(string)
+	location, _ := request.Params.Arguments["location"].(string)
+
+	if index <= 0 && issueType == "" {
+		return mcp.NewToolResultError("index or type parameter is required"), nil
+	}
+
+	options, errResult := issueOptions(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	issue, err := currentAnalyzer.FindIssueWithOptions(options, index, issueType, location)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	explanation := currentAnalyzer.ExplainIssue(issue)
+
+	result := map[string]interface{}{
+		"type":        issue.Type,
+		"severity":    issue.Severity,
+		"score":       formatNumber(issue.Score, 1),
+		"description": issue.Description,
+		"location":    issue.Location,
+		"suggestion":  explanation.Suggestion,
+	}
+
+	if summary := explanation.Summary; summary != nil {
+		result["function"] = map[string]interface{}{
+			"name":           summary.Name,
+			"file":           summary.File,
+			"line":           summary.Line,
+			"total_duration": formatDuration(summary.Duration),
+			"self_duration":  formatDuration(summary.SelfDuration),
+			"call_count":     summary.CallCount,
+			"avg_duration":   formatDuration(summary.AvgDuration),
+		}
+		result["callers"] = formatCallEdges(explanation.Callers)
+		result["callees"] = formatCallEdges(explanation.Callees)
+
+		invocations := make([]map[string]interface{}, len(explanation.WorstInvocations))
+		for i, invocation := range explanation.WorstInvocations {
+			invocations[i] = map[string]interface{}{
+				"duration":      formatDuration(invocation.Duration),
+				"self_duration": formatDuration(invocation.SelfDuration),
+				"depth":         invocation.Depth,
+				"thread_id":     invocation.ThreadID,
+				"thread_name":   invocation.ThreadName,
+				"offset":        formatDuration(currentAnalyzer.CaptureOffset(invocation.Begin)),
+			}
+		}
+		result["worst_invocations"] = invocations
+	}
+
+	if stats := explanation.Thread; stats != nil {
+		result["thread"] = map[string]interface{}{
+			"thread_id":        stats.ThreadID,
+			"thread_name":      stats.ThreadName,
+			"total_duration":   formatDuration(stats.TotalDuration),
+			"block_count":      stats.BlockCount,
+			"context_switches": stats.ContextSwitches,
+			"percent_of_total": formatPercent(stats.PercentOfTotal),
+		}
+	}
+
+	return jsonResult(result)
+}
+
+// formatCallEdges formats callers or callees for output
+func formatCallEdges(edges []*analyzer.CallEdge) []map[string]interface{} {
+	results := make([]map[string]interface{}, len(edges))
+	for i, edge := range edges {
+		results[i] = map[string]interface{}{
+			"name":       edge.Name,
+			"call_count": edge.CallCount,
+			"duration":   formatDuration(edge.Duration),
+		}
+	}
+	return results
+}
+
+func getBlockGanttDataHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	filter := analyzer.GanttFilter{MaxDepth: -1}
+
+	if threadRef, ok := request.Params.Arguments["thread"].(string); ok && threadRef != "" {
+		thread, err := currentAnalyzer.FindThread(threadRef)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		filter.Threads = []*parser.ThreadData{thread}
+	}
+
+	begin := currentProfile.CaptureBegin()
+	if w, ok := request.Params.Arguments["window_start"].(string); ok && w != "" {
+		offset, err := time.ParseDuration(w)
+		if err != nil || offset < 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid window_start: %s", w)), nil
+		}
+		filter.WindowBegin = begin + uint64(offset)
+	}
+	if w, ok := request.Params.Arguments["window_end"].(string); ok && w != "" {
+		offset, err := time.ParseDuration(w)
+		if err != nil || offset <= 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid window_end: %s", w)), nil
+		}
+		filter.WindowEnd = begin + uint64(offset)
+	}
+	if d, ok := request.Params.Arguments["max_depth"].(float64); ok && d >= 0 {
+		filter.MaxDepth = int(d)
+	}
+
+	// Clamped so the page offset can't overflow
+	page := 1
+	if p, ok := request.Params.Arguments["page"].(float64); ok && p >= 1 {
+		page = int(min(p, math.MaxInt32))
+	}
+	pageSize := 1000
+	if s, ok := request.Params.Arguments["page_size"].(float64); ok && s >= 1 {
+		pageSize = int(min(s, math.MaxInt32))
+	}
+
+	intervals := currentAnalyzer.GetGanttIntervals(filter)
+
+	total := len(intervals)
+	from := min((page-1)*pageSize, total)
+	to := min(from+pageSize, total)
+
+	// Group the page by thread, keeping entries compact
+	threads := make(map[string][]map[string]interface{})
+	for _, iv := range intervals[from:to] {
+		key := strconv.FormatUint(iv.ThreadID, 10)
+		threads[key] = append(threads[key], map[string]interface{}{
+			"name":          iv.Name,
+			"begin":         uint64(currentAnalyzer.CaptureOffset(iv.Begin)),
+			"end":           uint64(currentAnalyzer.CaptureOffset(iv.End)),
+			"depth":         iv.Depth,
+			"descriptor_id": iv.DescriptorID,
+		})
+	}
+
+	result := map[string]interface{}{
+		"total_intervals": total,
+		"page":            page,
+		"page_size":       pageSize,
+		"has_more":        to < total,
+		"threads":         threads,
+	}
+
+	return jsonResult(result)
+}
+
+func getMostCalledHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	limit := 10
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	functions := currentAnalyzer.GetMostCalled(limit)
+
+	// Format results
+	results := make([]map[string]interface{}, len(functions))
+	for i, function := range functions {
+		results[i] = map[string]interface{}{
+			"rank":           i + 1,
+			"name":           function.Name,
+			"file":           function.File,
+			"line":           function.Line,
+			"call_count":     function.CallCount,
+			"total_duration": formatDuration(function.Duration),
+			"avg_duration":   formatDuration(function.AvgDuration),
+		}
+	}
+
+	return jsonResult(results)
+}
+
+func getBlockPathHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	query, ok := request.Params.Arguments["block"].(string)
+	if !ok || query == "" {
+		return mcp.NewToolResultError("block parameter is required"), nil
+	}
+
+	identities, err := currentAnalyzer.GetBlockIdentity(query)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Format results
+	matches := make([]map[string]interface{}, len(identities))
+	for i, identity := range identities {
+		descriptor := identity.Descriptor
+		matches[i] = map[string]interface{}{
+			"descriptor_id":       descriptor.ID,
+			"name":                identity.Name(),
+			"file":                identity.File(),
+			"line":                descriptor.Line,
+			"color":               identity.Color(),
+			"type":                descriptor.Type.String(),
+			"status":              descriptor.Status,
+			"call_count":          identity.CallCount,
+			"total_duration":      formatDuration(identity.Duration),
+			"total_self_duration": formatDuration(identity.SelfDuration),
+		}
+	}
+
+	if len(matches) == 1 {
+		return jsonResult(matches[0])
+	}
+
+	result := map[string]interface{}{
+		"note":    fmt.Sprintf("%d descriptors are named '%s'; pass a descriptor_id to select one", len(matches), query),
+		"matches": matches,
+	}
+
+	return jsonResult(result)
+}
+
+func getThreadAffinityReportHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	affinities, err := currentAnalyzer.GetThreadAffinity()
+	if errors.Is(err, analyzer.ErrNoCoreData) {
+		result := map[string]interface{}{
+			"available": false,
+			"message": "This profile has no CPU core data. EasyProfiler context switch records store only " +
+				"the switch target thread and process name; cores are recognized only when the switch " +
+				"name contains them (e.g. \"CPU 3\"). Use get_thread_dependency_graph for switch targets instead.",
+		}
+		return jsonResult(result)
+	}
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Format results
+	threads := make([]map[string]interface{}, len(affinities))
+	for i, affinity := range affinities {
+		threads[i] = map[string]interface{}{
+			"thread_id":           affinity.ThreadID,
+			"thread_name":         affinity.ThreadName,
+			"switches":            affinity.Switches,
+			"migrations":          affinity.Migrations,
+			"cores":               affinity.Cores,
+			"dominant_core":       affinity.DominantCore,
+			"dominant_core_share": formatPercent(affinity.DominantCoreShare),
+		}
+	}
+
+	result := map[string]interface{}{
+		"available": true,
+		"threads":   threads,
+	}
+
+	return jsonResult(result)
+}
+
+func getCaptureMetadataHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentProfile == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	header := currentProfile.FileHeader()
+	result := map[string]interface{}{
+		"version":               header.VersionString(),
+		"version_raw":           fmt.Sprintf("0x%08X", header.Version),
+		"pid":                   header.PID,
+		"cpu_frequency":         header.CPUFrequency,
+		"begin_timestamp":       header.BeginTime,
+		"end_timestamp":         header.EndTime,
+		"total_duration":        formatDuration(currentProfile.GetTotalDuration()),
+		"memory_mb":             formatMB(int64(header.MemorySize)),
+		"descriptors_memory_mb": formatMB(int64(header.DescriptorsMemorySize)),
+		"blocks_count":          header.BlocksCount,
+		"descriptors_count":     header.DescriptorsCount,
+		"note":                  "The .prof format records no host name or build information",
+	}
+
+	// Thread and bookmark counts are only recorded in the header since v2.1.0
+	if header.Version >= parser.Version210 {
+		result["threads_count"] = header.ThreadsCount
+		result["bookmarks_count"] = header.BookmarksCount
+	}
+
+	return jsonResult(result)
+}
+
+func getCrossThreadLatencyHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	name, ok := request.Params.Arguments["name"].(string)
+	if !ok || name == "" {
+		return mcp.NewToolResultError("name parameter is required"), nil
+	}
+
+	maxGap := 10 * time.Millisecond
+	if g, ok := request.Params.Arguments["max_gap"].(string); ok && g != "" {
+		var err error
+		maxGap, err = time.ParseDuration(g)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid max_gap: %v", err)), nil
+		}
+	}
+
+	limit := 10
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	rawTimestamps := false
+	if raw, ok := request.Params.Arguments["raw_timestamps"].(bool); ok {
+		rawTimestamps = raw
+	}
+
+	report, err := currentAnalyzer.CorrelateHandoffs(name, maxGap)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Format results
+	chains := report.Chains[:min(limit, len(report.Chains))]
+	operations := make([]map[string]interface{}, len(chains))
+	for i, chain := range chains {
+		stages := make([]map[string]interface{}, len(chain.Segments))
+		for j, segment := range chain.Segments {
+			stages[j] = map[string]interface{}{
+				"thread_id":   segment.ThreadID,
+				"thread_name": segment.ThreadName,
+				"start":       formatDuration(currentAnalyzer.CaptureOffset(segment.Begin)),
+				"duration":    formatDuration(time.Duration(segment.End - segment.Begin)),
+			}
+			if rawTimestamps {
+				addRawTimestamps(stages[j], segment.Begin, segment.End)
+			}
+		}
+		operations[i] = map[string]interface{}{
+			"end_to_end": formatDuration(chain.EndToEnd),
+			"busy":       formatDuration(chain.Busy),
+			"gap":        formatDuration(chain.Gap),
+			"stages":     stages,
+		}
+	}
+
+	result := map[string]interface{}{
+		"name":             report.Name,
+		"max_gap":          formatDuration(maxGap),
+		"operations_count": len(report.Chains),
+		"unlinked_blocks":  report.Unlinked,
+		"avg_end_to_end":   formatDuration(report.AvgEndToEnd),
+		"max_end_to_end":   formatDuration(report.MaxEndToEnd),
+		"slowest":          operations,
+		"note":             "Stages are linked heuristically by name and timing; verify that the name is only used by this operation",
+	}
+
+	return jsonResult(result)
+}
+
+func getBlockHeatmapHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	limit := 10
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		limit = int(l)
+	}
+
+	buckets := 20
+	if b, ok := request.Params.Arguments["buckets"].(float64); ok {
+		buckets = int(b)
+	}
+
+	heatmap, err := currentAnalyzer.GetBlockHeatmap(limit, buckets)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Format results: labels plus a compact matrix of microseconds, one row per function
+	bucketStarts := make([]interface{}, heatmap.Buckets)
+	for i := range bucketStarts {
+		bucketStarts[i] = formatDuration(time.Duration(i) * heatmap.BucketSize)
+	}
+
+	functions := make([]map[string]interface{}, len(heatmap.Rows))
+	cells := make([][]int64, len(heatmap.Rows))
+	for i, row := range heatmap.Rows {
+		functions[i] = map[string]interface{}{
+			"name":  row.Name,
+			"file":  row.File,
+			"line":  row.Line,
+			"total": formatDuration(row.Total),
+		}
+		cells[i] = make([]int64, len(row.Cells))
+		for j, cell := range row.Cells {
+			cells[i][j] = cell.Microseconds()
+		}
+	}
+
+	result := map[string]interface{}{
+		"bucket_size":   formatDuration(heatmap.BucketSize),
+		"bucket_starts": bucketStarts,
+		"functions":     functions,
+		"cells_us":      cells,
+	}
+
+	return jsonResult(result)
+}
+
+func getGCPausesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	pattern, _ := request.Params.Arguments["pattern"].(string)
+
+	bucketSize := time.Second
+	if b, ok := request.Params.Arguments["bucket"].(string); ok && b != "" {
+		var err error
+		bucketSize, err = time.ParseDuration(b)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid bucket: %v", err)), nil
+		}
+	}
+
+	limit := 50
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	report, err := currentAnalyzer.GetGCPauses(pattern, bucketSize)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Format results
+	formatPause := func(pause *analyzer.GCPause) map[string]interface{} {
+		return map[string]interface{}{
+			"start":    formatDuration(currentAnalyzer.CaptureOffset(pause.Begin)),
+			"duration": formatDuration(pause.Duration),
+			"threads":  pause.Threads,
+		}
+	}
+
+	pauses := make([]map[string]interface{}, 0, min(limit, len(report.Pauses)))
+	for _, pause := range report.Pauses[:min(limit, len(report.Pauses))] {
+		pauses = append(pauses, formatPause(pause))
+	}
+
+	buckets := make([]map[string]interface{}, len(report.Buckets))
+	for i, bucket := range report.Buckets {
+		buckets[i] = map[string]interface{}{
+			"start":   formatDuration(bucket.Start),
+			"pauses":  bucket.Count,
+			"gc_time": formatDuration(bucket.Time),
+		}
+	}
+
+	result := map[string]interface{}{
+		"pattern":      report.Pattern,
+		"pause_count":  len(report.Pauses),
+		"total_time":   formatDuration(report.TotalTime),
+		"bucket_size":  formatDuration(report.BucketSize),
+		"pauses":       pauses,
+		"per_interval": buckets,
+	}
+	if captureDuration := currentProfile.GetTotalDuration(); captureDuration > 0 {
+		result["percent_of_capture"] = formatPercent(float64(report.TotalTime) / float64(captureDuration) * 100)
+	}
+	if report.LongestPause != nil {
+		result["longest_pause"] = formatPause(report.LongestPause)
+	}
+
+	return jsonResult(result)
+}
+
+func getBlockByNameSummaryHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	name, ok := request.Params.Arguments["name"].(string)
+	if !ok || name == "" {
+		return mcp.NewToolResultError("name parameter is required"), nil
+	}
+
+	summary, err := currentAnalyzer.GetFunctionSummary(name)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Format results
+	path := make([]map[string]interface{}, len(summary.TypicalPath))
+	for i, node := range summary.TypicalPath {
+		path[i] = map[string]interface{}{
+			"depth":             node.Depth,
+			"name":              node.Name,
+			"file":              node.File,
+			"line":              node.Line,
+			"duration":          formatDuration(node.Duration),
+			"percent_of_parent": formatPercent(node.PercentOfParent),
+		}
+	}
+
+	result := map[string]interface{}{
+		"name":                 summary.Name,
+		"file":                 summary.File,
+		"line":                 summary.Line,
+		"call_count":           summary.CallCount,
+		"threads":              summary.Threads,
+		"total_duration":       formatDuration(summary.Duration),
+		"total_self_duration":  formatDuration(summary.SelfDuration),
+		"avg_duration":         formatDuration(summary.AvgDuration),
+		"p95_duration":         formatDuration(summary.P95Duration),
+		"max_duration":         formatDuration(summary.MaxDuration),
+		"typical_path":         path,
+		"typical_path_calls":   summary.TypicalPathCalls,
+		"typical_path_percent": formatPercent(float64(summary.TypicalPathCalls) / float64(summary.CallCount) * 100),
+		"distinct_paths":       summary.DistinctPaths,
+	}
+
+	return jsonResult(result)
+}
+
+func getRollingHotspotsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	window := time.Second
+	if w, ok := request.Params.Arguments["window"].(string); ok && w != "" {
+		var err error
+		window, err = time.ParseDuration(w)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid window: %v", err)), nil
+		}
+	}
+
+	step := window
+	if s, ok := request.Params.Arguments["step"].(string); ok && s != "" {
+		var err error
+		step, err = time.ParseDuration(s)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid step: %v", err)), nil
+		}
+	}
+
+	top := 1
+	if t, ok := request.Params.Arguments["top"].(float64); ok {
+		top = int(t)
+	}
+
+	mode := timeModeArg(request)
+
+	rolling, err := currentAnalyzer.GetRollingHotspots(window, step, top, mode)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Format results
+	windows := make([]map[string]interface{}, len(rolling.Windows))
+	for i, w := range rolling.Windows {
+		hotspots := make([]map[string]interface{}, len(w.Hotspots))
+		for j, hotspot := range w.Hotspots {
+			hotspots[j] = map[string]interface{}{
+				"name":              hotspot.Name,
+				"file":              hotspot.File,
+				"line":              hotspot.Line,
+				"duration":          formatDuration(mode.Of(hotspot)),
+				"call_count":        hotspot.CallCount,
+				"percent_of_window": formatPercent(float64(mode.Of(hotspot)) / float64(w.End-w.Start) * 100),
+			}
+		}
+		windows[i] = map[string]interface{}{
+			"start":    formatDuration(w.Start),
+			"end":      formatDuration(w.End),
+			"hotspots": hotspots,
+		}
+	}
+
+	shifts := make([]map[string]interface{}, len(rolling.Shifts))
+	for i, shift := range rolling.Shifts {
+		name := shift.Name
+		if name == "" {
+			name = "(idle)"
+		}
+		shifts[i] = map[string]interface{}{
+			"start":    formatDuration(shift.Start),
+			"end":      formatDuration(shift.End),
+			"name":     name,
+			"windows":  shift.Windows,
+			"duration": formatDuration(shift.Duration),
+		}
+	}
+
+	result := map[string]interface{}{
+		"window":    formatDuration(rolling.WindowSize),
+		"step":      formatDuration(rolling.Step),
+		"time_mode": mode.String(),
+		"timeline":  shifts,
+		"windows":   windows,
+	}
+
+	return jsonResult(result)
+}
+
+func getLeafHotspotsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	limit := 20
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	leaves, total := currentAnalyzer.GetLeafHotspots(limit)
+
+	// Format results
+	results := make([]map[string]interface{}, len(leaves))
+	for i, leaf := range leaves {
+		// The total is 0 when every leaf is an event or zero-length
+		percent := 0.0
+		if total > 0 {
+			percent = float64(leaf.SelfDuration) / float64(total) * 100
+		}
+
+		results[i] = map[string]interface{}{
+			"name":                 leaf.Name,
+			"file":                 leaf.File,
+			"line":                 leaf.Line,
+			"self_duration":        formatDuration(leaf.SelfDuration),
+			"call_count":           leaf.CallCount,
+			"avg_duration":         formatDuration(leaf.AvgDuration),
+			"percent_of_leaf_time": formatPercent(percent),
+		}
+	}
+
+	result := map[string]interface{}{
+		"total_leaf_time": formatDuration(total),
+		"leaves":          results,
+	}
+
+	return jsonResult(result)
+}
+
+func exportIssuesMarkdownHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	issues, shown, errResult := detectIssues(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	title, _ := request.Params.Arguments["title"].(string)
+	if title == "" {
+		title = currentProfileID
+		if loaded, err := lookupProfile(""); err == nil {
+			title = filepath.Base(loaded.FilePath)
+		}
+	}
+
+	report := renderIssuesMarkdown(title, issues, shown)
+
+	if outputPath, ok := request.Params.Arguments["output_path"].(string); ok && outputPath != "" {
+		if err := os.WriteFile(outputPath, []byte(report), 0o644); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to write report: %v", err)), nil
+		}
+	}
+
+	return mcp.NewToolResultText(report), nil
+}
+
+func getInstrumentationOverheadHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	limit := 5
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	overhead := currentAnalyzer.EstimateInstrumentationOverhead(limit)
+
+	// Format results
+	densest := make([]map[string]interface{}, len(overhead.Densest))
+	for i, function := range overhead.Densest {
+		densest[i] = map[string]interface{}{
+			"name":               function.Name,
+			"file":               function.File,
+			"line":               function.Line,
+			"call_count":         function.CallCount,
+			"avg_duration":       formatDuration(function.AvgDuration),
+			"estimated_overhead": formatDuration(time.Duration(function.CallCount) * overhead.BlockCost),
+		}
+	}
+
+	blockCostSource := "assumed"
+	if overhead.BlockCostMeasured {
+		blockCostSource = "measured"
+	}
+
+	var recommendation string
+	switch overhead.Level {
+	case "high":
+		recommendation = "The profiler likely distorted this capture. Remove instrumentation from the densest functions below (or move it to their callers) and re-capture before trusting small differences"
+	case "moderate":
+		recommendation = "Overhead is noticeable: treat timings of short, frequently called functions with caution and consider removing their instrumentation"
+	default:
+		recommendation = "Instrumentation density is reasonable; overhead should not distort the results"
+	}
+
+	result := map[string]interface{}{
+		"level":              overhead.Level,
+		"estimated_overhead": formatDuration(overhead.Overhead),
+		"overhead_percent":   formatPercent(overhead.OverheadPercent),
+		"busy_time":          formatDuration(overhead.BusyTime),
+		"blocks_count":       overhead.Blocks,
+		"tiny_blocks_count":  overhead.TinyBlocks,
+		"block_cost":         formatDuration(overhead.BlockCost),
+		"block_cost_source":  blockCostSource,
+		"gap_samples":        overhead.GapSamples,
+		"densest_functions":  densest,
+		"recommendation":     recommendation,
+		"note":               "Heuristic: per-block cost is the median of sub-microsecond gaps between back-to-back blocks, multiplied by the number of blocks",
+	}
+	if overhead.BusyTime > 0 {
+		result["blocks_per_ms"] = formatNumber(float64(overhead.Blocks)/(float64(overhead.BusyTime)/float64(time.Millisecond)), 2)
+	}
+
+	return jsonResult(result)
+}
+
+func getThreadComparisonHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	var threads [2]*parser.ThreadData
+	for i, param := range []string{"thread_a", "thread_b"} {
+		ref, ok := request.Params.Arguments[param].(string)
+		if !ok {
+			return mcp.NewToolResultError(param + " parameter is required"), nil
+		}
+		thread, err := currentAnalyzer.FindThread(ref)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		threads[i] = thread
+	}
+	if threads[0] == threads[1] {
+		return mcp.NewToolResultError("thread_a and thread_b refer to the same thread"), nil
+	}
+
+	divergence := 25.0
+	if d, ok := request.Params.Arguments["divergence_percent"].(float64); ok {
+		divergence = d
+	}
+
+	limit := 20
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	mode := timeModeArg(request)
+
+	comparison := currentAnalyzer.CompareThreads(threads[0], threads[1], mode, divergence)
+
+	// Format results
+	formatSide := func(side *analyzer.ThreadSide) map[string]interface{} {
+		return map[string]interface{}{
+			"thread_id":      side.ThreadID,
+			"thread_name":    side.ThreadName,
+			"total_duration": formatDuration(side.TotalDuration),
+			"block_count":    side.BlockCount,
+		}
+	}
+	formatDiffs := func(diffs []*analyzer.ThreadFunctionDiff) []map[string]interface{} {
+		diffs = diffs[:min(limit, len(diffs))]
+		results := make([]map[string]interface{}, len(diffs))
+		for i, diff := range diffs {
+			results[i] = map[string]interface{}{
+				"name":               diff.Name,
+				"file":               diff.File,
+				"line":               diff.Line,
+				"duration_a":         formatDuration(diff.FirstDuration),
+				"duration_b":         formatDuration(diff.SecondDuration),
+				"calls_a":            diff.FirstCalls,
+				"calls_b":            diff.SecondCalls,
+				"delta":              formatDuration(diff.Delta),
+				"divergence_percent": formatPercent(diff.DivergencePercent),
+			}
+		}
+		return results
+	}
+	formatUnique := func(functions []*analyzer.BlockInfo) []map[string]interface{} {
+		functions = functions[:min(limit, len(functions))]
+		results := make([]map[string]interface{}, len(functions))
+		for i, function := range functions {
+			results[i] = map[string]interface{}{
+				"name":       function.Name,
+				"file":       function.File,
+				"line":       function.Line,
+				"duration":   formatDuration(mode.Of(function)),
+				"call_count": function.CallCount,
+			}
+		}
+		return results
+	}
+
+	result := map[string]interface{}{
+		"thread_a":          formatSide(comparison.First),
+		"thread_b":          formatSide(comparison.Second),
+		"time_mode":         mode.String(),
+		"divergent":         formatDiffs(comparison.Divergent),
+		"divergent_count":   len(comparison.Divergent),
+		"shared":            formatDiffs(comparison.Shared),
+		"shared_count":      len(comparison.Shared),
+		"only_in_a":         formatUnique(comparison.OnlyFirst),
+		"only_in_a_count":   len(comparison.OnlyFirst),
+		"only_in_b":         formatUnique(comparison.OnlySecond),
+		"only_in_b_count":   len(comparison.OnlySecond),
+		"total_delta":       formatDuration(comparison.Second.TotalDuration - comparison.First.TotalDuration),
+		"block_count_delta": comparison.Second.BlockCount - comparison.First.BlockCount,
+	}
+
+	return jsonResult(result)
+}
+
+func getFunctionTableHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	column := analyzer.FunctionsByTotalTime
+	if sortBy, ok := request.Params.Arguments["sort_by"].(string); ok && sortBy != "" {
+		parsed, err := analyzer.ParseFunctionTableColumn(sortBy)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		column = parsed
+	}
+
+	limit := 0
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	rows := currentAnalyzer.GetFunctionTable(column, limit)
+	totalDuration := currentProfile.GetTotalDuration()
+
+	// Format results
+	results := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		percent := 0.0
+		if totalDuration > 0 {
+			percent = float64(row.Duration) / float64(totalDuration) * 100
+		}
+
+		descriptor := row.Descriptor
+		results[i] = map[string]interface{}{
+			"descriptor_id":       descriptor.ID,
+			"name":                row.Name(),
+			"location":            fmt.Sprintf("%s:%d", row.File(), descriptor.Line),
+			"type":                descriptor.Type.String(),
+			"color":               row.Color(),
+			"call_count":          row.CallCount,
+			"total_duration":      formatDuration(row.Duration),
+			"total_self_duration": formatDuration(row.SelfDuration),
+			"percent_of_total":    formatPercent(percent),
+		}
+	}
+
+	return jsonResult(map[string]interface{}{
+		"sort_by":   column.String(),
+		"functions": results,
+	})
+}
+
+func getBlockDurationOverTimeHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	name, ok := request.Params.Arguments["name"].(string)
+	if !ok || name == "" {
+		return mcp.NewToolResultError("name parameter is required"), nil
+	}
+
+	maxPoints := 200
+	if m, ok := request.Params.Arguments["max_points"].(float64); ok {
+		if m < 0 {
+			return mcp.NewToolResultError("max_points must not be negative"), nil
+		}
+		maxPoints = int(m)
+	}
+
+	series, err := currentAnalyzer.GetDurationOverTime(name, timeModeArg(request), maxPoints)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Format results
+	points := make([]map[string]interface{}, len(series.Points))
+	for i, point := range series.Points {
+		points[i] = map[string]interface{}{
+			"offset":   formatDuration(point.Offset),
+			"duration": formatDuration(point.Duration),
+		}
+		if series.Downsampled {
+			points[i]["max_duration"] = formatDuration(point.MaxDuration)
+			points[i]["calls"] = point.Calls
+		}
+	}
+
+	result := map[string]interface{}{
+		"name":             series.Name,
+		"time_mode":        series.Mode.String(),
+		"call_count":       series.Calls,
+		"downsampled":      series.Downsampled,
+		"slope_per_second": formatDuration(series.Slope),
+		"change_percent":   formatPercent(series.ChangePercent),
+		"points":           points,
+	}
+
+	return jsonResult(result)
+}
+
+func getOverlappingBlocksHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+	if currentProfile.RetainedSlowestCount() > 0 {
+		return mcp.NewToolResultError("The profile was loaded with retain_slowest: blocks were kept without their call trees, so unrelated blocks overlap. Reload it without retain_slowest"), nil
+	}
+
+	limit := 20
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	budgetCtx, cancel, errResult := timeBudgetArg(ctx, request)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer cancel()
+
+	report := currentAnalyzer.FindOverlappingBlocksContext(budgetCtx, limit)
+
+	// Format results
+	formatSide := func(block *analyzer.OverlapBlock) map[string]interface{} {
+		return map[string]interface{}{
+			"name":  block.Name,
+			"file":  block.File,
+			"line":  block.Line,
+			"start": formatDuration(currentAnalyzer.CaptureOffset(block.Begin)),
+			"end":   formatDuration(currentAnalyzer.CaptureOffset(block.End)),
+		}
+	}
+
+	overlaps := make([]map[string]interface{}, len(report.Worst))
+	for i, overlap := range report.Worst {
+		overlaps[i] = map[string]interface{}{
+			"thread_id":   overlap.ThreadID,
+			"thread_name": overlap.ThreadName,
+			"depth":       overlap.First.Depth,
+			"first":       formatSide(overlap.First),
+			"second":      formatSide(overlap.Second),
+			"overlap":     formatDuration(overlap.Overlap),
+		}
+	}
+
+	result := map[string]interface{}{
+		"count":            report.Count,
+		"threads_affected": report.Threads,
+		"overlaps":         overlaps,
+	}
+	if report.TimedOut {
+		result["timed_out"] = true
+	} else if report.Count == 0 {
+		result["note"] = "No overlapping blocks: every pair of blocks on a thread is either nested or disjoint"
+	}
+
+	return jsonResult(result)
+}
+
+func getPhasesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	threadRef, ok := request.Params.Arguments["thread"].(string)
+	if !ok {
+		return mcp.NewToolResultError("thread parameter is required"), nil
+	}
+
+	minGap := 10 * time.Millisecond
+	if g, ok := request.Params.Arguments["min_gap"].(string); ok && g != "" {
+		var err error
+		minGap, err = time.ParseDuration(g)
+		if err != nil || minGap <= 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid min_gap: %s", g)), nil
+		}
+	}
+
+	thread, err := currentAnalyzer.FindThread(threadRef)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-	// Start server using stdio
-	if err := server.ServeStdio(s); err != nil {
-		log.Fatal(err)
+	breakdown := currentAnalyzer.GetPhases(thread, minGap)
+
+	// Format results
+	phases := make([]map[string]interface{}, len(breakdown.Phases))
+	for i, phase := range breakdown.Phases {
+		phases[i] = map[string]interface{}{
+			"index":       i + 1,
+			"start":       formatDuration(phase.Start),
+			"end":         formatDuration(phase.End),
+			"duration":    formatDuration(phase.End - phase.Start),
+			"busy":        formatDuration(phase.Busy),
+			"idle_before": formatDuration(phase.IdleBefore),
+			"blocks":      phase.Blocks,
+		}
+		if top := phase.Top; top != nil {
+			percent := 0.0
+			if phase.Busy > 0 {
+				percent = float64(top.Duration) / float64(phase.Busy) * 100
+			}
+			phases[i]["top_function"] = map[string]interface{}{
+				"name":            top.Name,
+				"file":            top.File,
+				"line":            top.Line,
+				"total_duration":  formatDuration(top.Duration),
+				"call_count":      top.CallCount,
+				"percent_of_busy": formatPercent(percent),
+			}
+		}
 	}
+
+	return jsonResult(map[string]interface{}{
+		"thread_id":   breakdown.ThreadID,
+		"thread_name": breakdown.ThreadName,
+		"min_gap":     formatDuration(breakdown.MinGap),
+		"idle_time":   formatDuration(breakdown.IdleTime),
+		"phase_count": len(breakdown.Phases),
+		"phases":      phases,
+	})
 }
 
-func registerTools(s *server.MCPServer) {
-	// Tool 1: Load profile
-	loadProfileTool := mcp.NewTool("load_profile",
-		mcp.WithDescription("Load an EasyProfiler .prof file for analysis. For large files (>100MB), use fast_mode=true"),
-		mcp.WithString("file_path",
-			mcp.Required(),
-			mcp.Description("Path to the .prof file to load"),
-		),
-		mcp.WithBoolean("fast_mode",
-			mcp.Description("Use fast mode for large files - skips context switches and bookmarks (default: false)"),
-		),
-	)
+func getBlockCountByDepthHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
 
-	s.AddTool(loadProfileTool, loadProfileHandler)
+	report := currentAnalyzer.GetBlockCountByDepth()
 
-	// Tool 2: Get slowest blocks
-	slowestBlocksTool := mcp.NewTool("get_slowest_blocks",
-		mcp.WithDescription("Get the slowest profiling blocks"),
-		mcp.WithNumber("limit",
-			mcp.Description("Number of blocks to return (default: 10)"),
-		),
-	)
+	// Format results
+	threads := make([]map[string]interface{}, len(report.Threads))
+	for i, histogram := range report.Threads {
+		threads[i] = formatDepthHistogram(histogram)
+		threads[i]["thread_id"] = histogram.ThreadID
+		threads[i]["thread_name"] = histogram.ThreadName
+	}
+
+	return jsonResult(map[string]interface{}{
+		"total":   formatDepthHistogram(report.Total),
+		"threads": threads,
+	})
+}
 
-	s.AddTool(slowestBlocksTool, getSlowestBlocksHandler)
+// formatDepthHistogram renders a depth histogram with each level's share of blocks
+func formatDepthHistogram(histogram *analyzer.DepthHistogram) map[string]interface{} {
+	levels := make([]map[string]interface{}, len(histogram.Counts))
+	for depth, count := range histogram.Counts {
+		levels[depth] = map[string]interface{}{
+			"depth":      depth,
+			"blocks":     count,
+			"percentage": formatPercent(float64(count) / float64(histogram.Blocks) * 100),
+		}
+	}
 
-	// Tool 3: Get thread statistics
-	threadStatsTool := mcp.NewTool("get_thread_statistics",
-		mcp.WithDescription("Get statistics for all threads in the profile"),
-	)
+	return map[string]interface{}{
+		"blocks":            histogram.Blocks,
+		"max_depth":         len(histogram.Counts) - 1,
+		"levels_for_95_pct": histogram.LevelsCovering(95),
+		"levels_for_99_pct": histogram.LevelsCovering(99),
+		"depths":            levels,
+	}
+}
 
-	s.AddTool(threadStatsTool, getThreadStatisticsHandler)
+func getWorstInvocationHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+	// Retained blocks have no children, so their self time is unknown
+	if currentProfile.RetainedSlowestCount() > 0 {
+		return mcp.NewToolResultError("Self time needs the call trees, which aren't kept for profiles loaded with retain_slowest. Reload without it."), nil
+	}
 
-	// Tool 4: Get hotspots
-	hotspotsTool := mcp.NewTool("get_hotspots",
-		mcp.WithDescription("Get functions with the highest cumulative execution time"),
-		mcp.WithNumber("limit",
-			mcp.Description("Number of hotspots to return (default: 10)"),
-		),
-	)
+	rawTimestamps, _ := request.Params.Arguments["raw_timestamps"].(bool)
 
-	s.AddTool(hotspotsTool, getHotspotsHandler)
+	worst := currentAnalyzer.FindWorstInvocation()
+	if worst == nil {
+		return mcp.NewToolResultError("The profile has no closed blocks"), nil
+	}
 
-	// Tool 5: Analyze performance issues
-	analyzeIssuesTool := mcp.NewTool("analyze_performance_issues",
-		mcp.WithDescription("Perform comprehensive performance analysis and detect common issues"),
-	)
+	// Format results
+	path := make([]map[string]interface{}, len(worst.Path))
+	for i, node := range worst.Path {
+		path[i] = map[string]interface{}{
+			"depth":             node.Depth,
+			"name":              node.Name,
+			"file":              node.File,
+			"line":              node.Line,
+			"duration":          formatDuration(node.Duration),
+			"percent_of_parent": formatPercent(node.PercentOfParent),
+		}
+		if rawTimestamps {
+			addRawTimestamps(path[i], node.Begin, node.End)
+		}
+	}
+	block := worst.Path[len(worst.Path)-1]
 
-	s.AddTool(analyzeIssuesTool, analyzePerformanceIssuesHandler)
+	return jsonResult(map[string]interface{}{
+		"name":          block.Name,
+		"file":          block.File,
+		"line":          block.Line,
+		"thread_id":     worst.ThreadID,
+		"thread_name":   worst.ThreadName,
+		"block_key":     worst.Key,
+		"offset":        formatDuration(worst.Offset),
+		"self_duration": formatDuration(worst.SelfDuration),
+		"duration":      formatDuration(block.Duration),
+		"depth":         block.Depth,
+		"path":          path,
+	})
 }
 
-func loadProfileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	filePath, ok := request.Params.Arguments["file_path"].(string)
-	if !ok {
-		return mcp.NewToolResultError("file_path parameter is required"), nil
+func listSourceFilesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
 	}
 
-	// Check if fast mode is requested
-	fastMode := false
-	if fast, ok := request.Params.Arguments["fast_mode"].(bool); ok {
-		fastMode = fast
+	files := currentAnalyzer.GetSourceFiles()
+	totalDuration := currentProfile.GetTotalDuration()
+
+	// Format results
+	results := make([]map[string]interface{}, len(files))
+	for i, file := range files {
+		percent := 0.0
+		if totalDuration > 0 {
+			percent = float64(file.SelfDuration) / float64(totalDuration) * 100
+		}
+
+		results[i] = map[string]interface{}{
+			"file":                file.File,
+			"functions":           file.Functions,
+			"used_functions":      file.UsedFunctions,
+			"call_count":          file.CallCount,
+			"total_self_duration": formatDuration(file.SelfDuration),
+			"percent_of_total":    formatPercent(percent),
+		}
 	}
 
-	// Choose read options
-	options := parser.DefaultReadOptions()
-	if fastMode {
-		options = parser.FastReadOptions()
+	return jsonResult(map[string]interface{}{
+		"file_count": len(files),
+		"files":      results,
+	})
+}
+
+func exportPrometheusHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
 	}
 
-	// Parse the profile
-	reader, err := parser.NewReaderWithOptions(filePath, options)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to open file: %v", err)), nil
+	limit := 20
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
 	}
-	defer reader.Close()
 
-	profile, err := reader.Parse()
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to parse profile: %v", err)), nil
+	issues, _, errResult := detectIssues(ctx, request)
+	if errResult != nil {
+		return errResult, nil
 	}
 
-	// Store globally
-	currentProfile = profile
-	currentAnalyzer = analyzer.NewAnalyzer(profile)
+	metrics := renderPrometheus(
+		currentProfile.GetTotalDuration(),
+		currentAnalyzer.GetSelfTimeHotspots(limit),
+		currentAnalyzer.GetThreadStatistics(),
+		issues)
 
-	// Prepare summary
-	summary := map[string]interface{}{
-		"status":            "success",
-		"file":              filePath,
-		"fast_mode":         fastMode,
-		"version":           fmt.Sprintf("0x%X", profile.Header.Version),
-		"pid":               profile.Header.PID,
-		"total_duration":    profile.GetTotalDuration().String(),
-		"threads_count":     profile.GetThreadCount(),
-		"blocks_count":      profile.GetBlocksCount(),
-		"descriptors_count": len(profile.Descriptors),
-		"bookmarks_count":   len(profile.Bookmarks),
-		"memory_mb":         fmt.Sprintf("%.2f", float64(profile.Header.MemorySize)/1024/1024),
+	if outputPath, ok := request.Params.Arguments["output_path"].(string); ok && outputPath != "" {
+		if err := os.WriteFile(outputPath, []byte(metrics), 0o644); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to write metrics: %v", err)), nil
+		}
 	}
 
-	data, _ := json.MarshalIndent(summary, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	return mcp.NewToolResultText(metrics), nil
 }
 
-func getSlowestBlocksHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func getFrameStatisticsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	if currentAnalyzer == nil {
 		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
 	}
 
-	limit := 10
-	if l, ok := request.Params.Arguments["limit"].(float64); ok {
-		limit = int(l)
+	delimiter, _ := request.Params.Arguments["frame_block"].(string)
+
+	stats, err := currentAnalyzer.GetFrameStatistics(delimiter)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Format results
+	result := map[string]interface{}{
+		"frame_block":     stats.Delimiter,
+		"auto_detected":   stats.AutoDetected,
+		"thread_id":       stats.ThreadID,
+		"thread_name":     stats.ThreadName,
+		"frames":          stats.Frames,
+		"avg_duration":    formatDuration(stats.AvgDuration),
+		"median_duration": formatDuration(stats.MedianDuration),
+		"p95_duration":    formatDuration(stats.P95Duration),
+		"p99_duration":    formatDuration(stats.P99Duration),
+		"min_duration":    formatDuration(stats.MinDuration),
+		"max_duration":    formatDuration(stats.MaxDuration),
+	}
+	if stats.AutoDetected {
+		result["confidence"] = formatPercent(stats.Confidence)
+	}
+	if stats.Frames > 1 {
+		result["avg_interval"] = formatDuration(stats.AvgInterval)
+		result["frames_per_second"] = formatNumber(stats.FramesPerSecond, 1)
+	}
+
+	return jsonResult(result)
+}
+
+func detectLockConvoysHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if currentAnalyzer == nil {
+		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
+	}
+
+	pattern, _ := request.Params.Arguments["pattern"].(string)
+
+	maxGap := 50 * time.Microsecond
+	if g, ok := request.Params.Arguments["max_gap"].(string); ok && g != "" {
+		var err error
+		maxGap, err = time.ParseDuration(g)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid max_gap: %v", err)), nil
+		}
+		if maxGap < 0 {
+			return mcp.NewToolResultError("max_gap must not be negative"), nil
+		}
+	}
+
+	minHandoffs := 3
+	if n, ok := request.Params.Arguments["min_handoffs"].(float64); ok {
+		minHandoffs = max(int(n), 1)
 	}
 
-	blocks := currentAnalyzer.GetSlowestBlocks(limit)
+	rawTimestamps, _ := request.Params.Arguments["raw_timestamps"].(bool)
+
+	reports, err := currentAnalyzer.DetectLockConvoys(pattern, maxGap, minHandoffs)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	// Format results
-	results := make([]map[string]interface{}, len(blocks))
-	for i, block := range blocks {
+	captureDuration := currentProfile.GetTotalDuration()
+	results := make([]map[string]interface{}, len(reports))
+	for i, report := range reports {
+		longest := map[string]interface{}{
+			"start":        formatDuration(currentAnalyzer.CaptureOffset(report.Longest.Begin)),
+			"duration":     formatDuration(time.Duration(report.Longest.End - report.Longest.Begin)),
+			"acquisitions": report.Longest.Acquisitions,
+			"threads":      report.Longest.Threads,
+		}
+		if rawTimestamps {
+			addRawTimestamps(longest, report.Longest.Begin, report.Longest.End)
+		}
+
 		results[i] = map[string]interface{}{
-			"rank":        i + 1,
-			"name":        block.Name,
-			"file":        block.File,
-			"line":        block.Line,
-			"duration":    block.Duration.String(),
-			"duration_ns": block.Duration.Nanoseconds(),
-			"thread_id":   block.ThreadID,
-			"thread_name": block.ThreadName,
+			"lock":            report.Lock,
+			"threads":         report.Threads,
+			"convoys":         len(report.Convoys),
+			"handoffs":        report.Handoffs,
+			"acquisitions":    report.Acquisitions,
+			"serialized_time": formatDuration(report.SerializedTime),
+			"longest_convoy":  longest,
+		}
+		if captureDuration > 0 {
+			results[i]["percent_of_capture"] = formatPercent(float64(report.SerializedTime) / float64(captureDuration) * 100)
 		}
 	}
 
-	data, _ := json.MarshalIndent(results, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	return jsonResult(map[string]interface{}{
+		"max_gap":      formatDuration(maxGap),
+		"min_handoffs": minHandoffs,
+		"lock_count":   len(results),
+		"locks":        results,
+	})
 }
 
-func getThreadStatisticsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func getDurationDistributionHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	if currentAnalyzer == nil {
 		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
 	}
 
-	stats := currentAnalyzer.GetThreadStatistics()
+	mode := timeModeArg(request)
+	if currentProfile.RetainedSlowestCount() > 0 {
+		return mcp.NewToolResultError("The profile was loaded with retain_slowest: only the slowest blocks were kept, so their distribution says nothing about the capture. Reload it without retain_slowest"), nil
+	}
+
+	distribution := currentAnalyzer.GetDurationDistribution(mode)
 
 	// Format results
-	results := make([]map[string]interface{}, len(stats))
-	for i, stat := range stats {
-		results[i] = map[string]interface{}{
-			"thread_id":          stat.ThreadID,
-			"thread_name":        stat.ThreadName,
-			"total_duration":     stat.TotalDuration.String(),
-			"block_count":        stat.BlockCount,
-			"context_switches":   stat.ContextSwitches,
-			"avg_block_duration": stat.AvgBlockDuration.String(),
-			"percent_of_total":   fmt.Sprintf("%.2f%%", stat.PercentOfTotal),
+	buckets := make([]map[string]interface{}, len(distribution.Buckets))
+	for i, bucket := range distribution.Buckets {
+		percentOfBlocks, percentOfTime := 0.0, 0.0
+		if distribution.Blocks > 0 {
+			percentOfBlocks = float64(bucket.Count) / float64(distribution.Blocks) * 100
+		}
+		if distribution.TotalDuration > 0 {
+			percentOfTime = float64(bucket.TotalDuration) / float64(distribution.TotalDuration) * 100
+		}
+
+		buckets[i] = map[string]interface{}{
+			"range":             bucket.Label(),
+			"count":             bucket.Count,
+			"percent_of_blocks": formatPercent(percentOfBlocks),
+			"total_duration":    formatDuration(bucket.TotalDuration),
+			"percent_of_time":   formatPercent(percentOfTime),
 		}
 	}
 
-	data, _ := json.MarshalIndent(results, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	return jsonResult(map[string]interface{}{
+		"block_count":    distribution.Blocks,
+		"total_duration": formatDuration(distribution.TotalDuration),
+		"time_mode":      mode.String(),
+		"buckets":        buckets,
+	})
 }
 
-func getHotspotsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func getBlockByKeyHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	if currentAnalyzer == nil {
 		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
 	}
 
-	limit := 10
-	if l, ok := request.Params.Arguments["limit"].(float64); ok {
-		limit = int(l)
+	key, ok := request.Params.Arguments["block_key"].(string)
+	if !ok || key == "" {
+		return mcp.NewToolResultError("block_key parameter is required"), nil
 	}
+	rawTimestamps, _ := request.Params.Arguments["raw_timestamps"].(bool)
 
-	hotspots := currentAnalyzer.GetHotspots(limit)
-	totalDuration := currentProfile.GetTotalDuration()
+	block, ancestors, err := currentAnalyzer.FindBlockByKey(key)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	// Format results
-	results := make([]map[string]interface{}, len(hotspots))
-	for i, hotspot := range hotspots {
-		percent := float64(hotspot.Duration) / float64(totalDuration) * 100
-
-		results[i] = map[string]interface{}{
-			"rank":             i + 1,
-			"name":             hotspot.Name,
-			"file":             hotspot.File,
-			"line":             hotspot.Line,
-			"total_duration":   hotspot.Duration.String(),
-			"call_count":       hotspot.CallCount,
-			"avg_duration":     hotspot.AvgDuration.String(),
-			"percent_of_total": fmt.Sprintf("%.2f%%", percent),
+	path := make([]map[string]interface{}, len(ancestors))
+	for i, node := range ancestors {
+		path[i] = map[string]interface{}{
+			"depth": node.Depth,
+			"name":  node.Name,
+			"file":  node.File,
+			"line":  node.Line,
 		}
 	}
+	result := map[string]interface{}{
+		"block_key":     block.Key(),
+		"name":          block.Name,
+		"file":          block.File,
+		"line":          block.Line,
+		"thread_id":     block.ThreadID,
+		"thread_name":   block.ThreadName,
+		"offset":        formatDuration(currentAnalyzer.CaptureOffset(block.Begin)),
+		"duration":      formatDuration(block.Duration),
+		"self_duration": formatDuration(block.SelfDuration),
+		"depth":         block.Depth,
+		"path":          path,
+	}
+	if rawTimestamps {
+		addRawTimestamps(result, block.Begin, block.End)
+	}
 
-	data, _ := json.MarshalIndent(results, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	return jsonResult(result)
 }
 
-func analyzePerformanceIssuesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// narrativeTextLimit caps the names and headline in get_narrative_summary, keeping
+// its payload small whatever the capture
+const narrativeTextLimit = 120
+
+func getNarrativeSummaryHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	if currentAnalyzer == nil {
 		return mcp.NewToolResultError("No profile loaded. Use load_profile first."), nil
 	}
+	// Retained blocks have no children, so their self time is unknown
+	if currentProfile.RetainedSlowestCount() > 0 {
+		return mcp.NewToolResultError("Self time needs the call trees, which aren't kept for profiles loaded with retain_slowest. Reload without it."), nil
+	}
 
-	issues := currentAnalyzer.AnalyzePerformanceIssues()
+	summary := currentAnalyzer.GetNarrativeSummary(progressReporter(ctx, request))
 
-	// Group by severity
-	grouped := map[string][]map[string]interface{}{
-		"high":   make([]map[string]interface{}, 0),
-		"medium": make([]map[string]interface{}, 0),
-		"low":    make([]map[string]interface{}, 0),
+	// Format results
+	result := map[string]interface{}{
+		"total_duration": formatDuration(summary.CaptureDuration),
+		"parallelism":    formatNumber(summary.Parallelism, 2),
 	}
 
-	for _, issue := range issues {
-		issueData := map[string]interface{}{
-			"type":        issue.Type,
-			"description": issue.Description,
-			"location":    issue.Location,
+	if thread := summary.DominantThread; thread != nil {
+		dominant := map[string]interface{}{
+			"thread_id":        thread.ThreadID,
+			"thread_name":      truncateText(thread.ThreadName, narrativeTextLimit),
+			"busy_time":        formatDuration(thread.TotalDuration),
+			"percent_of_total": formatPercent(thread.PercentOfTotal),
+		}
+		if top := summary.TopFunction; top != nil {
+			function := map[string]interface{}{
+				"name":          truncateText(top.Name, narrativeTextLimit),
+				"self_duration": formatDuration(top.SelfDuration),
+				"call_count":    top.CallCount,
+			}
+			if thread.TotalDuration > 0 {
+				function["percent_of_thread"] = formatPercent(float64(top.SelfDuration) / float64(thread.TotalDuration) * 100)
+			}
+			dominant["top_function"] = function
 		}
+		result["dominant_thread"] = dominant
+	}
 
-		if issue.Duration > 0 {
-			issueData["duration"] = issue.Duration.String()
+	if worst := summary.WorstBlock; worst != nil {
+		block := worst.Path[len(worst.Path)-1]
+		result["worst_block"] = map[string]interface{}{
+			"name":          truncateText(block.Name, narrativeTextLimit),
+			"thread_name":   truncateText(worst.ThreadName, narrativeTextLimit),
+			"offset":        formatDuration(worst.Offset),
+			"self_duration": formatDuration(worst.SelfDuration),
+			"duration":      formatDuration(block.Duration),
+			"block_key":     worst.Key,
 		}
-		if issue.ThreadName != "" {
-			issueData["thread_name"] = issue.ThreadName
+	}
+
+	issues := map[string]interface{}{
+		"high_severity": summary.HighSeverityIssues,
+		"total":         summary.TotalIssues,
+	}
+	if headline := summary.Headline; headline != nil {
+		issues["headline"] = map[string]interface{}{
+			"type":        headline.Type,
+			"description": truncateText(headline.Description, narrativeTextLimit),
+			"score":       formatNumber(headline.Score, 1),
 		}
+	}
+	result["issues"] = issues
 
-		grouped[issue.Severity] = append(grouped[issue.Severity], issueData)
+	return jsonResult(result)
+}
+
+func clearCacheHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cache, err := openProfileCache()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to open cache: %v", err)), nil
+	}
+
+	removed, err := cache.Clear()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to clear cache: %v", err)), nil
 	}
 
 	result := map[string]interface{}{
-		"total_issues": len(issues),
-		"by_severity":  grouped,
-		"summary": fmt.Sprintf("Found %d performance issues (%d high, %d medium, %d low)",
-			len(issues),
-			len(grouped["high"]),
-			len(grouped["medium"]),
-			len(grouped["low"])),
+		"status":          "success",
+		"cache_dir":       cache.Dir(),
+		"entries_removed": removed,
+	}
+
+	return jsonResult(result)
+}
+
+// formatMB renders a byte count in megabytes
+func formatMB(bytes int64) interface{} {
+	return formatNumber(float64(bytes)/1024/1024, 2)
+}
+
+// parseDurationList parses a comma-separated list of Go durations such as "1ms,10ms"
+func parseDurationList(s string) ([]time.Duration, error) {
+	var result []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+// parseNameList parses a comma-separated list of names, dropping empty entries
+func parseNameList(s string) []string {
+	var result []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// nestingAnomaliesShown is the number of worst nesting anomalies listed by load_profile
+const nestingAnomaliesShown = 5
+
+// formatNestingReport renders blocks whose children exceed them for the load summary
+func formatNestingReport(a *analyzer.Analyzer, report *analyzer.NestingReport) map[string]interface{} {
+	worst := make([]map[string]interface{}, len(report.Worst))
+	for i, anomaly := range report.Worst {
+		worst[i] = map[string]interface{}{
+			"name":              anomaly.Name,
+			"file":              anomaly.File,
+			"line":              anomaly.Line,
+			"thread_name":       anomaly.ThreadName,
+			"start":             formatDuration(a.CaptureOffset(anomaly.Begin)),
+			"duration":          formatDuration(anomaly.Duration),
+			"children_duration": formatDuration(anomaly.ChildrenDuration),
+			"excess":            formatDuration(anomaly.Excess),
+		}
 	}
 
-	data, _ := json.MarshalIndent(result, "", "  ")
-	return mcp.NewToolResultText(string(data)), nil
+	return map[string]interface{}{
+		"count": report.Count,
+		"worst": worst,
+		"warning": fmt.Sprintf("%d blocks have children that add up to more time than the block itself (overlapping blocks or clock skew); their self time is reported as 0",
+			report.Count),
+	}
+}
+
+// formatIntegrity renders a declared-versus-parsed count comparison for the load summary
+func formatIntegrity(report *parser.IntegrityReport) map[string]interface{} {
+	result := map[string]interface{}{
+		"declared_blocks":      report.Blocks.Declared,
+		"blocks_in_file":       report.Blocks.InFile,
+		"loaded_blocks":        report.Blocks.Loaded,
+		"declared_descriptors": report.Descriptors.Declared,
+		"loaded_descriptors":   report.Descriptors.Loaded,
+	}
+
+	if skipped := report.Blocks.Skipped(); skipped > 0 {
+		result["intentionally_skipped_blocks"] = skipped
+		result["skip_reasons"] = report.SkipReasons
+	}
+	if duplicates := report.Descriptors.Skipped(); duplicates > 0 {
+		result["duplicate_descriptor_ids"] = duplicates
+	}
+
+	if missing := report.Blocks.Missing(); missing > 0 {
+		result["missing_blocks"] = missing
+		result["warning"] = fmt.Sprintf("The header declares %d blocks but the file contains only %d; the capture may be truncated",
+			report.Blocks.Declared, report.Blocks.InFile)
+	} else if extra := report.Blocks.Unexpected(); extra > 0 {
+		result["unexpected_blocks"] = extra
+		result["warning"] = fmt.Sprintf("The file contains %d more blocks than the header declares", extra)
+	}
+
+	return result
+}
+
+// addRawTimestamps adds the block's unconverted timestamps and the capture's CPU frequency to a result entry
+func addRawTimestamps(entry map[string]interface{}, begin, end uint64) {
+	entry["begin_ticks"] = begin
+	entry["end_ticks"] = end
+	entry["cpu_frequency"] = currentProfile.FileHeader().CPUFrequency
+}
+
+// timeModeArg reads the "exclusive" flag shared by duration-reporting tools
+func timeModeArg(request mcp.CallToolRequest) analyzer.TimeMode {
+	if exclusive, ok := request.Params.Arguments["exclusive"].(bool); ok && exclusive {
+		return analyzer.Exclusive
+	}
+	return analyzer.Inclusive
+}
+
+// timeBudgetOption declares the "time_budget" parameter shared by analyses that can
+// be slow on huge profiles
+func timeBudgetOption() mcp.ToolOption {
+	return mcp.WithString("time_budget",
+		mcp.Description("Longest time the analysis may take, as a Go duration (e.g. \"2s\"); when it runs out, the result built so far is returned with timed_out: true (default: no limit)"),
+	)
+}
+
+// timeBudgetArg reads the "time_budget" parameter and returns ctx bounded by it. The
+// returned cancel func must be called once the analysis is done.
+func timeBudgetArg(ctx context.Context, request mcp.CallToolRequest) (context.Context, context.CancelFunc, *mcp.CallToolResult) {
+	b, ok := request.Params.Arguments["time_budget"].(string)
+	if !ok || b == "" {
+		return ctx, func() {}, nil
+	}
+	budget, err := time.ParseDuration(b)
+	if err != nil || budget <= 0 {
+		return ctx, func() {}, mcp.NewToolResultError(fmt.Sprintf("Invalid time_budget: %s", b))
+	}
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	return ctx, cancel, nil
+}
+
+// percentBaseArg reads the "percent_base" parameter shared by hotspot and issue tools
+func percentBaseArg(request mcp.CallToolRequest) (analyzer.PercentBase, *mcp.CallToolResult) {
+	base, ok := request.Params.Arguments["percent_base"].(string)
+	if !ok || base == "" {
+		return analyzer.PercentOfCapture, nil
+	}
+	parsed, err := analyzer.ParsePercentBase(base)
+	if err != nil {
+		return analyzer.PercentOfCapture, mcp.NewToolResultError(err.Error())
+	}
+	return parsed, nil
+}
+
+// openProfileCache opens the parse cache, honoring EASYPROFILER_CACHE_DIR if set
+func openProfileCache() (*parser.Cache, error) {
+	dir := os.Getenv("EASYPROFILER_CACHE_DIR")
+	if dir == "" {
+		var err error
+		dir, err = parser.DefaultCacheDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parser.NewCache(dir)
 }
@@ -0,0 +1,3746 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/pprof/profile"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yourusername/easyprofiler-mcp/analyzer"
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// resetRegistry unloads every profile and restores the output format, now and
+// when the test ends
+func resetRegistry(t *testing.T) {
+	t.Helper()
+	savedFormat := outputFormat
+	reset := func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		loadedProfiles = make(map[string]*loadedProfile)
+		lastLoadByPath = make(map[string]string)
+		nextProfileID = 1
+		setCurrentProfile(nil)
+		outputFormat = savedFormat
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// loadTestProfile writes the capture and loads it through load_profile with the
+// extra arguments, returning the load summary
+func loadTestProfile(t *testing.T, p *proftest.Profile, args map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	if args == nil {
+		args = make(map[string]interface{})
+	}
+	args["file_path"] = p.WriteFile(t)
+	return callToolJSON(t, loadProfileHandler, args)
+}
+
+// callTool runs handler with the arguments and returns the result text and
+// whether it was reported as a tool error
+func callTool(t *testing.T, handler server.ToolHandlerFunc, args map[string]interface{}) (string, bool) {
+	t.Helper()
+	var request mcp.CallToolRequest
+	request.Params.Arguments = args
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handler returned an error: %v", err)
+	}
+	if len(result.Content) != 1 {
+		t.Fatalf("result has %d content items, want 1", len(result.Content))
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("result content is %T, want text", result.Content[0])
+	}
+	return text.Text, result.IsError
+}
+
+// callToolJSON runs handler, failing the test on a tool error, and decodes its JSON object result
+func callToolJSON(t *testing.T, handler server.ToolHandlerFunc, args map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	text, isError := callTool(t, handler, args)
+	if isError {
+		t.Fatalf("tool error: %s", text)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &result); err != nil {
+		t.Fatalf("result is not a JSON object: %v\n%s", err, text)
+	}
+	return result
+}
+
+// callToolList runs handler, failing the test on a tool error, and decodes its JSON list result
+func callToolList(t *testing.T, handler server.ToolHandlerFunc, args map[string]interface{}) []map[string]interface{} {
+	t.Helper()
+	text, isError := callTool(t, handler, args)
+	if isError {
+		t.Fatalf("tool error: %s", text)
+	}
+	var result []map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &result); err != nil {
+		t.Fatalf("result is not a JSON list of objects: %v\n%s", err, text)
+	}
+	return result
+}
+
+// list returns result[key] as a slice of JSON objects, failing the test if it isn't one
+func list(t *testing.T, result map[string]interface{}, key string) []map[string]interface{} {
+	t.Helper()
+	raw, ok := result[key].([]interface{})
+	if !ok {
+		t.Fatalf("%s is %T, want a list", key, result[key])
+	}
+	items := make([]map[string]interface{}, len(raw))
+	for i, item := range raw {
+		if items[i], ok = item.(map[string]interface{}); !ok {
+			t.Fatalf("%s[%d] is %T, want an object", key, i, item)
+		}
+	}
+	return items
+}
+
+func TestHandlersRequireLoadedProfile(t *testing.T) {
+	resetRegistry(t)
+
+	tests := []struct {
+		name    string
+		handler server.ToolHandlerFunc
+	}{
+		{"get_slowest_blocks", getSlowestBlocksHandler},
+		{"get_thread_statistics", getThreadStatisticsHandler},
+		{"get_hotspots", getHotspotsHandler},
+		{"analyze_performance_issues", analyzePerformanceIssuesHandler},
+		{"get_startup_cost", getStartupCostHandler},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, isError := callTool(t, tt.handler, nil)
+			if !isError {
+				t.Errorf("succeeded without a loaded profile: %s", text)
+			}
+		})
+	}
+}
+
+func TestGetStartupCostHandler(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Init", "Ready"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 1000},
+			{ID: 2, Begin: 1000, End: 4000},
+		}}},
+	}, nil)
+	outputFormat.Raw = true
+
+	tests := []struct {
+		name         string
+		args         map[string]interface{}
+		wantErr      bool
+		wantDuration float64
+		wantPercent  float64
+	}{
+		{"first block", nil, false, 1000, 0.25},
+		{"marker", map[string]interface{}{"marker": "Ready"}, false, 1000, 0.25},
+		{"missing marker", map[string]interface{}{"marker": "Nope"}, true, 0, 0},
+		{"negative limit", map[string]interface{}{"limit": float64(-1)}, true, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, getStartupCostHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+			result := callToolJSON(t, getStartupCostHandler, tt.args)
+			if got := result["startup_duration_ns"]; got != tt.wantDuration {
+				t.Errorf("startup_duration_ns = %v, want %v", got, tt.wantDuration)
+			}
+			if got := result["percent_of_total"]; got != tt.wantPercent {
+				t.Errorf("percent_of_total = %v, want %v", got, tt.wantPercent)
+			}
+			if contributors := list(t, result, "top_contributors"); len(contributors) == 0 || contributors[0]["name"] != "Init" {
+				t.Errorf("top_contributors = %v, want Init first", contributors)
+			}
+		})
+	}
+}
+
+func TestLoadProfileReportsSanitizedNames(t *testing.T) {
+	tests := []struct {
+		name      string
+		blockName string
+		want      interface{}
+	}{
+		{"valid names", "Update", nil},
+		{"invalid UTF-8", "Up\xffdate", float64(2)}, // In the name and the derived file
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			summary := loadTestProfile(t, &proftest.Profile{
+				Descriptors: proftest.Descriptors(tt.blockName),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 100}}}},
+			}, nil)
+			if got := summary["sanitized_names"]; got != tt.want {
+				t.Errorf("sanitized_names = %v, want %v", got, tt.want)
+			}
+			if _, warned := summary["warning"]; warned != (tt.want != nil) {
+				t.Errorf("warning present = %t", warned)
+			}
+		})
+	}
+}
+
+func TestGetHotPathForThreadHandler(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{{ID: 7, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 100, End: 600},
+			{ID: 1, Begin: 0, End: 1000},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantErr   bool
+		wantNames []interface{}
+		wantRaw   bool
+	}{
+		{"by name", map[string]interface{}{"thread": "Main"}, false, []interface{}{"Frame", "Update"}, false},
+		{"by ID", map[string]interface{}{"thread": "7"}, false, []interface{}{"Frame", "Update"}, false},
+		{"raw timestamps", map[string]interface{}{"thread": "Main", "raw_timestamps": true}, false, []interface{}{"Frame", "Update"}, true},
+		{"missing thread argument", nil, true, nil, false},
+		{"unknown thread", map[string]interface{}{"thread": "Nope"}, true, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, getHotPathForThreadHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+			path := list(t, callToolJSON(t, getHotPathForThreadHandler, tt.args), "path")
+			var names []interface{}
+			for _, node := range path {
+				names = append(names, node["name"])
+				if _, ok := node["begin_ticks"]; ok != tt.wantRaw {
+					t.Errorf("raw timestamps present = %t, want %t", ok, tt.wantRaw)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("path = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestLoadProfileMinBlockDuration(t *testing.T) {
+	capture := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Tiny"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 100, End: 200},
+			{ID: 1, Begin: 0, End: 5000},
+		}}},
+	}
+
+	tests := []struct {
+		name        string
+		minUs       interface{}
+		wantBlocks  float64
+		wantDropped interface{}
+	}{
+		{"not set", nil, 2, nil},
+		{"zero keeps all", float64(0), 2, nil},
+		{"drops short blocks", float64(1), 1, float64(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			args := map[string]interface{}{}
+			if tt.minUs != nil {
+				args["min_block_duration_us"] = tt.minUs
+			}
+			summary := loadTestProfile(t, capture, args)
+			if summary["blocks_count"] != tt.wantBlocks {
+				t.Errorf("blocks_count = %v, want %v", summary["blocks_count"], tt.wantBlocks)
+			}
+			if summary["dropped_blocks"] != tt.wantDropped {
+				t.Errorf("dropped_blocks = %v, want %v", summary["dropped_blocks"], tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestGetOverviewHandler(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 400}, {ID: 1, Begin: 0, End: 1000}}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 500}}},
+		},
+	}, nil)
+	outputFormat.Raw = true
+
+	result := callToolJSON(t, getOverviewHandler, nil)
+
+	tests := []struct {
+		key  string
+		want interface{}
+	}{
+		{"parallelism", 1.5},
+		{"coverage", 1.0},
+		{"total_duration", float64(1000)},
+	}
+	for _, tt := range tests {
+		if got := result[tt.key]; got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	if busiest, _ := result["busiest_thread"].(map[string]interface{}); busiest["thread_name"] != "Main" {
+		t.Errorf("busiest_thread = %v, want Main", result["busiest_thread"])
+	}
+	if top := list(t, result, "top_self_time"); len(top) != 2 || top[0]["name"] != "Update" {
+		t.Errorf("top_self_time = %v, want Update first", top)
+	}
+}
+
+func TestGetValueSummaryHandler(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: []proftest.Descriptor{
+			{ID: 1, Name: "Frame", Type: parser.BlockTypeBlock},
+			{ID: 2, Name: "count", Type: parser.BlockTypeValue},
+		},
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10, End: 1, Payload: proftest.Int32Value(3)},
+			{ID: 2, Begin: 20, End: 1, Payload: proftest.Int32Value(4)},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}, nil)
+
+	summaries := callToolList(t, getValueSummaryHandler, nil)
+	want := map[string]interface{}{"name": "count", "type": "int32", "sample_count": 2.0, "avg": 3.5, "sum": 7.0, "min": 3.0, "max": 4.0, "last": 4.0}
+	if len(summaries) != 1 || !reflect.DeepEqual(summaries[0], want) {
+		t.Errorf("summaries = %v, want [%v]", summaries, want)
+	}
+}
+
+func TestGetValueSummaryHandlerMinBlockDuration(t *testing.T) {
+	// Two count values inside Frame and one inside the 10ns Tiny block
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: []proftest.Descriptor{
+			{ID: 1, Name: "Frame", Type: parser.BlockTypeBlock},
+			{ID: 2, Name: "count", Type: parser.BlockTypeValue},
+			{ID: 3, Name: "Tiny", Type: parser.BlockTypeBlock},
+		},
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10, End: 1, Payload: proftest.Int32Value(3)},
+			{ID: 2, Begin: 20, End: 1, Payload: proftest.Int32Value(4)},
+			{ID: 2, Begin: 35, End: 1, Payload: proftest.Int32Value(100)},
+			{ID: 3, Begin: 30, End: 40},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}, map[string]interface{}{"min_block_duration_us": 0.05})
+
+	summaries := callToolList(t, getValueSummaryHandler, nil)
+	if len(summaries) != 1 || summaries[0]["sample_count"] != 2.0 || summaries[0]["max"] != 4.0 {
+		t.Errorf("summaries = %v, want the two values inside Frame", summaries)
+	}
+}
+
+func TestAnalyzePerformanceIssuesHandlerCutoffs(t *testing.T) {
+	resetRegistry(t)
+	ms := uint64(time.Millisecond)
+	loadTestProfile(t, &proftest.Profile{
+		Begin: 0, End: 1000 * ms,
+		Descriptors: proftest.Descriptors("Load"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 300 * ms}}}},
+	}, nil)
+
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		severity string
+	}{
+		{"default cutoffs", nil, "high"},
+		{"raised high cutoff", map[string]interface{}{"high_cutoff": float64(70)}, "medium"},
+		{"raised both cutoffs", map[string]interface{}{"high_cutoff": float64(95), "medium_cutoff": float64(90)}, "low"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grouped, _ := callToolJSON(t, analyzePerformanceIssuesHandler, tt.args)["by_severity"].(map[string]interface{})
+			found := false
+			for _, item := range list(t, grouped, tt.severity) {
+				if item["type"] == "Long Blocking Operation" {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("no Long Blocking Operation rated %s in %v", tt.severity, grouped)
+			}
+		})
+	}
+}
+
+func TestAnalyzePerformanceIssuesHandlerFilters(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Load"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: uint64(300 * time.Millisecond)}}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: uint64(time.Millisecond)}}},
+		},
+	}, nil)
+
+	shown := func(result map[string]interface{}) map[string]int {
+		counts := make(map[string]int)
+		grouped, _ := result["by_severity"].(map[string]interface{})
+		for _, severity := range []string{"high", "medium", "low"} {
+			if _, ok := grouped[severity]; ok {
+				counts[severity] = len(list(t, grouped, severity))
+			}
+		}
+		return counts
+	}
+	all := shown(callToolJSON(t, analyzePerformanceIssuesHandler, nil))
+	total := all["high"] + all["medium"] + all["low"]
+	if all["high"] == 0 || total < 2 {
+		t.Fatalf("capture has issues %v, want a high one and at least two in all", all)
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr bool
+		shown   int
+	}{
+		{"no filters", map[string]interface{}{}, false, total},
+		{"high only", map[string]interface{}{"min_severity": "high"}, false, all["high"]},
+		{"nothing rated high", map[string]interface{}{"min_severity": "high", "high_cutoff": 101.0}, false, 0},
+		{"capped", map[string]interface{}{"max_issues": 1.0}, false, 1},
+		{"zero cap", map[string]interface{}{"max_issues": 0.0}, false, total},
+		{"invalid severity", map[string]interface{}{"min_severity": "severe"}, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, analyzePerformanceIssuesHandler, tt.args); !isError || !strings.Contains(text, "Invalid min_severity") {
+					t.Errorf("result = %q (error %t), want an invalid min_severity error", text, isError)
+				}
+				return
+			}
+
+			result := callToolJSON(t, analyzePerformanceIssuesHandler, tt.args)
+			counts := shown(result)
+			if got := counts["high"] + counts["medium"] + counts["low"]; got != tt.shown {
+				t.Errorf("listed %d issues %v, want %d", got, counts, tt.shown)
+			}
+			if tt.shown == total {
+				if _, ok := result["omitted_issues"]; ok {
+					t.Errorf("omitted_issues = %v with nothing omitted", result["omitted_issues"])
+				}
+				return
+			}
+			if result["shown_issues"] != float64(tt.shown) || result["omitted_issues"] != float64(total-tt.shown) {
+				t.Errorf("shown_issues = %v, omitted_issues = %v; want %d and %d", result["shown_issues"], result["omitted_issues"], tt.shown, total-tt.shown)
+			}
+		})
+	}
+}
+
+func TestAnalyzePerformanceIssuesHandlerSuggestions(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Load"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: uint64(300 * time.Millisecond)}}}},
+	}, nil)
+
+	grouped, _ := callToolJSON(t, analyzePerformanceIssuesHandler, nil)["by_severity"].(map[string]interface{})
+	for _, severity := range []string{"high", "medium", "low"} {
+		for _, item := range list(t, grouped, severity) {
+			if suggestion, _ := item["suggestion"].(string); suggestion == "" {
+				t.Errorf("%v issue has no suggestion", item["type"])
+			}
+		}
+	}
+}
+
+func TestRawTimestamps(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		CPUFrequency: 3_000_000_000,
+		Descriptors:  proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 1200, End: 1500},
+			{ID: 1, Begin: 1000, End: 2000},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want []interface{} // begin_ticks, end_ticks and cpu_frequency of the slowest block
+	}{
+		{"off by default", nil, []interface{}{nil, nil, nil}},
+		{"off", map[string]interface{}{"raw_timestamps": false}, []interface{}{nil, nil, nil}},
+		{"on", map[string]interface{}{"raw_timestamps": true}, []interface{}{1000.0, 2000.0, 3e9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := callToolList(t, getSlowestBlocksHandler, tt.args)
+			if len(blocks) != 2 {
+				t.Fatalf("got %d blocks, want 2", len(blocks))
+			}
+			got := []interface{}{blocks[0]["begin_ticks"], blocks[0]["end_ticks"], blocks[0]["cpu_frequency"]}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("raw timestamps = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetThreadDependencyGraphHandler(t *testing.T) {
+	capture := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Work"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", ContextSwitches: []proftest.ContextSwitch{{ThreadID: 2, Begin: 0, End: 100}},
+				Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}},
+			{ID: 2, Name: "Worker"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		loadArgs  map[string]interface{}
+		wantErr   bool
+		wantEdges int
+	}{
+		{"context switches loaded", nil, false, 1},
+		{"fast mode skips them", map[string]interface{}{"fast_mode": true}, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, capture, tt.loadArgs)
+			if tt.wantErr {
+				if text, isError := callTool(t, getThreadDependencyGraphHandler, nil); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+			result := callToolJSON(t, getThreadDependencyGraphHandler, nil)
+			if edges := list(t, result, "edges"); len(edges) != tt.wantEdges {
+				t.Errorf("got %d edges, want %d", len(edges), tt.wantEdges)
+			}
+		})
+	}
+}
+
+func TestExportPprofHandler(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 100, End: 500},
+			{ID: 1, Begin: 0, End: 1000},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr bool
+	}{
+		{"writes the file", map[string]interface{}{"output_path": filepath.Join(t.TempDir(), "cpu.pb.gz")}, false},
+		{"missing output path", nil, true},
+		{"unwritable output path", map[string]interface{}{"output_path": filepath.Join(t.TempDir(), "missing", "cpu.pb.gz")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, exportPprofHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+
+			result := callToolJSON(t, exportPprofHandler, tt.args)
+			file, err := os.Open(tt.args["output_path"].(string))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			prof, err := profile.Parse(file)
+			if err != nil {
+				t.Fatalf("profile.Parse: %v", err)
+			}
+			if float64(len(prof.Sample)) != result["samples_count"] || float64(len(prof.Function)) != result["functions_count"] {
+				t.Errorf("file has %d samples and %d functions, result reports %v and %v",
+					len(prof.Sample), len(prof.Function), result["samples_count"], result["functions_count"])
+			}
+		})
+	}
+}
+
+func TestExportSpeedscopeHandler(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 100, End: 500},
+			{ID: 1, Begin: 0, End: 1000},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr bool
+	}{
+		{"writes the file", map[string]interface{}{"output_path": filepath.Join(t.TempDir(), "trace.speedscope.json")}, false},
+		{"missing output path", nil, true},
+		{"unwritable output path", map[string]interface{}{"output_path": filepath.Join(t.TempDir(), "missing", "trace.json")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, exportSpeedscopeHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+
+			result := callToolJSON(t, exportSpeedscopeHandler, tt.args)
+			data, err := os.ReadFile(tt.args["output_path"].(string))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var file analyzer.SpeedscopeFile
+			if err := json.Unmarshal(data, &file); err != nil {
+				t.Fatalf("file is not JSON: %v", err)
+			}
+			if file.Name != "capture.prof" {
+				t.Errorf("document name = %q, want the capture file name", file.Name)
+			}
+			if len(file.Profiles) != 1 || result["threads_count"] != 1.0 || result["frames_count"] != 2.0 || result["events_count"] != 4.0 {
+				t.Errorf("result %v doesn't describe a file with %d profiles", result, len(file.Profiles))
+			}
+		})
+	}
+}
+
+func TestGetBlockHeatmapHandler(t *testing.T) {
+	resetRegistry(t)
+	us := uint64(time.Microsecond)
+	loadTestProfile(t, &proftest.Profile{
+		Begin:       0,
+		End:         100 * us,
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10 * us, End: 60 * us},
+			{ID: 1, Begin: 0, End: 100 * us},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr bool
+		rows    int
+		columns int
+	}{
+		{"defaults", map[string]interface{}{}, false, 2, 20},
+		{"limited", map[string]interface{}{"limit": 1.0, "buckets": 4.0}, false, 1, 4},
+		{"zero buckets", map[string]interface{}{"buckets": 0.0}, true, 0, 0},
+		{"too many buckets", map[string]interface{}{"buckets": 1e9}, true, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, getBlockHeatmapHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getBlockHeatmapHandler, tt.args)
+			cells, _ := result["cells_us"].([]interface{})
+			if len(list(t, result, "functions")) != tt.rows || len(cells) != tt.rows {
+				t.Fatalf("got %d functions and %d cell rows, want %d", len(list(t, result, "functions")), len(cells), tt.rows)
+			}
+			starts, _ := result["bucket_starts"].([]interface{})
+			if row, _ := cells[0].([]interface{}); len(row) != tt.columns || len(starts) != tt.columns {
+				t.Errorf("got %d cells and %d bucket starts, want %d", len(row), len(starts), tt.columns)
+			}
+		})
+	}
+
+	// Frame is busy throughout: each of 4 buckets holds 25µs
+	result := callToolJSON(t, getBlockHeatmapHandler, map[string]interface{}{"limit": 1.0, "buckets": 4.0})
+	if got, want := result["cells_us"], []interface{}{[]interface{}{25.0, 25.0, 25.0, 25.0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cells_us = %v, want %v", got, want)
+	}
+}
+
+func TestGetDurationHistogramHandler(t *testing.T) {
+	resetRegistry(t)
+	ms := uint64(time.Millisecond)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Update"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 2 * ms},
+			{ID: 1, Begin: 10 * ms, End: 15 * ms},
+			{ID: 1, Begin: 20 * ms, End: 70 * ms},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name       string
+		args       map[string]interface{}
+		wantErr    bool
+		wantRanges []interface{}
+		wantCounts []interface{}
+	}{
+		{"default buckets", map[string]interface{}{"name": "Update"}, false,
+			[]interface{}{"<1ms", "1ms-10ms", "10ms-100ms", ">=100ms"}, []interface{}{0.0, 2.0, 1.0, 0.0}},
+		{"custom buckets", map[string]interface{}{"name": "Update", "buckets": "3ms, 40ms"}, false,
+			[]interface{}{"<3ms", "3ms-40ms", ">=40ms"}, []interface{}{1.0, 1.0, 1.0}},
+		{"invalid buckets", map[string]interface{}{"name": "Update", "buckets": "3 parsecs"}, true, nil, nil},
+		{"unknown function", map[string]interface{}{"name": "Missing"}, true, nil, nil},
+		{"missing name", nil, true, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, getDurationHistogramHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+			var ranges, counts []interface{}
+			for _, bucket := range list(t, callToolJSON(t, getDurationHistogramHandler, tt.args), "buckets") {
+				ranges = append(ranges, bucket["range"])
+				counts = append(counts, bucket["count"])
+			}
+			if !reflect.DeepEqual(ranges, tt.wantRanges) || !reflect.DeepEqual(counts, tt.wantCounts) {
+				t.Errorf("buckets %v with counts %v, want %v and %v", ranges, counts, tt.wantRanges, tt.wantCounts)
+			}
+		})
+	}
+}
+
+func TestGetHotspotsHandlerUnknownAndZeroDuration(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: []proftest.Descriptor{{ID: 1, Line: 7}},
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 1000, End: 1000},
+		}}},
+	}, nil)
+
+	hotspots := callToolList(t, getHotspotsHandler, nil)
+	if len(hotspots) != 1 {
+		t.Fatalf("got %d hotspots, want 1", len(hotspots))
+	}
+	want := map[string]interface{}{"name": "(unnamed block #1)", "file": "(unknown file)", "percent_of_total": "0.00%"}
+	for key, value := range want {
+		if hotspots[0][key] != value {
+			t.Errorf("%s = %v, want %v", key, hotspots[0][key], value)
+		}
+	}
+}
+
+func TestRankingHandlersRejectNegativeLimit(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Update"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}}}},
+	}, nil)
+
+	tests := []struct {
+		name    string
+		handler server.ToolHandlerFunc
+	}{
+		{"get_slowest_blocks", getSlowestBlocksHandler},
+		{"get_hotspots", getHotspotsHandler},
+		{"get_most_called", getMostCalledHandler},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if text, isError := callTool(t, tt.handler, map[string]interface{}{"limit": -1.0}); !isError {
+				t.Errorf("negative limit succeeded: %s", text)
+			}
+			if _, isError := callTool(t, tt.handler, map[string]interface{}{"limit": 0.0}); isError {
+				t.Error("zero limit failed")
+			}
+		})
+	}
+}
+
+func TestGetCrossThreadLatencyHandler(t *testing.T) {
+	resetRegistry(t)
+	ms := uint64(time.Millisecond)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Job"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Producer", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10 * ms}, {ID: 1, Begin: 100 * ms, End: 110 * ms}}},
+			{ID: 2, Name: "Consumer", Blocks: []proftest.Block{{ID: 1, Begin: 12 * ms, End: 20 * ms}, {ID: 1, Begin: 115 * ms, End: 150 * ms}}},
+		},
+	}, nil)
+
+	tests := []struct {
+		name       string
+		args       map[string]interface{}
+		wantErr    string
+		operations float64
+		slowest    int
+		raw        bool
+	}{
+		{"defaults", map[string]interface{}{"name": "Job"}, "", 2, 2, false},
+		{"limited", map[string]interface{}{"name": "Job", "limit": 1.0}, "", 2, 1, false},
+		{"zero limit", map[string]interface{}{"name": "Job", "limit": 0.0}, "", 2, 0, false},
+		{"tight gap", map[string]interface{}{"name": "Job", "max_gap": "1ms"}, "", 0, 0, false},
+		{"raw timestamps", map[string]interface{}{"name": "Job", "raw_timestamps": true}, "", 2, 2, true},
+		{"negative limit", map[string]interface{}{"name": "Job", "limit": -1.0}, "limit must not be negative", 0, 0, false},
+		{"invalid gap", map[string]interface{}{"name": "Job", "max_gap": "soon"}, "Invalid max_gap", 0, 0, false},
+		{"missing name", map[string]interface{}{}, "name parameter is required", 0, 0, false},
+		{"unknown name", map[string]interface{}{"name": "Missing"}, "Missing", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr != "" {
+				if text, isError := callTool(t, getCrossThreadLatencyHandler, tt.args); !isError || !strings.Contains(text, tt.wantErr) {
+					t.Errorf("result = %q (error %t), want an error containing %q", text, isError, tt.wantErr)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getCrossThreadLatencyHandler, tt.args)
+			if result["operations_count"] != tt.operations {
+				t.Errorf("operations_count = %v, want %v", result["operations_count"], tt.operations)
+			}
+			slowest := list(t, result, "slowest")
+			if len(slowest) != tt.slowest {
+				t.Fatalf("listed %d operations, want %d", len(slowest), tt.slowest)
+			}
+			for _, operation := range slowest {
+				stage := list(t, operation, "stages")[0]
+				if _, ok := stage["begin_ticks"]; ok != tt.raw {
+					t.Errorf("stage %v has raw timestamps = %t, want %t", stage, ok, tt.raw)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMemoryEstimateHandler(t *testing.T) {
+	resetRegistry(t)
+	capture := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Update"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: proftest.Nested(1, 0, 50)}},
+	}
+	loadTestProfile(t, capture, nil)
+	loadTestProfile(t, capture, nil)
+
+	result := callToolJSON(t, getMemoryEstimateHandler, nil)
+	want := currentProfile.EstimateMemory().TotalBytes
+	if result["estimated_heap_bytes"] != float64(want) || want <= 0 {
+		t.Errorf("estimated_heap_bytes = %v, want %d", result["estimated_heap_bytes"], want)
+	}
+	if result["loaded_profiles"] != 2.0 || result["profile_id"] != "p2" {
+		t.Errorf("loaded_profiles = %v, profile_id = %v; want 2 and p2", result["loaded_profiles"], result["profile_id"])
+	}
+}
+
+func TestGetSlowestBlocksHandlerDistinct(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Update", "Render"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 50},
+			{ID: 1, Begin: 100, End: 140},
+			{ID: 2, Begin: 200, End: 230},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name      string
+		distinct  bool
+		want      []interface{}
+		instances []interface{}
+	}{
+		{"every block", false, []interface{}{"Update", "Update", "Render"}, []interface{}{nil, nil, nil}},
+		{"distinct", true, []interface{}{"Update", "Render"}, []interface{}{2.0, 1.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names, instances []interface{}
+			for _, block := range callToolList(t, getSlowestBlocksHandler, map[string]interface{}{"distinct": tt.distinct}) {
+				names = append(names, block["name"])
+				instances = append(instances, block["instances"])
+			}
+			if !reflect.DeepEqual(names, tt.want) || !reflect.DeepEqual(instances, tt.instances) {
+				t.Errorf("blocks %v with instances %v, want %v with %v", names, instances, tt.want, tt.instances)
+			}
+		})
+	}
+}
+
+func TestGetMostCalledHandler(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Tick", "Load"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 10},
+			{ID: 1, Begin: 10, End: 20},
+			{ID: 1, Begin: 20, End: 30},
+			{ID: 2, Begin: 100, End: 1100},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name  string
+		args  map[string]interface{}
+		want  []interface{}
+		calls []interface{}
+	}{
+		{"default limit", map[string]interface{}{}, []interface{}{"Tick", "Load"}, []interface{}{3.0, 1.0}},
+		{"limited", map[string]interface{}{"limit": 1.0}, []interface{}{"Tick"}, []interface{}{3.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names, calls []interface{}
+			for _, function := range callToolList(t, getMostCalledHandler, tt.args) {
+				names = append(names, function["name"])
+				calls = append(calls, function["call_count"])
+			}
+			if !reflect.DeepEqual(names, tt.want) || !reflect.DeepEqual(calls, tt.calls) {
+				t.Errorf("functions %v with calls %v, want %v with %v", names, calls, tt.want, tt.calls)
+			}
+		})
+	}
+}
+
+func TestGetBlockPathHandler(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: []proftest.Descriptor{
+			{ID: 1, Name: "Parse", File: "json.cpp", Line: 10, Color: 0xFFFF0000, Type: parser.BlockTypeBlock},
+			{ID: 2, Name: "Parse", File: "xml.cpp", Line: 20, Type: parser.BlockTypeEvent},
+			{ID: 3, Line: 30, Type: parser.BlockTypeBlock},
+		},
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 100},
+			{ID: 2, Begin: 200, End: 200},
+			{ID: 3, Begin: 300, End: 310},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name    string
+		block   interface{}
+		wantErr string
+		want    map[string]interface{}
+		matches int
+	}{
+		{"by ID", "1", "", map[string]interface{}{"name": "Parse", "file": "json.cpp", "color": "#FF0000", "type": "block", "call_count": 1.0}, 0},
+		{"ambiguous name", "Parse", "", nil, 2},
+		{"anonymous", "3", "", map[string]interface{}{"name": "(unnamed block #3)", "file": "(unknown file)", "type": "block", "call_count": 1.0}, 0},
+		{"unknown", "Render", "not found", nil, 0},
+		{"missing", nil, "block parameter is required", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]interface{}{}
+			if tt.block != nil {
+				args["block"] = tt.block
+			}
+			if tt.wantErr != "" {
+				if text, isError := callTool(t, getBlockPathHandler, args); !isError || !strings.Contains(text, tt.wantErr) {
+					t.Errorf("result = %q (error %t), want an error containing %q", text, isError, tt.wantErr)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getBlockPathHandler, args)
+			if tt.matches > 0 {
+				if got := len(list(t, result, "matches")); got != tt.matches {
+					t.Errorf("got %d matches, want %d", got, tt.matches)
+				}
+				return
+			}
+			for key, want := range tt.want {
+				if !reflect.DeepEqual(result[key], want) {
+					t.Errorf("%s = %#v, want %#v", key, result[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetThreadAffinityReportHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		switches  []string
+		available bool
+		threads   int
+	}{
+		{"no core data", []string{"game.exe"}, false, 0},
+		{"core data", []string{"CPU 0", "CPU 1"}, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			thread := proftest.Thread{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}}
+			for i, name := range tt.switches {
+				thread.ContextSwitches = append(thread.ContextSwitches, proftest.ContextSwitch{ThreadID: 1, Begin: uint64(i) * 100, End: uint64(i)*100 + 10, Name: name})
+			}
+			loadTestProfile(t, &proftest.Profile{Descriptors: proftest.Descriptors("Run"), Threads: []proftest.Thread{thread}}, nil)
+
+			result := callToolJSON(t, getThreadAffinityReportHandler, nil)
+			if result["available"] != tt.available {
+				t.Fatalf("available = %v, want %t", result["available"], tt.available)
+			}
+			if !tt.available {
+				if message, _ := result["message"].(string); message == "" {
+					t.Error("no explanation for the missing core data")
+				}
+				return
+			}
+			threads := list(t, result, "threads")
+			if len(threads) != tt.threads {
+				t.Fatalf("got %d threads, want %d", len(threads), tt.threads)
+			}
+			if threads[0]["migrations"] != 1.0 || threads[0]["dominant_core_share"] != "50.00%" {
+				t.Errorf("thread = %v, want 1 migration and a 50.00%% dominant share", threads[0])
+			}
+		})
+	}
+}
+
+func TestLoadProfileAnonymousBlocks(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", ""),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10, End: 40},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}
+
+	tests := []struct {
+		policy   interface{}
+		want     string
+		hotspots int
+		wantErr  bool
+	}{
+		{nil, "descriptor", 2, false},
+		{"parent", "parent", 1, false},
+		{"exclude", "exclude", 1, false},
+		{"sideways", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.policy), func(t *testing.T) {
+			resetRegistry(t)
+			args := map[string]interface{}{"file_path": p.WriteFile(t)}
+			if tt.policy != nil {
+				args["anonymous_blocks"] = tt.policy
+			}
+			if tt.wantErr {
+				if text, isError := callTool(t, loadProfileHandler, args); !isError || !strings.Contains(text, "unknown anonymous block policy") {
+					t.Errorf("result = %q (error %t), want an unknown policy error", text, isError)
+				}
+				return
+			}
+
+			summary := callToolJSON(t, loadProfileHandler, args)
+			if summary["anonymous_blocks"] != tt.want {
+				t.Errorf("anonymous_blocks = %v, want %s", summary["anonymous_blocks"], tt.want)
+			}
+			if hotspots := callToolList(t, getHotspotsHandler, nil); len(hotspots) != tt.hotspots {
+				t.Errorf("got %d hotspots, want %d", len(hotspots), tt.hotspots)
+			}
+		})
+	}
+}
+
+func TestGetCaptureMetadataHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     uint32
+		wantVersion string
+		wantCounts  bool
+	}{
+		{"v2.1", parser.Version210, "2.1.0", true},
+		// Older headers have no thread and bookmark counts
+		{"v1.3", parser.Version130, "1.3.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, &proftest.Profile{
+				PID:         42,
+				Descriptors: proftest.Descriptors("Frame"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 1000, End: 3000}}}},
+				Bookmarks:   []proftest.Bookmark{{Position: 1500, Text: "spike"}},
+			}, nil)
+			currentProfile.(*parser.ProfileData).Header.Version = tt.version
+
+			result := callToolJSON(t, getCaptureMetadataHandler, nil)
+			want := map[string]interface{}{
+				"version":         tt.wantVersion,
+				"version_raw":     fmt.Sprintf("0x%08X", tt.version),
+				"pid":             42.0,
+				"cpu_frequency":   1e9,
+				"begin_timestamp": 1000.0,
+				"end_timestamp":   3000.0,
+				"blocks_count":    1.0,
+			}
+			for key, value := range want {
+				if result[key] != value {
+					t.Errorf("%s = %v, want %v", key, result[key], value)
+				}
+			}
+			_, hasThreads := result["threads_count"]
+			_, hasBookmarks := result["bookmarks_count"]
+			if hasThreads != tt.wantCounts || hasBookmarks != tt.wantCounts {
+				t.Errorf("threads_count present %t, bookmarks_count present %t; want %t", hasThreads, hasBookmarks, tt.wantCounts)
+			}
+		})
+	}
+}
+
+func TestLoadProfileThreadSelection(t *testing.T) {
+	p := &proftest.Profile{Descriptors: proftest.Descriptors("Run")}
+	for i, name := range []string{"Main", "Render", "Worker"} {
+		p.Threads = append(p.Threads, proftest.Thread{ID: uint64(i + 1), Name: name, Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 100}}})
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		threads float64
+		skipped interface{}
+		wantErr bool
+	}{
+		{"all threads", map[string]interface{}{}, 3, nil, false},
+		{"filtered", map[string]interface{}{"thread_filter": "Render|Worker"}, 2, 1.0, false},
+		{"max threads", map[string]interface{}{"max_threads": 1.0}, 1, 2.0, false},
+		{"negative max threads loads all", map[string]interface{}{"max_threads": -1.0}, 3, nil, false},
+		{"invalid filter", map[string]interface{}{"thread_filter": "("}, 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			tt.args["file_path"] = p.WriteFile(t)
+			if tt.wantErr {
+				if text, isError := callTool(t, loadProfileHandler, tt.args); !isError || !strings.Contains(text, "invalid thread name filter") {
+					t.Errorf("result = %q (error %t), want an invalid filter error", text, isError)
+				}
+				return
+			}
+
+			summary := callToolJSON(t, loadProfileHandler, tt.args)
+			if summary["threads_count"] != tt.threads || summary["skipped_threads"] != tt.skipped {
+				t.Errorf("threads_count = %v, skipped_threads = %v; want %v and %v", summary["threads_count"], summary["skipped_threads"], tt.threads, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestGetPerSecondRateHandler(t *testing.T) {
+	resetRegistry(t)
+	ms := uint64(time.Millisecond)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: []proftest.Descriptor{{ID: 1, Name: "Present", File: "main.cpp", Type: parser.BlockTypeEvent}},
+		Begin:       0,
+		End:         2000 * ms,
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 100 * ms, End: 100 * ms},
+			{ID: 1, Begin: 1100 * ms, End: 1100 * ms},
+			{ID: 1, Begin: 1200 * ms, End: 1200 * ms},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr bool
+		counts  []interface{}
+	}{
+		{"default bucket", map[string]interface{}{"name": "Present"}, false, []interface{}{1.0, 2.0}},
+		{"custom bucket", map[string]interface{}{"name": "Present", "bucket": "500ms"}, false, []interface{}{1.0, 0.0, 2.0, 0.0}},
+		{"invalid bucket", map[string]interface{}{"name": "Present", "bucket": "soon"}, true, nil},
+		{"tiny bucket", map[string]interface{}{"name": "Present", "bucket": "1ns"}, true, nil},
+		{"missing name", nil, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, getPerSecondRateHandler, tt.args); !isError {
+					t.Errorf("succeeded: %.200s", text)
+				}
+				return
+			}
+			var counts []interface{}
+			for _, bucket := range list(t, callToolJSON(t, getPerSecondRateHandler, tt.args), "buckets") {
+				counts = append(counts, bucket["count"])
+			}
+			if !reflect.DeepEqual(counts, tt.counts) {
+				t.Errorf("bucket counts = %v, want %v", counts, tt.counts)
+			}
+		})
+	}
+}
+
+func TestPeekProfileHandler(t *testing.T) {
+	resetRegistry(t)
+	path := (&proftest.Profile{
+		PID:         42,
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: uint64(3 * time.Millisecond)}}}},
+	}).WriteFile(t)
+
+	result := callToolJSON(t, peekProfileHandler, map[string]interface{}{"file_path": path})
+	want := map[string]interface{}{
+		"version":           "2.1.0",
+		"pid":               42.0,
+		"total_duration":    "3ms",
+		"blocks_count":      1.0,
+		"descriptors_count": 2.0,
+		"threads_count":     1.0,
+		"bookmarks_count":   0.0,
+	}
+	for key, value := range want {
+		if result[key] != value {
+			t.Errorf("%s = %v, want %v", key, result[key], value)
+		}
+	}
+	if currentProfile != nil {
+		t.Error("peek_profile loaded the profile")
+	}
+
+	if text, isError := callTool(t, peekProfileHandler, map[string]interface{}{"file_path": filepath.Join(t.TempDir(), "missing.prof")}); !isError {
+		t.Errorf("missing file succeeded: %s", text)
+	}
+}
+
+func TestGetParentAtTimestampHandler(t *testing.T) {
+	resetRegistry(t)
+	us := uint64(time.Microsecond)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{{ID: 7, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 100 * us, End: 300 * us},
+			{ID: 1, Begin: 0, End: 500 * us},
+			{ID: 1, Begin: 800 * us, End: 1000 * us},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantErr   bool
+		state     string
+		innermost interface{}
+	}{
+		{"nested", map[string]interface{}{"thread": "Main", "offset": "150us"}, false, "active", "Update"},
+		{"by thread ID", map[string]interface{}{"thread": "7", "offset": "400us"}, false, "active", "Frame"},
+		{"idle", map[string]interface{}{"thread": "Main", "offset": "600us"}, false, "idle", nil},
+		{"negative offset", map[string]interface{}{"thread": "Main", "offset": "-1us"}, true, "", nil},
+		{"invalid offset", map[string]interface{}{"thread": "Main", "offset": "later"}, true, "", nil},
+		{"unknown thread", map[string]interface{}{"thread": "Render", "offset": "1us"}, true, "", nil},
+		{"missing offset", map[string]interface{}{"thread": "Main"}, true, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, getParentAtTimestampHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+			result := callToolJSON(t, getParentAtTimestampHandler, tt.args)
+			if result["state"] != tt.state || result["innermost"] != tt.innermost {
+				t.Errorf("state %v with innermost %v, want %s with %v", result["state"], result["innermost"], tt.state, tt.innermost)
+			}
+		})
+	}
+}
+
+func TestGetHotspotsHandlerTimeMode(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 0, End: 700},
+			{ID: 1, Begin: 0, End: 1000},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name      string
+		exclusive bool
+		want      []interface{}
+	}{
+		{"inclusive", false, []interface{}{"Frame inclusive 1µs", "Update inclusive 700ns"}},
+		{"exclusive", true, []interface{}{"Update exclusive 700ns", "Frame exclusive 300ns"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []interface{}
+			for _, hotspot := range callToolList(t, getHotspotsHandler, map[string]interface{}{"exclusive": tt.exclusive}) {
+				got = append(got, fmt.Sprintf("%s %s %s", hotspot["name"], hotspot["time_mode"], hotspot["total_duration"]))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hotspots = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProfileRejectsDeepNesting(t *testing.T) {
+	resetRegistry(t)
+	path := (&proftest.Profile{
+		Descriptors: proftest.Descriptors("Recurse"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: proftest.Nested(1, 0, parser.DefaultMaxTreeDepth+2)}},
+	}).WriteFile(t)
+
+	text, isError := callTool(t, loadProfileHandler, map[string]interface{}{"file_path": path})
+	if !isError || !strings.Contains(text, parser.ErrTreeTooDeep.Error()) {
+		t.Errorf("load_profile = %q, want a %q error", text, parser.ErrTreeTooDeep)
+	}
+	if currentProfile != nil {
+		t.Error("the rejected profile was loaded")
+	}
+}
+
+func TestGetHotspotsHandlerScaleSampled(t *testing.T) {
+	var blocks []proftest.Block
+	for i := uint64(0); i < 20; i++ {
+		blocks = append(blocks, proftest.Block{ID: 1, Begin: i * 100, End: i*100 + 50})
+	}
+	capture := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Update"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+	}
+
+	tests := []struct {
+		name      string
+		fastMode  bool
+		scale     bool
+		calls     float64
+		estimated interface{}
+	}{
+		{"full load", false, true, 20, nil},
+		{"fast mode", true, false, 2, nil},
+		{"fast mode scaled", true, true, 20, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			summary := loadTestProfile(t, capture, map[string]interface{}{"fast_mode": tt.fastMode})
+			if tt.fastMode && summary["sampling_factor"] != 10.0 {
+				t.Errorf("sampling_factor = %v, want 10", summary["sampling_factor"])
+			}
+
+			hotspot := callToolList(t, getHotspotsHandler, map[string]interface{}{"scale_sampled": tt.scale})[0]
+			if hotspot["call_count"] != tt.calls || hotspot["estimated"] != tt.estimated {
+				t.Errorf("call_count %v, estimated %v; want %v, %v", hotspot["call_count"], hotspot["estimated"], tt.calls, tt.estimated)
+			}
+		})
+	}
+}
+
+func TestLoadProfileReportsDepthLimitedBlocks(t *testing.T) {
+	// fast_mode keeps every 10th record, here levels 0, 10, ... 50 nested six deep,
+	// and drops blocks nested 5 or more levels deep
+	capture := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Recurse"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: proftest.Nested(1, 0, 60)}},
+	}
+
+	tests := []struct {
+		name         string
+		fastMode     bool
+		blocks       float64
+		depthLimited interface{}
+	}{
+		{"full load", false, 60, nil},
+		{"fast mode", true, 5, 1.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			summary := loadTestProfile(t, capture, map[string]interface{}{"fast_mode": tt.fastMode})
+			if summary["blocks_count"] != tt.blocks || summary["depth_limited_blocks"] != tt.depthLimited {
+				t.Errorf("blocks_count %v, depth_limited_blocks %v; want %v, %v",
+					summary["blocks_count"], summary["depth_limited_blocks"], tt.blocks, tt.depthLimited)
+			}
+		})
+	}
+}
+
+func TestExplainIssueHandler(t *testing.T) {
+	resetRegistry(t)
+	ms := uint64(time.Millisecond)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Load"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10 * ms, End: 160 * ms},
+			{ID: 1, Begin: 0, End: 170 * ms},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		wantErr  bool
+		function string
+	}{
+		{"by type and location", map[string]interface{}{"type": "Long Blocking Operation", "location": "Load.cpp:20"}, false, "Load"},
+		{"by index", map[string]interface{}{"index": 1.0}, false, ""},
+		{"no index or type", nil, true, ""},
+		{"negative index", map[string]interface{}{"index": -1.0}, true, ""},
+		{"index out of range", map[string]interface{}{"index": 99.0}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, explainIssueHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+			result := callToolJSON(t, explainIssueHandler, tt.args)
+			if tt.function == "" {
+				return
+			}
+			function, _ := result["function"].(map[string]interface{})
+			if function["name"] != tt.function {
+				t.Errorf("function = %v, want %s", result["function"], tt.function)
+			}
+			if callers := list(t, result, "callers"); len(callers) != 1 || callers[0]["name"] != "Frame" {
+				t.Errorf("callers = %v, want Frame", callers)
+			}
+			if thread, _ := result["thread"].(map[string]interface{}); thread["thread_name"] != "Main" {
+				t.Errorf("thread = %v, want Main", result["thread"])
+			}
+		})
+	}
+}
+
+func TestExplainIssueHandlerDetectionOptions(t *testing.T) {
+	// One long Load and seven short ones across Main and Worker in a 2s capture
+	ms := uint64(time.Millisecond)
+	blocks := []proftest.Block{
+		{ID: 2, Begin: 10 * ms, End: 160 * ms},
+		{ID: 1, Begin: 0, End: 200 * ms},
+	}
+	for i := uint64(0); i < 6; i++ {
+		begin := (200 + 10*i) * ms
+		blocks = append(blocks, proftest.Block{ID: 2, Begin: begin, End: begin + (i+1)*ms})
+	}
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Begin:       0,
+		End:         2000 * ms,
+		Descriptors: proftest.Descriptors("Frame", "Load"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: blocks},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 2 * ms}}},
+		},
+	}, nil)
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+	}{
+		{"defaults", nil},
+		{"cutoffs", map[string]interface{}{"high_cutoff": 30.0, "medium_cutoff": 20.0}},
+		// Load is also reported as Fragmented Work, shifting the issues after it
+		{"cutoffs and fragmented work", map[string]interface{}{"high_cutoff": 30.0, "medium_cutoff": 20.0, "fragmented_min_calls": 5.0, "fragmented_max_avg_us": 100000.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listed := make(map[float64]string)
+			bySeverity, _ := callToolJSON(t, analyzePerformanceIssuesHandler, tt.args)["by_severity"].(map[string]interface{})
+			for severity, issues := range bySeverity {
+				for _, issue := range issues.([]interface{}) {
+					issue := issue.(map[string]interface{})
+					listed[issue["index"].(float64)] = fmt.Sprintf("%s %v at %v", severity, issue["type"], issue["location"])
+				}
+			}
+			if len(listed) == 0 {
+				t.Fatal("no issues listed")
+			}
+
+			for index, want := range listed {
+				args := map[string]interface{}{"index": index}
+				for key, value := range tt.args {
+					args[key] = value
+				}
+				explained := callToolJSON(t, explainIssueHandler, args)
+				if got := fmt.Sprintf("%v %v at %v", explained["severity"], explained["type"], explained["location"]); got != want {
+					t.Errorf("issue %v explained as %s, listed as %s", index, got, want)
+				}
+			}
+		})
+	}
+
+	// Without the listing's parameters, the third issue is a different one
+	args := map[string]interface{}{"index": 3.0}
+	for key, value := range tests[2].args {
+		args[key] = value
+	}
+	if with, without := callToolJSON(t, explainIssueHandler, args), callToolJSON(t, explainIssueHandler, map[string]interface{}{"index": 3.0}); with["type"] != "Fragmented Work" || without["type"] == "Fragmented Work" {
+		t.Errorf("issue 3 is %v with the listing's parameters and %v without, want Fragmented Work only with them", with["type"], without["type"])
+	}
+}
+
+func TestGetSlowestBlocksHandlerOnCPU(t *testing.T) {
+	capture := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Work"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main",
+			ContextSwitches: []proftest.ContextSwitch{{ThreadID: 1, Begin: 250, End: 1000}},
+			Blocks:          []proftest.Block{{ID: 1, Begin: 0, End: 1000}},
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		onCPU   interface{}
+		percent interface{}
+	}{
+		{"with context switches", nil, "250ns", "25.00%"},
+		{"fast mode skips context switches", map[string]interface{}{"fast_mode": true}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, capture, tt.args)
+			block := callToolList(t, getSlowestBlocksHandler, nil)[0]
+			if block["on_cpu_duration"] != tt.onCPU || block["on_cpu_percent"] != tt.percent {
+				t.Errorf("on_cpu_duration %v, on_cpu_percent %v; want %v, %v", block["on_cpu_duration"], block["on_cpu_percent"], tt.onCPU, tt.percent)
+			}
+		})
+	}
+}
+
+func TestGetBlockGanttDataHandler(t *testing.T) {
+	resetRegistry(t)
+	var blocks []proftest.Block
+	for i := uint64(0); i < 5; i++ {
+		blocks = append(blocks, proftest.Block{ID: 1, Begin: i * 100, End: i*100 + 50})
+	}
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+	}, nil)
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr bool
+		total   float64
+		begins  []interface{}
+		hasMore bool
+	}{
+		{"everything", nil, false, 5, []interface{}{0.0, 100.0, 200.0, 300.0, 400.0}, false},
+		{"first page", map[string]interface{}{"page_size": 2.0}, false, 5, []interface{}{0.0, 100.0}, true},
+		{"last page", map[string]interface{}{"page_size": 2.0, "page": 3.0}, false, 5, []interface{}{400.0}, false},
+		{"past the last page", map[string]interface{}{"page_size": 2.0, "page": 1e18}, false, 5, nil, false},
+		{"huge page size", map[string]interface{}{"page_size": 1e19}, false, 5, []interface{}{0.0, 100.0, 200.0, 300.0, 400.0}, false},
+		{"window", map[string]interface{}{"window_start": "120ns", "window_end": "300ns"}, false, 2, []interface{}{100.0, 200.0}, false},
+		{"invalid window", map[string]interface{}{"window_start": "-1ns"}, true, 0, nil, false},
+		{"empty window end", map[string]interface{}{"window_end": "0s"}, true, 0, nil, false},
+		{"unknown thread", map[string]interface{}{"thread": "Render"}, true, 0, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, getBlockGanttDataHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+			result := callToolJSON(t, getBlockGanttDataHandler, tt.args)
+			var begins []interface{}
+			if threads, _ := result["threads"].(map[string]interface{}); threads["1"] != nil {
+				for _, iv := range threads["1"].([]interface{}) {
+					begins = append(begins, iv.(map[string]interface{})["begin"])
+				}
+			}
+			if result["total_intervals"] != tt.total || !reflect.DeepEqual(begins, tt.begins) || result["has_more"] != tt.hasMore {
+				t.Errorf("total %v, begins %v, has_more %v; want %v, %v, %t",
+					result["total_intervals"], begins, result["has_more"], tt.total, tt.begins, tt.hasMore)
+			}
+		})
+	}
+}
+
+func TestParseNameList(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"read,write", []string{"read", "write"}},
+		{" read , ,write ,", []string{"read", "write"}},
+		{"", nil},
+		{" , ", nil},
+	}
+
+	for _, tt := range tests {
+		if got := parseNameList(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseNameList(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestGetGCPausesHandler(t *testing.T) {
+	resetRegistry(t)
+	ms := uint64(time.Millisecond)
+	loadTestProfile(t, &proftest.Profile{
+		Begin:       0,
+		End:         2000 * ms,
+		Descriptors: proftest.Descriptors("Frame", "GC::Collect"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 100 * ms, End: 150 * ms},
+			{ID: 1, Begin: 0, End: 1000 * ms},
+			{ID: 2, Begin: 1200 * ms, End: 1300 * ms},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantErr   bool
+		pauses    int
+		intervals int
+	}{
+		{"defaults", map[string]interface{}{}, false, 2, 2},
+		{"limited", map[string]interface{}{"limit": 1.0, "bucket": "500ms"}, false, 1, 4},
+		{"no matches", map[string]interface{}{"pattern": "Audio"}, false, 0, 2},
+		{"negative limit", map[string]interface{}{"limit": -1.0}, true, 0, 0},
+		{"invalid bucket", map[string]interface{}{"bucket": "soon"}, true, 0, 0},
+		{"tiny bucket", map[string]interface{}{"bucket": "1ns"}, true, 0, 0},
+		{"invalid pattern", map[string]interface{}{"pattern": "("}, true, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, getGCPausesHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getGCPausesHandler, tt.args)
+			if got := len(list(t, result, "pauses")); got != tt.pauses {
+				t.Errorf("got %d pauses, want %d", got, tt.pauses)
+			}
+			if got := len(list(t, result, "per_interval")); got != tt.intervals {
+				t.Errorf("got %d intervals, want %d", got, tt.intervals)
+			}
+			if _, ok := result["longest_pause"]; ok != (tt.pauses > 0) {
+				t.Errorf("longest_pause present = %t, want %t", ok, tt.pauses > 0)
+			}
+		})
+	}
+
+	result := callToolJSON(t, getGCPausesHandler, nil)
+	if result["pause_count"] != 2.0 || result["percent_of_capture"] != "7.50%" {
+		t.Errorf("pause_count = %v, percent_of_capture = %v; want 2 and 7.50%%", result["pause_count"], result["percent_of_capture"])
+	}
+	if longest, _ := result["longest_pause"].(map[string]interface{}); longest["start"] != formatDuration(1200*time.Millisecond) {
+		t.Errorf("longest_pause = %v, want the pause at 1.2s", longest)
+	}
+}
+
+func TestLoadProfileReportsUnclosedBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		blocks   []proftest.Block
+		unclosed interface{}
+	}{
+		{"all closed", []proftest.Block{{ID: 1, Begin: 0, End: 100}}, nil},
+		{"truncated", []proftest.Block{{ID: 1, Begin: 0, End: 100}, {ID: 2, Begin: 200, End: 0}}, 1.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			summary := loadTestProfile(t, &proftest.Profile{
+				Begin:       0,
+				End:         1000,
+				Descriptors: proftest.Descriptors("Frame", "Crash"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: tt.blocks}},
+			}, nil)
+			if summary["unclosed_blocks"] != tt.unclosed {
+				t.Errorf("unclosed_blocks = %v, want %v", summary["unclosed_blocks"], tt.unclosed)
+			}
+
+			slowest := callToolList(t, getSlowestBlocksHandler, nil)
+			if len(slowest) != 1 || slowest[0]["name"] != "Frame" {
+				t.Errorf("slowest blocks = %v, want only Frame", slowest)
+			}
+		})
+	}
+}
+
+func TestGetBlockByNameSummaryHandler(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10, End: 40},
+			{ID: 1, Begin: 0, End: 100},
+			{ID: 2, Begin: 150, End: 160},
+			{ID: 1, Begin: 100, End: 200},
+			{ID: 2, Begin: 300, End: 320},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr bool
+		want    map[string]interface{}
+		path    []string
+	}{
+		{"nested", map[string]interface{}{"name": "Update"}, false, map[string]interface{}{
+			"call_count": 3.0, "total_duration": "60ns", "p95_duration": "30ns", "typical_path_calls": 2.0,
+			"typical_path_percent": "66.67%", "distinct_paths": 2.0, "file": "Update.cpp",
+		}, []string{"Frame", "Update"}},
+		{"top level", map[string]interface{}{"name": "Frame"}, false, map[string]interface{}{
+			"call_count": 2.0, "total_self_duration": "160ns", "avg_duration": "100ns", "typical_path_percent": "100.00%",
+		}, []string{"Frame"}},
+		{"unknown name", map[string]interface{}{"name": "Render"}, true, nil, nil},
+		{"missing name", nil, true, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, getBlockByNameSummaryHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getBlockByNameSummaryHandler, tt.args)
+			for key, value := range tt.want {
+				if result[key] != value {
+					t.Errorf("%s = %v, want %v", key, result[key], value)
+				}
+			}
+			var path []string
+			for i, node := range list(t, result, "typical_path") {
+				if node["depth"] != float64(i) {
+					t.Errorf("path node %d has depth %v", i, node["depth"])
+				}
+				path = append(path, node["name"].(string))
+			}
+			if !reflect.DeepEqual(path, tt.path) {
+				t.Errorf("typical path = %v, want %v", path, tt.path)
+			}
+		})
+	}
+}
+
+func TestGetThreadStatisticsHandlerSortBy(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 100}}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}, {ID: 1, Begin: 20, End: 30}, {ID: 1, Begin: 40, End: 50}}},
+			{ID: 3, Name: "Audio", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 50}}, ContextSwitches: []proftest.ContextSwitch{
+				{ThreadID: 3, Begin: 0, End: 10, Name: "game"},
+			}},
+		},
+	}, nil)
+
+	tests := []struct {
+		sortBy  string
+		want    []string
+		wantErr bool
+	}{
+		{"", []string{"Main", "Audio", "Worker"}, false},
+		{"duration", []string{"Main", "Audio", "Worker"}, false},
+		{"name", []string{"Audio", "Main", "Worker"}, false},
+		{"block_count", []string{"Worker", "Main", "Audio"}, false},
+		{"context_switches", []string{"Audio", "Main", "Worker"}, false},
+		{"threads", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			args := map[string]interface{}{"sort_by": tt.sortBy}
+			if tt.wantErr {
+				if text, isError := callTool(t, getThreadStatisticsHandler, args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+			var got []string
+			for _, thread := range callToolList(t, getThreadStatisticsHandler, args) {
+				got = append(got, thread["thread_name"].(string))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("threads = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProfileReportsIntegrity(t *testing.T) {
+	blocks := []proftest.Block{{ID: 1, Begin: 0, End: 100}, {ID: 1, Begin: 100, End: 200}}
+
+	tests := []struct {
+		name     string
+		declared uint32
+		args     map[string]interface{}
+		want     map[string]interface{} // nil: no integrity entry
+	}{
+		{"complete", 0, nil, nil},
+		{"over-reported", 5, nil, map[string]interface{}{"declared_blocks": 5.0, "blocks_in_file": 2.0, "missing_blocks": 3.0}},
+		{"under-reported", 1, nil, map[string]interface{}{"declared_blocks": 1.0, "blocks_in_file": 2.0, "unexpected_blocks": 1.0}},
+		{"sampled", 0, map[string]interface{}{"fast_mode": true}, map[string]interface{}{"loaded_blocks": 1.0, "intentionally_skipped_blocks": 1.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			summary := loadTestProfile(t, &proftest.Profile{
+				DeclaredBlocks: tt.declared,
+				Descriptors:    proftest.Descriptors("Frame"),
+				Threads:        []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+			}, tt.args)
+
+			integrity, ok := summary["integrity"].(map[string]interface{})
+			if ok != (tt.want != nil) {
+				t.Fatalf("integrity = %v, want present %t", summary["integrity"], tt.want != nil)
+			}
+			for key, value := range tt.want {
+				if integrity[key] != value {
+					t.Errorf("%s = %v, want %v", key, integrity[key], value)
+				}
+			}
+			if _, warned := integrity["warning"]; warned != (tt.declared != 0) {
+				t.Errorf("warning = %v, want present %t", integrity["warning"], tt.declared != 0)
+			}
+		})
+	}
+}
+
+func TestGetRollingHotspotsHandler(t *testing.T) {
+	resetRegistry(t)
+	ms := uint64(time.Millisecond)
+	loadTestProfile(t, &proftest.Profile{
+		Begin:       0,
+		End:         3000 * ms,
+		Descriptors: proftest.Descriptors("Update", "Spike"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 900 * ms},
+			{ID: 2, Begin: 1100 * ms, End: 1900 * ms},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		wantErr  bool
+		timeline []string // "name windows"
+		windows  int
+	}{
+		{"defaults", nil, false, []string{"Update 1", "Spike 1", "(idle) 1"}, 3},
+		{"sliding", map[string]interface{}{"window": "2s", "step": "1s"}, false, []string{"Update 1", "Spike 1", "(idle) 1"}, 3},
+		{"all functions", map[string]interface{}{"window": "3s", "top": 0.0}, false, []string{"Update 1"}, 1},
+		{"invalid window", map[string]interface{}{"window": "long"}, true, nil, 0},
+		{"invalid step", map[string]interface{}{"step": "-"}, true, nil, 0},
+		{"negative step", map[string]interface{}{"step": "-1s"}, true, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, getRollingHotspotsHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getRollingHotspotsHandler, tt.args)
+			var timeline []string
+			for _, shift := range list(t, result, "timeline") {
+				timeline = append(timeline, fmt.Sprintf("%s %v", shift["name"], shift["windows"]))
+			}
+			if !reflect.DeepEqual(timeline, tt.timeline) {
+				t.Errorf("timeline = %v, want %v", timeline, tt.timeline)
+			}
+			if got := len(list(t, result, "windows")); got != tt.windows {
+				t.Errorf("got %d windows, want %d", got, tt.windows)
+			}
+		})
+	}
+
+	// With every function listed, the 3s window holds Update's 900ms (30%) and Spike's 800ms
+	window := list(t, callToolJSON(t, getRollingHotspotsHandler, map[string]interface{}{"window": "3s", "top": 0.0}), "windows")[0]
+	hotspots, _ := window["hotspots"].([]interface{})
+	if len(hotspots) != 2 || hotspots[0].(map[string]interface{})["percent_of_window"] != "30.00%" {
+		t.Errorf("hotspots = %v, want Update at 30.00%% then Spike", hotspots)
+	}
+}
+
+func TestGetLeafHotspotsHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		blocks  []proftest.Block
+		args    map[string]interface{}
+		wantErr bool
+		total   string
+		want    []string // "name percent"
+	}{
+		{"composite parent excluded", []proftest.Block{
+			{ID: 2, Begin: 10, End: 70},
+			{ID: 1, Begin: 0, End: 100},
+			{ID: 2, Begin: 100, End: 120},
+		}, nil, false, "80ns", []string{"Update 100.00%"}},
+		{"limited", []proftest.Block{
+			{ID: 2, Begin: 0, End: 60},
+			{ID: 1, Begin: 100, End: 120},
+		}, map[string]interface{}{"limit": 1.0}, false, "80ns", []string{"Update 75.00%"}},
+		{"zero total", []proftest.Block{{ID: 1, Begin: 50, End: 50}}, nil, false, "0s", nil},
+		{"negative limit", []proftest.Block{{ID: 1, Begin: 0, End: 10}}, map[string]interface{}{"limit": -1.0}, true, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, &proftest.Profile{
+				Descriptors: proftest.Descriptors("Frame", "Update"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: tt.blocks}},
+			}, nil)
+
+			if tt.wantErr {
+				if text, isError := callTool(t, getLeafHotspotsHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getLeafHotspotsHandler, tt.args)
+			if result["total_leaf_time"] != tt.total {
+				t.Errorf("total_leaf_time = %v, want %s", result["total_leaf_time"], tt.total)
+			}
+			var got []string
+			for _, leaf := range list(t, result, "leaves") {
+				got = append(got, fmt.Sprintf("%s %s", leaf["name"], leaf["percent_of_leaf_time"]))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("leaves = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProfileReportsNestingAnomalies(t *testing.T) {
+	// overlapping puts n Frames 1µs apart, each holding two overlapping children
+	// that add up to 10ns more than the Frame
+	overlapping := func(n int) []proftest.Block {
+		var blocks []proftest.Block
+		for i := 0; i < n; i++ {
+			begin := uint64(i) * 1000
+			blocks = append(blocks,
+				proftest.Block{ID: 2, Begin: begin + 10, End: begin + 60},
+				proftest.Block{ID: 2, Begin: begin + 40, End: begin + 100},
+				proftest.Block{ID: 1, Begin: begin, End: begin + 100},
+			)
+		}
+		return blocks
+	}
+
+	tests := []struct {
+		name  string
+		count int
+		worst int
+	}{
+		{"well formed", 0, 0},
+		{"one anomaly", 1, 1},
+		{"more than shown", nestingAnomaliesShown + 2, nestingAnomaliesShown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			blocks := overlapping(tt.count)
+			if tt.count == 0 {
+				blocks = []proftest.Block{{ID: 2, Begin: 10, End: 60}, {ID: 1, Begin: 0, End: 100}}
+			}
+			summary := loadTestProfile(t, &proftest.Profile{
+				Descriptors: proftest.Descriptors("Frame", "Update"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+			}, nil)
+
+			nesting, ok := summary["nesting_anomalies"].(map[string]interface{})
+			if ok != (tt.count > 0) {
+				t.Fatalf("nesting_anomalies = %v, want present %t", summary["nesting_anomalies"], tt.count > 0)
+			}
+			if !ok {
+				return
+			}
+			worst, _ := nesting["worst"].([]interface{})
+			if nesting["count"] != float64(tt.count) || len(worst) != tt.worst {
+				t.Errorf("count %v with %d listed, want %d with %d", nesting["count"], len(worst), tt.count, tt.worst)
+			}
+			if first := worst[0].(map[string]interface{}); first["excess"] != "10ns" || first["name"] != "Frame" {
+				t.Errorf("worst[0] = %v, want Frame exceeded by 10ns", first)
+			}
+
+			// The Frame's self time is clamped rather than negative
+			for _, hotspot := range callToolList(t, getHotspotsHandler, map[string]interface{}{"exclusive": true}) {
+				if hotspot["name"] == "Frame" && hotspot["total_duration"] != "0s" {
+					t.Errorf("Frame self time = %v, want 0s", hotspot["total_duration"])
+				}
+			}
+		})
+	}
+}
+
+func TestGetInstrumentationOverheadHandler(t *testing.T) {
+	// dense is a 30µs Frame holding 100 back-to-back 200ns Tiny blocks 100ns apart
+	var dense []proftest.Block
+	for i := uint64(0); i < 100; i++ {
+		dense = append(dense, proftest.Block{ID: 2, Begin: i * 300, End: i*300 + 200})
+	}
+	dense = append(dense, proftest.Block{ID: 1, Begin: 0, End: 30000})
+	sparse := []proftest.Block{
+		{ID: 2, Begin: 0, End: uint64(100 * time.Millisecond)},
+		{ID: 1, Begin: 0, End: uint64(time.Second)},
+	}
+
+	tests := []struct {
+		name    string
+		blocks  []proftest.Block
+		args    map[string]interface{}
+		wantErr bool
+		want    map[string]interface{}
+		densest []string
+	}{
+		{"extreme density", dense, nil, false, map[string]interface{}{
+			"level": "high", "blocks_count": 101.0, "tiny_blocks_count": 100.0, "block_cost": "100ns",
+			"block_cost_source": "measured", "estimated_overhead": "10.1µs", "overhead_percent": "33.67%",
+		}, []string{"Tiny"}},
+		{"zero limit lists all", dense, map[string]interface{}{"limit": 0.0}, false, map[string]interface{}{"level": "high"}, []string{"Tiny"}},
+		{"sparse", sparse, nil, false, map[string]interface{}{
+			"level": "low", "blocks_count": 2.0, "block_cost": "50ns", "block_cost_source": "assumed",
+		}, nil},
+		{"negative limit", dense, map[string]interface{}{"limit": -1.0}, true, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, &proftest.Profile{
+				Descriptors: proftest.Descriptors("Frame", "Tiny"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: tt.blocks}},
+			}, nil)
+
+			if tt.wantErr {
+				if text, isError := callTool(t, getInstrumentationOverheadHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getInstrumentationOverheadHandler, tt.args)
+			for key, want := range tt.want {
+				if result[key] != want {
+					t.Errorf("%s = %#v, want %#v", key, result[key], want)
+				}
+			}
+			if tt.want["level"] == "high" && !strings.Contains(result["recommendation"].(string), "Remove instrumentation") {
+				t.Errorf("recommendation %q doesn't suggest removing instrumentation", result["recommendation"])
+			}
+
+			var densest []string
+			for _, function := range list(t, result, "densest_functions") {
+				densest = append(densest, function["name"].(string))
+			}
+			if !reflect.DeepEqual(densest, tt.densest) {
+				t.Errorf("densest functions = %v, want %v", densest, tt.densest)
+			}
+		})
+	}
+}
+
+func TestGetSlowestBlocksRetained(t *testing.T) {
+	// Frame holds Update and Render; Render is slower than Update
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 0, End: 10},
+			{ID: 3, Begin: 10, End: 40},
+			{ID: 1, Begin: 0, End: 50},
+		}}},
+	}
+
+	tests := []struct {
+		name     string
+		retain   float64
+		args     map[string]interface{}
+		wantErr  bool
+		retained interface{}
+		want     []string // "name duration"
+		depth    bool
+	}{
+		{"full parse", 0, nil, false, nil, []string{"Frame 50ns", "Render 30ns", "Update 10ns"}, true},
+		{"retained", 2, nil, false, 2.0, []string{"Frame 50ns", "Render 30ns"}, false},
+		{"retained and limited", 2, map[string]interface{}{"limit": 1.0}, false, 2.0, []string{"Frame 50ns"}, false},
+		{"negative retain keeps all", -1, nil, false, nil, []string{"Frame 50ns", "Render 30ns", "Update 10ns"}, true},
+		{"exclusive needs the trees", 2, map[string]interface{}{"exclusive": true}, true, 2.0, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			summary := loadTestProfile(t, p, map[string]interface{}{"retain_slowest": tt.retain})
+			if summary["retained_slowest"] != tt.retained {
+				t.Errorf("retained_slowest = %v, want %v", summary["retained_slowest"], tt.retained)
+			}
+			if _, noted := summary["retained_slowest_note"]; noted != (tt.retained != nil) {
+				t.Errorf("retained_slowest_note present %t, want %t", noted, tt.retained != nil)
+			}
+
+			if tt.wantErr {
+				if text, isError := callTool(t, getSlowestBlocksHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+
+			var got []string
+			for _, block := range callToolList(t, getSlowestBlocksHandler, tt.args) {
+				got = append(got, fmt.Sprintf("%s %s", block["name"], block["duration"]))
+				if _, hasDepth := block["depth"]; hasDepth != tt.depth {
+					t.Errorf("%s has depth %t, want %t", block["name"], hasDepth, tt.depth)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("slowest blocks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetThreadComparisonHandler(t *testing.T) {
+	// WorkerA decodes for 60ns and uploads; WorkerB decodes for 30ns and compresses
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Decode", "Upload", "Compress"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "WorkerA", Blocks: []proftest.Block{
+				{ID: 2, Begin: 10, End: 70},
+				{ID: 3, Begin: 70, End: 90},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+			{ID: 2, Name: "WorkerB", Blocks: []proftest.Block{
+				{ID: 2, Begin: 0, End: 30},
+				{ID: 4, Begin: 40, End: 90},
+				{ID: 1, Begin: 0, End: 110},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantErr   string
+		divergent []string // "name a b delta percent"
+		onlyA     []string
+		onlyB     []string
+	}{
+		{"by name", map[string]interface{}{"thread_a": "WorkerA", "thread_b": "WorkerB"}, "",
+			[]string{"Decode 60ns 30ns -30ns 50.00%"}, []string{"Upload"}, []string{"Compress"}},
+		{"by ID", map[string]interface{}{"thread_a": "2", "thread_b": "1"}, "",
+			[]string{"Decode 30ns 60ns 30ns 50.00%"}, []string{"Compress"}, []string{"Upload"}},
+		{"custom divergence", map[string]interface{}{"thread_a": "WorkerA", "thread_b": "WorkerB", "divergence_percent": 5.0}, "",
+			[]string{"Decode 60ns 30ns -30ns 50.00%", "Frame 100ns 110ns 10ns 9.09%"}, []string{"Upload"}, []string{"Compress"}},
+		{"zero limit", map[string]interface{}{"thread_a": "WorkerA", "thread_b": "WorkerB", "limit": 0.0}, "", nil, nil, nil},
+		{"negative limit", map[string]interface{}{"thread_a": "WorkerA", "thread_b": "WorkerB", "limit": -1.0}, "limit must not be negative", nil, nil, nil},
+		{"same thread", map[string]interface{}{"thread_a": "WorkerA", "thread_b": "1"}, "same thread", nil, nil, nil},
+		{"missing thread_b", map[string]interface{}{"thread_a": "WorkerA"}, "thread_b parameter is required", nil, nil, nil},
+		{"unknown thread", map[string]interface{}{"thread_a": "WorkerA", "thread_b": "Render"}, "not found", nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, p, nil)
+
+			if tt.wantErr != "" {
+				if text, isError := callTool(t, getThreadComparisonHandler, tt.args); !isError || !strings.Contains(text, tt.wantErr) {
+					t.Errorf("result = %q (error %t), want an error containing %q", text, isError, tt.wantErr)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getThreadComparisonHandler, tt.args)
+			var divergent, onlyA, onlyB []string
+			for _, diff := range list(t, result, "divergent") {
+				divergent = append(divergent, fmt.Sprintf("%s %s %s %s %s", diff["name"], diff["duration_a"], diff["duration_b"], diff["delta"], diff["divergence_percent"]))
+			}
+			for _, function := range list(t, result, "only_in_a") {
+				onlyA = append(onlyA, function["name"].(string))
+			}
+			for _, function := range list(t, result, "only_in_b") {
+				onlyB = append(onlyB, function["name"].(string))
+			}
+			if !reflect.DeepEqual(divergent, tt.divergent) || !reflect.DeepEqual(onlyA, tt.onlyA) || !reflect.DeepEqual(onlyB, tt.onlyB) {
+				t.Errorf("divergent %q, only in a %v, only in b %v; want %q, %v, %v", divergent, onlyA, onlyB, tt.divergent, tt.onlyA, tt.onlyB)
+			}
+
+			// Counts cover every function even when the lists are limited
+			if result["shared_count"] != 2.0 || result["only_in_a_count"] != 1.0 || result["only_in_b_count"] != 1.0 {
+				t.Errorf("counts: %v shared, %v only in a, %v only in b; want 2, 1, 1", result["shared_count"], result["only_in_a_count"], result["only_in_b_count"])
+			}
+			if result["block_count_delta"] != 0.0 {
+				t.Errorf("block_count_delta = %v, want 0", result["block_count_delta"])
+			}
+		})
+	}
+}
+
+func TestAnalyzePerformanceIssuesHandlerRecursion(t *testing.T) {
+	// Walk recurses 20 levels deep once, then two levels in each of five more calls
+	var blocks []proftest.Block
+	for level := uint64(20); level > 0; level-- {
+		blocks = append(blocks, proftest.Block{ID: 1, Begin: level, End: 1001 - level})
+	}
+	for i := uint64(0); i < 5; i++ {
+		begin := 2000 + i*100
+		blocks = append(blocks,
+			proftest.Block{ID: 1, Begin: begin + 10, End: begin + 50},
+			proftest.Block{ID: 1, Begin: begin, End: begin + 90},
+		)
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want bool
+	}{
+		{"defaults", nil, true},
+		{"disabled", map[string]interface{}{"max_recursion_depth": 0.0}, false},
+		{"depth allowed, spread flagged", map[string]interface{}{"max_recursion_depth": 20.0}, true},
+		{"depth allowed, spread tolerated", map[string]interface{}{"max_recursion_depth": 20.0, "recursion_depth_ratio": 11.0}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, &proftest.Profile{
+				Descriptors: proftest.Descriptors("Walk"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+			}, nil)
+
+			var found []map[string]interface{}
+			grouped, _ := callToolJSON(t, analyzePerformanceIssuesHandler, tt.args)["by_severity"].(map[string]interface{})
+			for severity := range grouped {
+				for _, item := range list(t, grouped, severity) {
+					if item["type"] == "Unbalanced Recursion" {
+						found = append(found, item)
+					}
+				}
+			}
+			if (len(found) != 0) != tt.want || len(found) > 1 {
+				t.Fatalf("found %d Unbalanced Recursion issues, want %t", len(found), tt.want)
+			}
+			if tt.want && (found[0]["location"] != "Walk.cpp:10" || !strings.Contains(found[0]["description"].(string), "recursed 20 levels deep")) {
+				t.Errorf("issue = %v", found[0])
+			}
+		})
+	}
+}
+
+func TestGetFunctionTableHandler(t *testing.T) {
+	// Frame spans the 100ns capture around Update and Render; Unused is never called
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         100,
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render", "Unused"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10, End: 30},
+			{ID: 3, Begin: 30, End: 90},
+			{ID: 2, Begin: 90, End: 95},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr string
+		sortBy  string
+		want    []string // "id name location calls total self percent"
+	}{
+		{"default order", nil, "", "total_time", []string{
+			"1 Frame Frame.cpp:10 1 100ns 15ns 100.00%",
+			"3 Render Render.cpp:30 1 60ns 60ns 60.00%",
+			"2 Update Update.cpp:20 2 25ns 25ns 25.00%",
+		}},
+		{"by call count, limited", map[string]interface{}{"sort_by": "call_count", "limit": 1.0}, "", "call_count", []string{
+			"2 Update Update.cpp:20 2 25ns 25ns 25.00%",
+		}},
+		{"by self time", map[string]interface{}{"sort_by": "self_time"}, "", "self_time", []string{
+			"3 Render Render.cpp:30 1 60ns 60ns 60.00%",
+			"2 Update Update.cpp:20 2 25ns 25ns 25.00%",
+			"1 Frame Frame.cpp:10 1 100ns 15ns 100.00%",
+		}},
+		{"unknown column", map[string]interface{}{"sort_by": "color"}, "unknown function table column", "", nil},
+		{"negative limit", map[string]interface{}{"limit": -1.0}, "limit must not be negative", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, p, nil)
+
+			if tt.wantErr != "" {
+				if text, isError := callTool(t, getFunctionTableHandler, tt.args); !isError || !strings.Contains(text, tt.wantErr) {
+					t.Errorf("result = %q (error %t), want an error containing %q", text, isError, tt.wantErr)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getFunctionTableHandler, tt.args)
+			if result["sort_by"] != tt.sortBy {
+				t.Errorf("sort_by = %v, want %s", result["sort_by"], tt.sortBy)
+			}
+			var got []string
+			for _, row := range list(t, result, "functions") {
+				got = append(got, fmt.Sprintf("%v %s %s %v %s %s %s", row["descriptor_id"], row["name"], row["location"],
+					row["call_count"], row["total_duration"], row["total_self_duration"], row["percent_of_total"]))
+				if row["type"] != "block" || !strings.HasPrefix(row["color"].(string), "#") {
+					t.Errorf("%s has type %v and color %v", row["name"], row["type"], row["color"])
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProfileReportsMissingEndSignature(t *testing.T) {
+	for _, missing := range []bool{false, true} {
+		t.Run(fmt.Sprintf("missing %t", missing), func(t *testing.T) {
+			resetRegistry(t)
+			summary := loadTestProfile(t, &proftest.Profile{
+				Descriptors:    proftest.Descriptors("Frame"),
+				Threads:        []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 100}}}},
+				NoEndSignature: missing,
+			}, nil)
+
+			if summary["status"] != "success" {
+				t.Fatalf("status = %v", summary["status"])
+			}
+			_, noted := summary["end_signature_note"]
+			if got := summary["missing_end_signature"] == true; got != missing || noted != missing {
+				t.Errorf("missing_end_signature = %v with note %t, want %t", summary["missing_end_signature"], noted, missing)
+			}
+		})
+	}
+}
+
+func TestLoadProfileWarnsWhenProfilerMostlyOff(t *testing.T) {
+	s := uint64(time.Second)
+
+	tests := []struct {
+		name   string
+		end    uint64
+		blocks []proftest.Block
+		warned bool
+	}{
+		{"long capture with almost no blocks", 60 * s, []proftest.Block{{ID: 1, Begin: 0, End: 1000}, {ID: 1, Begin: 30 * s, End: 30*s + 1000}}, true},
+		{"capture covered by its blocks", 60 * s, []proftest.Block{{ID: 1, Begin: 0, End: 60 * s}}, false},
+		{"short capture", 100, []proftest.Block{{ID: 1, Begin: 0, End: 10}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			summary := loadTestProfile(t, &proftest.Profile{
+				Begin:       0,
+				End:         tt.end,
+				Descriptors: proftest.Descriptors("Frame"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: tt.blocks}},
+			}, nil)
+
+			warning, warned := summary["profiler_health_warning"].(string)
+			if warned != tt.warned {
+				t.Fatalf("profiler_health_warning = %v, want one %t", summary["profiler_health_warning"], tt.warned)
+			}
+			if !warned {
+				return
+			}
+			if !strings.Contains(warning, "only 2 blocks over a 1m0s capture") {
+				t.Errorf("warning %q doesn't give the evidence", warning)
+			}
+			health, ok := summary["profiler_health"].(map[string]interface{})
+			if !ok || health["blocks"] != float64(2) {
+				t.Errorf("profiler_health = %v, want 2 blocks", summary["profiler_health"])
+			}
+		})
+	}
+}
+
+func TestGetHotspotsHandlerExclude(t *testing.T) {
+	// Frame spans the 100ns capture around Update and Render
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         100,
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 0, End: 60},
+			{ID: 3, Begin: 60, End: 90},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want []string // "rank name percent"
+	}{
+		{"nothing excluded", nil, []string{"1 Frame 100.00%", "2 Update 60.00%", "3 Render 30.00%"}},
+		{"top function excluded", map[string]interface{}{"exclude": "Frame"}, []string{"1 Update 60.00%", "2 Render 30.00%"}},
+		{"list with spaces", map[string]interface{}{"exclude": " Frame , Update ,"}, []string{"1 Render 30.00%"}},
+		{"pattern", map[string]interface{}{"exclude": "*e"}, []string{"1 Render 30.00%"}},
+		{"empty list", map[string]interface{}{"exclude": ""}, []string{"1 Frame 100.00%", "2 Update 60.00%", "3 Render 30.00%"}},
+		{"limited", map[string]interface{}{"exclude": "Frame", "limit": 1.0}, []string{"1 Update 60.00%"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, p, nil)
+
+			var got []string
+			for i, hotspot := range callToolList(t, getHotspotsHandler, tt.args) {
+				got = append(got, fmt.Sprintf("%d %s %s", i+1, hotspot["name"], hotspot["percent_of_total"]))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hotspots = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetHotspotsHandlerGranularity(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: []proftest.Descriptor{
+			{ID: 1, Name: "Tick", File: "engine.cpp", Line: 10, Type: parser.BlockTypeBlock},
+			{ID: 2, Name: "Tick", File: "render.cpp", Line: 20, Type: parser.BlockTypeBlock},
+		},
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 10},
+			{ID: 2, Begin: 10, End: 40},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		granularity string
+		want        []string // "name calls"
+		wantErr     bool
+	}{
+		{"", []string{"Tick 1", "Tick 1"}, false},
+		{"call_site", []string{"Tick 1", "Tick 1"}, false},
+		{"name", []string{"Tick 2"}, false},
+		{"descriptor", []string{"Tick 1", "Tick 1"}, false},
+		{"file", []string{"render.cpp 1", "engine.cpp 1"}, false},
+		{"thread", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.granularity, func(t *testing.T) {
+			args := map[string]interface{}{"granularity": tt.granularity}
+			if tt.wantErr {
+				if text, isError := callTool(t, getHotspotsHandler, args); !isError {
+					t.Fatalf("granularity %q accepted: %s", tt.granularity, text)
+				}
+				return
+			}
+			var got []string
+			for _, hotspot := range callToolList(t, getHotspotsHandler, args) {
+				got = append(got, fmt.Sprintf("%v %v", hotspot["name"], hotspot["call_count"]))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hotspots = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBlockDurationOverTimeHandler(t *testing.T) {
+	// Tick runs every 100ns, 5ns longer each time
+	var blocks []proftest.Block
+	for i := uint64(0); i < 6; i++ {
+		blocks = append(blocks, proftest.Block{ID: 1, Begin: i * 100, End: i*100 + 10 + i*5})
+	}
+	p := &proftest.Profile{
+		Begin:       0,
+		Descriptors: proftest.Descriptors("Tick"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr string
+		want    []string // "offset duration [max calls]"
+	}{
+		{"every call", map[string]interface{}{"name": "Tick"}, "",
+			[]string{"0s 10ns", "100ns 15ns", "200ns 20ns", "300ns 25ns", "400ns 30ns", "500ns 35ns"}},
+		{"zero keeps every call", map[string]interface{}{"name": "Tick", "max_points": 0.0}, "",
+			[]string{"0s 10ns", "100ns 15ns", "200ns 20ns", "300ns 25ns", "400ns 30ns", "500ns 35ns"}},
+		{"downsampled", map[string]interface{}{"name": "Tick", "max_points": 2.0}, "",
+			[]string{"0s 15ns 20ns 3", "300ns 30ns 35ns 3"}},
+		{"missing name", nil, "name parameter is required", nil},
+		{"negative max_points", map[string]interface{}{"name": "Tick", "max_points": -1.0}, "max_points must not be negative", nil},
+		{"unknown function", map[string]interface{}{"name": "Render"}, "Render", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, p, nil)
+
+			if tt.wantErr != "" {
+				if text, isError := callTool(t, getBlockDurationOverTimeHandler, tt.args); !isError || !strings.Contains(text, tt.wantErr) {
+					t.Errorf("result = %q (error %t), want an error containing %q", text, isError, tt.wantErr)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getBlockDurationOverTimeHandler, tt.args)
+			downsampled := len(tt.want) < 6
+			if result["call_count"] != 6.0 || result["downsampled"] != downsampled || result["time_mode"] != "inclusive" {
+				t.Errorf("%v calls, downsampled %v in %v; want 6, %t in inclusive", result["call_count"], result["downsampled"], result["time_mode"], downsampled)
+			}
+			if result["slope_per_second"] != "50ms" || result["change_percent"] != "250.00%" {
+				t.Errorf("slope %v, change %v; want 50ms and 250.00%%", result["slope_per_second"], result["change_percent"])
+			}
+
+			var got []string
+			for _, point := range list(t, result, "points") {
+				entry := fmt.Sprintf("%s %s", point["offset"], point["duration"])
+				if downsampled {
+					entry += fmt.Sprintf(" %s %v", point["max_duration"], point["calls"])
+				}
+				got = append(got, entry)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("points = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetOverlappingBlocksHandler(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Load", "Save"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 50},
+			{ID: 2, Begin: 30, End: 80},
+		}}},
+	}
+
+	tests := []struct {
+		name     string
+		load     map[string]interface{}
+		args     map[string]interface{}
+		overlaps int
+		wantErr  bool
+	}{
+		{"default limit", nil, nil, 1, false},
+		{"zero limit", nil, map[string]interface{}{"limit": float64(0)}, 0, false},
+		{"negative limit", nil, map[string]interface{}{"limit": float64(-1)}, 0, true},
+		{"retained profile", map[string]interface{}{"retain_slowest": float64(10)}, nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, p, tt.load)
+			args := tt.args
+			if args == nil {
+				args = map[string]interface{}{}
+			}
+
+			if tt.wantErr {
+				if text, isError := callTool(t, getOverlappingBlocksHandler, args); !isError {
+					t.Fatalf("want a tool error, got %s", text)
+				}
+				return
+			}
+			result := callToolJSON(t, getOverlappingBlocksHandler, args)
+			if result["count"] != float64(1) || result["threads_affected"] != float64(1) {
+				t.Errorf("count = %v on %v threads, want 1 on 1", result["count"], result["threads_affected"])
+			}
+			overlaps := list(t, result, "overlaps")
+			if len(overlaps) != tt.overlaps {
+				t.Fatalf("%d overlaps listed, want %d", len(overlaps), tt.overlaps)
+			}
+			if len(overlaps) > 0 && overlaps[0]["overlap"] != "20ns" {
+				t.Errorf("overlap = %v, want 20ns", overlaps[0]["overlap"])
+			}
+		})
+	}
+}
+
+func TestPercentBaseArgument(t *testing.T) {
+	resetRegistry(t)
+	outputFormat.Raw = true
+	// Audio keeps Worker busy for 40 of its 50ns, in a 1000ns capture
+	loadTestProfile(t, &proftest.Profile{
+		Begin:       0,
+		End:         1000,
+		Descriptors: proftest.Descriptors("Frame", "Audio", "Mix"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 40}, {ID: 3, Begin: 40, End: 50}}},
+		},
+	}, nil)
+
+	tests := []struct {
+		base    string
+		key     string
+		percent float64 // Of Audio
+		issues  int     // Hot Function issues
+		wantErr bool
+	}{
+		{"", "percent_of_total", 0.04, 1, false},
+		{"global", "percent_of_total", 0.04, 1, false},
+		{"thread", "percent_of_thread", 0.8, 3, false},
+		{"process", "", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.base, func(t *testing.T) {
+			args := map[string]interface{}{"percent_base": tt.base}
+			if tt.wantErr {
+				for _, handler := range []server.ToolHandlerFunc{getHotspotsHandler, analyzePerformanceIssuesHandler} {
+					if text, isError := callTool(t, handler, args); !isError {
+						t.Errorf("percent_base %q accepted: %s", tt.base, text)
+					}
+				}
+				return
+			}
+
+			for _, hotspot := range callToolList(t, getHotspotsHandler, args) {
+				if hotspot["name"] != "Audio" {
+					continue
+				}
+				if hotspot[tt.key] != tt.percent {
+					t.Errorf("%s = %v, want %v", tt.key, hotspot[tt.key], tt.percent)
+				}
+				if _, ok := hotspot["thread_time"]; ok != (tt.base == "thread") {
+					t.Errorf("thread_time = %v", hotspot["thread_time"])
+				}
+			}
+
+			grouped, _ := callToolJSON(t, analyzePerformanceIssuesHandler, args)["by_severity"].(map[string]interface{})
+			hot := 0
+			for severity := range grouped {
+				for _, issue := range list(t, grouped, severity) {
+					if issue["type"] == "Hot Function" {
+						hot++
+					}
+				}
+			}
+			if hot != tt.issues {
+				t.Errorf("%d Hot Function issues, want %d", hot, tt.issues)
+			}
+		})
+	}
+}
+
+func TestGetPhasesHandler(t *testing.T) {
+	resetRegistry(t)
+	ms := uint64(time.Millisecond)
+	loadTestProfile(t, &proftest.Profile{
+		Begin:       0,
+		End:         200 * ms,
+		Descriptors: proftest.Descriptors("Load", "Render"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 40 * ms},
+			{ID: 2, Begin: 100 * ms, End: 110 * ms},
+			{ID: 2, Begin: 115 * ms, End: 140 * ms},
+		}}},
+	}, nil)
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    []string // "start-end top percent"
+		wantErr bool
+	}{
+		{"default gap", map[string]interface{}{"thread": "Main"}, []string{"0s-40ms Load 100.00%", "100ms-140ms Render 100.00%"}, false},
+		{"wide gap", map[string]interface{}{"thread": "1", "min_gap": "100ms"}, []string{"0s-140ms Load 53.33%"}, false},
+		{"missing thread", map[string]interface{}{}, nil, true},
+		{"unknown thread", map[string]interface{}{"thread": "Audio"}, nil, true},
+		{"zero gap", map[string]interface{}{"thread": "Main", "min_gap": "0s"}, nil, true},
+		{"negative gap", map[string]interface{}{"thread": "Main", "min_gap": "-1ms"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, getPhasesHandler, tt.args); !isError {
+					t.Fatalf("want a tool error, got %s", text)
+				}
+				return
+			}
+			result := callToolJSON(t, getPhasesHandler, tt.args)
+			var got []string
+			for _, phase := range list(t, result, "phases") {
+				top := phase["top_function"].(map[string]interface{})
+				got = append(got, fmt.Sprintf("%v-%v %v %v", phase["start"], phase["end"], top["name"], top["percent_of_busy"]))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("phases = %q, want %q", got, tt.want)
+			}
+			if result["phase_count"] != float64(len(tt.want)) {
+				t.Errorf("phase_count = %v, want %d", result["phase_count"], len(tt.want))
+			}
+		})
+	}
+}
+
+func TestGetBlockCountByDepthHandler(t *testing.T) {
+	resetRegistry(t)
+	outputFormat.Raw = true
+	// Frame holds Update, which holds Step, on Main; Idle has no blocks
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Step"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 3, Begin: 20, End: 30},
+				{ID: 2, Begin: 10, End: 50},
+				{ID: 1, Begin: 0, End: 100},
+				{ID: 1, Begin: 100, End: 200},
+			}},
+			{ID: 2, Name: "Idle"},
+		},
+	}, nil)
+
+	result := callToolJSON(t, getBlockCountByDepthHandler, nil)
+	describe := func(histogram map[string]interface{}) string {
+		var levels []string
+		for _, level := range list(t, histogram, "depths") {
+			levels = append(levels, fmt.Sprintf("%v:%v(%v)", level["depth"], level["blocks"], level["percentage"]))
+		}
+		return fmt.Sprintf("%v blocks %v, 95%% in %v levels", histogram["blocks"], levels, histogram["levels_for_95_pct"])
+	}
+
+	if got, want := describe(result["total"].(map[string]interface{})), "4 blocks [0:2(0.5) 1:1(0.25) 2:1(0.25)], 95% in 3 levels"; got != want {
+		t.Errorf("total = %s, want %s", got, want)
+	}
+	threads := list(t, result, "threads")
+	if len(threads) != 2 {
+		t.Fatalf("%d threads, want 2", len(threads))
+	}
+	if threads[0]["thread_name"] != "Main" || threads[0]["max_depth"] != float64(2) {
+		t.Errorf("Main = %v, want max depth 2", threads[0])
+	}
+	if got, want := describe(threads[1]), "0 blocks [], 95% in 0 levels"; got != want {
+		t.Errorf("Idle = %s, want %s", got, want)
+	}
+}
+
+func TestGetWorstInvocationHandler(t *testing.T) {
+	// Frame holds Update, which spends 50 of its 60ns in Physics
+	p := &proftest.Profile{
+		Begin:       1000,
+		End:         1100,
+		Descriptors: proftest.Descriptors("Frame", "Update", "Physics"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 3, Begin: 1005, End: 1055},
+			{ID: 2, Begin: 1000, End: 1060},
+			{ID: 1, Begin: 1000, End: 1100},
+		}}},
+	}
+
+	tests := []struct {
+		name    string
+		load    map[string]interface{}
+		args    map[string]interface{}
+		path    []string // "name duration percent-of-parent"
+		wantErr bool
+	}{
+		{"worst leaf", nil, nil, []string{"Frame 100ns 100.00%", "Update 60ns 60.00%", "Physics 50ns 83.33%"}, false},
+		{"raw timestamps", nil, map[string]interface{}{"raw_timestamps": true}, []string{"Frame 100ns 100.00%", "Update 60ns 60.00%", "Physics 50ns 83.33%"}, false},
+		{"retained profile", map[string]interface{}{"retain_slowest": float64(10)}, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, p, tt.load)
+			if tt.wantErr {
+				if text, isError := callTool(t, getWorstInvocationHandler, tt.args); !isError {
+					t.Fatalf("want a tool error, got %s", text)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getWorstInvocationHandler, tt.args)
+			if result["name"] != "Physics" || result["self_duration"] != "50ns" || result["offset"] != "5ns" || result["block_key"] != "1/3/0" {
+				t.Errorf("worst = %v, want Physics with 50ns of self time at 5ns", result)
+			}
+			var path []string
+			for _, node := range list(t, result, "path") {
+				path = append(path, fmt.Sprintf("%s %s %s", node["name"], node["duration"], node["percent_of_parent"]))
+				if _, ok := node["begin_ticks"]; ok != (tt.args["raw_timestamps"] == true) {
+					t.Errorf("%s has begin_ticks %v", node["name"], node["begin_ticks"])
+				}
+			}
+			if !reflect.DeepEqual(path, tt.path) {
+				t.Errorf("path = %q, want %q", path, tt.path)
+			}
+		})
+	}
+}
+
+func TestGetWorstInvocationHandlerNoClosedBlocks(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Begin:       0,
+		End:         100,
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 10, End: 0}}}},
+	}, nil)
+	if text, isError := callTool(t, getWorstInvocationHandler, nil); !isError {
+		t.Errorf("want a tool error, got %s", text)
+	}
+}
+
+func TestLoadProfileHeaderWarnings(t *testing.T) {
+	t.Setenv("EASYPROFILER_CACHE_DIR", t.TempDir())
+	// Odd has a non-zero reserved padding field
+	clean := (&proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}}}},
+	}).WriteFile(t)
+	odd := (&proftest.Profile{
+		Padding:     0x10,
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}}}},
+	}).WriteFile(t)
+
+	// Each row runs after the previous ones. The strict cached row doesn't find the
+	// entry written by the lenient cached load, as strict is part of the cache key.
+	tests := []struct {
+		name     string
+		path     string
+		args     map[string]interface{}
+		warnings int
+		wantErr  bool
+	}{
+		{"clean", clean, nil, 0, false},
+		{"clean, strict", clean, map[string]interface{}{"strict_header": true}, 0, false},
+		{"reserved field set", odd, nil, 1, false},
+		{"reserved field set, strict", odd, map[string]interface{}{"strict_header": true}, 0, true},
+		{"cached lenient load", odd, map[string]interface{}{"use_cache": true}, 1, false},
+		{"cached strict load", odd, map[string]interface{}{"use_cache": true, "strict_header": true}, 0, true},
+	}
+
+	resetRegistry(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]interface{}{"file_path": tt.path}
+			for key, value := range tt.args {
+				args[key] = value
+			}
+			text, isError := callTool(t, loadProfileHandler, args)
+			if isError != tt.wantErr {
+				t.Fatalf("tool error = %t, want %t: %s", isError, tt.wantErr, text)
+			}
+			if isError {
+				if !strings.Contains(text, "unexpected header layout") {
+					t.Errorf("error %q doesn't explain the header", text)
+				}
+				return
+			}
+
+			var summary map[string]interface{}
+			if err := json.Unmarshal([]byte(text), &summary); err != nil {
+				t.Fatalf("summary is not JSON: %v", err)
+			}
+			warnings, _ := summary["header_warnings"].([]interface{})
+			if len(warnings) != tt.warnings {
+				t.Errorf("header_warnings = %v, want %d", summary["header_warnings"], tt.warnings)
+			}
+		})
+	}
+}
+
+func TestListSourceFilesHandler(t *testing.T) {
+	descriptors := []proftest.Descriptor{
+		{ID: 1, Name: "Update", File: "engine.cpp", Line: 10, Type: parser.BlockTypeBlock},
+		{ID: 2, Name: "Render", File: "engine.cpp", Line: 20, Type: parser.BlockTypeBlock},
+	}
+
+	tests := []struct {
+		name       string
+		begin, end uint64
+		blocks     []proftest.Block
+		want       string // "file functions calls self percent"
+	}{
+		{"two functions in one file", 0, 100, []proftest.Block{{ID: 1, Begin: 0, End: 30}, {ID: 2, Begin: 30, End: 50}}, "engine.cpp 2 2 50ns 50.00%"},
+		{"zero-length capture", 100, 100, []proftest.Block{{ID: 1, Begin: 100, End: 100}}, "engine.cpp 2 1 0s 0.00%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, &proftest.Profile{
+				Begin:       tt.begin,
+				End:         tt.end,
+				Descriptors: descriptors,
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: tt.blocks}},
+			}, nil)
+
+			result := callToolJSON(t, listSourceFilesHandler, nil)
+			files := list(t, result, "files")
+			if result["file_count"] != float64(1) || len(files) != 1 {
+				t.Fatalf("file_count = %v with %d files, want 1", result["file_count"], len(files))
+			}
+			file := files[0]
+			got := fmt.Sprintf("%s %v %v %s %s", file["file"], file["functions"], file["call_count"], file["total_self_duration"], file["percent_of_total"])
+			if got != tt.want {
+				t.Errorf("file = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetOverviewIdleBlocks(t *testing.T) {
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         1000,
+		Descriptors: proftest.Descriptors("Work", "Job", "WaitForWork"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 3, Begin: 200, End: 800}, {ID: 2, Begin: 0, End: 1000}}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		idle        interface{}
+		parallelism string
+		idleBlocks  string // Empty when the overview shouldn't list them
+		idleTime    string
+	}{
+		{"none", nil, "2.00", "", ""},
+		{"blank list", " , ", "2.00", "", ""},
+		{"one name", "WaitForWork", "1.40", "[WaitForWork]", "600ns"},
+		{"list with spaces", " Sleep , Wait* ", "1.40", "[Sleep Wait*]", "600ns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			args := map[string]interface{}{}
+			if tt.idle != nil {
+				args["idle_blocks"] = tt.idle
+			}
+			loadTestProfile(t, p, args)
+
+			overview := callToolJSON(t, getOverviewHandler, nil)
+			if overview["parallelism"] != tt.parallelism {
+				t.Errorf("parallelism = %v, want %s", overview["parallelism"], tt.parallelism)
+			}
+			blocks, listed := overview["idle_blocks"]
+			if listed != (tt.idleBlocks != "") {
+				t.Fatalf("idle_blocks = %v, want listed %t", blocks, tt.idleBlocks != "")
+			}
+			if listed && (fmt.Sprint(blocks) != tt.idleBlocks || overview["idle_time"] != tt.idleTime) {
+				t.Errorf("idle_blocks %v for %v, want %s for %s", blocks, overview["idle_time"], tt.idleBlocks, tt.idleTime)
+			}
+		})
+	}
+}
+
+func TestGetFrameStatisticsHandler(t *testing.T) {
+	// Frame runs every 100ns on Main; Worker calls Job once
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Job"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 1, Begin: 0, End: 50},
+				{ID: 1, Begin: 100, End: 150},
+				{ID: 1, Begin: 200, End: 250},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 20}}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr string
+		want    map[string]interface{}
+	}{
+		{"auto-detected", nil, "", map[string]interface{}{
+			"frame_block": "Frame", "auto_detected": true, "confidence": "100.00%", "thread_name": "Main",
+			"frames": 3.0, "avg_duration": "50ns", "avg_interval": "100ns", "frames_per_second": "10000000.0",
+		}},
+		{"named", map[string]interface{}{"frame_block": "Job"}, "", map[string]interface{}{
+			"frame_block": "Job", "auto_detected": false, "confidence": nil, "thread_name": "Worker",
+			"frames": 1.0, "max_duration": "20ns", "avg_interval": nil, "frames_per_second": nil,
+		}},
+		{"unknown block", map[string]interface{}{"frame_block": "Render"}, "function 'Render' not found", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, p, nil)
+
+			if tt.wantErr != "" {
+				if text, isError := callTool(t, getFrameStatisticsHandler, tt.args); !isError || text != tt.wantErr {
+					t.Errorf("result %q (error %t), want error %q", text, isError, tt.wantErr)
+				}
+				return
+			}
+			result := callToolJSON(t, getFrameStatisticsHandler, tt.args)
+			for key, value := range tt.want {
+				if result[key] != value {
+					t.Errorf("%s = %v, want %v", key, result[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestGetFrameStatisticsHandlerNoFrames(t *testing.T) {
+	resetRegistry(t)
+	if _, isError := callTool(t, getFrameStatisticsHandler, nil); !isError {
+		t.Error("succeeded without a profile")
+	}
+
+	loadTestProfile(t, runCapture(10, 20), nil)
+	if text, isError := callTool(t, getFrameStatisticsHandler, nil); !isError || !strings.Contains(text, "name the frame block explicitly") {
+		t.Errorf("result %q (error %t), want the detection error", text, isError)
+	}
+}
+
+func TestGetSlowestBlocksHandlerIncludePath(t *testing.T) {
+	// Frame holds Update, which calls memcpy, and Render
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render", "memcpy"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 4, Begin: 5, End: 45},
+			{ID: 2, Begin: 0, End: 50},
+			{ID: 3, Begin: 50, End: 60},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		retain  float64
+		wantErr bool
+		want    []string // "name parent path" per block, path as name:file:line:depth
+	}{
+		{"without path", nil, 0, false, []string{"Frame <nil> <nil>", "Update <nil> <nil>", "memcpy <nil> <nil>", "Render <nil> <nil>"}},
+		{"with path", map[string]interface{}{"include_path": true}, 0, false, []string{
+			"Frame <nil> []",
+			"Update Frame [Frame:Frame.cpp:10:0]",
+			"memcpy Update [Frame:Frame.cpp:10:0 Update:Update.cpp:20:1]",
+			"Render Frame [Frame:Frame.cpp:10:0]",
+		}},
+		{"distinct", map[string]interface{}{"include_path": true, "distinct": true, "limit": 2.0}, 0, false, []string{
+			"Frame <nil> []",
+			"Update Frame [Frame:Frame.cpp:10:0]",
+		}},
+		{"retained", map[string]interface{}{"include_path": true}, 2, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, p, map[string]interface{}{"retain_slowest": tt.retain})
+
+			if tt.wantErr {
+				if text, isError := callTool(t, getSlowestBlocksHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+
+			var got []string
+			for _, block := range callToolList(t, getSlowestBlocksHandler, tt.args) {
+				path := "<nil>"
+				if nodes, ok := block["path"].([]interface{}); ok {
+					var steps []string
+					for _, node := range nodes {
+						node := node.(map[string]interface{})
+						steps = append(steps, fmt.Sprintf("%v:%v:%v:%v", node["name"], node["file"], node["line"], node["depth"]))
+					}
+					path = fmt.Sprint(steps)
+				}
+				got = append(got, fmt.Sprintf("%v %v %s", block["name"], block["parent"], path))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blocks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBlockByKeyHandler(t *testing.T) {
+	// Frame holds Update, which calls memcpy, and Render
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render", "memcpy"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 4, Begin: 5, End: 45},
+			{ID: 2, Begin: 0, End: 50},
+			{ID: 3, Begin: 50, End: 60},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    string // "key name duration self depth path"
+		wantErr string
+	}{
+		{"top level", map[string]interface{}{"block_key": "1/1/0"}, "1/1/0 Frame 100ns 40ns 0 []", ""},
+		{"nested", map[string]interface{}{"block_key": "1/4/0"}, "1/4/0 memcpy 40ns 40ns 2 [Frame Update]", ""},
+		{"missing key", nil, "", "block_key parameter is required"},
+		{"malformed key", map[string]interface{}{"block_key": "Frame"}, "", "invalid block key"},
+		{"unknown block", map[string]interface{}{"block_key": "1/3/1"}, "", "block 1/3/1 not found"},
+	}
+
+	resetRegistry(t)
+	loadTestProfile(t, p, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr != "" {
+				if text, isError := callTool(t, getBlockByKeyHandler, tt.args); !isError || !strings.Contains(text, tt.wantErr) {
+					t.Errorf("result = %q (error %v), want an error containing %q", text, isError, tt.wantErr)
+				}
+				return
+			}
+
+			block := callToolJSON(t, getBlockByKeyHandler, tt.args)
+			var path []string
+			for _, node := range list(t, block, "path") {
+				path = append(path, fmt.Sprint(node["name"]))
+			}
+			got := fmt.Sprintf("%v %v %v %v %v %v", block["block_key"], block["name"], block["duration"], block["self_duration"], block["depth"], path)
+			if got != tt.want {
+				t.Errorf("block = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBlockByKeyHandlerRoundTrip(t *testing.T) {
+	// Every key get_slowest_blocks hands out resolves back to the same block
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render", "memcpy"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 4, Begin: 5, End: 45},
+			{ID: 2, Begin: 0, End: 50},
+			{ID: 3, Begin: 50, End: 60},
+			{ID: 1, Begin: 0, End: 100},
+			{ID: 4, Begin: 110, End: 130},
+			{ID: 2, Begin: 105, End: 140},
+			{ID: 1, Begin: 100, End: 200},
+		}}},
+	}
+	resetRegistry(t)
+	loadTestProfile(t, p, nil)
+
+	slowest := callToolList(t, getSlowestBlocksHandler, nil)
+	if len(slowest) != 7 {
+		t.Fatalf("got %d slowest blocks, want 7", len(slowest))
+	}
+	keys := make(map[string]bool)
+	for _, block := range slowest {
+		key, _ := block["block_key"].(string)
+		if keys[key] {
+			t.Errorf("block_key %q handed out twice", key)
+		}
+		keys[key] = true
+
+		found := callToolJSON(t, getBlockByKeyHandler, map[string]interface{}{"block_key": key})
+		if found["name"] != block["name"] || found["duration"] != block["duration"] {
+			t.Errorf("block_key %q resolves to %v %v, want %v %v", key, found["name"], found["duration"], block["name"], block["duration"])
+		}
+	}
+}
+
+func TestGetNarrativeSummaryHandler(t *testing.T) {
+	// Main runs Frame, which calls Update twice (30ns of self time each) and Render
+	// (20ns); Worker runs one 70ns Job, the worst single block
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         200,
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render", "Job"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 0, End: 30},
+				{ID: 2, Begin: 40, End: 70},
+				{ID: 3, Begin: 70, End: 90},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 4, Begin: 100, End: 170}}},
+		},
+	}
+	resetRegistry(t)
+	loadTestProfile(t, p, nil)
+
+	summary := callToolJSON(t, getNarrativeSummaryHandler, nil)
+	dominant, _ := summary["dominant_thread"].(map[string]interface{})
+	top, _ := dominant["top_function"].(map[string]interface{})
+	worst, _ := summary["worst_block"].(map[string]interface{})
+	issues, _ := summary["issues"].(map[string]interface{})
+
+	got := fmt.Sprintf("%v %v | %v %v %v %v | %v %v %v %v | %v",
+		summary["total_duration"], summary["parallelism"],
+		dominant["thread_name"], dominant["busy_time"], top["name"], top["self_duration"],
+		top["call_count"], top["percent_of_thread"], worst["name"], worst["block_key"],
+		worst["thread_name"])
+	want := "200ns 1.00 | Main 100ns Update 60ns | 2 60.00% Job 2/4/0 | Worker"
+	if got != want {
+		t.Errorf("summary = %s, want %s", got, want)
+	}
+
+	// Each fact matches the tool it is taken from
+	threads := callToolList(t, getThreadStatisticsHandler, nil)
+	for _, key := range []string{"thread_id", "thread_name", "percent_of_total"} {
+		if dominant[key] != threads[0][key] {
+			t.Errorf("dominant thread %s = %v, get_thread_statistics has %v", key, dominant[key], threads[0][key])
+		}
+	}
+	if dominant["busy_time"] != threads[0]["total_duration"] {
+		t.Errorf("dominant thread busy time = %v, get_thread_statistics has %v", dominant["busy_time"], threads[0]["total_duration"])
+	}
+	invocation := callToolJSON(t, getWorstInvocationHandler, nil)
+	for _, key := range []string{"name", "block_key", "self_duration", "offset", "thread_name"} {
+		if worst[key] != invocation[key] {
+			t.Errorf("worst block %s = %v, get_worst_invocation has %v", key, worst[key], invocation[key])
+		}
+	}
+	if overview := callToolJSON(t, getOverviewHandler, nil); summary["parallelism"] != overview["parallelism"] {
+		t.Errorf("parallelism = %v, get_overview has %v", summary["parallelism"], overview["parallelism"])
+	}
+	analysis := callToolJSON(t, analyzePerformanceIssuesHandler, nil)
+	bySeverity, _ := analysis["by_severity"].(map[string]interface{})
+	high, _ := bySeverity["high"].([]interface{})
+	if issues["total"] != analysis["total_issues"] || issues["high_severity"] != float64(len(high)) {
+		t.Errorf("issues = %v high of %v, analyze_performance_issues has %d of %v", issues["high_severity"], issues["total"], len(high), analysis["total_issues"])
+	}
+	if len(high) > 0 {
+		headline, _ := issues["headline"].(map[string]interface{})
+		first, _ := high[0].(map[string]interface{})
+		if headline["type"] != first["type"] || headline["description"] != first["description"] {
+			t.Errorf("headline = %v, want the first high-severity issue %v", headline, first)
+		}
+	}
+}
+
+func TestGetNarrativeSummaryHandlerSize(t *testing.T) {
+	// Long names are cut, so the payload stays small whatever the capture holds
+	long := strings.Repeat("VeryLongTemplateName<", 50)
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors(long+"Frame", long+"Update"),
+		Threads: []proftest.Thread{{ID: 1, Name: long + "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10, End: 60},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}
+	resetRegistry(t)
+	loadTestProfile(t, p, nil)
+
+	text, isError := callTool(t, getNarrativeSummaryHandler, nil)
+	if isError {
+		t.Fatalf("tool error: %s", text)
+	}
+	if len(text) > 2048 {
+		t.Errorf("summary is %d bytes, want at most 2048:\n%s", len(text), text)
+	}
+
+	var summary map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &summary); err != nil {
+		t.Fatalf("result is not JSON: %v", err)
+	}
+	dominant, _ := summary["dominant_thread"].(map[string]interface{})
+	top, _ := dominant["top_function"].(map[string]interface{})
+	worst, _ := summary["worst_block"].(map[string]interface{})
+	for _, name := range []interface{}{dominant["thread_name"], top["name"], worst["name"]} {
+		if s, _ := name.(string); utf8.RuneCountInString(s) != narrativeTextLimit || !strings.HasSuffix(s, nameEllipsis) {
+			t.Errorf("name %q is not cut to %d characters", s, narrativeTextLimit)
+		}
+	}
+}
+
+func TestGetNarrativeSummaryHandlerEdgeCases(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile *proftest.Profile
+		load    map[string]interface{}
+		want    string // "dominant top percent-of-thread worst", or the error
+		wantErr bool
+	}{
+		{"zero busy time", &proftest.Profile{
+			Begin:       100,
+			End:         200,
+			Descriptors: proftest.Descriptors("Mark"),
+			Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 150, End: 150}}}},
+		}, nil, "Main Mark <nil> 1/1/0", false},
+		{"no threads", &proftest.Profile{Begin: 100, End: 200}, nil, "<nil> <nil> <nil> <nil>", false},
+		{"retained profile", &proftest.Profile{
+			Descriptors: proftest.Descriptors("Frame"),
+			Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 100}}}},
+		}, map[string]interface{}{"retain_slowest": float64(10)}, "retain_slowest", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, tt.profile, tt.load)
+			if tt.wantErr {
+				if text, isError := callTool(t, getNarrativeSummaryHandler, nil); !isError || !strings.Contains(text, tt.want) {
+					t.Errorf("result = %q (error %v), want an error mentioning %s", text, isError, tt.want)
+				}
+				return
+			}
+
+			summary := callToolJSON(t, getNarrativeSummaryHandler, nil)
+			dominant, _ := summary["dominant_thread"].(map[string]interface{})
+			top, _ := dominant["top_function"].(map[string]interface{})
+			worst, _ := summary["worst_block"].(map[string]interface{})
+			got := fmt.Sprintf("%v %v %v %v", dominant["thread_name"], top["name"], top["percent_of_thread"], worst["block_key"])
+			if got != tt.want {
+				t.Errorf("summary = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetNarrativeSummaryHandlerNoProfile(t *testing.T) {
+	resetRegistry(t)
+	if text, isError := callTool(t, getNarrativeSummaryHandler, nil); !isError {
+		t.Errorf("succeeded without a profile: %s", text)
+	}
+}
+
+func TestDetectLockConvoysHandler(t *testing.T) {
+	// A, B and C pass Lock around back to back, then Mutex with only two handoffs
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         1000,
+		Descriptors: proftest.Descriptors("Lock", "Mutex"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "A", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}, {ID: 1, Begin: 30, End: 40}, {ID: 2, Begin: 500, End: 510}}},
+			{ID: 2, Name: "B", Blocks: []proftest.Block{{ID: 1, Begin: 10, End: 20}, {ID: 2, Begin: 510, End: 520}}},
+			{ID: 3, Name: "C", Blocks: []proftest.Block{{ID: 1, Begin: 20, End: 30}, {ID: 2, Begin: 520, End: 530}}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		capture  [2]uint64 // Begin and end in the header
+		args     map[string]interface{}
+		wantErr  string
+		settings string   // "max_gap min_handoffs"
+		want     []string // "lock handoffs serialized percent longest-threads"
+	}{
+		{"defaults", [2]uint64{0, 1000}, nil, "", "50µs 3", []string{"Lock 3 40ns 4.00% [A B C]"}},
+		{"fewer handoffs", [2]uint64{0, 1000}, map[string]interface{}{"min_handoffs": 2.0}, "", "50µs 2", []string{"Lock 3 40ns 4.00% [A B C]", "Mutex 2 30ns 3.00% [A B C]"}},
+		{"min handoffs clamped", [2]uint64{0, 1000}, map[string]interface{}{"min_handoffs": 0.0, "pattern": "Mutex"}, "", "50µs 1", []string{"Mutex 2 30ns 3.00% [A B C]"}},
+		{"zero gap", [2]uint64{0, 1000}, map[string]interface{}{"max_gap": "0s"}, "", "0s 3", []string{"Lock 3 40ns 4.00% [A B C]"}},
+		{"zero-length capture", [2]uint64{1000, 1000}, nil, "", "50µs 3", []string{"Lock 3 40ns <nil> [A B C]"}},
+		{"negative gap", [2]uint64{0, 1000}, map[string]interface{}{"max_gap": "-1ms"}, "max_gap must not be negative", "", nil},
+		{"bad gap", [2]uint64{0, 1000}, map[string]interface{}{"max_gap": "soon"}, "Invalid max_gap", "", nil},
+		{"bad pattern", [2]uint64{0, 1000}, map[string]interface{}{"pattern": "("}, "invalid lock pattern", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			p.Begin, p.End = tt.capture[0], tt.capture[1]
+			loadTestProfile(t, p, nil)
+
+			if tt.wantErr != "" {
+				if text, isError := callTool(t, detectLockConvoysHandler, tt.args); !isError || !strings.Contains(text, tt.wantErr) {
+					t.Errorf("result %q (error %t), want an error containing %q", text, isError, tt.wantErr)
+				}
+				return
+			}
+
+			result := callToolJSON(t, detectLockConvoysHandler, tt.args)
+			if settings := fmt.Sprintf("%v %v", result["max_gap"], result["min_handoffs"]); settings != tt.settings {
+				t.Errorf("settings = %s, want %s", settings, tt.settings)
+			}
+			var got []string
+			for _, lock := range list(t, result, "locks") {
+				longest := lock["longest_convoy"].(map[string]interface{})
+				got = append(got, fmt.Sprintf("%v %v %v %v %v", lock["lock"], lock["handoffs"], lock["serialized_time"], lock["percent_of_capture"], longest["threads"]))
+			}
+			if !reflect.DeepEqual(got, tt.want) || result["lock_count"] != float64(len(tt.want)) {
+				t.Errorf("%v locks = %q, want %q", result["lock_count"], got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeBudgetArgument(t *testing.T) {
+	// Overlapping siblings, so get_overlapping_blocks has something to find
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 0, End: 50},
+			{ID: 3, Begin: 40, End: 90},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}
+	resetRegistry(t)
+	loadTestProfile(t, p, nil)
+
+	handlers := []struct {
+		name    string
+		handler server.ToolHandlerFunc
+		args    map[string]interface{}
+	}{
+		{"get_hotspots", getHotspotsHandler, nil},
+		{"get_hot_path_for_thread", getHotPathForThreadHandler, map[string]interface{}{"thread": "Main"}},
+		{"get_overlapping_blocks", getOverlappingBlocksHandler, nil},
+	}
+	budgets := []struct {
+		budget  string
+		wantErr bool
+	}{
+		{"1h", false},
+		{"0s", true},
+		{"-1s", true},
+		{"soon", true},
+	}
+
+	for _, h := range handlers {
+		for _, b := range budgets {
+			t.Run(h.name+" "+b.budget, func(t *testing.T) {
+				args := map[string]interface{}{"time_budget": b.budget}
+				for key, value := range h.args {
+					args[key] = value
+				}
+				text, isError := callTool(t, h.handler, args)
+				if isError != b.wantErr {
+					t.Fatalf("isError = %t, want %t: %s", isError, b.wantErr, text)
+				}
+				if b.wantErr && text != "Invalid time_budget: "+b.budget {
+					t.Errorf("error = %q", text)
+				}
+				if !b.wantErr && strings.Contains(text, "timed_out\":true") {
+					t.Errorf("timed out within an hour: %s", text)
+				}
+			})
+		}
+	}
+}
+
+func TestTimeBudgetExpired(t *testing.T) {
+	// A context that is already done stands in for a budget running out. Main has
+	// overlapping siblings; Worker runs 2000 Steps, the i-th lasting i+1ns, enough
+	// for the critical path's scan to poll the budget.
+	steps := make([]proftest.Block, 2000)
+	for i := range steps {
+		begin := uint64(i) * 5000
+		steps[i] = proftest.Block{ID: 4, Begin: begin, End: begin + uint64(i) + 1}
+	}
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render", "Step"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 0, End: 50},
+				{ID: 3, Begin: 40, End: 90},
+				{ID: 1, Begin: 0, End: 100},
+			}},
+			{ID: 2, Name: "Worker", Blocks: steps},
+		},
+	}
+	resetRegistry(t)
+	loadTestProfile(t, p, nil)
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	call := func(handler server.ToolHandlerFunc, args map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		var request mcp.CallToolRequest
+		request.Params.Arguments = args
+		result, err := handler(done, request)
+		if err != nil || result.IsError {
+			t.Fatalf("handler failed: %v %+v", err, result)
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &decoded); err != nil {
+			t.Fatalf("result is not a JSON object: %v", err)
+		}
+		return decoded
+	}
+
+	tests := []struct {
+		name    string
+		handler server.ToolHandlerFunc
+		args    map[string]interface{}
+		check   func(result map[string]interface{}) error
+	}{
+		{"get_hotspots", getHotspotsHandler, map[string]interface{}{"time_budget": "1h"}, func(result map[string]interface{}) error {
+			if hotspots, _ := result["hotspots"].([]interface{}); len(hotspots) != 0 {
+				return fmt.Errorf("%d hotspots before aggregating anything", len(hotspots))
+			}
+			return nil
+		}},
+		{"get_hot_path_for_thread", getHotPathForThreadHandler, map[string]interface{}{"thread": "Worker", "time_budget": "1h"}, func(result map[string]interface{}) error {
+			// The scan stops at its first poll, before the 1025th Step and the longer ones after
+			path := result["path"].([]interface{})
+			if duration := path[0].(map[string]interface{})["duration"]; len(path) != 1 || duration != "1.024µs" {
+				return fmt.Errorf("path has %d nodes from a %v block, want one from the 1.024µs one", len(path), duration)
+			}
+			return nil
+		}},
+		{"get_overlapping_blocks", getOverlappingBlocksHandler, map[string]interface{}{"time_budget": "1h"}, func(result map[string]interface{}) error {
+			if _, noted := result["note"]; noted || result["count"] != 0.0 {
+				return fmt.Errorf("claims a complete search: %v", result)
+			}
+			return nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := call(tt.handler, tt.args)
+			if result["timed_out"] != true {
+				t.Errorf("timed_out = %v, want true", result["timed_out"])
+			}
+			if err := tt.check(result); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestGetHotspotsTimeBudgetShape(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, runCapture(10, 20), nil)
+
+	// Without a budget the result stays the bare list older clients expect
+	for _, budget := range []interface{}{nil, ""} {
+		args := map[string]interface{}{}
+		if budget != nil {
+			args["time_budget"] = budget
+		}
+		if hotspots := callToolList(t, getHotspotsHandler, args); len(hotspots) != 3 {
+			t.Errorf("time_budget %q: %d hotspots, want 3", budget, len(hotspots))
+		}
+	}
+
+	result := callToolJSON(t, getHotspotsHandler, map[string]interface{}{"time_budget": "1h"})
+	if result["timed_out"] != false || len(list(t, result, "hotspots")) != 3 {
+		t.Errorf("budgeted result = %v, want 3 hotspots, not timed out", result)
+	}
+}
+
+func TestGetDurationDistributionHandler(t *testing.T) {
+	us := uint64(time.Microsecond)
+	tests := []struct {
+		name    string
+		blocks  []proftest.Block
+		args    map[string]interface{}
+		retain  float64
+		wantErr bool
+		want    []string // "range count percent_of_blocks total percent_of_time" of non-empty buckets
+		mode    string
+	}{
+		{
+			name:   "tiny and huge",
+			blocks: []proftest.Block{{ID: 2, Begin: 0, End: 500}, {ID: 2, Begin: us, End: 2 * us}, {ID: 1, Begin: 0, End: 3 * us}},
+			want:   []string{"<1µs 1 33.33% 500ns 11.11%", "1µs-10µs 2 66.67% 4µs 88.89%"},
+			mode:   "inclusive",
+		},
+		{
+			name:   "exclusive",
+			blocks: []proftest.Block{{ID: 2, Begin: 0, End: 500}, {ID: 2, Begin: us, End: 2 * us}, {ID: 1, Begin: 0, End: 3 * us}},
+			args:   map[string]interface{}{"exclusive": true},
+			want:   []string{"<1µs 1 33.33% 500ns 16.67%", "1µs-10µs 2 66.67% 2.5µs 83.33%"},
+			mode:   "exclusive",
+		},
+		{
+			name:   "zero total time",
+			blocks: []proftest.Block{{ID: 1, Begin: 10, End: 10}, {ID: 1, Begin: 20, End: 20}},
+			want:   []string{"<1µs 2 100.00% 0s 0.00%"},
+			mode:   "inclusive",
+		},
+		{name: "retained", blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}}, retain: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, &proftest.Profile{
+				Descriptors: proftest.Descriptors("Frame", "Step"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: tt.blocks}},
+			}, map[string]interface{}{"retain_slowest": tt.retain})
+
+			if tt.wantErr {
+				if text, isError := callTool(t, getDurationDistributionHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+
+			result := callToolJSON(t, getDurationDistributionHandler, tt.args)
+			buckets := list(t, result, "buckets")
+			var got []string
+			for _, bucket := range buckets {
+				if bucket["count"] != 0.0 {
+					got = append(got, fmt.Sprintf("%v %v %v %v %v", bucket["range"], bucket["count"], bucket["percent_of_blocks"], bucket["total_duration"], bucket["percent_of_time"]))
+				}
+			}
+			if len(buckets) != 8 || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%d buckets with %q, want 8 with %q", len(buckets), got, tt.want)
+			}
+			if result["time_mode"] != tt.mode || result["block_count"] != float64(len(tt.blocks)) {
+				t.Errorf("time_mode %v, block_count %v", result["time_mode"], result["block_count"])
+			}
+		})
+	}
+}
+
+func TestGetDurationDistributionHandlerNoBlocks(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{Threads: []proftest.Thread{{ID: 1, Name: "Idle"}}}, nil)
+
+	result := callToolJSON(t, getDurationDistributionHandler, nil)
+	for _, bucket := range list(t, result, "buckets") {
+		if bucket["percent_of_blocks"] != "0.00%" || bucket["percent_of_time"] != "0.00%" {
+			t.Errorf("bucket %v has non-zero shares without blocks", bucket)
+		}
+	}
+}
+
+func TestGetThreadStatisticsHandlerWallSpan(t *testing.T) {
+	// Sparse works 20ns across a 1µs span, switched out between its blocks; Idle has no blocks
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Work"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Sparse",
+				ContextSwitches: []proftest.ContextSwitch{{ThreadID: 1, Begin: 10, End: 990}},
+				Blocks:          []proftest.Block{{ID: 1, Begin: 0, End: 10}, {ID: 1, Begin: 990, End: 1000}},
+			},
+			{ID: 2, Name: "Idle"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want map[string]string // thread name to "span idle busy% switched-out", <nil> for missing fields
+	}{
+		{"off", nil, map[string]string{"Sparse": "<nil> <nil> <nil> <nil>", "Idle": "<nil> <nil> <nil> <nil>"}},
+		{"on", map[string]interface{}{"wall_span": true}, map[string]string{
+			"Sparse": "1µs 980ns 2.00% 980ns",
+			"Idle":   "0s 0s <nil> 0s",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, p, nil)
+
+			got := make(map[string]string)
+			for _, stat := range callToolList(t, getThreadStatisticsHandler, tt.args) {
+				got[stat["thread_name"].(string)] = fmt.Sprintf("%v %v %v %v",
+					stat["wall_span"], stat["idle_in_span"], stat["busy_percent_of_span"], stat["switched_out_in_span"])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("threads = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetThreadStatisticsHandlerWallSpanWithoutSwitches(t *testing.T) {
+	mock := newMockProfile()
+	mock.NoContextSwitches = true
+	useMockProfile(t, mock)
+
+	stat := callToolList(t, getThreadStatisticsHandler, map[string]interface{}{"wall_span": true})[0]
+	if _, reported := stat["switched_out_in_span"]; reported || stat["wall_span"] != "100ns" {
+		t.Errorf("stat = %v, want a 100ns span without switched_out_in_span", stat)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/easyprofiler-mcp/analyzer"
+)
+
+// markdownSeverities are the report's sections, most severe first
+var markdownSeverities = []string{"high", "medium", "low"}
+
+// renderIssuesMarkdown formats detected issues as a Markdown report for pasting into
+// an issue or pull request: a summary line, then one section per severity with a
+// table of its issues. issues is the full detection result and shown the subset to
+// list; issue numbers match analyze_performance_issues so explain_issue can be used.
+func renderIssuesMarkdown(title string, issues, shown []*analyzer.PerformanceIssue) string {
+	var b strings.Builder
+
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		counts[issue.Severity]++
+	}
+
+	fmt.Fprintf(&b, "# Performance issues: %s\n\n", markdownText(title))
+	fmt.Fprintf(&b, "Found **%d** performance issues: %d high, %d medium, %d low.\n",
+		len(issues), counts["high"], counts["medium"], counts["low"])
+	if len(shown) < len(issues) {
+		fmt.Fprintf(&b, "\nShowing %d of %d issues.\n", len(shown), len(issues))
+	}
+
+	for _, severity := range markdownSeverities {
+		fmt.Fprintf(&b, "\n## %s severity\n\n", strings.ToUpper(severity[:1])+severity[1:])
+
+		rows := 0
+		for i, issue := range shown {
+			if issue.Severity != severity {
+				continue
+			}
+			if rows == 0 {
+				b.WriteString("| # | Type | Score | Location | Thread | Duration | Description | Suggestion |\n")
+				b.WriteString("|---|------|-------|----------|--------|----------|-------------|------------|\n")
+			}
+			rows++
+
+			duration := ""
+			if issue.Duration > 0 {
+				duration = humanDuration(issue.Duration)
+			}
+			fmt.Fprintf(&b, "| %d | %s | %.1f | %s | %s | %s | %s | %s |\n",
+				i+1,
+				markdownText(issue.Type),
+				issue.Score,
+				markdownCode(issue.Location),
+				markdownText(issue.ThreadName),
+				duration,
+				markdownText(issue.Description),
+				markdownText(issue.Suggestion))
+		}
+
+		if rows == 0 {
+			b.WriteString("_None._\n")
+		}
+	}
+
+	return b.String()
+}
+
+// markdownText escapes s for use in a table cell
+func markdownText(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r", "")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// markdownCode renders s as inline code in a table cell, or nothing if s is empty
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	s = strings.ReplaceAll(s, "`", "'")
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r", "")
+	return "`" + strings.ReplaceAll(s, "\n", " ") + "`"
+}
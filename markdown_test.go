@@ -0,0 +1,159 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/analyzer"
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// markdownSections maps each "## ..." heading of a report to the issue numbers of
+// its table rows, with "none" for a section reported as empty
+func markdownSections(t *testing.T, report string) map[string][]string {
+	t.Helper()
+	sections := make(map[string][]string)
+	section := ""
+	for _, line := range strings.Split(report, "\n") {
+		switch {
+		case strings.HasPrefix(line, "## "):
+			section = strings.TrimPrefix(line, "## ")
+			sections[section] = nil
+		case line == "_None._":
+			sections[section] = append(sections[section], "none")
+		case strings.HasPrefix(line, "| ") && !strings.HasPrefix(line, "| # "):
+			cells := strings.Split(line, " | ")
+			if len(cells) != 8 {
+				t.Fatalf("row %q has %d cells, want 8", line, len(cells))
+			}
+			sections[section] = append(sections[section], strings.TrimPrefix(cells[0], "| "))
+		}
+	}
+	return sections
+}
+
+func TestRenderIssuesMarkdown(t *testing.T) {
+	issues := []*analyzer.PerformanceIssue{
+		{Type: "hot_function", Severity: "high", Score: 80, Location: "Update (game.cpp:10)", Duration: 5 * time.Millisecond, ThreadName: "Main", Description: "Update is hot"},
+		{Type: "thread_imbalance", Severity: "high", Score: 60, Description: "Main does | everything\nelse idles"},
+		{Type: "fragmented", Severity: "medium", Score: 30, Location: "Tick", ThreadName: "Worker"},
+		{Type: "deep_recursion", Severity: "low", Score: 5, Location: "Walk"},
+	}
+
+	tests := []struct {
+		name     string
+		shown    int
+		sections map[string][]string
+		showing  bool
+	}{
+		{"all issues", 4, map[string][]string{
+			"High severity":   {"1", "2"},
+			"Medium severity": {"3"},
+			"Low severity":    {"4"},
+		}, false},
+		{"high only", 2, map[string][]string{
+			"High severity":   {"1", "2"},
+			"Medium severity": {"none"},
+			"Low severity":    {"none"},
+		}, true},
+		{"none shown", 0, map[string][]string{
+			"High severity":   {"none"},
+			"Medium severity": {"none"},
+			"Low severity":    {"none"},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := renderIssuesMarkdown("capture.prof", issues, issues[:tt.shown])
+			if !strings.HasPrefix(report, "# Performance issues: capture.prof\n\nFound **4** performance issues: 2 high, 1 medium, 1 low.\n") {
+				t.Errorf("report starts with %q", report[:min(len(report), 120)])
+			}
+			if got := markdownSections(t, report); !reflect.DeepEqual(got, tt.sections) {
+				t.Errorf("sections = %v, want %v", got, tt.sections)
+			}
+			if got := strings.Contains(report, "Showing "); got != tt.showing {
+				t.Errorf("report mentions a partial listing = %t, want %t", got, tt.showing)
+			}
+			if report != renderIssuesMarkdown("capture.prof", issues, issues[:tt.shown]) {
+				t.Error("rendering the same issues twice differs")
+			}
+		})
+	}
+}
+
+func TestMarkdownEscaping(t *testing.T) {
+	tests := []struct {
+		input string
+		text  string
+		code  string
+	}{
+		{"plain", "plain", "`plain`"},
+		{"a|b", `a\|b`, "`a\\|b`"},
+		{"two\r\nlines", "two<br>lines", "`two lines`"},
+		{`back\slash`, `back\\slash`, "`back\\slash`"},
+		{"`tick`", "`tick`", "`'tick'`"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := markdownText(tt.input); got != tt.text {
+				t.Errorf("markdownText(%q) = %q, want %q", tt.input, got, tt.text)
+			}
+			if got := markdownCode(tt.input); got != tt.code {
+				t.Errorf("markdownCode(%q) = %q, want %q", tt.input, got, tt.code)
+			}
+		})
+	}
+}
+
+func TestExportIssuesMarkdownHandler(t *testing.T) {
+	resetRegistry(t)
+	// Spin dominates Main, which is a high-severity hot function
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Spin"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: uint64(time.Second)}}}},
+	}, nil)
+	outputPath := filepath.Join(t.TempDir(), "issues.md")
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr bool
+		title   string
+	}{
+		{"default title", nil, false, "# Performance issues: capture.prof\n"},
+		{"custom title", map[string]interface{}{"title": "Nightly | run"}, false, "# Performance issues: Nightly \\| run\n"},
+		{"written to a file", map[string]interface{}{"output_path": outputPath}, false, "# Performance issues: capture.prof\n"},
+		{"invalid severity", map[string]interface{}{"min_severity": "urgent"}, true, ""},
+		{"unwritable path", map[string]interface{}{"output_path": filepath.Join(outputPath, "missing", "x.md")}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, isError := callTool(t, exportIssuesMarkdownHandler, tt.args)
+			if isError != tt.wantErr {
+				t.Fatalf("result = %q (error %t), want error %t", report, isError, tt.wantErr)
+			}
+			if isError {
+				return
+			}
+			if !strings.HasPrefix(report, tt.title) {
+				t.Errorf("report starts with %q, want %q", strings.SplitN(report, "\n", 2)[0], tt.title)
+			}
+			if rows := markdownSections(t, report)["High severity"]; len(rows) == 0 || rows[0] != "1" {
+				t.Errorf("high severity rows = %v, want issue 1 first", rows)
+			}
+			if path, _ := tt.args["output_path"].(string); path != "" {
+				if written, err := os.ReadFile(path); err != nil || string(written) != report {
+					t.Errorf("written report differs from the returned one (%v)", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yourusername/easyprofiler-mcp/analyzer"
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// useMockProfile makes the mock the current profile, without registering it
+func useMockProfile(t *testing.T, mock *proftest.Mock) {
+	t.Helper()
+	resetRegistry(t)
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	currentProfileID = "mock"
+	currentProfile = mock
+	currentAnalyzer = analyzer.NewAnalyzer(mock)
+}
+
+// newMockProfile has Frame holding Update and Render on Main, from 1000 to 1100
+func newMockProfile() *proftest.Mock {
+	return proftest.NewMock(1000, 1100,
+		[]*parser.BlockDescriptor{
+			{ID: 1, Name: "Frame", File: "Frame.cpp", Line: 10},
+			{ID: 2, Name: "Update", File: "Update.cpp", Line: 20},
+			{ID: 3, Name: "Render", File: "Render.cpp", Line: 30},
+		},
+		&parser.ThreadData{ThreadID: 1, ThreadName: "Main", FirstBlockBegin: 1000, LastBlockEnd: 1100, Blocks: []*parser.Block{
+			proftest.Tree(1, 1000, 1100, proftest.Tree(2, 1010, 1040), proftest.Tree(3, 1050, 1090)),
+		}},
+	)
+}
+
+func TestHandlersOnMockProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler server.ToolHandlerFunc
+		args    map[string]interface{}
+		setup   func(mock *proftest.Mock)
+		want    map[string]string // Fields of the result, or of its first item, printed
+		wantErr bool
+	}{
+		{
+			name:    "hotspots",
+			handler: getHotspotsHandler,
+			args:    map[string]interface{}{"limit": float64(1)},
+			want:    map[string]string{"name": "Frame", "total_duration": "100", "percent_of_total": "1"},
+		},
+		{
+			name:    "capture metadata from the header",
+			handler: getCaptureMetadataHandler,
+			setup:   func(mock *proftest.Mock) { mock.Header.PID = 42 },
+			want:    map[string]string{"pid": "42", "begin_timestamp": "1000", "total_duration": "100", "version": "2.1.0"},
+		},
+		{
+			name:    "gantt window from the capture begin",
+			handler: getBlockGanttDataHandler,
+			args:    map[string]interface{}{"window_start": "45ns", "window_end": "60ns"},
+			want:    map[string]string{"total_intervals": "2", "threads": "map[1:[map[begin:0 depth:0 descriptor_id:1 end:100 name:Frame] map[begin:50 depth:1 descriptor_id:3 end:90 name:Render]]]"},
+		},
+		{
+			name:    "memory estimate",
+			handler: getMemoryEstimateHandler,
+			setup:   func(mock *proftest.Mock) { mock.Memory = parser.MemoryEstimate{BlocksBytes: 300, TotalBytes: 512} },
+			want:    map[string]string{"estimated_heap_bytes": "512", "profile_id": "mock"},
+		},
+		{
+			name:    "dependency graph without context switches",
+			handler: getThreadDependencyGraphHandler,
+			setup:   func(mock *proftest.Mock) { mock.NoContextSwitches = true },
+			wantErr: true,
+		},
+		{
+			name:    "exclusive slowest blocks of a retained profile",
+			handler: getSlowestBlocksHandler,
+			args:    map[string]interface{}{"exclusive": true},
+			setup:   func(mock *proftest.Mock) { mock.RetainedSlowest = 10 },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockProfile()
+			if tt.setup != nil {
+				tt.setup(mock)
+			}
+			useMockProfile(t, mock)
+			outputFormat.Raw = true
+
+			args := tt.args
+			if args == nil {
+				args = map[string]interface{}{}
+			}
+			text, isError := callTool(t, tt.handler, args)
+			if isError != tt.wantErr {
+				t.Fatalf("tool error = %t, want %t: %s", isError, tt.wantErr, text)
+			}
+			if isError {
+				return
+			}
+
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+				t.Fatalf("result is not JSON: %v\n%s", err, text)
+			}
+			if items, ok := decoded.([]interface{}); ok && len(items) > 0 {
+				decoded = items[0]
+			}
+			result, ok := decoded.(map[string]interface{})
+			if !ok {
+				t.Fatalf("result is %T, want an object or a list of them", decoded)
+			}
+			for key, want := range tt.want {
+				if got := fmt.Sprint(result[key]); got != want {
+					t.Errorf("%s = %s, want %s", key, got, want)
+				}
+			}
+		})
+	}
+}
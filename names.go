@@ -0,0 +1,89 @@
+package main
+
+import "unicode/utf8"
+
+// minNameLength is the shortest accepted max_name_length, leaving room for a few
+// characters besides the ellipsis
+const minNameLength = 8
+
+// nameEllipsis replaces the end of a truncated name
+const nameEllipsis = "…"
+
+// nameListKeys are the result fields holding lists of function names rather than
+// a single "name"
+var nameListKeys = map[string]bool{"functions": true}
+
+// truncateName shortens name to at most outputFormat.MaxNameLength characters,
+// ending in an ellipsis, and reports whether it was cut
+func truncateName(name string) (string, bool) {
+	limit := outputFormat.MaxNameLength
+	if limit <= 0 || utf8.RuneCountInString(name) <= limit {
+		return name, false
+	}
+
+	return truncateText(name, limit), true
+}
+
+// truncateText shortens text to at most limit characters, ending in an ellipsis
+func truncateText(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	runes := []rune(text)
+	return string(runes[:limit-1]) + nameEllipsis
+}
+
+// shortenNames truncates the function names in a formatted tool result in place:
+// every "name" field, plus the name lists in nameListKeys. When full names are
+// requested, each truncated field gets a "full_name" (or "full_<key>") sibling
+// holding the original. Template-heavy C++ captures otherwise bloat every response.
+func shortenNames(v interface{}) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		shortenNameFields(value)
+		for _, field := range value {
+			shortenNames(field)
+		}
+	case []map[string]interface{}:
+		for _, item := range value {
+			shortenNames(item)
+		}
+	case []interface{}:
+		for _, item := range value {
+			shortenNames(item)
+		}
+	}
+}
+
+// shortenNameFields truncates the name fields directly in m
+func shortenNameFields(m map[string]interface{}) {
+	if name, ok := m["name"].(string); ok {
+		if short, cut := truncateName(name); cut {
+			m["name"] = short
+			if outputFormat.FullNames {
+				m["full_name"] = name
+			}
+		}
+	}
+
+	for key := range nameListKeys {
+		names, ok := m[key].([]string)
+		if !ok {
+			continue
+		}
+		shortened := make([]string, len(names))
+		anyCut := false
+		for i, name := range names {
+			var cut bool
+			shortened[i], cut = truncateName(name)
+			anyCut = anyCut || cut
+		}
+		if anyCut {
+			m[key] = shortened
+			if outputFormat.FullNames {
+				m["full_"+key] = names
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+func TestTruncateName(t *testing.T) {
+	long := strings.Repeat("a", 500)
+
+	tests := []struct {
+		name  string
+		input string
+		limit int
+		want  string
+		cut   bool
+	}{
+		{"no limit", long, 0, long, false},
+		{"shorter than the limit", "Update", 8, "Update", false},
+		{"exactly the limit", "Render::", 8, "Render::", false},
+		{"500 characters", long, 20, strings.Repeat("a", 19) + "…", true},
+		{"cut on characters, not bytes", "Größenänderung", 8, "Größenä…", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			outputFormat.MaxNameLength = tt.limit
+
+			got, cut := truncateName(tt.input)
+			if got != tt.want || cut != tt.cut {
+				t.Errorf("truncateName = %q, %t; want %q, %t", got, cut, tt.want, tt.cut)
+			}
+			if tt.limit > 0 && utf8.RuneCountInString(got) > tt.limit {
+				t.Errorf("%q is longer than %d characters", got, tt.limit)
+			}
+		})
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		limit int
+		want  string
+	}{
+		{"empty", "", 5, ""},
+		{"shorter than the limit", "Main", 5, "Main"},
+		{"exactly the limit", "Worker", 6, "Worker"},
+		{"one over the limit", "Workers", 6, "Worke…"},
+		{"cut on characters, not bytes", "Größenänderung", 8, "Größenä…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateText(tt.input, tt.limit); got != tt.want {
+				t.Errorf("truncateText(%q, %d) = %q, want %q", tt.input, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortenNames(t *testing.T) {
+	long := "std::vector<std::pair<int, std::string>>::emplace_back"
+	short := "std::vector<std::pa…"
+
+	// result is a formatted tool result with names at several levels
+	result := func() map[string]interface{} {
+		return map[string]interface{}{
+			"name": long,
+			"file": long,
+			"hotspots": []map[string]interface{}{
+				{"name": long},
+				{"name": "Update"},
+			},
+			"legend": []interface{}{
+				map[string]interface{}{"functions": []string{long, "Update"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		full  bool
+		want  map[string]interface{}
+	}{
+		{"no limit", 0, true, result()},
+		{"truncated", 20, false, map[string]interface{}{
+			"name": short,
+			"file": long,
+			"hotspots": []map[string]interface{}{
+				{"name": short},
+				{"name": "Update"},
+			},
+			"legend": []interface{}{
+				map[string]interface{}{"functions": []string{short, "Update"}},
+			},
+		}},
+		{"full names kept", 20, true, map[string]interface{}{
+			"name":      short,
+			"full_name": long,
+			"file":      long,
+			"hotspots": []map[string]interface{}{
+				{"name": short, "full_name": long},
+				{"name": "Update"},
+			},
+			"legend": []interface{}{
+				map[string]interface{}{"functions": []string{short, "Update"}, "full_functions": []string{long, "Update"}},
+			},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			outputFormat.MaxNameLength = tt.limit
+			outputFormat.FullNames = tt.full
+
+			got := result()
+			shortenNames(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shortenNames result = %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxNameLengthAcrossTools(t *testing.T) {
+	long := "Render" + strings.Repeat("<T>", 165) // 501 characters
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors(long, "Update"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 0, End: 10},
+			{ID: 1, Begin: 0, End: 30},
+		}}},
+	}
+
+	tests := []struct {
+		name string
+		full bool
+	}{
+		{"truncated", false},
+		{"full names", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, p, nil)
+			callToolJSON(t, setOutputFormatHandler, map[string]interface{}{"max_name_length": 32.0, "full_names": tt.full})
+
+			hotspots := callToolList(t, getHotspotsHandler, nil)
+			slowest := callToolList(t, getSlowestBlocksHandler, nil)
+			for _, entry := range []map[string]interface{}{hotspots[0], slowest[0]} {
+				name := entry["name"].(string)
+				if utf8.RuneCountInString(name) != 32 || !strings.HasPrefix(long, strings.TrimSuffix(name, "…")) || !strings.HasSuffix(name, "…") {
+					t.Errorf("name = %q, want the first 31 characters of the long name and an ellipsis", name)
+				}
+				if full, ok := entry["full_name"]; ok != tt.full || (ok && full != long) {
+					t.Errorf("full_name = %v (present %t), want present %t", full, ok, tt.full)
+				}
+			}
+			if hotspots[1]["name"] != "Update" {
+				t.Errorf("short name changed to %v", hotspots[1]["name"])
+			}
+			if _, ok := hotspots[1]["full_name"]; ok {
+				t.Error("untruncated name has a full_name")
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package parser
+
+// Accessors through which the analyzer reads a profile, so it doesn't depend on how
+// ProfileData lays out its threads and descriptors
+
+// ThreadIDs returns the ID of every loaded thread, in no particular order
+func (p *ProfileData) ThreadIDs() []uint64 {
+	ids := make([]uint64, 0, len(p.Threads))
+	for id := range p.Threads {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Thread returns the thread with the given ID, or nil if it wasn't loaded
+func (p *ProfileData) Thread(id uint64) *ThreadData {
+	return p.Threads[id]
+}
+
+// DescriptorIDs returns the ID of every loaded descriptor, in no particular order
+func (p *ProfileData) DescriptorIDs() []uint32 {
+	ids := make([]uint32, 0, len(p.Descriptors))
+	for id := range p.Descriptors {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Descriptor returns the descriptor with the given ID, or nil if the capture has none
+func (p *ProfileData) Descriptor(id uint32) *BlockDescriptor {
+	return p.Descriptors[id]
+}
+
+// CaptureBegin returns the raw timestamp the capture began at
+func (p *ProfileData) CaptureBegin() uint64 {
+	return p.Header.BeginTime
+}
+
+// ContextSwitchesLoaded reports whether context switches were read
+func (p *ProfileData) ContextSwitchesLoaded() bool {
+	return !p.ContextSwitchesSkipped
+}
+
+// BlockSampling returns N when only every Nth block was read, and 1 otherwise
+func (p *ProfileData) BlockSampling() int {
+	return max(p.SamplingFactor, 1)
+}
+
+// RetainedSlowestCount returns ReadOptions.RetainSlowest if it was set, and 0 otherwise
+func (p *ProfileData) RetainedSlowestCount() int {
+	return p.RetainedSlowest
+}
+
+// FileHeader returns the header the profile was read from
+func (p *ProfileData) FileHeader() FileHeader {
+	return p.Header
+}
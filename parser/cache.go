@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cacheFileExt is the extension used for cached profile files
+const cacheFileExt = ".profcache"
+
+// cacheFormatVersion is part of every entry's key. Bump it when the parsed data
+// changes, e.g. a new Block field, so entries written before don't load without it.
+const cacheFormatVersion = 2
+
+// Cache stores parsed profiles on disk so repeated loads of the same capture skip parsing.
+// Entries are keyed by source path and read options, and are invalidated when the
+// source file's size or modification time changes.
+type Cache struct {
+	dir string
+}
+
+// cacheEntry is the on-disk representation of a cached profile
+type cacheEntry struct {
+	SourcePath string
+	ModTime    int64
+	Size       int64
+	Profile    *ProfileData
+}
+
+// DefaultCacheDir returns the default cache directory under the user's cache dir
+func DefaultCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache dir: %w", err)
+	}
+	return filepath.Join(base, "easyprofiler-mcp"), nil
+}
+
+// NewCache creates a cache rooted at dir, creating the directory if needed
+func NewCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Dir returns the cache directory
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Load returns the cached profile for filePath and options if a valid entry exists.
+// A stale entry (source file changed) is removed and reported as a miss.
+func (c *Cache) Load(filePath string, options ReadOptions) (*ProfileData, bool, error) {
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	cachePath, err := c.entryPath(filePath, options)
+	if err != nil {
+		return nil, false, err
+	}
+
+	file, err := os.Open(cachePath)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open cache entry: %w", err)
+	}
+	defer file.Close()
+
+	var entry cacheEntry
+	if err := gob.NewDecoder(file).Decode(&entry); err != nil {
+		// Corrupt or incompatible entry - drop it and re-parse
+		os.Remove(cachePath)
+		return nil, false, nil
+	}
+
+	if entry.ModTime != stat.ModTime().UnixNano() || entry.Size != stat.Size() {
+		os.Remove(cachePath)
+		return nil, false, nil
+	}
+
+	return entry.Profile, true, nil
+}
+
+// Store writes the parsed profile for filePath and options to the cache
+func (c *Cache) Store(filePath string, options ReadOptions, data *ProfileData) error {
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	cachePath, err := c.entryPath(filePath, options)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file first so a crash never leaves a truncated entry
+	tmp, err := os.CreateTemp(c.dir, "entry-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	entry := cacheEntry{
+		SourcePath: filePath,
+		ModTime:    stat.ModTime().UnixNano(),
+		Size:       stat.Size(),
+		Profile:    data,
+	}
+	if err := gob.NewEncoder(tmp).Encode(&entry); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), cachePath); err != nil {
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+	return nil
+}
+
+// Clear removes all cached entries and returns how many were deleted
+func (c *Cache) Clear() (int, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache dir: %w", err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), cacheFileExt) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, entry.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", entry.Name(), err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// entryPath derives the cache file path from the absolute source path and read options
+func (c *Cache) entryPath(filePath string, options ReadOptions) (string, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00v%d", absPath, options.cacheKey(), cacheFormatVersion)
+	return filepath.Join(c.dir, hex.EncodeToString(hash.Sum(nil))+cacheFileExt), nil
+}
@@ -0,0 +1,287 @@
+package parser_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// cacheTestProfile has nested blocks, a value block, context switches and a bookmark,
+// so a cache round trip covers every part of ProfileData
+func cacheTestProfile() *proftest.Profile {
+	return &proftest.Profile{
+		Descriptors: []proftest.Descriptor{
+			{ID: 1, Name: "Frame", File: "main.cpp", Line: 10, Type: parser.BlockTypeBlock},
+			{ID: 2, Name: "Update", File: "update.cpp", Line: 20, Type: parser.BlockTypeBlock},
+			{ID: 3, Name: "fps", File: "main.cpp", Line: 30, Type: parser.BlockTypeValue},
+		},
+		Threads: []proftest.Thread{{
+			ID:   100,
+			Name: "Main",
+			ContextSwitches: []proftest.ContextSwitch{
+				{ThreadID: 100, Begin: 1500, End: 1800, Name: "game.exe"},
+			},
+			Blocks: []proftest.Block{
+				{ID: 2, Begin: 1100, End: 1400},
+				{ID: 1, Begin: 1000, End: 2000},
+				{ID: 2, Begin: 2100, End: 2400},
+				{ID: 1, Begin: 2000, End: 3000},
+				{ID: 3, Begin: 2500, End: 7, Payload: proftest.DoubleValue(59.5)},
+			},
+		}},
+		Bookmarks: []proftest.Bookmark{{Position: 1500, Color: 0xff0000, Text: "spike"}},
+	}
+}
+
+func newTestCache(t *testing.T) *parser.Cache {
+	t.Helper()
+	cache, err := parser.NewCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+	return cache
+}
+
+// clearEmptySlices sets empty slices to nil, as gob decodes them, so profiles can
+// be compared with reflect.DeepEqual
+func clearEmptySlices(data *parser.ProfileData) {
+	if len(data.Bookmarks) == 0 {
+		data.Bookmarks = nil
+	}
+	for _, thread := range data.Threads {
+		if len(thread.ContextSwitches) == 0 {
+			thread.ContextSwitches = nil
+		}
+		parser.WalkBlocks(thread.Blocks, parser.DefaultMaxTreeDepth, func(block *parser.Block, _ int) {
+			if len(block.Children) == 0 {
+				block.Children = nil
+			}
+		})
+	}
+}
+
+func TestCacheHitEqualsFreshParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		options parser.ReadOptions
+	}{
+		{"default", parser.DefaultReadOptions()},
+		{"fast", parser.FastReadOptions()},
+		{"skip context switches", parser.ReadOptions{SkipContextSwitches: true, SkipBookmarks: true}},
+		{"min block duration", parser.ReadOptions{MinBlockDuration: 500 * time.Nanosecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := cacheTestProfile().WriteFile(t)
+			cache := newTestCache(t)
+
+			fresh, err := proftest.ParseFile(path, tt.options)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if err := cache.Store(path, tt.options, fresh); err != nil {
+				t.Fatalf("Store: %v", err)
+			}
+
+			cached, hit, err := cache.Load(path, tt.options)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if !hit {
+				t.Fatal("Load missed an entry that was just stored")
+			}
+
+			reparsed, err := proftest.ParseFile(path, tt.options)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			clearEmptySlices(cached)
+			clearEmptySlices(reparsed)
+			if !reflect.DeepEqual(cached, reparsed) {
+				t.Errorf("cached profile differs from a fresh parse\ncached: %+v\nfresh:  %+v", cached, reparsed)
+			}
+		})
+	}
+}
+
+func TestCacheInvalidatedBySourceChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, path string)
+	}{
+		{"mtime", func(t *testing.T, path string) {
+			later := time.Now().Add(time.Hour)
+			if err := os.Chtimes(path, later, later); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"size", func(t *testing.T, path string) {
+			stat, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Write([]byte{0})
+			file.Close()
+			// Keep the modification time so only the size tells the change apart
+			if err := os.Chtimes(path, stat.ModTime(), stat.ModTime()); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := cacheTestProfile().WriteFile(t)
+			cache := newTestCache(t)
+			options := parser.DefaultReadOptions()
+
+			data, err := proftest.ParseFile(path, options)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if err := cache.Store(path, options, data); err != nil {
+				t.Fatalf("Store: %v", err)
+			}
+
+			tt.change(t, path)
+
+			if _, hit, err := cache.Load(path, options); err != nil || hit {
+				t.Fatalf("Load after %s change = hit %t, err %v; want a miss", tt.name, hit, err)
+			}
+			// The stale entry is removed, so storing again and loading hits
+			if err := cache.Store(path, options, data); err != nil {
+				t.Fatalf("Store: %v", err)
+			}
+			if _, hit, _ := cache.Load(path, options); !hit {
+				t.Error("Load missed the entry stored after the change")
+			}
+		})
+	}
+}
+
+func TestCacheKeyedByOptions(t *testing.T) {
+	stored := parser.DefaultReadOptions()
+
+	tests := []struct {
+		name    string
+		options parser.ReadOptions
+		hit     bool
+	}{
+		{"same options", parser.DefaultReadOptions(), true},
+		{"progress callbacks", parser.ReadOptions{ProgressCallback: func(int) {}, ProgressStep: 5, PhaseCallback: func(string, int) {}}, true},
+		{"max tree depth above default", parser.ReadOptions{MaxTreeDepth: parser.DefaultMaxTreeDepth + 1}, true},
+		{"max block depth", parser.ReadOptions{MaxBlockDepth: 1}, false},
+		{"sample blocks", parser.ReadOptions{SampleBlocks: 2}, false},
+		{"skip context switches", parser.ReadOptions{SkipContextSwitches: true}, false},
+		{"skip bookmarks", parser.ReadOptions{SkipBookmarks: true}, false},
+		{"max threads", parser.ReadOptions{MaxThreads: 1}, false},
+		{"thread name filter", parser.ReadOptions{ThreadNameFilter: "Main"}, false},
+		{"min block duration", parser.ReadOptions{MinBlockDuration: time.Microsecond}, false},
+		{"retain slowest", parser.ReadOptions{RetainSlowest: 3}, false},
+		{"max tree depth", parser.ReadOptions{MaxTreeDepth: 1}, false},
+		{"strict reserved fields", parser.ReadOptions{StrictReservedFields: true}, false},
+	}
+
+	path := cacheTestProfile().WriteFile(t)
+	cache := newTestCache(t)
+	data, err := proftest.ParseFile(path, stored)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if err := cache.Store(path, stored, data); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, hit, err := cache.Load(path, tt.options)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if hit != tt.hit {
+				t.Errorf("Load hit = %t, want %t", hit, tt.hit)
+			}
+		})
+	}
+}
+
+func TestCacheClear(t *testing.T) {
+	path := cacheTestProfile().WriteFile(t)
+	cache := newTestCache(t)
+
+	for _, options := range []parser.ReadOptions{parser.DefaultReadOptions(), parser.FastReadOptions()} {
+		data, err := proftest.ParseFile(path, options)
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if err := cache.Store(path, options, data); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+
+	removed, err := cache.Clear()
+	if err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Clear removed %d entries, want 2", removed)
+	}
+	if _, hit, _ := cache.Load(path, parser.DefaultReadOptions()); hit {
+		t.Error("Load hit after Clear")
+	}
+}
+
+func TestCacheRestoresDepths(t *testing.T) {
+	tests := []struct {
+		name    string
+		options parser.ReadOptions
+		want    []uint16
+	}{
+		{"default", parser.DefaultReadOptions(), []uint16{0, 1, 2, 3}},
+		{"max block depth", parser.ReadOptions{MaxBlockDepth: 2}, []uint16{0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &proftest.Profile{
+				Descriptors: proftest.Descriptors("Recurse"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: proftest.Nested(1, 0, 4)}},
+			}
+			path := p.WriteFile(t)
+			cache := newTestCache(t)
+			data, err := proftest.ParseFile(path, tt.options)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if err := cache.Store(path, tt.options, data); err != nil {
+				t.Fatalf("Store: %v", err)
+			}
+
+			cached, hit, err := cache.Load(path, tt.options)
+			if err != nil || !hit {
+				t.Fatalf("Load = hit %t, err %v; want a hit", hit, err)
+			}
+			var depths []uint16
+			for _, thread := range cached.Threads {
+				parser.WalkBlocks(thread.Blocks, parser.DefaultMaxTreeDepth, func(block *parser.Block, depth int) {
+					if int(block.Depth) != depth {
+						t.Errorf("block at %d has Depth %d, want %d", block.Begin, block.Depth, depth)
+					}
+					depths = append(depths, block.Depth)
+				})
+			}
+			if !reflect.DeepEqual(depths, tt.want) {
+				t.Errorf("depths = %v, want %v", depths, tt.want)
+			}
+		})
+	}
+}
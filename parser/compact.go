@@ -0,0 +1,75 @@
+package parser
+
+import "time"
+
+// CompactOptions selects what Compact removes from a loaded profile
+type CompactOptions struct {
+	// DropContextSwitches releases all context switch data
+	DropContextSwitches bool
+
+	// MinBlockDuration removes blocks (and their subtrees) shorter than this (0 = keep all).
+	// Values and events are only removed with an enclosing block.
+	MinBlockDuration time.Duration
+}
+
+// Compact frees memory in an already-parsed profile by dropping the selected data in
+// place. Top-level structure, descriptors and the remaining blocks are preserved so
+// hotspot and thread analyses keep working. It returns the number of blocks removed.
+func (p *ProfileData) Compact(options CompactOptions) int {
+	removed := 0
+
+	for _, thread := range p.Threads {
+		if options.DropContextSwitches {
+			thread.ContextSwitches = nil
+		}
+		if options.MinBlockDuration > 0 {
+			var n int
+			thread.Blocks, n = p.pruneBlocks(thread.Blocks, options.MinBlockDuration)
+			removed += n
+			thread.updateActivitySpan()
+		}
+	}
+
+	if options.DropContextSwitches {
+		p.ContextSwitchesSkipped = true
+	}
+
+	p.DroppedBlocksCount += removed
+	p.TotalBlocksCount = p.GetBlocksCount()
+	return removed
+}
+
+// pruneBlocks returns blocks with every block shorter than minDuration removed, along
+// with the number of blocks removed including their descendants. It walks the tree
+// with WalkBlocks, filtering each kept block's children before they are visited, so
+// deep trees don't overflow the stack.
+func (p *ProfileData) pruneBlocks(blocks []*Block, minDuration time.Duration) ([]*Block, int) {
+	kept, removed := p.filterShortBlocks(blocks, minDuration)
+	WalkBlocks(kept, DefaultMaxTreeDepth, func(block *Block, _ int) {
+		var n int
+		block.Children, n = p.filterShortBlocks(block.Children, minDuration)
+		removed += n
+	})
+	return kept, removed
+}
+
+// filterShortBlocks returns the blocks at least minDuration long and the number of
+// blocks removed including their descendants. The kept slice is reallocated so the
+// memory of the original backing array can be reclaimed.
+func (p *ProfileData) filterShortBlocks(blocks []*Block, minDuration time.Duration) ([]*Block, int) {
+	removed := 0
+	kept := make([]*Block, 0, len(blocks))
+
+	for _, block := range blocks {
+		if p.isShortBlock(block, minDuration) {
+			removed += 1 + countBlocks(block.Children)
+			continue
+		}
+		kept = append(kept, block)
+	}
+
+	if removed == 0 {
+		return blocks, 0
+	}
+	return kept, removed
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+// chain returns a single block nesting depth levels deep. Each level is one
+// nanosecond shorter on each side than its parent, starting at length ns.
+func chain(depth int, length uint64) *Block {
+	root := &Block{ID: 1, End: length}
+	parent := root
+	for i := 1; i < depth; i++ {
+		child := &Block{ID: 1, Begin: uint64(i), End: length - uint64(i), Depth: uint16(i)}
+		parent.Children = []*Block{child}
+		parent = child
+	}
+	return root
+}
+
+// chainDepth returns how many levels deep block nests
+func chainDepth(block *Block) int {
+	depth := 0
+	for ; block != nil; depth++ {
+		if len(block.Children) == 0 {
+			return depth + 1
+		}
+		block = block.Children[0]
+	}
+	return depth
+}
+
+func TestCompact(t *testing.T) {
+	const depth = DefaultMaxTreeDepth
+
+	tests := []struct {
+		name         string
+		options      CompactOptions
+		removed      int
+		remaining    int
+		depth        int
+		switchesGone bool
+	}{
+		{"drop context switches", CompactOptions{DropContextSwitches: true}, 0, depth + 1, depth, true},
+		{"keep all", CompactOptions{MinBlockDuration: time.Nanosecond}, 0, depth + 1, depth, false},
+		// Level i lasts 2*(depth-i) ns, so levels 0 to 100 are kept
+		{"prune deep tree", CompactOptions{MinBlockDuration: time.Duration(2*depth - 200)}, depth - 101, 102, 101, false},
+		{"prune short top-level blocks", CompactOptions{MinBlockDuration: time.Duration(4 * depth)}, depth, 1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thread := &ThreadData{
+				ThreadID:        1,
+				ContextSwitches: []*ContextSwitch{{ThreadID: 1, Begin: 10, End: 20}},
+				Blocks:          []*Block{chain(depth, 2*depth), {ID: 2, Begin: 2 * depth, End: 10 * depth}},
+			}
+			thread.updateActivitySpan()
+			profile := &ProfileData{Threads: map[uint64]*ThreadData{1: thread}}
+			profile.TotalBlocksCount = profile.GetBlocksCount()
+
+			removed := profile.Compact(tt.options)
+
+			if removed != tt.removed || profile.DroppedBlocksCount != tt.removed {
+				t.Errorf("removed %d blocks, DroppedBlocksCount %d; want %d", removed, profile.DroppedBlocksCount, tt.removed)
+			}
+			if profile.TotalBlocksCount != tt.remaining {
+				t.Errorf("TotalBlocksCount = %d, want %d", profile.TotalBlocksCount, tt.remaining)
+			}
+			gotDepth := 0
+			if thread.Blocks[0].ID == 1 {
+				gotDepth = chainDepth(thread.Blocks[0])
+			}
+			if gotDepth != tt.depth {
+				t.Errorf("chain nests %d levels, want %d", gotDepth, tt.depth)
+			}
+			if gone := thread.ContextSwitches == nil; gone != tt.switchesGone || profile.ContextSwitchesSkipped != tt.switchesGone {
+				t.Errorf("context switches dropped = %t, skipped = %t; want %t", gone, profile.ContextSwitchesSkipped, tt.switchesGone)
+			}
+			if thread.FirstBlockBegin != thread.Blocks[0].Begin || thread.LastBlockEnd != 10*depth {
+				t.Errorf("activity span [%d, %d] not updated", thread.FirstBlockBegin, thread.LastBlockEnd)
+			}
+		})
+	}
+}
+
+func TestCompactKeepsValuesAndEvents(t *testing.T) {
+	// Frame holds a Tick event and a count value; Short holds another Tick
+	thread := &ThreadData{
+		ThreadID: 1,
+		Blocks: []*Block{
+			{ID: 1, Begin: 0, End: 1000, Children: []*Block{
+				{ID: 2, Begin: 100, End: 100, Depth: 1},
+				{ID: 3, Begin: 200, End: 200, Depth: 1, Value: &Value{Type: ValueTypeInt32, Data: []byte{3, 0, 0, 0}}},
+			}},
+			{ID: 4, Begin: 1000, End: 1010, Children: []*Block{{ID: 2, Begin: 1005, End: 1005, Depth: 1}}},
+		},
+	}
+	profile := &ProfileData{
+		Descriptors: map[uint32]*BlockDescriptor{
+			1: {ID: 1, Name: "Frame", Type: BlockTypeBlock},
+			2: {ID: 2, Name: "Tick", Type: BlockTypeEvent},
+			3: {ID: 3, Name: "count", Type: BlockTypeValue},
+			4: {ID: 4, Name: "Short", Type: BlockTypeBlock},
+		},
+		Threads: map[uint64]*ThreadData{1: thread},
+	}
+	profile.TotalBlocksCount = profile.GetBlocksCount()
+
+	if removed := profile.Compact(CompactOptions{MinBlockDuration: 50}); removed != 2 {
+		t.Errorf("removed %d blocks, want Short and its Tick", removed)
+	}
+	if len(thread.Blocks) != 1 || len(thread.Blocks[0].Children) != 2 {
+		t.Fatalf("blocks = %+v, want Frame with its Tick and count", thread.Blocks)
+	}
+	if profile.TotalBlocksCount != 3 {
+		t.Errorf("TotalBlocksCount = %d, want 3", profile.TotalBlocksCount)
+	}
+}
@@ -0,0 +1,109 @@
+package parser
+
+// Extract returns a standalone profile holding copies of the given top-level blocks
+// and their subtrees from one thread, e.g. a single call or a call with its siblings.
+// The capture span is narrowed to the blocks, the blocks are re-rooted at depth 0 and
+// only the descriptors, context switches and bookmarks they touch are kept, so every
+// analysis treats the slice as if it had been captured on its own. blocks must all
+// belong to thread.
+func (p *ProfileData) Extract(thread *ThreadData, blocks []*Block) *ProfileData {
+	result := NewProfileData()
+	result.Header = p.Header
+	result.SamplingFactor = p.SamplingFactor
+	result.ContextSwitchesSkipped = p.ContextSwitchesSkipped
+
+	// The capture's memory sizes don't describe the slice
+	result.Header.MemorySize = 0
+	result.Header.DescriptorsMemorySize = 0
+
+	extracted := &ThreadData{
+		ThreadID:      thread.ThreadID,
+		ThreadName:    thread.ThreadName,
+		Blocks:        copyBlocks(blocks),
+		NameSanitized: thread.NameSanitized,
+	}
+	extracted.updateActivitySpan()
+	result.Threads[thread.ThreadID] = extracted
+
+	begin, end := extracted.FirstBlockBegin, extracted.LastBlockEnd
+	result.Header.BeginTime = begin
+	result.Header.EndTime = end
+
+	for _, cs := range thread.ContextSwitches {
+		if cs.End > begin && cs.Begin < end {
+			copied := *cs
+			extracted.ContextSwitches = append(extracted.ContextSwitches, &copied)
+		}
+	}
+
+	for _, bookmark := range p.Bookmarks {
+		if bookmark.Position >= begin && bookmark.Position <= end {
+			copied := *bookmark
+			result.Bookmarks = append(result.Bookmarks, &copied)
+		}
+	}
+
+	WalkBlocks(extracted.Blocks, DefaultMaxTreeDepth, func(block *Block, _ int) {
+		result.TotalBlocksCount++
+		if block.Unclosed {
+			result.UnclosedBlocksCount++
+		}
+		if descriptor, ok := p.Descriptors[block.ID]; ok {
+			if _, seen := result.Descriptors[block.ID]; !seen {
+				copied := *descriptor
+				result.Descriptors[block.ID] = &copied
+			}
+		}
+	})
+
+	// The slice is complete by construction, so declared and found counts match
+	result.BlockRecordsCount = result.TotalBlocksCount
+	result.DescriptorRecordsCount = len(result.Descriptors)
+	result.Header.BlocksCount = uint32(result.TotalBlocksCount)
+	result.Header.DescriptorsCount = uint32(len(result.Descriptors))
+	result.Header.ThreadsCount = 1
+	result.Header.BookmarksCount = uint16(min(len(result.Bookmarks), 0xFFFF))
+
+	return result
+}
+
+// copyBlocks deep-copies blocks and their subtrees, shifting depths so the given
+// blocks become top-level. It uses an explicit stack like WalkBlocks.
+func copyBlocks(blocks []*Block) []*Block {
+	if len(blocks) == 0 {
+		return nil
+	}
+	rootDepth := blocks[0].Depth
+
+	type frame struct {
+		source *Block
+		target *Block
+	}
+
+	result := make([]*Block, len(blocks))
+	stack := make([]frame, 0, len(blocks))
+	for i, block := range blocks {
+		result[i] = &Block{}
+		stack = append(stack, frame{source: block, target: result[i]})
+	}
+
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		*top.target = *top.source
+		top.target.Depth = top.source.Depth - rootDepth
+		top.target.Children = nil
+		if len(top.source.Children) == 0 {
+			continue
+		}
+
+		top.target.Children = make([]*Block, len(top.source.Children))
+		for i, child := range top.source.Children {
+			top.target.Children[i] = &Block{}
+			stack = append(stack, frame{source: child, target: top.target.Children[i]})
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,120 @@
+package parser_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestExtract(t *testing.T) {
+	// Main: Frame 0-100 holds Update (holding Physics) and Render; Frame 200-300 holds
+	// another Update. Worker runs Job.
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Physics", "Render", "Job"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 3, Begin: 20, End: 30},
+				{ID: 2, Begin: 10, End: 60},
+				{ID: 4, Begin: 60, End: 90},
+				{ID: 1, Begin: 0, End: 100},
+				{ID: 2, Begin: 210, End: 250},
+				{ID: 1, Begin: 200, End: 300},
+			}, ContextSwitches: []proftest.ContextSwitch{
+				{ThreadID: 1, Begin: 5, End: 8, Name: "game"},
+				{ThreadID: 1, Begin: 120, End: 130, Name: "game"},
+				{ThreadID: 1, Begin: 215, End: 220, Name: "game"},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 5, Begin: 0, End: 50}}},
+		},
+		Bookmarks: []proftest.Bookmark{{Position: 15, Text: "a"}, {Position: 150, Text: "b"}},
+	}
+	source := p.Load(t)
+	main := source.Threads[1]
+	frames := main.Blocks
+
+	tests := []struct {
+		name        string
+		blocks      []*parser.Block
+		outline     []string
+		begin, end  uint64
+		descriptors []string
+		switches    int
+		bookmarks   int
+	}{
+		{"one nested call", []*parser.Block{frames[0].Children[0]},
+			[]string{"Update@0", "Physics@1"}, 10, 60, []string{"Physics", "Update"}, 0, 1},
+		{"top-level call", []*parser.Block{frames[0]},
+			[]string{"Frame@0", "Update@1", "Physics@2", "Render@1"}, 0, 100, []string{"Frame", "Physics", "Render", "Update"}, 1, 1},
+		{"nested siblings", frames[0].Children,
+			[]string{"Update@0", "Physics@1", "Render@0"}, 10, 90, []string{"Physics", "Render", "Update"}, 0, 1},
+		{"top-level siblings", frames,
+			[]string{"Frame@0", "Update@1", "Physics@2", "Render@1", "Frame@0", "Update@1"}, 0, 300, []string{"Frame", "Physics", "Render", "Update"}, 3, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slice := source.Extract(main, tt.blocks)
+
+			if got := outline(slice, 1); !reflect.DeepEqual(got, tt.outline) {
+				t.Errorf("outline = %v, want %v", got, tt.outline)
+			}
+			parser.WalkBlocks(slice.Threads[1].Blocks, parser.DefaultMaxTreeDepth, func(block *parser.Block, depth int) {
+				if int(block.Depth) != depth {
+					t.Errorf("block at depth %d has Depth %d", depth, block.Depth)
+				}
+			})
+			if slice.TotalBlocksCount != len(tt.outline) || int(slice.Header.BlocksCount) != len(tt.outline) {
+				t.Errorf("blocks count %d (header %d), want the subtree size %d", slice.TotalBlocksCount, slice.Header.BlocksCount, len(tt.outline))
+			}
+			if slice.Header.BeginTime != tt.begin || slice.Header.EndTime != tt.end {
+				t.Errorf("span %d-%d, want %d-%d", slice.Header.BeginTime, slice.Header.EndTime, tt.begin, tt.end)
+			}
+
+			var descriptors []string
+			for _, descriptor := range slice.Descriptors {
+				descriptors = append(descriptors, descriptor.Name)
+			}
+			sort.Strings(descriptors)
+			if !reflect.DeepEqual(descriptors, tt.descriptors) {
+				t.Errorf("descriptors = %v, want %v", descriptors, tt.descriptors)
+			}
+
+			if len(slice.Threads) != 1 || slice.Header.ThreadsCount != 1 {
+				t.Errorf("got %d threads (header %d), want 1", len(slice.Threads), slice.Header.ThreadsCount)
+			}
+			if got := len(slice.Threads[1].ContextSwitches); got != tt.switches {
+				t.Errorf("got %d context switches, want %d", got, tt.switches)
+			}
+			if len(slice.Bookmarks) != tt.bookmarks || int(slice.Header.BookmarksCount) != tt.bookmarks {
+				t.Errorf("got %d bookmarks (header %d), want %d", len(slice.Bookmarks), slice.Header.BookmarksCount, tt.bookmarks)
+			}
+			if report := slice.CheckIntegrity(); report == nil || !report.Complete() {
+				t.Errorf("integrity = %+v, want complete", report)
+			}
+		})
+	}
+}
+
+func TestExtractCopies(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 10, End: 60},
+			{ID: 1, Begin: 0, End: 100},
+		}}},
+	}
+	source := p.Load(t)
+	slice := source.Extract(source.Threads[1], source.Threads[1].Blocks)
+
+	slice.Threads[1].Blocks[0].Children[0].End = 999
+	slice.Descriptors[2].Name = "Changed"
+	if got := source.Threads[1].Blocks[0].Children[0].End; got != 60 {
+		t.Errorf("source block end = %d after changing the slice, want 60", got)
+	}
+	if got := source.Descriptors[2].Name; got != "Update" {
+		t.Errorf("source descriptor = %q after changing the slice, want Update", got)
+	}
+}
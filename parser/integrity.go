@@ -0,0 +1,86 @@
+package parser
+
+// CountCheck compares the number of records the header declares with what the
+// file actually contained and what was kept
+type CountCheck struct {
+	Declared int // Count from the header
+	InFile   int // Records found in the file
+	Loaded   int // Records kept after intentional skipping
+}
+
+// Missing returns how many declared records the file didn't contain, e.g. because
+// it was truncated or the header over-reports
+func (c CountCheck) Missing() int {
+	return max(0, c.Declared-c.InFile)
+}
+
+// Unexpected returns how many records the file contained beyond the declared count
+func (c CountCheck) Unexpected() int {
+	return max(0, c.InFile-c.Declared)
+}
+
+// Skipped returns how many records in the file were deliberately not loaded
+func (c CountCheck) Skipped() int {
+	return max(0, c.InFile-c.Loaded)
+}
+
+// IntegrityReport compares the header's declared counts with the parsed data,
+// separating deliberate skips (sampling, duration and thread filters, duplicate
+// descriptor IDs) from records the file didn't contain
+type IntegrityReport struct {
+	Blocks      CountCheck
+	Descriptors CountCheck
+
+	// SkipReasons names the read options that deliberately dropped blocks
+	SkipReasons []string
+}
+
+// OK reports whether the file held exactly the declared number of records
+func (r *IntegrityReport) OK() bool {
+	return r.Blocks.Declared == r.Blocks.InFile && r.Descriptors.Declared == r.Descriptors.InFile
+}
+
+// Complete reports whether everything the header declares was loaded
+func (r *IntegrityReport) Complete() bool {
+	return r.OK() && r.Blocks.Skipped() == 0 && r.Descriptors.Skipped() == 0
+}
+
+// CheckIntegrity compares the declared block and descriptor counts with the parsed
+// data. It returns nil for profiles that don't record how many block records the
+// file contained, such as ones restored from a cache written by an older version.
+func (p *ProfileData) CheckIntegrity() *IntegrityReport {
+	if p.BlockRecordsCount == 0 && p.TotalBlocksCount > 0 {
+		return nil
+	}
+
+	report := &IntegrityReport{
+		Blocks: CountCheck{
+			Declared: int(p.Header.BlocksCount),
+			InFile:   p.BlockRecordsCount,
+			Loaded:   p.TotalBlocksCount,
+		},
+		Descriptors: CountCheck{
+			Declared: int(p.Header.DescriptorsCount),
+			InFile:   p.DescriptorRecordsCount,
+			Loaded:   len(p.Descriptors),
+		},
+	}
+
+	if p.SamplingFactor > 1 {
+		report.SkipReasons = append(report.SkipReasons, "block sampling")
+	}
+	if p.DroppedBlocksCount > 0 {
+		report.SkipReasons = append(report.SkipReasons, "minimum block duration")
+	}
+	if p.DepthLimitedBlocksCount > 0 {
+		report.SkipReasons = append(report.SkipReasons, "maximum block depth")
+	}
+	if p.SkippedThreadsCount > 0 {
+		report.SkipReasons = append(report.SkipReasons, "thread filter")
+	}
+	if p.RetainedSlowest > 0 {
+		report.SkipReasons = append(report.SkipReasons, "slowest blocks only")
+	}
+
+	return report
+}
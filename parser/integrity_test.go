@@ -0,0 +1,109 @@
+package parser_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestCountCheck(t *testing.T) {
+	tests := []struct {
+		name                         string
+		check                        parser.CountCheck
+		missing, unexpected, skipped int
+	}{
+		{"exact", parser.CountCheck{Declared: 5, InFile: 5, Loaded: 5}, 0, 0, 0},
+		{"truncated", parser.CountCheck{Declared: 8, InFile: 5, Loaded: 5}, 3, 0, 0},
+		{"under-reported", parser.CountCheck{Declared: 3, InFile: 5, Loaded: 5}, 0, 2, 0},
+		{"sampled", parser.CountCheck{Declared: 5, InFile: 5, Loaded: 2}, 0, 0, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check.Missing(); got != tt.missing {
+				t.Errorf("Missing() = %d, want %d", got, tt.missing)
+			}
+			if got := tt.check.Unexpected(); got != tt.unexpected {
+				t.Errorf("Unexpected() = %d, want %d", got, tt.unexpected)
+			}
+			if got := tt.check.Skipped(); got != tt.skipped {
+				t.Errorf("Skipped() = %d, want %d", got, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	// Main holds a long Frame and two short Updates, Worker one Update
+	capture := func(declaredBlocks uint32, descriptors []proftest.Descriptor) *proftest.Profile {
+		return &proftest.Profile{
+			DeclaredBlocks: declaredBlocks,
+			Descriptors:    descriptors,
+			Threads: []proftest.Thread{
+				{ID: 1, Name: "Main", Blocks: []proftest.Block{
+					{ID: 2, Begin: 10, End: 20},
+					{ID: 2, Begin: 30, End: 40},
+					{ID: 1, Begin: 0, End: 1000},
+				}},
+				{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 10}}},
+			},
+		}
+	}
+	descriptors := proftest.Descriptors("Frame", "Update")
+	duplicated := append(proftest.Descriptors("Frame", "Update"), proftest.Descriptor{ID: 2, Name: "Update2"})
+
+	tests := []struct {
+		name        string
+		profile     *proftest.Profile
+		options     parser.ReadOptions
+		blocks      parser.CountCheck
+		descriptors parser.CountCheck
+		reasons     []string
+		ok          bool
+		complete    bool
+	}{
+		{"complete", capture(0, descriptors), parser.ReadOptions{},
+			parser.CountCheck{Declared: 4, InFile: 4, Loaded: 4}, parser.CountCheck{Declared: 2, InFile: 2, Loaded: 2}, nil, true, true},
+		{"over-reported blocks", capture(10, descriptors), parser.ReadOptions{},
+			parser.CountCheck{Declared: 10, InFile: 4, Loaded: 4}, parser.CountCheck{Declared: 2, InFile: 2, Loaded: 2}, nil, false, false},
+		{"under-reported blocks", capture(2, descriptors), parser.ReadOptions{},
+			parser.CountCheck{Declared: 2, InFile: 4, Loaded: 4}, parser.CountCheck{Declared: 2, InFile: 2, Loaded: 2}, nil, false, false},
+		{"sampled", capture(0, descriptors), parser.ReadOptions{SampleBlocks: 2},
+			parser.CountCheck{Declared: 4, InFile: 4, Loaded: 3}, parser.CountCheck{Declared: 2, InFile: 2, Loaded: 2}, []string{"block sampling"}, true, false},
+		{"minimum duration", capture(0, descriptors), parser.ReadOptions{MinBlockDuration: 100},
+			parser.CountCheck{Declared: 4, InFile: 4, Loaded: 1}, parser.CountCheck{Declared: 2, InFile: 2, Loaded: 2}, []string{"minimum block duration"}, true, false},
+		{"thread filter", capture(0, descriptors), parser.ReadOptions{ThreadNameFilter: "Main"},
+			parser.CountCheck{Declared: 4, InFile: 4, Loaded: 3}, parser.CountCheck{Declared: 2, InFile: 2, Loaded: 2}, []string{"thread filter"}, true, false},
+		{"duplicate descriptor IDs", capture(0, duplicated), parser.ReadOptions{},
+			parser.CountCheck{Declared: 4, InFile: 4, Loaded: 4}, parser.CountCheck{Declared: 3, InFile: 3, Loaded: 2}, nil, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := tt.profile.Parse(t, tt.options).CheckIntegrity()
+			if report == nil {
+				t.Fatal("CheckIntegrity returned nil for a freshly parsed profile")
+			}
+			if report.Blocks != tt.blocks || report.Descriptors != tt.descriptors {
+				t.Errorf("blocks %+v, descriptors %+v; want %+v, %+v", report.Blocks, report.Descriptors, tt.blocks, tt.descriptors)
+			}
+			if !reflect.DeepEqual(report.SkipReasons, tt.reasons) {
+				t.Errorf("skip reasons = %v, want %v", report.SkipReasons, tt.reasons)
+			}
+			if report.OK() != tt.ok || report.Complete() != tt.complete {
+				t.Errorf("OK() = %t, Complete() = %t; want %t, %t", report.OK(), report.Complete(), tt.ok, tt.complete)
+			}
+		})
+	}
+}
+
+func TestCheckIntegrityWithoutRecordCounts(t *testing.T) {
+	// Profiles restored from an old cache have blocks but no record counts
+	data := parser.NewProfileData()
+	data.TotalBlocksCount = 3
+	if report := data.CheckIntegrity(); report != nil {
+		t.Errorf("CheckIntegrity = %+v, want nil", report)
+	}
+}
@@ -0,0 +1,63 @@
+package parser
+
+import "unsafe"
+
+// mapEntryOverhead approximates the per-entry bucket overhead of a Go map
+const mapEntryOverhead = 16
+
+// MemoryEstimate is an approximation of the Go heap retained by a parsed profile
+type MemoryEstimate struct {
+	BlocksBytes          int64
+	ContextSwitchesBytes int64
+	DescriptorsBytes     int64
+	ThreadsBytes         int64
+	BookmarksBytes       int64
+	TotalBytes           int64
+}
+
+// EstimateMemory approximates the retained size of the parsed profile from struct sizes,
+// string lengths and slice capacities. It doesn't account for allocator rounding.
+func (p *ProfileData) EstimateMemory() MemoryEstimate {
+	var estimate MemoryEstimate
+
+	for _, descriptor := range p.Descriptors {
+		estimate.DescriptorsBytes += int64(unsafe.Sizeof(*descriptor)) + mapEntryOverhead +
+			int64(len(descriptor.Name)+len(descriptor.File))
+	}
+
+	for _, thread := range p.Threads {
+		estimate.ThreadsBytes += int64(unsafe.Sizeof(*thread)) + mapEntryOverhead + int64(len(thread.ThreadName))
+
+		var csPointer *ContextSwitch
+		estimate.ContextSwitchesBytes += int64(cap(thread.ContextSwitches)) * int64(unsafe.Sizeof(csPointer))
+		for _, cs := range thread.ContextSwitches {
+			estimate.ContextSwitchesBytes += int64(unsafe.Sizeof(*cs)) + int64(len(cs.Name))
+		}
+
+		estimate.BlocksBytes += estimateBlocks(thread.Blocks)
+	}
+
+	for _, bookmark := range p.Bookmarks {
+		estimate.BookmarksBytes += int64(unsafe.Sizeof(*bookmark)) + int64(unsafe.Sizeof(bookmark)) + int64(len(bookmark.Text))
+	}
+
+	estimate.TotalBytes = estimate.BlocksBytes + estimate.ContextSwitchesBytes +
+		estimate.DescriptorsBytes + estimate.ThreadsBytes + estimate.BookmarksBytes
+	return estimate
+}
+
+// estimateBlocks returns the retained size of blocks, their names, values and child slices
+func estimateBlocks(blocks []*Block) int64 {
+	var pointer *Block
+	size := int64(cap(blocks)) * int64(unsafe.Sizeof(pointer))
+
+	WalkBlocks(blocks, DefaultMaxTreeDepth, func(block *Block, _ int) {
+		size += int64(unsafe.Sizeof(*block)) + int64(len(block.Name))
+		if block.Value != nil {
+			size += int64(unsafe.Sizeof(*block.Value)) + int64(cap(block.Value.Data))
+		}
+		size += int64(cap(block.Children)) * int64(unsafe.Sizeof(pointer))
+	})
+
+	return size
+}
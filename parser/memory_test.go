@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestEstimateMemory(t *testing.T) {
+	blockSize := int64(unsafe.Sizeof(Block{}) + unsafe.Sizeof(&Block{}))
+	// A block listed as its own child, as a tree-building bug could leave it
+	cyclic := &Block{}
+	cyclic.Children = []*Block{cyclic}
+
+	tests := []struct {
+		name    string
+		profile *ProfileData
+		want    MemoryEstimate
+	}{
+		{"empty", &ProfileData{}, MemoryEstimate{}},
+		{"one block", &ProfileData{Threads: map[uint64]*ThreadData{
+			1: {Blocks: []*Block{{Name: "abc"}}},
+		}}, MemoryEstimate{
+			BlocksBytes:  blockSize + 3,
+			ThreadsBytes: int64(unsafe.Sizeof(ThreadData{})) + mapEntryOverhead,
+		}},
+		{"nested blocks", &ProfileData{Threads: map[uint64]*ThreadData{
+			1: {ThreadName: "Main", Blocks: []*Block{{Children: []*Block{{}, {}}}}},
+		}}, MemoryEstimate{
+			BlocksBytes:  3 * blockSize,
+			ThreadsBytes: int64(unsafe.Sizeof(ThreadData{})) + mapEntryOverhead + 4,
+		}},
+		{"value block", &ProfileData{Threads: map[uint64]*ThreadData{
+			1: {Blocks: []*Block{{Value: &Value{Data: make([]byte, 8)}}}},
+		}}, MemoryEstimate{
+			BlocksBytes:  blockSize + int64(unsafe.Sizeof(Value{})) + 8,
+			ThreadsBytes: int64(unsafe.Sizeof(ThreadData{})) + mapEntryOverhead,
+		}},
+		{"cycle stops at the depth limit", &ProfileData{Threads: map[uint64]*ThreadData{
+			1: {Blocks: []*Block{cyclic}},
+		}}, MemoryEstimate{
+			BlocksBytes:  (DefaultMaxTreeDepth+1)*blockSize + int64(unsafe.Sizeof(cyclic)),
+			ThreadsBytes: int64(unsafe.Sizeof(ThreadData{})) + mapEntryOverhead,
+		}},
+		{"descriptor and bookmark", &ProfileData{
+			Descriptors: map[uint32]*BlockDescriptor{1: {Name: "Update", File: "a.cpp"}},
+			Bookmarks:   []*Bookmark{{Text: "spike"}},
+		}, MemoryEstimate{
+			DescriptorsBytes: int64(unsafe.Sizeof(BlockDescriptor{})) + mapEntryOverhead + 11,
+			BookmarksBytes:   int64(unsafe.Sizeof(Bookmark{})+unsafe.Sizeof(&Bookmark{})) + 5,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.TotalBytes = tt.want.BlocksBytes + tt.want.ContextSwitchesBytes +
+				tt.want.DescriptorsBytes + tt.want.ThreadsBytes + tt.want.BookmarksBytes
+			if got := tt.profile.EstimateMemory(); got != tt.want {
+				t.Errorf("EstimateMemory = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,14 @@
 package parser
 
+import (
+	"fmt"
+	"time"
+)
+
 // ReadOptions configures how the profile is parsed
 type ReadOptions struct {
-	// MaxBlockDepth limits how deep we read nested blocks (0 = unlimited)
+	// MaxBlockDepth, if > 0, drops blocks nested this many levels deep or deeper,
+	// with their subtrees, when the call trees are built (0 = unlimited)
 	MaxBlockDepth int
 
 	// SampleBlocks if > 0, only reads every Nth block (for large files)
@@ -14,11 +20,48 @@ type ReadOptions struct {
 	// SkipBookmarks skips reading bookmarks
 	SkipBookmarks bool
 
-	// MaxThreads limits how many threads to read (0 = all)
+	// MaxThreads limits how many threads to read (0 = all). Threads past the limit
+	// are skipped, as are threads rejected by ThreadNameFilter, which don't count.
 	MaxThreads int
 
-	// ProgressCallback is called periodically during parsing
+	// ThreadNameFilter, if set, is a regular expression; only threads whose name
+	// matches it are read. The others' context switches and blocks are skipped
+	// without being decoded. A plain substring such as "Render" works as well.
+	ThreadNameFilter string
+
+	// MinBlockDuration drops blocks shorter than this while reading (0 = keep all).
+	// A nested block can never outlast its parent, so dropping a block always
+	// drops its whole subtree as well and no children are orphaned. Values and
+	// events have no duration and are only dropped with an enclosing block.
+	MinBlockDuration time.Duration
+
+	// RetainSlowest, if > 0, keeps only the N slowest blocks of the whole file in a
+	// bounded min-heap while streaming through it, instead of every block. No trees
+	// are built: the survivors become childless top-level blocks of their threads,
+	// so only slowest-block queries stay meaningful. Unclosed blocks are never kept.
+	RetainSlowest int
+
+	// MaxTreeDepth rejects captures whose blocks nest deeper than this. 0 or values
+	// above DefaultMaxTreeDepth use DefaultMaxTreeDepth, which traversals rely on.
+	MaxTreeDepth int
+
+	// StrictReservedFields rejects files whose reserved header fields hold non-zero
+	// values or whose version is newer than LatestKnownVersion, instead of reading
+	// them with ProfileData.HeaderWarnings set
+	StrictReservedFields bool
+
+	// ProgressCallback is called periodically during parsing with the share of the
+	// file read so far
 	ProgressCallback func(percent int)
+
+	// ProgressStep is the minimum increase in percent between ProgressCallback calls
+	// (0 = 1). The first call and the final 100 are always reported.
+	ProgressStep int
+
+	// PhaseCallback, if set, is called whenever parsing enters a new phase, with a
+	// label such as "reading descriptors", "reading thread 3/8" or "building tree
+	// for thread 3/8" and the share of the file read so far
+	PhaseCallback func(phase string, percent int)
 }
 
 // DefaultReadOptions returns sensible defaults
@@ -43,3 +86,19 @@ func FastReadOptions() ReadOptions {
 		MaxThreads:          0,
 	}
 }
+
+// cacheKey returns a stable string identifying the options that affect parsed output
+// or whether parsing succeeds. The progress callbacks are excluded since they don't.
+func (o ReadOptions) cacheKey() string {
+	return fmt.Sprintf("depth=%d;sample=%d;skipcs=%t;skipbm=%t;threads=%d;minblock=%d;threadfilter=%q;slowest=%d;treedepth=%d;strict=%t",
+		o.MaxBlockDepth, o.SampleBlocks, o.SkipContextSwitches, o.SkipBookmarks, o.MaxThreads, o.MinBlockDuration, o.ThreadNameFilter, o.RetainSlowest,
+		o.maxTreeDepth(), o.StrictReservedFields)
+}
+
+// maxTreeDepth returns the effective MaxTreeDepth
+func (o ReadOptions) maxTreeDepth() int {
+	if o.MaxTreeDepth <= 0 || o.MaxTreeDepth > DefaultMaxTreeDepth {
+		return DefaultMaxTreeDepth
+	}
+	return o.MaxTreeDepth
+}
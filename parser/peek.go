@@ -0,0 +1,217 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// PeekRecord locates one size-prefixed record in the file
+type PeekRecord struct {
+	Offset int64 // Of the record's size prefix
+	Size   int   // Bytes including the 2-byte size prefix
+}
+
+// PeekDescriptor is a descriptor record with its decoded fields
+type PeekDescriptor struct {
+	PeekRecord
+	Descriptor *BlockDescriptor
+}
+
+// PeekBlock is a block record with its decoded fields, before tree building
+type PeekBlock struct {
+	PeekRecord
+	Block *Block
+}
+
+// PeekThread describes the start of one thread section
+type PeekThread struct {
+	Offset            int64 // Of the thread ID
+	ThreadID          uint64
+	Name              string
+	ContextSwitches   uint32
+	ContextSwitchesAt int64 // Offset of the context switch count
+	BlocksCount       uint32
+	BlocksAt          int64 // Offset of the block count
+	Blocks            []*PeekBlock
+	EndOffset         int64 // Offset just past the thread's last record
+	BlocksNotShown    int   // Block records skipped past the peeked ones
+}
+
+// PeekReport is a record-level dump of the start of a file, for diagnosing format
+// variants the parser gets wrong. Offsets are bytes from the start of the file.
+type PeekReport struct {
+	FileSize       int64
+	Header         FileHeader
+	HeaderSize     int64
+	HeaderWarnings []string
+
+	DescriptorsAt int64
+	Descriptors   []*PeekDescriptor // The first records only
+	ThreadsAt     int64
+	Threads       []*PeekThread // The first threads only
+
+	// StoppedAt and Error tell where and why decoding failed, if it did: the
+	// report holds everything decoded up to that point
+	StoppedAt int64
+	Error     string
+}
+
+// Peek decodes the header and the first records of each section, recording where
+// each record lies and how large it is: up to records descriptors, and up to
+// records threads with up to records blocks each. Decoding errors don't fail the
+// peek; they end it, and the report tells where. The reader is rewound afterwards.
+func (r *Reader) Peek(records int) (*PeekReport, error) {
+	if _, err := r.reader.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek to header: %w", err)
+	}
+
+	report := &PeekReport{FileSize: r.size}
+	if err := r.peek(report, records); err != nil {
+		report.StoppedAt = r.offset()
+		report.Error = err.Error()
+	}
+	report.Header = r.data.Header
+	report.HeaderWarnings = r.data.HeaderWarnings
+
+	// Leave no partial state behind for a subsequent Parse
+	r.data = NewProfileData()
+	if _, err := r.reader.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind reader: %w", err)
+	}
+
+	return report, nil
+}
+
+// peek fills report, returning the error that stopped decoding
+func (r *Reader) peek(report *PeekReport, records int) error {
+	if err := r.readHeader(); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	report.HeaderSize = r.offset()
+	if err := r.validateHeader(); err != nil {
+		return err
+	}
+
+	// Every descriptor is needed to decode value blocks, but only the first are shown
+	report.DescriptorsAt = r.offset()
+	for i := uint32(0); i < r.data.Header.DescriptorsCount; i++ {
+		offset := r.offset()
+		descriptor, err := r.readDescriptor()
+		if err != nil {
+			return fmt.Errorf("failed to read descriptor %d: %w", i, err)
+		}
+		r.data.Descriptors[descriptor.ID] = descriptor
+		if len(report.Descriptors) < records {
+			report.Descriptors = append(report.Descriptors, &PeekDescriptor{
+				PeekRecord: PeekRecord{Offset: offset, Size: int(r.offset() - offset)},
+				Descriptor: descriptor,
+			})
+		}
+	}
+
+	report.ThreadsAt = r.offset()
+	for len(report.Threads) < records {
+		if r.data.Header.Version >= Version210 && uint32(len(report.Threads)) >= r.data.Header.ThreadsCount {
+			return nil
+		}
+
+		thread, done, err := r.peekThread(records)
+		if thread != nil {
+			report.Threads = append(report.Threads, thread)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read thread %d: %w", len(report.Threads), err)
+		}
+		if done {
+			return nil
+		}
+	}
+	return nil
+}
+
+// peekThread decodes one thread section, keeping up to records blocks. done is set
+// at the end of the threads section.
+func (r *Reader) peekThread(records int) (thread *PeekThread, done bool, err error) {
+	thread = &PeekThread{Offset: r.offset()}
+
+	if r.data.Header.Version < Version130 {
+		var id uint32
+		if err := binary.Read(r.reader, binary.LittleEndian, &id); err != nil {
+			return nil, err == io.EOF, ignoreEOF(err)
+		}
+		if id == EasyProfilerSignature {
+			return nil, true, nil
+		}
+		thread.ThreadID = uint64(id)
+	} else {
+		if err := binary.Read(r.reader, binary.LittleEndian, &thread.ThreadID); err != nil {
+			return nil, err == io.EOF, ignoreEOF(err)
+		}
+		if uint32(thread.ThreadID) == EasyProfilerSignature && r.data.Header.Version < Version210 {
+			return nil, true, nil
+		}
+	}
+
+	var nameSize uint16
+	if err := binary.Read(r.reader, binary.LittleEndian, &nameSize); err != nil {
+		return thread, false, err
+	}
+	name := make([]byte, nameSize)
+	if _, err := io.ReadFull(r.reader, name); err != nil {
+		return thread, false, err
+	}
+	thread.Name, _ = r.decodeName(cString(name))
+
+	thread.ContextSwitchesAt = r.offset()
+	if err := binary.Read(r.reader, binary.LittleEndian, &thread.ContextSwitches); err != nil {
+		return thread, false, err
+	}
+	if err := r.skipRecords(thread.ContextSwitches); err != nil {
+		return thread, false, fmt.Errorf("failed to skip context switches: %w", err)
+	}
+
+	thread.BlocksAt = r.offset()
+	if err := binary.Read(r.reader, binary.LittleEndian, &thread.BlocksCount); err != nil {
+		return thread, false, err
+	}
+	for i := uint32(0); i < thread.BlocksCount; i++ {
+		if len(thread.Blocks) == records {
+			thread.BlocksNotShown = int(thread.BlocksCount - i)
+			if err := r.skipRecords(thread.BlocksCount - i); err != nil {
+				return thread, false, fmt.Errorf("failed to skip blocks: %w", err)
+			}
+			break
+		}
+
+		offset := r.offset()
+		block, err := r.readBlock()
+		if err != nil {
+			return thread, false, fmt.Errorf("failed to read block %d: %w", i, err)
+		}
+		thread.Blocks = append(thread.Blocks, &PeekBlock{
+			PeekRecord: PeekRecord{Offset: offset, Size: int(r.offset() - offset)},
+			Block:      block,
+		})
+	}
+	thread.EndOffset = r.offset()
+
+	return thread, false, nil
+}
+
+// offset returns the current position in the file, or -1 if it can't be told
+func (r *Reader) offset() int64 {
+	position, err := r.reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	return position
+}
+
+// ignoreEOF returns nil for io.EOF, which ends a threads section without a signature
+func ignoreEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
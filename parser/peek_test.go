@@ -0,0 +1,129 @@
+package parser_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// peekProfile lays out as: the 72-byte header; descriptors Frame (34 bytes) at 72
+// and Update (36) at 106; Main at 142 with three 23-byte blocks from 164; Worker at
+// 233 with one block at 257; the end signature at 280
+func peekProfile() *proftest.Profile {
+	return &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 10, End: 20},
+				{ID: 2, Begin: 30, End: 40},
+				{ID: 1, Begin: 0, End: 50},
+			}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 2, Begin: 0, End: 5}}},
+		},
+	}
+}
+
+// describePeek renders the offsets and sizes of a report's records
+func describePeek(report *parser.PeekReport) []string {
+	got := []string{fmt.Sprintf("header %d, descriptors at %d, threads at %d", report.HeaderSize, report.DescriptorsAt, report.ThreadsAt)}
+	for _, d := range report.Descriptors {
+		got = append(got, fmt.Sprintf("descriptor %s at %d size %d", d.Descriptor.Name, d.Offset, d.Size))
+	}
+	for _, thread := range report.Threads {
+		got = append(got, fmt.Sprintf("thread %s at %d, switches at %d, %d blocks at %d, end %d, %d not shown",
+			thread.Name, thread.Offset, thread.ContextSwitchesAt, thread.BlocksCount, thread.BlocksAt, thread.EndOffset, thread.BlocksNotShown))
+		for _, block := range thread.Blocks {
+			got = append(got, fmt.Sprintf("block %d %d-%d at %d size %d", block.Block.ID, block.Block.Begin, block.Block.End, block.Offset, block.Size))
+		}
+	}
+	return got
+}
+
+func TestPeek(t *testing.T) {
+	tests := []struct {
+		name    string
+		records int
+		want    []string
+	}{
+		{"every record", 5, []string{
+			"header 72, descriptors at 72, threads at 142",
+			"descriptor Frame at 72 size 34",
+			"descriptor Update at 106 size 36",
+			"thread Main at 142, switches at 156, 3 blocks at 160, end 233, 0 not shown",
+			"block 2 10-20 at 164 size 23",
+			"block 2 30-40 at 187 size 23",
+			"block 1 0-50 at 210 size 23",
+			"thread Worker at 233, switches at 249, 1 blocks at 253, end 280, 0 not shown",
+			"block 2 0-5 at 257 size 23",
+		}},
+		{"first records", 1, []string{
+			"header 72, descriptors at 72, threads at 142",
+			"descriptor Frame at 72 size 34",
+			"thread Main at 142, switches at 156, 3 blocks at 160, end 233, 2 not shown",
+			"block 2 10-20 at 164 size 23",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := parser.NewReader(peekProfile().WriteFile(t))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer reader.Close()
+
+			report, err := reader.Peek(tt.records)
+			if err != nil {
+				t.Fatalf("Peek: %v", err)
+			}
+			if report.Error != "" || report.FileSize != 284 {
+				t.Errorf("file size %d, error %q; want 284 and none", report.FileSize, report.Error)
+			}
+			if got := describePeek(report); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+
+			// The reader is rewound for a full parse
+			data, err := reader.Parse()
+			if err != nil {
+				t.Fatalf("Parse after Peek: %v", err)
+			}
+			if data.GetBlocksCount() != 4 {
+				t.Errorf("Parse after Peek read %d blocks, want 4", data.GetBlocksCount())
+			}
+		})
+	}
+}
+
+func TestPeekTruncated(t *testing.T) {
+	// Cut inside Main's second block, at 187-210
+	path := filepath.Join(t.TempDir(), "truncated.prof")
+	if err := os.WriteFile(path, peekProfile().Bytes()[:200], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := parser.NewReader(path)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer reader.Close()
+
+	report, err := reader.Peek(5)
+	if err != nil {
+		t.Fatalf("Peek: %v", err)
+	}
+	if !strings.Contains(report.Error, "failed to read thread 1: failed to read block 1") {
+		t.Errorf("error = %q, want it to name the second block of the first thread", report.Error)
+	}
+	if report.StoppedAt < 187 || report.StoppedAt > 200 {
+		t.Errorf("stopped at %d, want within the block at 187", report.StoppedAt)
+	}
+	if len(report.Threads) != 1 || len(report.Threads[0].Blocks) != 1 {
+		t.Errorf("kept %d threads, want Main with its first block", len(report.Threads))
+	}
+}
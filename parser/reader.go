@@ -1,10 +1,14 @@
 package parser
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"regexp"
+	"time"
 )
 
 // Reader parses EasyProfiler .prof files
@@ -12,6 +16,20 @@ type Reader struct {
 	reader  io.ReadSeeker
 	data    *ProfileData
 	options ReadOptions
+
+	// threadFilter is the compiled ReadOptions.ThreadNameFilter, nil if unset
+	threadFilter *regexp.Regexp
+
+	// size is the file size in bytes, used to compute progress
+	size int64
+
+	// reportedPercent is the last percent passed to ProgressCallback, -1 before the first call
+	reportedPercent int
+
+	// slowest holds the blocks kept so far under ReadOptions.RetainSlowest, and
+	// retainedOrder counts the blocks offered to it
+	slowest       slowestHeap
+	retainedOrder int
 }
 
 // NewReader creates a new Reader from a file path with default options
@@ -21,6 +39,15 @@ func NewReader(filePath string) (*Reader, error) {
 
 // NewReaderWithOptions creates a new Reader with custom read options
 func NewReaderWithOptions(filePath string, options ReadOptions) (*Reader, error) {
+	var threadFilter *regexp.Regexp
+	if options.ThreadNameFilter != "" {
+		var err error
+		threadFilter, err = regexp.Compile(options.ThreadNameFilter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid thread name filter: %w", err)
+		}
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
@@ -41,41 +68,53 @@ func NewReaderWithOptions(filePath string, options ReadOptions) (*Reader, error)
 	}
 
 	return &Reader{
-		reader:  file,
-		data:    NewProfileData(),
-		options: options,
+		reader:          file,
+		data:            NewProfileData(),
+		options:         options,
+		threadFilter:    threadFilter,
+		size:            fileSize,
+		reportedPercent: -1,
 	}, nil
 }
 
 // Parse reads and parses the entire .prof file
 func (r *Reader) Parse() (*ProfileData, error) {
 	// Read header
+	r.reportProgress("reading header", r.readPercent())
 	if err := r.readHeader(); err != nil {
 		return nil, fmt.Errorf("failed to read header: %w", err)
 	}
 
-	// Validate signature
-	if r.data.Header.Signature != EasyProfilerSignature {
-		return nil, fmt.Errorf("invalid file signature: 0x%X", r.data.Header.Signature)
-	}
-
-	// Validate version
-	if r.data.Header.Version < MinCompatibleVersion {
-		return nil, fmt.Errorf("unsupported version: 0x%X", r.data.Header.Version)
+	if err := r.validateHeader(); err != nil {
+		return nil, err
 	}
 
 	// Read descriptors
+	r.reportProgress("reading descriptors", r.readPercent())
 	if err := r.readDescriptors(); err != nil {
 		return nil, fmt.Errorf("failed to read descriptors: %w", err)
 	}
 
+	r.data.ContextSwitchesSkipped = r.options.SkipContextSwitches
+	if r.options.SampleBlocks > 1 {
+		r.data.SamplingFactor = r.options.SampleBlocks
+	}
+	if r.options.RetainSlowest > 0 {
+		r.data.RetainedSlowest = r.options.RetainSlowest
+	}
+
 	// Read threads
 	if err := r.readThreads(); err != nil {
 		return nil, fmt.Errorf("failed to read threads: %w", err)
 	}
+	if r.options.RetainSlowest > 0 {
+		r.placeRetained()
+	}
 
-	// Read bookmarks (if present and not skipped)
-	if !r.options.SkipBookmarks && r.data.Header.Version >= Version210 && r.data.Header.BookmarksCount > 0 {
+	// Read bookmarks (if present and not skipped). They follow the end signature,
+	// so a file missing it holds none.
+	if !r.options.SkipBookmarks && !r.data.MissingEndSignature && r.data.Header.Version >= Version210 && r.data.Header.BookmarksCount > 0 {
+		r.reportProgress("reading bookmarks", r.readPercent())
 		if err := r.readBookmarks(); err != nil {
 			return nil, fmt.Errorf("failed to read bookmarks: %w", err)
 		}
@@ -85,9 +124,52 @@ func (r *Reader) Parse() (*ProfileData, error) {
 	r.data.TotalBlocksCount = r.data.GetBlocksCount()
 	r.data.MemoryUsedBytes = int64(r.data.Header.MemorySize)
 
+	r.reportProgress("done", 100)
 	return r.data, nil
 }
 
+// ParseHeaderOnly reads and validates just the file header, without parsing
+// descriptors, threads or blocks. The reader is rewound afterwards, so Parse can
+// still be called on the same reader.
+func (r *Reader) ParseHeaderOnly() (*FileHeader, error) {
+	if _, err := r.reader.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek to header: %w", err)
+	}
+
+	if err := r.readHeader(); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	if err := r.validateHeader(); err != nil {
+		return nil, err
+	}
+
+	header := r.data.Header
+
+	// Leave no partial state behind for a subsequent Parse
+	r.data = NewProfileData()
+	if _, err := r.reader.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind reader: %w", err)
+	}
+
+	return &header, nil
+}
+
+// validateHeader checks the signature and version of the header just read
+func (r *Reader) validateHeader() error {
+	// Validate signature
+	if r.data.Header.Signature != EasyProfilerSignature {
+		return fmt.Errorf("invalid file signature: 0x%X", r.data.Header.Signature)
+	}
+
+	// Validate version
+	if r.data.Header.Version < MinCompatibleVersion {
+		return fmt.Errorf("unsupported version: 0x%X", r.data.Header.Version)
+	}
+
+	return r.checkReservedFields()
+}
+
 // Close closes the underlying file
 func (r *Reader) Close() error {
 	if closer, ok := r.reader.(io.Closer); ok {
@@ -186,61 +268,77 @@ func (r *Reader) readDescriptors() error {
 			return fmt.Errorf("failed to read descriptor %d: %w", i, err)
 		}
 		r.data.Descriptors[descriptor.ID] = descriptor
+		r.data.DescriptorRecordsCount++
 	}
 	return nil
 }
 
+// descriptorBaseSize is the size of a descriptor's fixed fields: ID, line, color,
+// type and status
+const descriptorBaseSize = 4 + 4 + 4 + 1 + 1
+
+// descriptorExtraFieldsSize returns the size of fields a format version inserts
+// between the fixed fields and the name length. No supported version has any; a
+// newer layout only needs an entry here to keep names and files aligned.
+func descriptorExtraFieldsSize(version uint32) int {
+	return 0
+}
+
 func (r *Reader) readDescriptor() (*BlockDescriptor, error) {
 	var size uint16
 	if err := binary.Read(r.reader, binary.LittleEndian, &size); err != nil {
 		return nil, err
 	}
 
-	descriptor := &BlockDescriptor{}
-
-	// Read base descriptor data
-	if err := binary.Read(r.reader, binary.LittleEndian, &descriptor.ID); err != nil {
+	// Read the whole record so that fields this reader doesn't know can't
+	// shift the stream, whatever the layout inside turns out to be
+	record := make([]byte, size)
+	if _, err := io.ReadFull(r.reader, record); err != nil {
 		return nil, err
 	}
-	if err := binary.Read(r.reader, binary.LittleEndian, &descriptor.Line); err != nil {
-		return nil, err
-	}
-	if err := binary.Read(r.reader, binary.LittleEndian, &descriptor.Color); err != nil {
-		return nil, err
-	}
-	if err := binary.Read(r.reader, binary.LittleEndian, &descriptor.Type); err != nil {
-		return nil, err
-	}
-	if err := binary.Read(r.reader, binary.LittleEndian, &descriptor.Status); err != nil {
-		return nil, err
+
+	nameOffset := descriptorBaseSize + descriptorExtraFieldsSize(r.data.Header.Version) + 2
+	if len(record) < nameOffset {
+		return nil, fmt.Errorf("descriptor record of %d bytes is shorter than its fixed fields (%d bytes)", len(record), nameOffset)
 	}
 
-	// Read name length
-	var nameLength uint16
-	if err := binary.Read(r.reader, binary.LittleEndian, &nameLength); err != nil {
-		return nil, err
+	// Read base descriptor data
+	descriptor := &BlockDescriptor{
+		ID:     binary.LittleEndian.Uint32(record[0:4]),
+		Line:   int32(binary.LittleEndian.Uint32(record[4:8])),
+		Color:  binary.LittleEndian.Uint32(record[8:12]),
+		Type:   BlockType(record[12]),
+		Status: record[13],
 	}
 
 	// Read name
-	nameBytes := make([]byte, nameLength)
-	if _, err := io.ReadFull(r.reader, nameBytes); err != nil {
-		return nil, err
+	nameLength := int(binary.LittleEndian.Uint16(record[nameOffset-2 : nameOffset]))
+	if nameOffset+nameLength > len(record) {
+		return nil, fmt.Errorf("descriptor %d name of %d bytes overflows its %d-byte record", descriptor.ID, nameLength, len(record))
 	}
-	descriptor.Name = string(nameBytes[:len(nameBytes)-1]) // Remove null terminator
+	var nameSanitized bool
+	descriptor.Name, nameSanitized = r.decodeName(cString(record[nameOffset : nameOffset+nameLength]))
 
-	// Read file name (remaining bytes)
-	remainingSize := size - (4 + 4 + 4 + 1 + 1 + 2 + nameLength)
-	if remainingSize > 0 {
-		fileBytes := make([]byte, remainingSize)
-		if _, err := io.ReadFull(r.reader, fileBytes); err != nil {
-			return nil, err
-		}
-		descriptor.File = string(fileBytes[:len(fileBytes)-1]) // Remove null terminator
+	// Read file name: the null-terminated string after the name. Any trailing
+	// bytes after its terminator belong to fields added by newer versions.
+	if rest := record[nameOffset+nameLength:]; len(rest) > 0 {
+		var fileSanitized bool
+		descriptor.File, fileSanitized = r.decodeName(cString(rest))
+		nameSanitized = nameSanitized || fileSanitized
 	}
+	descriptor.NameSanitized = nameSanitized
 
 	return descriptor, nil
 }
 
+// cString returns the bytes of b up to its first null byte, or all of b if there is none
+func cString(b []byte) []byte {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		return b[:i]
+	}
+	return b
+}
+
 func (r *Reader) readThreads() error {
 	threadsRead := uint32(0)
 	expectedThreads := r.data.Header.ThreadsCount
@@ -287,19 +385,31 @@ func (r *Reader) readThreads() error {
 			}
 		}
 
-		thread, err := r.readThread(threadID)
+		label := fmt.Sprintf("thread %d", threadsRead+1)
+		if expectedThreads != 0xFFFFFFFF {
+			label = fmt.Sprintf("thread %d/%d", threadsRead+1, expectedThreads)
+		}
+		r.reportProgress("reading "+label, r.readPercent())
+
+		thread, err := r.readThread(threadID, label)
 		if err != nil {
 			return fmt.Errorf("failed to read thread %d: %w", threadID, err)
 		}
-		r.data.Threads[threadID] = thread
+		if thread != nil {
+			r.data.Threads[threadID] = thread
+		} else {
+			r.data.SkippedThreadsCount++
+		}
 		threadsRead++
 	}
 
 	// Read end signature
 	var signature uint32
 	if err := binary.Read(r.reader, binary.LittleEndian, &signature); err != nil {
-		// EOF is acceptable here if we've read all expected threads
-		if err == io.EOF && threadsRead == expectedThreads {
+		// A missing or cut-off signature is acceptable if we've read all expected
+		// threads: captures stopped abruptly often don't flush it
+		if (err == io.EOF || err == io.ErrUnexpectedEOF) && threadsRead == expectedThreads {
+			r.data.MissingEndSignature = true
 			return nil
 		}
 		return fmt.Errorf("failed to read end signature: %w", err)
@@ -312,7 +422,78 @@ func (r *Reader) readThreads() error {
 	return nil
 }
 
-func (r *Reader) readThread(threadID uint64) (*ThreadData, error) {
+// readPercent returns the share of the file consumed so far, 0 if unknown
+func (r *Reader) readPercent() int {
+	if r.size <= 0 {
+		return 0
+	}
+	position, err := r.reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0
+	}
+	return int(min(100, position*100/r.size))
+}
+
+// reportProgress notifies PhaseCallback of a new phase, and ProgressCallback if
+// percent advanced by at least ProgressStep since its last call
+func (r *Reader) reportProgress(phase string, percent int) {
+	if r.options.PhaseCallback != nil {
+		r.options.PhaseCallback(phase, percent)
+	}
+
+	if r.options.ProgressCallback == nil {
+		return
+	}
+	step := max(1, r.options.ProgressStep)
+	if r.reportedPercent < 0 || percent >= r.reportedPercent+step || (percent == 100 && r.reportedPercent < 100) {
+		r.reportedPercent = percent
+		r.options.ProgressCallback(percent)
+	}
+}
+
+// wantThread reports whether a thread with the given name should be read
+func (r *Reader) wantThread(name string) bool {
+	if r.options.MaxThreads > 0 && len(r.data.Threads) >= r.options.MaxThreads {
+		return false
+	}
+	return r.threadFilter == nil || r.threadFilter.MatchString(name)
+}
+
+// skipRecords skips count size-prefixed records without decoding them
+func (r *Reader) skipRecords(count uint32) error {
+	for i := uint32(0); i < count; i++ {
+		var size uint16
+		if err := binary.Read(r.reader, binary.LittleEndian, &size); err != nil {
+			return err
+		}
+		if _, err := r.reader.Seek(int64(size), io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipThreadData skips a thread's context switches and blocks, leaving the
+// stream at the next thread
+func (r *Reader) skipThreadData() error {
+	for section := 0; section < 2; section++ {
+		var count uint32
+		if err := binary.Read(r.reader, binary.LittleEndian, &count); err != nil {
+			return err
+		}
+		if err := r.skipRecords(count); err != nil {
+			return err
+		}
+		if section == 1 {
+			r.data.BlockRecordsCount += int(count)
+		}
+	}
+	return nil
+}
+
+// readThread reads a thread's data after its ID. It returns a nil thread, having
+// skipped its data, if the thread is excluded by the read options.
+func (r *Reader) readThread(threadID uint64, label string) (*ThreadData, error) {
 	thread := &ThreadData{
 		ThreadID:        threadID,
 		ContextSwitches: make([]*ContextSwitch, 0),
@@ -331,7 +512,11 @@ func (r *Reader) readThread(threadID uint64) (*ThreadData, error) {
 		if _, err := io.ReadFull(r.reader, nameBytes); err != nil {
 			return nil, err
 		}
-		thread.ThreadName = string(nameBytes)
+		thread.ThreadName, thread.NameSanitized = r.decodeName(nameBytes)
+	}
+
+	if !r.wantThread(thread.ThreadName) {
+		return nil, r.skipThreadData()
 	}
 
 	// Read context switches count
@@ -343,15 +528,8 @@ func (r *Reader) readThread(threadID uint64) (*ThreadData, error) {
 	// Read context switches (or skip them if option is set)
 	if r.options.SkipContextSwitches {
 		// Skip context switches by reading and discarding
-		for i := uint32(0); i < csCount; i++ {
-			var size uint16
-			if err := binary.Read(r.reader, binary.LittleEndian, &size); err != nil {
-				return nil, err
-			}
-			// Skip the data
-			if _, err := r.reader.Seek(int64(size), io.SeekCurrent); err != nil {
-				return nil, err
-			}
+		if err := r.skipRecords(csCount); err != nil {
+			return nil, err
 		}
 	} else {
 		// Read context switches normally
@@ -370,15 +548,53 @@ func (r *Reader) readThread(threadID uint64) (*ThreadData, error) {
 		return nil, err
 	}
 
+	r.data.BlockRecordsCount += int(blocksCount)
+
 	// Read blocks
+	occurrences := make(map[uint32]uint32)
 	for i := uint32(0); i < blocksCount; i++ {
 		block, err := r.readBlock()
 		if err != nil {
 			return nil, fmt.Errorf("failed to read block %d: %w", i, err)
 		}
+		block.Ordinal = occurrences[block.ID]
+		occurrences[block.ID]++
+		if r.options.SampleBlocks > 1 && i%uint32(r.options.SampleBlocks) != 0 {
+			continue
+		}
+		if r.isUnclosed(block) {
+			r.closeAtCaptureEnd(block)
+		}
+		if r.options.MinBlockDuration > 0 && r.data.isShortBlock(block, r.options.MinBlockDuration) {
+			// Records are written as blocks end, so the values and events kept inside
+			// the block are the last ones read; they go with it
+			kept := len(thread.Blocks)
+			for kept > 0 && thread.Blocks[kept-1].Begin >= block.Begin && thread.Blocks[kept-1].End <= block.End {
+				kept--
+			}
+			r.data.DroppedBlocksCount += 1 + len(thread.Blocks) - kept
+			thread.Blocks = thread.Blocks[:kept]
+			continue
+		}
+		if r.options.RetainSlowest > 0 {
+			if !block.Unclosed {
+				r.retainSlowest(block, threadID)
+			}
+			continue
+		}
 		thread.Blocks = append(thread.Blocks, block)
 	}
 
+	// Rebuild the call hierarchy from the flat block list
+	r.reportProgress("building tree for "+label, r.readPercent())
+	blocks, dropped, err := buildBlockTree(thread.Blocks, r.options.maxTreeDepth(), r.options.MaxBlockDepth)
+	if err != nil {
+		return nil, err
+	}
+	thread.Blocks = blocks
+	r.data.DepthLimitedBlocksCount += dropped
+	thread.updateActivitySpan()
+
 	return thread, nil
 }
 
@@ -407,7 +623,7 @@ func (r *Reader) readContextSwitch() (*ContextSwitch, error) {
 		if _, err := io.ReadFull(r.reader, nameBytes); err != nil {
 			return nil, err
 		}
-		cs.Name = string(nameBytes[:len(nameBytes)-1]) // Remove null terminator
+		cs.Name, _ = r.decodeName(nameBytes[:len(nameBytes)-1]) // Remove null terminator
 	}
 
 	return cs, nil
@@ -433,23 +649,72 @@ func (r *Reader) readBlock() (*Block, error) {
 		return nil, err
 	}
 
-	// Read name (remaining bytes)
 	remainingSize := size - 20 // 8 + 8 + 4
+
+	// Value blocks carry a typed payload instead of a name, and store the
+	// value ID in the End field - they're instantaneous samples
+	if descriptor := r.data.Descriptors[block.ID]; descriptor != nil && descriptor.Type == BlockTypeValue {
+		payload := make([]byte, remainingSize)
+		if _, err := io.ReadFull(r.reader, payload); err != nil {
+			return nil, err
+		}
+		value, err := decodeValue(block.End, payload)
+		if err != nil {
+			return nil, err
+		}
+		block.Value = value
+		block.End = block.Begin
+		return block, nil
+	}
+
+	// Read name (remaining bytes)
 	if remainingSize > 0 {
 		nameBytes := make([]byte, remainingSize)
 		if _, err := io.ReadFull(r.reader, nameBytes); err != nil {
 			return nil, err
 		}
 		if len(nameBytes) > 0 && nameBytes[len(nameBytes)-1] == 0 {
-			block.Name = string(nameBytes[:len(nameBytes)-1])
+			block.Name, _ = r.decodeName(nameBytes[:len(nameBytes)-1])
 		} else {
-			block.Name = string(nameBytes)
+			block.Name, _ = r.decodeName(nameBytes)
 		}
 	}
 
 	return block, nil
 }
 
+// isShortBlock reports whether block is shorter than minDuration. Values and events
+// are instantaneous samples rather than timed scopes, so they are never short.
+func (p *ProfileData) isShortBlock(block *Block, minDuration time.Duration) bool {
+	if descriptor := p.Descriptors[block.ID]; descriptor != nil && descriptor.Type != BlockTypeBlock {
+		return false
+	}
+	return block.Duration() < minDuration
+}
+
+// isUnclosed reports whether a block's End was never written, as happens when a
+// capture is cut short mid-block (e.g. by a crash): End is 0 or all ones, or lies
+// further past the capture end than the whole capture lasted
+func (r *Reader) isUnclosed(block *Block) bool {
+	if block.End == 0 {
+		return block.Begin > 0
+	}
+	if block.End == math.MaxUint64 {
+		return true
+	}
+
+	begin, end := r.data.Header.BeginTime, r.data.Header.EndTime
+	return end > begin && block.End > end && block.End-end > end-begin
+}
+
+// closeAtCaptureEnd marks block as unclosed and ends it with the capture, treating
+// it as still running when recording stopped
+func (r *Reader) closeAtCaptureEnd(block *Block) {
+	block.Unclosed = true
+	block.End = max(block.Begin, r.data.Header.EndTime)
+	r.data.UnclosedBlocksCount++
+}
+
 func (r *Reader) readBookmarks() error {
 	for i := uint16(0); i < r.data.Header.BookmarksCount; i++ {
 		bookmark, err := r.readBookmark()
@@ -493,8 +758,18 @@ func (r *Reader) readBookmark() (*Bookmark, error) {
 		if _, err := io.ReadFull(r.reader, textBytes); err != nil {
 			return nil, err
 		}
-		bookmark.Text = string(textBytes[:len(textBytes)-1]) // Remove null terminator
+		bookmark.Text, _ = r.decodeName(textBytes[:len(textBytes)-1]) // Remove null terminator
 	}
 
 	return bookmark, nil
 }
+
+// decodeName converts raw name bytes to a valid UTF-8 string and counts any
+// names that needed sanitizing
+func (r *Reader) decodeName(raw []byte) (string, bool) {
+	name, sanitized := sanitizeName(raw)
+	if sanitized {
+		r.data.SanitizedNamesCount++
+	}
+	return name, sanitized
+}
@@ -0,0 +1,792 @@
+package parser_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestParseSanitizesNames(t *testing.T) {
+	const bad = "\xff"
+	data := (&proftest.Profile{
+		Descriptors: []proftest.Descriptor{
+			{ID: 1, Name: "Good", File: "good.cpp", Type: parser.BlockTypeBlock},
+			{ID: 2, Name: "Bad" + bad, File: "bad.cpp", Type: parser.BlockTypeBlock},
+			{ID: 3, Name: "BadFile", File: "bad" + bad + ".cpp", Type: parser.BlockTypeBlock},
+		},
+		Threads: []proftest.Thread{{
+			ID:   1,
+			Name: "Main" + bad,
+			ContextSwitches: []proftest.ContextSwitch{
+				{ThreadID: 1, Begin: 10, End: 20, Name: "proc" + bad},
+			},
+			Blocks: []proftest.Block{
+				{ID: 1, Begin: 0, End: 100, Name: "runtime" + bad},
+			},
+		}},
+		Bookmarks: []proftest.Bookmark{{Position: 50, Text: "mark" + bad}},
+	}).Load(t)
+
+	marker := parser.InvalidUTF8Marker
+	tests := []struct {
+		name          string
+		got           string
+		want          string
+		sanitized     bool
+		wantSanitized bool
+	}{
+		{"clean descriptor", data.Descriptors[1].Name, "Good", data.Descriptors[1].NameSanitized, false},
+		{"descriptor name", data.Descriptors[2].Name, "Bad" + marker, data.Descriptors[2].NameSanitized, true},
+		{"descriptor file", data.Descriptors[3].File, "bad" + marker + ".cpp", data.Descriptors[3].NameSanitized, true},
+		{"thread name", data.Threads[1].ThreadName, "Main" + marker, data.Threads[1].NameSanitized, true},
+		{"context switch", data.Threads[1].ContextSwitches[0].Name, "proc" + marker, true, true},
+		{"block name", data.Threads[1].Blocks[0].Name, "runtime" + marker, true, true},
+		{"bookmark", data.Bookmarks[0].Text, "mark" + marker, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+			if tt.sanitized != tt.wantSanitized {
+				t.Errorf("NameSanitized = %t, want %t", tt.sanitized, tt.wantSanitized)
+			}
+		})
+	}
+
+	if data.SanitizedNamesCount != 6 {
+		t.Errorf("SanitizedNamesCount = %d, want 6", data.SanitizedNamesCount)
+	}
+}
+
+// outline lists a thread's blocks in depth-first order as "<descriptor name>@<depth>"
+func outline(data *parser.ProfileData, threadID uint64) []string {
+	lines := []string{}
+	parser.WalkBlocks(data.Threads[threadID].Blocks, parser.DefaultMaxTreeDepth, func(block *parser.Block, depth int) {
+		lines = append(lines, fmt.Sprintf("%s@%d", data.Descriptors[block.ID].Name, depth))
+	})
+	return lines
+}
+
+func TestParseMinBlockDuration(t *testing.T) {
+	capture := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Tiny", "Short"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 3, Begin: 110, End: 111},
+			{ID: 2, Begin: 100, End: 600},
+			{ID: 1, Begin: 0, End: 1000},
+			{ID: 4, Begin: 1000, End: 1050},
+		}}},
+	}
+
+	tests := []struct {
+		name        string
+		minDuration time.Duration
+		want        []string
+		wantDropped int
+	}{
+		{"keep all", 0, []string{"Frame@0", "Update@1", "Tiny@2", "Short@0"}, 0},
+		{"drop the tiny leaf", 10, []string{"Frame@0", "Update@1", "Short@0"}, 1},
+		{"threshold is exclusive", 50, []string{"Frame@0", "Update@1", "Short@0"}, 1},
+		{"drop a subtree", 501, []string{"Frame@0"}, 3},
+		{"drop everything", time.Second, []string{}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := capture.Parse(t, parser.ReadOptions{MinBlockDuration: tt.minDuration})
+			if got := outline(data, 1); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blocks = %v, want %v", got, tt.want)
+			}
+			if data.DroppedBlocksCount != tt.wantDropped {
+				t.Errorf("DroppedBlocksCount = %d, want %d", data.DroppedBlocksCount, tt.wantDropped)
+			}
+			if data.TotalBlocksCount != len(tt.want) {
+				t.Errorf("TotalBlocksCount = %d, want %d", data.TotalBlocksCount, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestParseMinBlockDurationKeepsEvents(t *testing.T) {
+	// Tick events inside Tiny, Update and Short; they have no duration of their own
+	descriptors := proftest.Descriptors("Frame", "Update", "Tiny", "Short", "Tick")
+	descriptors[4].Type = parser.BlockTypeEvent
+	capture := &proftest.Profile{
+		Descriptors: descriptors,
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 5, Begin: 110, End: 110},
+			{ID: 3, Begin: 110, End: 111},
+			{ID: 5, Begin: 150, End: 150},
+			{ID: 2, Begin: 100, End: 600},
+			{ID: 1, Begin: 0, End: 1000},
+			{ID: 5, Begin: 1020, End: 1020},
+			{ID: 4, Begin: 1000, End: 1050},
+		}}},
+	}
+
+	tests := []struct {
+		name        string
+		minDuration time.Duration
+		want        []string
+		wantDropped int
+	}{
+		{"keep all", 0, []string{"Frame@0", "Update@1", "Tiny@2", "Tick@3", "Tick@2", "Short@0", "Tick@1"}, 0},
+		{"events in kept blocks stay", 10, []string{"Frame@0", "Update@1", "Tick@2", "Short@0", "Tick@1"}, 2},
+		{"events go with their block", 501, []string{"Frame@0"}, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := capture.Parse(t, parser.ReadOptions{MinBlockDuration: tt.minDuration})
+			if got := outline(data, 1); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blocks = %v, want %v", got, tt.want)
+			}
+			if data.DroppedBlocksCount != tt.wantDropped {
+				t.Errorf("DroppedBlocksCount = %d, want %d", data.DroppedBlocksCount, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestParseHeaderOnly(t *testing.T) {
+	valid := &proftest.Profile{
+		PID:         42,
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 1000, End: 5000}}}},
+		Bookmarks:   []proftest.Bookmark{{Position: 2000, Text: "spike"}},
+	}
+
+	tests := []struct {
+		name    string
+		data    func() []byte
+		wantErr string
+	}{
+		{"valid", valid.Bytes, ""},
+		{"invalid signature", func() []byte {
+			data := valid.Bytes()
+			data[0] ^= 0xff
+			return data
+		}, "invalid file signature"},
+		{"unsupported version", func() []byte {
+			data := valid.Bytes()
+			binary.LittleEndian.PutUint32(data[4:], parser.MinCompatibleVersion-1)
+			return data
+		}, "unsupported version"},
+		{"truncated", func() []byte { return valid.Bytes()[:10] }, "failed to read header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "capture.prof")
+			if err := os.WriteFile(path, tt.data(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			reader, err := parser.NewReader(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+
+			header, err := reader.ParseHeaderOnly()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseHeaderOnly error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseHeaderOnly: %v", err)
+			}
+			want := parser.FileHeader{PID: 42, BeginTime: 1000, EndTime: 5000, BlocksCount: 1, DescriptorsCount: 1, ThreadsCount: 1, BookmarksCount: 1}
+			if header.PID != want.PID || header.BeginTime != want.BeginTime || header.EndTime != want.EndTime ||
+				header.BlocksCount != want.BlocksCount || header.DescriptorsCount != want.DescriptorsCount ||
+				header.ThreadsCount != want.ThreadsCount || header.BookmarksCount != want.BookmarksCount {
+				t.Errorf("header = %+v, want the fields of %+v", *header, want)
+			}
+
+			// The reader is rewound, so a full parse still works
+			data, err := reader.Parse()
+			if err != nil {
+				t.Fatalf("Parse after ParseHeaderOnly: %v", err)
+			}
+			if data.TotalBlocksCount != 1 || len(data.Bookmarks) != 1 {
+				t.Errorf("Parse after ParseHeaderOnly read %d blocks and %d bookmarks, want 1 and 1", data.TotalBlocksCount, len(data.Bookmarks))
+			}
+		})
+	}
+}
+
+func TestParseActivitySpan(t *testing.T) {
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         10000,
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{
+				{ID: 2, Begin: 1100, End: 1200},
+				{ID: 1, Begin: 1000, End: 2000},
+				{ID: 2, Begin: 7000, End: 7050},
+			}},
+			{ID: 2, Name: "Idle"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		options parser.ReadOptions
+		thread  uint64
+		first   uint64
+		last    uint64
+	}{
+		{"blocks", parser.DefaultReadOptions(), 1, 1000, 7050},
+		{"short blocks dropped", parser.ReadOptions{MinBlockDuration: 100 * time.Nanosecond}, 1, 1000, 2000},
+		{"no blocks", parser.DefaultReadOptions(), 2, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thread := p.Parse(t, tt.options).Threads[tt.thread]
+			if thread.FirstBlockBegin != tt.first || thread.LastBlockEnd != tt.last {
+				t.Errorf("activity span = [%d, %d], want [%d, %d]", thread.FirstBlockBegin, thread.LastBlockEnd, tt.first, tt.last)
+			}
+		})
+	}
+}
+
+func TestParseRejectsDeepNesting(t *testing.T) {
+	tests := []struct {
+		name     string
+		levels   int
+		maxDepth int
+		wantErr  bool
+	}{
+		{"default limit", parser.DefaultMaxTreeDepth + 1, 0, false},
+		{"past the default limit", parser.DefaultMaxTreeDepth + 2, 0, true},
+		{"far past the default limit", 100000, 0, true},
+		{"limit above the default is capped", parser.DefaultMaxTreeDepth + 2, parser.DefaultMaxTreeDepth + 10, true},
+		{"small limit", 6, 5, false},
+		{"past a small limit", 7, 5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &proftest.Profile{
+				Descriptors: proftest.Descriptors("Recurse"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: proftest.Nested(1, 0, tt.levels)}},
+			}
+			data, err := proftest.ParseFile(p.WriteFile(t), parser.ReadOptions{MaxTreeDepth: tt.maxDepth})
+			if tt.wantErr {
+				if !errors.Is(err, parser.ErrTreeTooDeep) {
+					t.Errorf("Parse error = %v, want ErrTreeTooDeep", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if data.TotalBlocksCount != tt.levels {
+				t.Errorf("parsed %d blocks, want %d", data.TotalBlocksCount, tt.levels)
+			}
+		})
+	}
+}
+
+func TestParseSampling(t *testing.T) {
+	// Six top-level Frames, each with an Update nested two levels deep
+	var blocks []proftest.Block
+	for i := uint64(0); i < 6; i++ {
+		begin := i * 1000
+		blocks = append(blocks,
+			proftest.Block{ID: 3, Begin: begin + 200, End: begin + 300},
+			proftest.Block{ID: 2, Begin: begin + 100, End: begin + 400},
+			proftest.Block{ID: 1, Begin: begin, End: begin + 500},
+		)
+	}
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Physics"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: blocks}},
+	}
+
+	tests := []struct {
+		name         string
+		options      parser.ReadOptions
+		blocks       int
+		factor       int
+		depthLimited int
+		want         []string
+	}{
+		{"all blocks", parser.ReadOptions{}, 18, 0, 0, nil},
+		{"every third record", parser.ReadOptions{SampleBlocks: 3}, 6, 3, 0, []string{"Physics@0", "Physics@0", "Physics@0", "Physics@0", "Physics@0", "Physics@0"}},
+		{"every fourth record", parser.ReadOptions{SampleBlocks: 4}, 5, 4, 0, []string{"Physics@0", "Update@0", "Frame@0", "Physics@0", "Update@0"}},
+		{"sample of one", parser.ReadOptions{SampleBlocks: 1}, 18, 0, 0, nil},
+		{"max block depth", parser.ReadOptions{MaxBlockDepth: 2}, 12, 0, 6, nil},
+		{"top level only", parser.ReadOptions{MaxBlockDepth: 1}, 6, 0, 12, []string{"Frame@0", "Frame@0", "Frame@0", "Frame@0", "Frame@0", "Frame@0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := p.Parse(t, tt.options)
+			if data.TotalBlocksCount != tt.blocks || data.SamplingFactor != tt.factor || data.DepthLimitedBlocksCount != tt.depthLimited {
+				t.Errorf("blocks %d, sampling factor %d, depth-limited %d; want %d, %d, %d",
+					data.TotalBlocksCount, data.SamplingFactor, data.DepthLimitedBlocksCount, tt.blocks, tt.factor, tt.depthLimited)
+			}
+			if tt.want != nil {
+				if got := outline(data, 1); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("outline = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBlockOrdinals(t *testing.T) {
+	// Main runs five Frames, each holding an Update; Worker runs Update four times
+	var main []proftest.Block
+	for i := uint64(0); i < 5; i++ {
+		begin := i * 1000
+		main = append(main,
+			proftest.Block{ID: 2, Begin: begin + 100, End: begin + 100 + 50*(i+1)},
+			proftest.Block{ID: 1, Begin: begin, End: begin + 900},
+		)
+	}
+	var worker []proftest.Block
+	for i := uint64(0); i < 4; i++ {
+		worker = append(worker, proftest.Block{ID: 2, Begin: i * 100, End: i*100 + 10*(i+1)})
+	}
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: main},
+			{ID: 2, Name: "Worker", Blocks: worker},
+		},
+	}
+
+	// keys maps each block's key to its span
+	keys := func(t *testing.T, data *parser.ProfileData) map[string]string {
+		t.Helper()
+		result := make(map[string]string)
+		for _, threadID := range data.ThreadIDs() {
+			thread := data.Thread(threadID)
+			parser.WalkBlocks(thread.Blocks, parser.DefaultMaxTreeDepth, func(block *parser.Block, _ int) {
+				key := block.Key(threadID)
+				if _, seen := result[key]; seen {
+					t.Errorf("key %s used twice", key)
+				}
+				result[key] = fmt.Sprintf("%d-%d", block.Begin, block.End)
+			})
+		}
+		return result
+	}
+
+	full := keys(t, p.Parse(t, parser.ReadOptions{}))
+	want := map[string]string{
+		"1/1/0": "0-900", "1/1/4": "4000-4900",
+		"1/2/0": "100-150", "1/2/4": "4100-4350",
+		"2/2/0": "0-10", "2/2/3": "300-340",
+	}
+	if len(full) != 14 {
+		t.Errorf("full load has %d keys, want 14", len(full))
+	}
+	for key, span := range want {
+		if full[key] != span {
+			t.Errorf("block %s spans %q, want %q", key, full[key], span)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		options parser.ReadOptions
+		blocks  int
+	}{
+		{"every third record", parser.ReadOptions{SampleBlocks: 3}, 6},
+		{"every second record", parser.ReadOptions{SampleBlocks: 2}, 7},
+		{"min duration", parser.ReadOptions{MinBlockDuration: 100 * time.Nanosecond}, 9},
+		{"top level only", parser.ReadOptions{MaxBlockDepth: 1}, 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			partial := keys(t, p.Parse(t, tt.options))
+			if len(partial) != tt.blocks {
+				t.Errorf("%d blocks, want %d", len(partial), tt.blocks)
+			}
+			// Every block kept has the key of the same block in the full load
+			for key, span := range partial {
+				if full[key] != span {
+					t.Errorf("block %s spans %s, but %q in the full load", key, span, full[key])
+				}
+			}
+		})
+	}
+}
+
+// headerSize is the size of a v2.1 file header, after which descriptors start
+const headerSize = 72
+
+// withDescriptorRecord returns the capture with its first descriptor record replaced
+// by one for ID 1 holding the given name field and the raw bytes that follow it
+func withDescriptorRecord(p *proftest.Profile, name string, nameLength int, rest []byte) []byte {
+	data := p.Bytes()
+	oldSize := int(binary.LittleEndian.Uint16(data[headerSize:]))
+
+	record := binary.LittleEndian.AppendUint32(nil, 1) // ID
+	record = binary.LittleEndian.AppendUint32(record, 10)
+	record = binary.LittleEndian.AppendUint32(record, 0xff00ff00)
+	record = append(record, byte(parser.BlockTypeBlock), 1)
+	record = binary.LittleEndian.AppendUint16(record, uint16(nameLength))
+	record = append(record, name...)
+	record = append(record, rest...)
+
+	result := append([]byte(nil), data[:headerSize]...)
+	result = binary.LittleEndian.AppendUint16(result, uint16(len(record)))
+	result = append(result, record...)
+	return append(result, data[headerSize+2+oldSize:]...)
+}
+
+func TestParseDescriptorRecords(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 100}}}},
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantName string
+		wantFile string
+		wantErr  string
+	}{
+		{"written by proftest", p.Bytes(), "Frame", "Frame.cpp", ""},
+		{"standard layout", withDescriptorRecord(p, "Frame\x00", 6, []byte("frame.cpp\x00")), "Frame", "frame.cpp", ""},
+		{"fields after the file", withDescriptorRecord(p, "Frame\x00", 6, []byte("frame.cpp\x00\x01\x02\x03\x04")), "Frame", "frame.cpp", ""},
+		{"unterminated file", withDescriptorRecord(p, "Frame\x00", 6, []byte("frame.cpp")), "Frame", "frame.cpp", ""},
+		{"unterminated name", withDescriptorRecord(p, "Frame", 5, []byte("frame.cpp\x00")), "Frame", "frame.cpp", ""},
+		{"no file", withDescriptorRecord(p, "Frame\x00", 6, nil), "Frame", "", ""},
+		{"name overflows the record", withDescriptorRecord(p, "Frame\x00", 60, nil), "", "", "overflows"},
+		{"record shorter than fixed fields", func() []byte {
+			data := withDescriptorRecord(p, "", 0, nil)
+			binary.LittleEndian.PutUint16(data[headerSize:], 4)
+			return data
+		}(), "", "", "shorter than its fixed fields"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "capture.prof")
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			data, err := proftest.ParseFile(path, parser.DefaultReadOptions())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Parse error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			descriptor := data.Descriptors[1]
+			if descriptor == nil || descriptor.Name != tt.wantName || descriptor.File != tt.wantFile {
+				t.Fatalf("descriptor = %+v, want %s in %q", descriptor, tt.wantName, tt.wantFile)
+			}
+			// The records after the descriptor are still read in step
+			if data.TotalBlocksCount != 1 || data.Threads[1].ThreadName != "Main" {
+				t.Errorf("read %d blocks on thread %q, want 1 on Main", data.TotalBlocksCount, data.Threads[1].ThreadName)
+			}
+		})
+	}
+}
+
+func TestParseThreadSelection(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Run"),
+		Bookmarks:   []proftest.Bookmark{{Position: 50, Text: "end"}},
+	}
+	for i, name := range []string{"Main", "Render", "Worker 1", "Worker 2", "RenderWorker"} {
+		id := uint64(i + 1)
+		p.Threads = append(p.Threads, proftest.Thread{
+			ID:              id,
+			Name:            name,
+			ContextSwitches: []proftest.ContextSwitch{{ThreadID: id, Begin: 10, End: 20, Name: "game.exe"}},
+			Blocks:          []proftest.Block{{ID: 1, Begin: 0, End: 100}, {ID: 1, Begin: 200, End: 300}},
+		})
+	}
+
+	tests := []struct {
+		name    string
+		options parser.ReadOptions
+		want    []string
+		wantErr string
+	}{
+		{"all", parser.ReadOptions{}, []string{"Main", "Render", "Worker 1", "Worker 2", "RenderWorker"}, ""},
+		{"substring", parser.ReadOptions{ThreadNameFilter: "Render"}, []string{"Render", "RenderWorker"}, ""},
+		{"regular expression", parser.ReadOptions{ThreadNameFilter: "^Worker [0-9]$"}, []string{"Worker 1", "Worker 2"}, ""},
+		{"max threads", parser.ReadOptions{MaxThreads: 2}, []string{"Main", "Render"}, ""},
+		{"rejected threads don't count", parser.ReadOptions{ThreadNameFilter: "Worker", MaxThreads: 2}, []string{"Worker 1", "Worker 2"}, ""},
+		{"no match", parser.ReadOptions{ThreadNameFilter: "Audio"}, nil, ""},
+		{"invalid expression", parser.ReadOptions{ThreadNameFilter: "Render("}, nil, "invalid thread name filter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := proftest.ParseFile(p.WriteFile(t), tt.options)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Parse error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			var got []string
+			for id := uint64(1); id <= uint64(len(p.Threads)); id++ {
+				if thread := data.Threads[id]; thread != nil {
+					got = append(got, thread.ThreadName)
+					if len(thread.Blocks) != 2 || len(thread.ContextSwitches) != 1 {
+						t.Errorf("thread %s has %d blocks and %d context switches, want 2 and 1", thread.ThreadName, len(thread.Blocks), len(thread.ContextSwitches))
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("threads = %q, want %q", got, tt.want)
+			}
+			if want := len(p.Threads) - len(tt.want); data.SkippedThreadsCount != want {
+				t.Errorf("SkippedThreadsCount = %d, want %d", data.SkippedThreadsCount, want)
+			}
+			// Skipped threads leave the stream aligned for the bookmarks
+			if len(data.Bookmarks) != 1 || data.Bookmarks[0].Text != "end" {
+				t.Errorf("bookmarks = %+v, want the end bookmark", data.Bookmarks)
+			}
+		})
+	}
+}
+
+func TestParseUnclosedBlocks(t *testing.T) {
+	tests := []struct {
+		name       string
+		begin, end uint64
+		unclosed   bool
+		wantEnd    uint64
+	}{
+		{"closed", 1100, 1500, false, 1500},
+		{"zero end", 1500, 0, true, 2000},
+		{"all ones end", 1500, math.MaxUint64, true, 2000},
+		{"end far past the capture", 1500, 3500, true, 2000},
+		{"end shortly past the capture", 1500, 2500, false, 2500},
+		{"zero-length at zero", 0, 0, false, 0},
+		{"begins after the capture", 2500, 0, true, 2500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &proftest.Profile{
+				Begin:       1000,
+				End:         2000,
+				Descriptors: proftest.Descriptors("Frame"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: tt.begin, End: tt.end}}}},
+			}
+			data := p.Load(t)
+			block := data.Threads[1].Blocks[0]
+			if block.Unclosed != tt.unclosed || block.End != tt.wantEnd {
+				t.Errorf("unclosed %t, end %d; want %t, %d", block.Unclosed, block.End, tt.unclosed, tt.wantEnd)
+			}
+			want := 0
+			if tt.unclosed {
+				want = 1
+			}
+			if data.UnclosedBlocksCount != want {
+				t.Errorf("unclosed blocks = %d, want %d", data.UnclosedBlocksCount, want)
+			}
+		})
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	p := &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads: []proftest.Thread{
+			{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}},
+			{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 500}}},
+		},
+		Bookmarks: []proftest.Bookmark{{Position: 100, Text: "start"}},
+	}
+
+	tests := []struct {
+		name    string
+		options parser.ReadOptions
+		phases  []string
+	}{
+		{"all phases", parser.ReadOptions{}, []string{
+			"reading header",
+			"reading descriptors",
+			"reading thread 1/2",
+			"building tree for thread 1/2",
+			"reading thread 2/2",
+			"building tree for thread 2/2",
+			"reading bookmarks",
+			"done",
+		}},
+		{"skipped bookmarks", parser.ReadOptions{SkipBookmarks: true}, []string{
+			"reading header",
+			"reading descriptors",
+			"reading thread 1/2",
+			"building tree for thread 1/2",
+			"reading thread 2/2",
+			"building tree for thread 2/2",
+			"done",
+		}},
+		{"filtered thread", parser.ReadOptions{ThreadNameFilter: "Worker"}, []string{
+			"reading header",
+			"reading descriptors",
+			"reading thread 1/2",
+			"reading thread 2/2",
+			"building tree for thread 2/2",
+			"reading bookmarks",
+			"done",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var phases []string
+			var percents []int
+			tt.options.PhaseCallback = func(phase string, percent int) {
+				phases = append(phases, phase)
+				percents = append(percents, percent)
+			}
+			p.Parse(t, tt.options)
+
+			if !reflect.DeepEqual(phases, tt.phases) {
+				t.Errorf("phases = %q, want %q", phases, tt.phases)
+			}
+			for i := 1; i < len(percents); i++ {
+				if percents[i] < percents[i-1] {
+					t.Fatalf("percent went back: %v", percents)
+				}
+			}
+			if percents[len(percents)-1] != 100 {
+				t.Errorf("last percent = %d, want 100", percents[len(percents)-1])
+			}
+		})
+	}
+}
+
+func TestParseProgressStep(t *testing.T) {
+	var blocks []proftest.Block
+	for i := uint64(0); i < 20; i++ {
+		blocks = append(blocks, proftest.Block{ID: 1, Begin: i * 10, End: i*10 + 5})
+	}
+	p := &proftest.Profile{Descriptors: proftest.Descriptors("Frame")}
+	for id := uint64(1); id <= 8; id++ {
+		p.Threads = append(p.Threads, proftest.Thread{ID: id, Name: fmt.Sprintf("T%d", id), Blocks: blocks})
+	}
+
+	tests := []struct {
+		name string
+		step int
+	}{
+		{"default step", 0},
+		{"step of 1", 1},
+		{"step of 20", 20},
+		{"step past 100", 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var percents []int
+			p.Parse(t, parser.ReadOptions{ProgressStep: tt.step, ProgressCallback: func(percent int) {
+				percents = append(percents, percent)
+			}})
+
+			if len(percents) < 2 || percents[len(percents)-1] != 100 {
+				t.Fatalf("percents = %v, want at least a first call and a final 100", percents)
+			}
+			step := max(1, tt.step)
+			for i := 1; i < len(percents)-1; i++ {
+				if percents[i]-percents[i-1] < step {
+					t.Fatalf("percents = %v advance by less than %d", percents, step)
+				}
+			}
+			if last := len(percents) - 1; percents[last] <= percents[last-1] {
+				t.Errorf("percents = %v, want the final 100 reported once", percents)
+			}
+		})
+	}
+}
+
+func TestParseMissingEndSignature(t *testing.T) {
+	// capture has two threads and a bookmark, which follows the end signature
+	capture := func(noEndSignature bool) *proftest.Profile {
+		return &proftest.Profile{
+			Descriptors: proftest.Descriptors("Frame"),
+			Threads: []proftest.Thread{
+				{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 100}}},
+				{ID: 2, Name: "Worker", Blocks: []proftest.Block{{ID: 1, Begin: 50, End: 80}}},
+			},
+			Bookmarks:      []proftest.Bookmark{{Position: 60, Text: "spike"}},
+			NoEndSignature: noEndSignature,
+		}
+	}
+	signature := binary.LittleEndian.AppendUint32(nil, parser.EasyProfilerSignature)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		missing   bool
+		bookmarks int
+		wantErr   string
+	}{
+		{"signature present", capture(false).Bytes(), false, 1, ""},
+		{"signature missing", capture(true).Bytes(), true, 0, ""},
+		{"signature cut off", append(capture(true).Bytes(), signature[:2]...), true, 0, ""},
+		{"wrong signature", append(capture(true).Bytes(), 0xEF, 0xBE, 0xAD, 0xDE), false, 0, "invalid end signature"},
+		{"declared thread missing", func() []byte {
+			data := capture(true).Bytes()
+			binary.LittleEndian.PutUint32(data[64:], 3) // ThreadsCount
+			return data
+		}(), false, 0, "failed to read end signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "capture.prof")
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			data, err := proftest.ParseFile(path, parser.DefaultReadOptions())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Parse error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			if data.MissingEndSignature != tt.missing || len(data.Bookmarks) != tt.bookmarks {
+				t.Errorf("missing end signature %t with %d bookmarks, want %t with %d",
+					data.MissingEndSignature, len(data.Bookmarks), tt.missing, tt.bookmarks)
+			}
+			if len(data.Threads) != 2 || data.TotalBlocksCount != 2 {
+				t.Errorf("read %d threads and %d blocks, want 2 and 2", len(data.Threads), data.TotalBlocksCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LatestKnownVersion is the newest format version whose header layout is known
+const LatestKnownVersion = Version210
+
+// reservedField is a header field without meaning in the versions that carry it,
+// written as zero by the profiler
+type reservedField struct {
+	name  string
+	since uint32 // First version carrying the field
+	value func(header *FileHeader) uint64
+}
+
+// reservedFields lists the reserved header fields by the version introducing them
+var reservedFields = []reservedField{
+	{name: "padding", since: Version210, value: func(header *FileHeader) uint64 { return uint64(header.Padding) }},
+}
+
+// checkReservedFields looks for signs that the header layout differs from the one
+// read: reserved fields holding non-zero values, or a version newer than any known.
+// Either way later offsets may be off. The findings become HeaderWarnings, or an
+// error under ReadOptions.StrictReservedFields.
+func (r *Reader) checkReservedFields() error {
+	header := &r.data.Header
+
+	var warnings []string
+	for _, field := range reservedFields {
+		if header.Version < field.since {
+			continue
+		}
+		if value := field.value(header); value != 0 {
+			warnings = append(warnings, fmt.Sprintf("reserved header field '%s' holds 0x%X instead of 0; the file may use a header layout other than v%s's",
+				field.name, value, header.VersionString()))
+		}
+	}
+	if header.Version > LatestKnownVersion {
+		warnings = append(warnings, fmt.Sprintf("format version %s is newer than the newest known (%s); fields it added may be misread",
+			header.VersionString(), FormatVersion(LatestKnownVersion)))
+	}
+
+	if len(warnings) > 0 && r.options.StrictReservedFields {
+		return fmt.Errorf("unexpected header layout: %s", strings.Join(warnings, "; "))
+	}
+	r.data.HeaderWarnings = warnings
+	return nil
+}
@@ -0,0 +1,144 @@
+package parser_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+func TestParseReservedHeaderFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  uint32
+		padding  uint16
+		strict   bool
+		warnings []string // Substrings of each warning, in order
+		wantErr  string
+	}{
+		{name: "zero padding"},
+		{name: "zero padding, strict", strict: true},
+		{
+			name:     "non-zero padding",
+			padding:  0xBEEF,
+			warnings: []string{"reserved header field 'padding' holds 0xBEEF instead of 0; the file may use a header layout other than v2.1.0's"},
+		},
+		{
+			name:     "newer version",
+			version:  0x02020000,
+			warnings: []string{"format version 2.2.0 is newer than the newest known (2.1.0)"},
+		},
+		{
+			name:     "both",
+			version:  0x02020000,
+			padding:  1,
+			warnings: []string{"'padding' holds 0x1", "newer than the newest known"},
+		},
+		{name: "non-zero padding, strict", padding: 0xBEEF, strict: true, wantErr: "unexpected header layout: reserved header field 'padding' holds 0xBEEF"},
+		{name: "newer version, strict", version: 0x02020000, strict: true, wantErr: "unexpected header layout: format version 2.2.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &proftest.Profile{
+				Version:     tt.version,
+				Padding:     tt.padding,
+				Descriptors: proftest.Descriptors("Frame"),
+				Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}}}},
+			}
+			options := parser.DefaultReadOptions()
+			options.StrictReservedFields = tt.strict
+
+			data, err := proftest.ParseFile(p.WriteFile(t), options)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			if len(data.HeaderWarnings) != len(tt.warnings) {
+				t.Fatalf("warnings = %q, want %d", data.HeaderWarnings, len(tt.warnings))
+			}
+			for i, want := range tt.warnings {
+				if !strings.Contains(data.HeaderWarnings[i], want) {
+					t.Errorf("warning %d = %q, want it to contain %q", i, data.HeaderWarnings[i], want)
+				}
+			}
+			// The padding doesn't shift the records after it
+			if data.GetBlocksCount() != 1 || data.Header.Padding != tt.padding {
+				t.Errorf("read %d blocks with padding 0x%X", data.GetBlocksCount(), data.Header.Padding)
+			}
+		})
+	}
+}
+
+func TestPeekReportsHeaderWarnings(t *testing.T) {
+	p := &proftest.Profile{
+		Padding:     0x10,
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}}}},
+	}
+	reader, err := parser.NewReader(p.WriteFile(t))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer reader.Close()
+
+	report, err := reader.Peek(1)
+	if err != nil {
+		t.Fatalf("Peek: %v", err)
+	}
+	if len(report.HeaderWarnings) != 1 || report.Error != "" || len(report.Threads) != 1 {
+		t.Errorf("warnings %q, error %q, %d threads; want one warning and the thread", report.HeaderWarnings, report.Error, len(report.Threads))
+	}
+}
+
+func TestCacheHonorsStrictReservedFields(t *testing.T) {
+	path := (&proftest.Profile{
+		Padding:     0x10,
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 10}}}},
+	}).WriteFile(t)
+	cache := newTestCache(t)
+
+	lenient := parser.DefaultReadOptions()
+	data, err := proftest.ParseFile(path, lenient)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if err := cache.Store(path, lenient, data); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		strict bool
+		hit    bool
+	}{
+		{"lenient load hits", false, true},
+		{"strict load misses", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := parser.DefaultReadOptions()
+			options.StrictReservedFields = tt.strict
+			cached, hit, err := cache.Load(path, options)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if hit != tt.hit {
+				t.Fatalf("hit = %t, want %t", hit, tt.hit)
+			}
+			if hit && !reflect.DeepEqual(cached.HeaderWarnings, data.HeaderWarnings) {
+				t.Errorf("cached warnings = %q, want %q", cached.HeaderWarnings, data.HeaderWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package parser
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// InvalidUTF8Marker replaces invalid UTF-8 sequences in names read from a capture
+const InvalidUTF8Marker = "�"
+
+// sanitizeName converts raw name bytes to a valid UTF-8 string, replacing each run
+// of invalid bytes with InvalidUTF8Marker. It reports whether a replacement was made.
+func sanitizeName(raw []byte) (string, bool) {
+	if utf8.Valid(raw) {
+		return string(raw), false
+	}
+	return strings.ToValidUTF8(string(raw), InvalidUTF8Marker), true
+}
@@ -0,0 +1,28 @@
+package parser
+
+import "testing"
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           []byte
+		want          string
+		wantSanitized bool
+	}{
+		{"ascii", []byte("Update"), "Update", false},
+		{"multibyte", []byte("Обновление"), "Обновление", false},
+		{"empty", nil, "", false},
+		{"invalid byte", []byte("Up\xffdate"), "Up" + InvalidUTF8Marker + "date", true},
+		{"invalid run becomes one marker", []byte("a\xff\xfe\xfdb"), "a" + InvalidUTF8Marker + "b", true},
+		{"truncated sequence", []byte("ab\xd0"), "ab" + InvalidUTF8Marker, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, sanitized := sanitizeName(tt.raw)
+			if got != tt.want || sanitized != tt.wantSanitized {
+				t.Errorf("sanitizeName(%q) = %q, %t; want %q, %t", tt.raw, got, sanitized, tt.want, tt.wantSanitized)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// retainedBlock is a block kept by ReadOptions.RetainSlowest with its thread
+type retainedBlock struct {
+	block    *Block
+	threadID uint64
+	order    int // Position in the stream, breaking duration ties in favor of earlier blocks
+}
+
+// slowestHeap is a min-heap of retained blocks: its root is the fastest one, which is
+// evicted first when a slower block arrives
+type slowestHeap []retainedBlock
+
+func (h slowestHeap) Len() int { return len(h) }
+
+func (h slowestHeap) Less(i, j int) bool {
+	di, dj := h[i].block.Duration(), h[j].block.Duration()
+	if di != dj {
+		return di < dj
+	}
+	return h[i].order > h[j].order
+}
+
+func (h slowestHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *slowestHeap) Push(x any) { *h = append(*h, x.(retainedBlock)) }
+
+func (h *slowestHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// retainSlowest offers a block to the bounded heap of the slowest blocks seen so far.
+// Memory stays proportional to RetainSlowest however many blocks the file holds.
+func (r *Reader) retainSlowest(block *Block, threadID uint64) {
+	candidate := retainedBlock{block: block, threadID: threadID, order: r.retainedOrder}
+	r.retainedOrder++
+
+	if r.slowest.Len() < r.options.RetainSlowest {
+		heap.Push(&r.slowest, candidate)
+		return
+	}
+	if block.Duration() <= r.slowest[0].block.Duration() {
+		return
+	}
+	r.slowest[0] = candidate
+	heap.Fix(&r.slowest, 0)
+}
+
+// placeRetained hands the retained blocks to their threads as childless top-level
+// blocks in time order
+func (r *Reader) placeRetained() {
+	for _, retained := range r.slowest {
+		retained.block.Children = nil
+		if thread, ok := r.data.Threads[retained.threadID]; ok {
+			thread.Blocks = append(thread.Blocks, retained.block)
+		}
+	}
+	r.slowest = nil
+
+	for _, thread := range r.data.Threads {
+		sort.Slice(thread.Blocks, func(i, j int) bool {
+			return thread.Blocks[i].Begin < thread.Blocks[j].Begin
+		})
+		thread.updateActivitySpan()
+	}
+}
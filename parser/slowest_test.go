@@ -0,0 +1,129 @@
+package parser_test
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// slowestStream is a capture of 6000 blocks: three threads of 1000 Frames, each
+// holding an Update. Every block has a distinct duration, so the slowest K are
+// unambiguous.
+func slowestStream() *proftest.Profile {
+	p := &proftest.Profile{Descriptors: proftest.Descriptors("Frame", "Update")}
+	for id := uint64(1); id <= 3; id++ {
+		thread := proftest.Thread{ID: id, Name: fmt.Sprintf("T%d", id)}
+		for i := uint64(0); i < 1000; i++ {
+			spread := (i*7919)%1000*3 + id - 1 // A permutation of 0-2999 across threads
+			begin := i * 10000
+			thread.Blocks = append(thread.Blocks,
+				proftest.Block{ID: 2, Begin: begin + 100, End: begin + 100 + 1000 + spread},
+				proftest.Block{ID: 1, Begin: begin, End: begin + 5000 + spread},
+			)
+		}
+		p.Threads = append(p.Threads, thread)
+	}
+	return p
+}
+
+// blockKeys returns "thread:begin-end" for each block, sorted
+func blockKeys(blocks map[uint64][]*parser.Block) []string {
+	var keys []string
+	for threadID, list := range blocks {
+		for _, block := range list {
+			keys = append(keys, fmt.Sprintf("%d:%d-%d", threadID, block.Begin, block.End))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestParseRetainSlowest(t *testing.T) {
+	p := slowestStream()
+
+	// The full parse's slowest K, from every block of every tree
+	type threadBlock struct {
+		threadID uint64
+		block    *parser.Block
+	}
+	var all []threadBlock
+	for threadID, thread := range p.Load(t).Threads {
+		if err := parser.WalkBlocks(thread.Blocks, parser.DefaultMaxTreeDepth, func(block *parser.Block, _ int) {
+			all = append(all, threadBlock{threadID, block})
+		}); err != nil {
+			t.Fatalf("WalkBlocks: %v", err)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].block.Duration() > all[j].block.Duration() })
+
+	for _, k := range []int{1, 10, 500, 2500, 6000, 10000} {
+		t.Run(fmt.Sprint(k), func(t *testing.T) {
+			full := make(map[uint64][]*parser.Block)
+			for _, entry := range all[:min(k, len(all))] {
+				full[entry.threadID] = append(full[entry.threadID], entry.block)
+			}
+
+			data := p.Parse(t, parser.ReadOptions{RetainSlowest: k})
+			if data.RetainedSlowest != k {
+				t.Errorf("RetainedSlowest = %d, want %d", data.RetainedSlowest, k)
+			}
+			retained := make(map[uint64][]*parser.Block)
+			for threadID, thread := range data.Threads {
+				retained[threadID] = thread.Blocks
+				if !sort.SliceIsSorted(thread.Blocks, func(i, j int) bool { return thread.Blocks[i].Begin < thread.Blocks[j].Begin }) {
+					t.Errorf("thread %d blocks aren't in time order", threadID)
+				}
+				for _, block := range thread.Blocks {
+					if len(block.Children) != 0 {
+						t.Errorf("retained block %d-%d has children", block.Begin, block.End)
+					}
+				}
+			}
+
+			if got, want := blockKeys(retained), blockKeys(full); !reflect.DeepEqual(got, want) {
+				t.Errorf("retained %d blocks differing from the full parse's slowest %d", len(got), len(want))
+			}
+			if report := data.CheckIntegrity(); !slices.Contains(report.SkipReasons, "slowest blocks only") {
+				t.Errorf("skip reasons %v don't mention the retained slowest blocks", report.SkipReasons)
+			}
+		})
+	}
+}
+
+func TestParseRetainSlowestTiesAndUnclosed(t *testing.T) {
+	// Three 10ns blocks tie; an unclosed block would be the slowest
+	p := &proftest.Profile{
+		Begin:       0,
+		End:         1000,
+		Descriptors: proftest.Descriptors("Frame", "Hang"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 1, Begin: 0, End: 10},
+			{ID: 1, Begin: 20, End: 30},
+			{ID: 2, Begin: 35, End: 0},
+			{ID: 1, Begin: 40, End: 50},
+		}}},
+	}
+
+	tests := []struct {
+		k    int
+		want []string
+	}{
+		{1, []string{"1:0-10"}},
+		{2, []string{"1:0-10", "1:20-30"}},
+		{5, []string{"1:0-10", "1:20-30", "1:40-50"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.k), func(t *testing.T) {
+			data := p.Parse(t, parser.ReadOptions{RetainSlowest: tt.k})
+			if got := blockKeys(map[uint64][]*parser.Block{1: data.Threads[1].Blocks}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("retained %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
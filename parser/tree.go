@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+)
+
+// buildBlockTree nests a thread's flat block list by interval containment and
+// returns the top-level blocks. EasyProfiler writes blocks in the order they
+// close, so children precede their parents in the stream; sorting by begin time
+// (longest first on ties) lets a single stack pass rebuild the hierarchy.
+// Blocks that overlap without nesting are kept as siblings. Blocks nested
+// keepDepth or more levels deep are dropped with their subtrees, and counted in
+// the returned number (keepDepth 0 keeps all). Nesting deeper than maxDepth is
+// rejected with ErrTreeTooDeep.
+func buildBlockTree(blocks []*Block, maxDepth, keepDepth int) ([]*Block, int, error) {
+	sorted := make([]*Block, len(blocks))
+	copy(sorted, blocks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Begin != sorted[j].Begin {
+			return sorted[i].Begin < sorted[j].Begin
+		}
+		return sorted[i].End > sorted[j].End
+	})
+
+	roots := make([]*Block, 0)
+	dropped := 0
+	var stack []*Block
+	for _, block := range sorted {
+		for len(stack) > 0 && !stack[len(stack)-1].contains(block) {
+			stack = stack[:len(stack)-1]
+		}
+
+		// Not pushed, so its descendants land at the same depth and are dropped too
+		if keepDepth > 0 && len(stack) >= keepDepth {
+			dropped++
+			continue
+		}
+		if len(stack) > maxDepth {
+			return nil, 0, fmt.Errorf("%w (%d)", ErrTreeTooDeep, maxDepth)
+		}
+
+		block.Depth = uint16(len(stack))
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, block)
+		} else {
+			roots = append(roots, block)
+		}
+		stack = append(stack, block)
+	}
+
+	return roots, dropped, nil
+}
+
+// contains reports whether other lies entirely within b's interval
+func (b *Block) contains(other *Block) bool {
+	return other.Begin >= b.Begin && other.End <= b.End
+}
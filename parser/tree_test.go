@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// treeShape renders a block tree as "id[depth](children...)" for comparison
+func treeShape(blocks []*Block) []string {
+	shape := make([]string, len(blocks))
+	for i, block := range blocks {
+		shape[i] = shapeOf(block)
+	}
+	return shape
+}
+
+func shapeOf(block *Block) string {
+	s := string(rune('A'+block.ID-1)) + string(rune('0'+block.Depth))
+	if len(block.Children) > 0 {
+		s += "("
+		for i, child := range block.Children {
+			if i > 0 {
+				s += " "
+			}
+			s += shapeOf(child)
+		}
+		s += ")"
+	}
+	return s
+}
+
+func TestBuildBlockTree(t *testing.T) {
+	tests := []struct {
+		name      string
+		blocks    []*Block
+		keepDepth int
+		want      []string
+		dropped   int
+	}{
+		{
+			name: "children written before their parents",
+			blocks: []*Block{
+				{ID: 2, Begin: 10, End: 20},
+				{ID: 3, Begin: 30, End: 40},
+				{ID: 1, Begin: 0, End: 100},
+			},
+			want: []string{"A0(B1 C1)"},
+		},
+		{
+			name: "equal begins put the longer block outside",
+			blocks: []*Block{
+				{ID: 2, Begin: 0, End: 50},
+				{ID: 1, Begin: 0, End: 100},
+			},
+			want: []string{"A0(B1)"},
+		},
+		{
+			name: "overlapping blocks stay siblings",
+			blocks: []*Block{
+				{ID: 1, Begin: 0, End: 60},
+				{ID: 2, Begin: 50, End: 100},
+			},
+			want: []string{"A0", "B0"},
+		},
+		{
+			name: "deep nesting",
+			blocks: []*Block{
+				{ID: 3, Begin: 20, End: 30},
+				{ID: 2, Begin: 10, End: 40},
+				{ID: 1, Begin: 0, End: 50},
+				{ID: 4, Begin: 60, End: 70},
+			},
+			want: []string{"A0(B1(C2))", "D0"},
+		},
+		{
+			name: "keep depth drops deeper subtrees",
+			blocks: []*Block{
+				{ID: 3, Begin: 20, End: 30},
+				{ID: 2, Begin: 10, End: 40},
+				{ID: 1, Begin: 0, End: 50},
+				{ID: 4, Begin: 12, End: 15},
+			},
+			keepDepth: 1,
+			want:      []string{"A0"},
+			dropped:   3,
+		},
+		{
+			name:   "empty thread",
+			blocks: nil,
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, dropped, err := buildBlockTree(tt.blocks, DefaultMaxTreeDepth, tt.keepDepth)
+			if err != nil {
+				t.Fatalf("buildBlockTree: %v", err)
+			}
+			if got := treeShape(roots); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tree = %v, want %v", got, tt.want)
+			}
+			if dropped != tt.dropped {
+				t.Errorf("dropped = %d, want %d", dropped, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestBuildBlockTreeRejectsDeepNesting(t *testing.T) {
+	blocks := []*Block{
+		{ID: 1, Begin: 0, End: 100},
+		{ID: 2, Begin: 10, End: 90},
+		{ID: 3, Begin: 20, End: 80},
+	}
+
+	tests := []struct {
+		maxDepth int
+		wantErr  bool
+	}{
+		{maxDepth: 0, wantErr: true},
+		{maxDepth: 1, wantErr: true},
+		{maxDepth: 2, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		fresh := make([]*Block, len(blocks))
+		for i, block := range blocks {
+			copied := *block
+			fresh[i] = &copied
+		}
+		_, _, err := buildBlockTree(fresh, tt.maxDepth, 0)
+		if got := errors.Is(err, ErrTreeTooDeep); got != tt.wantErr {
+			t.Errorf("maxDepth %d: err = %v, want ErrTreeTooDeep %t", tt.maxDepth, err, tt.wantErr)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package parser
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 const (
 	EasyProfilerSignature = 0x45617379 // "Easy" in ASCII
@@ -20,6 +23,20 @@ const (
 	BlockTypeValue BlockType = 2
 )
 
+// String returns the lowercase name of the block type
+func (t BlockType) String() string {
+	switch t {
+	case BlockTypeEvent:
+		return "event"
+	case BlockTypeBlock:
+		return "block"
+	case BlockTypeValue:
+		return "value"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(t))
+	}
+}
+
 // FileHeader represents the header of a .prof file
 type FileHeader struct {
 	Signature              uint32
@@ -37,6 +54,17 @@ type FileHeader struct {
 	Padding                uint16
 }
 
+// FormatVersion decodes a packed EasyProfiler version (major<<24 | minor<<16 | patch)
+// into "major.minor.patch", e.g. 0x02010000 becomes "2.1.0"
+func FormatVersion(version uint32) string {
+	return fmt.Sprintf("%d.%d.%d", version>>24, (version>>16)&0xFF, version&0xFFFF)
+}
+
+// VersionString returns the header's format version as "major.minor.patch"
+func (h *FileHeader) VersionString() string {
+	return FormatVersion(h.Version)
+}
+
 // BlockDescriptor describes a profiler block type
 type BlockDescriptor struct {
 	ID     uint32
@@ -46,6 +74,9 @@ type BlockDescriptor struct {
 	Status uint8
 	Name   string
 	File   string
+
+	// NameSanitized is set when Name or File contained invalid UTF-8
+	NameSanitized bool
 }
 
 // Block represents a profiler block (timing event)
@@ -53,8 +84,15 @@ type Block struct {
 	Begin    uint64 // Timestamp in nanoseconds
 	End      uint64 // Timestamp in nanoseconds
 	ID       uint32 // Reference to BlockDescriptor
+	Depth    uint16 // Nesting level, 0 for top-level blocks (fits in ID's padding)
+	Ordinal  uint32 // Occurrence of the descriptor ID among the thread's block records; see Key
 	Name     string // Runtime name (if any)
+	Value    *Value // Decoded payload for value blocks (nil otherwise)
 	Children []*Block
+
+	// Unclosed is set when the capture ended before the block did, leaving its End
+	// zero or implausible. End is then clamped to the capture end.
+	Unclosed bool
 }
 
 // Duration returns the duration of the block
@@ -62,6 +100,27 @@ func (b *Block) Duration() time.Duration {
 	return time.Duration(b.End - b.Begin)
 }
 
+// Key returns a stable identity of a block on the given thread, "thread/descriptor/ordinal".
+// Ordinals are counted in file order over every block record of the thread before
+// sampling or filtering drop any, so the same block has the same key in full,
+// sampled and filtered loads of one file, and in sub-profiles cut from them.
+func (b *Block) Key(threadID uint64) string {
+	return BlockKey(threadID, b.ID, b.Ordinal)
+}
+
+// BlockKey formats the stable identity of the ordinal-th block of a descriptor on a thread
+func BlockKey(threadID uint64, descriptorID, ordinal uint32) string {
+	return fmt.Sprintf("%d/%d/%d", threadID, descriptorID, ordinal)
+}
+
+// ParseBlockKey splits a key returned by Block.Key into its parts
+func ParseBlockKey(key string) (threadID uint64, descriptorID, ordinal uint32, err error) {
+	if _, err := fmt.Sscanf(key, "%d/%d/%d", &threadID, &descriptorID, &ordinal); err != nil || BlockKey(threadID, descriptorID, ordinal) != key {
+		return 0, 0, 0, fmt.Errorf("invalid block key '%s' (expected thread/descriptor/ordinal, e.g. 1234/5/67)", key)
+	}
+	return threadID, descriptorID, ordinal, nil
+}
+
 // ContextSwitch represents a context switch event
 type ContextSwitch struct {
 	Begin    uint64
@@ -81,6 +140,27 @@ type ThreadData struct {
 	ThreadName      string
 	ContextSwitches []*ContextSwitch
 	Blocks          []*Block
+
+	// FirstBlockBegin and LastBlockEnd bound the thread's instrumented activity
+	// (both 0 if the thread has no blocks)
+	FirstBlockBegin uint64
+	LastBlockEnd    uint64
+
+	// NameSanitized is set when ThreadName contained invalid UTF-8
+	NameSanitized bool
+}
+
+// updateActivitySpan recomputes FirstBlockBegin and LastBlockEnd from the top-level blocks
+func (t *ThreadData) updateActivitySpan() {
+	t.FirstBlockBegin, t.LastBlockEnd = 0, 0
+	for i, block := range t.Blocks {
+		if i == 0 || block.Begin < t.FirstBlockBegin {
+			t.FirstBlockBegin = block.Begin
+		}
+		if block.End > t.LastBlockEnd {
+			t.LastBlockEnd = block.End
+		}
+	}
 }
 
 // Bookmark represents a user-defined bookmark
@@ -100,6 +180,47 @@ type ProfileData struct {
 	// Memory statistics
 	TotalBlocksCount int
 	MemoryUsedBytes  int64
+
+	// SanitizedNamesCount is the number of names that contained invalid UTF-8
+	SanitizedNamesCount int
+
+	// DroppedBlocksCount is the number of blocks discarded by ReadOptions.MinBlockDuration
+	DroppedBlocksCount int
+
+	// DepthLimitedBlocksCount is the number of blocks discarded by ReadOptions.MaxBlockDepth
+	DepthLimitedBlocksCount int
+
+	// UnclosedBlocksCount is the number of blocks marked Unclosed
+	UnclosedBlocksCount int
+
+	// BlockRecordsCount and DescriptorRecordsCount are the numbers of records the file
+	// contained, including ones skipped by the read options; see CheckIntegrity
+	BlockRecordsCount      int
+	DescriptorRecordsCount int
+
+	// SkippedThreadsCount is the number of threads not loaded because of
+	// ReadOptions.ThreadNameFilter or ReadOptions.MaxThreads
+	SkippedThreadsCount int
+
+	// ContextSwitchesSkipped is set when context switches were not loaded
+	ContextSwitchesSkipped bool
+
+	// SamplingFactor is N when only every Nth block was kept (ReadOptions.SampleBlocks).
+	// 0 or 1 means every block was read.
+	SamplingFactor int
+
+	// RetainedSlowest is N when only the N slowest blocks were kept, as childless
+	// top-level blocks (ReadOptions.RetainSlowest). 0 means the full trees were read.
+	RetainedSlowest int
+
+	// MissingEndSignature is set when the file ended after the last declared thread
+	// without the end signature (and any bookmarks), as when the profiler was stopped
+	// before flushing it. Every thread was still read.
+	MissingEndSignature bool
+
+	// HeaderWarnings describes header fields suggesting a version mismatch, such as
+	// non-zero reserved fields; see ReadOptions.StrictReservedFields
+	HeaderWarnings []string
 }
 
 // NewProfileData creates a new empty ProfileData
@@ -131,10 +252,10 @@ func (p *ProfileData) GetBlocksCount() int {
 }
 
 func countBlocks(blocks []*Block) int {
-	count := len(blocks)
-	for _, block := range blocks {
-		count += countBlocks(block.Children)
-	}
+	count := 0
+	WalkBlocks(blocks, DefaultMaxTreeDepth, func(*Block, int) {
+		count++
+	})
 	return count
 }
 
@@ -149,9 +270,8 @@ func (p *ProfileData) GetAllBlocks() []*Block {
 
 func flattenBlocks(blocks []*Block) []*Block {
 	var result []*Block
-	for _, block := range blocks {
+	WalkBlocks(blocks, DefaultMaxTreeDepth, func(block *Block, _ int) {
 		result = append(result, block)
-		result = append(result, flattenBlocks(block.Children)...)
-	}
+	})
 	return result
 }
@@ -0,0 +1,93 @@
+package parser
+
+import "testing"
+
+func TestBlockTypeString(t *testing.T) {
+	tests := []struct {
+		blockType BlockType
+		want      string
+	}{
+		{BlockTypeEvent, "event"},
+		{BlockTypeBlock, "block"},
+		{BlockTypeValue, "value"},
+		{BlockType(9), "unknown(9)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.blockType.String(); got != tt.want {
+			t.Errorf("BlockType(%d).String() = %q, want %q", uint8(tt.blockType), got, tt.want)
+		}
+	}
+}
+
+func TestFormatVersion(t *testing.T) {
+	tests := []struct {
+		version uint32
+		want    string
+	}{
+		{Version210, "2.1.0"},
+		{Version130, "1.3.0"},
+		{0x01020003, "1.2.3"},
+		{0x0201FFFF, "2.1.65535"},
+		{0, "0.0.0"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatVersion(tt.version); got != tt.want {
+			t.Errorf("FormatVersion(0x%08X) = %q, want %q", tt.version, got, tt.want)
+		}
+		header := FileHeader{Version: tt.version}
+		if got := header.VersionString(); got != tt.want {
+			t.Errorf("VersionString() for 0x%08X = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestBlockKey(t *testing.T) {
+	tests := []struct {
+		threadID     uint64
+		descriptorID uint32
+		ordinal      uint32
+		want         string
+	}{
+		{1, 2, 3, "1/2/3"},
+		{0, 0, 0, "0/0/0"},
+		{1<<64 - 1, 1<<32 - 1, 1<<32 - 1, "18446744073709551615/4294967295/4294967295"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			block := &Block{ID: tt.descriptorID, Ordinal: tt.ordinal}
+			if got := block.Key(tt.threadID); got != tt.want {
+				t.Errorf("Key = %q, want %q", got, tt.want)
+			}
+			threadID, descriptorID, ordinal, err := ParseBlockKey(tt.want)
+			if err != nil || threadID != tt.threadID || descriptorID != tt.descriptorID || ordinal != tt.ordinal {
+				t.Errorf("ParseBlockKey = %d, %d, %d, %v; want %d, %d, %d", threadID, descriptorID, ordinal, err, tt.threadID, tt.descriptorID, tt.ordinal)
+			}
+		})
+	}
+}
+
+func TestParseBlockKeyRejects(t *testing.T) {
+	// Only the exact form Key produces parses, so one block can't have two keys
+	for _, key := range []string{
+		"",
+		"1/2",
+		"1/2/3/4",
+		"1/2/3x",
+		"1/2/3 ",
+		" 1/2/3",
+		"01/2/3",
+		"+1/2/3",
+		"-1/2/3",
+		"1/-2/3",
+		"1/2/4294967296",
+		"a/b/c",
+		"1:2:3",
+	} {
+		if _, _, _, err := ParseBlockKey(key); err == nil {
+			t.Errorf("ParseBlockKey(%q) succeeded", key)
+		}
+	}
+}
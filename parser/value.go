@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// ValueType is the data type of an arbitrary value recorded with EASY_VALUE
+type ValueType uint8
+
+const (
+	ValueTypeBool   ValueType = 0
+	ValueTypeChar   ValueType = 1
+	ValueTypeInt8   ValueType = 2
+	ValueTypeUint8  ValueType = 3
+	ValueTypeInt16  ValueType = 4
+	ValueTypeUint16 ValueType = 5
+	ValueTypeInt32  ValueType = 6
+	ValueTypeUint32 ValueType = 7
+	ValueTypeInt64  ValueType = 8
+	ValueTypeUint64 ValueType = 9
+	ValueTypeFloat  ValueType = 10
+	ValueTypeDouble ValueType = 11
+	ValueTypeString ValueType = 12
+)
+
+// valueHeaderSize is the size of the value payload header: data size, type and array flag
+const valueHeaderSize = 2 + 1 + 1
+
+var valueTypeNames = map[ValueType]string{
+	ValueTypeBool:   "bool",
+	ValueTypeChar:   "char",
+	ValueTypeInt8:   "int8",
+	ValueTypeUint8:  "uint8",
+	ValueTypeInt16:  "int16",
+	ValueTypeUint16: "uint16",
+	ValueTypeInt32:  "int32",
+	ValueTypeUint32: "uint32",
+	ValueTypeInt64:  "int64",
+	ValueTypeUint64: "uint64",
+	ValueTypeFloat:  "float",
+	ValueTypeDouble: "double",
+	ValueTypeString: "string",
+}
+
+// String returns the C++ name of the value type
+func (t ValueType) String() string {
+	if name, ok := valueTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(t))
+}
+
+// Value is the decoded payload of a value block
+type Value struct {
+	ID      uint64 // Value ID (stored in the block's End field on disk)
+	Type    ValueType
+	IsArray bool
+	Data    []byte // Raw little-endian element data
+}
+
+// IsFloat reports whether the value holds floating-point data
+func (v *Value) IsFloat() bool {
+	return v.Type == ValueTypeFloat || v.Type == ValueTypeDouble
+}
+
+// IsInteger reports whether the value holds integer (or bool/char) data
+func (v *Value) IsInteger() bool {
+	return v.Type <= ValueTypeUint64
+}
+
+// elementSize returns the size in bytes of a single element, or 0 for strings
+func (v *Value) elementSize() int {
+	switch v.Type {
+	case ValueTypeBool, ValueTypeChar, ValueTypeInt8, ValueTypeUint8:
+		return 1
+	case ValueTypeInt16, ValueTypeUint16:
+		return 2
+	case ValueTypeInt32, ValueTypeUint32, ValueTypeFloat:
+		return 4
+	case ValueTypeInt64, ValueTypeUint64, ValueTypeDouble:
+		return 8
+	}
+	return 0
+}
+
+// Integers decodes integer elements; it returns nil for non-integer values
+func (v *Value) Integers() []int64 {
+	size := v.elementSize()
+	if !v.IsInteger() || size == 0 {
+		return nil
+	}
+
+	result := make([]int64, 0, len(v.Data)/size)
+	for offset := 0; offset+size <= len(v.Data); offset += size {
+		raw := v.Data[offset : offset+size]
+		switch v.Type {
+		case ValueTypeBool, ValueTypeUint8:
+			result = append(result, int64(raw[0]))
+		case ValueTypeChar, ValueTypeInt8:
+			result = append(result, int64(int8(raw[0])))
+		case ValueTypeInt16:
+			result = append(result, int64(int16(binary.LittleEndian.Uint16(raw))))
+		case ValueTypeUint16:
+			result = append(result, int64(binary.LittleEndian.Uint16(raw)))
+		case ValueTypeInt32:
+			result = append(result, int64(int32(binary.LittleEndian.Uint32(raw))))
+		case ValueTypeUint32:
+			result = append(result, int64(binary.LittleEndian.Uint32(raw)))
+		case ValueTypeInt64, ValueTypeUint64:
+			result = append(result, int64(binary.LittleEndian.Uint64(raw)))
+		}
+	}
+	return result
+}
+
+// Floats decodes floating-point elements; it returns nil for non-float values
+func (v *Value) Floats() []float64 {
+	size := v.elementSize()
+	if !v.IsFloat() {
+		return nil
+	}
+
+	result := make([]float64, 0, len(v.Data)/size)
+	for offset := 0; offset+size <= len(v.Data); offset += size {
+		raw := v.Data[offset : offset+size]
+		if v.Type == ValueTypeFloat {
+			result = append(result, float64(math.Float32frombits(binary.LittleEndian.Uint32(raw))))
+		} else {
+			result = append(result, math.Float64frombits(binary.LittleEndian.Uint64(raw)))
+		}
+	}
+	return result
+}
+
+// decodeValue parses a value payload: [uint16 size][uint8 type][uint8 isArray][data]
+func decodeValue(valueID uint64, payload []byte) (*Value, error) {
+	if len(payload) < valueHeaderSize {
+		return nil, fmt.Errorf("value payload too short: %d bytes", len(payload))
+	}
+
+	dataSize := int(binary.LittleEndian.Uint16(payload[0:2]))
+	value := &Value{
+		ID:      valueID,
+		Type:    ValueType(payload[2]),
+		IsArray: payload[3] != 0,
+	}
+
+	data := payload[valueHeaderSize:]
+	if dataSize < len(data) {
+		data = data[:dataSize]
+	}
+	value.Data = data
+
+	return value, nil
+}
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeValue(t *testing.T) {
+	tests := []struct {
+		name       string
+		payload    []byte
+		wantErr    bool
+		wantType   ValueType
+		wantArray  bool
+		wantInts   []int64
+		wantFloats []float64
+	}{
+		{
+			name:     "int32",
+			payload:  []byte{4, 0, byte(ValueTypeInt32), 0, 0xfe, 0xff, 0xff, 0xff},
+			wantType: ValueTypeInt32,
+			wantInts: []int64{-2},
+		},
+		{
+			name:     "uint32 stays positive",
+			payload:  []byte{4, 0, byte(ValueTypeUint32), 0, 0xfe, 0xff, 0xff, 0xff},
+			wantType: ValueTypeUint32,
+			wantInts: []int64{0xfffffffe},
+		},
+		{
+			name:      "int16 array",
+			payload:   []byte{6, 0, byte(ValueTypeInt16), 1, 1, 0, 2, 0, 0xff, 0xff},
+			wantType:  ValueTypeInt16,
+			wantArray: true,
+			wantInts:  []int64{1, 2, -1},
+		},
+		{
+			name:       "float",
+			payload:    []byte{4, 0, byte(ValueTypeFloat), 0, 0, 0, 0xc0, 0x3f},
+			wantType:   ValueTypeFloat,
+			wantFloats: []float64{1.5},
+		},
+		{
+			name:       "double",
+			payload:    []byte{8, 0, byte(ValueTypeDouble), 0, 0, 0, 0, 0, 0, 0, 0x04, 0xc0},
+			wantType:   ValueTypeDouble,
+			wantFloats: []float64{-2.5},
+		},
+		{
+			name:     "data beyond the declared size is ignored",
+			payload:  []byte{1, 0, byte(ValueTypeUint8), 0, 7, 9},
+			wantType: ValueTypeUint8,
+			wantInts: []int64{7},
+		},
+		{
+			name:      "string",
+			payload:   []byte{3, 0, byte(ValueTypeString), 1, 'h', 'i', 0},
+			wantType:  ValueTypeString,
+			wantArray: true,
+		},
+		{
+			name:    "too short",
+			payload: []byte{4, 0, byte(ValueTypeInt32)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := decodeValue(42, tt.payload)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("decodeValue succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeValue: %v", err)
+			}
+			if value.ID != 42 || value.Type != tt.wantType || value.IsArray != tt.wantArray {
+				t.Errorf("value = id %d type %v array %t", value.ID, value.Type, value.IsArray)
+			}
+			if got := value.Integers(); !reflect.DeepEqual(got, tt.wantInts) {
+				t.Errorf("Integers() = %v, want %v", got, tt.wantInts)
+			}
+			if got := value.Floats(); !reflect.DeepEqual(got, tt.wantFloats) {
+				t.Errorf("Floats() = %v, want %v", got, tt.wantFloats)
+			}
+		})
+	}
+}
+
+func TestValueTypeString(t *testing.T) {
+	tests := []struct {
+		valueType ValueType
+		want      string
+	}{
+		{ValueTypeBool, "bool"},
+		{ValueTypeUint64, "uint64"},
+		{ValueTypeString, "string"},
+		{ValueType(99), "unknown(99)"},
+	}
+	for _, tt := range tests {
+		if got := tt.valueType.String(); got != tt.want {
+			t.Errorf("ValueType(%d).String() = %q, want %q", uint8(tt.valueType), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxTreeDepth bounds the block nesting accepted while parsing and followed by
+// traversals. Real captures nest a few dozen levels; anything near this limit points to
+// corrupt data or a cycle introduced by a tree-building bug.
+const DefaultMaxTreeDepth = 10000
+
+// ErrTreeTooDeep is returned when a block tree nests deeper than the allowed depth
+var ErrTreeTooDeep = errors.New("block tree exceeds maximum depth")
+
+// WalkBlocks visits blocks and all their descendants depth-first in pre-order, using an
+// explicit stack rather than recursion. depth is 0 for the given blocks. Descendants
+// deeper than maxDepth are not visited and ErrTreeTooDeep is returned, so a cyclic
+// structure terminates instead of looping forever.
+func WalkBlocks(blocks []*Block, maxDepth int, visit func(block *Block, depth int)) error {
+	return WalkBlocksUntil(blocks, maxDepth, func(block *Block, depth int) bool {
+		visit(block, depth)
+		return true
+	})
+}
+
+// WalkBlocksUntil is WalkBlocks stopping as soon as visit returns false, e.g. when a
+// caller's time budget runs out
+func WalkBlocksUntil(blocks []*Block, maxDepth int, visit func(block *Block, depth int) bool) error {
+	type frame struct {
+		block *Block
+		depth int
+	}
+
+	var err error
+	stack := make([]frame, 0, len(blocks))
+	for i := len(blocks) - 1; i >= 0; i-- {
+		stack = append(stack, frame{block: blocks[i]})
+	}
+
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if !visit(top.block, top.depth) {
+			break
+		}
+
+		if len(top.block.Children) == 0 {
+			continue
+		}
+		if top.depth+1 > maxDepth {
+			err = fmt.Errorf("%w (%d)", ErrTreeTooDeep, maxDepth)
+			continue
+		}
+
+		// Push in reverse so children are visited in order
+		for i := len(top.block.Children) - 1; i >= 0; i-- {
+			stack = append(stack, frame{block: top.block.Children[i], depth: top.depth + 1})
+		}
+	}
+
+	return err
+}
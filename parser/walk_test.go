@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWalkBlocks(t *testing.T) {
+	tree := func() []*Block {
+		return []*Block{
+			{ID: 1, Children: []*Block{
+				{ID: 2, Children: []*Block{{ID: 3}}},
+				{ID: 4},
+			}},
+			{ID: 5},
+		}
+	}
+	cyclic := func() []*Block {
+		root := &Block{ID: 1}
+		root.Children = []*Block{root}
+		return []*Block{root}
+	}
+
+	tests := []struct {
+		name     string
+		blocks   []*Block
+		maxDepth int
+		stopAt   uint32
+		want     []string
+		wantErr  bool
+	}{
+		{"pre-order", tree(), DefaultMaxTreeDepth, 0, []string{"A0", "B1", "C2", "D1", "E0"}, false},
+		{"depth limit", tree(), 1, 0, []string{"A0", "B1", "D1", "E0"}, true},
+		{"cycle terminates", cyclic(), 3, 0, []string{"A0", "A1", "A2", "A3"}, true},
+		{"stop early", tree(), DefaultMaxTreeDepth, 3, []string{"A0", "B1", "C2"}, false},
+		{"empty", nil, DefaultMaxTreeDepth, 0, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var visited []string
+			err := WalkBlocksUntil(tt.blocks, tt.maxDepth, func(block *Block, depth int) bool {
+				visited = append(visited, string(rune('A'+block.ID-1))+string(rune('0'+depth)))
+				return block.ID != tt.stopAt
+			})
+			if !reflect.DeepEqual(visited, tt.want) {
+				t.Errorf("visited %v, want %v", visited, tt.want)
+			}
+			if got := errors.Is(err, ErrTreeTooDeep); got != tt.wantErr {
+				t.Errorf("err = %v, want ErrTreeTooDeep %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaxTreeDepthOption(t *testing.T) {
+	tests := []struct {
+		option int
+		want   int
+	}{
+		{0, DefaultMaxTreeDepth},
+		{-1, DefaultMaxTreeDepth},
+		{5, 5},
+		{DefaultMaxTreeDepth, DefaultMaxTreeDepth},
+		{DefaultMaxTreeDepth + 1, DefaultMaxTreeDepth},
+	}
+
+	for _, tt := range tests {
+		if got := (ReadOptions{MaxTreeDepth: tt.option}).maxTreeDepth(); got != tt.want {
+			t.Errorf("MaxTreeDepth %d: effective depth %d, want %d", tt.option, got, tt.want)
+		}
+	}
+}
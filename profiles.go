@@ -0,0 +1,842 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yourusername/easyprofiler-mcp/analyzer"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// loadedProfile is a parsed profile kept in the registry
+type loadedProfile struct {
+	ID       string
+	FilePath string
+	LoadedAt time.Time
+	Profile  *parser.ProfileData
+	Analyzer *analyzer.Analyzer
+
+	// PreviousID is the profile loaded from the same path before this one, if any
+	PreviousID string
+
+	// SourceID is the profile this one was extracted from by extract_subprofile, if any
+	SourceID string
+}
+
+// maxLoadedProfiles is how many profiles the registry keeps. Each load adds one, so
+// beyond this the oldest ones are unloaded to keep memory bounded.
+const maxLoadedProfiles = 8
+
+var (
+	loadedProfiles   = make(map[string]*loadedProfile)
+	currentProfileID string
+	nextProfileID    = 1
+
+	// lastLoadByPath maps an absolute file path to the ID of its most recent load
+	lastLoadByPath = make(map[string]string)
+
+	// registryMu guards the registry, the current profile and the output format.
+	// Tools that change them hold the write lock; all others hold the read lock for
+	// their whole run, so a concurrent load never swaps the profile mid-analysis.
+	registryMu sync.RWMutex
+)
+
+// readLocked wraps a tool handler that only reads shared state
+func readLocked(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	handler = timed(handler) // Inside the lock: waiting for it isn't analysis time
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		registryMu.RLock()
+		defer registryMu.RUnlock()
+		return handler(ctx, request)
+	}
+}
+
+// writeLocked wraps a tool handler that changes shared state
+func writeLocked(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	handler = timed(handler)
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		return handler(ctx, request)
+	}
+}
+
+// registerProfile stores a parsed profile under a new ID and makes it the current profile.
+// The caller must hold registryMu for writing.
+func registerProfile(filePath string, profile *parser.ProfileData) *loadedProfile {
+	loaded := &loadedProfile{
+		ID:       fmt.Sprintf("p%d", nextProfileID),
+		FilePath: filePath,
+		LoadedAt: time.Now(),
+		Profile:  profile,
+		Analyzer: analyzer.NewAnalyzer(profile),
+	}
+	nextProfileID++
+
+	key := pathKey(filePath)
+	loaded.PreviousID = lastLoadByPath[key]
+	lastLoadByPath[key] = loaded.ID
+
+	loadedProfiles[loaded.ID] = loaded
+	setCurrentProfile(loaded)
+	return loaded
+}
+
+// registerSubprofile stores a profile extracted from source under a new ID and makes
+// it the current profile. It isn't a load of source's file, so diff_since_last_load
+// never pairs it with one. The caller must hold registryMu for writing.
+func registerSubprofile(source *loadedProfile, profile *parser.ProfileData) *loadedProfile {
+	loaded := &loadedProfile{
+		ID:       fmt.Sprintf("p%d", nextProfileID),
+		FilePath: source.FilePath,
+		LoadedAt: time.Now(),
+		Profile:  profile,
+		Analyzer: analyzer.NewAnalyzer(profile),
+		SourceID: source.ID,
+	}
+	nextProfileID++
+	loaded.Analyzer.SetAnonymousPolicy(source.Analyzer.AnonymousPolicy())
+	loaded.Analyzer.SetIdlePatterns(source.Analyzer.IdlePatterns())
+
+	loadedProfiles[loaded.ID] = loaded
+	setCurrentProfile(loaded)
+	return loaded
+}
+
+// evictOldProfiles unloads the oldest profiles other than the current one until at
+// most maxLoadedProfiles remain, returning their IDs. The caller must hold
+// registryMu for writing.
+func evictOldProfiles() []string {
+	var evicted []string
+	for _, loaded := range sortedProfiles() {
+		if len(loadedProfiles) <= maxLoadedProfiles {
+			break
+		}
+		if loaded.ID == currentProfileID {
+			continue
+		}
+		delete(loadedProfiles, loaded.ID)
+		evicted = append(evicted, loaded.ID)
+	}
+	return evicted
+}
+
+// pathKey normalizes a file path so different spellings of the same file match
+func pathKey(filePath string) string {
+	if abs, err := filepath.Abs(filePath); err == nil {
+		return abs
+	}
+	return filePath
+}
+
+// setCurrentProfile makes loaded the profile used by single-profile tools (nil clears it)
+func setCurrentProfile(loaded *loadedProfile) {
+	if loaded == nil {
+		currentProfileID = ""
+		currentProfile = nil
+		currentAnalyzer = nil
+		return
+	}
+	currentProfileID = loaded.ID
+	currentProfile = loaded.Profile
+	currentAnalyzer = loaded.Analyzer
+}
+
+// lookupProfile returns the profile with the given ID, or the current profile if id is empty
+func lookupProfile(id string) (*loadedProfile, error) {
+	if id == "" {
+		id = currentProfileID
+	}
+	if id == "" {
+		return nil, fmt.Errorf("No profile loaded. Use load_profile first.")
+	}
+	loaded, ok := loadedProfiles[id]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' not found", id)
+	}
+	return loaded, nil
+}
+
+// sortedProfiles returns the loaded profiles in load order
+func sortedProfiles() []*loadedProfile {
+	result := make([]*loadedProfile, 0, len(loadedProfiles))
+	for _, loaded := range loadedProfiles {
+		result = append(result, loaded)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		ni, _ := strconv.Atoi(result[i].ID[1:])
+		nj, _ := strconv.Atoi(result[j].ID[1:])
+		return ni < nj
+	})
+	return result
+}
+
+func registerProfileTools(s *server.MCPServer) {
+	// List loaded profiles
+	listProfilesTool := mcp.NewTool("list_profiles",
+		mcp.WithDescription("List all loaded profiles with their profile_id"),
+	)
+
+	s.AddTool(listProfilesTool, readLocked(listProfilesHandler))
+
+	// Unload a profile
+	unloadProfileTool := mcp.NewTool("unload_profile",
+		mcp.WithDescription(fmt.Sprintf("Unload a profile to free memory. Loaded profiles are otherwise kept for comparison until more than %d are loaded, when the oldest are unloaded", maxLoadedProfiles)),
+		mcp.WithString("profile_id",
+			mcp.Required(),
+			mcp.Description("ID of the profile to unload"),
+		),
+	)
+
+	s.AddTool(unloadProfileTool, writeLocked(unloadProfileHandler))
+
+	// Compare two profiles
+	compareProfilesTool := mcp.NewTool("compare_profiles",
+		mcp.WithDescription("Compare cumulative time per function between a baseline and a current profile"),
+		mcp.WithString("baseline_id",
+			mcp.Required(),
+			mcp.Description("profile_id of the baseline"),
+		),
+		mcp.WithString("current_id",
+			mcp.Description("profile_id to compare against the baseline (default: current profile)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of functions to return (default: 20)"),
+		),
+		mcp.WithBoolean("exclusive",
+			mcp.Description("Measure exclusive (self) time, excluding nested child blocks, instead of inclusive time (default: false)"),
+		),
+		mcp.WithBoolean("by_call_site",
+			mcp.Description("Compare each function separately per direct caller, so a regression is attributed to the calling context it happened in (default: false)"),
+		),
+		mcp.WithBoolean("normalize",
+			mcp.Description("Also compare each function's share of its capture's duration and rank by the change in share, so profiles of different lengths don't flag functions that merely scaled with the whole run. Absolute deltas are still reported (default: false)"),
+		),
+	)
+
+	s.AddTool(compareProfilesTool, readLocked(compareProfilesHandler))
+
+	// Regression check
+	regressionCheckTool := mcp.NewTool("regression_check",
+		mcp.WithDescription("CI-style gate: report functions that regressed beyond a threshold versus a baseline, with an overall pass/fail verdict"),
+		mcp.WithString("baseline_id",
+			mcp.Required(),
+			mcp.Description("profile_id of the baseline"),
+		),
+		mcp.WithString("current_id",
+			mcp.Description("profile_id to check (default: current profile)"),
+		),
+		mcp.WithNumber("threshold_percent",
+			mcp.Description("Maximum allowed slowdown per function in percent (default: 10)"),
+		),
+		mcp.WithNumber("min_duration_ms",
+			mcp.Description("Ignore functions below this cumulative time in both profiles (default: 1)"),
+		),
+		mcp.WithBoolean("exclusive",
+			mcp.Description("Measure exclusive (self) time, excluding nested child blocks, instead of inclusive time (default: false)"),
+		),
+	)
+
+	s.AddTool(regressionCheckTool, readLocked(regressionCheckHandler))
+
+	// Compact a profile
+	compactProfileTool := mcp.NewTool("compact_profile",
+		mcp.WithDescription("Free memory in a loaded profile by dropping context switches and/or short blocks in place. Hotspot and thread analyses keep working"),
+		mcp.WithString("profile_id",
+			mcp.Description("ID of the profile to compact (default: current profile)"),
+		),
+		mcp.WithBoolean("drop_context_switches",
+			mcp.Description("Drop all context switch data (default: false)"),
+		),
+		mcp.WithNumber("min_block_duration_us",
+			mcp.Description("Drop blocks shorter than this many microseconds, with their children; values and events inside kept blocks stay (default: 0, keep all)"),
+		),
+	)
+
+	s.AddTool(compactProfileTool, writeLocked(compactProfileHandler))
+
+	// Diff against the previous load of the same file
+	diffSinceLastLoadTool := mcp.NewTool("diff_since_last_load",
+		mcp.WithDescription("Compare a profile with the previous load of the same file path, returning the top regressions and improvements"),
+		mcp.WithString("profile_id",
+			mcp.Description("ID of the newer profile (default: current profile)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of regressions and of improvements to return (default: 10)"),
+		),
+		mcp.WithBoolean("exclusive",
+			mcp.Description("Measure exclusive (self) time, excluding nested child blocks, instead of inclusive time (default: false)"),
+		),
+	)
+
+	s.AddTool(diffSinceLastLoadTool, readLocked(diffSinceLastLoadHandler))
+
+	// Hotspots aggregated across several runs
+	aggregateHotspotsTool := mcp.NewTool("aggregate_hotspots",
+		mcp.WithDescription("Aggregate cumulative time per function across several loaded profiles of the same workload (e.g. repeated benchmark runs): mean, variance and coefficient of variation, to tell consistent bottlenecks from run-to-run jitter"),
+		mcp.WithString("profile_ids",
+			mcp.Description("Comma-separated profile_ids to aggregate (default: all loaded profiles)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of functions to return, by mean time (default: 20)"),
+		),
+		mcp.WithNumber("noisy_cv_percent",
+			mcp.Description("Coefficient of variation above which a function is reported as noisy rather than stable (default: 25)"),
+		),
+		mcp.WithBoolean("exclusive",
+			mcp.Description("Measure exclusive (self) time, excluding nested child blocks, instead of inclusive time (default: false)"),
+		),
+	)
+
+	s.AddTool(aggregateHotspotsTool, readLocked(aggregateHotspotsHandler))
+
+	// Extract a sub-profile around one call
+	extractSubprofileTool := mcp.NewTool("extract_subprofile",
+		mcp.WithDescription("Cut one call of a function (its block and everything nested in it) out of a profile into a new standalone profile_id, which becomes the current profile. Use it to analyze or export a single operation without the surrounding noise"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Function (block) name"),
+		),
+		mcp.WithNumber("invocation",
+			mcp.Description("Which call to extract, 1-based in thread then time order (default: the slowest call)"),
+		),
+		mcp.WithBoolean("include_siblings",
+			mcp.Description("Also keep the blocks sharing the call's parent, with their subtrees (default: false)"),
+		),
+		mcp.WithString("profile_id",
+			mcp.Description("ID of the profile to extract from (default: current profile)"),
+		),
+	)
+
+	s.AddTool(extractSubprofileTool, writeLocked(extractSubprofileHandler))
+
+	// Functions slower than their norm across loaded runs
+	historicalAnomaliesTool := mcp.NewTool("find_historical_anomalies",
+		mcp.WithDescription("Flag functions running abnormally slow in one profile: their average call compared with the mean of their average call across the other loaded profiles of the same workload, a lightweight anomaly detector over the session's baselines"),
+		mcp.WithString("profile_id",
+			mcp.Description("ID of the profile to check (default: current profile)"),
+		),
+		mcp.WithString("baseline_ids",
+			mcp.Description("Comma-separated profile_ids forming the norm (default: every other loaded profile, except extracted sub-profiles)"),
+		),
+		mcp.WithNumber("min_ratio",
+			mcp.Description("How many times its norm a function's average call must take to be flagged (default: 2)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of functions to return, largest ratio first (default: 20)"),
+		),
+		mcp.WithBoolean("exclusive",
+			mcp.Description("Measure exclusive (self) time, excluding nested child blocks, instead of inclusive time (default: false)"),
+		),
+	)
+
+	s.AddTool(historicalAnomaliesTool, readLocked(findHistoricalAnomaliesHandler))
+}
+
+func listProfilesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	profiles := sortedProfiles()
+
+	results := make([]map[string]interface{}, len(profiles))
+	for i, loaded := range profiles {
+		results[i] = map[string]interface{}{
+			"profile_id":     loaded.ID,
+			"file":           loaded.FilePath,
+			"loaded_at":      loaded.LoadedAt.Format(time.RFC3339),
+			"current":        loaded.ID == currentProfileID,
+			"total_duration": formatDuration(loaded.Profile.GetTotalDuration()),
+			"blocks_count":   loaded.Profile.TotalBlocksCount,
+		}
+		if loaded.SourceID != "" {
+			results[i]["extracted_from"] = loaded.SourceID
+		}
+	}
+
+	return jsonResult(results)
+}
+
+func unloadProfileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, ok := request.Params.Arguments["profile_id"].(string)
+	if !ok {
+		return mcp.NewToolResultError("profile_id parameter is required"), nil
+	}
+
+	if _, ok := loadedProfiles[id]; !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("profile '%s' not found", id)), nil
+	}
+	delete(loadedProfiles, id)
+
+	// Fall back to the most recently loaded remaining profile
+	if id == currentProfileID {
+		var latest *loadedProfile
+		if profiles := sortedProfiles(); len(profiles) > 0 {
+			latest = profiles[len(profiles)-1]
+		}
+		setCurrentProfile(latest)
+	}
+
+	result := map[string]interface{}{
+		"status":             "success",
+		"unloaded":           id,
+		"current_profile_id": currentProfileID,
+	}
+
+	return jsonResult(result)
+}
+
+func compareProfilesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseline, current, errResult := comparisonProfiles(request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	limit := 20
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	mode := timeModeArg(request)
+	byCallSite, _ := request.Params.Arguments["by_call_site"].(bool)
+	normalize, _ := request.Params.Arguments["normalize"].(bool)
+
+	var diffs []*analyzer.FunctionDiff
+	if byCallSite {
+		diffs = analyzer.CompareProfilesByCallSite(baseline.Analyzer, current.Analyzer, mode)
+	} else {
+		diffs = analyzer.CompareProfiles(baseline.Analyzer, current.Analyzer, mode)
+	}
+	if normalize {
+		analyzer.NormalizeDiffs(diffs, baseline.Analyzer, current.Analyzer)
+	}
+	if limit < len(diffs) {
+		diffs = diffs[:limit]
+	}
+
+	result := map[string]interface{}{
+		"baseline_id":  baseline.ID,
+		"current_id":   current.ID,
+		"time_mode":    mode.String(),
+		"by_call_site": byCallSite,
+		"normalized":   normalize,
+		"functions":    formatFunctionDiffs(diffs),
+	}
+	if normalize {
+		result["baseline_duration"] = formatDuration(baseline.Profile.GetTotalDuration())
+		result["current_duration"] = formatDuration(current.Profile.GetTotalDuration())
+	}
+
+	return jsonResult(result)
+}
+
+func regressionCheckHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	baseline, current, errResult := comparisonProfiles(request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	threshold := 10.0
+	if t, ok := request.Params.Arguments["threshold_percent"].(float64); ok {
+		threshold = t
+	}
+
+	minDuration := time.Millisecond
+	if m, ok := request.Params.Arguments["min_duration_ms"].(float64); ok {
+		minDuration = time.Duration(m * float64(time.Millisecond))
+	}
+
+	mode := timeModeArg(request)
+
+	report := analyzer.CheckRegressions(baseline.Analyzer, current.Analyzer, threshold, minDuration, mode)
+
+	verdict := "pass"
+	if !report.Passed {
+		verdict = "fail"
+	}
+
+	result := map[string]interface{}{
+		"verdict":            verdict,
+		"passed":             report.Passed,
+		"baseline_id":        baseline.ID,
+		"current_id":         current.ID,
+		"time_mode":          mode.String(),
+		"threshold_percent":  report.ThresholdPercent,
+		"min_duration":       formatDuration(report.MinDuration),
+		"functions_compared": report.FunctionsCompared,
+		"regressions_count":  len(report.Regressions),
+		"regressions":        formatFunctionDiffs(report.Regressions),
+	}
+
+	return jsonResult(result)
+}
+
+func compactProfileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.Params.Arguments["profile_id"].(string)
+	loaded, err := lookupProfile(id)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	options := parser.CompactOptions{}
+	if drop, ok := request.Params.Arguments["drop_context_switches"].(bool); ok {
+		options.DropContextSwitches = drop
+	}
+	if minUs, ok := request.Params.Arguments["min_block_duration_us"].(float64); ok && minUs > 0 {
+		options.MinBlockDuration = time.Duration(minUs * float64(time.Microsecond))
+	}
+
+	if !options.DropContextSwitches && options.MinBlockDuration == 0 {
+		return mcp.NewToolResultError("Nothing to compact: set drop_context_switches and/or min_block_duration_us"), nil
+	}
+
+	before := loaded.Profile.EstimateMemory().TotalBytes
+	removedBlocks := loaded.Profile.Compact(options)
+	after := loaded.Profile.EstimateMemory().TotalBytes
+
+	// The tree changed, so per-block results cached by the old analyzer are stale
+	previous := loaded.Analyzer
+	loaded.Analyzer = analyzer.NewAnalyzer(loaded.Profile)
+	loaded.Analyzer.SetAnonymousPolicy(previous.AnonymousPolicy())
+	loaded.Analyzer.SetIdlePatterns(previous.IdlePatterns())
+	if loaded.ID == currentProfileID {
+		setCurrentProfile(loaded)
+	}
+
+	result := map[string]interface{}{
+		"status":              "success",
+		"profile_id":          loaded.ID,
+		"removed_blocks":      removedBlocks,
+		"remaining_blocks":    loaded.Profile.TotalBlocksCount,
+		"estimated_before_mb": formatMB(before),
+		"estimated_after_mb":  formatMB(after),
+		"estimated_freed_mb":  formatMB(before - after),
+	}
+
+	return jsonResult(result)
+}
+
+func diffSinceLastLoadHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.Params.Arguments["profile_id"].(string)
+	current, err := lookupProfile(id)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if current.PreviousID == "" {
+		return mcp.NewToolResultError(fmt.Sprintf("No previous load of %s. Load the file again after re-profiling to diff.", current.FilePath)), nil
+	}
+	baseline, ok := loadedProfiles[current.PreviousID]
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("Previous load '%s' of %s was unloaded", current.PreviousID, current.FilePath)), nil
+	}
+
+	limit := 10
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	mode := timeModeArg(request)
+
+	var regressions, improvements []*analyzer.FunctionDiff
+	for _, diff := range analyzer.CompareProfiles(baseline.Analyzer, current.Analyzer, mode) {
+		if diff.Delta > 0 && len(regressions) < limit {
+			regressions = append(regressions, diff)
+		} else if diff.Delta < 0 && len(improvements) < limit {
+			improvements = append(improvements, diff)
+		}
+	}
+
+	result := map[string]interface{}{
+		"file":         current.FilePath,
+		"baseline_id":  baseline.ID,
+		"current_id":   current.ID,
+		"time_mode":    mode.String(),
+		"regressions":  formatFunctionDiffs(regressions),
+		"improvements": formatFunctionDiffs(improvements),
+	}
+
+	return jsonResult(result)
+}
+
+func aggregateHotspotsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var profiles []*loadedProfile
+	if ids, _ := request.Params.Arguments["profile_ids"].(string); ids != "" {
+		for _, id := range parseNameList(ids) {
+			loaded, err := lookupProfile(id)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			profiles = append(profiles, loaded)
+		}
+	} else {
+		profiles = sortedProfiles()
+	}
+
+	if len(profiles) < 2 {
+		return mcp.NewToolResultError("At least two profiles are needed to aggregate across runs. Load more profiles with load_profile."), nil
+	}
+
+	limit := 20
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	noisyCV := 25.0
+	if n, ok := request.Params.Arguments["noisy_cv_percent"].(float64); ok {
+		noisyCV = n
+	}
+
+	mode := timeModeArg(request)
+
+	analyzers := make([]*analyzer.Analyzer, len(profiles))
+	ids := make([]string, len(profiles))
+	for i, loaded := range profiles {
+		analyzers[i] = loaded.Analyzer
+		ids[i] = loaded.ID
+	}
+
+	stats := analyzer.AggregateHotspots(analyzers, mode)
+	if limit < len(stats) {
+		stats = stats[:limit]
+	}
+
+	// Format results
+	functions := make([]map[string]interface{}, len(stats))
+	for i, s := range stats {
+		perRun := make([]interface{}, len(s.Durations))
+		for j, d := range s.Durations {
+			perRun[j] = formatDuration(d)
+		}
+
+		consistency := "stable"
+		if s.CV*100 > noisyCV {
+			consistency = "noisy"
+		}
+
+		functions[i] = map[string]interface{}{
+			"name":         s.Name,
+			"file":         s.File,
+			"line":         s.Line,
+			"runs":         s.Runs,
+			"mean":         formatDuration(s.Mean),
+			"stddev":       formatDuration(s.StdDev),
+			"variance_ns2": s.Variance,
+			"min":          formatDuration(s.Min),
+			"max":          formatDuration(s.Max),
+			"cv_percent":   formatPercent(s.CV * 100),
+			"consistency":  consistency,
+			"per_run":      perRun,
+		}
+	}
+
+	result := map[string]interface{}{
+		"profile_ids": ids,
+		"time_mode":   mode.String(),
+		"functions":   functions,
+	}
+
+	return jsonResult(result)
+}
+
+// findHistoricalAnomaliesHandler flags functions slower than their norm across the baseline profiles
+func findHistoricalAnomaliesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.Params.Arguments["profile_id"].(string)
+	current, err := lookupProfile(id)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var baselines []*loadedProfile
+	if ids, _ := request.Params.Arguments["baseline_ids"].(string); ids != "" {
+		for _, id := range parseNameList(ids) {
+			loaded, err := lookupProfile(id)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			if loaded.ID != current.ID {
+				baselines = append(baselines, loaded)
+			}
+		}
+	} else {
+		for _, loaded := range sortedProfiles() {
+			if loaded.ID != current.ID && loaded.SourceID == "" {
+				baselines = append(baselines, loaded)
+			}
+		}
+	}
+
+	if len(baselines) == 0 {
+		return mcp.NewToolResultError("No baseline profiles to compare with. Load other runs of the same workload with load_profile."), nil
+	}
+
+	minRatio := 2.0
+	if r, ok := request.Params.Arguments["min_ratio"].(float64); ok {
+		minRatio = r
+	}
+
+	limit := 20
+	if l, ok := request.Params.Arguments["limit"].(float64); ok {
+		if l < 0 {
+			return mcp.NewToolResultError("limit must not be negative"), nil
+		}
+		limit = int(l)
+	}
+
+	mode := timeModeArg(request)
+
+	analyzers := make([]*analyzer.Analyzer, len(baselines))
+	ids := make([]string, len(baselines))
+	for i, loaded := range baselines {
+		analyzers[i] = loaded.Analyzer
+		ids[i] = loaded.ID
+	}
+
+	anomalies := analyzer.FindHistoricalAnomalies(current.Analyzer, analyzers, mode, minRatio)
+	if limit < len(anomalies) {
+		anomalies = anomalies[:limit]
+	}
+
+	// Format results
+	functions := make([]map[string]interface{}, len(anomalies))
+	for i, anomaly := range anomalies {
+		functions[i] = map[string]interface{}{
+			"name":           anomaly.Name,
+			"file":           anomaly.File,
+			"line":           anomaly.Line,
+			"current_avg":    formatDuration(anomaly.CurrentAvg),
+			"current_calls":  anomaly.CurrentCalls,
+			"historical_avg": formatDuration(anomaly.HistoricalAvg),
+			"baseline_runs":  anomaly.Runs,
+			"ratio":          formatNumber(anomaly.Ratio, 2),
+		}
+	}
+
+	return jsonResult(map[string]interface{}{
+		"profile_id":   current.ID,
+		"baseline_ids": ids,
+		"time_mode":    mode.String(),
+		"min_ratio":    minRatio,
+		"functions":    functions,
+	})
+}
+
+// comparisonProfiles resolves the baseline_id and current_id arguments
+func comparisonProfiles(request mcp.CallToolRequest) (*loadedProfile, *loadedProfile, *mcp.CallToolResult) {
+	baselineID, ok := request.Params.Arguments["baseline_id"].(string)
+	if !ok {
+		return nil, nil, mcp.NewToolResultError("baseline_id parameter is required")
+	}
+	currentID, _ := request.Params.Arguments["current_id"].(string)
+
+	baseline, err := lookupProfile(baselineID)
+	if err != nil {
+		return nil, nil, mcp.NewToolResultError(err.Error())
+	}
+	current, err := lookupProfile(currentID)
+	if err != nil {
+		return nil, nil, mcp.NewToolResultError(err.Error())
+	}
+
+	return baseline, current, nil
+}
+
+// formatFunctionDiffs renders function diffs for JSON output
+func formatFunctionDiffs(diffs []*analyzer.FunctionDiff) []map[string]interface{} {
+	results := make([]map[string]interface{}, len(diffs))
+	for i, diff := range diffs {
+		var deltaPercent interface{} = "new"
+		if !math.IsInf(diff.DeltaPercent, 1) {
+			deltaPercent = fmt.Sprintf("%+.2f%%", diff.DeltaPercent)
+			if outputFormat.Raw {
+				deltaPercent = diff.DeltaPercent / 100
+			}
+		}
+
+		results[i] = map[string]interface{}{
+			"name":              diff.Name,
+			"file":              diff.File,
+			"line":              diff.Line,
+			"baseline_duration": formatDuration(diff.BaselineDuration),
+			"current_duration":  formatDuration(diff.CurrentDuration),
+			"baseline_calls":    diff.BaselineCalls,
+			"current_calls":     diff.CurrentCalls,
+			"delta":             formatDuration(diff.Delta),
+			"delta_ns":          diff.Delta.Nanoseconds(),
+			"delta_percent":     deltaPercent,
+		}
+		if diff.Caller != "" {
+			results[i]["caller"] = diff.Caller
+		}
+		if diff.Normalized {
+			results[i]["baseline_share"] = formatPercent(diff.BaselineShare)
+			results[i]["current_share"] = formatPercent(diff.CurrentShare)
+			results[i]["share_delta_points"] = formatNumber(diff.ShareDelta, 2)
+		}
+	}
+	return results
+}
+
+func extractSubprofileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.Params.Arguments["profile_id"].(string)
+	source, err := lookupProfile(id)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	name, ok := request.Params.Arguments["name"].(string)
+	if !ok {
+		return mcp.NewToolResultError("name parameter is required"), nil
+	}
+
+	invocation := 0
+	if i, ok := request.Params.Arguments["invocation"].(float64); ok {
+		invocation = int(i)
+		if invocation < 1 {
+			return mcp.NewToolResultError("invocation must be at least 1"), nil
+		}
+	}
+	withSiblings, _ := request.Params.Arguments["include_siblings"].(bool)
+
+	subprofile, err := source.Analyzer.ExtractSubprofile(name, invocation, withSiblings)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	loaded := registerSubprofile(source, subprofile.Profile)
+	evicted := evictOldProfiles()
+
+	root := subprofile.Root
+	result := map[string]interface{}{
+		"status":         "success",
+		"profile_id":     loaded.ID,
+		"source_id":      source.ID,
+		"name":           name,
+		"invocation":     subprofile.Invocation,
+		"thread_id":      root.ThreadID,
+		"thread_name":    root.ThreadName,
+		"start":          formatDuration(source.Analyzer.CaptureOffset(root.Block.Begin)),
+		"duration":       formatDuration(root.Block.Duration()),
+		"root_blocks":    subprofile.Roots,
+		"blocks_count":   loaded.Profile.TotalBlocksCount,
+		"total_duration": formatDuration(loaded.Profile.GetTotalDuration()),
+	}
+	if len(evicted) > 0 {
+		result["evicted_profiles"] = evicted
+	}
+
+	return jsonResult(result)
+}
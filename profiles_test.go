@@ -0,0 +1,621 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+	"github.com/yourusername/easyprofiler-mcp/parser"
+)
+
+// runCapture is a capture of one frame calling Update and Render for the given
+// number of milliseconds each
+func runCapture(updateMs, renderMs uint64) *proftest.Profile {
+	ms := uint64(time.Millisecond)
+	return &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame", "Update", "Render"),
+		Threads: []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{
+			{ID: 2, Begin: 0, End: updateMs * ms},
+			{ID: 3, Begin: updateMs * ms, End: (updateMs + renderMs) * ms},
+			{ID: 1, Begin: 0, End: (updateMs + renderMs) * ms},
+		}}},
+	}
+}
+
+// profileIDs returns the profile_id of each entry of list_profiles, and the current one
+func profileIDs(t *testing.T) ([]string, string) {
+	t.Helper()
+	var ids []string
+	current := ""
+	for _, entry := range callToolList(t, listProfilesHandler, nil) {
+		id := entry["profile_id"].(string)
+		ids = append(ids, id)
+		if entry["current"] == true {
+			current = id
+		}
+	}
+	return ids, current
+}
+
+func TestProfileRegistry(t *testing.T) {
+	resetRegistry(t)
+	capture := runCapture(10, 20)
+	path := capture.WriteFile(t)
+
+	first := callToolJSON(t, loadProfileHandler, map[string]interface{}{"file_path": path})
+	second := callToolJSON(t, loadProfileHandler, map[string]interface{}{"file_path": path})
+	if first["profile_id"] != "p1" || second["profile_id"] != "p2" {
+		t.Fatalf("profile IDs %v and %v, want p1 and p2", first["profile_id"], second["profile_id"])
+	}
+	if second["previous_profile_id"] != "p1" {
+		t.Errorf("previous_profile_id = %v, want p1", second["previous_profile_id"])
+	}
+
+	if ids, current := profileIDs(t); fmt.Sprint(ids) != "[p1 p2]" || current != "p2" {
+		t.Errorf("list_profiles = %v with current %s, want [p1 p2] with current p2", ids, current)
+	}
+
+	tests := []struct {
+		name        string
+		id          string
+		wantErr     bool
+		wantIDs     string
+		wantCurrent string
+	}{
+		{"unknown", "p9", true, "[p1 p2]", "p2"},
+		{"current falls back to latest", "p2", false, "[p1]", "p1"},
+		{"last", "p1", false, "[]", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, isError := callTool(t, unloadProfileHandler, map[string]interface{}{"profile_id": tt.id})
+			if isError != tt.wantErr {
+				t.Fatalf("unload_profile error = %t, want %t: %s", isError, tt.wantErr, text)
+			}
+			if ids, current := profileIDs(t); fmt.Sprint(ids) != tt.wantIDs || current != tt.wantCurrent {
+				t.Errorf("list_profiles = %v with current %q, want %s with current %q", ids, current, tt.wantIDs, tt.wantCurrent)
+			}
+		})
+	}
+}
+
+func TestProfileRegistryEvictsOldest(t *testing.T) {
+	resetRegistry(t)
+	path := runCapture(10, 20).WriteFile(t)
+
+	for i := 1; i <= maxLoadedProfiles+2; i++ {
+		summary := callToolJSON(t, loadProfileHandler, map[string]interface{}{"file_path": path})
+
+		var want interface{}
+		if i > maxLoadedProfiles {
+			want = []interface{}{fmt.Sprintf("p%d", i-maxLoadedProfiles)}
+		}
+		if fmt.Sprint(summary["evicted_profiles"]) != fmt.Sprint(want) {
+			t.Errorf("load %d evicted %v, want %v", i, summary["evicted_profiles"], want)
+		}
+	}
+
+	ids, current := profileIDs(t)
+	if len(ids) != maxLoadedProfiles || ids[0] != "p3" || current != fmt.Sprintf("p%d", maxLoadedProfiles+2) {
+		t.Errorf("list_profiles = %v with current %s after %d loads", ids, current, maxLoadedProfiles+2)
+	}
+}
+
+func TestCompareProfilesHandler(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, runCapture(10, 20), nil)
+	loadTestProfile(t, runCapture(15, 18), nil)
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr bool
+		want    []string
+	}{
+		{"inclusive", map[string]interface{}{"baseline_id": "p1"}, false, []string{"Update 5ms", "Frame 3ms", "Render -2ms"}},
+		{"exclusive", map[string]interface{}{"baseline_id": "p1", "exclusive": true}, false, []string{"Update 5ms", "Render -2ms", "Frame 0s"}},
+		{"limit", map[string]interface{}{"baseline_id": "p1", "limit": 1.0}, false, []string{"Update 5ms"}},
+		{"zero limit", map[string]interface{}{"baseline_id": "p1", "limit": 0.0}, false, nil},
+		{"explicit current", map[string]interface{}{"baseline_id": "p2", "current_id": "p1", "limit": 1.0}, false, []string{"Update -5ms"}},
+		{"negative limit", map[string]interface{}{"baseline_id": "p1", "limit": -1.0}, true, nil},
+		{"missing baseline", nil, true, nil},
+		{"unknown baseline", map[string]interface{}{"baseline_id": "p9"}, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, compareProfilesHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+			var got []string
+			for _, diff := range list(t, callToolJSON(t, compareProfilesHandler, tt.args), "functions") {
+				got = append(got, fmt.Sprintf("%s %s", diff["name"], diff["delta"]))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("functions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareProfilesHandlerByCallSite(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, runCapture(10, 20), nil)
+	loadTestProfile(t, runCapture(15, 18), nil)
+
+	tests := []struct {
+		byCallSite bool
+		want       []string // "caller>name delta"
+	}{
+		{false, []string{"<nil>>Update 5ms", "<nil>>Frame 3ms", "<nil>>Render -2ms"}},
+		{true, []string{"Frame>Update 5ms", "(thread root)>Frame 3ms", "Frame>Render -2ms"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.byCallSite), func(t *testing.T) {
+			result := callToolJSON(t, compareProfilesHandler, map[string]interface{}{"baseline_id": "p1", "by_call_site": tt.byCallSite})
+			if result["by_call_site"] != tt.byCallSite {
+				t.Errorf("by_call_site = %v, want %t", result["by_call_site"], tt.byCallSite)
+			}
+			var got []string
+			for _, diff := range list(t, result, "functions") {
+				got = append(got, fmt.Sprintf("%v>%s %s", diff["caller"], diff["name"], diff["delta"]))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("functions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareProfilesHandlerNormalize(t *testing.T) {
+	resetRegistry(t)
+	// The second run takes twice as long, keeping every function's share
+	loadTestProfile(t, runCapture(10, 20), nil)
+	loadTestProfile(t, runCapture(20, 40), nil)
+
+	tests := []struct {
+		normalize bool
+		want      []string // "name delta share-delta"
+	}{
+		{false, []string{"Frame 30ms <nil>", "Render 20ms <nil>", "Update 10ms <nil>"}},
+		{true, []string{"Frame 30ms 0.00", "Render 20ms 0.00", "Update 10ms 0.00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.normalize), func(t *testing.T) {
+			result := callToolJSON(t, compareProfilesHandler, map[string]interface{}{"baseline_id": "p1", "normalize": tt.normalize})
+			if result["normalized"] != tt.normalize {
+				t.Errorf("normalized = %v, want %t", result["normalized"], tt.normalize)
+			}
+			if _, ok := result["current_duration"]; ok != tt.normalize {
+				t.Errorf("current_duration = %v", result["current_duration"])
+			}
+			var got []string
+			for _, diff := range list(t, result, "functions") {
+				got = append(got, fmt.Sprintf("%s %s %v", diff["name"], diff["delta"], diff["share_delta_points"]))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("functions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegressionCheckHandler(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, runCapture(10, 20), nil)
+	loadTestProfile(t, runCapture(15, 21), nil)
+
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		verdict     string
+		regressions []string
+	}{
+		{"default threshold", map[string]interface{}{"baseline_id": "p1"}, "fail", []string{"Update", "Frame"}},
+		{"loose threshold", map[string]interface{}{"baseline_id": "p1", "threshold_percent": 25.0}, "fail", []string{"Update"}},
+		{"looser threshold", map[string]interface{}{"baseline_id": "p1", "threshold_percent": 60.0}, "pass", nil},
+		{"min duration", map[string]interface{}{"baseline_id": "p1", "min_duration_ms": 25.0}, "fail", []string{"Frame"}},
+		{"improvement", map[string]interface{}{"baseline_id": "p2", "current_id": "p1"}, "pass", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := callToolJSON(t, regressionCheckHandler, tt.args)
+			var got []string
+			for _, diff := range list(t, result, "regressions") {
+				got = append(got, diff["name"].(string))
+			}
+			if result["verdict"] != tt.verdict || fmt.Sprint(got) != fmt.Sprint(tt.regressions) {
+				t.Errorf("verdict %v with regressions %v, want %s with %v", result["verdict"], got, tt.verdict, tt.regressions)
+			}
+		})
+	}
+}
+
+func TestCompactProfileHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantErr   bool
+		removed   float64
+		remaining []string
+	}{
+		{"nothing to compact", nil, true, 0, nil},
+		{"unknown profile", map[string]interface{}{"profile_id": "p9", "drop_context_switches": true}, true, 0, nil},
+		{"drop context switches", map[string]interface{}{"drop_context_switches": true}, false, 0, []string{"Frame", "Render", "Update"}},
+		{"min block duration", map[string]interface{}{"min_block_duration_us": 15000.0}, false, 1, []string{"Frame", "Render"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, runCapture(10, 20), nil)
+
+			if tt.wantErr {
+				if text, isError := callTool(t, compactProfileHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+			result := callToolJSON(t, compactProfileHandler, tt.args)
+			if result["removed_blocks"] != tt.removed {
+				t.Errorf("removed_blocks = %v, want %v", result["removed_blocks"], tt.removed)
+			}
+
+			// The current analyzer is rebuilt on the compacted tree
+			var names []string
+			for _, hotspot := range callToolList(t, getHotspotsHandler, nil) {
+				names = append(names, hotspot["name"].(string))
+			}
+			sort.Strings(names)
+			if fmt.Sprint(names) != fmt.Sprint(tt.remaining) {
+				t.Errorf("hotspots after compacting = %v, want %v", names, tt.remaining)
+			}
+		})
+	}
+}
+
+func TestDiffSinceLastLoadHandler(t *testing.T) {
+	resetRegistry(t)
+	path := runCapture(10, 20).WriteFile(t)
+	other := runCapture(10, 20).WriteFile(t)
+	callToolJSON(t, loadProfileHandler, map[string]interface{}{"file_path": path})
+	callToolJSON(t, loadProfileHandler, map[string]interface{}{"file_path": other})
+	if err := os.WriteFile(path, runCapture(16, 15).Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	callToolJSON(t, loadProfileHandler, map[string]interface{}{"file_path": path})
+
+	tests := []struct {
+		name         string
+		args         map[string]interface{}
+		wantErr      bool
+		baseline     string
+		regressions  []string
+		improvements []string
+	}{
+		{"current profile", nil, false, "p1", []string{"Update 6ms", "Frame 1ms"}, []string{"Render -5ms"}},
+		{"limit", map[string]interface{}{"limit": 1.0}, false, "p1", []string{"Update 6ms"}, []string{"Render -5ms"}},
+		{"exclusive", map[string]interface{}{"exclusive": true}, false, "p1", []string{"Update 6ms"}, []string{"Render -5ms"}},
+		{"negative limit", map[string]interface{}{"limit": -1.0}, true, "", nil, nil},
+		{"first load", map[string]interface{}{"profile_id": "p1"}, true, "", nil, nil},
+		{"unknown profile", map[string]interface{}{"profile_id": "p9"}, true, "", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, diffSinceLastLoadHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+			result := callToolJSON(t, diffSinceLastLoadHandler, tt.args)
+			diffs := func(key string) []string {
+				var got []string
+				for _, diff := range list(t, result, key) {
+					got = append(got, fmt.Sprintf("%s %s", diff["name"], diff["delta"]))
+				}
+				return got
+			}
+			if result["baseline_id"] != tt.baseline {
+				t.Errorf("baseline_id = %v, want %s", result["baseline_id"], tt.baseline)
+			}
+			if got := diffs("regressions"); fmt.Sprint(got) != fmt.Sprint(tt.regressions) {
+				t.Errorf("regressions = %v, want %v", got, tt.regressions)
+			}
+			if got := diffs("improvements"); fmt.Sprint(got) != fmt.Sprint(tt.improvements) {
+				t.Errorf("improvements = %v, want %v", got, tt.improvements)
+			}
+		})
+	}
+
+	// The previous load is gone once unloaded
+	callToolJSON(t, unloadProfileHandler, map[string]interface{}{"profile_id": "p1"})
+	if text, isError := callTool(t, diffSinceLastLoadHandler, nil); !isError {
+		t.Errorf("diff against an unloaded profile succeeded: %s", text)
+	}
+}
+
+func TestAggregateHotspotsHandler(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, runCapture(10, 2), nil)
+	loadTestProfile(t, runCapture(10, 30), nil)
+	loadTestProfile(t, runCapture(10, 10), nil)
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr bool
+		want    []string // "name mean consistency"
+	}{
+		{"all profiles", nil, false, []string{"Frame 24ms noisy", "Render 14ms noisy", "Update 10ms stable"}},
+		{"limit", map[string]interface{}{"limit": 1.0}, false, []string{"Frame 24ms noisy"}},
+		{"zero limit", map[string]interface{}{"limit": 0.0}, false, nil},
+		{"chosen profiles", map[string]interface{}{"profile_ids": "p1, p3"}, false, []string{"Frame 16ms noisy", "Update 10ms stable", "Render 6ms noisy"}},
+		{"loose noise threshold", map[string]interface{}{"noisy_cv_percent": 200.0, "limit": 2.0}, false, []string{"Frame 24ms stable", "Render 14ms stable"}},
+		{"exclusive", map[string]interface{}{"exclusive": true}, false, []string{"Render 14ms noisy", "Update 10ms stable", "Frame 0s stable"}},
+		{"negative limit", map[string]interface{}{"limit": -1.0}, true, nil},
+		{"one profile", map[string]interface{}{"profile_ids": "p2"}, true, nil},
+		{"unknown profile", map[string]interface{}{"profile_ids": "p1,p9"}, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr {
+				if text, isError := callTool(t, aggregateHotspotsHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+			var got []string
+			for _, function := range list(t, callToolJSON(t, aggregateHotspotsHandler, tt.args), "functions") {
+				got = append(got, fmt.Sprintf("%s %s %s", function["name"], function["mean"], function["consistency"]))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("functions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConcurrentToolCalls runs loads, unloads and analyses against the same registry
+// at once. Run it with -race: tools must only touch shared state under registryMu.
+func TestConcurrentToolCalls(t *testing.T) {
+	resetRegistry(t)
+	srv := server.NewMCPServer("test", "1.0.0")
+	registerTools(srv)
+	registerProfileTools(srv)
+	path := runCapture(10, 20).WriteFile(t)
+
+	tests := []struct {
+		tool string
+		args map[string]interface{}
+	}{
+		{"load_profile", map[string]interface{}{"file_path": path}},
+		{"unload_profile", map[string]interface{}{"profile_id": "p1"}},
+		{"get_hotspots", map[string]interface{}{"limit": 5.0}},
+		{"get_thread_statistics", nil},
+		{"analyze_performance_issues", nil},
+		{"list_profiles", nil},
+		{"compare_profiles", map[string]interface{}{"baseline_id": "p2"}},
+		{"set_output_format", map[string]interface{}{"mode": "raw"}},
+		{"set_output_format", map[string]interface{}{"mode": "human"}},
+	}
+
+	const rounds = 10
+	var wg sync.WaitGroup
+	for round := 0; round < rounds; round++ {
+		for i, tt := range tests {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				params, _ := json.Marshal(map[string]interface{}{"name": tt.tool, "arguments": tt.args})
+				message := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":%s}`, id, params)
+				response := srv.HandleMessage(context.Background(), json.RawMessage(message))
+				if _, ok := response.(mcp.JSONRPCResponse); !ok {
+					t.Errorf("%s: response = %+v, want a result", tt.tool, response)
+				}
+			}(round*len(tests) + i)
+		}
+	}
+	wg.Wait()
+
+	// Every load registered exactly once, whatever the interleaving
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if nextProfileID != rounds+1 {
+		t.Errorf("next profile ID = %d, want %d after %d loads", nextProfileID, rounds+1, rounds)
+	}
+}
+
+func TestRegistryLockWrappers(t *testing.T) {
+	// Writers bump counter unsynchronized and readers read it: -race flags the
+	// test unless the wrappers exclude writers from each other and from readers
+	counter := 0
+	write := writeLocked(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		counter++
+		return mcp.NewToolResultText(""), nil
+	})
+	read := readLocked(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(fmt.Sprint(counter)), nil
+	})
+
+	tests := []struct {
+		name    string
+		handler server.ToolHandlerFunc
+		calls   int
+	}{
+		{"writers", write, 50},
+		{"readers", read, 50},
+	}
+
+	var wg sync.WaitGroup
+	for _, tt := range tests {
+		for i := 0; i < tt.calls; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := tt.handler(context.Background(), mcp.CallToolRequest{}); err != nil {
+					t.Errorf("%s: %v", tt.name, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if counter != tests[0].calls {
+		t.Errorf("counter = %d, want %d", counter, tests[0].calls)
+	}
+}
+
+func TestExtractSubprofileHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr bool
+		want    map[string]interface{}
+	}{
+		{"slowest call", map[string]interface{}{"name": "Update"}, false,
+			map[string]interface{}{"profile_id": "p2", "source_id": "p1", "invocation": 1.0, "blocks_count": 1.0, "root_blocks": 1.0, "duration": "10ms"}},
+		{"with siblings", map[string]interface{}{"name": "Update", "include_siblings": true}, false,
+			map[string]interface{}{"profile_id": "p2", "blocks_count": 2.0, "root_blocks": 2.0, "total_duration": "30ms"}},
+		{"whole frame", map[string]interface{}{"name": "Frame", "invocation": 1.0, "profile_id": "p1"}, false,
+			map[string]interface{}{"profile_id": "p2", "blocks_count": 3.0, "total_duration": "30ms"}},
+		{"missing name", nil, true, nil},
+		{"invocation zero", map[string]interface{}{"name": "Update", "invocation": 0.0}, true, nil},
+		{"invocation out of range", map[string]interface{}{"name": "Update", "invocation": 2.0}, true, nil},
+		{"unknown profile", map[string]interface{}{"name": "Update", "profile_id": "p9"}, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, runCapture(10, 20), nil)
+
+			if tt.wantErr {
+				if text, isError := callTool(t, extractSubprofileHandler, tt.args); !isError {
+					t.Errorf("succeeded: %s", text)
+				}
+				return
+			}
+
+			result := callToolJSON(t, extractSubprofileHandler, tt.args)
+			for key, value := range tt.want {
+				if result[key] != value {
+					t.Errorf("%s = %v, want %v", key, result[key], value)
+				}
+			}
+
+			// The slice is registered and current, with as many blocks as the subtree
+			ids, current := profileIDs(t)
+			if !reflect.DeepEqual(ids, []string{"p1", "p2"}) || current != "p2" {
+				t.Errorf("profiles %v with %s current, want p1 and p2 with p2 current", ids, current)
+			}
+			if got := currentProfile.(*parser.ProfileData).TotalBlocksCount; float64(got) != result["blocks_count"] {
+				t.Errorf("current profile has %d blocks, want %v", got, result["blocks_count"])
+			}
+		})
+	}
+}
+
+func TestIdleBlocksCarryOver(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler server.ToolHandlerFunc
+		args    map[string]interface{}
+	}{
+		{"compact", compactProfileHandler, map[string]interface{}{"drop_context_switches": true}},
+		{"extract", extractSubprofileHandler, map[string]interface{}{"name": "Frame"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, runCapture(10, 20), map[string]interface{}{"idle_blocks": "Render"})
+			callToolJSON(t, tt.handler, tt.args)
+
+			// The rebuilt or new analyzer keeps counting Render as idle
+			overview := callToolJSON(t, getOverviewHandler, nil)
+			if fmt.Sprint(overview["idle_blocks"]) != "[Render]" || overview["idle_time"] != "20ms" {
+				t.Errorf("idle_blocks %v for %v, want [Render] for 20ms", overview["idle_blocks"], overview["idle_time"])
+			}
+		})
+	}
+}
+
+func TestFindHistoricalAnomaliesHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantErr   string
+		baselines []string
+		want      []string // "name current historical runs ratio"
+	}{
+		{"tripled update", nil, "", []string{"p1", "p2"}, []string{"Update 30ms 10ms 2 3.00"}},
+		{"lower threshold", map[string]interface{}{"min_ratio": 1.5}, "", []string{"p1", "p2"}, []string{"Update 30ms 10ms 2 3.00", "Frame 50ms 30ms 2 1.67"}},
+		{"chosen baselines", map[string]interface{}{"baseline_ids": "p2, p3"}, "", []string{"p2"}, []string{"Update 30ms 10ms 1 3.00"}},
+		{"against an earlier run", map[string]interface{}{"profile_id": "p1", "min_ratio": 0.5}, "", []string{"p2", "p3"}, []string{"Render 20ms 20ms 2 1.00", "Frame 30ms 40ms 2 0.75", "Update 10ms 20ms 2 0.50"}},
+		{"zero limit", map[string]interface{}{"limit": 0.0}, "", []string{"p1", "p2"}, nil},
+		{"negative limit", map[string]interface{}{"limit": -1.0}, "limit must not be negative", nil, nil},
+		{"only the current profile", map[string]interface{}{"baseline_ids": "p3"}, "No baseline profiles", nil, nil},
+		{"unknown baseline", map[string]interface{}{"baseline_ids": "p9"}, "p9", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			loadTestProfile(t, runCapture(10, 20), nil)
+			loadTestProfile(t, runCapture(10, 20), nil)
+			loadTestProfile(t, runCapture(30, 20), nil)
+
+			if tt.wantErr != "" {
+				if text, isError := callTool(t, findHistoricalAnomaliesHandler, tt.args); !isError || !strings.Contains(text, tt.wantErr) {
+					t.Errorf("result %q (error %t), want an error containing %q", text, isError, tt.wantErr)
+				}
+				return
+			}
+
+			result := callToolJSON(t, findHistoricalAnomaliesHandler, tt.args)
+			if fmt.Sprint(result["baseline_ids"]) != fmt.Sprint(tt.baselines) {
+				t.Errorf("baseline_ids = %v, want %v", result["baseline_ids"], tt.baselines)
+			}
+			var got []string
+			for _, function := range list(t, result, "functions") {
+				got = append(got, fmt.Sprintf("%v %v %v %v %v", function["name"], function["current_avg"], function["historical_avg"], function["baseline_runs"], function["ratio"]))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("functions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindHistoricalAnomaliesHandlerSkipsSubprofiles(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, runCapture(10, 20), nil)
+	// A slice of p1 is not another run of the workload, so it isn't a baseline
+	callToolJSON(t, extractSubprofileHandler, map[string]interface{}{"name": "Update"})
+	loadTestProfile(t, runCapture(30, 20), nil)
+
+	result := callToolJSON(t, findHistoricalAnomaliesHandler, nil)
+	if fmt.Sprint(result["baseline_ids"]) != "[p1]" {
+		t.Errorf("baseline_ids = %v, want [p1]", result["baseline_ids"])
+	}
+}
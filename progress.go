@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yourusername/easyprofiler-mcp/analyzer"
+)
+
+// progressReporter returns a sink that forwards analysis progress to the client as
+// notifications/progress messages. It returns nil, which the analyzer treats as
+// "no progress wanted", unless the client sent a progress token with the request
+// and the server can be reached from ctx. Delivery is best-effort: a full
+// notification queue drops the update rather than stalling the analysis.
+func progressReporter(ctx context.Context, request mcp.CallToolRequest) analyzer.ProgressFunc {
+	srv, token := progressTarget(ctx, request)
+	if srv == nil {
+		return nil
+	}
+
+	return func(done, total int) {
+		_ = srv.SendNotificationToClient("notifications/progress", map[string]interface{}{
+			"progressToken": token,
+			"progress":      done,
+			"total":         total,
+		})
+	}
+}
+
+// phaseReporter is progressReporter for parsing: it forwards the percent of the
+// file read, with the current phase (e.g. "reading thread 3/8") as the message
+func phaseReporter(ctx context.Context, request mcp.CallToolRequest) func(phase string, percent int) {
+	srv, token := progressTarget(ctx, request)
+	if srv == nil {
+		return nil
+	}
+
+	return func(phase string, percent int) {
+		_ = srv.SendNotificationToClient("notifications/progress", map[string]interface{}{
+			"progressToken": token,
+			"progress":      percent,
+			"total":         100,
+			"message":       phase,
+		})
+	}
+}
+
+// progressTarget returns the server and token to send progress for request to,
+// or a nil server if the client didn't ask for progress
+func progressTarget(ctx context.Context, request mcp.CallToolRequest) (*server.MCPServer, mcp.ProgressToken) {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil, nil
+	}
+	return server.ServerFromContext(ctx), request.Params.Meta.ProgressToken
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yourusername/easyprofiler-mcp/internal/proftest"
+)
+
+// lineBuffer collects the lines a stdio server writes from several goroutines
+type lineBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lineBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lineBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+func TestProgressReporter(t *testing.T) {
+	srv := server.NewMCPServer("test", "1.0.0")
+
+	tests := []struct {
+		name    string
+		request string
+		ctx     context.Context
+		want    bool
+	}{
+		{"no meta", `{"params":{}}`, context.Background(), false},
+		{"no token", `{"params":{"_meta":{}}}`, context.Background(), false},
+		{"no server", `{"params":{"_meta":{"progressToken":"t"}}}`, context.Background(), false},
+		{"token and server", `{"params":{"_meta":{"progressToken":"t"}}}`, contextWithServer(t, srv), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request mcp.CallToolRequest
+			if err := json.Unmarshal([]byte(tt.request), &request); err != nil {
+				t.Fatal(err)
+			}
+			if got := progressReporter(tt.ctx, request) != nil; got != tt.want {
+				t.Errorf("progressReporter returned a sink = %t, want %t", got, tt.want)
+			}
+			if got := phaseReporter(tt.ctx, request) != nil; got != tt.want {
+				t.Errorf("phaseReporter returned a sink = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// contextWithServer returns a context carrying srv, captured from inside a tool call
+// since mcp-go only attaches the server while handling a message
+func contextWithServer(t *testing.T, srv *server.MCPServer) context.Context {
+	t.Helper()
+	var captured context.Context
+	srv.AddTool(mcp.NewTool("capture_context"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		captured = ctx
+		return mcp.NewToolResultText(""), nil
+	})
+	srv.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"capture_context"}}`))
+	if captured == nil {
+		t.Fatal("tool was not called")
+	}
+	return captured
+}
+
+func TestProgressNotifications(t *testing.T) {
+	resetRegistry(t)
+	loadTestProfile(t, &proftest.Profile{
+		Descriptors: proftest.Descriptors("Frame"),
+		Threads:     []proftest.Thread{{ID: 1, Name: "Main", Blocks: []proftest.Block{{ID: 1, Begin: 0, End: 1000}}}},
+	}, nil)
+
+	tests := []struct {
+		tool  string
+		token interface{}
+	}{
+		{"analyze_performance_issues", "issues"},
+		{"get_overview", 7.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			srv := server.NewMCPServer("test", "1.0.0")
+			registerTools(srv)
+
+			token, _ := json.Marshal(tt.token)
+			input := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":%q,"_meta":{"progressToken":%s}}}`+"\n", tt.tool, token)
+			output := &lineBuffer{}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if err := server.NewStdioServer(srv).Listen(ctx, strings.NewReader(input), output); err != nil {
+				t.Fatalf("Listen: %v", err)
+			}
+
+			// Notifications are forwarded asynchronously, so wait for the last step
+			var progress []float64
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				progress = progress[:0]
+				total := 0.0
+				for _, line := range output.lines() {
+					var message struct {
+						Method string                 `json:"method"`
+						Params map[string]interface{} `json:"params"`
+					}
+					if json.Unmarshal([]byte(line), &message) != nil || message.Method != "notifications/progress" {
+						continue
+					}
+					if message.Params["progressToken"] != tt.token {
+						t.Fatalf("notification token = %v, want %v", message.Params["progressToken"], tt.token)
+					}
+					progress = append(progress, message.Params["progress"].(float64))
+					total = message.Params["total"].(float64)
+				}
+				if n := len(progress); n > 0 && progress[n-1] == total {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("got progress %v, never reaching the total", progress)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			for i, done := range progress {
+				if done != float64(i+1) {
+					t.Fatalf("progress = %v, want 1, 2, ... in order", progress)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/analyzer"
+)
+
+// Metric families written by export_prometheus. The names and labels are part of
+// the tool's contract: dashboards and alerts built on them must keep working, so
+// rename nothing here, only add.
+const (
+	metricCaptureDuration = "easyprofiler_capture_duration_seconds"
+	metricFunctionSelf    = "easyprofiler_function_self_seconds"
+	metricFunctionTotal   = "easyprofiler_function_inclusive_seconds"
+	metricFunctionCalls   = "easyprofiler_function_calls"
+	metricThreadBusy      = "easyprofiler_thread_busy_seconds"
+	metricThreadBlocks    = "easyprofiler_thread_blocks"
+	metricIssues          = "easyprofiler_issues"
+)
+
+// renderPrometheus formats the capture's key aggregates in the Prometheus text
+// exposition format: the capture duration, the given top functions by self time,
+// every thread's busy time and block count, and the number of issues per severity.
+// All families are gauges, as each export describes a single capture.
+func renderPrometheus(duration time.Duration, functions []*analyzer.BlockInfo, threads []*analyzer.ThreadStats, issues []*analyzer.PerformanceIssue) string {
+	var b strings.Builder
+
+	writePrometheusFamily(&b, metricCaptureDuration, "Duration of the capture.")
+	writePrometheusSample(&b, metricCaptureDuration, nil, duration.Seconds())
+
+	functionLabels := func(info *analyzer.BlockInfo) [][2]string {
+		return [][2]string{
+			{"function", info.Name},
+			{"file", info.File},
+			{"line", strconv.Itoa(int(info.Line))},
+		}
+	}
+	writePrometheusFamily(&b, metricFunctionSelf, "Time spent in a function's own code, excluding nested blocks.")
+	for _, info := range functions {
+		writePrometheusSample(&b, metricFunctionSelf, functionLabels(info), info.SelfDuration.Seconds())
+	}
+	writePrometheusFamily(&b, metricFunctionTotal, "Time spent in a function including nested blocks; recursive calls count once.")
+	for _, info := range functions {
+		writePrometheusSample(&b, metricFunctionTotal, functionLabels(info), info.Duration.Seconds())
+	}
+	writePrometheusFamily(&b, metricFunctionCalls, "Number of calls of a function.")
+	for _, info := range functions {
+		writePrometheusSample(&b, metricFunctionCalls, functionLabels(info), float64(info.CallCount))
+	}
+
+	threadLabels := func(stats *analyzer.ThreadStats) [][2]string {
+		return [][2]string{
+			{"thread", stats.ThreadName},
+			{"thread_id", strconv.FormatUint(stats.ThreadID, 10)},
+		}
+	}
+	writePrometheusFamily(&b, metricThreadBusy, "Summed duration of a thread's top-level blocks.")
+	for _, stats := range threads {
+		writePrometheusSample(&b, metricThreadBusy, threadLabels(stats), stats.TotalDuration.Seconds())
+	}
+	writePrometheusFamily(&b, metricThreadBlocks, "Number of blocks recorded on a thread.")
+	for _, stats := range threads {
+		writePrometheusSample(&b, metricThreadBlocks, threadLabels(stats), float64(stats.BlockCount))
+	}
+
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		counts[issue.Severity]++
+	}
+	writePrometheusFamily(&b, metricIssues, "Number of detected performance issues by severity.")
+	for _, severity := range markdownSeverities {
+		writePrometheusSample(&b, metricIssues, [][2]string{{"severity", severity}}, float64(counts[severity]))
+	}
+
+	return b.String()
+}
+
+// writePrometheusFamily writes the HELP and TYPE lines that start a gauge family
+func writePrometheusFamily(b *strings.Builder, name, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s gauge\n", name)
+}
+
+// writePrometheusSample writes one sample line with its labels in the given order
+func writePrometheusSample(b *strings.Builder, name string, labels [][2]string, value float64) {
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", label[0], prometheusLabelValue(label[1]))
+		}
+		b.WriteByte('}')
+	}
+	fmt.Fprintf(b, " %s\n", strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// prometheusLabelValue escapes a label value as the exposition format requires
+func prometheusLabelValue(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	return strings.ReplaceAll(s, "\n", "\\n")
+}
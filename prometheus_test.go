@@ -0,0 +1,426 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/easyprofiler-mcp/analyzer"
+)
+
+// promSample is one sample line of a Prometheus text exposition
+type promSample struct {
+	labels map[string]string
+	value  float64
+}
+
+// promFamily is a metric family with the HELP and TYPE lines that introduced it
+type promFamily struct {
+	help, typ string
+	samples   []promSample
+}
+
+var (
+	promMetricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	promLabelName  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	promTypes      = map[string]bool{"counter": true, "gauge": true, "histogram": true, "summary": true, "untyped": true}
+)
+
+// parsePrometheusText checks text against the Prometheus text exposition format
+// (version 0.0.4) and returns its families by name. Beyond the line syntax it
+// enforces what scrapers reject: HELP and TYPE at most once and before the
+// family's samples, a family's samples kept together, and no repeated label sets.
+func parsePrometheusText(text string) (map[string]*promFamily, error) {
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		return nil, fmt.Errorf("text doesn't end with a line feed")
+	}
+
+	families := make(map[string]*promFamily)
+	family := func(name string) *promFamily {
+		if families[name] == nil {
+			families[name] = &promFamily{}
+		}
+		return families[name]
+	}
+	closed := make(map[string]bool) // Families whose samples ended
+	current := ""
+	seen := make(map[string]bool) // name plus label set of every sample
+
+	for i, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		lineNo := i + 1
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || (fields[1] != "HELP" && fields[1] != "TYPE") {
+				continue // Plain comment
+			}
+			if len(fields) < 3 || !promMetricName.MatchString(fields[2]) {
+				return nil, fmt.Errorf("line %d: %s without a valid metric name: %q", lineNo, fields[1], line)
+			}
+			name := fields[2]
+			if closed[name] || current == name {
+				return nil, fmt.Errorf("line %d: %s for %s after its samples", lineNo, fields[1], name)
+			}
+			f := family(name)
+			if fields[1] == "HELP" {
+				if f.help != "" {
+					return nil, fmt.Errorf("line %d: second HELP for %s", lineNo, name)
+				}
+				f.help = strings.TrimPrefix(line, "# HELP "+name+" ")
+				if f.help == "" || f.help == line {
+					return nil, fmt.Errorf("line %d: empty HELP for %s", lineNo, name)
+				}
+				continue
+			}
+			if f.typ != "" {
+				return nil, fmt.Errorf("line %d: second TYPE for %s", lineNo, name)
+			}
+			if len(fields) != 4 || !promTypes[fields[3]] {
+				return nil, fmt.Errorf("line %d: bad TYPE line %q", lineNo, line)
+			}
+			f.typ = fields[3]
+			continue
+		}
+
+		name, labels, rest, err := parsePromSampleHead(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		if name != current {
+			if closed[name] {
+				return nil, fmt.Errorf("line %d: samples of %s are split", lineNo, name)
+			}
+			if current != "" {
+				closed[current] = true
+			}
+			current = name
+		}
+
+		fields := strings.Split(rest, " ")
+		if len(fields) < 1 || len(fields) > 2 || fields[0] == "" {
+			return nil, fmt.Errorf("line %d: want a value and an optional timestamp after the labels: %q", lineNo, line)
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad value %q", lineNo, fields[0])
+		}
+		if len(fields) == 2 {
+			if _, err := strconv.ParseInt(fields[1], 10, 64); err != nil {
+				return nil, fmt.Errorf("line %d: bad timestamp %q", lineNo, fields[1])
+			}
+		}
+
+		keys := make([]string, 0, len(labels))
+		for key := range labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		identity := name
+		for _, key := range keys {
+			identity += "\x00" + key + "=" + labels[key]
+		}
+		if seen[identity] {
+			return nil, fmt.Errorf("line %d: repeated sample %q", lineNo, line)
+		}
+		seen[identity] = true
+
+		f := family(name)
+		f.samples = append(f.samples, promSample{labels: labels, value: value})
+	}
+
+	return families, nil
+}
+
+// parsePromSampleHead splits a sample line into its metric name, its labels and
+// the text after them, unescaping label values
+func parsePromSampleHead(line string) (string, map[string]string, string, error) {
+	end := strings.IndexAny(line, "{ ")
+	if end < 0 {
+		return "", nil, "", fmt.Errorf("sample without a value: %q", line)
+	}
+	name := line[:end]
+	if !promMetricName.MatchString(name) {
+		return "", nil, "", fmt.Errorf("bad metric name %q", name)
+	}
+
+	labels := make(map[string]string)
+	rest := line[end:]
+	if strings.HasPrefix(rest, "{") {
+		rest = rest[1:]
+		for !strings.HasPrefix(rest, "}") {
+			eq := strings.Index(rest, "=")
+			if eq < 0 {
+				return "", nil, "", fmt.Errorf("label without a value in %q", line)
+			}
+			label := rest[:eq]
+			if !promLabelName.MatchString(label) || strings.HasPrefix(label, "__") {
+				return "", nil, "", fmt.Errorf("bad label name %q", label)
+			}
+			if _, ok := labels[label]; ok {
+				return "", nil, "", fmt.Errorf("repeated label %q", label)
+			}
+			rest = rest[eq+1:]
+			if !strings.HasPrefix(rest, `"`) {
+				return "", nil, "", fmt.Errorf("unquoted value of label %q", label)
+			}
+
+			var value strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				switch {
+				case rest[i] == '\n':
+					return "", nil, "", fmt.Errorf("raw line feed in label %q", label)
+				case rest[i] != '\\':
+					value.WriteByte(rest[i])
+				case i+1 < len(rest) && rest[i+1] == '\\':
+					value.WriteByte('\\')
+					i++
+				case i+1 < len(rest) && rest[i+1] == '"':
+					value.WriteByte('"')
+					i++
+				case i+1 < len(rest) && rest[i+1] == 'n':
+					value.WriteByte('\n')
+					i++
+				default:
+					return "", nil, "", fmt.Errorf("bad escape in label %q", label)
+				}
+			}
+			if i == len(rest) {
+				return "", nil, "", fmt.Errorf("unterminated value of label %q", label)
+			}
+			labels[label] = value.String()
+			rest = rest[i+1:]
+
+			if strings.HasPrefix(rest, ",") {
+				rest = rest[1:]
+			} else if !strings.HasPrefix(rest, "}") {
+				return "", nil, "", fmt.Errorf("want ',' or '}' after label %q", label)
+			}
+		}
+		rest = rest[1:]
+	}
+
+	if !strings.HasPrefix(rest, " ") {
+		return "", nil, "", fmt.Errorf("no space before the value in %q", line)
+	}
+	return name, labels, strings.TrimLeft(rest, " "), nil
+}
+
+func TestParsePrometheusTextRejects(t *testing.T) {
+	// The checker has to catch malformed output for the export tests to mean anything
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"no trailing line feed", "m 1"},
+		{"bad metric name", "1m 1\n"},
+		{"no value", "m\n"},
+		{"bad value", "m one\n"},
+		{"extra field", "m 1 2 3\n"},
+		{"unquoted label", "m{a=b} 1\n"},
+		{"bad escape", `m{a="\t"} 1` + "\n"},
+		{"unterminated label", `m{a="x} 1` + "\n"},
+		{"reserved label", `m{__a="x"} 1` + "\n"},
+		{"repeated label", `m{a="x",a="y"} 1` + "\n"},
+		{"repeated sample", "m{a=\"x\"} 1\nm{a=\"x\"} 2\n"},
+		{"split family", "m 1\nn 1\nm{a=\"x\"} 1\n"},
+		{"type after samples", "m 1\n# TYPE m gauge\n"},
+		{"second type", "# TYPE m gauge\n# TYPE m gauge\nm 1\n"},
+		{"unknown type", "# TYPE m meter\nm 1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parsePrometheusText(tt.text); err == nil {
+				t.Errorf("accepted %q", tt.text)
+			}
+		})
+	}
+
+	valid := "# HELP m A metric.\n# TYPE m gauge\nm{a=\"x \\\"y\\\"\\\\z\\n\"} -Inf\nm 1e-09 1700000000\n# a comment\n"
+	families, err := parsePrometheusText(valid)
+	if err != nil {
+		t.Fatalf("rejected valid text: %v", err)
+	}
+	if m := families["m"]; m.typ != "gauge" || len(m.samples) != 2 || m.samples[0].labels["a"] != "x \"y\"\\z\n" || !math.IsInf(m.samples[0].value, -1) {
+		t.Errorf("parsed %+v", m)
+	}
+}
+
+// prometheusFamilies lists the families export_prometheus promises
+var prometheusFamilies = []string{
+	metricCaptureDuration,
+	metricFunctionCalls,
+	metricFunctionTotal,
+	metricFunctionSelf,
+	metricIssues,
+	metricThreadBlocks,
+	metricThreadBusy,
+}
+
+// checkPrometheusFamilies parses metrics and fails unless it holds exactly the
+// documented families, all of them gauges with help text
+func checkPrometheusFamilies(t *testing.T, metrics string) map[string]*promFamily {
+	t.Helper()
+	families, err := parsePrometheusText(metrics)
+	if err != nil {
+		t.Fatalf("invalid exposition: %v\n%s", err, metrics)
+	}
+	var names []string
+	for name, family := range families {
+		names = append(names, name)
+		if family.typ != "gauge" || family.help == "" {
+			t.Errorf("%s has type %q and help %q, want a gauge with help", name, family.typ, family.help)
+		}
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, prometheusFamilies) {
+		t.Fatalf("families = %v, want %v", names, prometheusFamilies)
+	}
+	return families
+}
+
+func TestRenderPrometheus(t *testing.T) {
+	functions := []*analyzer.BlockInfo{
+		{Name: `Say "hi"` + "\n", File: `C:\src\chat.cpp`, Line: 7, SelfDuration: 2 * time.Millisecond, Duration: 3 * time.Millisecond, CallCount: 4},
+		{Name: "Update", File: "game.cpp", Line: 10, SelfDuration: time.Millisecond, Duration: time.Millisecond, CallCount: 1},
+	}
+	threads := []*analyzer.ThreadStats{
+		{ThreadID: 1, ThreadName: "Main", TotalDuration: 5 * time.Millisecond, BlockCount: 5},
+		{ThreadID: 2, ThreadName: "", BlockCount: 0},
+	}
+	issues := []*analyzer.PerformanceIssue{{Severity: "high"}, {Severity: "low"}, {Severity: "high"}}
+
+	families := checkPrometheusFamilies(t, renderPrometheus(10*time.Millisecond, functions, threads, issues))
+
+	got := func(name string) []string {
+		var samples []string
+		for _, sample := range families[name].samples {
+			var labels []string
+			for key, value := range sample.labels {
+				labels = append(labels, key+"="+value)
+			}
+			sort.Strings(labels)
+			samples = append(samples, fmt.Sprintf("%v %v", labels, sample.value))
+		}
+		return samples
+	}
+
+	tests := []struct {
+		family string
+		want   []string
+	}{
+		{metricCaptureDuration, []string{"[] 0.01"}},
+		{metricFunctionSelf, []string{"[file=C:\\src\\chat.cpp function=Say \"hi\"\n line=7] 0.002", "[file=game.cpp function=Update line=10] 0.001"}},
+		{metricFunctionTotal, []string{"[file=C:\\src\\chat.cpp function=Say \"hi\"\n line=7] 0.003", "[file=game.cpp function=Update line=10] 0.001"}},
+		{metricFunctionCalls, []string{"[file=C:\\src\\chat.cpp function=Say \"hi\"\n line=7] 4", "[file=game.cpp function=Update line=10] 1"}},
+		{metricThreadBusy, []string{"[thread=Main thread_id=1] 0.005", "[thread= thread_id=2] 0"}},
+		{metricThreadBlocks, []string{"[thread=Main thread_id=1] 5", "[thread= thread_id=2] 0"}},
+		{metricIssues, []string{"[severity=high] 2", "[severity=medium] 0", "[severity=low] 1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.family, func(t *testing.T) {
+			if samples := got(tt.family); !reflect.DeepEqual(samples, tt.want) {
+				t.Errorf("samples = %q, want %q", samples, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPrometheusEmpty(t *testing.T) {
+	// A capture with nothing in it still exposes every family, with zero counts
+	families := checkPrometheusFamilies(t, renderPrometheus(0, nil, nil, nil))
+	if len(families[metricFunctionSelf].samples) != 0 || len(families[metricThreadBusy].samples) != 0 {
+		t.Errorf("function or thread samples without functions or threads")
+	}
+	if samples := families[metricIssues].samples; len(samples) != 3 || samples[0].value != 0 {
+		t.Errorf("issue samples = %+v, want three zeros", samples)
+	}
+	if value := families[metricCaptureDuration].samples[0].value; value != 0 {
+		t.Errorf("capture duration = %v, want 0", value)
+	}
+}
+
+func TestExportPrometheusHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantErr   bool
+		functions []string // function label of each self-time sample
+	}{
+		{"default limit", nil, false, []string{"Render", "Update", "Frame"}},
+		{"limit", map[string]interface{}{"limit": 1.0}, false, []string{"Render"}},
+		{"zero limit", map[string]interface{}{"limit": 0.0}, false, nil},
+		{"negative limit", map[string]interface{}{"limit": -1.0}, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			p := runCapture(10, 20)
+			p.End = uint64(40 * time.Millisecond)
+			loadTestProfile(t, p, nil)
+
+			text, isError := callTool(t, exportPrometheusHandler, tt.args)
+			if isError != tt.wantErr {
+				t.Fatalf("isError = %t, want %t: %s", isError, tt.wantErr, text)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			families := checkPrometheusFamilies(t, text)
+			var functions []string
+			for _, sample := range families[metricFunctionSelf].samples {
+				functions = append(functions, sample.labels["function"])
+			}
+			if !reflect.DeepEqual(functions, tt.functions) {
+				t.Errorf("functions = %v, want %v", functions, tt.functions)
+			}
+			if duration := families[metricCaptureDuration].samples[0].value; duration != 0.04 {
+				t.Errorf("capture duration = %v, want 0.04", duration)
+			}
+			if threads := families[metricThreadBlocks].samples; len(threads) != 1 || threads[0].labels["thread"] != "Main" || threads[0].value != 3 {
+				t.Errorf("thread blocks = %+v, want 3 on Main", threads)
+			}
+		})
+	}
+}
+
+func TestExportPrometheusHandlerOutputPath(t *testing.T) {
+	resetRegistry(t)
+	if text, isError := callTool(t, exportPrometheusHandler, nil); !isError {
+		t.Errorf("succeeded without a profile: %s", text)
+	}
+
+	loadTestProfile(t, runCapture(10, 20), nil)
+	path := filepath.Join(t.TempDir(), "profile.prom")
+	text, isError := callTool(t, exportPrometheusHandler, map[string]interface{}{"output_path": path})
+	if isError {
+		t.Fatalf("export failed: %s", text)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != text {
+		t.Errorf("file holds %q, want the returned metrics %q", written, text)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing", "profile.prom")
+	if text, isError := callTool(t, exportPrometheusHandler, map[string]interface{}{"output_path": missing}); !isError {
+		t.Errorf("wrote into a missing directory: %s", text)
+	}
+}
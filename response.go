@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultMaxResponseBytes caps the size of a single tool response
+const defaultMaxResponseBytes = 1 << 20
+
+// maxResponseBytes returns the response size cap, honoring EASYPROFILER_MAX_RESPONSE_BYTES if set
+func maxResponseBytes() int {
+	if value := os.Getenv("EASYPROFILER_MAX_RESPONSE_BYTES"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultMaxResponseBytes
+}
+
+// jsonResult marshals v as the tool's text result, indented unless the output
+// format is raw, after truncating long function names if configured. Marshal failures are reported as tool errors, and output larger
+// than maxResponseBytes is truncated with a note so the transport never receives
+// an oversized message.
+func jsonResult(v interface{}) (*mcp.CallToolResult, error) {
+	if outputFormat.MaxNameLength > 0 {
+		shortenNames(v)
+	}
+
+	var data []byte
+	var err error
+	if outputFormat.Raw {
+		data, err = json.Marshal(v)
+	} else {
+		data, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode result: %v", err)), nil
+	}
+
+	limit := maxResponseBytes()
+	if len(data) <= limit {
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	// Cut on a rune boundary so the truncated text stays valid UTF-8
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(data[cut]) {
+		cut--
+	}
+
+	note := fmt.Sprintf("\n\n[TRUNCATED: response is %d bytes, limit is %d. "+
+		"Use a smaller limit, narrow the query, or export the data to a file (e.g. export_pprof).]",
+		len(data), limit)
+	return mcp.NewToolResultText(string(data[:cut]) + note), nil
+}
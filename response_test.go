@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestMaxResponseBytes(t *testing.T) {
+	tests := []struct {
+		env  string
+		want int
+	}{
+		{"", defaultMaxResponseBytes},
+		{"2048", 2048},
+		{"0", defaultMaxResponseBytes},
+		{"-5", defaultMaxResponseBytes},
+		{"lots", defaultMaxResponseBytes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv("EASYPROFILER_MAX_RESPONSE_BYTES", tt.env)
+			if got := maxResponseBytes(); got != tt.want {
+				t.Errorf("maxResponseBytes() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     interface{}
+		limit     string
+		raw       bool
+		wantErr   bool
+		wantText  string
+		truncated bool
+	}{
+		{"indented", map[string]int{"a": 1}, "", false, false, "{\n  \"a\": 1\n}", false},
+		{"raw", map[string]int{"a": 1}, "", true, false, `{"a":1}`, false},
+		{"marshal error", map[string]float64{"a": math.NaN()}, "", false, true, "", false},
+		{"at the limit", "abcd", "6", false, false, `"abcd"`, false},
+		{"over the limit", "abcdef", "6", false, false, `"abcde`, true},
+		{"cut on a rune boundary", "aé", "3", false, false, `"a`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			outputFormat.Raw = tt.raw
+			t.Setenv("EASYPROFILER_MAX_RESPONSE_BYTES", tt.limit)
+
+			result, err := jsonResult(tt.value)
+			if err != nil {
+				t.Fatalf("jsonResult returned an error: %v", err)
+			}
+			if result.IsError != tt.wantErr {
+				t.Fatalf("IsError = %t, want %t", result.IsError, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			text := result.Content[0].(mcp.TextContent).Text
+			body, note, truncated := strings.Cut(text, "\n\n[TRUNCATED:")
+			if body != tt.wantText || truncated != tt.truncated {
+				t.Errorf("text = %q (truncated %t), want %q (truncated %t)", body, truncated, tt.wantText, tt.truncated)
+			}
+			if truncated && !strings.Contains(note, "limit is "+tt.limit) {
+				t.Errorf("truncation note %q doesn't give the limit", note)
+			}
+			if !utf8.ValidString(text) {
+				t.Errorf("text %q is not valid UTF-8", text)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolTiming accumulates how long the calls of one tool took
+type toolTiming struct {
+	Calls int
+	Total time.Duration
+	Last  time.Duration
+	Max   time.Duration
+}
+
+// serverStats records how long the server spends parsing and analyzing, so users
+// running it on large files can tell where the time goes. It has its own lock:
+// tools holding the registry's read lock record their timings concurrently.
+var serverStats = struct {
+	mu sync.Mutex
+
+	startedAt time.Time
+
+	parses        int
+	lastParse     time.Duration
+	lastParseFile string
+	lastParseAt   time.Time
+
+	tools map[string]*toolTiming
+
+	// peakHeap is the largest live heap seen after a parse or tool call. The
+	// runtime doesn't track a true peak, so short spikes within a call are missed.
+	peakHeap uint64
+}{
+	startedAt: time.Now(),
+	tools:     make(map[string]*toolTiming),
+}
+
+// timed wraps a tool handler to record how long each call takes
+func timed(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		defer func() {
+			recordToolCall(request.Params.Name, time.Since(start))
+		}()
+		return handler(ctx, request)
+	}
+}
+
+// recordToolCall adds one call of the named tool to the stats
+func recordToolCall(name string, elapsed time.Duration) {
+	heap := heapInUse()
+
+	serverStats.mu.Lock()
+	defer serverStats.mu.Unlock()
+
+	timing, ok := serverStats.tools[name]
+	if !ok {
+		timing = &toolTiming{}
+		serverStats.tools[name] = timing
+	}
+	timing.Calls++
+	timing.Total += elapsed
+	timing.Last = elapsed
+	timing.Max = max(timing.Max, elapsed)
+	serverStats.peakHeap = max(serverStats.peakHeap, heap)
+}
+
+// recordParse records a parse of filePath, excluding cache hits
+func recordParse(filePath string, elapsed time.Duration) {
+	heap := heapInUse()
+
+	serverStats.mu.Lock()
+	defer serverStats.mu.Unlock()
+
+	serverStats.parses++
+	serverStats.lastParse = elapsed
+	serverStats.lastParseFile = filePath
+	serverStats.lastParseAt = time.Now()
+	serverStats.peakHeap = max(serverStats.peakHeap, heap)
+}
+
+// heapInUse returns the bytes of live heap objects
+func heapInUse() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+func registerStatsTools(s *server.MCPServer) {
+	// Report server-side analysis performance
+	serverStatsTool := mcp.NewTool("get_server_stats",
+		mcp.WithDescription("Report the server's own performance: how long the last parse took, how long each tool's calls took, heap memory (current and peak) and how many profiles are loaded. Use it to understand slowness on large files"),
+	)
+
+	s.AddTool(serverStatsTool, readLocked(getServerStatsHandler))
+}
+
+func getServerStatsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	serverStats.mu.Lock()
+	defer serverStats.mu.Unlock()
+
+	names := make([]string, 0, len(serverStats.tools))
+	for name := range serverStats.tools {
+		names = append(names, name)
+	}
+	// Most total time first
+	sort.Slice(names, func(i, j int) bool {
+		ti, tj := serverStats.tools[names[i]].Total, serverStats.tools[names[j]].Total
+		if ti != tj {
+			return ti > tj
+		}
+		return names[i] < names[j]
+	})
+
+	// Format results
+	tools := make([]map[string]interface{}, len(names))
+	for i, name := range names {
+		timing := serverStats.tools[name]
+		tools[i] = map[string]interface{}{
+			"tool":           name,
+			"calls":          timing.Calls,
+			"last_duration":  formatDuration(timing.Last),
+			"avg_duration":   formatDuration(timing.Total / time.Duration(timing.Calls)),
+			"max_duration":   formatDuration(timing.Max),
+			"total_duration": formatDuration(timing.Total),
+		}
+	}
+
+	result := map[string]interface{}{
+		"uptime":          formatDuration(time.Since(serverStats.startedAt)),
+		"profiles_loaded": len(loadedProfiles),
+		"parses":          serverStats.parses,
+		"memory": map[string]interface{}{
+			"heap_mb":      formatMB(int64(m.HeapAlloc)),
+			"peak_heap_mb": formatMB(int64(max(serverStats.peakHeap, m.HeapAlloc))),
+			"sys_mb":       formatMB(int64(m.Sys)),
+		},
+		"tools": tools,
+	}
+	if serverStats.parses > 0 {
+		result["last_parse"] = map[string]interface{}{
+			"file":      serverStats.lastParseFile,
+			"duration":  formatDuration(serverStats.lastParse),
+			"parsed_at": serverStats.lastParseAt.Format(time.RFC3339),
+		}
+	}
+
+	return jsonResult(result)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// resetServerStats clears the server stats for the test and again after it
+func resetServerStats(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		serverStats.mu.Lock()
+		defer serverStats.mu.Unlock()
+		serverStats.parses = 0
+		serverStats.lastParse = 0
+		serverStats.lastParseFile = ""
+		serverStats.lastParseAt = time.Time{}
+		serverStats.tools = make(map[string]*toolTiming)
+		serverStats.peakHeap = 0
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestRecordToolCall(t *testing.T) {
+	tests := []struct {
+		name  string
+		calls []time.Duration
+		want  toolTiming
+	}{
+		{"one call", []time.Duration{5}, toolTiming{Calls: 1, Total: 5, Last: 5, Max: 5}},
+		{"slowest first", []time.Duration{30, 10, 20}, toolTiming{Calls: 3, Total: 60, Last: 20, Max: 30}},
+		{"slowest last", []time.Duration{10, 20, 30}, toolTiming{Calls: 3, Total: 60, Last: 30, Max: 30}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetServerStats(t)
+			for _, elapsed := range tt.calls {
+				recordToolCall("get_hotspots", elapsed)
+			}
+			recordToolCall("get_overview", time.Second)
+
+			if got := *serverStats.tools["get_hotspots"]; got != tt.want {
+				t.Errorf("timing = %+v, want %+v", got, tt.want)
+			}
+			if serverStats.peakHeap == 0 {
+				t.Error("peak heap not recorded")
+			}
+		})
+	}
+}
+
+func TestGetServerStatsHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		calls []string // Tools called through the server after loading the profile
+		want  map[string]float64
+	}{
+		{"load only", nil, map[string]float64{"load_profile": 1}},
+		{"load and analyses", []string{"get_hotspots", "get_hotspots", "analyze_performance_issues"},
+			map[string]float64{"load_profile": 1, "get_hotspots": 2, "analyze_performance_issues": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			resetServerStats(t)
+			srv := server.NewMCPServer("test", "1.0.0")
+			registerTools(srv)
+			path := runCapture(10, 20).WriteFile(t)
+
+			call := func(tool string, args map[string]interface{}) {
+				params, _ := json.Marshal(map[string]interface{}{"name": tool, "arguments": args})
+				message := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":%s}`, params)
+				if response, ok := srv.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse); !ok {
+					t.Fatalf("%s: response = %+v, want a result", tool, response)
+				}
+			}
+			call("load_profile", map[string]interface{}{"file_path": path})
+			for _, tool := range tt.calls {
+				call(tool, nil)
+			}
+
+			outputFormat.Raw = true // Durations as nanoseconds
+			stats := callToolJSON(t, getServerStatsHandler, nil)
+			if stats["profiles_loaded"] != 1.0 || stats["parses"] != 1.0 {
+				t.Errorf("%v profiles loaded after %v parses, want 1 and 1", stats["profiles_loaded"], stats["parses"])
+			}
+			lastParse, _ := stats["last_parse"].(map[string]interface{})
+			if lastParse["file"] != path || !(lastParse["duration"].(float64) > 0) {
+				t.Errorf("last parse = %v, want %s with a duration", lastParse, path)
+			}
+			memory, _ := stats["memory"].(map[string]interface{})
+			if !(memory["peak_heap_mb"].(float64) > 0) || memory["peak_heap_mb"].(float64) < memory["heap_mb"].(float64) {
+				t.Errorf("memory = %v, want a peak heap at least the current one", memory)
+			}
+
+			got := make(map[string]float64)
+			for _, tool := range list(t, stats, "tools") {
+				name := tool["tool"].(string)
+				got[name] = tool["calls"].(float64)
+				for _, key := range []string{"last_duration", "avg_duration", "max_duration", "total_duration"} {
+					if !(tool[key].(float64) > 0) {
+						t.Errorf("%s %s = %v, want a duration", name, key, tool[key])
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tool calls = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}